@@ -0,0 +1,91 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package annotator
+
+import (
+	"errors"
+
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/creator"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// BarcodeFieldAppearance implements interface model.FieldAppearanceGenerator
+// and generates appearance streams for text fields, which display the values
+// of the fields as barcodes. The barcodes are drawn as vector graphics,
+// scaled to fit the widget annotations of the fields.
+type BarcodeFieldAppearance struct {
+	// Type is the symbology used to encode the values of the fields.
+	Type creator.BarcodeType
+
+	// OnlyIfMissing specifies whether appearance streams are generated only
+	// for the widget annotations which do not have one.
+	OnlyIfMissing bool
+
+	// Configure is called, if set, in order to customize the barcode
+	// generated for the value of the specified field (e.g. colors, quiet
+	// zone, human-readable text).
+	Configure func(field *model.PdfField, barcode *creator.Barcode) error
+}
+
+// GenerateAppearanceDict generates an appearance dictionary for the specified
+// widget annotation of the text field, displaying the value of the field as
+// a barcode. Fields other than text fields are ignored.
+func (fa BarcodeFieldAppearance) GenerateAppearanceDict(form *model.PdfAcroForm, field *model.PdfField, wa *model.PdfAnnotationWidget) (*core.PdfObjectDictionary, error) {
+	text, ok := field.GetContext().(*model.PdfFieldText)
+	if !ok {
+		common.Log.Trace("Barcode appearances only handle text fields - ignoring")
+		return nil, nil
+	}
+	if apDict, ok := core.GetDict(wa.AP); ok && fa.OnlyIfMissing {
+		common.Log.Trace("Already populated - ignoring")
+		return apDict, nil
+	}
+
+	value, ok := core.GetString(text.V)
+	if !ok || value.Decoded() == "" {
+		return nil, nil
+	}
+
+	rectArr, ok := core.GetArray(wa.Rect)
+	if !ok {
+		return nil, errors.New("invalid widget annotation rectangle")
+	}
+	rect, err := model.NewPdfRectangle(*rectArr)
+	if err != nil {
+		return nil, err
+	}
+
+	barcode, err := creator.NewBarcode(fa.Type, value.Decoded())
+	if err != nil {
+		return nil, err
+	}
+	if fa.Configure != nil {
+		if err := fa.Configure(field, barcode); err != nil {
+			return nil, err
+		}
+	}
+
+	xform, err := barcode.ToXObjectForm(rect.Width(), rect.Height())
+	if err != nil {
+		return nil, err
+	}
+
+	apDict := core.MakeDict()
+	apDict.Set("N", xform.ToPdfObject())
+	return apDict, nil
+}
+
+// WrapContentStream ensures that the entire content stream for a `page` is
+// wrapped within q ... Q operands.
+func (fa BarcodeFieldAppearance) WrapContentStream(page *model.PdfPage) error {
+	return FieldAppearance{}.WrapContentStream(page)
+}
@@ -0,0 +1,272 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+// Package autotag implements automatic tagging of untagged PDF documents. The
+// layout analysis of the extractor package (paragraphs, tables and reading
+// order) is used to build a structure tree of headings, paragraphs, lists,
+// tables and figures. The content streams are marked accordingly, running
+// headers and footers being marked as artifacts.
+//
+// Each created element has a confidence score, so that the elements most
+// likely to be misclassified can be reviewed:
+//
+//	tagger := autotag.New(reader, nil)
+//	if err := tagger.Tag(); err != nil {
+//		return err
+//	}
+//	for _, elem := range tagger.Elements() {
+//		if elem.Confidence < 0.6 {
+//			fmt.Println(elem)
+//		}
+//	}
+//	err := tagger.WriteToFile(outputPath)
+package autotag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/extractor"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// ErrAlreadyTagged is returned when tagging a document having a structure
+// tree.
+var ErrAlreadyTagged = errors.New("document is already tagged")
+
+// Options configures the auto-tagging.
+type Options struct {
+	// Language is the natural language of the document, e.g. "en-US". Sets
+	// the Lang entry of the document catalog, if not empty.
+	Language string
+
+	// MarginRatio is the part of the page height, at the top and bottom of
+	// the pages, where running headers and footers are detected.
+	MarginRatio float64
+
+	// HeadingRatio is the minimal ratio of the font size of headings to the
+	// font size of the body text.
+	HeadingRatio float64
+
+	// MinFigureSize is the minimal width and height of the figures. Smaller
+	// images are marked as artifacts.
+	MinFigureSize float64
+
+	// AltText returns the alternative text of the figure at bbox on the page
+	// (1-based). Figures without alternative text have a low confidence.
+	AltText func(page int, bbox model.PdfRectangle) string
+}
+
+// DefaultOptions returns the default auto-tagging options.
+func DefaultOptions() *Options {
+	return &Options{
+		MarginRatio:   0.08,
+		HeadingRatio:  1.15,
+		MinFigureSize: 8,
+	}
+}
+
+// Element describes a structure element or artifact created by the
+// auto-tagger.
+type Element struct {
+	// Type is the structure type of the element, e.g. "H1", "P", "LI",
+	// "Table" or "Figure", or "Artifact" for headers and footers.
+	Type string
+
+	// Page is the number of the page of the element (1-based).
+	Page int
+
+	// BBox is the bounding box of the element.
+	BBox model.PdfRectangle
+
+	// Text is the text of the element.
+	Text string
+
+	// Confidence is the confidence of the classification of the element,
+	// from 0 to 1.
+	Confidence float64
+
+	// Note explains a low confidence.
+	Note string
+}
+
+// String returns a description of the element.
+func (e Element) String() string {
+	s := fmt.Sprintf("page %d %s (%.2f) %q", e.Page, e.Type, e.Confidence, e.Text)
+	if e.Note != "" {
+		s += ": " + e.Note
+	}
+	return s
+}
+
+// AutoTagger tags the content of an untagged document.
+type AutoTagger struct {
+	reader   *model.PdfReader
+	opts     Options
+	elements []Element
+	root     *core.PdfIndirectObject
+}
+
+// New returns an AutoTagger for the document of reader. Options can be nil.
+// NOTE: The pages of the reader are modified when tagging.
+func New(reader *model.PdfReader, opts *Options) *AutoTagger {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	return &AutoTagger{reader: reader, opts: *opts}
+}
+
+// Elements returns the elements created by Tag, in logical order.
+func (t *AutoTagger) Elements() []Element { return t.elements }
+
+// Tag analyzes the layout of the pages, marks their content and builds the
+// structure tree of the document.
+func (t *AutoTagger) Tag() error {
+	if t.root != nil {
+		return errors.New("document already processed")
+	}
+	if _, ok := t.reader.GetCatalogStructTreeRoot(); ok {
+		return ErrAlreadyTagged
+	}
+	numPages, err := t.reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	pages := make([]*pageLayout, numPages)
+	for i := range pages {
+		page, err := t.reader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+		if pages[i], err = newPageLayout(page, i+1); err != nil {
+			return err
+		}
+	}
+
+	c := newClassifier(&t.opts, pages)
+	doc := &node{typ: "Document"}
+	parentTree := core.MakeArray()
+	for i, layout := range pages {
+		c.classify(layout)
+		doc.kids = append(doc.kids, layout.nodes...)
+		mcids, err := layout.markContent(&t.opts)
+		if err != nil {
+			return err
+		}
+		layout.page.StructParents = core.MakeInteger(int64(i))
+		parentTree.Append(core.MakeInteger(int64(i)), mcids)
+	}
+
+	t.root = core.MakeIndirectObject(core.MakeDict())
+	root := t.root.PdfObject.(*core.PdfObjectDictionary)
+	root.Set("Type", core.MakeName("StructTreeRoot"))
+	if docObj := doc.build(t.root); docObj != nil {
+		root.Set("K", docObj)
+	}
+	root.Set("ParentTree", core.MakeIndirectObject(core.MakeDictMap(map[string]core.PdfObject{"Nums": parentTree})))
+	root.Set("ParentTreeNextKey", core.MakeInteger(int64(numPages)))
+	doc.collect(&t.elements)
+	return nil
+}
+
+// Write writes the tagged document to w.
+func (t *AutoTagger) Write(w io.Writer) error {
+	if t.root == nil {
+		return errors.New("document not tagged")
+	}
+	writer, err := t.reader.ToWriter(nil)
+	if err != nil {
+		return err
+	}
+	if err = writer.SetCatalogStructTreeRoot(t.root); err != nil {
+		return err
+	}
+	if err = writer.SetCatalogMarkInfo(core.MakeDictMap(map[string]core.PdfObject{
+		"Marked": core.MakeBool(true),
+	})); err != nil {
+		return err
+	}
+	if t.opts.Language != "" {
+		if err = writer.SetCatalogLanguage(core.MakeString(t.opts.Language)); err != nil {
+			return err
+		}
+	}
+	return writer.Write(w)
+}
+
+// WriteToFile writes the tagged document to outputPath.
+func (t *AutoTagger) WriteToFile(outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return t.Write(file)
+}
+
+// pageLayout holds the layout of a page.
+type pageLayout struct {
+	page   *model.PdfPage
+	number int
+	box    model.PdfRectangle
+	text   *extractor.PageText
+	paras  []extractor.TextParagraph
+	ops    *contentstream.ContentStreamOperations
+
+	// graphics are the images and form XObjects drawn by the page.
+	graphics []*graphic
+
+	// strings are the string operands of the text-showing operators of the
+	// page content stream.
+	strings map[core.PdfObject]bool
+
+	// nodes are the top-level structure elements of the page.
+	nodes []*node
+
+	// targets maps the text objects of the marks to their leaf elements or
+	// artifacts.
+	targets map[core.PdfObject]*node
+
+	// leaves are the elements the text was assigned to, in order.
+	leaves []*node
+}
+
+func newPageLayout(page *model.PdfPage, number int) (*pageLayout, error) {
+	box, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	if page.CropBox != nil {
+		box = page.CropBox
+	}
+	ex, err := extractor.New(page)
+	if err != nil {
+		return nil, err
+	}
+	text, _, _, err := ex.ExtractPageText()
+	if err != nil {
+		return nil, err
+	}
+	layout := &pageLayout{
+		page:    page,
+		number:  number,
+		box:     *box,
+		text:    text,
+		paras:   text.ParagraphsWithTables(nil),
+		targets: map[core.PdfObject]*node{},
+	}
+	if err = layout.scanGraphics(); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
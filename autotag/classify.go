@@ -0,0 +1,483 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package autotag
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/extractor"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+var (
+	reDigits     = regexp.MustCompile(`[0-9]+`)
+	reSpaces     = regexp.MustCompile(`\s+`)
+	rePageNumber = regexp.MustCompile(`(?i)^(page\s*)?([0-9]+|[ivxlc]+)(\s*(of|/)\s*[0-9]+)?$`)
+
+	// reListLabel matches the label of list items: bullets, numbers, letters
+	// and roman numerals. The label kind is the index of the matching group.
+	reListLabel = regexp.MustCompile(`^\s*(?:([•◦▪▫‣⁃●○■□–*-])|\(?([0-9]{1,3})[.)]|\(?([ivxlcIVXLC]{1,6})[.)]|\(?([a-zA-Z])[.)])\s+\S`)
+)
+
+// classifier classifies the paragraphs of the pages using statistics of the
+// whole document.
+type classifier struct {
+	opts *Options
+
+	// bodySize is the most common font size of the text.
+	bodySize float64
+	bodyBold bool
+
+	// headingSizes are the font sizes of the headings, in decreasing order.
+	headingSizes []float64
+
+	// repeated counts the pages on which the normalized text of the
+	// paragraphs in the page margins occurs.
+	repeated map[string]int
+}
+
+// paraStats are the font statistics of a paragraph.
+type paraStats struct {
+	size  float64
+	bold  float64
+	runes int
+	lines int
+}
+
+func statsOf(para extractor.TextParagraph) paraStats {
+	var st paraStats
+	var size, bold float64
+	for _, mark := range para.Marks.Elements() {
+		if mark.Meta {
+			if mark.Text == "\n" {
+				st.lines++
+			}
+			continue
+		}
+		n := utf8.RuneCountInString(mark.Text)
+		st.runes += n
+		size += mark.FontSize * float64(n)
+		if isBold(mark.Font) {
+			bold += float64(n)
+		}
+	}
+	st.lines++
+	if st.runes > 0 {
+		st.size = size / float64(st.runes)
+		st.bold = bold / float64(st.runes)
+	}
+	return st
+}
+
+// isBold returns true if the name of the font denotes a bold weight.
+func isBold(font *model.PdfFont) bool {
+	if font == nil {
+		return false
+	}
+	name := strings.ToLower(font.BaseFont())
+	for _, weight := range []string{"bold", "black", "heavy", "semibold", "demi"} {
+		if strings.Contains(name, weight) {
+			return true
+		}
+	}
+	return false
+}
+
+// roundSize rounds font sizes to half points.
+func roundSize(size float64) float64 { return math.Round(size*2) / 2 }
+
+func newClassifier(opts *Options, pages []*pageLayout) *classifier {
+	c := &classifier{opts: opts, repeated: map[string]int{}}
+	sizes := map[float64]int{}
+	boldRunes, runes := 0.0, 0
+	for _, layout := range pages {
+		seen := map[string]bool{}
+		for _, para := range layout.paras {
+			if c.marginKind(layout, para) != "" {
+				key := normalizeText(para.Text)
+				if !seen[key] {
+					seen[key] = true
+					c.repeated[key]++
+				}
+				continue
+			}
+			st := statsOf(para)
+			sizes[roundSize(st.size)] += st.runes
+			boldRunes += st.bold * float64(st.runes)
+			runes += st.runes
+		}
+	}
+	best := 0
+	for size, count := range sizes {
+		if count > best || (count == best && size < c.bodySize) {
+			c.bodySize, best = size, count
+		}
+	}
+	c.bodyBold = runes > 0 && boldRunes/float64(runes) > 0.5
+
+	headings := map[float64]bool{}
+	for _, layout := range pages {
+		for _, para := range layout.paras {
+			if para.Table != nil || c.marginKind(layout, para) != "" {
+				continue
+			}
+			if st := statsOf(para); c.isHeadingSize(st) {
+				headings[roundSize(st.size)] = true
+			}
+		}
+	}
+	for size := range headings {
+		c.headingSizes = append(c.headingSizes, size)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(c.headingSizes)))
+	return c
+}
+
+// isHeadingSize returns true if the paragraph is short and its font is larger
+// than the body text.
+func (c *classifier) isHeadingSize(st paraStats) bool {
+	return c.bodySize > 0 && st.runes > 0 && st.runes <= 200 && st.lines <= 3 &&
+		st.size >= c.bodySize*c.opts.HeadingRatio
+}
+
+// marginKind returns Header or Footer if the paragraph lies in the top or
+// bottom margin of the page, or an empty string.
+func (c *classifier) marginKind(layout *pageLayout, para extractor.TextParagraph) string {
+	band := (layout.box.Ury - layout.box.Lly) * c.opts.MarginRatio
+	switch {
+	case para.BBox.Lly >= layout.box.Ury-band:
+		return "Header"
+	case para.BBox.Ury <= layout.box.Lly+band:
+		return "Footer"
+	}
+	return ""
+}
+
+// stripSpaces returns the text without white space.
+func stripSpaces(text string) string {
+	return reSpaces.ReplaceAllString(text, "")
+}
+
+// normalizeText returns the text with its numbers and spaces normalized, to
+// match running headers and footers across pages.
+func normalizeText(text string) string {
+	text = reDigits.ReplaceAllString(strings.ToLower(text), "#")
+	return strings.TrimSpace(reSpaces.ReplaceAllString(text, " "))
+}
+
+// classify creates the structure elements of the paragraphs and graphics of
+// the page.
+func (c *classifier) classify(layout *pageLayout) {
+	var list *node
+	for _, para := range layout.paras {
+		text := strings.TrimSpace(para.Text)
+		if text == "" {
+			continue
+		}
+		elem := &Element{Page: layout.number, BBox: para.BBox, Text: text}
+
+		if kind := c.marginKind(layout, para); kind != "" {
+			repeated := c.repeated[normalizeText(para.Text)] >= 2
+			if repeated || rePageNumber.MatchString(text) {
+				elem.Type, elem.Confidence, elem.Note = "Artifact", 0.9, strings.ToLower(kind)
+				if !repeated {
+					elem.Confidence, elem.Note = 0.8, "page number"
+				}
+				n := newNode("Artifact", elem)
+				n.subtype = kind
+				layout.assign(n, para.Marks.Elements())
+				layout.nodes = append(layout.nodes, n)
+				list = nil
+				continue
+			}
+		}
+
+		if para.Table != nil {
+			layout.nodes = append(layout.nodes, c.table(layout, para, elem))
+			list = nil
+			continue
+		}
+
+		st := statsOf(para)
+		if typ, conf, note := c.headingType(st, text); typ != "" {
+			elem.Type, elem.Confidence, elem.Note = typ, conf, note
+			n := newNode(typ, elem)
+			layout.assign(n, para.Marks.Elements())
+			layout.nodes = append(layout.nodes, n)
+			list = nil
+			continue
+		}
+
+		if items := listItems(para); items != nil {
+			if list == nil {
+				list = newNode("L", nil)
+				layout.nodes = append(layout.nodes, list)
+			}
+			for _, item := range items {
+				itemElem := &Element{Type: "LI", Page: layout.number, Text: item.text, Confidence: item.confidence, Note: item.note}
+				body := newNode("LBody", nil)
+				layout.assign(body, item.marks)
+				itemElem.BBox = body.bbox
+				li := newNode("LI", itemElem)
+				li.kids = []*node{body}
+				list.kids = append(list.kids, li)
+			}
+			continue
+		}
+		list = nil
+
+		elem.Type, elem.Confidence = "P", 0.9
+		if c.bodySize > 0 && st.size < c.bodySize*0.85 {
+			elem.Confidence, elem.Note = 0.75, "font size smaller than body text"
+		}
+		n := newNode("P", elem)
+		layout.assign(n, para.Marks.Elements())
+		layout.nodes = append(layout.nodes, n)
+	}
+	c.placeGraphics(layout)
+}
+
+// headingType returns the heading type of the paragraph, with its
+// confidence, or an empty string if the paragraph is not a heading.
+func (c *classifier) headingType(st paraStats, text string) (string, float64, string) {
+	if c.isHeadingSize(st) {
+		level := sort.Search(len(c.headingSizes), func(i int) bool {
+			return c.headingSizes[i] <= roundSize(st.size)
+		}) + 1
+		ratio := st.size / c.bodySize
+		conf := math.Min(0.95, 0.55+ratio-1)
+		var note string
+		if ratio < 1.3 {
+			note = "font size slightly larger than body text"
+		}
+		return "H" + strconv.Itoa(min(level, 6)), conf, note
+	}
+	if !c.bodyBold && st.bold >= 0.8 && st.runes <= 120 && st.lines <= 2 &&
+		!strings.HasSuffix(text, ".") && st.size >= c.bodySize*0.95 {
+		level := min(len(c.headingSizes)+1, 6)
+		return "H" + strconv.Itoa(level), 0.6, "bold text classified as heading"
+	}
+	return "", 0, ""
+}
+
+// listItem is a list item of a paragraph.
+type listItem struct {
+	text       string
+	marks      []extractor.TextMark
+	confidence float64
+	note       string
+}
+
+// listItems splits the paragraph into list items, if its first line starts
+// with a list label. Returns nil otherwise.
+func listItems(para extractor.TextParagraph) []*listItem {
+	var items []*listItem
+	var item *listItem
+	var line []extractor.TextMark
+	var lineText strings.Builder
+	endLine := func() {
+		text := lineText.String()
+		if m := reListLabel.FindStringSubmatch(text); m != nil {
+			item = &listItem{confidence: 0.85}
+			switch {
+			case m[2] != "":
+				item.confidence = 0.8
+			case m[3] != "" || m[4] != "":
+				item.confidence, item.note = 0.6, "letter or roman numeral list label"
+			}
+			items = append(items, item)
+		} else if item == nil {
+			return
+		} else {
+			item.text += " "
+		}
+		item.text += strings.TrimSpace(text)
+		item.marks = append(item.marks, line...)
+	}
+	for _, mark := range para.Marks.Elements() {
+		if mark.Meta && mark.Text == "\n" {
+			endLine()
+			if item == nil {
+				return nil
+			}
+			line, lineText = nil, strings.Builder{}
+			continue
+		}
+		line = append(line, mark)
+		lineText.WriteString(mark.Text)
+	}
+	endLine()
+	return items
+}
+
+// table creates the structure elements of a table paragraph. The first row is
+// assumed to be the header row.
+func (c *classifier) table(layout *pageLayout, para extractor.TextParagraph, elem *Element) *node {
+	tbl := para.Table
+	elem.Type, elem.Confidence = "Table", 0.7
+	if tbl.H > 1 {
+		elem.Note = "first row assumed to be the header row"
+	}
+	table := newNode("Table", elem)
+	cells := make([][]*node, tbl.H)
+	for y := 0; y < tbl.H; y++ {
+		row := newNode("TR", nil)
+		cells[y] = make([]*node, tbl.W)
+		for x := 0; x < tbl.W; x++ {
+			cell := newNode("TD", nil)
+			if y == 0 && tbl.H > 1 {
+				cell.typ, cell.scope = "TH", "Column"
+			}
+			cells[y][x] = cell
+			row.kids = append(row.kids, cell)
+		}
+		table.kids = append(table.kids, row)
+	}
+
+	// The marks of the table paragraph follow the cells in row order. Each
+	// cell takes the marks that make up its text.
+	marks := para.Marks.Elements()
+	for y := 0; y < tbl.H && y < len(tbl.Cells); y++ {
+		for x := 0; x < tbl.W && x < len(tbl.Cells[y]); x++ {
+			want := len(stripSpaces(tbl.Cells[y][x].Text))
+			var cellMarks []extractor.TextMark
+			for got := 0; got < want && len(marks) > 0; marks = marks[1:] {
+				if !marks[0].Meta {
+					got += len(stripSpaces(marks[0].Text))
+					cellMarks = append(cellMarks, marks[0])
+				}
+			}
+			layout.assign(cells[y][x], cellMarks)
+		}
+	}
+	return table
+}
+
+// assign marks the text objects of the marks as content of the node, unless
+// assigned already.
+func (l *pageLayout) assign(n *node, marks []extractor.TextMark) {
+	for _, mark := range marks {
+		if mark.Meta || mark.DirectObject == nil {
+			continue
+		}
+		obj := core.TraceToDirectObject(mark.DirectObject)
+		if _, ok := l.targets[obj]; !ok {
+			l.targets[obj] = n
+		}
+		if l.strings[obj] {
+			n.pageContent = true
+		}
+		if n.bbox == (model.PdfRectangle{}) {
+			l.leaves = append(l.leaves, n)
+			n.bbox = mark.BBox
+		}
+		n.bbox = unionRect(n.bbox, mark.BBox)
+	}
+}
+
+// placeGraphics creates the figures of the images and form XObjects of the
+// page, inserted in reading order. Text drawn by form XObjects is attributed
+// to the element it was extracted to.
+func (c *classifier) placeGraphics(layout *pageLayout) {
+	pageArea := (layout.box.Urx - layout.box.Llx) * (layout.box.Ury - layout.box.Lly)
+	for _, g := range layout.graphics {
+		w, h := g.bbox.Width(), g.bbox.Height()
+		switch {
+		case g.form && g.hasText:
+			if target := layout.formTarget(g.bbox); target != nil {
+				g.target = target
+				if target.report != nil {
+					target.report.Confidence = math.Min(target.report.Confidence, 0.6)
+					target.report.Note = "drawn by a form XObject"
+				}
+				continue
+			}
+			elem := &Element{Type: "P", Page: layout.number, BBox: g.bbox, Confidence: 0.4,
+				Note: "text of a form XObject not found by the layout analysis"}
+			g.target = newNode("P", elem)
+			g.target.bbox = g.bbox
+			layout.insert(g.target)
+		case w < c.opts.MinFigureSize || h < c.opts.MinFigureSize:
+		case g.form && w*h > pageArea/2:
+		default:
+			elem := &Element{Type: "Figure", Page: layout.number, BBox: g.bbox, Confidence: 0.8}
+			n := newNode("Figure", elem)
+			n.bbox, n.hasBBox = g.bbox, true
+			if c.opts.AltText != nil {
+				n.alt = c.opts.AltText(layout.number, g.bbox)
+			}
+			if n.alt == "" {
+				elem.Confidence, elem.Note = 0.3, "alternative text required"
+			}
+			if g.form {
+				elem.Confidence = math.Min(elem.Confidence, 0.4)
+				elem.Note = strings.TrimPrefix(elem.Note+", vector graphics tagged as figure", ", ")
+			}
+			g.target = n
+			layout.insert(n)
+		}
+	}
+}
+
+// formTarget returns the element of the text not drawn by the page content
+// stream that overlaps bbox most, or nil if none.
+func (l *pageLayout) formTarget(bbox model.PdfRectangle) *node {
+	var best *node
+	bestArea := 0.0
+	for _, leaf := range l.leaves {
+		if leaf.pageContent {
+			continue
+		}
+		r := leaf.bbox
+		w := math.Min(r.Urx, bbox.Urx) - math.Max(r.Llx, bbox.Llx)
+		h := math.Min(r.Ury, bbox.Ury) - math.Max(r.Lly, bbox.Lly)
+		if w > 0 && h > 0 && w*h > bestArea {
+			best, bestArea = leaf, w*h
+		}
+	}
+	return best
+}
+
+// insert inserts the top-level node before the first node starting below it.
+func (l *pageLayout) insert(n *node) {
+	for i, other := range l.nodes {
+		if other.typ != "Artifact" && nodeTop(other) < n.bbox.Ury {
+			l.nodes = append(l.nodes[:i], append([]*node{n}, l.nodes[i:]...)...)
+			return
+		}
+	}
+	l.nodes = append(l.nodes, n)
+}
+
+// nodeTop returns the top of the content of the node.
+func nodeTop(n *node) float64 {
+	top := math.Inf(-1)
+	if n.bbox != (model.PdfRectangle{}) {
+		top = n.bbox.Ury
+	}
+	for _, kid := range n.kids {
+		top = math.Max(top, nodeTop(kid))
+	}
+	return top
+}
+
+// unionRect returns the smallest rectangle containing a and b.
+func unionRect(a, b model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(a.Llx, b.Llx), Lly: math.Min(a.Lly, b.Lly),
+		Urx: math.Max(a.Urx, b.Urx), Ury: math.Max(a.Ury, b.Ury),
+	}
+}
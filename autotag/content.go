@@ -0,0 +1,387 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package autotag
+
+import (
+	"math"
+
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/internal/transform"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// graphic is an image or form XObject drawn by a page.
+type graphic struct {
+	op   *contentstream.ContentStreamOperation
+	bbox model.PdfRectangle
+
+	// form is true for form XObjects, hasText is true if the form draws text.
+	form    bool
+	hasText bool
+
+	// target is the element or artifact the graphic is marked as.
+	target *node
+}
+
+// scanGraphics finds the images and form XObjects drawn by the page content
+// stream, and the string operands of its text-showing operators.
+func (l *pageLayout) scanGraphics() error {
+	l.ops = l.text.GetContentStreamOps()
+	l.strings = map[core.PdfObject]bool{}
+	if l.ops == nil {
+		return nil
+	}
+	ctm := transform.IdentityMatrix()
+	var stack []transform.Matrix
+	for _, op := range *l.ops {
+		switch op.Operand {
+		case "q":
+			stack = append(stack, ctm)
+		case "Q":
+			if len(stack) > 0 {
+				ctm = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if m, ok := matrixFromObjects(op.Params); ok {
+				ctm = ctm.Mult(m)
+			}
+		case "Tj", "'", "\"", "TJ":
+			for _, obj := range textOperands(op) {
+				l.strings[obj] = true
+			}
+		case "BI":
+			l.graphics = append(l.graphics, &graphic{op: op, bbox: transformRect(ctm, 0, 0, 1, 1)})
+		case "Do":
+			if len(op.Params) == 0 || l.page.Resources == nil {
+				continue
+			}
+			name, ok := core.GetName(op.Params[0])
+			if !ok {
+				continue
+			}
+			stream, typ := l.page.Resources.GetXObjectByName(*name)
+			switch typ {
+			case model.XObjectTypeImage:
+				l.graphics = append(l.graphics, &graphic{op: op, bbox: transformRect(ctm, 0, 0, 1, 1)})
+			case model.XObjectTypeForm:
+				bbox, ok := core.GetArray(stream.Get("BBox"))
+				if !ok {
+					continue
+				}
+				rect, err := model.NewPdfRectangle(*bbox)
+				if err != nil {
+					return err
+				}
+				m := ctm
+				if matrix, ok := core.GetArray(stream.Get("Matrix")); ok {
+					if fm, ok := matrixFromObjects(matrix.Elements()); ok {
+						m = ctm.Mult(fm)
+					}
+				}
+				l.graphics = append(l.graphics, &graphic{
+					op:      op,
+					bbox:    transformRect(m, rect.Llx, rect.Lly, rect.Urx, rect.Ury),
+					form:    true,
+					hasText: formHasText(stream, 0),
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// matrixFromObjects returns the matrix of the 6 numbers of objs.
+func matrixFromObjects(objs []core.PdfObject) (transform.Matrix, bool) {
+	if len(objs) != 6 {
+		return transform.Matrix{}, false
+	}
+	vals, err := core.GetNumbersAsFloat(objs)
+	if err != nil {
+		return transform.Matrix{}, false
+	}
+	return transform.NewMatrix(vals[0], vals[1], vals[2], vals[3], vals[4], vals[5]), true
+}
+
+// transformRect returns the bounding box of the rectangle transformed by m.
+func transformRect(m transform.Matrix, llx, lly, urx, ury float64) model.PdfRectangle {
+	rect := model.PdfRectangle{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+	for _, p := range [][2]float64{{llx, lly}, {urx, lly}, {urx, ury}, {llx, ury}} {
+		x, y := m.Transform(p[0], p[1])
+		rect.Llx, rect.Lly = math.Min(rect.Llx, x), math.Min(rect.Lly, y)
+		rect.Urx, rect.Ury = math.Max(rect.Urx, x), math.Max(rect.Ury, y)
+	}
+	return rect
+}
+
+// maxFormDepth is the maximal nesting depth of the form XObjects scanned for
+// text.
+const maxFormDepth = 5
+
+// formHasText returns true if the form XObject, or a form XObject it draws,
+// shows text.
+func formHasText(stream *core.PdfObjectStream, depth int) bool {
+	if depth > maxFormDepth {
+		return false
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to decode form XObject: %v", err)
+		return false
+	}
+	ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+	if err != nil {
+		return false
+	}
+	resources, _ := core.GetDict(stream.Get("Resources"))
+	for _, op := range *ops {
+		switch op.Operand {
+		case "Tj", "'", "\"", "TJ":
+			return true
+		case "Do":
+			if resources == nil || len(op.Params) == 0 {
+				continue
+			}
+			name, _ := core.GetName(op.Params[0])
+			xobjects, ok := core.GetDict(resources.Get("XObject"))
+			if !ok || name == nil {
+				continue
+			}
+			if form, ok := core.GetStream(xobjects.Get(*name)); ok {
+				if subtype, _ := core.GetNameVal(form.Get("Subtype")); subtype == "Form" && formHasText(form, depth+1) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// textOperands returns the string operands of a text-showing operator.
+func textOperands(op *contentstream.ContentStreamOperation) []core.PdfObject {
+	switch op.Operand {
+	case "Tj", "'":
+		if len(op.Params) == 1 {
+			return []core.PdfObject{core.TraceToDirectObject(op.Params[0])}
+		}
+	case "\"":
+		if len(op.Params) == 3 {
+			return []core.PdfObject{core.TraceToDirectObject(op.Params[2])}
+		}
+	case "TJ":
+		if len(op.Params) == 1 {
+			if arr, ok := core.GetArray(op.Params[0]); ok {
+				var objs []core.PdfObject
+				for _, obj := range arr.Elements() {
+					if _, ok := core.GetString(obj); ok {
+						objs = append(objs, core.TraceToDirectObject(obj))
+					}
+				}
+				return objs
+			}
+		}
+	}
+	return nil
+}
+
+// Operators of the content streams, by category.
+var (
+	pathConstructionOps = map[string]bool{"m": true, "l": true, "c": true, "v": true, "y": true, "h": true, "re": true, "W": true, "W*": true}
+	pathPaintingOps     = map[string]bool{"S": true, "s": true, "f": true, "F": true, "f*": true, "B": true, "B*": true, "b": true, "b*": true, "n": true}
+
+	// stateOps are the operators that can be part of a marked-content
+	// sequence without being marked.
+	stateOps = map[string]bool{
+		"Tc": true, "Tw": true, "Tz": true, "TL": true, "Tf": true, "Tr": true, "Ts": true,
+		"Td": true, "TD": true, "Tm": true, "T*": true,
+		"CS": true, "cs": true, "SC": true, "SCN": true, "sc": true, "scn": true,
+		"G": true, "g": true, "RG": true, "rg": true, "K": true, "k": true,
+		"gs": true, "w": true, "J": true, "j": true, "M": true, "d": true, "ri": true, "i": true,
+	}
+)
+
+// marker writes the operators of a content stream, enclosing the painting
+// operators in marked-content sequences.
+type marker struct {
+	page    *model.PdfPage
+	out     contentstream.ContentStreamOperations
+	open    *node
+	parents *core.PdfObjectArray
+}
+
+// begin starts a marked-content sequence for target, unless open already.
+func (m *marker) begin(target *node) {
+	if m.open == target {
+		return
+	}
+	m.end()
+	target.marked = true
+	switch {
+	case target.typ == "Artifact" && target.subtype != "":
+		m.emit("BDC", core.MakeName("Artifact"), core.MakeDictMap(map[string]core.PdfObject{
+			"Type":    core.MakeName("Pagination"),
+			"Subtype": core.MakeName(target.subtype),
+		}))
+	case target.typ == "Artifact":
+		m.emit("BMC", core.MakeName("Artifact"))
+	default:
+		mcid := int64(m.parents.Len())
+		m.parents.Append(target.object())
+		target.page = m.page
+		target.mcids = append(target.mcids, mcid)
+		m.emit("BDC", core.MakeName(target.typ), core.MakeDictMap(map[string]core.PdfObject{
+			"MCID": core.MakeInteger(mcid),
+		}))
+	}
+	m.open = target
+}
+
+// end ends the open marked-content sequence, if any.
+func (m *marker) end() {
+	if m.open != nil {
+		m.emit("EMC")
+		m.open = nil
+	}
+}
+
+func (m *marker) emit(operand string, params ...core.PdfObject) {
+	m.out = append(m.out, &contentstream.ContentStreamOperation{Operand: operand, Params: params})
+}
+
+// markContent encloses the content of the page in marked-content sequences of
+// the elements and artifacts of the page. Returns the parent tree entry of the
+// page.
+func (l *pageLayout) markContent(opts *Options) (*core.PdfObjectArray, error) {
+	m := &marker{page: l.page, parents: core.MakeArray()}
+	if l.ops == nil {
+		return m.parents, nil
+	}
+	decoration := &node{typ: "Artifact"}
+	graphics := make(map[*contentstream.ContentStreamOperation]*graphic, len(l.graphics))
+	for _, g := range l.graphics {
+		graphics[g.op] = g
+	}
+	textTarget := func(obj core.PdfObject) *node {
+		if target := l.targets[obj]; target != nil {
+			return target
+		}
+		return decoration
+	}
+
+	var path []*contentstream.ContentStreamOperation
+	flushPath := func(target *node) {
+		if target == nil {
+			m.end()
+		} else {
+			m.begin(target)
+		}
+		m.out = append(m.out, path...)
+		path = nil
+	}
+	for _, op := range *l.ops {
+		if pathConstructionOps[op.Operand] {
+			path = append(path, op)
+			continue
+		}
+		if pathPaintingOps[op.Operand] {
+			path = append(path, op)
+			if op.Operand == "n" {
+				flushPath(nil)
+			} else {
+				flushPath(decoration)
+			}
+			continue
+		}
+		if len(path) > 0 {
+			// Unterminated path.
+			flushPath(nil)
+		}
+
+		switch op.Operand {
+		case "Tj", "'", "\"":
+			target := decoration
+			if objs := textOperands(op); len(objs) == 1 {
+				target = textTarget(objs[0])
+			}
+			m.begin(target)
+			m.out = append(m.out, op)
+		case "TJ":
+			l.markTJ(m, op, textTarget)
+		case "Do", "BI":
+			target := decoration
+			if g := graphics[op]; g != nil && g.target != nil {
+				target = g.target
+			}
+			m.begin(target)
+			m.out = append(m.out, op)
+		case "sh":
+			m.begin(decoration)
+			m.out = append(m.out, op)
+		default:
+			if !stateOps[op.Operand] {
+				m.end()
+			}
+			m.out = append(m.out, op)
+		}
+	}
+	if len(path) > 0 {
+		flushPath(nil)
+	}
+	m.end()
+
+	if err := l.page.SetContentStreams([]string{m.out.String()}, core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+	return m.parents, nil
+}
+
+// markTJ marks the TJ operator. The operator is split when its strings belong
+// to different elements.
+func (l *pageLayout) markTJ(m *marker, op *contentstream.ContentStreamOperation, textTarget func(core.PdfObject) *node) {
+	var arr *core.PdfObjectArray
+	if len(op.Params) == 1 {
+		arr, _ = core.GetArray(op.Params[0])
+	}
+	if arr == nil {
+		m.begin(textTarget(nil))
+		m.out = append(m.out, op)
+		return
+	}
+	type group struct {
+		target *node
+		elems  []core.PdfObject
+	}
+	var groups []*group
+	for _, obj := range arr.Elements() {
+		if _, ok := core.GetString(obj); ok {
+			target := textTarget(core.TraceToDirectObject(obj))
+			if len(groups) == 0 || groups[len(groups)-1].target != target {
+				groups = append(groups, &group{target: target})
+			}
+		} else if len(groups) == 0 {
+			groups = append(groups, &group{target: m.open})
+		}
+		g := groups[len(groups)-1]
+		g.elems = append(g.elems, obj)
+	}
+	if len(groups) == 1 && groups[0].target != nil {
+		m.begin(groups[0].target)
+		m.out = append(m.out, op)
+		return
+	}
+	for _, g := range groups {
+		if g.target != nil {
+			m.begin(g.target)
+		}
+		m.emit("TJ", core.MakeArray(g.elems...))
+	}
+}
@@ -0,0 +1,125 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package autotag
+
+import (
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// node is a structure element, or an artifact if its type is "Artifact".
+type node struct {
+	typ  string
+	kids []*node
+
+	// subtype is the subtype of pagination artifacts: Header or Footer.
+	subtype string
+
+	// page is the page of the marked content of the element.
+	page  *model.PdfPage
+	mcids []int64
+
+	bbox    model.PdfRectangle
+	hasBBox bool
+	alt     string
+	scope   string
+
+	// report describes the element in the results of the auto-tagger.
+	report *Element
+
+	// pageContent is true if marks of the element are drawn by the content
+	// stream of the page, rather than by form XObjects.
+	pageContent bool
+
+	// marked is true if content was marked as the element.
+	marked bool
+
+	obj   *core.PdfIndirectObject
+	built bool
+}
+
+// newNode returns a new node reported as elem, if not nil.
+func newNode(typ string, elem *Element) *node {
+	return &node{typ: typ, report: elem}
+}
+
+// object returns the indirect object of the structure element.
+func (n *node) object() *core.PdfIndirectObject {
+	if n.obj == nil {
+		n.obj = core.MakeIndirectObject(core.MakeDict())
+	}
+	return n.obj
+}
+
+// build fills the structure element and its descendants. Elements without
+// content are dropped. Returns the object of the element, or nil if dropped.
+func (n *node) build(parent *core.PdfIndirectObject) *core.PdfIndirectObject {
+	if n.typ == "Artifact" {
+		return nil
+	}
+	obj := n.object()
+	var kids []core.PdfObject
+	for _, mcid := range n.mcids {
+		kids = append(kids, core.MakeInteger(mcid))
+	}
+	for _, kid := range n.kids {
+		if kidObj := kid.build(obj); kidObj != nil {
+			kids = append(kids, kidObj)
+		}
+	}
+	if len(kids) == 0 {
+		return nil
+	}
+	n.built = true
+
+	dict := obj.PdfObject.(*core.PdfObjectDictionary)
+	dict.Set("Type", core.MakeName("StructElem"))
+	dict.Set("S", core.MakeName(n.typ))
+	dict.Set("P", parent)
+	if len(n.mcids) > 0 && n.page != nil {
+		dict.Set("Pg", n.page.GetPageAsIndirectObject())
+	}
+	dict.Set("K", core.MakeArray(kids...))
+	if n.alt != "" {
+		dict.Set("Alt", core.MakeString(n.alt))
+	}
+	var attrs []core.PdfObject
+	if n.scope != "" {
+		attrs = append(attrs, core.MakeDictMap(map[string]core.PdfObject{
+			"O":     core.MakeName("Table"),
+			"Scope": core.MakeName(n.scope),
+		}))
+	}
+	if n.hasBBox {
+		attrs = append(attrs, core.MakeDictMap(map[string]core.PdfObject{
+			"O":    core.MakeName("Layout"),
+			"BBox": n.bbox.ToPdfObject(),
+		}))
+	}
+	switch len(attrs) {
+	case 0:
+	case 1:
+		dict.Set("A", attrs[0])
+	default:
+		dict.Set("A", core.MakeArray(attrs...))
+	}
+	return obj
+}
+
+// collect appends the reported elements of the tree to elems. Structure
+// elements dropped by build are skipped.
+func (n *node) collect(elems *[]Element) {
+	if n.report != nil && (n.built || (n.typ == "Artifact" && n.marked)) {
+		*elems = append(*elems, *n.report)
+	}
+	for _, kid := range n.kids {
+		kid.collect(elems)
+	}
+}
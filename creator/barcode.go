@@ -0,0 +1,658 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/aztec"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/pdf417"
+	"github.com/boombuler/barcode/qr"
+	"github.com/boombuler/barcode/twooffive"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// BarcodeType represents the symbology of a barcode.
+type BarcodeType int
+
+// Supported barcode symbologies.
+const (
+	// BarcodeCode128 represents the Code 128 linear symbology.
+	BarcodeCode128 BarcodeType = iota
+
+	// BarcodeCode39 represents the Code 39 linear symbology. Full ASCII
+	// content is supported.
+	BarcodeCode39
+
+	// BarcodeEAN13 represents the EAN-13 linear symbology. The content
+	// consists of 12 digits, or 13 digits including the check digit.
+	BarcodeEAN13
+
+	// BarcodeEAN8 represents the EAN-8 linear symbology. The content
+	// consists of 7 digits, or 8 digits including the check digit.
+	BarcodeEAN8
+
+	// BarcodeUPCA represents the UPC-A linear symbology. The content
+	// consists of 11 digits, or 12 digits including the check digit.
+	BarcodeUPCA
+
+	// BarcodeITF represents the Interleaved 2 of 5 linear symbology.
+	// The content consists of an even number of digits.
+	BarcodeITF
+
+	// BarcodeQR represents the QR Code matrix symbology.
+	BarcodeQR
+
+	// BarcodeDataMatrix represents the Data Matrix (ECC 200) matrix
+	// symbology.
+	BarcodeDataMatrix
+
+	// BarcodePDF417 represents the PDF417 stacked linear symbology.
+	BarcodePDF417
+
+	// BarcodeAztec represents the Aztec Code matrix symbology.
+	BarcodeAztec
+)
+
+// String returns the name of the barcode symbology.
+func (t BarcodeType) String() string {
+	switch t {
+	case BarcodeCode128:
+		return "Code128"
+	case BarcodeCode39:
+		return "Code39"
+	case BarcodeEAN13:
+		return "EAN13"
+	case BarcodeEAN8:
+		return "EAN8"
+	case BarcodeUPCA:
+		return "UPCA"
+	case BarcodeITF:
+		return "ITF"
+	case BarcodeQR:
+		return "QR"
+	case BarcodeDataMatrix:
+		return "DataMatrix"
+	case BarcodePDF417:
+		return "PDF417"
+	case BarcodeAztec:
+		return "Aztec"
+	}
+	return fmt.Sprintf("BarcodeType(%d)", int(t))
+}
+
+// isLinear returns true if the barcode symbology is a linear (1D) one.
+func (t BarcodeType) isLinear() bool {
+	switch t {
+	case BarcodeQR, BarcodeDataMatrix, BarcodePDF417, BarcodeAztec:
+		return false
+	}
+	return true
+}
+
+// QRErrorCorrectionLevel represents the error correction level of QR codes.
+type QRErrorCorrectionLevel int
+
+// Supported QR code error correction levels.
+const (
+	// QRErrorCorrectionLow recovers up to 7% of the codewords.
+	QRErrorCorrectionLow QRErrorCorrectionLevel = iota
+
+	// QRErrorCorrectionMedium recovers up to 15% of the codewords.
+	QRErrorCorrectionMedium
+
+	// QRErrorCorrectionQuartile recovers up to 25% of the codewords.
+	QRErrorCorrectionQuartile
+
+	// QRErrorCorrectionHigh recovers up to 30% of the codewords.
+	QRErrorCorrectionHigh
+)
+
+// ErrBarcodeUnsupported is returned when creating a barcode having an
+// unsupported symbology.
+var ErrBarcodeUnsupported = errors.New("unsupported barcode type")
+
+// Barcode represents a barcode component. The modules of the barcode are
+// drawn as filled paths, which makes the output resolution independent.
+// Linear barcodes can display their content as human-readable text, below
+// the bars. Implements the Drawable interface.
+type Barcode struct {
+	taggedDrawable
+	kind    BarcodeType
+	content string
+	level   QRErrorCorrectionLevel
+
+	// Dark modules of the barcode, by row. Linear barcodes have one row.
+	modules [][]bool
+
+	moduleWidth float64
+	barHeight   float64
+	quietZone   int
+
+	showText  bool
+	text      string
+	textStyle TextStyle
+
+	color      Color
+	background Color
+	angle      float64
+
+	margins     Margins
+	positioning Positioning
+	x, y        float64
+}
+
+// NewBarcode returns a new barcode component, encoding the specified content
+// using the specified symbology. The human-readable text of linear barcodes
+// is displayed using the default font of the creator.
+func (c *Creator) NewBarcode(kind BarcodeType, content string) (*Barcode, error) {
+	b, err := NewBarcode(kind, content)
+	if err != nil {
+		return nil, err
+	}
+	b.textStyle = c.NewTextStyle()
+	return b, nil
+}
+
+// NewBarcode returns a new barcode component, encoding the specified content
+// using the specified symbology. Linear barcodes display their content as
+// human-readable text by default, using the Helvetica font.
+func NewBarcode(kind BarcodeType, content string) (*Barcode, error) {
+	b := &Barcode{
+		kind:           kind,
+		content:        content,
+		level:          QRErrorCorrectionMedium,
+		moduleWidth:    1,
+		barHeight:      50,
+		quietZone:      10,
+		showText:       kind.isLinear(),
+		color:          ColorBlack,
+		positioning:    PositionRelative,
+		taggedDrawable: taggedDrawable{_edggf: model.StructureTypeFigure},
+	}
+	switch kind {
+	case BarcodeQR:
+		b.moduleWidth, b.quietZone = 3, 4
+	case BarcodeDataMatrix:
+		b.moduleWidth, b.quietZone = 3, 1
+	case BarcodePDF417:
+		b.quietZone = 2
+	case BarcodeAztec:
+		b.moduleWidth, b.quietZone = 3, 0
+	}
+
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	if err != nil {
+		font = model.DefaultFont()
+	}
+	b.textStyle = TextStyle{
+		Color:             ColorBlack,
+		Font:              font,
+		FontSize:          10,
+		OutlineSize:       1,
+		HorizontalScaling: DefaultHorizontalScaling,
+	}
+
+	if err := b.encode(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// encode encodes the content of the barcode into modules.
+func (b *Barcode) encode() error {
+	var (
+		code barcode.Barcode
+		err  error
+	)
+	switch b.kind {
+	case BarcodeCode128:
+		code, err = code128.Encode(b.content)
+	case BarcodeCode39:
+		code, err = code39.Encode(b.content, false, true)
+	case BarcodeEAN13, BarcodeEAN8:
+		code, err = ean.Encode(b.content)
+		if err == nil && code.Metadata().CodeKind != map[BarcodeType]string{
+			BarcodeEAN13: barcode.TypeEAN13,
+			BarcodeEAN8:  barcode.TypeEAN8,
+		}[b.kind] {
+			err = fmt.Errorf("invalid %s content length: %d", b.kind, len(b.content))
+		}
+	case BarcodeUPCA:
+		if n := len(b.content); n != 11 && n != 12 {
+			return fmt.Errorf("invalid %s content length: %d", b.kind, n)
+		}
+		code, err = ean.Encode("0" + b.content)
+	case BarcodeITF:
+		code, err = twooffive.Encode(b.content, true)
+	case BarcodeQR:
+		levels := map[QRErrorCorrectionLevel]qr.ErrorCorrectionLevel{
+			QRErrorCorrectionLow:      qr.L,
+			QRErrorCorrectionMedium:   qr.M,
+			QRErrorCorrectionQuartile: qr.Q,
+			QRErrorCorrectionHigh:     qr.H,
+		}
+		level, ok := levels[b.level]
+		if !ok {
+			return fmt.Errorf("invalid QR error correction level: %d", b.level)
+		}
+		code, err = qr.Encode(b.content, level, qr.Auto)
+	case BarcodeDataMatrix:
+		code, err = datamatrix.Encode(b.content)
+	case BarcodePDF417:
+		code, err = pdf417.Encode(b.content, 2)
+	case BarcodeAztec:
+		code, err = aztec.Encode([]byte(b.content), 33, 0)
+	default:
+		return ErrBarcodeUnsupported
+	}
+	if err != nil {
+		return err
+	}
+
+	bounds := code.Bounds()
+	rows := bounds.Dy()
+	if b.kind.isLinear() {
+		rows = 1
+	}
+	b.modules = make([][]bool, rows)
+	for y := 0; y < rows; y++ {
+		row := make([]bool, bounds.Dx())
+		for x := range row {
+			cr, cg, cb, _ := code.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			row[x] = cr+cg+cb < 3*0x8000
+		}
+		b.modules[y] = row
+	}
+	return nil
+}
+
+// Type returns the symbology of the barcode.
+func (b *Barcode) Type() BarcodeType {
+	return b.kind
+}
+
+// Content returns the content encoded by the barcode.
+func (b *Barcode) Content() string {
+	return b.content
+}
+
+// SetErrorCorrection sets the error correction level of QR codes. The content
+// of the barcode is encoded again. The level is ignored by other symbologies.
+func (b *Barcode) SetErrorCorrection(level QRErrorCorrectionLevel) error {
+	b.level = level
+	if b.kind != BarcodeQR {
+		return nil
+	}
+	return b.encode()
+}
+
+// SetModuleWidth sets the width of the narrowest bar (the X-dimension) of
+// linear barcodes, and the size of the modules of matrix barcodes.
+func (b *Barcode) SetModuleWidth(width float64) {
+	b.moduleWidth = width
+}
+
+// SetBarHeight sets the height of the bars of linear barcodes.
+func (b *Barcode) SetBarHeight(height float64) {
+	b.barHeight = height
+}
+
+// SetQuietZone sets the width of the blank margin surrounding the barcode,
+// as a number of modules. The default value depends on the symbology.
+func (b *Barcode) SetQuietZone(modules int) {
+	if modules < 0 {
+		modules = 0
+	}
+	b.quietZone = modules
+}
+
+// SetShowText sets whether the human-readable text is displayed below the
+// barcode. Enabled by default for linear barcodes.
+func (b *Barcode) SetShowText(showText bool) {
+	b.showText = showText
+}
+
+// SetText sets the human-readable text of the barcode. By default, the
+// encoded content is displayed.
+func (b *Barcode) SetText(text string) {
+	b.text = text
+}
+
+// SetTextStyle sets the style of the human-readable text.
+func (b *Barcode) SetTextStyle(style TextStyle) {
+	b.textStyle = style
+}
+
+// SetColor sets the color of the dark modules of the barcode.
+func (b *Barcode) SetColor(color Color) {
+	b.color = color
+}
+
+// SetBackgroundColor sets the color of the light modules and of the quiet
+// zone of the barcode. The background is transparent by default.
+func (b *Barcode) SetBackgroundColor(color Color) {
+	b.background = color
+}
+
+// SetAngle sets the rotation angle of the barcode, in degrees. The barcode
+// is rotated counter-clockwise, around its center.
+func (b *Barcode) SetAngle(angle float64) {
+	b.angle = angle
+}
+
+// Angle returns the rotation angle of the barcode, in degrees.
+func (b *Barcode) Angle() float64 {
+	return b.angle
+}
+
+// SetMargins sets the margins of the barcode.
+func (b *Barcode) SetMargins(left, right, top, bottom float64) {
+	b.margins.Left = left
+	b.margins.Right = right
+	b.margins.Top = top
+	b.margins.Bottom = bottom
+}
+
+// GetMargins returns the margins of the barcode: left, right, top, bottom.
+func (b *Barcode) GetMargins() (float64, float64, float64, float64) {
+	return b.margins.Left, b.margins.Right, b.margins.Top, b.margins.Bottom
+}
+
+// SetPos sets the absolute position of the barcode. Changes the positioning
+// of the barcode to absolute.
+func (b *Barcode) SetPos(x, y float64) {
+	b.positioning = PositionAbsolute
+	b.x = x
+	b.y = y
+}
+
+// ScaleToWidth sets the module width of the barcode, so that the width of
+// the unrotated barcode, including the quiet zone, matches the specified
+// width.
+func (b *Barcode) ScaleToWidth(width float64) {
+	if columns := b.columns(); columns > 0 {
+		b.moduleWidth = width / float64(columns)
+	}
+}
+
+// columns returns the number of modules of a row of the barcode, including
+// the quiet zone.
+func (b *Barcode) columns() int {
+	if len(b.modules) == 0 {
+		return 0
+	}
+	return len(b.modules[0]) + 2*b.quietZone
+}
+
+// rowHeight returns the height of a row of modules of matrix barcodes.
+func (b *Barcode) rowHeight() float64 {
+	if b.kind == BarcodePDF417 {
+		// The encoded PDF417 rows span two modules. Use a row height of
+		// three times the module width.
+		return 1.5 * b.moduleWidth
+	}
+	return b.moduleWidth
+}
+
+// codeHeight returns the height of the modules of the barcode, including
+// the quiet zone.
+func (b *Barcode) codeHeight() float64 {
+	if b.kind.isLinear() {
+		return b.barHeight
+	}
+	return float64(len(b.modules))*b.rowHeight() + 2*float64(b.quietZone)*b.moduleWidth
+}
+
+// textHeight returns the height of the human-readable text of the barcode.
+func (b *Barcode) textHeight() float64 {
+	if !b.showText {
+		return 0
+	}
+	return b.textStyle.FontSize + 2
+}
+
+// unrotatedSize returns the size of the barcode, before rotation.
+func (b *Barcode) unrotatedSize() (float64, float64) {
+	return float64(b.columns()) * b.moduleWidth, b.codeHeight() + b.textHeight()
+}
+
+// Width returns the width of the bounding box of the rotated barcode.
+func (b *Barcode) Width() float64 {
+	width, _ := b.block().RotatedSize()
+	return width
+}
+
+// Height returns the height of the bounding box of the rotated barcode.
+func (b *Barcode) Height() float64 {
+	_, height := b.block().RotatedSize()
+	return height
+}
+
+// block returns an empty block having the size of the barcode, before
+// rotation.
+func (b *Barcode) block() *Block {
+	width, height := b.unrotatedSize()
+	block := NewBlock(width, height)
+	block.SetAngle(b.angle)
+	return block
+}
+
+// contents returns the content stream operations which draw the modules of
+// the barcode, in a coordinate system having the origin at the bottom left
+// corner of the unrotated barcode.
+func (b *Barcode) contents() *contentstream.ContentCreator {
+	width, height := b.unrotatedSize()
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if b._bffbg != nil {
+		structType := b._bffbg.StructureType
+		if structType == model.StructureTypeUnknown {
+			structType = b._edggf
+		}
+		cc.Add_BDC(*core.MakeName(string(structType)), map[string]core.PdfObject{
+			"MCID": core.MakeInteger(b._bffbg.Mcid),
+		})
+	}
+
+	if b.background != nil {
+		cc.SetNonStrokingColor(_edaa(b.background))
+		cc.Add_re(0, 0, width, height)
+		cc.Add_f()
+	}
+
+	cc.SetNonStrokingColor(_edaa(b.color))
+	mw := b.moduleWidth
+	offset := float64(b.quietZone) * mw
+	for i, row := range b.modules {
+		y, rowHeight := b.textHeight(), b.barHeight
+		if !b.kind.isLinear() {
+			rowHeight = b.rowHeight()
+			y = height - offset - float64(i+1)*rowHeight
+		}
+
+		// Draw runs of adjacent dark modules as single rectangles.
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			cc.Add_re(offset+float64(start)*mw, y, float64(x-start)*mw, rowHeight)
+		}
+	}
+	cc.Add_f()
+
+	if b._bffbg != nil {
+		cc.Add_EMC()
+	}
+	cc.Add_Q()
+	return cc
+}
+
+// draw draws the unrotated barcode on a new block.
+func (b *Barcode) draw() (*Block, error) {
+	block := b.block()
+	if err := block.addContentsByString(b.contents().String()); err != nil {
+		return nil, err
+	}
+
+	if b.showText {
+		text := b.text
+		if text == "" {
+			text = b.content
+		}
+		width, _ := b.unrotatedSize()
+		p := _dfae(b.textStyle)
+		p.SetEnableWrap(false)
+		p.SetTextAlignment(TextAlignmentCenter)
+		p.SetWidth(width)
+		p.SetPos(0, b.codeHeight()+1)
+		p.Append(text)
+		if err := block.Draw(p); err != nil {
+			return nil, err
+		}
+	}
+	return block, nil
+}
+
+// GeneratePageBlocks draws the barcode on a new block representing the page.
+// Implements the Drawable interface.
+func (b *Barcode) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
+	code, err := b.draw()
+	if err != nil {
+		return nil, ctx, err
+	}
+	return placeBlock(ctx, code, b.margins, b.positioning, b.x, b.y)
+}
+
+// placeBlock draws the specified block, which holds the contents of a
+// component having a fixed size, on a new block representing the page.
+// Relatively positioned components are moved to the next page if they do not
+// fit in the space available on the current page. Absolutely positioned
+// components are drawn at the specified coordinates. Rotated blocks are
+// positioned so that their bounding box starts at the drawing position.
+func placeBlock(ctx DrawContext, content *Block, margins Margins, positioning Positioning, x, y float64) ([]*Block, DrawContext, error) {
+	var blocks []*Block
+	origCtx := ctx
+	width, height := content.RotatedSize()
+
+	isRelative := positioning.IsRelative()
+	if isRelative {
+		ctx.X += margins.Left
+		ctx.Y += margins.Top
+		ctx.Width -= margins.Left + margins.Right
+		ctx.Height -= margins.Top + margins.Bottom
+
+		if height > ctx.Height {
+			blocks = append(blocks, NewBlock(ctx.PageWidth, ctx.PageHeight))
+			ctx.Page++
+			ctx.X = ctx.Margins.Left + margins.Left
+			ctx.Y = ctx.Margins.Top + margins.Top
+			ctx.Width = ctx.PageWidth - ctx.Margins.Left - ctx.Margins.Right - margins.Left - margins.Right
+			ctx.Height = ctx.PageHeight - ctx.Margins.Top - ctx.Margins.Bottom - margins.Top - margins.Bottom
+		}
+	} else {
+		ctx.X = x
+		ctx.Y = y
+	}
+
+	// Blocks are rotated around their center.
+	content.SetPos(ctx.X+(width-content.Width())/2, ctx.Y+(height-content.Height())/2)
+	block := NewBlock(ctx.PageWidth, ctx.PageHeight)
+	if err := block.Draw(content); err != nil {
+		return nil, ctx, err
+	}
+	blocks = append(blocks, block)
+
+	if isRelative {
+		ctx.X = origCtx.X
+		ctx.Width = origCtx.Width
+		ctx.Y += height + margins.Bottom
+		ctx.Height -= height + margins.Bottom
+	} else {
+		ctx = origCtx
+	}
+	return blocks, ctx, nil
+}
+
+// ToXObjectForm returns a form XObject containing the barcode, scaled
+// uniformly to fit the specified size and centered. The form can be used,
+// for example, as the appearance stream of form fields and annotations.
+func (b *Barcode) ToXObjectForm(width, height float64) (*model.XObjectForm, error) {
+	code, err := b.draw()
+	if err != nil {
+		return nil, err
+	}
+
+	codeWidth, codeHeight := code.RotatedSize()
+	if codeWidth <= 0 || codeHeight <= 0 {
+		return nil, errors.New("invalid barcode size")
+	}
+	scale := width / codeWidth
+	if s := height / codeHeight; s < scale {
+		scale = s
+	}
+
+	// Draw the barcode on a block having the size of the form, centered and
+	// scaled to fit.
+	block := NewBlock(codeWidth, codeHeight)
+	code.SetPos((codeWidth-code.Width())/2, (codeHeight-code.Height())/2)
+	if err := block.Draw(code); err != nil {
+		return nil, err
+	}
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	cc.Add_cm(scale, 0, 0, scale, (width-codeWidth*scale)/2, (height-codeHeight*scale)/2)
+	ops := append(*cc.Operations(), *block._fce...)
+	ops = append(ops, &contentstream.ContentStreamOperation{Operand: "Q"})
+
+	form := model.NewXObjectForm()
+	form.FormType = core.MakeInteger(1)
+	form.Resources = block._fcb
+	form.BBox = core.MakeArrayFromFloats([]float64{0, 0, width, height})
+	form.Matrix = core.MakeArrayFromFloats([]float64{1, 0, 0, 1, 0, 0})
+	if err := form.SetContentStream(ops.Bytes(), core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+// SetBarcode sets the barcode of the invoice, drawn after the notes of the
+// invoice (e.g. a payment or tracking code).
+func (i *Invoice) SetBarcode(b *Barcode) {
+	i._dfbeg = b
+}
+
+// Barcode returns the barcode of the invoice, if any.
+func (i *Invoice) Barcode() *Barcode {
+	return i._dfbeg
+}
+
+// generateBarcodeBlocks draws the barcode of the invoice, if any.
+func (i *Invoice) generateBarcodeBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
+	if i._dfbeg == nil {
+		return nil, ctx, nil
+	}
+	return i._dfbeg.GeneratePageBlocks(ctx)
+}
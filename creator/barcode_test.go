@@ -0,0 +1,86 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBarcodeEAN13(t *testing.T) {
+	c := New()
+	c.NewPage()
+	b, err := c.NewBarcode(BarcodeEAN13, "5901234123457")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// 95 modules, with a quiet zone of 10 modules on each side.
+	if w := b.Width(); w != 115 {
+		t.Fatalf("expected width 115, got %.2f", w)
+	}
+	row := b.modules[0]
+	guard := []bool{true, false, true}
+	for i, dark := range guard {
+		if row[i] != dark || row[len(row)-3+i] != dark {
+			t.Fatalf("missing guard bars: %v", row)
+		}
+	}
+
+	if err := c.Draw(b); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if text := creatorPageTexts(t, c)[0]; !strings.Contains(text, "5901234123457") {
+		t.Fatalf("human-readable text not drawn: %q", text)
+	}
+
+	if _, err := NewBarcode(BarcodeEAN13, "590123412345X"); err == nil {
+		t.Fatalf("expected an error for invalid EAN-13 content")
+	}
+}
+
+func TestBarcodeQR(t *testing.T) {
+	c := New()
+	c.NewPage()
+	b, err := c.NewBarcode(BarcodeQR, "https://unidoc.io")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Version 2 symbol, having 25x25 modules, with a quiet zone of 4
+	// modules of 3 points.
+	n := len(b.modules)
+	if n != 25 || len(b.modules[0]) != 25 {
+		t.Fatalf("expected 25x25 modules, got %dx%d", n, len(b.modules[0]))
+	}
+	if w, h := b.Width(), b.Height(); w != 99 || h != 99 {
+		t.Fatalf("expected size 99x99, got %.2fx%.2f", w, h)
+	}
+
+	// Finder patterns at the top left, top right and bottom left corners.
+	for _, corner := range [][2]int{{0, 0}, {0, n - 7}, {n - 7, 0}} {
+		for i := 0; i < 7; i++ {
+			for j := 0; j < 7; j++ {
+				ring := i == 0 || i == 6 || j == 0 || j == 6
+				center := i >= 2 && i <= 4 && j >= 2 && j <= 4
+				if dark := b.modules[corner[0]+i][corner[1]+j]; dark != (ring || center) {
+					t.Fatalf("invalid finder pattern at %v", corner)
+				}
+			}
+		}
+	}
+
+	if err := c.Draw(b); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if text := creatorPageTexts(t, c)[0]; strings.Contains(text, "unidoc") {
+		t.Fatalf("unexpected human-readable text for QR code: %q", text)
+	}
+}
@@ -221,7 +221,7 @@ _bfbcd ,_ffefb ,_bgcg :=_gggbe .GeneratePageBlocks (_eafg );if _bgcg !=nil {retu
 
 // Decode decodes the child elements of element.
 func (_gacb *GraphicSVGElement )Decode (decoder *_ee .Decoder )error {for {_ggbe ,_gabe :=decoder .Token ();if _ggbe ==nil &&_gabe ==_gab .EOF {break ;};if _gabe !=nil {return _gabe ;};switch _egde :=_ggbe .(type ){case _ee .StartElement :_bgab :=_caec (_egde );
-_dgdd :=_bgab .Decode (decoder );if _dgdd !=nil {return _dgdd ;};_gacb .Children =append (_gacb .Children ,_bgab );case _ee .CharData :_fddb :=_ag .TrimSpace (string (_egde ));if _fddb !=""{_gacb .Content =string (_egde );};case _ee .EndElement :if _egde .Name .Local ==_gacb .Name {return nil ;
+_dgdd :=_bgab .Decode (decoder );if _dgdd !=nil {return _dgdd ;};_gacb .Children =append (_gacb .Children ,_bgab );case _ee .CharData :_fddb :=_ag .TrimSpace (string (_egde ));if _fddb !=""{_gacb .Content =string (_egde );};switch _gacb .Name {case "text","tspan","textPath","a":_gacb .Children =append (_gacb .Children ,&GraphicSVGElement {Name :"#text",Content :string (_egde ),Attributes :map[string ]string {}});};case _ee .EndElement :if _egde .Name .Local ==_gacb .Name {return nil ;
 };};};return nil ;};func (_bfdga *Grid )updateRowHeights (_gceb float64 ){for _ ,_agge :=range _bfdga ._bddb {_agge .updateRowHeight (_gceb );};};type rgbColor struct{_bebg ,_cgcb ,_bfc float64 };
 
 // SetFitMode sets the fit mode of the rectangle.
@@ -336,14 +336,14 @@ Margins Margins ;
 PageWidth float64 ;PageHeight float64 ;
 
 // Controls whether the components are stacked horizontally
-Inline bool ;_egbc rune ;_eega []error ;};func (_bbcb rgbColor )ToRGB ()(float64 ,float64 ,float64 ){return _bbcb ._bebg ,_bbcb ._cgcb ,_bbcb ._bfc ;};func _cdaa (_edfb TextStyle )*List {return &List {_cfgf :TextChunk {Text :"\u2022\u0020",Style :_edfb },_cdafd :0,_cefgb :true ,_fggb :PositionRelative ,_gegcbc :_edfb ,taggedDrawable :taggedDrawable {_edggf :_bb .StructureTypeList }};
+Inline bool ;_egbc rune ;_eega []error ;_xrefs *crossReferences ;};func (_bbcb rgbColor )ToRGB ()(float64 ,float64 ,float64 ){return _bbcb ._bebg ,_bbcb ._cgcb ,_bbcb ._bfc ;};func _cdaa (_edfb TextStyle )*List {return &List {_cfgf :TextChunk {Text :"\u2022\u0020",Style :_edfb },_cdafd :0,_cefgb :true ,_fggb :PositionRelative ,_gegcbc :_edfb ,taggedDrawable :taggedDrawable {_edggf :_bb .StructureTypeList }};
 };
 
 // GeneratePageBlocks generates the page blocks. Multiple blocks are generated
 // if the contents wrap over multiple pages. Implements the Drawable interface.
 func (_ccbe *StyledParagraph )GeneratePageBlocks (ctx DrawContext )([]*Block ,DrawContext ,error ){_affbf :=ctx ;var _decg []*Block ;_dbeb :=NewBlock (ctx .PageWidth ,ctx .PageHeight );if _ccbe ._fdbb .IsRelative (){ctx .X +=_ccbe ._ceffe .Left ;ctx .Y +=_ccbe ._ceffe .Top ;
 ctx .Width -=_ccbe ._ceffe .Left +_ccbe ._ceffe .Right ;ctx .Height -=_ccbe ._ceffe .Top ;_ccbe .SetWidth (ctx .Width );}else {if int (_ccbe ._gecdc )<=0{_ccbe .SetWidth (_ccbe .getTextWidth ()/1000.0);};ctx .X =_ccbe ._gggdg ;ctx .Y =_ccbe ._cebe ;};if _ccbe ._gbfcd !=nil {_ccbe ._gbfcd (_ccbe ,ctx );
-};if _cbcg :=_ccbe .wrapText ();_cbcg !=nil {return nil ,ctx ,_cbcg ;};_afbdb :=_ccbe ._aabfc ;_gggfa :=0;for {_ccgfg ,_dfedd ,_fbeb :=_ddfa (_dbeb ,_ccbe ,_afbdb ,ctx );if _fbeb !=nil {_fee .Log .Debug ("\u0045R\u0052\u004f\u0052\u003a\u0020\u0025v",_fbeb );
+};_ccbe .resolveReferences (ctx );if _cbcg :=_ccbe .wrapText ();_cbcg !=nil {return nil ,ctx ,_cbcg ;};if _ccbe ._fdbb .IsRelative (){ctx .Height =_ccbe .lineControlHeight (ctx );};_afbdb :=_ccbe ._aabfc ;_gggfa :=0;for {_ccgfg ,_dfedd ,_fbeb :=_ddfa (_dbeb ,_ccbe ,_afbdb ,ctx );if _fbeb !=nil {_fee .Log .Debug ("\u0045R\u0052\u004f\u0052\u003a\u0020\u0025v",_fbeb );
 return nil ,ctx ,_fbeb ;};ctx =_ccgfg ;_decg =append (_decg ,_dbeb );if _afbdb =_dfedd ;len (_dfedd )==0{break ;};if len (_dfedd )==_gggfa {return nil ,ctx ,_ef .New ("\u006e\u006f\u0074\u0020\u0065\u006e\u006f\u0075\u0067\u0068 \u0073\u0070\u0061\u0063\u0065\u0020\u0066o\u0072\u0020\u0070\u0061\u0072\u0061\u0067\u0072\u0061\u0070\u0068");
 };_ccbe ._daed =nil ;_dbeb =NewBlock (ctx .PageWidth ,ctx .PageHeight );ctx .Page ++;_ccgfg =ctx ;_ccgfg .Y =ctx .Margins .Top ;_ccgfg .X =ctx .Margins .Left +_ccbe ._ceffe .Left ;_ccgfg .Height =ctx .PageHeight -ctx .Margins .Top -ctx .Margins .Bottom ;
 _ccgfg .Width =ctx .PageWidth -ctx .Margins .Left -ctx .Margins .Right -_ccbe ._ceffe .Left -_ccbe ._ceffe .Right ;ctx =_ccgfg ;_gggfa =len (_dfedd );};if _ccbe ._fdbb .IsRelative (){ctx .Y +=_ccbe ._ceffe .Bottom ;ctx .Height -=_ccbe ._ceffe .Bottom ;
@@ -369,7 +369,7 @@ func (_dgbd *Creator )NewLinearGradientColor (colorPoints []*ColorPoint )*Linear
 // also be set externally, using the SetTOC and SetOutlineTree methods.
 // Finalize should only be called once, after all draw calls have taken place,
 // as it will return immediately if the creator instance has been finalized.
-func (_ddbb *Creator )Finalize ()error {if _ddbb ._bffa {return nil ;};_faed :=len (_ddbb ._gcfe );_faab :=0;if _ddbb ._eff !=nil {_debd :=*_ddbb ;_ddbb ._gcfe =nil ;_ddbb ._adbca =nil ;_ddbb .initContext ();_bba :=FrontpageFunctionArgs {PageNum :1,TotalPages :_faed };
+func (_ddbb *Creator )Finalize ()error {if _ddbb ._bffa {return nil ;};_ddbb .endPageFlow ();if _dcec :=_ddbb .drawIndex ();_dcec !=nil {return _dcec ;};_faed :=len (_ddbb ._gcfe );_faab :=0;if _ddbb ._eff !=nil {_debd :=*_ddbb ;_ddbb ._gcfe =nil ;_ddbb ._adbca =nil ;_ddbb .initContext ();_bba :=FrontpageFunctionArgs {PageNum :1,TotalPages :_faed };
 _ddbb ._eff (_bba );_faab +=len (_ddbb ._gcfe );_ddbb ._gcfe =_debd ._gcfe ;_ddbb ._adbca =_debd ._adbca ;};if _ddbb .AddTOC {_ddbb .initContext ();_ddbb ._aada .Page =_faab +1;if _ddbb .CustomTOC &&_ddbb ._ecfd !=nil {_gebd :=*_ddbb ;_ddbb ._gcfe =nil ;
 _ddbb ._adbca =nil ;if _cbgc :=_ddbb ._ecfd (_ddbb ._febc );_cbgc !=nil {return _cbgc ;};_faab +=len (_ddbb ._gcfe );_ddbb ._gcfe =_gebd ._gcfe ;_ddbb ._adbca =_gebd ._adbca ;}else {if _ddbb ._ecfd !=nil {if _ddag :=_ddbb ._ecfd (_ddbb ._febc );_ddag !=nil {return _ddag ;
 };};_gde ,_ ,_cbfd :=_ddbb ._febc .GeneratePageBlocks (_ddbb ._aada );if _cbfd !=nil {_fee .Log .Debug ("\u0046\u0061i\u006c\u0065\u0064\u0020\u0074\u006f\u0020\u0067\u0065\u006e\u0065\u0072\u0061\u0074\u0065\u0020\u0062\u006c\u006f\u0063\u006b\u0073: \u0025\u0076",_cbfd );
@@ -383,10 +383,10 @@ _gfac :=_ddbb ._gcfe [len (_fabc ):];_ddbb ._gcfe =append ([]*_bb .PdfPage {},_f
 _fdbcg =func (_dagc *_bb .OutlineItem ){_dagc .Dest .Page +=int64 (_faab );if _fffe :=int (_dagc .Dest .Page );_fffe >=0&&_fffe < len (_ddbb ._gcfe ){_dagc .Dest .PageObj =_ddbb ._gcfe [_fffe ].GetPageAsIndirectObject ();}else {_fee .Log .Debug ("\u0057\u0041R\u004e\u003a\u0020\u0063\u006f\u0075\u006c\u0064\u0020\u006e\u006f\u0074\u0020\u0067\u0065\u0074\u0020\u0070\u0061\u0067\u0065\u0020\u0063\u006f\u006e\u0074\u0061\u0069\u006e\u0065\u0072\u0020\u0066\u006f\u0072\u0020\u0070\u0061\u0067\u0065\u0020\u0025\u0064",_fffe );
 };_dagc .Dest .Y =_fd .RoundDefault (_ddbb ._eae -_dagc .Dest .Y );_bde :=_dagc .Items ();for _ ,_deac :=range _bde {_fdbcg (_deac );};};_ecgc :=_ddbb ._fag .Items ();for _ ,_adbf :=range _ecgc {_fdbcg (_adbf );};if _ddbb .AddTOC {var _afe int ;if _faca {_afe =len (_fabc );
 };_cda :=_bb .NewOutlineDest (int64 (_afe ),0,_ddbb ._eae );if _afe >=0&&_afe < len (_ddbb ._gcfe ){_cda .PageObj =_ddbb ._gcfe [_afe ].GetPageAsIndirectObject ();}else {_fee .Log .Debug ("\u0057\u0041R\u004e\u003a\u0020\u0063\u006f\u0075\u006c\u0064\u0020\u006e\u006f\u0074\u0020\u0067\u0065\u0074\u0020\u0070\u0061\u0067\u0065\u0020\u0063\u006f\u006e\u0074\u0061\u0069\u006e\u0065\u0072\u0020\u0066\u006f\u0072\u0020\u0070\u0061\u0067\u0065\u0020\u0025\u0064",_afe );
-};_ddbb ._fag .Insert (0,_bb .NewOutlineItem ("\u0054\u0061\u0062\u006c\u0065\u0020\u006f\u0066\u0020\u0043\u006f\u006et\u0065\u006e\u0074\u0073",_cda ));};};for _aeggf ,_ecfce :=range _ddbb ._gcfe {_ddbb .setActivePage (_ecfce );if _ddbb ._faaf !=nil {_ffcae ,_gdfc ,_bdbeb :=_ecfce .Size ();
+};_ddbb ._fag .Insert (0,_bb .NewOutlineItem ("\u0054\u0061\u0062\u006c\u0065\u0020\u006f\u0066\u0020\u0043\u006f\u006et\u0065\u006e\u0074\u0073",_cda ));};};for _aeggf ,_ecfce :=range _ddbb ._gcfe {_ddbb .setActivePage (_ecfce );_ddbb ._xrefs .setPage (_aeggf +1,_faed );if _ddbb ._faaf !=nil {_ffcae ,_gdfc ,_bdbeb :=_ecfce .Size ();
 if _bdbeb !=nil {return _bdbeb ;};_bbcg :=PageFinalizeFunctionArgs {PageNum :_aeggf +1,PageWidth :_ffcae ,PageHeight :_gdfc ,TOCPages :len (_eabdf ),TotalPages :_faed };if _fbge :=_ddbb ._faaf (_bbcg );_fbge !=nil {_fee .Log .Debug ("\u0045\u0052\u0052\u004f\u0052\u003a \u0070\u0061\u0067\u0065\u0020\u0066\u0069\u006e\u0061\u006c\u0069\u007a\u0065 \u0063\u0061\u006c\u006c\u0062\u0061\u0063k\u003a\u0020\u0025\u0076",_fbge );
-return _fbge ;};};if _ddbb ._efbe !=nil {_acad :=NewBlock (_ddbb ._fdbc ,_ddbb ._gfge .Top );_gca :=HeaderFunctionArgs {PageNum :_aeggf +1,TotalPages :_faed };_ddbb ._efbe (_acad ,_gca );_acad .SetPos (0,0);if _gbbef :=_ddbb .Draw (_acad );_gbbef !=nil {_fee .Log .Debug ("\u0045R\u0052\u004f\u0052\u003a \u0064\u0072\u0061\u0077\u0069n\u0067 \u0068e\u0061\u0064\u0065\u0072\u003a\u0020\u0025v",_gbbef );
-return _gbbef ;};};if _ddbb ._eadf !=nil {_bgad :=NewBlock (_ddbb ._fdbc ,_ddbb ._gfge .Bottom );_abca :=FooterFunctionArgs {PageNum :_aeggf +1,TotalPages :_faed };_ddbb ._eadf (_bgad ,_abca );_bgad .SetPos (0,_ddbb ._eae -_bgad ._fca );if _aggcd :=_ddbb .Draw (_bgad );
+return _fbge ;};};if _ddbb ._efbe !=nil {_acad :=NewBlock (_ddbb ._fdbc ,_ddbb ._gfge .Top );_acad ._xrefs =_ddbb ._xrefs ;_gca :=HeaderFunctionArgs {PageNum :_aeggf +1,TotalPages :_faed };_ddbb ._efbe (_acad ,_gca );_acad .SetPos (0,0);if _gbbef :=_ddbb .Draw (_acad );_gbbef !=nil {_fee .Log .Debug ("\u0045R\u0052\u004f\u0052\u003a \u0064\u0072\u0061\u0077\u0069n\u0067 \u0068e\u0061\u0064\u0065\u0072\u003a\u0020\u0025v",_gbbef );
+return _gbbef ;};};if _ddbb ._eadf !=nil {_bgad :=NewBlock (_ddbb ._fdbc ,_ddbb ._gfge .Bottom );_bgad ._xrefs =_ddbb ._xrefs ;_abca :=FooterFunctionArgs {PageNum :_aeggf +1,TotalPages :_faed };_ddbb ._eadf (_bgad ,_abca );_bgad .SetPos (0,_ddbb ._eae -_bgad ._fca );if _aggcd :=_ddbb .Draw (_bgad );
 _aggcd !=nil {_fee .Log .Debug ("\u0045R\u0052\u004f\u0052\u003a \u0064\u0072\u0061\u0077\u0069n\u0067 \u0066o\u006f\u0074\u0065\u0072\u003a\u0020\u0025v",_aggcd );return _aggcd ;};};_cbgg ,_agaf :=_ddbb ._fdba [_ecfce ];if _bgbb ,_gdeb :=_ddbb ._fcbb [_ecfce ];
 _gdeb {if _agaf {_cbgg .transformBlock (_bgbb );};if _fgfc :=_bgbb .drawToPage (_ecfce );_fgfc !=nil {_fee .Log .Debug ("\u0045\u0052\u0052\u004f\u0052\u003a \u0064\u0072\u0061\u0077\u0069\u006e\u0067\u0020\u0070\u0061\u0067\u0065\u0020%\u0064\u0020\u0062\u006c\u006f\u0063\u006bs\u003a\u0020\u0025\u0076",_aeggf +1,_fgfc );
 return _fgfc ;};};if _agaf {if _ddgb :=_cbgg .transformPage (_ecfce );_ddgb !=nil {_fee .Log .Debug ("E\u0052\u0052\u004f\u0052\u003a\u0020c\u006f\u0075\u006c\u0064\u0020\u006eo\u0074\u0020\u0074\u0072\u0061\u006e\u0073f\u006f\u0072\u006d\u0020\u0070\u0061\u0067\u0065\u003a\u0020%\u0076",_ddgb );
@@ -610,9 +610,7 @@ _fffcf .resetColumnWidths ();_fee .Log .Debug ("\u0054a\u0062l\u0065\u003a\u0020
 }else {_fffcf ._gaag [_ebafb ._bfdbc -1]=_fea .Max (_fffcf ._gaag [_ebafb ._bfdbc -1],_gfccd );};_fffcf ._cfgc =append (_fffcf ._cfgc ,_ebafb );};_fffcf .sortCells ();};
 
 // ToContentCreator convert SVG and add elements contentstream then returns `contentstream.ContentCreator`.
-func (_cdeaf *GraphicSVGElement )ToContentCreator (cc *_ed .ContentCreator ,res *_bb .PdfPageResources ,scaleX ,scaleY ,translateX ,translateY float64 )*_ed .ContentCreator {if _cdeaf .Name =="\u0073\u0076\u0067"{_cdeaf .SetScaling (scaleX ,scaleY );cc .Add_cm (1,0,0,1,translateX ,translateY );
-_cdeaf .setDefaultScaling (_cdeaf ._aaag );cc .Add_W ();cc .Add_n ();_cdeaf .processDefs ();for _ ,_bgbc :=range _cdeaf .Children {_bgbc .ViewBox =_cdeaf .ViewBox ;_bgbc ._abgg =_cdeaf ._abgg ;_bgbc ._ceffc =_cdeaf ._ceffc ;_bgbc .toContentStream (cc ,res );
-};return cc ;};return nil ;};func _abfe (_edea _ga .Image )(*Image ,error ){_fedb ,_afgff :=_bb .ImageHandling .NewImageFromGoImage (_edea );if _afgff !=nil {return nil ,_afgff ;};return _afgbd (_fedb );};
+func (_cdeaf *GraphicSVGElement )ToContentCreator (cc *_ed .ContentCreator ,res *_bb .PdfPageResources ,scaleX ,scaleY ,translateX ,translateY float64 )*_ed .ContentCreator {if _cdeaf .Name =="\u0073\u0076\u0067"{cc .Add_cm (1,0,0,1,translateX ,translateY );renderSVGDocument (_cdeaf ,cc ,res );return cc ;};return nil ;};func _abfe (_edea _ga .Image )(*Image ,error ){_fedb ,_afgff :=_bb .ImageHandling .NewImageFromGoImage (_edea );if _afgff !=nil {return nil ,_afgff ;};return _afgbd (_fedb );};
 
 // SetStructPageNumber sets the page object where the structure element for this drawable is located.
 func (_gcec *taggedDrawable )SetStructPageNumber (pageNumber *int64 ){if _gcec ._bffbg ==nil {_gcec ._bffbg =_bb .NewStructureTagInfo ();_gcec ._bffbg .StructureType =_gcec ._edggf ;};_gcec ._bffbg .StructPageNumber =pageNumber ;};func (_ggcaa *Invoice )generateHeaderBlocks (_bdgcd DrawContext )([]*Block ,DrawContext ,error ){_acfe :=_dfae (_ggcaa ._geeg );
@@ -786,7 +784,7 @@ _affc ._eega =append (_affc ._eega ,_fcbfc );_fee .Log .Debug (_fcbfc .Error ())
 _fdcc :=_fcfe ._fcb .SetFontByName (_fdbbc ,_bbeag .ToPdfObject ());if _fdcc !=nil {return _affc ,nil ,_fdcc ;};_dcbf ++;_caegg =false ;};_aacea .SetNonStrokingColor (_edaa (_ecgef .Color )).Add_Tf (_fdbbc ,_ecgef .FontSize ).Add_TJ ([]_fc .PdfObject {_fc .MakeStringFromBytes (_cfac )}...);
 };if len (_acbgd )> 0{_aacea .Add_EMC ();};_egceg :=_fceda [_gcebb ]/1000.0;if _ecgef .Underline {_dbegf :=_ecgef .UnderlineStyle .Color ;if _dbegf ==nil {_dbegf =_caef .Style .Color ;};_abee ,_dgegb ,_cfcdb :=_dbegf .ToRGB ();_dgadf :=_aadgee -_affc .X ;
 _efaga :=_gecbe -_aeedd +_ecgef .TextRise -_ecgef .UnderlineStyle .Offset ;_gecbg =append (_gecbg ,&_ae .BasicLine {X1 :_dgadf ,Y1 :_efaga ,X2 :_dgadf +_egceg ,Y2 :_efaga ,LineWidth :_caef .Style .UnderlineStyle .Thickness ,LineColor :_bb .NewPdfColorDeviceRGB (_abee ,_dgegb ,_cfcdb )});
-};for _efgcf ,_cebef :=range _caef ._dagb {var _fgdbd *_fc .PdfObjectArray ;if len (_caef ._edca )==_efgcf {switch _gacfb :=_cebef .GetContext ().(type ){case *_bb .PdfAnnotationLink :_fgdbd =_fc .MakeArray ();_gacfb .Rect =_fgdbd ;_bfcac ,_edbf :=_gacfb .Dest .(*_fc .PdfObjectArray );
+};_affc ._xrefs .markIndexTerms (_caef ,_affc .Page ,_aadgee ,_affc .PageHeight -_gecbe -_cbefc );for _efgcf ,_cebef :=range _caef ._dagb {var _fgdbd *_fc .PdfObjectArray ;if len (_caef ._edca )==_efgcf {switch _gacfb :=_cebef .GetContext ().(type ){case *_bb .PdfAnnotationLink :_fgdbd =_fc .MakeArray ();_gacfb .Rect =_fgdbd ;_bfcac ,_edbf :=_gacfb .Dest .(*_fc .PdfObjectArray );
 if _edbf &&_bfcac .Len ()==5{_bfaa ,_agfeg :=_bfcac .Get (1).(*_fc .PdfObjectName );if _agfeg &&_bfaa .String ()=="\u0058\u0059\u005a"{_eced ,_eefaa :=_fc .GetNumberAsFloat (_bfcac .Get (3));if _eefaa ==nil {_bfcac .Set (3,_fc .MakeFloat (_affc .PageHeight -_eced ));
 };};};case *_bb .PdfAnnotationHighlight :_fgdbd =_fc .MakeArray ();_gacfb .Rect =_fgdbd ;_bfggf :=_aadgee ;_edeba :=_gecbe +_ecgef .TextRise ;_eadad :=_defcf (&_bb .PdfRectangle {Llx :_bfggf ,Lly :_edeba ,Urx :_bfggf +_egceg ,Ury :_edeba +_cbefc },_gdcec ._ccbc );
 _gacfb .QuadPoints =_fc .MakeArrayFromFloats ([]float64 {_eadad [0].X ,_eadad [0].Y ,_eadad [1].X ,_eadad [1].Y ,_eadad [3].X ,_eadad [3].Y ,_eadad [2].X ,_eadad [2].Y });};_caef ._edca =append (_caef ._edca ,true );};if _fgdbd !=nil {_fadcf :=_ae .NewPoint (_aadgee -_affc .X ,_gecbe +_ecgef .TextRise -_aeedd ).Rotate (_gdcec ._ccbc );
@@ -871,7 +869,7 @@ type GridRow struct{_dcdd []*GridCell ;_ggcad float64 ;_gcfge float64 ;_dfdcf in
 
 // Draw draws the drawable d on the block.
 // Note that the drawable must not wrap, i.e. only return one block. Otherwise an error is returned.
-func (_afa *Block )Draw (d Drawable )error {_gdbg :=DrawContext {};_gdbg .Width =_afa ._fdb ;_gdbg .Height =_afa ._fca ;_gdbg .PageWidth =_afa ._fdb ;_gdbg .PageHeight =_afa ._fca ;_gdbg .X =0;_gdbg .Y =0;_gfc ,_ ,_dbgg :=d .GeneratePageBlocks (_gdbg );
+func (_afa *Block )Draw (d Drawable )error {_gdbg :=DrawContext {_xrefs :_afa ._xrefs };_gdbg .Width =_afa ._fdb ;_gdbg .Height =_afa ._fca ;_gdbg .PageWidth =_afa ._fdb ;_gdbg .PageHeight =_afa ._fca ;_gdbg .X =0;_gdbg .Y =0;_gfc ,_ ,_dbgg :=d .GeneratePageBlocks (_gdbg );
 if _dbgg !=nil {return _dbgg ;};if len (_gfc )!=1{return ErrContentNotFit ;};for _ ,_ggg :=range _gfc {if _bbc :=_afa .mergeBlocks (_ggg );_bbc !=nil {return _bbc ;};};return nil ;};
 
 // SetAddressStyle sets the style properties used to render the content of
@@ -895,9 +893,13 @@ _eefc +=_bfbfg ;_dffad =append (_dffad ,_bfbfg );};};if len (_fdbe )> 0{_eade :=
 // page. Each generated block is assigned to the creator page it will be
 // rendered to. In order to render the generated blocks to the creator pages,
 // call Finalize, Write or WriteToFile.
-func (_afba *Creator )Draw (d Drawable )error {if _afba .getActivePage ()==nil {_afba .NewPage ();};if _afba ._bbb {_afba ._fgb ++;_bcaa :=int64 (len (_afba ._gcfe ));d .SetStructPageNumber (&_bcaa );switch _ceeaf :=d .(type ){case *Table :_ceeaf .AddTag (_afba ._efag );
+// NOTE: drawables having the KeepWithNext pagination property set are laid
+// out immediately. If the beginning of the next drawable does not fit after
+// them, they are moved to the next page, along with the moves of the drawing
+// context done in between.
+func (_afba *Creator )Draw (d Drawable )error {return _afba .drawPageFlow (d )};func (_afba *Creator )draw (d Drawable )error {if _afba .getActivePage ()==nil {_afba .NewPage ();};if _afba ._bbb {_afba ._fgb ++;_bcaa :=int64 (len (_afba ._gcfe ));d .SetStructPageNumber (&_bcaa );switch _ceeaf :=d .(type ){case *Table :_ceeaf .AddTag (_afba ._efag );
 _ceeaf .SetMarkedContentID (_afba ._fgb );case *Grid :_ceeaf .AddTag (_afba ._efag );_ceeaf .SetMarkedContentID (_afba ._fgb );case *List :_ceeaf .AddTag (_afba ._efag );_ceeaf .SetMarkedContentID (_afba ._fgb );case *Division :_ceeaf .AddTag (_afba ._efag );
-_ceeaf .SetMarkedContentID (_afba ._fgb );default:_ceeaf .SetMarkedContentID (_afba ._fgb );_ccee ,_dcc :=_ceeaf .GenerateKDict ();if _dcc !=nil {return _dcc ;};if _ccee !=nil {_afba ._efag .AddKChild (_ccee );};};};_feaca ,_eaf ,_gded :=d .GeneratePageBlocks (_afba ._aada );
+_ceeaf .SetMarkedContentID (_afba ._fgb );case *VectorChart :_ceeaf .AddTag (_afba ._efag );_ceeaf .SetMarkedContentID (_afba ._fgb );_afba ._fgb +=_ceeaf .markedContentCount ()-1;default:_ceeaf .SetMarkedContentID (_afba ._fgb );_ccee ,_dcc :=_ceeaf .GenerateKDict ();if _dcc !=nil {return _dcc ;};if _ccee !=nil {_afba ._efag .AddKChild (_ccee );};};};_feaca ,_eaf ,_gded :=d .GeneratePageBlocks (_afba ._aada );
 if _gded !=nil {return _gded ;};if len (_eaf ._eega )> 0{_afba .Errors =append (_afba .Errors ,_eaf ._eega ...);};for _aaf ,_ebef :=range _feaca {if _aaf > 0{_afba .NewPage ();};_beae :=_afba .getActivePage ();if _efbd ,_gffb :=_afba ._fcbb [_beae ];_gffb {if _bdac :=_efbd .mergeBlocks (_ebef );
 _bdac !=nil {return _bdac ;};if _afg :=_aec (_ebef ._fcb ,_efbd ._fcb );_afg !=nil {return _afg ;};}else {_afba ._fcbb [_beae ]=_ebef ;};};_afba ._aada .X =_eaf .X ;_afba ._aada .Y =_eaf .Y ;_afba ._aada .Height =_fd .RoundDefault (_eaf .PageHeight -_eaf .Y -_eaf .Margins .Bottom );
 return nil ;};
@@ -944,7 +946,7 @@ func (_bcfd *Block )RotatedSize ()(float64 ,float64 ){_ ,_ ,_dbf ,_af :=_cagda (
 );for _ ,_bdgcb :=range _gdeab ._dadab {_dfebb :=[]rune (_bdgcb .Text );if _cbgaf ==nil {_cbgaf =_bdgcb .Style .Font ;};_beec :=_bdgcb ._dagb ;_bddg :=_bdgcb .VerticalAlignment ;if len (_bebda )> 0{if len (_dfebb )==1&&_fe .IsPunct (_dfebb [0])&&_bdgcb .Style .Font ==_cbgaf {_cfff :=[]rune (_bebda [len (_bebda )-1].Text );
 _bebda [len (_bebda )-1].Text =string (append (_cfff ,_dfebb [0]));continue ;}else {_ ,_gged :=_age .Atoi (_bdgcb .Text );if _gged ==nil {_geead :=[]rune (_bebda [len (_bebda )-1].Text );_cfdd :=len (_geead );if _cfdd >=2{_ ,_dbgefe :=_age .Atoi (string (_geead [_cfdd -2]));
 if _dbgefe ==nil &&_fe .IsPunct (_geead [_cfdd -1]){_bebda [len (_bebda )-1].Text =string (append (_geead ,_dfebb ...));continue ;};};};};};_dcab ,_dadaa :=_ecada (_bdgcb .Text );if _dadaa !=nil {_fee .Log .Debug ("\u0045\u0052\u0052O\u0052\u003a\u0020\u0055\u006e\u0061\u0062\u006c\u0065\u0020\u0074\u006f\u0020\u0062\u0072\u0065\u0061\u006b\u0020\u0073\u0074\u0072\u0069\u006e\u0067\u0020\u0074\u006f\u0020w\u006f\u0072\u0064\u0073\u003a\u0020\u0025\u0076",_dadaa );
-_dcab =[]string {_bdgcb .Text };};for _ ,_dddfa :=range _dcab {_ffab :=NewTextChunk (_dddfa ,_bdgcb .Style );_ffab ._dagb =_acfea (_beec );_ffab .VerticalAlignment =_bddg ;_bebda =append (_bebda ,_ffab );};_cbgaf =_bdgcb .Style .Font ;};if len (_bebda )> 0{_gdeab ._dadab =_bebda ;
+_dcab =[]string {_bdgcb .Text };};for _ ,_dddfa :=range _dcab {_ffab :=NewTextChunk (_dddfa ,_bdgcb .Style );_ffab ._dagb =_acfea (_beec );_ffab ._gbda =_bdgcb ._gbda ;_ffab .VerticalAlignment =_bddg ;_bebda =append (_bebda ,_ffab );};_cbgaf =_bdgcb .Style .Font ;};if len (_bebda )> 0{_gdeab ._dadab =_bebda ;
 };};
 
 // Height returns the Block's height.
@@ -985,7 +987,7 @@ return nil ,_gfgf ;};}else {_abdg ,_aedb :=_cacee (_gcae ._gefda ,64);if _aedb !
 func New ()*Creator {const _cdg ="c\u0072\u0065\u0061\u0074\u006f\u0072\u002e\u004e\u0065\u0077";_badc :=&Creator {};_badc ._gcfe =[]*_bb .PdfPage {};_badc ._fcbb =map[*_bb .PdfPage ]*Block {};_badc ._fdba =map[*_bb .PdfPage ]*pageTransformations {};_badc .SetPageSize (PageSizeLetter );
 _eeff :=0.1*_badc ._fdbc ;_badc ._gfge .Left =_eeff ;_badc ._gfge .Right =_eeff ;_badc ._gfge .Top =_eeff ;_badc ._gfge .Bottom =_eeff ;var _affd error ;_badc ._bffd ,_affd =_bb .NewStandard14Font (_bb .HelveticaName );if _affd !=nil {_badc ._bffd =_bb .DefaultFont ();
 };_badc ._ceeag ,_affd =_bb .NewStandard14Font (_bb .HelveticaBoldName );if _affd !=nil {_badc ._bffd =_bb .DefaultFont ();};_badc ._febc =_badc .NewTOC ("\u0054\u0061\u0062\u006c\u0065\u0020\u006f\u0066\u0020\u0043\u006f\u006et\u0065\u006e\u0074\u0073");
-_badc .AddOutlines =true ;_badc ._fag =_bb .NewOutline ();_badc .AutofixPageContentStream =true ;_ca .TrackUse (_cdg );return _badc ;};func _eegbc (_efbfdd string )([]float64 ,error ){_babbf :=-1;var _debdb []float64 ;_aacf :=' ';for _dgdac ,_dbaf :=range _efbfdd {if !_fe .IsNumber (_dbaf )&&_dbaf !='.'&&!(_dbaf =='-'&&_aacf =='e')&&_dbaf !='e'{if _babbf !=-1{_aaceb ,_ggage :=_befc (_efbfdd [_babbf :_dgdac ]);
+_badc .AddOutlines =true ;_badc ._fag =_bb .NewOutline ();_badc ._xrefs =newCrossReferences ();_badc ._egdd =_badc .NewIndex ("\u0049\u006e\u0064\u0065\u0078");_badc .AutofixPageContentStream =true ;_ca .TrackUse (_cdg );return _badc ;};func _eegbc (_efbfdd string )([]float64 ,error ){_babbf :=-1;var _debdb []float64 ;_aacf :=' ';for _dgdac ,_dbaf :=range _efbfdd {if !_fe .IsNumber (_dbaf )&&_dbaf !='.'&&!(_dbaf =='-'&&_aacf =='e')&&_dbaf !='e'{if _babbf !=-1{_aaceb ,_ggage :=_befc (_efbfdd [_babbf :_dgdac ]);
 if _ggage !=nil {return _debdb ,_ggage ;};_debdb =append (_debdb ,_aaceb ...);};if _dbaf =='-'{_babbf =_dgdac ;}else {_babbf =-1;};}else if _babbf ==-1{_babbf =_dgdac ;};_aacf =_dbaf ;};if _babbf !=-1&&_babbf !=len (_efbfdd ){_gcaab ,_dbbgb :=_befc (_efbfdd [_babbf :]);
 if _dbbgb !=nil {return _debdb ,_dbbgb ;};_debdb =append (_debdb ,_gcaab ...);};return _debdb ,nil ;};
 
@@ -1109,7 +1111,7 @@ func (_dbgfd *LinearShading )SetAngle (angle float64 ){_dbgfd ._gfcgc =angle };
 func (_cgg *border )SetColorTop (col Color ){_cgg ._ggd =col };
 
 // MoveTo moves the drawing context to absolute coordinates (x, y).
-func (_dedd *Creator )MoveTo (x ,y float64 ){_dedd ._aada .X =x ;_dedd ._aada .Y =y };
+func (_dedd *Creator )MoveTo (x ,y float64 ){_dedd .movePageFlow (func (_cgfd *DrawContext ){_cgfd .X =x ;_cgfd .Y =y })};
 
 // SetAngle sets the rotation angle of the text.
 func (_cfgfd *Paragraph )SetAngle (angle float64 ){_cfgfd ._abfa =angle };
@@ -1174,7 +1176,7 @@ _bfcgc :=_ag .Fields (_cddac );_dagfc :=make ([]int64 ,0,len (_bfcgc ));for _ ,_
 // - CellHorizontalAlignmentCenter
 // - CellHorizontalAlignmentRight
 func (_afadc *TableCell )SetHorizontalAlignment (halign CellHorizontalAlignment ){_afadc ._dcad =halign ;};func _dagfe (_afdd int )*Grid {_ccaa :=&Grid {_egfa :_afdd ,_fcbea :10.0,_bbfbf :[]float64 {}};_ccaa ._edggf =_bb .StructureTypeTable ;_ccaa .resetColumnWidths ();
-return _ccaa ;};type taggedDrawable struct{_edggf _bb .StructureType ;_bffbg *_bb .StructureTagInfo ;};
+return _ccaa ;};type taggedDrawable struct{pageFlow ;_edggf _bb .StructureType ;_bffbg *_bb .StructureTagInfo ;};
 
 // Width returns Image's document width.
 func (_defb *Image )Width ()float64 {return _defb ._fcggg };
@@ -1220,7 +1222,7 @@ func (_aeeff *TableCell )SetSideBorderStyle (side CellBorderSide ,style CellBord
 case CellBorderSideBottom :_aeeff ._cggde =style ;case CellBorderSideLeft :_aeeff ._bfeg =style ;case CellBorderSideRight :_aeeff ._bcdba =style ;};};func _dgade (_fdfdf *_ee .Decoder )(int ,int ){return _fdfdf .InputPos ()};
 
 // MoveRight moves the drawing context right by relative displacement dx (negative goes left).
-func (_aaea *Creator )MoveRight (dx float64 ){_aaea ._aada .X +=dx };
+func (_aaea *Creator )MoveRight (dx float64 ){_aaea .movePageFlow (func (_cgfd *DrawContext ){_cgfd .X +=dx })};
 
 // AddColorStop add color stop info for rendering gradient color.
 func (_eceb *RadialShading )AddColorStop (color Color ,point float64 ){_eceb ._adfaf .AddColorStop (color ,point );};
@@ -1228,7 +1230,7 @@ func (_eceb *RadialShading )AddColorStop (color Color ,point float64 ){_eceb ._a
 // GetMargins returns the margins of the ellipse: left, right, top, bottom.
 func (_eec *Ellipse )GetMargins ()(float64 ,float64 ,float64 ,float64 ){return _eec ._cebd .Left ,_eec ._cebd .Right ,_eec ._cebd .Top ,_eec ._cebd .Bottom ;};func (_fbc *Creator )initContext (){_fbc ._aada .X =_fd .RoundDefault (_fbc ._gfge .Left );_fbc ._aada .Y =_fd .RoundDefault (_fbc ._gfge .Top );
 _fbc ._aada .Width =_fd .RoundDefault (_fbc ._fdbc -_fbc ._gfge .Right -_fbc ._gfge .Left );_fbc ._aada .Height =_fd .RoundDefault (_fbc ._eae -_fbc ._gfge .Bottom -_fbc ._gfge .Top );_fbc ._aada .PageHeight =_fd .RoundDefault (_fbc ._eae );_fbc ._aada .PageWidth =_fd .RoundDefault (_fbc ._fdbc );
-_fbc ._aada .Margins =_fbc ._gfge ;_fbc ._aada ._egbc =_fbc .UnsupportedCharacterReplacement ;};
+_fbc ._aada .Margins =_fbc ._gfge ;_fbc ._aada ._egbc =_fbc .UnsupportedCharacterReplacement ;_fbc ._aada ._xrefs =_fbc ._xrefs ;};
 
 // SetMargins sets the Block's left, right, top, bottom, margins.
 func (_fae *Block )SetMargins (left ,right ,top ,bottom float64 ){_fae ._da .Left =left ;_fae ._da .Right =right ;_fae ._da .Top =top ;_fae ._da .Bottom =bottom ;};
@@ -1242,7 +1244,7 @@ Errors []error ;
 
 // UnsupportedCharacterReplacement is character that will be used to replace unsupported glyph.
 // The value will be passed to drawing context.
-UnsupportedCharacterReplacement rune ;_gcfe []*_bb .PdfPage ;_fcbb map[*_bb .PdfPage ]*Block ;_fdba map[*_bb .PdfPage ]*pageTransformations ;_adbca *_bb .PdfPage ;_gbdf PageSize ;_aada DrawContext ;_gfge Margins ;_fdbc ,_eae float64 ;_bdbe int ;_eff func (_fgc FrontpageFunctionArgs );
+UnsupportedCharacterReplacement rune ;_ocProperties *_bb .OCProperties ;_gcfe []*_bb .PdfPage ;_fcbb map[*_bb .PdfPage ]*Block ;_fdba map[*_bb .PdfPage ]*pageTransformations ;_adbca *_bb .PdfPage ;_gbdf PageSize ;_aada DrawContext ;_gfge Margins ;_fdbc ,_eae float64 ;_bdbe int ;_eff func (_fgc FrontpageFunctionArgs );
 _ecfd func (_dce *TOC )error ;_efbe func (_dfcb *Block ,_fba HeaderFunctionArgs );_eadf func (_fgf *Block ,_fbbd FooterFunctionArgs );_faaf func (_ccc PageFinalizeFunctionArgs )error ;_aca func (_cdeb *_bb .PdfWriter )error ;_bffa bool ;
 
 // Controls whether a table of contents will be generated.
@@ -1258,7 +1260,7 @@ CustomTOC bool ;_febc *TOC ;
 
 // Controls whether outlines will be generated.
 AddOutlines bool ;_fag *_bb .Outline ;_bab *_bb .PdfOutlineTreeNode ;_acc *_bb .PdfAcroForm ;_ccce _fc .PdfObject ;_cedf _bb .Optimizer ;_dfec []*_bb .PdfFont ;_bffd *_bb .PdfFont ;_ceeag *_bb .PdfFont ;_bbb bool ;_efag *_bb .KDict ;_fgb int64 ;_dfb *_bb .StructTreeRoot ;
-_cbg *_bb .ViewerPreferences ;_ccba string ;
+_cbg *_bb .ViewerPreferences ;_ccba string ;_xrefs *crossReferences ;_flowQueue []flowEntry ;_flowState *pageFlowState ;_flowBreak bool ;_pageImporter *_bb .PageImporter ;_egdd *Index ;_fdeb func (_cfbe *Index )error ;
 
 // AutofixPageContentStream indicates whether the creator should attempt to fix
 // page content streams that have unclosed `q` and `Q` commands.
@@ -1314,7 +1316,7 @@ func (_gadd *Invoice )SetNoteHeadingStyle (style TextStyle ){_gadd ._bcefe =styl
 // be placed anywhere on a Page.  It can even contain a whole Page, and is used in the creator
 // where each Drawable object can output one or more blocks, each representing content for separate pages
 // (typically needed when Page breaks occur).
-type Block struct{_fce *_ed .ContentStreamOperations ;_fcb *_bb .PdfPageResources ;_ab Positioning ;_fff ,_cb float64 ;_fdb float64 ;_fca float64 ;_db float64 ;_da Margins ;_ded []*_bb .PdfAnnotation ;};
+type Block struct{pageFlow ;_xrefs *crossReferences ;_fce *_ed .ContentStreamOperations ;_fcb *_bb .PdfPageResources ;_ab Positioning ;_fff ,_cb float64 ;_fdb float64 ;_fca float64 ;_db float64 ;_da Margins ;_ded []*_bb .PdfAnnotation ;};
 
 // SetCoords sets the upper left corner coordinates of the rectangle.
 func (_gfab *Rectangle )SetCoords (x ,y float64 ){_gfab ._bgae =x ;_gfab ._fbac =y };
@@ -1429,9 +1431,9 @@ func (_cebb *Division )GeneratePageBlocks (ctx DrawContext )([]*Block ,DrawConte
 _edbg int64 ;);if _adbe &&!_cebb ._dadc &&!_cebb ._dede {_fbag :=_cebb .ctxHeight (ctx .Width );if _fbag > ctx .Height -_cebb ._gcbc .Top &&_fbag <=ctx .PageHeight -ctx .Margins .Top -ctx .Margins .Bottom {if _dcf ,ctx ,_gecff =_ebbb ().GeneratePageBlocks (ctx );
 _gecff !=nil {return nil ,ctx ,_gecff ;};_gdce =true ;_cfge =0;};};_gedg :=ctx ;_gfaf :=ctx ;if _adbe {ctx .X +=_cebb ._gcbc .Left ;ctx .Y +=_cfge ;ctx .Width -=_cebb ._gcbc .Left +_cebb ._gcbc .Right ;ctx .Height -=_cfge ;_gfaf =ctx ;ctx .X +=_cebb ._gea .Left ;
 ctx .Y +=_cebb ._gea .Top ;ctx .Width -=_cebb ._gea .Left +_cebb ._gea .Right ;ctx .Height -=_cebb ._gea .Top ;ctx .Margins .Top +=_cebb ._gea .Top ;ctx .Margins .Bottom +=_cebb ._gea .Bottom ;ctx .Margins .Left +=_cebb ._gcbc .Left +_cebb ._gea .Left ;
-ctx .Margins .Right +=_cebb ._gcbc .Right +_cebb ._gea .Right ;};ctx .Inline =_cebb ._dede ;_dacc :=ctx ;_abagd :=ctx ;var _fefc float64 ;for _ ,_bacb :=range _cebb ._fcbee {if ctx .Inline {if (ctx .X -_dacc .X )+_bacb .Width ()<=ctx .Width {ctx .Y =_abagd .Y ;
+ctx .Margins .Right +=_cebb ._gcbc .Right +_cebb ._gea .Right ;};ctx .Inline =_cebb ._dede ;_dacc :=ctx ;_abagd :=ctx ;var _fefc float64 ;_fcfb :=_cebb .pageFlowItems ();for _egdfb ,_bacb :=range _cebb ._fcbee {if ctx .Inline {if (ctx .X -_dacc .X )+_bacb .Width ()<=ctx .Width {ctx .Y =_abagd .Y ;
 ctx .Height =_abagd .Height ;}else {ctx .X =_dacc .X ;ctx .Width =_dacc .Width ;_abagd .Y +=_fefc ;_abagd .Height -=_fefc ;_fefc =0;};};_gbed :=false ;switch _bacb .(type ){case *Paragraph ,*StyledParagraph :_gbed =true ;};_ddde :=_bb .StructureTypeParagraph ;
-if _eebg &&_gbed {_bacb .SetMarkedContentID (_edbg );_bacb .SetStructureType (_ddde );};_dddff ,_gede ,_ggda :=_bacb .GeneratePageBlocks (ctx );if _ggda !=nil {_fee .Log .Debug ("\u0045\u0072\u0072\u006f\u0072\u0020\u0067\u0065\u006e\u0065\u0072\u0061\u0074\u0069\u006eg\u0020p\u0061\u0067\u0065\u0020\u0062\u006c\u006f\u0063\u006b\u0073\u003a\u0020\u0025\u0076",_ggda );
+if _eebg &&_gbed {_bacb .SetMarkedContentID (_edbg );_bacb .SetStructureType (_ddde );};if !ctx .Inline {_dbbef ,_gaadf ,_fcbd :=beginPageFlow (ctx ,_fcfb ,_egdfb );if _fcbd !=nil {return nil ,ctx ,_fcbd ;};if len (_dbbef )> 0{if len (_dcf )==0{_gdce =true ;};if _dcf ,_fcbd =mergePageBlocks (_dcf ,_dbbef );_fcbd !=nil {return nil ,ctx ,_fcbd ;};ctx =_gaadf ;};};_dddff ,_gede ,_ggda :=_bacb .GeneratePageBlocks (ctx );if _ggda !=nil {_fee .Log .Debug ("\u0045\u0072\u0072\u006f\u0072\u0020\u0067\u0065\u006e\u0065\u0072\u0061\u0074\u0069\u006eg\u0020p\u0061\u0067\u0065\u0020\u0062\u006c\u006f\u0063\u006b\u0073\u003a\u0020\u0025\u0076",_ggda );
 return nil ,ctx ,_ggda ;};if _eebg &&_gbed {_befa :=int64 (_gede .Page );_bacb .SetStructPageNumber (&_befa );_bbge ,_cag :=_bacb .GenerateKDict ();if _cag !=nil {return nil ,ctx ,_cag ;};_cebb ._bffbg .ComponentKObj .AddKChild (_bbge );if len (_dddff )> 0{for _ceba ,_bgeag :=range _dddff {if _ceba ==0{_dfcdb (_bgeag ,&_bb .StructureTagInfo {Mcid :_edbg ,StructureType :_ddde });
 };if _ceba ==len (_dddff )-1{_ggbcec (_bgeag );};};};_edbg ++;};if len (_dddff )< 1{continue ;};if len (_dcf )> 0{_dcf [len (_dcf )-1].mergeBlocks (_dddff [0]);_dcf =append (_dcf ,_dddff [1:]...);}else {if _ceae :=_dddff [0]._fce ;_ceae ==nil ||len (*_ceae )==0{_gdce =true ;
 };_dcf =append (_dcf ,_dddff [0:]...);};_dfaaf :=0.0;switch _caaf :=_bacb .(type ){case *Paragraph :_dfaaf =(0.5*_caaf ._beeee *_caaf ._cgead );case *StyledParagraph :_dfaaf =(0.5*_caaf .getTextHeight ());};_gede .Y +=_dfaaf ;_gede .Height -=_dfaaf ;if ctx .Inline {if ctx .Page !=_gede .Page {_dacc .Y =ctx .Margins .Top ;
@@ -1686,7 +1688,7 @@ _cdfad !=nil {return _cdfad ;};return ColorBlack ;};
 func (_egd *border )SetColorRight (col Color ){_egd ._eegf =col };
 
 // MoveX moves the drawing context to absolute position x.
-func (_cecg *Creator )MoveX (x float64 ){_cecg ._aada .X =x };
+func (_cecg *Creator )MoveX (x float64 ){_cecg .movePageFlow (func (_cgfd *DrawContext ){_cgfd .X =x })};
 
 // Polygon represents a polygon shape.
 // Implements the Drawable interface and can be drawn on PDF using the Creator.
@@ -1704,7 +1706,7 @@ func (_gedc *Creator )SetOutlineTree (outlineTree *_bb .PdfOutlineTreeNode ){_ge
 func (_beef *Ellipse )BorderOpacity ()float64 {return _beef ._dada };
 
 // MoveDown moves the drawing context down by relative displacement dy (negative goes up).
-func (_gadb *Creator )MoveDown (dy float64 ){_gadb ._aada .Y +=dy };
+func (_gadb *Creator )MoveDown (dy float64 ){_gadb .movePageFlow (func (_cgfd *DrawContext ){_cgfd .Y +=dy })};
 
 // NewParagraph creates a new text paragraph.
 // Default attributes:
@@ -1730,7 +1732,7 @@ func (_abffc *TableCell )Width (ctx DrawContext )float64 {_feeeg :=float64 (0.0)
 
 // Write output of creator to io.Writer interface.
 func (_eeab *Creator )Write (ws _gab .Writer )error {if _bdgde :=_eeab .Finalize ();_bdgde !=nil {return _bdgde ;};_baea :="";if _dafa ,_bcac :=ws .(*_eg .File );_bcac {_baea =_dafa .Name ();};_cgfc :=_bb .NewPdfWriter ();_cgfc .SetOptimizer (_eeab ._cedf );
-_cgfc .SetFileName (_baea );if _eeab ._acc !=nil {_cea :=_cgfc .SetForms (_eeab ._acc );if _cea !=nil {_fee .Log .Debug ("F\u0061\u0069\u006c\u0075\u0072\u0065\u003a\u0020\u0025\u0076",_cea );return _cea ;};};if _eeab ._bab !=nil {_cgfc .AddOutlineTree (_eeab ._bab );
+_cgfc .SetFileName (_baea );if _eeab ._ocProperties !=nil {if _dgpe :=_cgfc .SetOCProperties (_eeab ._ocProperties .ToPdfObject ());_dgpe !=nil {return _dgpe ;};};if _eeab ._acc !=nil {_cea :=_cgfc .SetForms (_eeab ._acc );if _cea !=nil {_fee .Log .Debug ("F\u0061\u0069\u006c\u0075\u0072\u0065\u003a\u0020\u0025\u0076",_cea );return _cea ;};};if _eeab ._bab !=nil {_cgfc .AddOutlineTree (_eeab ._bab );
 }else if _eeab ._fag !=nil &&_eeab .AddOutlines {_cgfc .AddOutlineTree (&_eeab ._fag .ToPdfOutline ().PdfOutlineTreeNode );};if _eeab ._ccce !=nil {if _caed :=_cgfc .SetPageLabels (_eeab ._ccce );_caed !=nil {_fee .Log .Debug ("\u0045\u0052RO\u0052\u003a\u0020C\u006f\u0075\u006c\u0064 no\u0074 s\u0065\u0074\u0020\u0070\u0061\u0067\u0065 l\u0061\u0062\u0065\u006c\u0073\u003a\u0020%\u0076",_caed );
 return _caed ;};};if _eeab ._dfec !=nil {for _ ,_gaba :=range _eeab ._dfec {_efda :=_gaba .SubsetRegistered ();if _efda !=nil {_fee .Log .Debug ("\u0045\u0052\u0052\u004f\u0052\u003a\u0020\u0043\u006f\u0075\u006c\u0064\u0020\u006e\u006ft\u0020s\u0075\u0062\u0073\u0065\u0074\u0020\u0066\u006f\u006e\u0074\u003a\u0020\u0025\u0076",_efda );
 return _efda ;};};};if _eeab ._bbb &&_eeab ._dfb !=nil {_cgfc .SetCatalogMarkInfo (_fc .MakeDictMap (map[string ]_fc .PdfObject {"\u004d\u0061\u0072\u006b\u0065\u0064":_fc .MakeBool (true )}));};if _eeab ._aca !=nil {_afgb :=_eeab ._aca (&_cgfc );if _afgb !=nil {_fee .Log .Debug ("F\u0061\u0069\u006c\u0075\u0072\u0065\u003a\u0020\u0025\u0076",_afgb );
@@ -1928,7 +1930,7 @@ if _fdfde <=0{_fdfde =3;};var _dccde *_bb .PdfFont ;var _daaa float64 ;if len (_
 };_adfad .Style .FontSize =_bdagf ;_dadag ._fced =true ;_bfdcb :=_dadag ._dadab ;_dadag ._dadab =[]*TextChunk {_adfad };_dadag ._dadab =append (_dadag ._dadab ,_bfdcb ...);};};};_dadag ._aabfc =[][]*TextChunk {};var _ffeae []*TextChunk ;var _ccddd float64 ;
 _eefdcf :=_fe .IsSpace ;if !_ebbc {_eefdcf =func (rune )bool {return false };};_dbeg :=_dgadc (_dadag ._gecdc *1000.0,0.000001);_gffg :=0;_acgb :=0;var _deccd *TextChunk ;if len (_dadag ._dadab )> 0&&_dadag ._daed !=nil {_bdfa :=_dadag ._daed ;if _bdfa .Type !=DropCapsNone &&_dadag ._fced {_deccd =_dadag ._dadab [0];
 _acgb =1;};};for _bcaba :=_acgb ;_bcaba < len (_dadag ._dadab );_bcaba ++{_ffff :=_dadag ._dadab [_bcaba ];_fbdc :=_ffff .Style ;_gaeac :=_ffff ._dagb ;_cegad :=_ffff .VerticalAlignment ;var (_acgdf []rune ;_gdfaa []float64 ;);_gfee :=_fc .IsTextWriteDirectionLTR (_ffff .Text );
-for _ ,_fbedb :=range _ffff .Text {if _fbedb =='\u000A'{if !_ebbc {_acgdf =append (_acgdf ,_fbedb );};_ffeae =append (_ffeae ,&TextChunk {taggedDrawable :_ffff .taggedDrawable ,Text :_ag .TrimRightFunc (string (_acgdf ),_eefdcf ),Style :_fbdc ,_dagb :_acfea (_gaeac ),VerticalAlignment :_cegad ,_cbfa :_ffff ._cbfa ,_efcgc :_ffff ._efcgc ,_cfgad :_ffff ._cfgad });
+for _ ,_fbedb :=range _ffff .Text {if _fbedb =='\u000A'{if !_ebbc {_acgdf =append (_acgdf ,_fbedb );};_ffeae =append (_ffeae ,&TextChunk {taggedDrawable :_ffff .taggedDrawable ,Text :_ag .TrimRightFunc (string (_acgdf ),_eefdcf ),Style :_fbdc ,_dagb :_acfea (_gaeac ),VerticalAlignment :_cegad ,_cbfa :_ffff ._cbfa ,_efcgc :_ffff ._efcgc ,_cfgad :_ffff ._cfgad ,_gbda :_ffff ._gbda });
 if _dfga :=_dadag .addLine (_ffeae );!_dfga {return nil ;};_gffg ++;_ffeae =nil ;_ccddd =0;_acgdf =nil ;_gdfaa =nil ;continue ;};_ddgcg :=_fbedb ==' ';_gffe ,_acdb :=_fbdc .Font .GetRuneMetrics (_fbedb );if _gffe .Wx ==0&&_fbdc .MultiFont !=nil ||_fbdc .MultiFont !=nil &&!_acdb {_gffe ,_acdb =_fbdc .MultiFont .GetRuneMetrics (_fbedb );
 };if !_acdb {_fee .Log .Debug ("\u0052\u0075\u006e\u0065\u0020\u0063\u0068\u0061\u0072\u0020\u006d\u0065\u0074\u0072\u0069c\u0073 \u006e\u006f\u0074\u0020\u0066\u006f\u0075\u006e\u0064\u0021\u0020\u0025\u0076\u000a",_fbedb );return _ef .New ("\u0067\u006c\u0079\u0070\u0068\u0020\u0063\u0068\u0061\u0072\u0020m\u0065\u0074\u0072\u0069\u0063\u0073\u0020\u006d\u0069\u0073s\u0069\u006e\u0067");
 };_feaccd :=_fbdc .FontSize *_gffe .Wx *_fbdc .horizontalScale ();_efef :=_feaccd ;if !_ddgcg {_efef =_feaccd +_fbdc .CharSpacing *1000.0;};_bgcbd :=_dbeg ;if _deccd !=nil {_badgb :=_dadag ._daed ;var _aeefg float64 ;_fafcb :=[]rune (_deccd .Text );for _beegc ,_ggccg :=range _fafcb {_aedf ,_cgag :=_deccd .Style .Font .GetRuneMetrics (_ggccg );
@@ -1936,12 +1938,12 @@ if _cgag {_aeefg +=_deccd .Style .FontSize *_aedf .Wx *_deccd .Style .horizontal
 };case DropCapsInline :_bgcbd =_dbeg -(_aeefg *1000.0)-(_cbdde *1000.0);};};if _ccddd +_feaccd > _bgcbd {_gebbd :=-1;if !_ddgcg {for _eegcd :=len (_acgdf )-1;_eegcd >=0;_eegcd --{if _acgdf [_eegcd ]==' '{_gebbd =_eegcd ;break ;};};};if _dadag ._gbcgd {_bbfff :=len (_ffeae );
 if _bbfff > 0{_ffeae [_bbfff -1].Text =_ag .TrimRightFunc (_ffeae [_bbfff -1].Text ,_eefdcf );_dadag ._aabfc =append (_dadag ._aabfc ,_ffeae );_ffeae =[]*TextChunk {};};_acgdf =append (_acgdf ,_fbedb );_gdfaa =append (_gdfaa ,_efef );if _gebbd >=0{_acgdf =_acgdf [_gebbd +1:];
 _gdfaa =_gdfaa [_gebbd +1:];};_ccddd =0;for _ ,_cdag :=range _gdfaa {_ccddd +=_cdag ;};if _ccddd > _dbeg {_ebgb :=string (_acgdf [:len (_acgdf )-1]);if !_dadag ._fedfc {_ebgb =_fc .FormatWriteDirectionLTR (_ebgb ,_gfee );};if !_ebbc &&_ddgcg {_ebgb +="\u0020";
-};_ffeae =append (_ffeae ,&TextChunk {taggedDrawable :_ffff .taggedDrawable ,Text :_ag .TrimRightFunc (_ebgb ,_eefdcf ),Style :_fbdc ,_dagb :_acfea (_gaeac ),VerticalAlignment :_cegad ,_cbfa :_ffff ._cbfa ,_efcgc :_ffff ._efcgc ,_cfgad :_ffff ._cfgad });
+};_ffeae =append (_ffeae ,&TextChunk {taggedDrawable :_ffff .taggedDrawable ,Text :_ag .TrimRightFunc (_ebgb ,_eefdcf ),Style :_fbdc ,_dagb :_acfea (_gaeac ),VerticalAlignment :_cegad ,_cbfa :_ffff ._cbfa ,_efcgc :_ffff ._efcgc ,_cfgad :_ffff ._cfgad ,_gbda :_ffff ._gbda });
 if _fged :=_dadag .addLine (_ffeae );!_fged {return nil ;};_gffg ++;_ffeae =[]*TextChunk {};_acgdf =[]rune {_fbedb };_gdfaa =[]float64 {_efef };_ccddd =_efef ;};continue ;};_ccbg :=string (_acgdf );if _gebbd >=0{_ccbg =string (_acgdf [0:_gebbd +1]);_acgdf =_acgdf [_gebbd +1:];
 _acgdf =append (_acgdf ,_fbedb );_gdfaa =_gdfaa [_gebbd +1:];_gdfaa =append (_gdfaa ,_efef );_ccddd =0;for _ ,_agedf :=range _gdfaa {_ccddd +=_agedf ;};}else {if _ddgcg {_ccddd =0;_acgdf =[]rune {};_gdfaa =[]float64 {};}else {_ccddd =_efef ;_acgdf =[]rune {_fbedb };
-_gdfaa =[]float64 {_efef };};};if !_dadag ._fedfc {_ccbg =_fc .FormatWriteDirectionLTR (_ccbg ,_gfee );};if !_ebbc &&_ddgcg {_ccbg +="\u0020";};_ffeae =append (_ffeae ,&TextChunk {taggedDrawable :_ffff .taggedDrawable ,Text :_ag .TrimRightFunc (_ccbg ,_eefdcf ),Style :_fbdc ,_dagb :_acfea (_gaeac ),VerticalAlignment :_cegad ,_cbfa :_ffff ._cbfa ,_efcgc :_ffff ._efcgc ,_cfgad :_ffff ._cfgad });
+_gdfaa =[]float64 {_efef };};};if !_dadag ._fedfc {_ccbg =_fc .FormatWriteDirectionLTR (_ccbg ,_gfee );};if !_ebbc &&_ddgcg {_ccbg +="\u0020";};_ffeae =append (_ffeae ,&TextChunk {taggedDrawable :_ffff .taggedDrawable ,Text :_ag .TrimRightFunc (_ccbg ,_eefdcf ),Style :_fbdc ,_dagb :_acfea (_gaeac ),VerticalAlignment :_cegad ,_cbfa :_ffff ._cbfa ,_efcgc :_ffff ._efcgc ,_cfgad :_ffff ._cfgad ,_gbda :_ffff ._gbda });
 if _cdff :=_dadag .addLine (_ffeae );!_cdff {return nil ;};_gffg ++;_ffeae =[]*TextChunk {};}else {_ccddd +=_efef ;_acgdf =append (_acgdf ,_fbedb );_gdfaa =append (_gdfaa ,_efef );};};if len (_acgdf )> 0{_cfgfa :=string (_acgdf );if !_dadag ._fedfc {_cfgfa =_fc .FormatWriteDirectionLTR (_cfgfa ,_gfee );
-};_ffeae =append (_ffeae ,&TextChunk {taggedDrawable :_ffff .taggedDrawable ,Text :_cfgfa ,Style :_fbdc ,_dagb :_acfea (_gaeac ),VerticalAlignment :_cegad ,_cbfa :_ffff ._cbfa ,_efcgc :_ffff ._efcgc ,_cfgad :_ffff ._cfgad });};};if len (_ffeae )> 0{if _eefb :=_dadag .addLine (_ffeae );
+};_ffeae =append (_ffeae ,&TextChunk {taggedDrawable :_ffff .taggedDrawable ,Text :_cfgfa ,Style :_fbdc ,_dagb :_acfea (_gaeac ),VerticalAlignment :_cegad ,_cbfa :_ffff ._cbfa ,_efcgc :_ffff ._efcgc ,_cfgad :_ffff ._cfgad ,_gbda :_ffff ._gbda });};};if len (_ffeae )> 0{if _eefb :=_dadag .addLine (_ffeae );
 !_eefb {return nil ;};_gffg ++;};if _deccd !=nil {if len (_dadag ._aabfc )> 0{_dadag ._aabfc [0]=append ([]*TextChunk {_deccd },_dadag ._aabfc [0]...);}else {_dadag ._aabfc =append (_dadag ._aabfc ,[]*TextChunk {_deccd });};};return nil ;};
 
 // SetColorBottom sets border color for bottom.
@@ -2235,8 +2237,8 @@ func (_aeaa *Invoice )NewCellProps ()InvoiceCellProps {_adfab :=ColorRGBFrom8bit
 // SetSideBorderWidth sets the cell's side border width.
 func (_cfcgf *TableCell )SetSideBorderWidth (side CellBorderSide ,width float64 ){switch side {case CellBorderSideAll :_cfcgf ._gccg =width ;_cfcgf ._gfbbe =width ;_cfcgf ._cadc =width ;_cfcgf ._ggdf =width ;case CellBorderSideTop :_cfcgf ._gccg =width ;
 case CellBorderSideBottom :_cfcgf ._gfbbe =width ;case CellBorderSideLeft :_cfcgf ._cadc =width ;case CellBorderSideRight :_cfcgf ._ggdf =width ;};};func _dag (_cge *_ed .ContentStreamOperations ,_ebd *_bb .PdfPageResources ,_fege *_ed .ContentStreamOperations ,_gee *_bb .PdfPageResources )error {_gba :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};
-_gbf :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};_fec :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};_edef :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};_ce :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};_eeg :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};
-for _ ,_cd :=range *_fege {switch _cd .Operand {case "\u0044\u006f":if len (_cd .Params )==1{if _bcgc ,_eba :=_cd .Params [0].(*_fc .PdfObjectName );_eba {if _ ,_fg :=_gba [*_bcgc ];!_fg {var _aad _fc .PdfObjectName ;_edg ,_ :=_gee .GetXObjectByName (*_bcgc );
+_gbf :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};_fec :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};_edef :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};_ce :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};_eeg :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};_dgp :=map[_fc .PdfObjectName ]_fc .PdfObjectName {};
+for _ ,_cd :=range *_fege {switch _cd .Operand {case "\u0042\u0044\u0043","\u0044\u0050":mergeProperties (_cd ,_dgp ,_ebd ,_gee );case "\u0044\u006f":if len (_cd .Params )==1{if _bcgc ,_eba :=_cd .Params [0].(*_fc .PdfObjectName );_eba {if _ ,_fg :=_gba [*_bcgc ];!_fg {var _aad _fc .PdfObjectName ;_edg ,_ :=_gee .GetXObjectByName (*_bcgc );
 if _edg !=nil {_aad =*_bcgc ;for {_bca ,_ :=_ebd .GetXObjectByName (_aad );if _bca ==nil ||_bca ==_edg {break ;};_aad =*_fc .MakeName (_gbd (_aad .String ()));};};_ebd .SetXObjectByName (_aad ,_edg );_gba [*_bcgc ]=_aad ;};_bce :=_gba [*_bcgc ];_cd .Params [0]=&_bce ;
 };};case "\u0054\u0066":if len (_cd .Params )==2{if _bccd ,_dbc :=_cd .Params [0].(*_fc .PdfObjectName );_dbc {if _ ,_ffg :=_gbf [*_bccd ];!_ffg {_gff ,_dde :=_gee .GetFontByName (*_bccd );_cgaf :=*_bccd ;if _dde &&_gff !=nil {_cgaf =_gaf (_bccd .String (),_gff ,_ebd );
 };_ebd .SetFontByName (_cgaf ,_gff );_gbf [*_bccd ]=_cgaf ;};_ad :=_gbf [*_bccd ];_cd .Params [0]=&_ad ;};};case "\u0043\u0053","\u0063\u0073":if len (_cd .Params )==1{if _add ,_dec :=_cd .Params [0].(*_fc .PdfObjectName );_dec {if _ ,_cbe :=_fec [*_add ];
@@ -2475,13 +2477,13 @@ func (_fcea *Chapter )SetIncludeInTOC (includeInTOC bool ){_fcea ._fdaa =include
 
 // GeneratePageBlocks generate the Page blocks.  Multiple blocks are generated if the contents wrap
 // over multiple pages.
-func (_ffdfc *Chapter )GeneratePageBlocks (ctx DrawContext )([]*Block ,DrawContext ,error ){_gcb :=ctx ;_dea :=_ffdfc ._bffbg !=nil &&_ffdfc ._bffbg .ApplyTag ;var _fdgg int64 ;if _ffdfc ._dab .IsRelative (){ctx .X +=_ffdfc ._egcf .Left ;ctx .Y +=_ffdfc ._egcf .Top ;
-ctx .Width -=_ffdfc ._egcf .Left +_ffdfc ._egcf .Right ;ctx .Height -=_ffdfc ._egcf .Top ;};if _dea {_ffdfc ._cdc .SetMarkedContentID (_fdgg );_ffdfc ._cdc .SetStructureType (_bb .StructureTypeHeader );};_efgb ,_edec ,_gabg :=_ffdfc ._cdc .GeneratePageBlocks (ctx );
+func (_ffdfc *Chapter )GeneratePageBlocks (ctx DrawContext )([]*Block ,DrawContext ,error ){defer ctx ._xrefs .enterChapter (_ffdfc )();_gcb :=ctx ;_dea :=_ffdfc ._bffbg !=nil &&_ffdfc ._bffbg .ApplyTag ;var _fdgg int64 ;if _ffdfc ._dab .IsRelative (){ctx .X +=_ffdfc ._egcf .Left ;ctx .Y +=_ffdfc ._egcf .Top ;
+ctx .Width -=_ffdfc ._egcf .Left +_ffdfc ._egcf .Right ;ctx .Height -=_ffdfc ._egcf .Top ;};if _dea {_ffdfc ._cdc .SetMarkedContentID (_fdgg );_ffdfc ._cdc .SetStructureType (_bb .StructureTypeHeader );};_bbcfd :=_ffdfc .pageFlowItems ();_cbbfd ,ctx ,_gabg :=beginPageFlow (ctx ,_bbcfd ,0);if _gabg !=nil {return nil ,ctx ,_gabg ;};_efgb ,_edec ,_gabg :=_ffdfc ._cdc .GeneratePageBlocks (ctx );
 if _gabg !=nil {return _efgb ,ctx ,_gabg ;};if _dea {_affb :=int64 (_edec .Page );_ffdfc ._cdc .SetStructPageNumber (&_affb );_cec ,_feagg :=_ffdfc ._cdc .GenerateKDict ();if _feagg !=nil {return nil ,ctx ,_feagg ;};_ffdfc ._bffbg .ComponentKObj .AddKChild (_cec );
-if len (_efgb )> 0{for _fafg ,_bad :=range _efgb {if _fafg ==0{_dfcdb (_bad ,&_bb .StructureTagInfo {Mcid :_fdgg ,StructureType :_bb .StructureTypeHeader });};if _fafg ==len (_efgb )-1{_ggbcec (_bad );};};};_fdgg ++;};ctx =_edec ;_gggf :=ctx .X ;_bgd :=ctx .Y -_ffdfc ._cdc .Height ();
+if len (_efgb )> 0{for _fafg ,_bad :=range _efgb {if _fafg ==0{_dfcdb (_bad ,&_bb .StructureTagInfo {Mcid :_fdgg ,StructureType :_bb .StructureTypeHeader });};if _fafg ==len (_efgb )-1{_ggbcec (_bad );};};};_fdgg ++;};if len (_cbbfd )> 0{if _efgb ,_gabg =mergePageBlocks (_cbbfd ,_efgb );_gabg !=nil {return nil ,ctx ,_gabg ;};};ctx =_edec ;_gggf :=ctx .X ;_bgd :=ctx .Y -_ffdfc ._cdc .Height ();
 _cced :=int64 (ctx .Page );_gggb :=_ffdfc .headingNumber ();_def :=_ffdfc .headingText ();if _ffdfc ._fdaa {_ege :=_ffdfc ._bbdcc .Add (_gggb ,_ffdfc ._gecf ,_age .FormatInt (_cced ,10),_ffdfc ._gdgb );if _ffdfc ._bbdcc ._ecgg {_ege .SetLink (_cced ,_gggf ,_bgd );
 };};if _ffdfc ._acfd ==nil {_ffdfc ._acfd =_bb .NewOutlineItem (_def ,_bb .NewOutlineDest (_cced -1,_gggf ,_bgd ));if _ffdfc ._gebf !=nil {_ffdfc ._gebf ._acfd .Add (_ffdfc ._acfd );}else {_ffdfc ._ggcg .Add (_ffdfc ._acfd );};}else {_bggc :=&_ffdfc ._acfd .Dest ;
-_bggc .Page =_cced -1;_bggc .X =_gggf ;_bggc .Y =_bgd ;};for _ ,_edeg :=range _ffdfc ._bdfgc {_gaga :=false ;var _deaf _bb .StructureType ;if _dea {switch _eef :=_edeg .(type ){case *Paragraph ,*StyledParagraph :_gaga =true ;_deaf =_bb .StructureTypeParagraph ;
+_bggc .Page =_cced -1;_bggc .X =_gggf ;_bggc .Y =_bgd ;};for _gfbbe ,_edeg :=range _ffdfc ._bdfgc {_abcfe ,_dfbcf ,_cffdf :=beginPageFlow (ctx ,_bbcfd ,_gfbbe +1);if _cffdf !=nil {return _efgb ,ctx ,_cffdf ;};if len (_abcfe )> 0{if _efgb ,_cffdf =mergePageBlocks (_efgb ,_abcfe );_cffdf !=nil {return nil ,ctx ,_cffdf ;};ctx =_dfbcf ;};_gaga :=false ;var _deaf _bb .StructureType ;if _dea {switch _eef :=_edeg .(type ){case *Paragraph ,*StyledParagraph :_gaga =true ;_deaf =_bb .StructureTypeParagraph ;
 _edeg .SetMarkedContentID (_fdgg );_edeg .SetStructureType (_deaf );case *Division :_gaga =true ;_deaf =_bb .StructureTypeDivision ;_eef .AddTag (_ffdfc ._bffbg .ComponentKObj );case *Table :_gaga =true ;_deaf =_bb .StructureTypeTable ;_eef .AddTag (_ffdfc ._bffbg .ComponentKObj );
 case *List :_gaga =true ;_deaf =_bb .StructureTypeList ;_eef .AddTag (_ffdfc ._bffbg .ComponentKObj );case *Chapter :_gaga =true ;_deaf =_bb .StructureTypeSection ;_eef .AddTag (_ffdfc ._bffbg .ComponentKObj );};};_gce ,_bgge ,_eegb :=_edeg .GeneratePageBlocks (ctx );
 if _eegb !=nil {return _efgb ,ctx ,_eegb ;};if _dea &&_gaga {_fcaf :=int64 (_bgge .Page );_edeg .SetStructPageNumber (&_fcaf );if _deaf ==_bb .StructureTypeParagraph {_cabe ,_fab :=_edeg .GenerateKDict ();if _fab !=nil {return nil ,ctx ,_fab ;};_ffdfc ._bffbg .ComponentKObj .AddKChild (_cabe );
@@ -2589,7 +2591,7 @@ func (_baae *Margins )Vertical ()float64 {return _baae .Bottom +_baae .Top };
 func (_badb *GridRow )SetHeight (h float64 ){_badb ._ggcad =h };
 
 // MoveY moves the drawing context to absolute position y.
-func (_ebe *Creator )MoveY (y float64 ){_ebe ._aada .Y =y };
+func (_ebe *Creator )MoveY (y float64 ){_ebe .movePageFlow (func (_cgfd *DrawContext ){_cgfd .Y =y })};
 
 // SetLanguage sets the language identifier that will be stored inside document catalog.
 func (_fgbb *Creator )SetLanguage (language string ){_fgbb ._ccba =language };
@@ -2735,7 +2737,7 @@ return _eebe ;};func (_dca *Block )duplicate ()*Block {_edf :=&Block {};*_edf =*
 
 // GeneratePageBlocks generate the Page blocks. Multiple blocks are generated
 // if the contents wrap over multiple pages.
-func (_afbc *Invoice )GeneratePageBlocks (ctx DrawContext )([]*Block ,DrawContext ,error ){_ebcc :=ctx ;_gaca :=[]func (_cfceg DrawContext )([]*Block ,DrawContext ,error ){_afbc .generateHeaderBlocks ,_afbc .generateInformationBlocks ,_afbc .generateLineBlocks ,_afbc .generateTotalBlocks ,_afbc .generateNoteBlocks };
+func (_afbc *Invoice )GeneratePageBlocks (ctx DrawContext )([]*Block ,DrawContext ,error ){_ebcc :=ctx ;_gaca :=[]func (_cfceg DrawContext )([]*Block ,DrawContext ,error ){_afbc .generateHeaderBlocks ,_afbc .generateInformationBlocks ,_afbc .generateLineBlocks ,_afbc .generateTotalBlocks ,_afbc .generateNoteBlocks ,_afbc .generateBarcodeBlocks };
 var _bedbb []*Block ;for _ ,_adca :=range _gaca {_cacc ,_affaa ,_egbg :=_adca (ctx );if _egbg !=nil {return _bedbb ,ctx ,_egbg ;};if len (_bedbb )==0{_bedbb =_cacc ;}else if len (_cacc )> 0{_bedbb [len (_bedbb )-1].mergeBlocks (_cacc [0]);_bedbb =append (_bedbb ,_cacc [1:]...);
 };ctx =_affaa ;};if _afbc ._ebac .IsRelative (){ctx .X =_ebcc .X ;};if _afbc ._ebac .IsAbsolute (){return _bedbb ,_ebcc ,nil ;};return _bedbb ,ctx ,nil ;};
 
@@ -2754,7 +2756,7 @@ func (_abff *Creator )NewCurve (x1 ,y1 ,cx ,cy ,x2 ,y2 float64 )*Curve {return _
 // Invoice represents a configurable invoice template.
 type Invoice struct{_cgbf string ;_dcgcf *Image ;_becba *InvoiceAddress ;_baaef *InvoiceAddress ;_adfa string ;_dbdfb [2]*InvoiceCell ;_cdeba [2]*InvoiceCell ;_adfb [2]*InvoiceCell ;_cbefd [][2]*InvoiceCell ;_ffbgf []*InvoiceCell ;_fbec [][]*InvoiceCell ;
 _decfc [2]*InvoiceCell ;_ddagb [2]*InvoiceCell ;_geac [][2]*InvoiceCell ;_gabf [2]string ;_fcdda [2]string ;_bbee [][2]string ;_ggbb TextStyle ;_ccedf TextStyle ;_geeg TextStyle ;_cafd TextStyle ;_eeca TextStyle ;_gfbf TextStyle ;_bcefe TextStyle ;_gdec InvoiceCellProps ;
-_cbff InvoiceCellProps ;_beag InvoiceCellProps ;_dccd InvoiceCellProps ;_ebac Positioning ;};
+_cbff InvoiceCellProps ;_beag InvoiceCellProps ;_dccd InvoiceCellProps ;_ebac Positioning ;_dfbeg *Barcode ;};
 
 // SetLevel sets the indentation level of the TOC line.
 func (_eefbb *TOCLine )SetLevel (level uint ){_eefbb ._gced =level ;_eefbb ._gggbb ._ceffe .Left =_eefbb ._fefcb +float64 (_eefbb ._gced -1)*_eefbb ._egccc ;};func (_acdac *GraphicSVGElement )drawPath (_gaedgd *_ed .ContentCreator ,_gcfgc *_bb .PdfPageResources ){_gaedgd .Add_q ();
@@ -3055,7 +3057,7 @@ func (_cgfe *Line )Style ()_ae .LineStyle {return _cgfe ._gdeg };func (_cggfcc *
 func (_dfge *GraphicSVG )GeneratePageBlocks (ctx DrawContext )([]*Block ,DrawContext ,error ){_gbbf :=ctx ;_baec :=_dfge ._gada .IsRelative ();var _cbfb []*Block ;if _baec {_eceg :=1.0;_cfcf :=_dfge ._ecbf .Top ;if _dfge ._gdbd .Height > ctx .Height -_dfge ._ecbf .Top {_cbfb =[]*Block {NewBlock (ctx .PageWidth ,ctx .PageHeight -ctx .Y )};
 var _ddac error ;if _ ,ctx ,_ddac =_ebbb ().GeneratePageBlocks (ctx );_ddac !=nil {return nil ,ctx ,_ddac ;};_cfcf =0;};ctx .X +=_dfge ._ecbf .Left +_eceg ;ctx .Y +=_cfcf ;ctx .Width -=_dfge ._ecbf .Left +_dfge ._ecbf .Right +2*_eceg ;ctx .Height -=_cfcf ;
 }else {ctx .X =_dfge ._gadfb ;ctx .Y =_dfge ._ebafa ;};_dfcd :=_ed .NewContentCreator ();_dfcd .Translate (0,ctx .PageHeight );_dfcd .Scale (1,-1);_dfcd .Translate (ctx .X ,ctx .Y );_fada :=_dfge ._gdbd .Width /_dfge ._gdbd .ViewBox .W ;_cede :=_dfge ._gdbd .Height /_dfge ._gdbd .ViewBox .H ;
-_gagg :=0.0;_dceda :=0.0;if _baec {_gagg =_dfge ._gadfb -(_dfge ._gdbd .ViewBox .X *_fea .Max (_fada ,_cede ));_dceda =_dfge ._ebafa -(_dfge ._gdbd .ViewBox .Y *_fea .Max (_fada ,_cede ));};_ccceg :=NewBlock (ctx .PageWidth ,ctx .PageHeight );if _dfge ._bffbg !=nil {_dfcd .Add_BDC (*_fc .MakeName (string (_dfge ._bffbg .StructureType )),map[string ]_fc .PdfObject {"\u004d\u0043\u0049\u0044":_fc .MakeInteger (_dfge ._bffbg .Mcid )});
+_gagg :=0.0;_dceda :=0.0;_ccceg :=NewBlock (ctx .PageWidth ,ctx .PageHeight );if _dfge ._bffbg !=nil {_dfcd .Add_BDC (*_fc .MakeName (string (_dfge ._bffbg .StructureType )),map[string ]_fc .PdfObject {"\u004d\u0043\u0049\u0044":_fc .MakeInteger (_dfge ._bffbg .Mcid )});
 };_dfge ._gdbd .SetPos (ctx .X ,ctx .Y );_dfge ._gdbd .ToContentCreator (_dfcd ,_ccceg ._fcb ,_fada ,_cede ,_gagg ,_dceda );if _dfge ._bffbg !=nil {_dfcd .Add_EMC ();};_dgbf :=_dfcd .Operations ();_dgbf .WrapIfNeeded ();_ccceg .addWrappedContents (_dgbf );
 if _baec {_becb :=_dfge .Height ()+_dfge ._ecbf .Bottom ;ctx .Y +=_becb ;ctx .Height -=_becb ;}else {ctx =_gbbf ;};_cbfb =append (_cbfb ,_ccceg );return _cbfb ,ctx ,nil ;};
 
@@ -3639,7 +3641,7 @@ Style *GraphicSVGStyle ;
 Width float64 ;
 
 // Height of element.
-Height float64 ;_aaag float64 ;_abgg map[string ]*LinearShading ;_ceffc map[string ]*RadialShading ;_becf float64 ;_egf float64 ;};type pageTransformations struct{_gbfc *_de .Matrix ;_dfgd bool ;_dad bool ;};
+Height float64 ;_aaag float64 ;_abgg map[string ]*LinearShading ;_ceffc map[string ]*RadialShading ;_becf float64 ;_egf float64 ;_fonts map[string ]*_bb .PdfFont ;};type pageTransformations struct{_gbfc *_de .Matrix ;_dfgd bool ;_dad bool ;};
 
 // SetPageLabels adds the specified page labels to the PDF file generated
 // by the creator. See section 12.4.2 "Page Labels" (p. 382 PDF32000_2008).
@@ -3754,7 +3756,7 @@ Text string ;
 Style TextStyle ;_dagb []*_bb .PdfAnnotation ;_edca []bool ;
 
 // The vertical alignment of the text chunk.
-VerticalAlignment TextVerticalAlignment ;_cbfa *string ;_efcgc *string ;_cfgad *string ;};
+VerticalAlignment TextVerticalAlignment ;_cbfa *string ;_efcgc *string ;_cfgad *string ;_ref *textReference ;_gbda []*indexMark ;};
 
 // SetMargins sets the margins of the graphic svg component.
 func (_dcfa *GraphicSVG )SetMargins (left ,right ,top ,bottom float64 ){_dcfa ._ecbf .Left =left ;_dcfa ._ecbf .Right =right ;_dcfa ._ecbf .Top =top ;_dcfa ._ecbf .Bottom =bottom ;};
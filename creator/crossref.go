@@ -0,0 +1,519 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// ReferenceType represents the type of value a cross-reference resolves to.
+type ReferenceType int
+
+// Supported reference types.
+const (
+	// ReferencePageNumber resolves to the number of the page the anchor
+	// target is drawn on.
+	ReferencePageNumber ReferenceType = iota
+
+	// ReferenceLabel resolves to the label of the anchor target
+	// (e.g. "Figure 3"). The label of a chapter defaults to its title.
+	ReferenceLabel
+
+	// ReferenceChapterNumber resolves to the number of the chapter the
+	// anchor target is part of (e.g. "2.1").
+	ReferenceChapterNumber
+
+	// ReferenceCurrentPage resolves to the number of the page the text
+	// chunk is drawn on. Can be used in page headers and footers.
+	ReferenceCurrentPage
+
+	// ReferencePageCount resolves to the total number of pages of the
+	// document. Can be used in page headers and footers.
+	ReferencePageCount
+)
+
+// maxLayoutPasses is the maximum number of times the layout function
+// provided to Creator.Layout is called in order to resolve cross-references.
+const maxLayoutPasses = 3
+
+// referencePlaceholder is the text of the cross-references which cannot be
+// resolved (yet).
+const referencePlaceholder = "??"
+
+// textReference represents the cross-reference of a text chunk.
+type textReference struct {
+	kind   ReferenceType
+	anchor string
+	link   bool
+}
+
+// anchorTarget holds the location of a named anchor, registered during layout.
+type anchorTarget struct {
+	page    int
+	x, y    float64
+	label   string
+	chapter string
+}
+
+// resolvedReference records the text a cross-reference resolved to.
+type resolvedReference struct {
+	ref  *textReference
+	text string
+	page int
+}
+
+// crossReferences keeps track of the anchors registered by the page flow of
+// the creator and resolves the cross-references of text chunks. It also
+// records the locations of the index terms marked on the drawn text chunks.
+type crossReferences struct {
+	anchors  map[string]*anchorTarget
+	previous map[string]*anchorTarget
+	resolved []resolvedReference
+	chapters []*Chapter
+
+	// Number of the pages inserted before the laid out content (front page
+	// and table of contents), and total number of pages, as determined at
+	// the end of the previous layout pass.
+	pageOffset int
+	pageCount  int
+
+	// Locations of the index terms marked on the drawn text chunks.
+	indexTerms []indexOccurrence
+
+	// Number of the page being finalized. Set while drawing the headers and
+	// footers of the pages.
+	page int
+}
+
+// newCrossReferences returns a new cross-reference registry.
+func newCrossReferences() *crossReferences {
+	return &crossReferences{
+		anchors:  map[string]*anchorTarget{},
+		previous: map[string]*anchorTarget{},
+	}
+}
+
+// register records the location of the anchor of the specified drawable,
+// if it has one.
+func (xr *crossReferences) register(ctx DrawContext, d Drawable) {
+	if xr == nil {
+		return
+	}
+	pf, ok := d.(interface{ Anchor() (string, string) })
+	if !ok {
+		return
+	}
+	name, label := pf.Anchor()
+	if name == "" {
+		return
+	}
+
+	target := &anchorTarget{page: ctx.Page, x: ctx.X, y: ctx.Y, label: label}
+	if chap, ok := d.(*Chapter); ok {
+		target.chapter = chap.referenceNumber()
+		if target.label == "" {
+			target.label = chap._gecf
+		}
+	} else if n := len(xr.chapters); n > 0 {
+		target.chapter = xr.chapters[n-1].referenceNumber()
+	}
+	xr.anchors[name] = target
+}
+
+// enterChapter marks the beginning of the layout of the specified chapter.
+// The returned function marks the end of the layout of the chapter.
+func (xr *crossReferences) enterChapter(chap *Chapter) func() {
+	if xr == nil {
+		return func() {}
+	}
+	xr.chapters = append(xr.chapters, chap)
+	return func() {
+		xr.chapters = xr.chapters[:len(xr.chapters)-1]
+	}
+}
+
+// setPage sets the number of the page being finalized and the total number
+// of pages of the document.
+func (xr *crossReferences) setPage(page, pageCount int) {
+	if xr == nil {
+		return
+	}
+	xr.page = page
+	xr.pageCount = pageCount
+}
+
+// target returns the location of the specified anchor. Anchors registered in
+// the current layout pass take precedence over the ones registered in the
+// previous pass.
+func (xr *crossReferences) target(anchor string) *anchorTarget {
+	if target, ok := xr.anchors[anchor]; ok {
+		return target
+	}
+	return xr.previous[anchor]
+}
+
+// lookup returns the text the specified reference resolves to, along with
+// its anchor target, if any.
+func (xr *crossReferences) lookup(ref *textReference, page int) (string, *anchorTarget) {
+	switch ref.kind {
+	case ReferenceCurrentPage:
+		if xr.page > 0 {
+			return strconv.Itoa(xr.page), nil
+		}
+		return strconv.Itoa(page + xr.pageOffset), nil
+	case ReferencePageCount:
+		if xr.pageCount > 0 {
+			return strconv.Itoa(xr.pageCount), nil
+		}
+		return referencePlaceholder, nil
+	}
+
+	target := xr.target(ref.anchor)
+	if target == nil {
+		return referencePlaceholder, nil
+	}
+	switch ref.kind {
+	case ReferencePageNumber:
+		return strconv.Itoa(target.page + xr.pageOffset), target
+	case ReferenceLabel:
+		return target.label, target
+	case ReferenceChapterNumber:
+		return target.chapter, target
+	}
+	return referencePlaceholder, target
+}
+
+// resolve updates the text of the specified chunk with the value its
+// reference resolves to. If the reference is a link, an internal link
+// annotation pointing to the anchor target is added to the chunk.
+func (xr *crossReferences) resolve(chunk *TextChunk, ctx DrawContext) {
+	ref := chunk._ref
+	text, target := xr.lookup(ref, ctx.Page)
+	chunk.Text = text
+
+	if ref.link && target != nil {
+		page := int64(target.page - 1 + xr.pageOffset)
+		chunk.SetAnnotation(_bcccc(page, target.x, target.y, 0, ""))
+		chunk._edca = []bool{}
+	}
+	if xr.page == 0 {
+		xr.resolved = append(xr.resolved, resolvedReference{ref: ref, text: text, page: ctx.Page})
+	}
+}
+
+// endPass concludes a layout pass, using the specified page offset and total
+// page count for the next pass. Returns true if any of the references
+// resolved during the pass has a different value at the end of the pass,
+// in which case another layout pass is required.
+func (xr *crossReferences) endPass(pageOffset, pageCount int) bool {
+	xr.pageOffset = pageOffset
+	xr.pageCount = pageCount
+
+	stale := false
+	for _, r := range xr.resolved {
+		if text, _ := xr.lookup(r.ref, r.page); text != r.text {
+			stale = true
+			break
+		}
+	}
+
+	xr.previous = xr.anchors
+	xr.anchors = map[string]*anchorTarget{}
+	xr.resolved = nil
+	return stale
+}
+
+// resolveReferences resolves the cross-references of the chunks of the
+// paragraph.
+func (p *StyledParagraph) resolveReferences(ctx DrawContext) {
+	if ctx._xrefs == nil {
+		return
+	}
+	for _, chunk := range p._dadab {
+		if chunk._ref != nil {
+			ctx._xrefs.resolve(chunk, ctx)
+		}
+	}
+}
+
+// referenceNumber returns the number of the chapter, including the numbers
+// of its parent chapters (e.g. "2.1").
+func (chap *Chapter) referenceNumber() string {
+	number := strconv.Itoa(chap._eabd)
+	if chap._gebf != nil {
+		number = chap._gebf.referenceNumber() + "." + number
+	}
+	return strings.TrimPrefix(number, "0.")
+}
+
+// SetAnchor sets the name of the anchor of the component, which can be used
+// as the target of the cross-references of text chunks. The label is the
+// text references of type ReferenceLabel resolve to (e.g. "Figure 3").
+// NOTE: anchors are registered by the page flow of the creator and of the
+// chapter and division components.
+func (pf *pageFlow) SetAnchor(name, label string) {
+	pf.anchor = name
+	pf.anchorLabel = label
+}
+
+// Anchor returns the name and the label of the anchor of the component.
+func (pf *pageFlow) Anchor() (string, string) {
+	return pf.anchor, pf.anchorLabel
+}
+
+// NewReferenceChunk returns a new text chunk which acts as a placeholder for
+// the value of a cross-reference. The text of the chunk is resolved at layout
+// time, to the value of the specified type, for the specified anchor. The
+// anchor is not used for references of type ReferenceCurrentPage and
+// ReferencePageCount.
+func NewReferenceChunk(kind ReferenceType, anchor string, style TextStyle) *TextChunk {
+	chunk := NewTextChunk(referencePlaceholder, style)
+	chunk.SetReference(kind, anchor, kind <= ReferenceChapterNumber)
+	return chunk
+}
+
+// SetReference makes the text of the chunk a placeholder for the value of a
+// cross-reference of the specified type, targeting the specified anchor.
+// If `link` is true, the chunk links to the location of the anchor target.
+func (tc *TextChunk) SetReference(kind ReferenceType, anchor string, link bool) {
+	tc._ref = &textReference{kind: kind, anchor: anchor, link: link}
+}
+
+// AddReference appends a new cross-reference text chunk to the paragraph,
+// using the default style of the paragraph. The chunk links to the location
+// of the anchor target.
+func (p *StyledParagraph) AddReference(kind ReferenceType, anchor string) *TextChunk {
+	return p.appendChunk(NewReferenceChunk(kind, anchor, p._cgffg))
+}
+
+// Layout draws the contents of the document using the specified layout
+// function. The cross-references of the drawn text chunks are resolved
+// after the layout function returns. If the value of any of the references
+// differs from the one used while drawing, the drawn contents are discarded
+// and the layout function is called again, using the resolved values. This
+// allows references to anchors which are drawn later in the document, and
+// makes sure line breaks take into account the resolved values.
+// NOTE: the layout function can be called more than once, so it should draw
+// all the contents to be laid out, using new components.
+func (c *Creator) Layout(layoutFunc func(c *Creator) error) error {
+	state := c.saveLayoutState()
+	for pass := 1; ; pass++ {
+		if err := layoutFunc(c); err != nil {
+			return err
+		}
+		c.endPageFlow()
+
+		offset, err := c.prefacePageCount()
+		if err != nil {
+			return err
+		}
+		if !c._xrefs.endPass(offset, offset+len(c._gcfe)) || pass == maxLayoutPasses {
+			return nil
+		}
+		c.restoreLayoutState(state)
+		c._xrefs.indexTerms = nil
+	}
+}
+
+// layoutState holds the state of the creator, which is restored before
+// running additional layout passes.
+type layoutState struct {
+	creator    Creator
+	pages      []*model.PdfPage
+	blocks     map[*model.PdfPage]*Block
+	transforms map[*model.PdfPage]*pageTransformations
+	tocLines   []*TOCLine
+	outline    []*model.OutlineItem
+	structRoot *model.KDict
+
+	// partial is set if the content of only some of the pages was saved.
+	partial bool
+}
+
+// saveLayoutState saves the state of the creator affected by drawing.
+func (c *Creator) saveLayoutState() *layoutState {
+	state := &layoutState{
+		creator:    *c,
+		pages:      append([]*model.PdfPage(nil), c._gcfe...),
+		blocks:     make(map[*model.PdfPage]*Block, len(c._fcbb)),
+		transforms: make(map[*model.PdfPage]*pageTransformations, len(c._fdba)),
+	}
+	for page, block := range c._fcbb {
+		state.blocks[page] = block.snapshot()
+	}
+	for page, transforms := range c._fdba {
+		state.transforms[page] = transforms
+	}
+	c.saveDrawingState(state)
+	return state
+}
+
+// savePagesState saves the state of the creator affected by drawing, except
+// for the content of the pages other than the specified ones, which is kept
+// as it is when the state is restored.
+func (c *Creator) savePagesState(pages []*model.PdfPage) *layoutState {
+	state := &layoutState{
+		creator: *c,
+		pages:   append([]*model.PdfPage(nil), c._gcfe...),
+		blocks:  make(map[*model.PdfPage]*Block, len(pages)),
+		partial: true,
+	}
+	for _, page := range pages {
+		if block, ok := c._fcbb[page]; ok {
+			state.blocks[page] = block.snapshot()
+		}
+	}
+	c.saveDrawingState(state)
+	return state
+}
+
+// saveDrawingState saves the table of contents, outline and structure tree
+// of the creator to the specified state.
+func (c *Creator) saveDrawingState(state *layoutState) {
+	if c._febc != nil {
+		state.tocLines = append([]*TOCLine(nil), c._febc._ddda...)
+	}
+	if c._fag != nil {
+		state.outline = append([]*model.OutlineItem(nil), c._fag.Entries...)
+	}
+	if c._efag != nil {
+		root := *c._efag
+		state.structRoot = &root
+	}
+}
+
+// restoreLayoutState restores the state of the creator to the specified one.
+// The cross-reference registry is preserved.
+func (c *Creator) restoreLayoutState(state *layoutState) {
+	xrefs := c._xrefs
+	*c = state.creator
+	c._xrefs = xrefs
+
+	blocks, transforms := c._fcbb, c._fdba
+	c._gcfe = append([]*model.PdfPage(nil), state.pages...)
+	c._fcbb = make(map[*model.PdfPage]*Block, len(state.blocks))
+	for page, block := range state.blocks {
+		c._fcbb[page] = block.snapshot()
+	}
+	if state.partial {
+		// Keep the current content and transformations of the pages which
+		// were not saved, dropping the pages added since.
+		c._fdba = make(map[*model.PdfPage]*pageTransformations, len(transforms))
+		for _, page := range state.pages {
+			if _, ok := state.blocks[page]; !ok {
+				if block, ok := blocks[page]; ok {
+					c._fcbb[page] = block
+				}
+			}
+			if t, ok := transforms[page]; ok {
+				c._fdba[page] = t
+			}
+		}
+	} else {
+		c._fdba = make(map[*model.PdfPage]*pageTransformations, len(state.transforms))
+		for page, t := range state.transforms {
+			c._fdba[page] = t
+		}
+	}
+	if c._febc != nil {
+		c._febc._ddda = append([]*TOCLine(nil), state.tocLines...)
+	}
+	if c._fag != nil {
+		c._fag.Entries = append([]*model.OutlineItem(nil), state.outline...)
+	}
+	if c._efag != nil && state.structRoot != nil {
+		*c._efag = *state.structRoot
+	}
+	c._aada._xrefs = xrefs
+}
+
+// snapshot returns a copy of the block which is not affected by subsequent
+// changes to the original block, such as merging other blocks into it.
+func (blk *Block) snapshot() *Block {
+	dup := blk.duplicate()
+	dup._ded = append([]*model.PdfAnnotation(nil), blk._ded...)
+	if blk._fcb != nil {
+		dup._fcb = copyPageResources(blk._fcb)
+	}
+	return dup
+}
+
+// copyPageResources returns a copy of the specified resources. The resource
+// category dictionaries are copied, so that resources added to the copy are
+// not added to the original.
+func copyPageResources(res *model.PdfPageResources) *model.PdfPageResources {
+	dict, ok := core.GetDict(res.ToPdfObject())
+	if !ok {
+		return res
+	}
+
+	dup := core.MakeDict()
+	for _, key := range dict.Keys() {
+		val := dict.Get(key)
+		if category, ok := val.(*core.PdfObjectDictionary); ok {
+			val = core.MakeDict().Merge(category)
+		}
+		dup.Set(key, val)
+	}
+
+	dupRes, err := model.NewPdfPageResourcesFromDict(dup)
+	if err != nil {
+		return res
+	}
+	return dupRes
+}
+
+// prefacePageCount returns the number of pages which are inserted before the
+// laid out content when the creator is finalized (front page and table of
+// contents). The state of the creator is preserved.
+func (c *Creator) prefacePageCount() (int, error) {
+	if c._eff == nil && !c.AddTOC {
+		return 0, nil
+	}
+
+	state := c.saveLayoutState()
+	defer c.restoreLayoutState(state)
+
+	count := 0
+	if c._eff != nil {
+		c._gcfe = nil
+		c._adbca = nil
+		c.initContext()
+		c._eff(FrontpageFunctionArgs{PageNum: 1, TotalPages: len(state.pages)})
+		count += len(c._gcfe)
+	}
+	if c.AddTOC {
+		c._gcfe = nil
+		c._adbca = nil
+		c.initContext()
+		if c.CustomTOC && c._ecfd != nil {
+			if err := c._ecfd(c._febc); err != nil {
+				return 0, err
+			}
+			count += len(c._gcfe)
+		} else {
+			if c._ecfd != nil {
+				if err := c._ecfd(c._febc); err != nil {
+					return 0, err
+				}
+			}
+			blocks, _, err := c._febc.GeneratePageBlocks(c._aada)
+			if err != nil {
+				return 0, err
+			}
+			count += len(blocks)
+		}
+	}
+	return count, nil
+}
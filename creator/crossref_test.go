@@ -0,0 +1,67 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCrossReferences(t *testing.T) {
+	c := New()
+	c.DrawFooter(func(b *Block, args FooterFunctionArgs) {
+		p := c.NewStyledParagraph()
+		p.Append("Page ")
+		p.AddReference(ReferenceCurrentPage, "")
+		p.Append(" of ")
+		p.AddReference(ReferencePageCount, "")
+		b.Draw(p)
+	})
+
+	err := c.Layout(func(c *Creator) error {
+		// Forward references to an anchor drawn on the last page.
+		c.NewPage()
+		p := c.NewStyledParagraph()
+		p.Append("See ")
+		p.AddReference(ReferenceLabel, "target")
+		p.Append(" on page ")
+		p.AddReference(ReferencePageNumber, "target")
+		p.Append(", missing ")
+		p.AddReference(ReferencePageNumber, "missing")
+		if err := c.Draw(p); err != nil {
+			return err
+		}
+
+		c.NewPage()
+		c.NewPage()
+		target := c.NewStyledParagraph()
+		target.Append("Target")
+		target.SetAnchor("target", "Figure 7")
+		return c.Draw(target)
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	texts := creatorPageTexts(t, c)
+	if len(texts) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(texts))
+	}
+	expected := "See Figure 7 on page 3, missing " + referencePlaceholder
+	if !strings.Contains(texts[0], expected) {
+		t.Fatalf("expected %q on page 1, got %q", expected, texts[0])
+	}
+	for i, text := range texts {
+		if footer := fmt.Sprintf("Page %d of 3", i+1); !strings.Contains(text, footer) {
+			t.Fatalf("expected %q on page %d, got %q", footer, i+1, text)
+		}
+	}
+}
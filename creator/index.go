@@ -0,0 +1,604 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// Index represents a back-of-book index component. The entries of the index
+// are collected from the terms marked on the text chunks drawn by the
+// creator (see TextChunk.AddIndexTerm). The entries are sorted according to
+// the collation rules of the index language, grouped by their first letter
+// and laid out on multiple columns. The pages of each entry are listed as
+// deduplicated page ranges, which link to the marked text.
+type Index struct {
+	taggedDrawable
+	heading *StyledParagraph
+
+	columns   int
+	columnGap float64
+
+	groupStyle   TextStyle
+	termStyle    TextStyle
+	pageStyle    TextStyle
+	seeAlsoStyle TextStyle
+
+	pageSeparator  string
+	rangeSeparator string
+	seeText        string
+	seeAlsoText    string
+	levelOffset    float64
+	showGroups     bool
+	showLinks      bool
+
+	language    string
+	docLanguage string
+	seeAlso     map[string][]string
+}
+
+// newIndex returns a new index component, having the specified title.
+func newIndex(title string, style, styleHeading TextStyle) *Index {
+	headingStyle := styleHeading
+	headingStyle.FontSize = 14
+
+	heading := _dfae(headingStyle)
+	heading.SetEnableWrap(true)
+	heading.SetTextAlignment(TextAlignmentLeft)
+	heading.SetMargins(0, 0, 0, 5)
+	chunk := heading.Append(title)
+	chunk.Style = headingStyle
+
+	groupStyle := styleHeading
+	groupStyle.FontSize = 12
+
+	return &Index{
+		heading:        heading,
+		columns:        2,
+		columnGap:      20,
+		groupStyle:     groupStyle,
+		termStyle:      style,
+		pageStyle:      style,
+		seeAlsoStyle:   style,
+		pageSeparator:  ", ",
+		rangeSeparator: "–",
+		seeText:        "See",
+		seeAlsoText:    "see also",
+		levelOffset:    10,
+		showGroups:     true,
+		showLinks:      true,
+		seeAlso:        map[string][]string{},
+		taggedDrawable: taggedDrawable{_edggf: model.StructureTypeIndex},
+	}
+}
+
+// NewIndex creates a new index component, having the specified title.
+func (c *Creator) NewIndex(title string) *Index {
+	headingStyle := c.NewTextStyle()
+	headingStyle.Font = c._ceeag
+	return newIndex(title, c.NewTextStyle(), headingStyle)
+}
+
+// CreateIndex sets a function to customize the index of the document. When
+// set, the index is generated when the creator is finalized and drawn on new
+// pages, added after the laid out content. The function is called before the
+// index is drawn, and can be used to change the style of the index.
+// Passing in a nil function generates the index using the default style.
+func (c *Creator) CreateIndex(genIndexFunc func(idx *Index) error) {
+	if genIndexFunc == nil {
+		genIndexFunc = func(*Index) error { return nil }
+	}
+	c._fdeb = genIndexFunc
+}
+
+// Index returns the index component of the creator.
+func (c *Creator) Index() *Index {
+	return c._egdd
+}
+
+// Heading returns the heading component of the index.
+func (idx *Index) Heading() *StyledParagraph {
+	return idx.heading
+}
+
+// SetHeading sets the text and the style of the heading of the index.
+func (idx *Index) SetHeading(text string, style TextStyle) {
+	idx.heading.Reset()
+	chunk := idx.heading.Append(text)
+	chunk.Style = style
+}
+
+// SetColumns sets the number of columns the entries of the index are laid
+// out on.
+func (idx *Index) SetColumns(columns int) {
+	if columns < 1 {
+		columns = 1
+	}
+	idx.columns = columns
+}
+
+// SetColumnGap sets the horizontal space between the columns of the index.
+func (idx *Index) SetColumnGap(gap float64) {
+	idx.columnGap = gap
+}
+
+// SetGroupStyle sets the style of the letter headings of the index groups.
+func (idx *Index) SetGroupStyle(style TextStyle) {
+	idx.groupStyle = style
+}
+
+// SetTermStyle sets the style of the terms of the index entries.
+func (idx *Index) SetTermStyle(style TextStyle) {
+	idx.termStyle = style
+}
+
+// SetPageStyle sets the style of the page numbers of the index entries.
+func (idx *Index) SetPageStyle(style TextStyle) {
+	idx.pageStyle = style
+}
+
+// SetSeeAlsoStyle sets the style of the see and see also references of the
+// index entries.
+func (idx *Index) SetSeeAlsoStyle(style TextStyle) {
+	idx.seeAlsoStyle = style
+}
+
+// SetPageSeparator sets the separator of the page numbers of the index
+// entries. The default separator is ", ".
+func (idx *Index) SetPageSeparator(separator string) {
+	idx.pageSeparator = separator
+}
+
+// SetRangeSeparator sets the separator of the bounds of page ranges.
+// The default separator is an en dash (e.g. "12–14").
+func (idx *Index) SetRangeSeparator(separator string) {
+	idx.rangeSeparator = separator
+}
+
+// SetSeeText sets the text introducing the references of the entries which
+// have no page numbers, and the references of the entries which have page
+// numbers. The default values are "See" and "see also".
+func (idx *Index) SetSeeText(see, seeAlso string) {
+	idx.seeText = see
+	idx.seeAlsoText = seeAlso
+}
+
+// SetLevelOffset sets the amount of space an indentation level occupies.
+// Sub-terms are indented by one level relative to their parent term.
+func (idx *Index) SetLevelOffset(levelOffset float64) {
+	idx.levelOffset = levelOffset
+}
+
+// SetShowGroups sets whether the entries of the index are grouped by their
+// first letter, under letter headings.
+func (idx *Index) SetShowGroups(showGroups bool) {
+	idx.showGroups = showGroups
+}
+
+// SetShowLinks sets whether the page numbers of the index entries link to
+// the location of the marked text.
+func (idx *Index) SetShowLinks(showLinks bool) {
+	idx.showLinks = showLinks
+}
+
+// SetLanguage sets the language used to sort and group the entries of the
+// index, as a BCP 47 language tag (e.g. "de", "sv-SE"). If not set, the
+// language of the document is used (see Creator.SetLanguage).
+func (idx *Index) SetLanguage(language string) {
+	idx.language = language
+}
+
+// AddSeeAlso adds references to other terms of the index, to the specified
+// term. The term is added to the index, if not already present.
+func (idx *Index) AddSeeAlso(term string, seeAlso ...string) {
+	idx.seeAlso[term] = append(idx.seeAlso[term], seeAlso...)
+}
+
+// indexMark represents an index term marked on a text chunk.
+type indexMark struct {
+	terms   []string
+	seeAlso []string
+}
+
+// indexOccurrence holds the location of a drawn index mark.
+type indexOccurrence struct {
+	mark *indexMark
+	page int
+	x, y float64
+}
+
+// AddIndexTerm marks the specified term, to be included in the index of the
+// document. The optional subterms are nested under the term, in order
+// (e.g. "Fonts", "embedding"). The index entry lists the page the chunk is
+// drawn on.
+func (tc *TextChunk) AddIndexTerm(term string, subterms ...string) {
+	terms := append([]string{term}, subterms...)
+	tc._gbda = append(tc._gbda, &indexMark{terms: terms})
+}
+
+// AddIndexSeeAlso marks the specified term, to be included in the index of
+// the document, along with references to other terms of the index.
+// Unlike AddIndexTerm, the page the chunk is drawn on is not listed.
+func (tc *TextChunk) AddIndexSeeAlso(term string, seeAlso ...string) {
+	tc._gbda = append(tc._gbda, &indexMark{terms: []string{term}, seeAlso: seeAlso})
+}
+
+// markIndexTerms records the location of the index terms marked on the
+// specified chunk, drawn on the specified page, at the specified position.
+func (xr *crossReferences) markIndexTerms(chunk *TextChunk, page int, x, y float64) {
+	if xr == nil || xr.page > 0 {
+		return
+	}
+	for _, mark := range chunk._gbda {
+		xr.indexTerms = append(xr.indexTerms, indexOccurrence{mark: mark, page: page, x: x, y: y})
+	}
+}
+
+// indexEntry represents an entry of the index, along with its subentries.
+type indexEntry struct {
+	term     string
+	pages    map[int]indexOccurrence
+	seeAlso  []string
+	children map[string]*indexEntry
+}
+
+// child returns the subentry of the entry having the specified term,
+// creating it if it does not exist.
+func (e *indexEntry) child(term string) *indexEntry {
+	if e.children == nil {
+		e.children = map[string]*indexEntry{}
+	}
+	c, ok := e.children[term]
+	if !ok {
+		c = &indexEntry{term: term, pages: map[int]indexOccurrence{}}
+		e.children[term] = c
+	}
+	return c
+}
+
+// sortedChildren returns the subentries of the entry, sorted using the
+// specified collator.
+func (e *indexEntry) sortedChildren(coll *collate.Collator) []*indexEntry {
+	children := make([]*indexEntry, 0, len(e.children))
+	for _, c := range e.children {
+		children = append(children, c)
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		if cmp := coll.CompareString(children[i].term, children[j].term); cmp != 0 {
+			return cmp < 0
+		}
+		return children[i].term < children[j].term
+	})
+	return children
+}
+
+// buildEntries builds the tree of index entries out of the specified index
+// mark occurrences.
+func (idx *Index) buildEntries(occurrences []indexOccurrence) *indexEntry {
+	root := &indexEntry{}
+	for _, occ := range occurrences {
+		entry := root
+		for _, term := range occ.mark.terms {
+			entry = entry.child(term)
+		}
+		if occ.mark.seeAlso != nil {
+			entry.seeAlso = append(entry.seeAlso, occ.mark.seeAlso...)
+			continue
+		}
+		if _, ok := entry.pages[occ.page]; !ok {
+			entry.pages[occ.page] = occ
+		}
+	}
+	for term, seeAlso := range idx.seeAlso {
+		entry := root.child(term)
+		entry.seeAlso = append(entry.seeAlso, seeAlso...)
+	}
+	return root
+}
+
+// collator returns the collator used to sort the entries of the index.
+func (idx *Index) collator(options ...collate.Option) *collate.Collator {
+	lang := idx.language
+	if lang == "" {
+		lang = idx.docLanguage
+	}
+	tag, err := language.Parse(lang)
+	if err != nil {
+		tag = language.Und
+	}
+	return collate.New(tag, options...)
+}
+
+// groupKey returns the letter heading of the group the specified term
+// belongs to. Letters which only differ by diacritics from a base letter
+// are grouped under the base letter, unless they are distinct letters in
+// the language of the index. Terms not starting with a letter are grouped
+// under "#".
+func groupKey(term string, loose *collate.Collator) string {
+	r := []rune(strings.TrimSpace(term))
+	if len(r) == 0 || !unicode.IsLetter(r[0]) {
+		return "#"
+	}
+	letter := string(unicode.ToUpper(r[0]))
+	base := []rune(norm.NFD.String(letter))
+	if len(base) > 1 && loose.CompareString(letter, string(base[0])) == 0 {
+		return string(base[0])
+	}
+	return letter
+}
+
+// pageRanges groups the specified sorted page numbers into ranges of
+// consecutive pages.
+func pageRanges(pages []int) [][2]int {
+	var ranges [][2]int
+	for _, page := range pages {
+		if n := len(ranges); n > 0 && ranges[n-1][1]+1 == page {
+			ranges[n-1][1] = page
+			continue
+		}
+		ranges = append(ranges, [2]int{page, page})
+	}
+	return ranges
+}
+
+// entryParagraph returns the paragraph displaying the specified entry,
+// indented to the specified level.
+func (idx *Index) entryParagraph(entry *indexEntry, level int, pageOffset int) *StyledParagraph {
+	p := _dfae(idx.termStyle)
+	p.SetEnableWrap(true)
+	p.SetTextAlignment(TextAlignmentLeft)
+	p.SetMargins(float64(level)*idx.levelOffset, 0, 0, 0)
+
+	term := p.Append(entry.term)
+	term.Style = idx.termStyle
+
+	pages := make([]int, 0, len(entry.pages))
+	for page := range entry.pages {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+
+	addPage := func(page int) {
+		chunk := NewTextChunk(strconv.Itoa(page+pageOffset), idx.pageStyle)
+		if idx.showLinks {
+			occ := entry.pages[page]
+			chunk.AddAnnotation(_bcccc(int64(page-1+pageOffset), occ.x, occ.y, 0, ""))
+		}
+		p.appendChunk(chunk)
+	}
+	for i, r := range pageRanges(pages) {
+		if i == 0 {
+			p.appendChunk(NewTextChunk(idx.pageSeparator, idx.termStyle))
+		} else {
+			p.appendChunk(NewTextChunk(idx.pageSeparator, idx.pageStyle))
+		}
+		addPage(r[0])
+		if r[1] != r[0] {
+			p.appendChunk(NewTextChunk(idx.rangeSeparator, idx.pageStyle))
+			addPage(r[1])
+		}
+	}
+
+	if len(entry.seeAlso) > 0 {
+		var seeAlso []string
+		seen := map[string]struct{}{}
+		for _, term := range entry.seeAlso {
+			if _, ok := seen[term]; !ok {
+				seen[term] = struct{}{}
+				seeAlso = append(seeAlso, term)
+			}
+		}
+
+		text := ". " + idx.seeText + " "
+		if len(pages) > 0 {
+			text = "; " + idx.seeAlsoText + " "
+		}
+		p.appendChunk(NewTextChunk(text+strings.Join(seeAlso, "; "), idx.seeAlsoStyle))
+	}
+	return p
+}
+
+// indexLine represents a line of the index, which is either a letter
+// heading or an entry.
+type indexLine struct {
+	paragraph *StyledParagraph
+	isHeading bool
+}
+
+// lines returns the lines of the index, out of the specified index mark
+// occurrences.
+func (idx *Index) lines(occurrences []indexOccurrence, pageOffset int) []indexLine {
+	root := idx.buildEntries(occurrences)
+	coll := idx.collator()
+	loose := idx.collator(collate.Loose)
+
+	var lines []indexLine
+	var addEntries func(entries []*indexEntry, level int)
+	addEntries = func(entries []*indexEntry, level int) {
+		for _, entry := range entries {
+			lines = append(lines, indexLine{paragraph: idx.entryParagraph(entry, level, pageOffset)})
+			addEntries(entry.sortedChildren(coll), level+1)
+		}
+	}
+
+	var group string
+	for i, entry := range root.sortedChildren(coll) {
+		if idx.showGroups {
+			if key := groupKey(entry.term, loose); i == 0 || key != group {
+				group = key
+				heading := _dfae(idx.groupStyle)
+				heading.SetMargins(0, 0, 8, 2)
+				chunk := heading.Append(key)
+				chunk.Style = idx.groupStyle
+				lines = append(lines, indexLine{paragraph: heading, isHeading: true})
+			}
+		}
+		addEntries([]*indexEntry{entry}, 0)
+	}
+	return lines
+}
+
+// lineHeight returns the height of the specified paragraph, drawn on a
+// column having the specified width.
+func lineHeight(p *StyledParagraph, width float64) float64 {
+	p.SetWidth(width - p._ceffe.Left - p._ceffe.Right)
+	return p.Height() + p._ceffe.Top + p._ceffe.Bottom
+}
+
+// splitLines returns the wrapped lines of the specified paragraph, drawn on
+// a column having the specified width, as separate paragraphs. This allows
+// the lines of long entries to be distributed over multiple columns.
+func splitLines(p *StyledParagraph, width float64) []*StyledParagraph {
+	p.SetWidth(width - p._ceffe.Left - p._ceffe.Right)
+	if err := p.wrapText(); err != nil || len(p._aabfc) <= 1 {
+		return []*StyledParagraph{p}
+	}
+
+	lines := make([]*StyledParagraph, 0, len(p._aabfc))
+	for i, chunks := range p._aabfc {
+		line := *p
+		line._dadab = chunks
+		line._aabfc = nil
+		if i > 0 {
+			line._ceffe.Top = 0
+		}
+		if i < len(p._aabfc)-1 {
+			line._ceffe.Bottom = 0
+		}
+		lines = append(lines, &line)
+	}
+	return lines
+}
+
+// GeneratePageBlocks generates the page blocks of the index. The entries of
+// the index are laid out on columns, filling a column before moving on to
+// the next one. The lines of entries which do not fit in the remaining space
+// of a column continue on the next column. Implements the Drawable interface.
+func (idx *Index) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
+	origCtx := ctx
+	blocks, ctx, err := idx.heading.GeneratePageBlocks(ctx)
+	if err != nil {
+		return blocks, ctx, err
+	}
+
+	var occurrences []indexOccurrence
+	var pageOffset int
+	if ctx._xrefs != nil {
+		occurrences = ctx._xrefs.indexTerms
+		pageOffset = ctx._xrefs.pageOffset
+	}
+	entries := idx.lines(occurrences, pageOffset)
+	if len(entries) == 0 {
+		ctx.X = origCtx.X
+		return blocks, ctx, nil
+	}
+
+	columns := float64(idx.columns)
+	colWidth := (ctx.Width - idx.columnGap*(columns-1)) / columns
+	bottom := ctx.PageHeight - ctx.Margins.Bottom
+
+	var lines []indexLine
+	for _, entry := range entries {
+		if entry.isHeading {
+			lines = append(lines, entry)
+			continue
+		}
+		for _, p := range splitLines(entry.paragraph, colWidth) {
+			lines = append(lines, indexLine{paragraph: p})
+		}
+	}
+
+	top, y, maxY := ctx.Y, ctx.Y, ctx.Y
+	col := 0
+	for i, line := range lines {
+		required := lineHeight(line.paragraph, colWidth)
+		height := required
+		if line.isHeading && i+1 < len(lines) {
+			// Keep letter headings together with the first entry.
+			required += lineHeight(lines[i+1].paragraph, colWidth)
+		}
+
+		if y+required > bottom && y > top {
+			col++
+			if col >= idx.columns {
+				blocks = append(blocks, NewBlock(ctx.PageWidth, ctx.PageHeight))
+				ctx.Page++
+				top = ctx.Margins.Top
+				maxY = top
+				col = 0
+			}
+			y = top
+		}
+
+		lineCtx := ctx
+		lineCtx.X = ctx.X + float64(col)*(colWidth+idx.columnGap)
+		lineCtx.Y = y
+		lineCtx.Width = colWidth
+		lineCtx.Height = bottom - y
+		lineBlocks, _, err := line.paragraph.GeneratePageBlocks(lineCtx)
+		if err != nil {
+			return blocks, ctx, err
+		}
+		for _, b := range lineBlocks {
+			if err := blocks[len(blocks)-1].mergeBlocks(b); err != nil {
+				return blocks, ctx, err
+			}
+		}
+
+		y += height
+		if y > maxY {
+			maxY = y
+		}
+	}
+
+	ctx.X = origCtx.X
+	ctx.Y = maxY
+	ctx.Height = bottom - maxY
+	return blocks, ctx, nil
+}
+
+// drawIndex draws the index of the document on new pages, added after the
+// laid out content, if the index was enabled using CreateIndex. Indexes
+// without content are skipped.
+func (c *Creator) drawIndex() error {
+	if c._fdeb == nil || c._egdd == nil {
+		return nil
+	}
+	if len(c._xrefs.indexTerms) == 0 && len(c._egdd.seeAlso) == 0 {
+		return nil
+	}
+
+	offset, err := c.prefacePageCount()
+	if err != nil {
+		return err
+	}
+	c._xrefs.pageOffset = offset
+
+	if err := c._fdeb(c._egdd); err != nil {
+		return err
+	}
+	c._egdd.docLanguage = c._ccba
+
+	c.NewPage()
+	if err := c.Draw(c._egdd); err != nil {
+		return err
+	}
+	c.endPageFlow()
+	return nil
+}
@@ -0,0 +1,41 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"testing"
+)
+
+func TestIndexLongLocatorList(t *testing.T) {
+	c := New()
+	chunk := NewTextChunk("Term", c.NewTextStyle())
+	chunk.AddIndexTerm("term")
+	for page := 1; page <= 4000; page += 2 {
+		c._xrefs.markIndexTerms(chunk, page, 0, 0)
+	}
+
+	idx := c.NewIndex("Index")
+	idx.SetColumns(2)
+	c.NewPage()
+	ctx := c.Context()
+	blocks, ctx, err := idx.GeneratePageBlocks(ctx)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// The locators of the entry do not fit in a single column, so its lines
+	// continue on the second column and on the next page.
+	if len(blocks) < 2 {
+		t.Fatalf("expected the entry to span multiple pages, got %d blocks", len(blocks))
+	}
+	if bottom := ctx.PageHeight - ctx.Margins.Bottom; ctx.Y > bottom {
+		t.Fatalf("index ends at y %.2f, below the bottom margin %.2f", ctx.Y, bottom)
+	}
+}
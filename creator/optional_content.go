@@ -0,0 +1,129 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// SetOCProperties sets the optional content properties (layers) of the document. The groups referenced by
+// content drawn with NewLayered or Block.WrapOptionalContent should be added to them.
+func (c *Creator) SetOCProperties(props *model.OCProperties) {
+	c._ocProperties = props
+}
+
+// WrapOptionalContent marks the contents of the block as optional content controlled by `oc`, so that it
+// is shown or hidden with the layer.
+func (blk *Block) WrapOptionalContent(oc model.OptionalContent) error {
+	if len(*blk._fce) == 0 {
+		return nil
+	}
+	name, err := blk._fcb.AddOptionalContent(oc)
+	if err != nil {
+		return err
+	}
+	blk._fce.WrapIfNeeded()
+	ops := contentstream.ContentStreamOperations{{
+		Operand: "BDC",
+		Params:  []core.PdfObject{core.MakeName("OC"), core.MakeName(string(name))},
+	}}
+	ops = append(ops, *blk._fce...)
+	ops = append(ops, &contentstream.ContentStreamOperation{Operand: "EMC"})
+	*blk._fce = ops
+	return nil
+}
+
+// Layered is a drawable whose content is optional content of a layer. Viewers show or hide it with the
+// optional content group or membership dictionary controlling it.
+type Layered struct {
+	_drawable Drawable
+	_oc       model.OptionalContent
+}
+
+// NewLayered returns a drawable that draws `d` as optional content controlled by `oc`, e.g. the
+// dimensions of a drawing or the text of a translation.
+func (c *Creator) NewLayered(d Drawable, oc model.OptionalContent) *Layered {
+	return &Layered{_drawable: d, _oc: oc}
+}
+
+// Drawable returns the drawable of the layer.
+func (l *Layered) Drawable() Drawable { return l._drawable }
+
+// OptionalContent returns the optional content controlling the visibility of the drawable.
+func (l *Layered) OptionalContent() model.OptionalContent { return l._oc }
+
+// GeneratePageBlocks draws the drawable of the layer and marks the blocks as optional content.
+// Implements the Drawable interface.
+func (l *Layered) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
+	blocks, ctx, err := l._drawable.GeneratePageBlocks(ctx)
+	if err != nil {
+		return nil, ctx, err
+	}
+	for _, blk := range blocks {
+		if err := blk.WrapOptionalContent(l._oc); err != nil {
+			return nil, ctx, err
+		}
+	}
+	return blocks, ctx, nil
+}
+
+// SetMarkedContentID sets the marked content id of the drawable of the layer.
+func (l *Layered) SetMarkedContentID(id int64) { l._drawable.SetMarkedContentID(id) }
+
+// SetStructureType sets the structure type of the drawable of the layer.
+func (l *Layered) SetStructureType(structureType model.StructureType) {
+	l._drawable.SetStructureType(structureType)
+}
+
+// GenerateKDict generates the K dictionary of the drawable of the layer.
+func (l *Layered) GenerateKDict() (*model.KDict, error) { return l._drawable.GenerateKDict() }
+
+// SetStructPageNumber sets the page number of the structure element of the drawable of the layer.
+func (l *Layered) SetStructPageNumber(pageNumber *int64) { l._drawable.SetStructPageNumber(pageNumber) }
+
+// mergeProperties adds the property list named by the marked content operation `op` in the resources
+// `src` to the resources `dst`, renaming it if `dst` has a different property list with the same name.
+// The names of the property lists added are recorded in `names`.
+func mergeProperties(op *contentstream.ContentStreamOperation, names map[core.PdfObjectName]core.PdfObjectName, dst, src *model.PdfPageResources) {
+	if len(op.Params) != 2 || src == nil {
+		return
+	}
+	name, ok := op.Params[1].(*core.PdfObjectName)
+	if !ok {
+		return
+	}
+	if _, added := names[*name]; !added {
+		srcProps, ok := core.GetDict(src.Properties)
+		if !ok {
+			return
+		}
+		props := srcProps.Get(*name)
+		if props == nil {
+			return
+		}
+		if dst.Properties == nil {
+			dst.Properties = core.MakeDict()
+		}
+		dstProps, ok := core.GetDict(dst.Properties)
+		if !ok {
+			return
+		}
+		newName := *name
+		for existing := dstProps.Get(newName); existing != nil && existing != props; existing = dstProps.Get(newName) {
+			newName = core.PdfObjectName(_gbd(newName.String()))
+		}
+		dstProps.Set(newName, props)
+		names[*name] = newName
+	}
+	newName := names[*name]
+	op.Params[1] = &newName
+}
@@ -0,0 +1,142 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// layerTexts returns the text of the page drawn outside optional content, keyed by "", and the text of
+// each optional content of the Properties resources of the page, keyed by the name of its group.
+func layerTexts(t *testing.T, page *model.PdfPage) map[string]string {
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	props, _ := core.GetDict(page.Resources.Properties)
+	resources := page.Resources.ToPdfObject()
+
+	texts := map[string]string{}
+	layer := ""
+	var layerOps contentstream.ContentStreamOperations
+	for _, op := range *ops {
+		switch {
+		case op.Operand == "BDC" && len(op.Params) == 2:
+			if tag, _ := core.GetNameVal(op.Params[0]); tag != "OC" || layer != "" {
+				continue
+			}
+			name, _ := core.GetName(op.Params[1])
+			oc, ok := core.GetDict(props.Get(*name))
+			if !ok {
+				t.Fatalf("optional content %s is not in the resources", *name)
+			}
+			layer, _ = core.GetStringVal(oc.Get("Name"))
+			layerOps = nil
+		case op.Operand == "EMC" && layer != "":
+			var b strings.Builder
+			contentText(t, &b, layerOps.String(), resources, 0)
+			texts[layer] += b.String()
+			layer = ""
+		case layer != "":
+			layerOps = append(layerOps, op)
+		default:
+			var b strings.Builder
+			contentText(t, &b, (&contentstream.ContentStreamOperations{op}).String(), resources, 0)
+			texts[""] += b.String()
+		}
+	}
+	return texts
+}
+
+func TestOptionalContentLayers(t *testing.T) {
+	props := model.NewOCProperties()
+	dimensions := props.AddGroup("Dimensions")
+	dimensions.Locked = true
+	dimensions.PrintState = "OFF"
+	english := props.AddGroup("English")
+	french := props.AddGroup("French")
+	french.Visible = false
+	props.AddRadioButtonGroup(english, french)
+
+	c := New()
+	c.SetOCProperties(props)
+	c.NewPage()
+	for _, d := range []Drawable{
+		c.NewParagraph("Drawing"),
+		c.NewLayered(c.NewParagraph("12 mm"), dimensions),
+		c.NewLayered(c.NewParagraph("Hello"), english),
+		c.NewLayered(c.NewParagraph("Bonjour"), french),
+	} {
+		if err := c.Draw(d); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if err := c.Finalize(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	texts := layerTexts(t, c._gcfe[0])
+	expected := map[string]string{"": "Drawing\n", "Dimensions": "12 mm\n", "English": "Hello\n", "French": "Bonjour\n"}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("expected layer texts %q, got %q", expected, texts)
+	}
+
+	// The groups of the content are the groups of the document.
+	ocProperties := props.ToPdfObject()
+	view := model.NewOCVisibility(ocProperties, model.OCEventView)
+	var names []string
+	for _, layer := range view.Layers() {
+		names = append(names, layer.Name)
+		if layer.Locked != (layer.Name == "Dimensions") {
+			t.Fatalf("layer %s: unexpected locked state %v", layer.Name, layer.Locked)
+		}
+	}
+	if expected := []string{"Dimensions", "English", "French"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected layers %v, got %v", expected, names)
+	}
+	for _, test := range []struct {
+		oc      model.OptionalContent
+		event   model.OCEvent
+		visible bool
+	}{
+		{dimensions, model.OCEventView, true},
+		{dimensions, model.OCEventPrint, false},
+		{english, model.OCEventView, true},
+		{french, model.OCEventView, false},
+		{model.NewOCMembership(model.OCPolicyAllOn, english, french), model.OCEventView, false},
+		{model.NewOCMembership(model.OCPolicyAnyOn, english, french), model.OCEventView, true},
+		{&model.OCMembership{Expression: model.OCNot(french)}, model.OCEventView, true},
+	} {
+		visibility := model.NewOCVisibility(ocProperties, test.event)
+		if visible := visibility.IsVisible(test.oc.ToPdfObject()); visible != test.visible {
+			t.Fatalf("%s: expected visible %v for %v", test.event, test.visible, test.oc.ToPdfObject())
+		}
+	}
+
+	// Only one of the translations is visible at a time.
+	config, _ := core.GetDict(ocProperties.(*core.PdfObjectDictionary).Get("D"))
+	rb, ok := core.GetArray(config.Get("RBGroups"))
+	if !ok || rb.Len() != 1 {
+		t.Fatalf("expected one radio button group, got %v", config.Get("RBGroups"))
+	}
+	if group, _ := core.GetArray(rb.Get(0)); group.Len() != 2 || group.Get(0) != english.ToPdfObject() {
+		t.Fatalf("unexpected radio button group %v", group)
+	}
+}
@@ -0,0 +1,298 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"errors"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// ImportedPage represents a page of an existing document, drawn as a form
+// XObject. The page can be scaled, rotated and clipped to a region. Drawing
+// the same imported page several times references a single form XObject.
+// Implements the Drawable interface.
+type ImportedPage struct {
+	taggedDrawable
+	form *model.XObjectForm
+
+	// Size of the imported page, as displayed by viewers.
+	pageWidth, pageHeight float64
+
+	// Visible region of the page, relative to its top left corner.
+	clipX, clipY, clipWidth, clipHeight float64
+
+	width, height float64
+	angle         float64
+
+	margins     Margins
+	positioning Positioning
+	x, y        float64
+}
+
+// NewImportedPage returns a component drawing the specified page, which can
+// belong to any document. The resources of the page are copied once per
+// creator, so pages sharing fonts or images, or pages imported repeatedly,
+// do not duplicate them in the output document.
+func (c *Creator) NewImportedPage(page *model.PdfPage) (*ImportedPage, error) {
+	if c._pageImporter == nil {
+		c._pageImporter = model.NewPageImporter()
+	}
+	form, err := c._pageImporter.Import(page)
+	if err != nil {
+		return nil, err
+	}
+	return NewImportedPageFromForm(form)
+}
+
+// NewImportedPage returns a component drawing the specified page, which can
+// belong to any document. The resources of the page are copied on each call.
+func NewImportedPage(page *model.PdfPage) (*ImportedPage, error) {
+	form, err := page.ToXObjectForm()
+	if err != nil {
+		return nil, err
+	}
+	return NewImportedPageFromForm(form)
+}
+
+// NewImportedPageFromForm returns a component drawing a form XObject created
+// by model.PageImporter, or any other form XObject having a bounding box.
+// The size of the component is the size of the bounding box of the form,
+// transformed by its matrix.
+func NewImportedPageFromForm(form *model.XObjectForm) (*ImportedPage, error) {
+	bbox, err := core.GetNumbersAsFloat(formArray(form.BBox))
+	if err != nil || len(bbox) != 4 {
+		return nil, errors.New("form XObject has an invalid bounding box")
+	}
+	matrix, err := core.GetNumbersAsFloat(formArray(form.Matrix))
+	if err != nil || len(matrix) != 6 {
+		matrix = []float64{1, 0, 0, 1, 0, 0}
+	}
+
+	// Bounding box of the form in its parent coordinate system.
+	var minX, minY, maxX, maxY float64
+	for i, corner := range [][2]float64{{bbox[0], bbox[1]}, {bbox[2], bbox[1]}, {bbox[2], bbox[3]}, {bbox[0], bbox[3]}} {
+		x := matrix[0]*corner[0] + matrix[2]*corner[1] + matrix[4]
+		y := matrix[1]*corner[0] + matrix[3]*corner[1] + matrix[5]
+		if i == 0 || x < minX {
+			minX = x
+		}
+		if i == 0 || x > maxX {
+			maxX = x
+		}
+		if i == 0 || y < minY {
+			minY = y
+		}
+		if i == 0 || y > maxY {
+			maxY = y
+		}
+	}
+	width, height := maxX-minX, maxY-minY
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("form XObject has an empty bounding box")
+	}
+	if minX != 0 || minY != 0 {
+		// Move the bounding box to the origin.
+		matrix[4] -= minX
+		matrix[5] -= minY
+		form.Matrix = core.MakeArrayFromFloats(matrix)
+	}
+
+	return &ImportedPage{
+		form:           form,
+		pageWidth:      width,
+		pageHeight:     height,
+		clipWidth:      width,
+		clipHeight:     height,
+		width:          width,
+		height:         height,
+		positioning:    PositionRelative,
+		taggedDrawable: taggedDrawable{_edggf: model.StructureTypeFigure},
+	}, nil
+}
+
+func formArray(obj core.PdfObject) []core.PdfObject {
+	arr, ok := core.GetArray(obj)
+	if !ok || arr == nil {
+		return nil
+	}
+	return arr.Elements()
+}
+
+// XObjectForm returns the form XObject drawn by the component.
+func (p *ImportedPage) XObjectForm() *model.XObjectForm {
+	return p.form
+}
+
+// PageSize returns the size of the imported page, as displayed by viewers.
+func (p *ImportedPage) PageSize() (float64, float64) {
+	return p.pageWidth, p.pageHeight
+}
+
+// SetClip restricts the component to a rectangular region of the imported
+// page, specified in page units, relative to the top left corner of the
+// page. The size of the component is set to the size of the region, scaled
+// by the current scale factors of the component.
+func (p *ImportedPage) SetClip(x, y, width, height float64) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	sx, sy := p.scaleFactors()
+	p.clipX, p.clipY = x, y
+	p.clipWidth, p.clipHeight = width, height
+	p.width, p.height = width*sx, height*sy
+}
+
+// Clip returns the region of the imported page drawn by the component: x, y,
+// width, height.
+func (p *ImportedPage) Clip() (float64, float64, float64, float64) {
+	return p.clipX, p.clipY, p.clipWidth, p.clipHeight
+}
+
+// scaleFactors returns the horizontal and vertical scale factors at which
+// the visible region of the page is drawn.
+func (p *ImportedPage) scaleFactors() (float64, float64) {
+	return p.width / p.clipWidth, p.height / p.clipHeight
+}
+
+// Scale scales the component by the specified factors.
+func (p *ImportedPage) Scale(xFactor, yFactor float64) {
+	p.width *= xFactor
+	p.height *= yFactor
+}
+
+// ScaleToWidth scales the component to the specified width, keeping the
+// aspect ratio.
+func (p *ImportedPage) ScaleToWidth(width float64) {
+	p.height = p.height * width / p.width
+	p.width = width
+}
+
+// ScaleToHeight scales the component to the specified height, keeping the
+// aspect ratio.
+func (p *ImportedPage) ScaleToHeight(height float64) {
+	p.width = p.width * height / p.height
+	p.height = height
+}
+
+// ScaleToFit scales the component uniformly to the largest size fitting the
+// specified width and height.
+func (p *ImportedPage) ScaleToFit(width, height float64) {
+	scale := width / p.width
+	if s := height / p.height; s < scale {
+		scale = s
+	}
+	p.Scale(scale, scale)
+}
+
+// SetWidth sets the width of the component, before rotation.
+func (p *ImportedPage) SetWidth(width float64) {
+	p.width = width
+}
+
+// SetHeight sets the height of the component, before rotation.
+func (p *ImportedPage) SetHeight(height float64) {
+	p.height = height
+}
+
+// Width returns the width of the component, before rotation.
+func (p *ImportedPage) Width() float64 {
+	return p.width
+}
+
+// Height returns the height of the component, before rotation.
+func (p *ImportedPage) Height() float64 {
+	return p.height
+}
+
+// SetAngle sets the rotation angle of the component, in degrees. The page is
+// rotated counter-clockwise, around its center.
+func (p *ImportedPage) SetAngle(angle float64) {
+	p.angle = angle
+}
+
+// Angle returns the rotation angle of the component, in degrees.
+func (p *ImportedPage) Angle() float64 {
+	return p.angle
+}
+
+// SetMargins sets the margins of the component.
+func (p *ImportedPage) SetMargins(left, right, top, bottom float64) {
+	p.margins.Left = left
+	p.margins.Right = right
+	p.margins.Top = top
+	p.margins.Bottom = bottom
+}
+
+// GetMargins returns the margins of the component: left, right, top, bottom.
+func (p *ImportedPage) GetMargins() (float64, float64, float64, float64) {
+	return p.margins.Left, p.margins.Right, p.margins.Top, p.margins.Bottom
+}
+
+// SetPos sets the absolute position of the component. Changes the
+// positioning of the component to absolute.
+func (p *ImportedPage) SetPos(x, y float64) {
+	p.positioning = PositionAbsolute
+	p.x = x
+	p.y = y
+}
+
+// draw draws the unrotated component on a new block.
+func (p *ImportedPage) draw() (*Block, error) {
+	if p.width <= 0 || p.height <= 0 {
+		return nil, errors.New("imported page has an invalid size")
+	}
+	block := NewBlock(p.width, p.height)
+	block.SetAngle(p.angle)
+
+	name := block._fcb.GenerateXObjectName()
+	if err := block._fcb.SetXObjectFormByName(name, p.form); err != nil {
+		return nil, err
+	}
+
+	// Map the visible region of the page to the block.
+	sx, sy := p.scaleFactors()
+	clipBottom := p.pageHeight - p.clipY - p.clipHeight
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	if p._bffbg != nil {
+		structType := p._bffbg.StructureType
+		if structType == model.StructureTypeUnknown {
+			structType = p._edggf
+		}
+		cc.Add_BDC(*core.MakeName(string(structType)), map[string]core.PdfObject{
+			"MCID": core.MakeInteger(p._bffbg.Mcid),
+		})
+	}
+	cc.Add_re(0, 0, p.width, p.height).Add_W().Add_n()
+	cc.Add_cm(sx, 0, 0, sy, -p.clipX*sx, -clipBottom*sy)
+	cc.Add_Do(name)
+	if p._bffbg != nil {
+		cc.Add_EMC()
+	}
+	cc.Add_Q()
+	if err := block.addContentsByString(cc.String()); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// GeneratePageBlocks draws the imported page on a new block representing
+// the page. Implements the Drawable interface.
+func (p *ImportedPage) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
+	block, err := p.draw()
+	if err != nil {
+		return nil, ctx, err
+	}
+	return placeBlock(ctx, block, p.margins, p.positioning, p.x, p.y)
+}
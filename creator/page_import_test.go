@@ -0,0 +1,94 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// importSourcePage returns a page of a new document, displaying the
+// specified text.
+func importSourcePage(t *testing.T, text string) *model.PdfPage {
+	c := New()
+	c.SetPageSize(PageSize{200, 100})
+	page := c.NewPage()
+	if err := c.Draw(c.NewParagraph(text)); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := c.Finalize(); err != nil {
+		t.Fatalf("unable to finalize source document: %v", err)
+	}
+	return page
+}
+
+func TestImportedPage(t *testing.T) {
+	src := importSourcePage(t, "Imported text")
+	rotate := int64(90)
+	src.Rotate = &rotate
+
+	c := New()
+	c.NewPage()
+	var forms []*model.XObjectForm
+	for i := 0; i < 2; i++ {
+		p, err := c.NewImportedPage(src)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		// The rotated page is displayed in portrait orientation.
+		if w, h := p.PageSize(); w != 100 || h != 200 {
+			t.Fatalf("expected page size 100x200, got %.2fx%.2f", w, h)
+		}
+		p.ScaleToWidth(50)
+		if h := p.Height(); h != 100 {
+			t.Fatalf("expected scaled height 100, got %.2f", h)
+		}
+		if err := c.Draw(p); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		forms = append(forms, p.XObjectForm())
+	}
+	if forms[0] != forms[1] {
+		t.Fatalf("page imported twice by the same creator")
+	}
+
+	// The resources of the form are copies of the resources of the page.
+	srcFonts, _ := core.GetDict(src.Resources.Font)
+	formFonts, _ := core.GetDict(forms[0].Resources.Font)
+	if srcFonts == nil || formFonts == nil || len(formFonts.Keys()) != len(srcFonts.Keys()) {
+		t.Fatalf("fonts of the page not imported")
+	}
+	for _, key := range srcFonts.Keys() {
+		if formFonts.Get(key) == srcFonts.Get(key) {
+			t.Fatalf("font %s shared with the source document", key)
+		}
+	}
+
+	text := creatorPageTexts(t, c)[0]
+	if n := strings.Count(text, "Imported text"); n != 2 {
+		t.Fatalf("expected the imported text twice, got %q", text)
+	}
+
+	// Both drawings reference the same form XObject.
+	xobjs, ok := core.GetDict(c._gcfe[0].Resources.XObject)
+	if !ok {
+		t.Fatalf("page has no XObject resources")
+	}
+	streams := map[core.PdfObject]bool{}
+	for _, key := range xobjs.Keys() {
+		streams[core.TraceToDirectObject(xobjs.Get(key))] = true
+	}
+	if len(streams) != 1 {
+		t.Fatalf("expected a single form XObject, got %d", len(streams))
+	}
+}
@@ -0,0 +1,546 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import "github.com/unidoc/unipdf/v4/model"
+
+// Pagination defines the page flow properties of a drawable component.
+// The properties are resolved by the page flow of the creator, as well as by
+// the page flow of the container components (chapters and divisions).
+// NOTE: the page flow properties are only applied to components drawn using
+// relative positioning.
+type Pagination struct {
+	// KeepTogether specifies whether the component should not be split
+	// across pages. If the component does not fit in the space available on
+	// the current page, it is moved to the next page. Components taller than
+	// a page are split regardless.
+	KeepTogether bool
+
+	// KeepWithNext specifies whether the component should be placed on the
+	// same page as the beginning of the next component. Can be used, for
+	// example, in order to keep a heading together with the first lines of
+	// the following paragraph.
+	KeepWithNext bool
+
+	// Orphans is the minimum number of lines of a paragraph which must be
+	// left at the bottom of a page, before a page break.
+	Orphans int
+
+	// Widows is the minimum number of lines of a paragraph which must be
+	// carried over to the top of the next page, after a page break.
+	// NOTE: only applies to styled paragraphs. Paragraph components are not
+	// split across pages, being moved to the next page as a whole instead.
+	Widows int
+
+	// BreakBefore specifies whether a page break is inserted before the
+	// component. No break is inserted if the component is already at the top
+	// of a page.
+	BreakBefore bool
+
+	// BreakAfter specifies whether a page break is inserted after the
+	// component. The break is resolved when the next component is drawn.
+	BreakAfter bool
+}
+
+// pageFlow holds the page flow properties of a component: its pagination
+// properties and the anchor cross-references can target. It is embedded in
+// drawable components in order to provide a uniform page flow API.
+type pageFlow struct {
+	pagination  Pagination
+	anchor      string
+	anchorLabel string
+}
+
+// Pagination returns the page flow properties of the component.
+func (pf *pageFlow) Pagination() Pagination {
+	return pf.pagination
+}
+
+// SetPagination sets the page flow properties of the component.
+func (pf *pageFlow) SetPagination(pagination Pagination) {
+	pf.pagination = pagination
+}
+
+// SetKeepTogether sets whether the component should be kept on a single page.
+// If the component does not fit on the current page, it is moved to the next.
+func (pf *pageFlow) SetKeepTogether(keepTogether bool) {
+	pf.pagination.KeepTogether = keepTogether
+}
+
+// SetKeepWithNext sets whether the component should be kept on the same page
+// as the beginning of the next component.
+func (pf *pageFlow) SetKeepWithNext(keepWithNext bool) {
+	pf.pagination.KeepWithNext = keepWithNext
+}
+
+// SetOrphans sets the minimum number of lines of the component which must be
+// left at the bottom of a page before a page break.
+func (pf *pageFlow) SetOrphans(lines int) {
+	pf.pagination.Orphans = lines
+}
+
+// SetWidows sets the minimum number of lines of the component which must be
+// carried over to the top of the next page after a page break.
+// NOTE: widow control is only applied to StyledParagraph components, as
+// Paragraph components are never split across pages.
+func (pf *pageFlow) SetWidows(lines int) {
+	pf.pagination.Widows = lines
+}
+
+// SetBreakBefore sets whether a page break is inserted before the component.
+func (pf *pageFlow) SetBreakBefore(breakBefore bool) {
+	pf.pagination.BreakBefore = breakBefore
+}
+
+// SetBreakAfter sets whether a page break is inserted after the component.
+func (pf *pageFlow) SetBreakAfter(breakAfter bool) {
+	pf.pagination.BreakAfter = breakAfter
+}
+
+// paginatedDrawable is implemented by components which have page flow
+// properties.
+type paginatedDrawable interface {
+	Pagination() Pagination
+}
+
+// paginationOf returns the page flow properties of the specified drawable.
+func paginationOf(d Drawable) Pagination {
+	if pd, ok := d.(paginatedDrawable); ok {
+		return pd.Pagination()
+	}
+	return Pagination{}
+}
+
+// isAbsolutelyPositioned returns true if the specified drawable is
+// positioned absolutely, in which case the page flow properties are ignored.
+func isAbsolutelyPositioned(d Drawable) bool {
+	switch t := d.(type) {
+	case *Block:
+		return t._ab.IsAbsolute()
+	case *Paragraph:
+		return t._cagd.IsAbsolute()
+	case *StyledParagraph:
+		return t._fdbb.IsAbsolute()
+	case *Chapter:
+		return t._dab.IsAbsolute()
+	case *Division:
+		return t._bfcf.IsAbsolute()
+	case *Image:
+		return t._fdbde.IsAbsolute()
+	case *Table:
+		return t._dcga.IsAbsolute()
+	case *Grid:
+		return t._bcag.IsAbsolute()
+	case *GraphicSVG:
+		return t._gada.IsAbsolute()
+	case *Chart:
+		return t._dcgc.IsAbsolute()
+	case *Barcode:
+		return t.positioning.IsAbsolute()
+	case *VectorChart:
+		return t.positioning.IsAbsolute()
+	case interface{ Positioning() Positioning }:
+		return t.Positioning().IsAbsolute()
+	}
+	return false
+}
+
+// isAtPageTop returns true if the current position of the context is at the
+// top of the page.
+func isAtPageTop(ctx DrawContext) bool {
+	return ctx.Y <= ctx.Margins.Top+0.5
+}
+
+// pageFlowBreak returns true if the component at index `i` of `items` must be
+// moved to the next page, in order to satisfy the page flow properties of the
+// components starting at that index.
+func pageFlowBreak(ctx DrawContext, items []Drawable, i int) bool {
+	if ctx.Inline || isAtPageTop(ctx) || isAbsolutelyPositioned(items[i]) {
+		return false
+	}
+
+	p := paginationOf(items[i])
+	if p.BreakBefore {
+		return true
+	}
+	if i > 0 && paginationOf(items[i-1]).BreakAfter {
+		return true
+	}
+
+	// Accumulate the heights of the chain of components which must be kept
+	// with the next one.
+	var required float64
+	j := i
+	for ; j < len(items)-1 && paginationOf(items[j]).KeepWithNext; j++ {
+		required += fullHeight(items[j], ctx.Width)
+	}
+
+	switch {
+	case j > i:
+		required += leadHeight(items[j], ctx.Width)
+	case p.KeepTogether:
+		required += fullHeight(items[j], ctx.Width)
+	case p.Orphans > 1:
+		required += leadHeight(items[j], ctx.Width)
+	default:
+		return false
+	}
+
+	pageHeight := ctx.PageHeight - ctx.Margins.Top - ctx.Margins.Bottom
+	return required > ctx.Height && required <= pageHeight
+}
+
+// beginPageFlow returns the blocks and the context resulting from moving the
+// component at index `i` of `items` to the next page, if required by the page
+// flow properties of the components. If no break is required, the returned
+// blocks are nil and the input context is returned unchanged. The anchor of
+// the component is registered at the resulting position.
+func beginPageFlow(ctx DrawContext, items []Drawable, i int) ([]*Block, DrawContext, error) {
+	var blocks []*Block
+	if pageFlowBreak(ctx, items, i) {
+		var err error
+		if blocks, ctx, err = (&PageBreak{}).GeneratePageBlocks(ctx); err != nil {
+			return nil, ctx, err
+		}
+	}
+	ctx._xrefs.register(ctx, items[i])
+	return blocks, ctx, nil
+}
+
+// mergePageBlocks appends the blocks in `next` to the blocks in `blocks`.
+// The first block of `next` is merged into the last block of `blocks`, as
+// they are drawn on the same page.
+func mergePageBlocks(blocks, next []*Block) ([]*Block, error) {
+	if len(blocks) == 0 {
+		return next, nil
+	}
+	if len(next) == 0 {
+		return blocks, nil
+	}
+	if err := blocks[len(blocks)-1].mergeBlocks(next[0]); err != nil {
+		return nil, err
+	}
+	return append(blocks, next[1:]...), nil
+}
+
+// fullHeight returns the height the specified drawable occupies when drawn
+// in a context having the specified width.
+func fullHeight(d Drawable, width float64) float64 {
+	switch t := d.(type) {
+	case *Chapter:
+		height := t._egcf.Top + t._egcf.Bottom + _cgb(t._cdc, width)
+		for _, child := range t._bdfgc {
+			height += fullHeight(child, width)
+		}
+		return height
+	case VectorDrawable:
+		return _cgb(t, width)
+	}
+	return 0
+}
+
+// leadHeight returns the minimum height which must be available on a page in
+// order for the specified drawable to start on it.
+func leadHeight(d Drawable, width float64) float64 {
+	p := paginationOf(d)
+	if p.KeepTogether {
+		return fullHeight(d, width)
+	}
+
+	lines := p.Orphans
+	if lines < 1 {
+		lines = 1
+	}
+
+	switch t := d.(type) {
+	case *StyledParagraph:
+		if t._bbde {
+			t.SetWidth(width - t._ceffe.Left - t._ceffe.Right)
+		}
+		height := t._ceffe.Top
+		for i, lineHeight := range t.lineHeights() {
+			if i >= lines {
+				break
+			}
+			height += lineHeight
+		}
+		return height
+	case *Paragraph:
+		if t._fddgg {
+			t.SetWidth(width - t._ccac.Left - t._ccac.Right)
+		}
+		t.wrapText()
+		if n := len(t._efacg); n < lines {
+			lines = n
+		}
+		return t._ccac.Top + float64(lines)*t._cgead*t._beeee
+	case *Chapter:
+		height := t._egcf.Top + _cgb(t._cdc, width)
+		if len(t._bdfgc) > 0 {
+			height += leadHeight(t._bdfgc[0], width)
+		}
+		return height
+	case *Table:
+		height := _cgb(t, width)
+		if rowHeight, err := t.GetRowHeight(1); err == nil {
+			height = t._ddecf.Top + rowHeight
+		}
+		return height
+	case VectorDrawable:
+		return _cgb(t, width)
+	}
+	return 0
+}
+
+// lineHeights returns the heights of the wrapped lines of the paragraph.
+func (p *StyledParagraph) lineHeights() []float64 {
+	if err := p.wrapText(); err != nil {
+		return nil
+	}
+
+	heights := make([]float64, 0, len(p._aabfc))
+	for _, line := range p._aabfc {
+		var height float64
+		if len(line) > 0 {
+			height = line[0].Style.FontSize
+		}
+		for _, chunk := range line {
+			if chunk.Text != "" && chunk.Style.FontSize > height {
+				height = chunk.Style.FontSize
+			}
+		}
+		heights = append(heights, height*p._eccfc)
+	}
+	return heights
+}
+
+// lineControlHeight returns the height available for the lines of the
+// paragraph on the current page, adjusted so that the widow and orphan
+// constraints of the paragraph are satisfied. A return value of 0 means
+// that the whole paragraph should be moved to the next page.
+func (p *StyledParagraph) lineControlHeight(ctx DrawContext) float64 {
+	pg := p.Pagination()
+	if pg.Orphans <= 1 && pg.Widows <= 1 || isAtPageTop(ctx) {
+		return ctx.Height
+	}
+
+	heights := p.lineHeights()
+	var used float64
+	fit := 0
+	for fit < len(heights) && used+heights[fit] <= ctx.Height {
+		used += heights[fit]
+		fit++
+	}
+	if fit == len(heights) {
+		return ctx.Height
+	}
+	if fit < pg.Orphans {
+		return 0
+	}
+	if remaining := len(heights) - fit; remaining < pg.Widows {
+		fit = len(heights) - pg.Widows
+		if fit < 1 || fit < pg.Orphans {
+			return 0
+		}
+		used = 0
+		for _, height := range heights[:fit] {
+			used += height
+		}
+	}
+	return used
+}
+
+// flowEntry is an operation performed on the creator while components kept
+// with the next one are pending: drawing a component, or moving the position
+// of the drawing context.
+type flowEntry struct {
+	drawable Drawable
+	move     func(ctx *DrawContext)
+}
+
+// pageFlowState is the state of the creator before drawing a chain of
+// components kept with the next one. It is restored when the chain has to be
+// moved to the next page.
+type pageFlowState struct {
+	layout     *layoutState
+	indexTerms int
+	resolved   int
+}
+
+// keptWithNext returns true if the specified drawable must be placed on the
+// same page as the beginning of the next one.
+func keptWithNext(d Drawable) bool {
+	return paginationOf(d).KeepWithNext && !isAbsolutelyPositioned(d)
+}
+
+// drawPageFlow draws the specified component, resolving the page flow
+// properties of the components drawn before it. Components kept with the
+// next one are laid out immediately, so that the context of the creator is
+// up to date and layout errors are returned to the caller. When the next
+// component does not fit after them, they are moved to the next page along
+// with the operations performed on the creator since.
+func (c *Creator) drawPageFlow(d Drawable) error {
+	absolute := isAbsolutelyPositioned(d)
+	if len(c._flowQueue) > 0 && !absolute {
+		if err := c.resolveKeepWithNext(d); err != nil {
+			return err
+		}
+	}
+
+	kept := keptWithNext(d)
+	if kept && len(c._flowQueue) == 0 {
+		c._flowState = c.savePageFlowState()
+	}
+	if err := c.drawFlowItem(d); err != nil {
+		c.endPageFlow()
+		return err
+	}
+
+	switch {
+	case kept || absolute && len(c._flowQueue) > 0:
+		c._flowQueue = append(c._flowQueue, flowEntry{drawable: d})
+	case !absolute:
+		c.endPageFlow()
+	}
+	return nil
+}
+
+// drawFlowItem draws the specified component, inserting a page break before
+// it if required by its page flow properties.
+func (c *Creator) drawFlowItem(d Drawable) error {
+	if c.getActivePage() == nil {
+		c.NewPage()
+	}
+
+	if !isAbsolutelyPositioned(d) {
+		ctx := c._aada
+		if (c._flowBreak || pageFlowBreak(ctx, []Drawable{d}, 0)) && !isAtPageTop(ctx) {
+			c.NewPage()
+		}
+		c._flowBreak = false
+	}
+	c._xrefs.register(c._aada, d)
+
+	if err := c.draw(d); err != nil {
+		return err
+	}
+	if !isAbsolutelyPositioned(d) {
+		c._flowBreak = paginationOf(d).BreakAfter
+	}
+	return nil
+}
+
+// resolveKeepWithNext moves the pending components kept with the next one to
+// the next page, if the beginning of the specified component does not fit on
+// the page after them.
+func (c *Creator) resolveKeepWithNext(next Drawable) error {
+	state := c._flowState
+	start := state.layout.creator._aada
+	if start.Inline || isAtPageTop(start) {
+		return nil
+	}
+
+	// The height used by the pending components is known if they fit on the
+	// page they started on. Otherwise, it is estimated from their heights.
+	var used float64
+	if ctx := c._aada; ctx.Page == start.Page {
+		used = ctx.Y - start.Y
+	} else {
+		for _, e := range c._flowQueue {
+			if e.drawable != nil && !isAbsolutelyPositioned(e.drawable) {
+				used += fullHeight(e.drawable, start.Width)
+			}
+		}
+	}
+
+	required := used + leadHeight(next, start.Width)
+	available := start.PageHeight - start.Y - start.Margins.Bottom
+	pageHeight := start.PageHeight - start.Margins.Top - start.Margins.Bottom
+	if required <= available || required > pageHeight {
+		return nil
+	}
+
+	entries := c._flowQueue
+	c.restoreLayoutState(state.layout)
+	if xr := c._xrefs; xr != nil {
+		xr.indexTerms = xr.indexTerms[:state.indexTerms]
+		xr.resolved = xr.resolved[:state.resolved]
+	}
+	c.NewPage()
+
+	// The chain continues on the new page, as the next component may be kept
+	// with the following one too.
+	state = c.savePageFlowState()
+	for _, e := range entries {
+		if e.move != nil {
+			c.movePageFlow(e.move)
+			continue
+		}
+		if err := c.drawFlowItem(e.drawable); err != nil {
+			return err
+		}
+	}
+	c._flowQueue, c._flowState = entries, state
+	return nil
+}
+
+// movePageFlow applies the specified move to the drawing context of the
+// creator and updates the height available on the page. The move is recorded
+// if components kept with the next one are pending, in order to be repeated
+// if they are moved to the next page.
+func (c *Creator) movePageFlow(move func(ctx *DrawContext)) {
+	move(&c._aada)
+	c._aada.Height = c._aada.PageHeight - c._aada.Y - c._aada.Margins.Bottom
+	if len(c._flowQueue) > 0 {
+		c._flowQueue = append(c._flowQueue, flowEntry{move: move})
+	}
+}
+
+// savePageFlowState saves the state of the creator before drawing a chain of
+// components kept with the next one. Only the content of the active page is
+// saved, as drawing does not change the content of the previous pages.
+func (c *Creator) savePageFlowState() *pageFlowState {
+	var pages []*model.PdfPage
+	if page := c.getActivePage(); page != nil {
+		pages = append(pages, page)
+	}
+
+	state := &pageFlowState{layout: c.savePagesState(pages)}
+	if xr := c._xrefs; xr != nil {
+		state.indexTerms = len(xr.indexTerms)
+		state.resolved = len(xr.resolved)
+	}
+	return state
+}
+
+// endPageFlow ends the chain of components kept with the next one. The
+// components of the chain stay where they were laid out.
+func (c *Creator) endPageFlow() {
+	c._flowQueue = nil
+	c._flowState = nil
+}
+
+// pageFlowItems returns the components of the chapter, starting with the
+// chapter heading, in the order they are laid out.
+func (chap *Chapter) pageFlowItems() []Drawable {
+	items := make([]Drawable, 0, len(chap._bdfgc)+1)
+	items = append(items, chap._cdc)
+	return append(items, chap._bdfgc...)
+}
+
+// pageFlowItems returns the components of the division in the order they are
+// laid out.
+func (div *Division) pageFlowItems() []Drawable {
+	items := make([]Drawable, 0, len(div._fcbee))
+	for _, d := range div._fcbee {
+		items = append(items, d)
+	}
+	return items
+}
@@ -0,0 +1,180 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// anchorOf returns the position the component with the specified anchor was
+// laid out at.
+func anchorOf(t *testing.T, c *Creator, name string) *anchorTarget {
+	target := c._xrefs.target(name)
+	if target == nil {
+		t.Fatalf("anchor %q not registered", name)
+	}
+	return target
+}
+
+// styledText returns a styled paragraph with the specified anchor, having
+// the specified number of lines.
+func styledText(c *Creator, anchor string, lines int) *StyledParagraph {
+	p := c.NewStyledParagraph()
+	p.Append(strings.TrimSpace(strings.Repeat("Line\n", lines)))
+	p.SetAnchor(anchor, "")
+	return p
+}
+
+// moveToBottom moves the position of the creator so that the specified
+// height is left on the current page.
+func moveToBottom(c *Creator, height float64) {
+	ctx := c.Context()
+	c.MoveY(ctx.PageHeight - ctx.Margins.Bottom - height)
+}
+
+func TestKeepWithNextPageBreak(t *testing.T) {
+	c := New()
+	c.NewPage()
+
+	heading := c.NewStyledParagraph()
+	heading.Append("Heading")
+	heading.SetAnchor("heading", "")
+	heading.SetKeepWithNext(true)
+	body := styledText(c, "body", 5)
+
+	// The heading fits at the bottom of the page, but the first line of the
+	// body does not.
+	moveToBottom(c, heading.Height()+1)
+	if err := c.Draw(heading); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := c.Draw(body); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	top := c.Context().Margins.Top
+	if h := anchorOf(t, c, "heading"); h.page != 2 || math.Abs(h.y-top) > 0.01 {
+		t.Fatalf("heading at page %d, y %.2f, expected page 2, y %.2f", h.page, h.y, top)
+	}
+	if b := anchorOf(t, c, "body"); b.page != 2 || math.Abs(b.y-top-heading.Height()) > 0.01 {
+		t.Fatalf("body at page %d, y %.2f, expected page 2, y %.2f", b.page, b.y, top+heading.Height())
+	}
+	if n := len(c._gcfe); n != 2 {
+		t.Fatalf("expected 2 pages, got %d", n)
+	}
+}
+
+func TestKeepWithNextContext(t *testing.T) {
+	for _, height := range []float64{0, 15} {
+		c := New()
+		c.NewPage()
+
+		heading := c.NewStyledParagraph()
+		heading.Append("Heading")
+		heading.SetKeepWithNext(true)
+		if height > 0 {
+			moveToBottom(c, heading.Height()+height)
+		}
+
+		before := c.Context()
+		if err := c.Draw(heading); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		after := c.Context()
+		if math.Abs(after.Y-before.Y-heading.Height()) > 0.01 {
+			t.Fatalf("context not updated after kept component: y %.2f, expected %.2f",
+				after.Y, before.Y+heading.Height())
+		}
+
+		// The move is applied between the heading and the body, even if
+		// they are moved to the next page.
+		c.MoveDown(10)
+		if err := c.Draw(styledText(c, "body", 5)); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+
+		expectedPage, expectedY := 1, after.Y+10
+		if height > 0 {
+			expectedPage, expectedY = 2, after.Margins.Top+heading.Height()+10
+		}
+		if b := anchorOf(t, c, "body"); b.page != expectedPage || math.Abs(b.y-expectedY) > 0.01 {
+			t.Fatalf("body at page %d, y %.2f, expected page %d, y %.2f",
+				b.page, b.y, expectedPage, expectedY)
+		}
+	}
+}
+
+func TestOrphansAndWidows(t *testing.T) {
+	c := New()
+	lineHeight := styledText(c, "", 1).lineHeights()[0]
+
+	// Two lines fit on the first page, but three orphans are required.
+	c.NewPage()
+	moveToBottom(c, 2.5*lineHeight)
+	orphans := styledText(c, "orphans", 10)
+	orphans.SetOrphans(3)
+	if err := c.Draw(orphans); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if p := anchorOf(t, c, "orphans"); p.page != 2 {
+		t.Fatalf("paragraph starts at page %d, expected 2", p.page)
+	}
+
+	// Five of six lines fit on the page, but three widows are required.
+	c.NewPage()
+	moveToBottom(c, 5.5*lineHeight)
+	widows := styledText(c, "widows", 6)
+	widows.SetWidows(3)
+	if err := c.Draw(widows); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if p := anchorOf(t, c, "widows"); p.page != 3 {
+		t.Fatalf("paragraph starts at page %d, expected 3", p.page)
+	}
+	ctx := c.Context()
+	if ctx.Page != 4 || math.Abs(ctx.Y-ctx.Margins.Top-3*lineHeight) > 0.01 {
+		t.Fatalf("paragraph ends at page %d, y %.2f, expected page 4, y %.2f",
+			ctx.Page, ctx.Y, ctx.Margins.Top+3*lineHeight)
+	}
+}
+
+func TestKeepWithNextChain(t *testing.T) {
+	c := New()
+	c.NewPage()
+
+	var kept []*StyledParagraph
+	for _, name := range []string{"chapter", "section"} {
+		p := c.NewStyledParagraph()
+		p.Append(name)
+		p.SetAnchor(name, "")
+		p.SetKeepWithNext(true)
+		kept = append(kept, p)
+	}
+
+	// Both headings fit at the bottom of the page, but the first line of the
+	// body does not.
+	moveToBottom(c, kept[0].Height()+kept[1].Height()+1)
+	for _, p := range kept {
+		if err := c.Draw(p); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if err := c.Draw(styledText(c, "body", 5)); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for _, name := range []string{"chapter", "section", "body"} {
+		if target := anchorOf(t, c, name); target.page != 2 {
+			t.Fatalf("%s at page %d, expected 2", name, target.page)
+		}
+	}
+}
@@ -0,0 +1,515 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/internal/transform"
+)
+
+// svgSegment represents a segment of an SVG path, expressed using the path
+// construction operators of PDF content streams: move to ('M'), line to ('L'),
+// cubic Bézier curve to ('C') and close path ('Z').
+type svgSegment struct {
+	op  byte
+	pts [3]transform.Point
+}
+
+// svgPath represents the geometry of an SVG shape.
+type svgPath []svgSegment
+
+// moveTo starts a new subpath at the specified point.
+func (p *svgPath) moveTo(x, y float64) {
+	*p = append(*p, svgSegment{op: 'M', pts: [3]transform.Point{{X: x, Y: y}}})
+}
+
+// lineTo appends a straight line to the specified point.
+func (p *svgPath) lineTo(x, y float64) {
+	*p = append(*p, svgSegment{op: 'L', pts: [3]transform.Point{{X: x, Y: y}}})
+}
+
+// curveTo appends a cubic Bézier curve, having the specified control points
+// and end point.
+func (p *svgPath) curveTo(x1, y1, x2, y2, x, y float64) {
+	*p = append(*p, svgSegment{op: 'C', pts: [3]transform.Point{{X: x1, Y: y1}, {X: x2, Y: y2}, {X: x, Y: y}}})
+}
+
+// closePath closes the current subpath.
+func (p *svgPath) closePath() {
+	*p = append(*p, svgSegment{op: 'Z'})
+}
+
+// ellipse appends a closed ellipse centered at (cx, cy), having the specified
+// radiuses.
+func (p *svgPath) ellipse(cx, cy, rx, ry float64) {
+	const k = 0.5522847498307936
+	ox, oy := rx*k, ry*k
+	p.moveTo(cx+rx, cy)
+	p.curveTo(cx+rx, cy+oy, cx+ox, cy+ry, cx, cy+ry)
+	p.curveTo(cx-ox, cy+ry, cx-rx, cy+oy, cx-rx, cy)
+	p.curveTo(cx-rx, cy-oy, cx-ox, cy-ry, cx, cy-ry)
+	p.curveTo(cx+ox, cy-ry, cx+rx, cy-oy, cx+rx, cy)
+	p.closePath()
+}
+
+// roundedRect appends a closed rectangle having the specified corner radiuses.
+func (p *svgPath) roundedRect(x, y, w, h, rx, ry float64) {
+	if rx <= 0 || ry <= 0 {
+		p.moveTo(x, y)
+		p.lineTo(x+w, y)
+		p.lineTo(x+w, y+h)
+		p.lineTo(x, y+h)
+		p.closePath()
+		return
+	}
+
+	const k = 0.5522847498307936
+	ox, oy := rx*k, ry*k
+	p.moveTo(x+rx, y)
+	p.lineTo(x+w-rx, y)
+	p.curveTo(x+w-rx+ox, y, x+w, y+ry-oy, x+w, y+ry)
+	p.lineTo(x+w, y+h-ry)
+	p.curveTo(x+w, y+h-ry+oy, x+w-rx+ox, y+h, x+w-rx, y+h)
+	p.lineTo(x+rx, y+h)
+	p.curveTo(x+rx-ox, y+h, x, y+h-ry+oy, x, y+h-ry)
+	p.lineTo(x, y+ry)
+	p.curveTo(x, y+ry-oy, x+rx-ox, y, x+rx, y)
+	p.closePath()
+}
+
+// arcTo appends an elliptical arc, as specified by the SVG arc command, going
+// from (x0, y0) to (x, y). The arc is approximated using cubic Bézier curves.
+// The conversion follows the implementation notes of the SVG specification.
+func (p *svgPath) arcTo(x0, y0, rx, ry, rotation float64, large, sweep bool, x, y float64) {
+	if x0 == x && y0 == y {
+		return
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		p.lineTo(x, y)
+		return
+	}
+
+	phi := rotation * math.Pi / 180
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+
+	// Compute the center of the ellipse.
+	dx, dy := (x0-x)/2, (y0-y)/2
+	x1 := cosPhi*dx + sinPhi*dy
+	y1 := -sinPhi*dx + cosPhi*dy
+
+	// Scale up the radiuses if they are too small.
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		s := math.Sqrt(lambda)
+		rx, ry = rx*s, ry*s
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := 0.0
+	if den != 0 && num > 0 {
+		coef = math.Sqrt(num / den)
+	}
+	if large == sweep {
+		coef = -coef
+	}
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+	cx := cosPhi*cx1 - sinPhi*cy1 + (x0+x)/2
+	cy := sinPhi*cx1 + cosPhi*cy1 + (y0+y)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	// Split the arc in segments of at most 90 degrees.
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(n)
+	k := 4.0 / 3.0 * math.Tan(step/4)
+	point := func(a float64) (float64, float64) {
+		ex, ey := rx*math.Cos(a), ry*math.Sin(a)
+		return cosPhi*ex - sinPhi*ey + cx, sinPhi*ex + cosPhi*ey + cy
+	}
+	derivative := func(a float64) (float64, float64) {
+		ex, ey := -rx*math.Sin(a), ry*math.Cos(a)
+		return cosPhi*ex - sinPhi*ey, sinPhi*ex + cosPhi*ey
+	}
+
+	a0 := theta
+	for i := 0; i < n; i++ {
+		a1 := a0 + step
+		px0, py0 := point(a0)
+		px1, py1 := point(a1)
+		dx0, dy0 := derivative(a0)
+		dx1, dy1 := derivative(a1)
+		if i == n-1 {
+			px1, py1 = x, y
+		}
+		p.curveTo(px0+k*dx0, py0+k*dy0, px1-k*dx1, py1-k*dy1, px1, py1)
+		a0 = a1
+	}
+}
+
+// transform returns the path, having its points transformed using the
+// specified matrix.
+func (p svgPath) transform(m transform.Matrix) svgPath {
+	out := make(svgPath, len(p))
+	for i, seg := range p {
+		out[i].op = seg.op
+		for j, pt := range seg.pts {
+			out[i].pts[j].X, out[i].pts[j].Y = m.Transform(pt.X, pt.Y)
+		}
+	}
+	return out
+}
+
+// bounds returns the bounding box of the path. The control points of the
+// curves are included, so the result may be larger than the exact bounds.
+func (p svgPath) bounds() (svgRect, bool) {
+	var r svgRect
+	found := false
+	for _, seg := range p {
+		n := 0
+		switch seg.op {
+		case 'M', 'L':
+			n = 1
+		case 'C':
+			n = 3
+		}
+		for _, pt := range seg.pts[:n] {
+			r, found = r.include(pt.X, pt.Y, found), true
+		}
+	}
+	return r, found
+}
+
+// draw appends the path construction operators of the path to the specified
+// content creator.
+func (p svgPath) draw(cc *contentstream.ContentCreator) {
+	for _, seg := range p {
+		switch seg.op {
+		case 'M':
+			cc.Add_m(seg.pts[0].X, seg.pts[0].Y)
+		case 'L':
+			cc.Add_l(seg.pts[0].X, seg.pts[0].Y)
+		case 'C':
+			cc.Add_c(seg.pts[0].X, seg.pts[0].Y, seg.pts[1].X, seg.pts[1].Y, seg.pts[2].X, seg.pts[2].Y)
+		case 'Z':
+			cc.Add_h()
+		}
+	}
+}
+
+// svgRect represents a rectangle in SVG user space.
+type svgRect struct {
+	X, Y, W, H float64
+}
+
+// include returns the smallest rectangle containing the rectangle and the
+// specified point. If `valid` is false, the rectangle is considered empty.
+func (r svgRect) include(x, y float64, valid bool) svgRect {
+	if !valid {
+		return svgRect{X: x, Y: y}
+	}
+	x0, y0 := math.Min(r.X, x), math.Min(r.Y, y)
+	x1, y1 := math.Max(r.X+r.W, x), math.Max(r.Y+r.H, y)
+	return svgRect{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+}
+
+// union returns the smallest rectangle containing both rectangles.
+func (r svgRect) union(o svgRect) svgRect {
+	return r.include(o.X, o.Y, true).include(o.X+o.W, o.Y+o.H, true)
+}
+
+// transform returns the bounding box of the rectangle, transformed using the
+// specified matrix.
+func (r svgRect) transform(m transform.Matrix) svgRect {
+	var out svgRect
+	for i, c := range [][2]float64{{r.X, r.Y}, {r.X + r.W, r.Y}, {r.X, r.Y + r.H}, {r.X + r.W, r.Y + r.H}} {
+		x, y := m.Transform(c[0], c[1])
+		out = out.include(x, y, i > 0)
+	}
+	return out
+}
+
+// expand returns the rectangle, grown by `d` on all sides.
+func (r svgRect) expand(d float64) svgRect {
+	return svgRect{X: r.X - d, Y: r.Y - d, W: r.W + 2*d, H: r.H + 2*d}
+}
+
+// bboxMatrix returns the matrix mapping the unit square onto the rectangle,
+// used for resolving objectBoundingBox units.
+func (r svgRect) bboxMatrix() transform.Matrix {
+	return transform.NewMatrix(r.W, 0, 0, r.H, r.X, r.Y)
+}
+
+// svgPathScanner tokenizes SVG path data and point lists.
+type svgPathScanner struct {
+	data string
+	pos  int
+}
+
+// skipSeparators skips whitespace and commas.
+func (s *svgPathScanner) skipSeparators() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r', '\f', ',':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// done returns true if there is no more data to scan.
+func (s *svgPathScanner) done() bool {
+	s.skipSeparators()
+	return s.pos >= len(s.data)
+}
+
+// hasNumber returns true if the next token is a number.
+func (s *svgPathScanner) hasNumber() bool {
+	if s.done() {
+		return false
+	}
+	c := s.data[s.pos]
+	return c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9')
+}
+
+// number scans the next number. Numbers can be adjacent, as long as they
+// can be told apart (e.g. "1-2" or "0.5.5").
+func (s *svgPathScanner) number() (float64, bool) {
+	if !s.hasNumber() {
+		return 0, false
+	}
+	start := s.pos
+	if c := s.data[s.pos]; c == '-' || c == '+' {
+		s.pos++
+	}
+	digits, dot := false, false
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c == '.' && !dot:
+			dot = true
+		case (c == 'e' || c == 'E') && digits:
+			// Only treat as exponent if followed by digits.
+			next := s.pos + 1
+			if next < len(s.data) && (s.data[next] == '-' || s.data[next] == '+') {
+				next++
+			}
+			if next >= len(s.data) || s.data[next] < '0' || s.data[next] > '9' {
+				return parseSVGFloat(s.data[start:s.pos])
+			}
+			s.pos = next
+			for s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+				s.pos++
+			}
+			return parseSVGFloat(s.data[start:s.pos])
+		default:
+			return parseSVGFloat(s.data[start:s.pos])
+		}
+		s.pos++
+	}
+	return parseSVGFloat(s.data[start:s.pos])
+}
+
+// flag scans the next arc flag, which can be adjacent to the next token.
+func (s *svgPathScanner) flag() (bool, bool) {
+	if s.done() {
+		return false, false
+	}
+	c := s.data[s.pos]
+	if c != '0' && c != '1' {
+		return false, false
+	}
+	s.pos++
+	return c == '1', true
+}
+
+// parseSVGPoints parses the points attribute of polyline and polygon elements.
+func parseSVGPoints(data string) []transform.Point {
+	s := &svgPathScanner{data: data}
+	var points []transform.Point
+	for {
+		x, ok := s.number()
+		if !ok {
+			break
+		}
+		y, ok := s.number()
+		if !ok {
+			break
+		}
+		points = append(points, transform.Point{X: x, Y: y})
+	}
+	return points
+}
+
+// parseSVGPathData parses the data of an SVG path element. As required by the
+// SVG specification, parsing stops at the first error, and the path parsed so
+// far is returned.
+func parseSVGPathData(data string) svgPath {
+	s := &svgPathScanner{data: data}
+	var (
+		p           svgPath
+		cmd         byte
+		cx, cy      float64 // Current point.
+		sx, sy      float64 // Start of the current subpath.
+		qx, qy      float64 // Last control point of quadratic curves.
+		kx, ky      float64 // Last control point of cubic curves.
+		prev        byte
+		started     bool
+		numbersRead = func(vals ...*float64) bool {
+			for _, v := range vals {
+				n, ok := s.number()
+				if !ok {
+					return false
+				}
+				*v = n
+			}
+			return true
+		}
+	)
+
+	for !s.done() {
+		c := s.data[s.pos]
+		if strings.IndexByte("MmZzLlHhVvCcSsQqTtAa", c) >= 0 {
+			cmd = c
+			s.pos++
+		} else if cmd == 0 || !s.hasNumber() {
+			break
+		} else if cmd == 'M' {
+			// Subsequent coordinate pairs of move to commands are line to.
+			cmd = 'L'
+		} else if cmd == 'm' {
+			cmd = 'l'
+		}
+		if !started && cmd != 'M' && cmd != 'm' {
+			break
+		}
+
+		rel := cmd >= 'a'
+		ox, oy := 0.0, 0.0
+		if rel {
+			ox, oy = cx, cy
+		}
+
+		var a [7]float64
+		switch cmd | 0x20 {
+		case 'z':
+			p.closePath()
+			cx, cy = sx, sy
+			// A close path command can be followed by any command, so
+			// clear the current command in order to avoid repeating it.
+			prev, cmd = 'z', 0
+			if !s.done() && s.hasNumber() {
+				return p
+			}
+			continue
+		case 'm':
+			if !numbersRead(&a[0], &a[1]) {
+				return p
+			}
+			cx, cy = ox+a[0], oy+a[1]
+			sx, sy = cx, cy
+			p.moveTo(cx, cy)
+			started = true
+		case 'l':
+			if !numbersRead(&a[0], &a[1]) {
+				return p
+			}
+			cx, cy = ox+a[0], oy+a[1]
+			p.lineTo(cx, cy)
+		case 'h':
+			if !numbersRead(&a[0]) {
+				return p
+			}
+			cx = ox + a[0]
+			p.lineTo(cx, cy)
+		case 'v':
+			if !numbersRead(&a[0]) {
+				return p
+			}
+			if rel {
+				cy += a[0]
+			} else {
+				cy = a[0]
+			}
+			p.lineTo(cx, cy)
+		case 'c':
+			if !numbersRead(&a[0], &a[1], &a[2], &a[3], &a[4], &a[5]) {
+				return p
+			}
+			kx, ky = ox+a[2], oy+a[3]
+			p.curveTo(ox+a[0], oy+a[1], kx, ky, ox+a[4], oy+a[5])
+			cx, cy = ox+a[4], oy+a[5]
+		case 's':
+			if !numbersRead(&a[0], &a[1], &a[2], &a[3]) {
+				return p
+			}
+			x1, y1 := cx, cy
+			if prev == 'c' || prev == 's' {
+				x1, y1 = 2*cx-kx, 2*cy-ky
+			}
+			kx, ky = ox+a[0], oy+a[1]
+			p.curveTo(x1, y1, kx, ky, ox+a[2], oy+a[3])
+			cx, cy = ox+a[2], oy+a[3]
+		case 'q':
+			if !numbersRead(&a[0], &a[1], &a[2], &a[3]) {
+				return p
+			}
+			qx, qy = ox+a[0], oy+a[1]
+			x, y := ox+a[2], oy+a[3]
+			p.curveTo(cx+2*(qx-cx)/3, cy+2*(qy-cy)/3, x+2*(qx-x)/3, y+2*(qy-y)/3, x, y)
+			cx, cy = x, y
+		case 't':
+			if !numbersRead(&a[0], &a[1]) {
+				return p
+			}
+			if prev == 'q' || prev == 't' {
+				qx, qy = 2*cx-qx, 2*cy-qy
+			} else {
+				qx, qy = cx, cy
+			}
+			x, y := ox+a[0], oy+a[1]
+			p.curveTo(cx+2*(qx-cx)/3, cy+2*(qy-cy)/3, x+2*(qx-x)/3, y+2*(qy-y)/3, x, y)
+			cx, cy = x, y
+		case 'a':
+			if !numbersRead(&a[0], &a[1], &a[2]) {
+				return p
+			}
+			large, ok := s.flag()
+			if !ok {
+				return p
+			}
+			sweep, ok := s.flag()
+			if !ok || !numbersRead(&a[5], &a[6]) {
+				return p
+			}
+			x, y := ox+a[5], oy+a[6]
+			p.arcTo(cx, cy, a[0], a[1], a[2], large, sweep, x, y)
+			cx, cy = x, y
+		}
+		prev = cmd | 0x20
+	}
+	return p
+}
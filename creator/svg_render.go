@@ -0,0 +1,1984 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/internal/transform"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// SetFont sets the font used for rendering the text of the SVG elements
+// having the specified font family. The bold and italic flags specify the
+// font variant the font is used for. If no font is registered for a variant,
+// the regular font of the family is used. Text using font families without
+// registered fonts is rendered using the closest standard 14 font.
+func (e *GraphicSVGElement) SetFont(family string, bold, italic bool, font *model.PdfFont) {
+	if e._fonts == nil {
+		e._fonts = map[string]*model.PdfFont{}
+	}
+	e._fonts[svgFontKey(family, bold, italic)] = font
+}
+
+// SetFont sets the font used for rendering the text of the SVG elements
+// having the specified font family. See GraphicSVGElement.SetFont.
+func (g *GraphicSVG) SetFont(family string, bold, italic bool, font *model.PdfFont) {
+	g._gdbd.SetFont(family, bold, italic, font)
+}
+
+// svgFontKey returns the key of the registered font having the specified
+// family and variant.
+func svgFontKey(family string, bold, italic bool) string {
+	family = strings.ToLower(strings.Trim(strings.TrimSpace(family), `"'`))
+	return fmt.Sprintf("%s|%t|%t", family, bold, italic)
+}
+
+// renderSVGDocument renders the specified svg root element onto the
+// specified content creator. The document is fitted into the rectangle
+// defined by its width and height, expressed in points.
+func renderSVGDocument(root *GraphicSVGElement, cc *contentstream.ContentCreator, res *model.PdfPageResources) {
+	par := root.Attributes["preserveAspectRatio"]
+	if _, ok := parseSVGViewBox(root.Attributes["viewBox"]); !ok {
+		// The user space of documents without a viewBox is scaled
+		// along with the width and height of the document.
+		par = "none"
+	}
+	viewport := svgRect{W: root.Width, H: root.Height}
+	newSVGRenderer(root, root._fonts).render(newSVGCanvas(cc, res), viewport, par)
+}
+
+// svgCanvas represents the content stream SVG elements are rendered to,
+// along with the resources the content stream refers to.
+type svgCanvas struct {
+	cc     *contentstream.ContentCreator
+	res    *model.PdfPageResources
+	fonts  map[*model.PdfFont]core.PdfObjectName
+	states map[string]core.PdfObjectName
+}
+
+// newSVGCanvas returns a new canvas wrapping the specified content creator
+// and resources.
+func newSVGCanvas(cc *contentstream.ContentCreator, res *model.PdfPageResources) *svgCanvas {
+	return &svgCanvas{
+		cc:     cc,
+		res:    res,
+		fonts:  map[*model.PdfFont]core.PdfObjectName{},
+		states: map[string]core.PdfObjectName{},
+	}
+}
+
+// newSVGFormCanvas returns a new canvas, used for generating the content of
+// a form XObject.
+func newSVGFormCanvas() *svgCanvas {
+	return newSVGCanvas(contentstream.NewContentCreator(), model.NewPdfPageResources())
+}
+
+// name returns the first resource name starting with the specified prefix,
+// which is not in use.
+func (cv *svgCanvas) name(prefix string, exists func(core.PdfObjectName) bool) core.PdfObjectName {
+	for i := 1; ; i++ {
+		name := core.PdfObjectName(fmt.Sprintf("%s%d", prefix, i))
+		if !exists(name) {
+			return name
+		}
+	}
+}
+
+// addExtGState adds the specified graphics state parameter dictionary to the
+// resources of the canvas and returns its name.
+func (cv *svgCanvas) addExtGState(gs *core.PdfObjectDictionary) core.PdfObjectName {
+	// HasExtGState looks up the fonts of the resources, so the graphics
+	// states are looked up directly.
+	name := cv.name("SvgGS", func(name core.PdfObjectName) bool {
+		_, ok := cv.res.GetExtGState(name)
+		return ok
+	})
+	cv.res.AddExtGState(name, gs)
+	return name
+}
+
+// addFont adds the specified font to the resources of the canvas and
+// returns its name.
+func (cv *svgCanvas) addFont(font *model.PdfFont) core.PdfObjectName {
+	if name, ok := cv.fonts[font]; ok {
+		return name
+	}
+	name := cv.name("SvgF", cv.res.HasFontByName)
+	cv.res.SetFontByName(name, font.ToPdfObject())
+	cv.fonts[font] = name
+	return name
+}
+
+// addForm adds the specified form XObject to the resources of the canvas
+// and returns its name.
+func (cv *svgCanvas) addForm(form *model.XObjectForm) core.PdfObjectName {
+	name := cv.name("SvgFm", cv.res.HasXObjectByName)
+	cv.res.SetXObjectFormByName(name, form)
+	return name
+}
+
+// addImage adds the specified image XObject to the resources of the canvas
+// and returns its name.
+func (cv *svgCanvas) addImage(img *model.XObjectImage) core.PdfObjectName {
+	name := cv.name("SvgIm", cv.res.HasXObjectByName)
+	cv.res.SetXObjectImageByName(name, img)
+	return name
+}
+
+// addShading adds the specified shading to the resources of the canvas and
+// returns its name.
+func (cv *svgCanvas) addShading(shading core.PdfObject) core.PdfObjectName {
+	name := cv.name("SvgSh", cv.res.HasShadingByName)
+	cv.res.SetShadingByName(name, shading)
+	return name
+}
+
+// addPattern adds the specified pattern to the resources of the canvas and
+// returns its name.
+func (cv *svgCanvas) addPattern(pattern core.PdfObject) core.PdfObjectName {
+	name := cv.name("SvgP", cv.res.HasPatternByName)
+	cv.res.SetPatternByName(name, pattern)
+	return name
+}
+
+// setAlpha sets the fill and stroke constant alpha of the graphics state.
+func (cv *svgCanvas) setAlpha(fill, stroke float64) {
+	if fill >= 1 && stroke >= 1 {
+		return
+	}
+	key := fmt.Sprintf("%.4f/%.4f", fill, stroke)
+	name, ok := cv.states[key]
+	if !ok {
+		gs := core.MakeDict()
+		gs.Set("ca", core.MakeFloat(fill))
+		gs.Set("CA", core.MakeFloat(stroke))
+		name = cv.addExtGState(gs)
+		cv.states[key] = name
+	}
+	cv.cc.Add_gs(name)
+}
+
+// setSoftMask sets the soft mask of the graphics state to the specified
+// form XObject. The luminosity or the alpha of the form is used as mask
+// values.
+func (cv *svgCanvas) setSoftMask(form *model.XObjectForm, luminosity bool, alpha float64) {
+	subtype := "Alpha"
+	if luminosity {
+		subtype = "Luminosity"
+	}
+	mask := core.MakeDict()
+	mask.Set("Type", core.MakeName("Mask"))
+	mask.Set("S", core.MakeName(subtype))
+	mask.Set("G", form.ToPdfObject())
+
+	gs := core.MakeDict()
+	gs.Set("SMask", mask)
+	if alpha < 1 {
+		gs.Set("ca", core.MakeFloat(alpha))
+		gs.Set("CA", core.MakeFloat(alpha))
+	}
+	cv.cc.Add_gs(cv.addExtGState(gs))
+}
+
+// form returns a form XObject having the content of the canvas. If `group`
+// is true, the form is a transparency group using the specified color space.
+func (cv *svgCanvas) form(bbox svgRect, group bool, colorspace string) (*model.XObjectForm, error) {
+	form := model.NewXObjectForm()
+	form.BBox = core.MakeArrayFromFloats([]float64{bbox.X, bbox.Y, bbox.X + bbox.W, bbox.Y + bbox.H})
+	form.Resources = cv.res
+	if group {
+		g := core.MakeDict()
+		g.Set("Type", core.MakeName("Group"))
+		g.Set("S", core.MakeName("Transparency"))
+		g.Set("CS", core.MakeName(colorspace))
+		form.Group = g
+	}
+	if err := form.SetContentStream(cv.cc.Operations().Bytes(), core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+// tilingPattern returns a colored tiling pattern having the content of the
+// canvas as pattern cell. The cell covers the specified rectangle, which is
+// mapped to the default space of the parent content stream using `m`.
+func (cv *svgCanvas) tilingPattern(cell svgRect, m transform.Matrix) (*core.PdfObjectStream, error) {
+	stream, err := core.MakeStream(cv.cc.Operations().Bytes(), core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	dict := stream.PdfObjectDictionary
+	dict.Set("Type", core.MakeName("Pattern"))
+	dict.Set("PatternType", core.MakeInteger(1))
+	dict.Set("PaintType", core.MakeInteger(1))
+	dict.Set("TilingType", core.MakeInteger(1))
+	dict.Set("BBox", core.MakeArrayFromFloats([]float64{cell.X, cell.Y, cell.X + cell.W, cell.Y + cell.H}))
+	dict.Set("XStep", core.MakeFloat(cell.W))
+	dict.Set("YStep", core.MakeFloat(cell.H))
+	dict.Set("Resources", cv.res.ToPdfObject())
+	dict.Set("Matrix", core.MakeArrayFromFloats([]float64{m[0], m[1], m[3], m[4], m[6], m[7]}))
+	return stream, nil
+}
+
+// svgConcat concatenates the specified matrix to the current transformation
+// matrix.
+func svgConcat(cc *contentstream.ContentCreator, m transform.Matrix) {
+	if m.Identity() {
+		return
+	}
+	cc.Add_cm(m[0], m[1], m[3], m[4], m[6], m[7])
+}
+
+// svgContext holds the state used for rendering an SVG element.
+type svgContext struct {
+	canvas    *svgCanvas
+	style     *svgStyle
+	ancestors []*GraphicSVGElement
+	vw, vh    float64
+
+	// alpha is the opacity of the closest ancestors, applied directly to
+	// the painted elements instead of using a transparency group.
+	alpha float64
+}
+
+// newSVGContext returns a new context for rendering the descendants of the
+// specified element.
+func newSVGContext(cv *svgCanvas, e *GraphicSVGElement, style *svgStyle, vw, vh float64) svgContext {
+	return svgContext{
+		canvas:    cv,
+		style:     style,
+		ancestors: []*GraphicSVGElement{e},
+		vw:        vw,
+		vh:        vh,
+		alpha:     1,
+	}
+}
+
+// child returns the context of the specified child element, having the
+// specified computed style.
+func (ctx svgContext) child(e *GraphicSVGElement, style *svgStyle) svgContext {
+	ancestors := make([]*GraphicSVGElement, len(ctx.ancestors), len(ctx.ancestors)+1)
+	copy(ancestors, ctx.ancestors)
+	ctx.ancestors = append(ancestors, e)
+	ctx.style = style
+	return ctx
+}
+
+// lengthValue resolves the specified length. Percentages are resolved
+// relative to the viewport width ('x' axis), height ('y' axis) or diagonal.
+func (ctx svgContext) lengthValue(s string, axis byte, def float64) float64 {
+	ref := math.Sqrt((ctx.vw*ctx.vw + ctx.vh*ctx.vh) / 2)
+	switch axis {
+	case 'x':
+		ref = ctx.vw
+	case 'y':
+		ref = ctx.vh
+	}
+	v, ok := parseSVGLength(s, ref, ctx.style.fontSize())
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// length resolves the specified length attribute of the element.
+func (ctx svgContext) length(e *GraphicSVGElement, name string, axis byte, def float64) float64 {
+	v, ok := e.Attributes[name]
+	if !ok {
+		return def
+	}
+	return ctx.lengthValue(v, axis, def)
+}
+
+// lengths resolves the specified attribute of the element as a list of
+// lengths.
+func (ctx svgContext) lengths(e *GraphicSVGElement, name string, axis byte) []float64 {
+	var vals []float64
+	for _, f := range strings.FieldsFunc(e.Attributes[name], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}) {
+		vals = append(vals, ctx.lengthValue(f, axis, 0))
+	}
+	return vals
+}
+
+// currentColor returns the value of the color property.
+func (ctx svgContext) currentColor() svgColor {
+	c, ok := parseSVGColor(ctx.style.get("color"), svgColor{a: 1})
+	if !ok {
+		return svgColor{a: 1}
+	}
+	return c
+}
+
+// parseSVGViewBox parses the specified viewBox attribute.
+func parseSVGViewBox(s string) (svgRect, bool) {
+	vals := parseSVGNumbers(s)
+	if len(vals) != 4 || vals[2] <= 0 || vals[3] <= 0 {
+		return svgRect{}, false
+	}
+	return svgRect{X: vals[0], Y: vals[1], W: vals[2], H: vals[3]}, true
+}
+
+// svgViewBoxTransform returns the matrix mapping the specified viewBox onto
+// a viewport of the specified size, according to the preserveAspectRatio
+// attribute `par`.
+func svgViewBoxTransform(vb svgRect, par string, w, h float64) transform.Matrix {
+	if vb.W <= 0 || vb.H <= 0 {
+		return transform.IdentityMatrix()
+	}
+	fields := strings.Fields(par)
+	if len(fields) > 0 && fields[0] == "defer" {
+		fields = fields[1:]
+	}
+	align, slice := "xMidYMid", false
+	if len(fields) > 0 {
+		align = fields[0]
+	}
+	if len(fields) > 1 {
+		slice = fields[1] == "slice"
+	}
+
+	sx, sy := w/vb.W, h/vb.H
+	tx, ty := 0.0, 0.0
+	if align != "none" {
+		s := math.Min(sx, sy)
+		if slice {
+			s = math.Max(sx, sy)
+		}
+		sx, sy = s, s
+		ex, ey := w-vb.W*s, h-vb.H*s
+		switch {
+		case strings.Contains(align, "xMid"):
+			tx = ex / 2
+		case strings.Contains(align, "xMax"):
+			tx = ex
+		}
+		switch {
+		case strings.Contains(align, "YMid"):
+			ty = ey / 2
+		case strings.Contains(align, "YMax"):
+			ty = ey
+		}
+	}
+	return transform.TranslationMatrix(tx, ty).Mult(transform.ScaleMatrix(sx, sy)).Mult(transform.TranslationMatrix(-vb.X, -vb.Y))
+}
+
+// svgRenderer renders SVG documents onto PDF content streams.
+type svgRenderer struct {
+	root      *GraphicSVGElement
+	rootStyle *svgStyle
+	ids       map[string]*GraphicSVGElement
+	sheet     *svgStyleSheet
+	fonts     map[string]*model.PdfFont
+	stdFonts  map[model.StdFontName]*model.PdfFont
+
+	// active contains the referenced elements being rendered, used for
+	// detecting circular references.
+	active map[*GraphicSVGElement]bool
+}
+
+// newSVGRenderer returns a new renderer for the specified svg root element,
+// using the specified registered fonts.
+func newSVGRenderer(root *GraphicSVGElement, fonts map[string]*model.PdfFont) *svgRenderer {
+	r := &svgRenderer{
+		root:     root,
+		ids:      map[string]*GraphicSVGElement{},
+		sheet:    &svgStyleSheet{},
+		fonts:    fonts,
+		stdFonts: map[model.StdFontName]*model.PdfFont{},
+		active:   map[*GraphicSVGElement]bool{},
+	}
+	r.index(root)
+	return r
+}
+
+// index collects the IDs and the style sheets of the specified element and
+// of its descendants.
+func (r *svgRenderer) index(e *GraphicSVGElement) {
+	if e == nil {
+		return
+	}
+	if id := e.Attributes["id"]; id != "" {
+		if _, ok := r.ids[id]; !ok {
+			r.ids[id] = e
+		}
+	}
+	if e.Name == "style" {
+		r.sheet.add(svgElementText(e))
+	}
+	for _, c := range e.Children {
+		r.index(c)
+	}
+}
+
+// svgElementText returns the character data of the specified element.
+func svgElementText(e *GraphicSVGElement) string {
+	var sb strings.Builder
+	for _, c := range e.Children {
+		if c.Name == "#text" {
+			sb.WriteString(c.Content)
+		}
+	}
+	if sb.Len() == 0 {
+		return e.Content
+	}
+	return sb.String()
+}
+
+// render renders the document, fitting it into the specified viewport.
+func (r *svgRenderer) render(cv *svgCanvas, viewport svgRect, par string) {
+	root := r.root
+	if viewport.W <= 0 || viewport.H <= 0 {
+		return
+	}
+	vb, ok := parseSVGViewBox(root.Attributes["viewBox"])
+	if !ok {
+		vb = svgRect{W: root.ViewBox.W / _degac, H: root.ViewBox.H / _degac}
+	}
+	style := r.sheet.computeStyle(root, nil, nil)
+	r.rootStyle = style
+
+	cc := cv.cc
+	cc.Add_q()
+	if overflow := style.get("overflow"); overflow != "visible" && overflow != "auto" {
+		cc.Add_re(viewport.X, viewport.Y, viewport.W, viewport.H)
+		cc.Add_W()
+		cc.Add_n()
+	}
+	m := transform.TranslationMatrix(viewport.X, viewport.Y)
+	svgConcat(cc, m.Mult(svgViewBoxTransform(vb, par, viewport.W, viewport.H)))
+
+	ctx := newSVGContext(cv, root, style, vb.W, vb.H)
+	r.renderWithEffects(ctx, root, func(ctx svgContext) {
+		r.renderChildren(ctx, root)
+	})
+	cc.Add_Q()
+}
+
+// svgRenderable specifies whether the element having the specified name is
+// rendered directly, as opposed to being referenced.
+func svgRenderable(name string) bool {
+	switch name {
+	case "g", "a", "switch", "svg", "use", "text", "image",
+		"rect", "circle", "ellipse", "line", "polyline", "polygon", "path":
+		return true
+	}
+	return false
+}
+
+// renderChildren renders the children of the specified container element.
+func (r *svgRenderer) renderChildren(ctx svgContext, e *GraphicSVGElement) {
+	for _, c := range e.Children {
+		r.renderElement(ctx, c)
+	}
+}
+
+// renderElement renders the specified element. The context is the context
+// of its parent.
+func (r *svgRenderer) renderElement(ctx svgContext, e *GraphicSVGElement) {
+	if !svgRenderable(e.Name) {
+		return
+	}
+	style := r.sheet.computeStyle(e, ctx.style, ctx.ancestors)
+	if style.get("display") == "none" {
+		return
+	}
+	r.renderWithEffects(ctx.child(e, style), e, func(ctx svgContext) {
+		r.renderContent(ctx, e)
+	})
+}
+
+// renderContent renders the content of the specified element, ignoring its
+// transform and effects.
+func (r *svgRenderer) renderContent(ctx svgContext, e *GraphicSVGElement) {
+	switch e.Name {
+	case "g", "a":
+		r.renderChildren(ctx, e)
+	case "switch":
+		for _, c := range e.Children {
+			if svgRenderable(c.Name) {
+				r.renderElement(ctx, c)
+				break
+			}
+		}
+	case "svg":
+		r.renderViewport(ctx, e, nil)
+	case "use":
+		r.renderUse(ctx, e)
+	case "text":
+		r.renderText(ctx, e)
+	case "image":
+		r.renderImage(ctx, e)
+	default:
+		r.renderShape(ctx, e)
+	}
+}
+
+// renderWithEffects renders the element using the specified function,
+// applying its transform, clipping path, mask and opacity.
+func (r *svgRenderer) renderWithEffects(ctx svgContext, e *GraphicSVGElement, draw func(svgContext)) {
+	m := parseSVGTransform(e.Attributes["transform"])
+	clipRef, _ := parseSVGURL(ctx.style.get("clip-path"))
+	maskRef, _ := parseSVGURL(ctx.style.get("mask"))
+	opacity := ctx.style.opacity("opacity")
+	if opacity <= 0 {
+		return
+	}
+	if m.Identity() && clipRef == "" && maskRef == "" && opacity >= 1 {
+		draw(ctx)
+		return
+	}
+
+	cc := ctx.canvas.cc
+	cc.Add_q()
+	defer cc.Add_Q()
+	svgConcat(cc, m)
+
+	var bbox svgRect
+	var hasBBox bool
+	if clipRef != "" || maskRef != "" {
+		bbox, hasBBox = r.bounds(ctx, e, false)
+	}
+	if clipRef != "" {
+		r.applyClipPath(ctx, clipRef, bbox, hasBBox)
+	}
+	if maskRef != "" {
+		r.applyMask(ctx, maskRef, bbox, hasBBox)
+	}
+	if opacity >= 1 {
+		draw(ctx)
+		return
+	}
+	if svgPaintsOnce(e, ctx) {
+		ctx.alpha *= opacity
+		draw(ctx)
+		return
+	}
+
+	// Render the element as a transparency group.
+	region, ok := r.bounds(ctx, e, true)
+	if !ok {
+		return
+	}
+	cv := newSVGFormCanvas()
+	gctx := ctx
+	gctx.canvas, gctx.alpha = cv, 1
+	draw(gctx)
+	form, err := cv.form(region, true, "DeviceRGB")
+	if err != nil {
+		common.Log.Debug("Unable to create SVG transparency group: %v", err)
+		return
+	}
+	ctx.canvas.setAlpha(opacity, opacity)
+	cc.Add_Do(ctx.canvas.addForm(form))
+}
+
+// svgPaintsOnce returns true if the specified element is painted using a
+// single painting operation, in which case its opacity can be applied
+// directly, without using a transparency group.
+func svgPaintsOnce(e *GraphicSVGElement, ctx svgContext) bool {
+	switch e.Name {
+	case "image":
+		return true
+	case "rect", "circle", "ellipse", "line", "polyline", "polygon", "path", "text":
+		fill := parseSVGPaint(ctx.style.get("fill"), svgColor{})
+		stroke := parseSVGPaint(ctx.style.get("stroke"), svgColor{})
+		return fill.none || stroke.none
+	}
+	return false
+}
+
+// renderViewport renders a nested svg element, or a symbol instantiated by
+// the specified use element, establishing a new viewport.
+func (r *svgRenderer) renderViewport(ctx svgContext, e, use *GraphicSVGElement) {
+	x, y := 0.0, 0.0
+	if e.Name == "svg" {
+		x, y = ctx.length(e, "x", 'x', 0), ctx.length(e, "y", 'y', 0)
+	}
+	w, h := ctx.length(e, "width", 'x', ctx.vw), ctx.length(e, "height", 'y', ctx.vh)
+	if use != nil {
+		w, h = ctx.length(use, "width", 'x', w), ctx.length(use, "height", 'y', h)
+	}
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	cc := ctx.canvas.cc
+	cc.Add_q()
+	defer cc.Add_Q()
+	if overflow := ctx.style.get("overflow"); overflow != "visible" && overflow != "auto" {
+		cc.Add_re(x, y, w, h)
+		cc.Add_W()
+		cc.Add_n()
+	}
+	m := transform.TranslationMatrix(x, y)
+	ctx.vw, ctx.vh = w, h
+	if vb, ok := parseSVGViewBox(e.Attributes["viewBox"]); ok {
+		m = m.Mult(svgViewBoxTransform(vb, e.Attributes["preserveAspectRatio"], w, h))
+		ctx.vw, ctx.vh = vb.W, vb.H
+	}
+	svgConcat(cc, m)
+	r.renderChildren(ctx, e)
+}
+
+// renderUse renders the element referenced by the specified use element.
+func (r *svgRenderer) renderUse(ctx svgContext, e *GraphicSVGElement) {
+	ref := r.ids[svgHref(e)]
+	if ref == nil || ref == e || r.active[e] {
+		return
+	}
+	r.active[e] = true
+	defer delete(r.active, e)
+
+	cc := ctx.canvas.cc
+	cc.Add_q()
+	defer cc.Add_Q()
+	svgConcat(cc, transform.TranslationMatrix(ctx.length(e, "x", 'x', 0), ctx.length(e, "y", 'y', 0)))
+
+	switch ref.Name {
+	case "symbol", "svg":
+		style := r.sheet.computeStyle(ref, ctx.style, ctx.ancestors)
+		if style.get("display") == "none" {
+			return
+		}
+		r.renderWithEffects(ctx.child(ref, style), ref, func(ctx svgContext) {
+			r.renderViewport(ctx, ref, e)
+		})
+	default:
+		r.renderElement(ctx, ref)
+	}
+}
+
+// shapePath returns the geometry of the specified basic shape or path.
+func (r *svgRenderer) shapePath(ctx svgContext, e *GraphicSVGElement) svgPath {
+	var p svgPath
+	switch e.Name {
+	case "rect":
+		x, y := ctx.length(e, "x", 'x', 0), ctx.length(e, "y", 'y', 0)
+		w, h := ctx.length(e, "width", 'x', 0), ctx.length(e, "height", 'y', 0)
+		if w <= 0 || h <= 0 {
+			return nil
+		}
+		rx, ry := ctx.length(e, "rx", 'x', -1), ctx.length(e, "ry", 'y', -1)
+		switch {
+		case rx < 0 && ry < 0:
+			rx, ry = 0, 0
+		case rx < 0:
+			rx = ry
+		case ry < 0:
+			ry = rx
+		}
+		p.roundedRect(x, y, w, h, math.Min(rx, w/2), math.Min(ry, h/2))
+	case "circle":
+		rad := ctx.length(e, "r", 'd', 0)
+		if rad <= 0 {
+			return nil
+		}
+		p.ellipse(ctx.length(e, "cx", 'x', 0), ctx.length(e, "cy", 'y', 0), rad, rad)
+	case "ellipse":
+		rx, ry := ctx.length(e, "rx", 'x', 0), ctx.length(e, "ry", 'y', 0)
+		if rx <= 0 || ry <= 0 {
+			return nil
+		}
+		p.ellipse(ctx.length(e, "cx", 'x', 0), ctx.length(e, "cy", 'y', 0), rx, ry)
+	case "line":
+		p.moveTo(ctx.length(e, "x1", 'x', 0), ctx.length(e, "y1", 'y', 0))
+		p.lineTo(ctx.length(e, "x2", 'x', 0), ctx.length(e, "y2", 'y', 0))
+	case "polyline", "polygon":
+		pts := parseSVGPoints(e.Attributes["points"])
+		if len(pts) < 2 {
+			return nil
+		}
+		p.moveTo(pts[0].X, pts[0].Y)
+		for _, pt := range pts[1:] {
+			p.lineTo(pt.X, pt.Y)
+		}
+		if e.Name == "polygon" {
+			p.closePath()
+		}
+	case "path":
+		p = parseSVGPathData(e.Attributes["d"])
+	}
+	return p
+}
+
+// renderShape renders the specified basic shape or path.
+func (r *svgRenderer) renderShape(ctx svgContext, e *GraphicSVGElement) {
+	if ctx.style.get("visibility") != "visible" {
+		return
+	}
+	p := r.shapePath(ctx, e)
+	if len(p) == 0 {
+		return
+	}
+	style, current := ctx.style, ctx.currentColor()
+	bbox, hasBBox := p.bounds()
+	fill, server := r.resolvePaint(parseSVGPaint(style.get("fill"), current))
+	alpha := style.opacity("fill-opacity") * ctx.alpha
+	if server != nil {
+		r.fillWithServer(ctx, server, bbox, hasBBox, bbox, alpha, func() {
+			p.draw(ctx.canvas.cc)
+			if style.get("fill-rule") == "evenodd" {
+				ctx.canvas.cc.Add_W_starred()
+			} else {
+				ctx.canvas.cc.Add_W()
+			}
+			ctx.canvas.cc.Add_n()
+		})
+	} else if !fill.none && fill.color.a*alpha > 0 {
+		cc := ctx.canvas.cc
+		cc.Add_q()
+		ctx.canvas.setAlpha(fill.color.a*alpha, 1)
+		cc.Add_rg(fill.color.r, fill.color.g, fill.color.b)
+		p.draw(cc)
+		if style.get("fill-rule") == "evenodd" {
+			cc.Add_f_starred()
+		} else {
+			cc.Add_f()
+		}
+		cc.Add_Q()
+	}
+
+	if stroke, ok := r.strokeColor(ctx); ok {
+		cc := ctx.canvas.cc
+		cc.Add_q()
+		ctx.canvas.setAlpha(1, stroke.a)
+		cc.Add_RG(stroke.r, stroke.g, stroke.b)
+		r.setLineStyle(ctx)
+		p.draw(cc)
+		cc.Add_S()
+		cc.Add_Q()
+	}
+}
+
+// resolvePaint resolves the paint server referenced by the specified paint.
+// If the paint server does not exist, the fallback paint is returned.
+func (r *svgRenderer) resolvePaint(p svgPaint) (svgPaint, *GraphicSVGElement) {
+	if p.ref == "" {
+		return p, nil
+	}
+	switch server := r.ids[p.ref]; {
+	case server == nil:
+	case server.Name == "linearGradient", server.Name == "radialGradient", server.Name == "pattern":
+		return p, server
+	}
+	if p.fallback != nil {
+		return *p.fallback, nil
+	}
+	return svgPaint{none: true}, nil
+}
+
+// strokeColor returns the stroke color of the element, including the stroke
+// opacity. Strokes painted using gradients are approximated using the
+// average color of the gradient stops.
+func (r *svgRenderer) strokeColor(ctx svgContext) (svgColor, bool) {
+	style := ctx.style
+	paint, server := r.resolvePaint(parseSVGPaint(style.get("stroke"), ctx.currentColor()))
+	if server != nil {
+		c, ok := r.serverColor(server)
+		if !ok {
+			return svgColor{}, false
+		}
+		paint = svgPaint{color: c}
+	}
+	if paint.none || ctx.lengthValue(style.get("stroke-width"), 'd', 1) <= 0 {
+		return svgColor{}, false
+	}
+	c := paint.color
+	c.a *= style.opacity("stroke-opacity") * ctx.alpha
+	return c, c.a > 0
+}
+
+// setLineStyle sets the line width, cap, join, miter limit and dash pattern
+// of the graphics state, according to the stroke properties.
+func (r *svgRenderer) setLineStyle(ctx svgContext) {
+	style, cc := ctx.style, ctx.canvas.cc
+	cc.Add_w(ctx.lengthValue(style.get("stroke-width"), 'd', 1))
+
+	caps := map[string]int64{"butt": 0, "round": 1, "square": 2}
+	joins := map[string]int64{"miter": 0, "miter-clip": 0, "arcs": 0, "round": 1, "bevel": 2}
+	if v := caps[style.get("stroke-linecap")]; v != 0 {
+		cc.AddOperand(contentstream.ContentStreamOperation{Operand: "J", Params: []core.PdfObject{core.MakeInteger(v)}})
+	}
+	if v := joins[style.get("stroke-linejoin")]; v != 0 {
+		cc.AddOperand(contentstream.ContentStreamOperation{Operand: "j", Params: []core.PdfObject{core.MakeInteger(v)}})
+	}
+	if limit := style.number("stroke-miterlimit"); limit >= 1 && limit != 10 {
+		cc.Add_M(limit)
+	}
+
+	dashes := splitSVGList(strings.ReplaceAll(style.get("stroke-dasharray"), " ", ","), ',')
+	var pattern []float64
+	sum := 0.0
+	for _, d := range dashes {
+		if d = strings.TrimSpace(d); d == "" {
+			continue
+		}
+		v := ctx.lengthValue(d, 'd', -1)
+		if v < 0 {
+			return
+		}
+		pattern, sum = append(pattern, v), sum+v
+	}
+	if sum <= 0 {
+		return
+	}
+	if len(pattern)%2 == 1 {
+		pattern = append(pattern, pattern...)
+	}
+	offset := ctx.lengthValue(style.get("stroke-dashoffset"), 'd', 0)
+	cc.AddOperand(contentstream.ContentStreamOperation{
+		Operand: "d",
+		Params:  []core.PdfObject{core.MakeArrayFromFloats(pattern), core.MakeFloat(offset)},
+	})
+}
+
+// fillWithServer fills the area defined by the clipping path set by the
+// specified function using the specified paint server. The `bbox` rectangle
+// is the bounding box of the painted element, while `region` is the area
+// covered by the clipping path.
+func (r *svgRenderer) fillWithServer(ctx svgContext, server *GraphicSVGElement, bbox svgRect, hasBBox bool, region svgRect, alpha float64, clip func()) {
+	if alpha <= 0 {
+		return
+	}
+	cc := ctx.canvas.cc
+	cc.Add_q()
+	defer cc.Add_Q()
+	clip()
+	if server.Name == "pattern" {
+		r.paintPattern(ctx, server, bbox, hasBBox, region, alpha)
+	} else {
+		r.paintGradient(ctx, server, bbox, hasBBox, region, alpha)
+	}
+}
+
+// templateAttr returns the value of the specified attribute of the paint
+// server, following the href references to its template elements.
+func (r *svgRenderer) templateAttr(e *GraphicSVGElement, name string) (string, bool) {
+	seen := map[*GraphicSVGElement]bool{}
+	for e != nil && !seen[e] {
+		seen[e] = true
+		if v, ok := e.Attributes[name]; ok {
+			return v, true
+		}
+		e = r.ids[svgHref(e)]
+	}
+	return "", false
+}
+
+// templateChildren returns the children of the first template element of the
+// paint server having children with the specified names, following the href
+// references.
+func (r *svgRenderer) templateChildren(e *GraphicSVGElement, match func(string) bool) []*GraphicSVGElement {
+	seen := map[*GraphicSVGElement]bool{}
+	for e != nil && !seen[e] {
+		seen[e] = true
+		var children []*GraphicSVGElement
+		for _, c := range e.Children {
+			if match(c.Name) {
+				children = append(children, c)
+			}
+		}
+		if len(children) > 0 {
+			return children
+		}
+		e = r.ids[svgHref(e)]
+	}
+	return nil
+}
+
+// serverStyle returns the computed style of the specified paint server or
+// resource element, which inherits from the root element instead of the
+// referencing element.
+func (r *svgRenderer) serverStyle(e *GraphicSVGElement) *svgStyle {
+	return r.sheet.computeStyle(e, r.rootStyle, nil)
+}
+
+// svgStop represents a gradient stop.
+type svgStop struct {
+	offset float64
+	color  svgColor
+}
+
+// gradientStops returns the stops of the specified gradient.
+func (r *svgRenderer) gradientStops(g *GraphicSVGElement) []svgStop {
+	gstyle := r.serverStyle(g)
+	var stops []svgStop
+	last := 0.0
+	for _, s := range r.templateChildren(g, func(name string) bool { return name == "stop" }) {
+		style := r.sheet.computeStyle(s, gstyle, []*GraphicSVGElement{g})
+		current, ok := parseSVGColor(style.get("color"), svgColor{a: 1})
+		if !ok {
+			current = svgColor{a: 1}
+		}
+
+		offset, ok := 0.0, false
+		if v := strings.TrimSpace(s.Attributes["offset"]); strings.HasSuffix(v, "%") {
+			offset, ok = parseSVGFloat(strings.TrimSuffix(v, "%"))
+			offset /= 100
+		} else {
+			offset, ok = parseSVGFloat(v)
+		}
+		if !ok {
+			offset = 0
+		}
+		offset = math.Max(last, math.Min(offset, 1))
+		last = offset
+
+		c, ok := parseSVGColor(style.get("stop-color"), current)
+		if !ok {
+			c = svgColor{a: 1}
+		}
+		c.a *= style.opacity("stop-opacity")
+		stops = append(stops, svgStop{offset: offset, color: c})
+	}
+	return stops
+}
+
+// serverColor returns the color approximating the specified paint server.
+func (r *svgRenderer) serverColor(server *GraphicSVGElement) (svgColor, bool) {
+	if server.Name == "pattern" {
+		return svgColor{}, false
+	}
+	stops := r.gradientStops(server)
+	if len(stops) == 0 {
+		return svgColor{}, false
+	}
+	var c svgColor
+	for _, s := range stops {
+		c.r, c.g, c.b, c.a = c.r+s.color.r, c.g+s.color.g, c.b+s.color.b, c.a+s.color.a
+	}
+	n := float64(len(stops))
+	return svgColor{r: c.r / n, g: c.g / n, b: c.b / n, a: c.a / n}, true
+}
+
+// svgFraction parses the specified number or percentage, used for
+// coordinates expressed in objectBoundingBox units.
+func svgFraction(s string) float64 {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "%") {
+		v, _ := parseSVGFloat(strings.TrimSuffix(s, "%"))
+		return v / 100
+	}
+	v, _ := parseSVGFloat(s)
+	return v
+}
+
+// fillRegion fills the specified region using the specified color.
+func (r *svgRenderer) fillRegion(ctx svgContext, region svgRect, c svgColor, alpha float64) {
+	cc := ctx.canvas.cc
+	ctx.canvas.setAlpha(c.a*alpha, 1)
+	cc.Add_rg(c.r, c.g, c.b)
+	cc.Add_re(region.X, region.Y, region.W, region.H)
+	cc.Add_f()
+}
+
+// paintGradient paints the specified region using the specified linear or
+// radial gradient. The gradient is painted using a shading, and the opacity
+// of its stops is applied using a soft mask.
+func (r *svgRenderer) paintGradient(ctx svgContext, g *GraphicSVGElement, bbox svgRect, hasBBox bool, region svgRect, alpha float64) {
+	stops := r.gradientStops(g)
+	switch len(stops) {
+	case 0:
+		return
+	case 1:
+		r.fillRegion(ctx, region, stops[0].color, alpha)
+		return
+	}
+
+	units, _ := r.templateAttr(g, "gradientUnits")
+	obb := units != "userSpaceOnUse"
+	if obb && (!hasBBox || bbox.W <= 0 || bbox.H <= 0) {
+		return
+	}
+	tv, _ := r.templateAttr(g, "gradientTransform")
+	m := parseSVGTransform(tv)
+	if obb {
+		m = bbox.bboxMatrix().Mult(m)
+	}
+	inv, ok := m.Inverse()
+	if !ok {
+		return
+	}
+	coord := func(name, def string, axis byte) float64 {
+		v, ok := r.templateAttr(g, name)
+		if !ok {
+			v = def
+		}
+		if obb {
+			return svgFraction(v)
+		}
+		return ctx.lengthValue(v, axis, 0)
+	}
+
+	// Compute the range of the gradient parameter covering the region.
+	var corners [4][2]float64
+	for i, c := range [][2]float64{{region.X, region.Y}, {region.X + region.W, region.Y}, {region.X, region.Y + region.H}, {region.X + region.W, region.Y + region.H}} {
+		corners[i][0], corners[i][1] = inv.Transform(c[0], c[1])
+	}
+	last := stops[len(stops)-1].color
+	tmin, tmax := math.Inf(1), math.Inf(-1)
+
+	var coords []float64
+	var shadingType int64
+	if g.Name == "linearGradient" {
+		x1, y1 := coord("x1", "0%", 'x'), coord("y1", "0%", 'y')
+		x2, y2 := coord("x2", "100%", 'x'), coord("y2", "0%", 'y')
+		dx, dy := x2-x1, y2-y1
+		l2 := dx*dx + dy*dy
+		if l2 == 0 {
+			r.fillRegion(ctx, region, last, alpha)
+			return
+		}
+		for _, c := range corners {
+			t := ((c[0]-x1)*dx + (c[1]-y1)*dy) / l2
+			tmin, tmax = math.Min(tmin, t), math.Max(tmax, t)
+		}
+		shadingType, coords = 2, []float64{x1, y1, x2, y2}
+	} else {
+		cx, cy := coord("cx", "50%", 'x'), coord("cy", "50%", 'y')
+		rad, fr := coord("r", "50%", 'd'), coord("fr", "0%", 'd')
+		if rad <= 0 {
+			r.fillRegion(ctx, region, last, alpha)
+			return
+		}
+		fx, fy := cx, cy
+		if _, ok := r.templateAttr(g, "fx"); ok {
+			fx = coord("fx", "50%", 'x')
+		}
+		if _, ok := r.templateAttr(g, "fy"); ok {
+			fy = coord("fy", "50%", 'y')
+		}
+		// Keep the focal point inside the end circle.
+		if d := math.Hypot(fx-cx, fy-cy); d > rad*0.999 {
+			k := rad * 0.999 / d
+			fx, fy = cx+(fx-cx)*k, cy+(fy-cy)*k
+		}
+		tmin = 0
+		for _, c := range corners {
+			tmax = math.Max(tmax, math.Hypot(c[0]-fx, c[1]-fy)/rad)
+		}
+		shadingType, coords = 3, []float64{fx, fy, math.Max(fr, 0), cx, cy, rad}
+	}
+
+	// Repeated and reflected gradients are emulated by extending the
+	// gradient vector over the cycles covering the region.
+	spread, _ := r.templateAttr(g, "spreadMethod")
+	n0, n1 := 0, 1
+	if spread == "repeat" || spread == "reflect" {
+		n0, n1 = int(math.Floor(tmin)), int(math.Ceil(tmax))
+		if n1 <= n0 {
+			n1 = n0 + 1
+		}
+		if n1-n0 > 64 {
+			n1 = n0 + 64
+		}
+		k0, k1 := float64(n0), float64(n1)
+		if shadingType == 2 {
+			x1, y1, x2, y2 := coords[0], coords[1], coords[2], coords[3]
+			coords = []float64{x1 + k0*(x2-x1), y1 + k0*(y2-y1), x1 + k1*(x2-x1), y1 + k1*(y2-y1)}
+		} else {
+			fx, fy, fr, cx, cy, rad := coords[0], coords[1], coords[2], coords[3], coords[4], coords[5]
+			coords = []float64{fx, fy, fr, fx + k1*(cx-fx), fy + k1*(cy-fy), fr + k1*(rad-fr)}
+		}
+	} else {
+		spread = "pad"
+	}
+	stops = svgExpandStops(stops, spread, n0, n1)
+
+	newShading := func(gray bool) core.PdfObject {
+		var cs model.PdfColorspace = model.NewPdfColorspaceDeviceRGB()
+		if gray {
+			cs = model.NewPdfColorspaceDeviceGray()
+		}
+		fn := []model.PdfFunction{svgStopsFunction(stops, gray)}
+		extend := core.MakeArray(core.MakeBool(true), core.MakeBool(true))
+		if shadingType == 2 {
+			sh := model.NewPdfShadingType2()
+			sh.ShadingType = core.MakeInteger(2)
+			sh.ColorSpace = cs
+			sh.Coords = core.MakeArrayFromFloats(coords)
+			sh.Extend = extend
+			sh.Function = fn
+			return sh.ToPdfObject()
+		}
+		sh := model.NewPdfShadingType3()
+		sh.ShadingType = core.MakeInteger(3)
+		sh.ColorSpace = cs
+		sh.Coords = core.MakeArrayFromFloats(coords)
+		sh.Extend = extend
+		sh.Function = fn
+		return sh.ToPdfObject()
+	}
+
+	translucent := false
+	for _, s := range stops {
+		if s.color.a < 1 {
+			translucent = true
+			break
+		}
+	}
+	cv, cc := ctx.canvas, ctx.canvas.cc
+	if translucent {
+		mask := newSVGFormCanvas()
+		svgConcat(mask.cc, m)
+		mask.cc.Add_sh(mask.addShading(newShading(true)))
+		form, err := mask.form(region, true, "DeviceGray")
+		if err != nil {
+			common.Log.Debug("Unable to create SVG gradient mask: %v", err)
+			return
+		}
+		cv.setSoftMask(form, true, alpha)
+	} else {
+		cv.setAlpha(alpha, 1)
+	}
+	svgConcat(cc, m)
+	cc.Add_sh(cv.addShading(newShading(false)))
+}
+
+// svgExpandStops returns the gradient stops, padded to cover the [0, 1]
+// interval and repeated over the gradient cycles from `n0` to `n1`.
+func svgExpandStops(stops []svgStop, spread string, n0, n1 int) []svgStop {
+	if stops[0].offset > 0 {
+		stops = append([]svgStop{{offset: 0, color: stops[0].color}}, stops...)
+	}
+	if last := stops[len(stops)-1]; last.offset < 1 {
+		stops = append(stops, svgStop{offset: 1, color: last.color})
+	}
+	if n1-n0 == 1 && n0 == 0 {
+		return stops
+	}
+
+	cycles := float64(n1 - n0)
+	var out []svgStop
+	for c := n0; c < n1; c++ {
+		reflected := spread == "reflect" && c%2 != 0
+		for i := range stops {
+			s := stops[i]
+			if reflected {
+				s = stops[len(stops)-1-i]
+				s.offset = 1 - s.offset
+			}
+			s.offset = (float64(c-n0) + s.offset) / cycles
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// svgStopsFunction returns the function interpolating the colors, or the
+// opacities if `gray` is true, of the specified gradient stops over the
+// [0, 1] interval.
+func svgStopsFunction(stops []svgStop, gray bool) model.PdfFunction {
+	values := func(c svgColor) []float64 {
+		if gray {
+			return []float64{c.a}
+		}
+		return []float64{c.r, c.g, c.b}
+	}
+	rng := []float64{0, 1, 0, 1, 0, 1}
+	if gray {
+		rng = rng[:2]
+	}
+
+	var funcs []model.PdfFunction
+	var bounds, encode []float64
+	for i := 0; i+1 < len(stops); i++ {
+		s0, s1 := stops[i], stops[i+1]
+		if s1.offset-s0.offset <= 1e-9 {
+			continue
+		}
+		if len(funcs) > 0 {
+			bounds = append(bounds, s0.offset)
+		}
+		funcs = append(funcs, &model.PdfFunctionType2{
+			Domain: []float64{0, 1},
+			Range:  rng,
+			C0:     values(s0.color),
+			C1:     values(s1.color),
+			N:      1,
+		})
+		encode = append(encode, 0, 1)
+	}
+	switch len(funcs) {
+	case 0:
+		c := values(stops[len(stops)-1].color)
+		return &model.PdfFunctionType2{Domain: []float64{0, 1}, Range: rng, C0: c, C1: c, N: 1}
+	case 1:
+		return funcs[0]
+	}
+	return &model.PdfFunctionType3{
+		Domain:    []float64{0, 1},
+		Range:     rng,
+		Functions: funcs,
+		Bounds:    bounds,
+		Encode:    encode,
+	}
+}
+
+// paintPattern paints the specified region using the tiles of the specified
+// pattern. The pattern tile is rendered to a tiling pattern, used for filling
+// the region. The region is filled inside a form XObject, as the matrix of a
+// pattern maps the pattern space to the default space of its parent content
+// stream.
+func (r *svgRenderer) paintPattern(ctx svgContext, pat *GraphicSVGElement, bbox svgRect, hasBBox bool, region svgRect, alpha float64) {
+	if r.active[pat] {
+		return
+	}
+	r.active[pat] = true
+	defer delete(r.active, pat)
+
+	units, _ := r.templateAttr(pat, "patternUnits")
+	contentUnits, _ := r.templateAttr(pat, "patternContentUnits")
+	obb, contentObb := units != "userSpaceOnUse", contentUnits == "objectBoundingBox"
+	if (obb || contentObb) && (!hasBBox || bbox.W <= 0 || bbox.H <= 0) {
+		return
+	}
+	value := func(name string, axis byte) float64 {
+		v, _ := r.templateAttr(pat, name)
+		if obb {
+			f := svgFraction(v)
+			switch name {
+			case "x":
+				return bbox.X + f*bbox.W
+			case "y":
+				return bbox.Y + f*bbox.H
+			case "width":
+				return f * bbox.W
+			}
+			return f * bbox.H
+		}
+		return ctx.lengthValue(v, axis, 0)
+	}
+	x, y := value("x", 'x'), value("y", 'y')
+	w, h := value("width", 'x'), value("height", 'y')
+	if w <= 0 || h <= 0 {
+		return
+	}
+	tv, _ := r.templateAttr(pat, "patternTransform")
+	m := parseSVGTransform(tv)
+	inv, ok := m.Inverse()
+	if !ok {
+		return
+	}
+
+	// Render the pattern tile.
+	tile := newSVGFormCanvas()
+	tctx := newSVGContext(tile, pat, r.serverStyle(pat), ctx.vw, ctx.vh)
+	vbs, _ := r.templateAttr(pat, "viewBox")
+	if vb, ok := parseSVGViewBox(vbs); ok {
+		par, _ := r.templateAttr(pat, "preserveAspectRatio")
+		svgConcat(tile.cc, svgViewBoxTransform(vb, par, w, h))
+		tctx.vw, tctx.vh = vb.W, vb.H
+	} else if contentObb {
+		svgConcat(tile.cc, transform.ScaleMatrix(bbox.W, bbox.H))
+	}
+	for _, c := range r.templateChildren(pat, svgRenderable) {
+		r.renderElement(tctx, c)
+	}
+	pattern, err := tile.tilingPattern(svgRect{W: w, H: h}, transform.TranslationMatrix(x, y))
+	if err != nil {
+		common.Log.Debug("Unable to create SVG pattern tile: %v", err)
+		return
+	}
+
+	// Fill the region, expressed in pattern space, with the pattern.
+	area := region.transform(inv)
+	fill := newSVGFormCanvas()
+	fill.cc.Add_cs("Pattern")
+	fill.cc.Add_scn_pattern(fill.addPattern(pattern))
+	fill.cc.Add_re(area.X, area.Y, area.W, area.H)
+	fill.cc.Add_f()
+	form, err := fill.form(area, false, "")
+	if err != nil {
+		common.Log.Debug("Unable to create SVG pattern fill: %v", err)
+		return
+	}
+
+	cv, cc := ctx.canvas, ctx.canvas.cc
+	cv.setAlpha(alpha, alpha)
+	svgConcat(cc, m)
+	cc.Add_Do(cv.addForm(form))
+}
+
+// applyClipPath intersects the clipping path of the graphics state with the
+// specified clipPath element.
+func (r *svgRenderer) applyClipPath(ctx svgContext, id string, bbox svgRect, hasBBox bool) {
+	cp := r.ids[id]
+	if cp == nil || cp.Name != "clipPath" || r.active[cp] {
+		return
+	}
+	r.active[cp] = true
+	defer delete(r.active, cp)
+
+	cc := ctx.canvas.cc
+	style := r.serverStyle(cp)
+	if ref, _ := parseSVGURL(style.get("clip-path")); ref != "" {
+		r.applyClipPath(ctx, ref, bbox, hasBBox)
+	}
+	m := parseSVGTransform(cp.Attributes["transform"])
+	if cp.Attributes["clipPathUnits"] == "objectBoundingBox" {
+		if !hasBBox || bbox.W <= 0 || bbox.H <= 0 {
+			cc.Add_re(0, 0, 0, 0)
+			cc.Add_W()
+			cc.Add_n()
+			return
+		}
+		m = bbox.bboxMatrix().Mult(m)
+	}
+
+	// The geometry of the children is combined into a single path. Text
+	// children are used for clipping only if the clipping path does not
+	// contain any shapes, as text clipping paths cannot be combined with
+	// other paths.
+	cctx := newSVGContext(ctx.canvas, cp, style, ctx.vw, ctx.vh)
+	var clip svgPath
+	var texts []*GraphicSVGElement
+	evenOdd := true
+	for _, c := range cp.Children {
+		shape, cm := c, parseSVGTransform(c.Attributes["transform"])
+		cstyle := r.sheet.computeStyle(c, style, cctx.ancestors)
+		if c.Name == "use" {
+			ref := r.ids[svgHref(c)]
+			if ref == nil {
+				continue
+			}
+			cm = cm.Mult(transform.TranslationMatrix(cctx.length(c, "x", 'x', 0), cctx.length(c, "y", 'y', 0)))
+			cm = cm.Mult(parseSVGTransform(ref.Attributes["transform"]))
+			shape, cstyle = ref, r.sheet.computeStyle(ref, cstyle, append(cctx.ancestors, c))
+		}
+		if cstyle.get("display") == "none" || cstyle.get("visibility") != "visible" {
+			continue
+		}
+		switch shape.Name {
+		case "rect", "circle", "ellipse", "line", "polyline", "polygon", "path":
+			p := r.shapePath(cctx.child(shape, cstyle), shape)
+			clip = append(clip, p.transform(m.Mult(cm))...)
+			evenOdd = evenOdd && cstyle.get("clip-rule") == "evenodd"
+		case "text":
+			if c.Name == "text" {
+				texts = append(texts, c)
+			}
+		}
+	}
+
+	if len(clip) == 0 && len(texts) > 0 {
+		cc.Add_BT()
+		for _, t := range texts {
+			tstyle := r.sheet.computeStyle(t, style, cctx.ancestors)
+			tm := m.Mult(parseSVGTransform(t.Attributes["transform"]))
+			for _, run := range r.layoutText(cctx.child(t, tstyle), t) {
+				if run.ctx.style.get("visibility") == "visible" {
+					r.showText(run, 7, tm)
+				}
+			}
+		}
+		cc.Add_ET()
+		return
+	}
+	if len(clip) == 0 {
+		cc.Add_re(0, 0, 0, 0)
+	} else {
+		clip.draw(cc)
+	}
+	if evenOdd && len(clip) > 0 {
+		cc.Add_W_starred()
+	} else {
+		cc.Add_W()
+	}
+	cc.Add_n()
+}
+
+// applyMask sets the soft mask of the graphics state to the specified mask
+// element.
+func (r *svgRenderer) applyMask(ctx svgContext, id string, bbox svgRect, hasBBox bool) {
+	mask := r.ids[id]
+	if mask == nil || mask.Name != "mask" || r.active[mask] {
+		return
+	}
+	r.active[mask] = true
+	defer delete(r.active, mask)
+
+	obb := mask.Attributes["maskUnits"] != "userSpaceOnUse"
+	contentObb := mask.Attributes["maskContentUnits"] == "objectBoundingBox"
+	if (obb || contentObb) && (!hasBBox || bbox.W <= 0 || bbox.H <= 0) {
+		return
+	}
+	attr := func(name, def string) string {
+		if v, ok := mask.Attributes[name]; ok {
+			return v
+		}
+		return def
+	}
+	var region svgRect
+	if obb {
+		region = svgRect{
+			X: bbox.X + svgFraction(attr("x", "-10%"))*bbox.W,
+			Y: bbox.Y + svgFraction(attr("y", "-10%"))*bbox.H,
+			W: svgFraction(attr("width", "120%")) * bbox.W,
+			H: svgFraction(attr("height", "120%")) * bbox.H,
+		}
+	} else {
+		region = svgRect{
+			X: ctx.lengthValue(attr("x", "-10%"), 'x', 0),
+			Y: ctx.lengthValue(attr("y", "-10%"), 'y', 0),
+			W: ctx.lengthValue(attr("width", "120%"), 'x', 0),
+			H: ctx.lengthValue(attr("height", "120%"), 'y', 0),
+		}
+	}
+	if region.W <= 0 || region.H <= 0 {
+		return
+	}
+
+	cv := newSVGFormCanvas()
+	cv.cc.Add_re(region.X, region.Y, region.W, region.H)
+	cv.cc.Add_W()
+	cv.cc.Add_n()
+	if contentObb {
+		svgConcat(cv.cc, bbox.bboxMatrix())
+	}
+	style := r.serverStyle(mask)
+	r.renderChildren(newSVGContext(cv, mask, style, ctx.vw, ctx.vh), mask)
+	form, err := cv.form(region, true, "DeviceRGB")
+	if err != nil {
+		common.Log.Debug("Unable to create SVG mask: %v", err)
+		return
+	}
+	maskType := style.get("mask-type")
+	if v, ok := mask.Attributes["mask-type"]; ok {
+		maskType = v
+	}
+	ctx.canvas.setSoftMask(form, maskType != "alpha", 1)
+}
+
+// bounds returns the bounding box of the specified element, in its user
+// space. If `visual` is true, the stroke of the shapes is included.
+func (r *svgRenderer) bounds(ctx svgContext, e *GraphicSVGElement, visual bool) (svgRect, bool) {
+	switch e.Name {
+	case "g", "a", "switch", "svg", "symbol":
+		if e.Name == "svg" && e != r.root {
+			x, y := ctx.length(e, "x", 'x', 0), ctx.length(e, "y", 'y', 0)
+			w, h := ctx.length(e, "width", 'x', ctx.vw), ctx.length(e, "height", 'y', ctx.vh)
+			return svgRect{X: x, Y: y, W: w, H: h}, w > 0 && h > 0
+		}
+		var out svgRect
+		found := false
+		for _, c := range e.Children {
+			if !svgRenderable(c.Name) {
+				continue
+			}
+			style := r.sheet.computeStyle(c, ctx.style, ctx.ancestors)
+			if style.get("display") == "none" {
+				continue
+			}
+			cb, ok := r.bounds(ctx.child(c, style), c, visual)
+			if !ok {
+				continue
+			}
+			cb = cb.transform(parseSVGTransform(c.Attributes["transform"]))
+			if found {
+				out = out.union(cb)
+			} else {
+				out, found = cb, true
+			}
+		}
+		return out, found
+	case "use":
+		ref := r.ids[svgHref(e)]
+		if ref == nil || ref == e || r.active[e] {
+			return svgRect{}, false
+		}
+		r.active[e] = true
+		defer delete(r.active, e)
+
+		x, y := ctx.length(e, "x", 'x', 0), ctx.length(e, "y", 'y', 0)
+		if ref.Name == "symbol" || ref.Name == "svg" {
+			w, h := ctx.length(e, "width", 'x', ctx.vw), ctx.length(e, "height", 'y', ctx.vh)
+			return svgRect{X: x, Y: y, W: w, H: h}, w > 0 && h > 0
+		}
+		style := r.sheet.computeStyle(ref, ctx.style, ctx.ancestors)
+		rb, ok := r.bounds(ctx.child(ref, style), ref, visual)
+		if !ok {
+			return rb, false
+		}
+		m := transform.TranslationMatrix(x, y).Mult(parseSVGTransform(ref.Attributes["transform"]))
+		return rb.transform(m), true
+	case "text":
+		var out svgRect
+		found := false
+		for _, run := range r.layoutText(ctx, e) {
+			if found {
+				out = out.union(run.bounds())
+			} else {
+				out, found = run.bounds(), true
+			}
+		}
+		return out, found
+	case "image":
+		x, y := ctx.length(e, "x", 'x', 0), ctx.length(e, "y", 'y', 0)
+		w, h := ctx.length(e, "width", 'x', 0), ctx.length(e, "height", 'y', 0)
+		return svgRect{X: x, Y: y, W: w, H: h}, w > 0 && h > 0
+	}
+
+	b, ok := r.shapePath(ctx, e).bounds()
+	if ok && visual {
+		if _, stroked := r.strokeColor(ctx); stroked {
+			width := ctx.lengthValue(ctx.style.get("stroke-width"), 'd', 1)
+			b = b.expand(width * math.Max(1, ctx.style.number("stroke-miterlimit")) / 2)
+		}
+	}
+	return b, ok
+}
+
+// svgTextChar represents an addressable character of an SVG text element.
+type svgTextChar struct {
+	r                        rune
+	ctx                      svgContext
+	x, y, dx, dy             float64
+	hasX, hasY, hasDX, hasDY bool
+}
+
+// svgTextRun represents a sequence of characters of an SVG text element,
+// sharing the same style and shown using a single text showing operator.
+type svgTextRun struct {
+	ctx     svgContext
+	font    *model.PdfFont
+	size    float64
+	spacing float64
+	text    []rune
+	x, y    float64
+	width   float64
+}
+
+// bounds returns the approximate bounding box of the run.
+func (run *svgTextRun) bounds() svgRect {
+	return svgRect{X: run.x, Y: run.y - 0.8*run.size, W: run.width, H: run.size}
+}
+
+// collectText collects the addressable characters of the specified text
+// content element and of its descendants, collapsing the white space.
+func (r *svgRenderer) collectText(ctx svgContext, e *GraphicSVGElement, chars []*svgTextChar, space *bool) []*svgTextChar {
+	start := len(chars)
+	addText := func(text string) {
+		for _, c := range text {
+			switch c {
+			case '\n', '\r':
+				continue
+			case '\t':
+				c = ' '
+			}
+			if c == ' ' && *space {
+				continue
+			}
+			*space = c == ' '
+			chars = append(chars, &svgTextChar{r: c, ctx: ctx})
+		}
+	}
+
+	hasText := false
+	for _, c := range e.Children {
+		switch c.Name {
+		case "#text":
+			hasText = true
+			addText(c.Content)
+		case "tspan", "a", "textPath":
+			style := r.sheet.computeStyle(c, ctx.style, ctx.ancestors)
+			if style.get("display") == "none" {
+				continue
+			}
+			chars = r.collectText(ctx.child(c, style), c, chars, space)
+		}
+	}
+	if !hasText && len(chars) == start {
+		addText(e.Content)
+	}
+
+	// Assign the positions specified by the element to the characters not
+	// positioned by its descendants.
+	for _, attr := range []string{"x", "y", "dx", "dy"} {
+		axis := attr[len(attr)-1]
+		for i, v := range ctx.lengths(e, attr, axis) {
+			if start+i >= len(chars) {
+				break
+			}
+			ch := chars[start+i]
+			switch attr {
+			case "x":
+				if !ch.hasX {
+					ch.x, ch.hasX = v, true
+				}
+			case "y":
+				if !ch.hasY {
+					ch.y, ch.hasY = v, true
+				}
+			case "dx":
+				if !ch.hasDX {
+					ch.dx, ch.hasDX = v, true
+				}
+			case "dy":
+				if !ch.hasDY {
+					ch.dy, ch.hasDY = v, true
+				}
+			}
+		}
+	}
+	return chars
+}
+
+// layoutText lays out the characters of the specified text element into
+// runs, applying the text-anchor property to the text chunks.
+func (r *svgRenderer) layoutText(ctx svgContext, e *GraphicSVGElement) []*svgTextRun {
+	space := true
+	chars := r.collectText(ctx, e, nil, &space)
+	for len(chars) > 0 && chars[len(chars)-1].r == ' ' {
+		chars = chars[:len(chars)-1]
+	}
+
+	var runs []*svgTextRun
+	var chunk []*svgTextRun
+	alignChunk := func() {
+		if len(chunk) == 0 {
+			return
+		}
+		width := 0.0
+		for _, run := range chunk {
+			width += run.width
+		}
+		shift := 0.0
+		switch chunk[0].ctx.style.get("text-anchor") {
+		case "middle":
+			shift = -width / 2
+		case "end":
+			shift = -width
+		}
+		for _, run := range chunk {
+			run.x += shift
+		}
+		chunk = nil
+	}
+
+	var run *svgTextRun
+	penX, penY := 0.0, 0.0
+	prevSpace := false
+	for i, ch := range chars {
+		if i > 0 && (ch.hasX || ch.hasY) {
+			alignChunk()
+		}
+		if ch.hasX {
+			penX = ch.x
+		}
+		if ch.hasY {
+			penY = ch.y
+		}
+		penX, penY = penX+ch.dx, penY+ch.dy
+
+		style := ch.ctx.style
+		spacing := ch.ctx.lengthValue(style.get("letter-spacing"), 'x', 0)
+		wordSpacing := ch.ctx.lengthValue(style.get("word-spacing"), 'x', 0)
+		if run == nil || ch.hasX || ch.hasY || ch.dx != 0 || ch.dy != 0 ||
+			run.ctx.style != style || (prevSpace && wordSpacing != 0) {
+			run = &svgTextRun{
+				ctx:     ch.ctx,
+				font:    r.font(style),
+				size:    style.fontSize(),
+				spacing: spacing,
+				x:       penX,
+				y:       penY,
+			}
+			runs = append(runs, run)
+			chunk = append(chunk, run)
+		}
+		run.text = append(run.text, ch.r)
+		advance := svgGlyphWidth(run.font, ch.r)*run.size/1000 + spacing
+		if ch.r == ' ' {
+			advance += wordSpacing
+		}
+		run.width += advance
+		penX += advance
+		prevSpace = ch.r == ' '
+	}
+	alignChunk()
+	return runs
+}
+
+// svgGlyphWidth returns the width of the glyph of the specified rune, in
+// glyph space units.
+func svgGlyphWidth(font *model.PdfFont, r rune) float64 {
+	if m, ok := font.GetRuneMetrics(r); ok && (m.Wx > 0 || r == ' ') {
+		return m.Wx
+	}
+	return 500
+}
+
+// font returns the font used for rendering text having the specified style.
+func (r *svgRenderer) font(style *svgStyle) *model.PdfFont {
+	bold := false
+	switch style.get("font-weight") {
+	case "bold", "bolder", "600", "700", "800", "900":
+		bold = true
+	}
+	italic := false
+	switch style.get("font-style") {
+	case "italic", "oblique":
+		italic = true
+	}
+
+	families := splitSVGList(style.get("font-family"), ',')
+	for _, family := range families {
+		for _, key := range []string{svgFontKey(family, bold, italic), svgFontKey(family, false, false)} {
+			if font, ok := r.fonts[key]; ok && font != nil {
+				return font
+			}
+		}
+	}
+
+	std := map[string][4]model.StdFontName{
+		"sans-serif": {model.HelveticaName, model.HelveticaBoldName, model.HelveticaObliqueName, model.HelveticaBoldObliqueName},
+		"serif":      {model.TimesRomanName, model.TimesBoldName, model.TimesItalicName, model.TimesBoldItalicName},
+		"monospace":  {model.CourierName, model.CourierBoldName, model.CourierObliqueName, model.CourierBoldObliqueName},
+	}
+	aliases := map[string]string{
+		"helvetica": "sans-serif", "arial": "sans-serif", "verdana": "sans-serif",
+		"times": "serif", "times new roman": "serif", "times-roman": "serif", "georgia": "serif",
+		"courier": "monospace", "courier new": "monospace", "consolas": "monospace",
+	}
+	variant := 0
+	if bold {
+		variant++
+	}
+	if italic {
+		variant += 2
+	}
+	name := model.HelveticaName
+	for _, family := range families {
+		family = strings.ToLower(strings.Trim(strings.TrimSpace(family), `"'`))
+		if alias, ok := aliases[family]; ok {
+			family = alias
+		}
+		if names, ok := std[family]; ok {
+			name = names[variant]
+			break
+		}
+		if family == "symbol" {
+			name = model.SymbolName
+			break
+		}
+	}
+	if name == model.HelveticaName {
+		name = std["sans-serif"][variant]
+	}
+
+	if font, ok := r.stdFonts[name]; ok {
+		return font
+	}
+	font := model.NewStandard14FontMustCompile(name)
+	r.stdFonts[name] = font
+	return font
+}
+
+// renderText renders the specified text element.
+func (r *svgRenderer) renderText(ctx svgContext, e *GraphicSVGElement) {
+	runs := r.layoutText(ctx, e)
+	if len(runs) == 0 {
+		return
+	}
+	var bbox svgRect
+	for i, run := range runs {
+		if i == 0 {
+			bbox = run.bounds()
+		} else {
+			bbox = bbox.union(run.bounds())
+		}
+	}
+	for _, run := range runs {
+		if run.ctx.style.get("visibility") == "visible" {
+			r.paintText(run, bbox)
+		}
+	}
+}
+
+// showText shows the characters of the specified run inside the current text
+// object, using the specified text rendering mode. The matrix `m` is applied
+// to the text in addition to the current transformation matrix.
+func (r *svgRenderer) showText(run *svgTextRun, mode int64, m transform.Matrix) {
+	enc := run.font.Encoder()
+	if enc == nil {
+		return
+	}
+	cc := run.ctx.canvas.cc
+	tm := m.Mult(transform.NewMatrix(1, 0, 0, -1, run.x, run.y))
+	cc.Add_Tf(run.ctx.canvas.addFont(run.font), run.size)
+	cc.Add_Tm(tm[0], tm[1], tm[3], tm[4], tm[6], tm[7])
+	cc.Add_Tc(run.spacing)
+	cc.Add_Tr(mode)
+	cc.Add_Tj(*core.MakeStringFromBytes(enc.Encode(string(run.text))))
+}
+
+// paintText paints the specified text run. The `bbox` rectangle is the
+// bounding box of the text element, used for resolving the paint servers.
+func (r *svgRenderer) paintText(run *svgTextRun, bbox svgRect) {
+	ctx := run.ctx
+	cv, cc, style := ctx.canvas, ctx.canvas.cc, ctx.style
+	fill, server := r.resolvePaint(parseSVGPaint(style.get("fill"), ctx.currentColor()))
+	fillAlpha := style.opacity("fill-opacity") * ctx.alpha
+	if server != nil {
+		r.fillWithServer(ctx, server, bbox, true, run.bounds(), fillAlpha, func() {
+			cc.Add_BT()
+			r.showText(run, 7, transform.IdentityMatrix())
+			cc.Add_ET()
+		})
+		fill = svgPaint{none: true}
+	}
+	fillAlpha *= fill.color.a
+	hasFill := !fill.none && fillAlpha > 0
+	stroke, hasStroke := r.strokeColor(ctx)
+	if !hasFill && !hasStroke {
+		return
+	}
+
+	cc.Add_q()
+	defer cc.Add_Q()
+	mode := int64(0)
+	switch {
+	case hasFill && hasStroke:
+		mode = 2
+		cv.setAlpha(fillAlpha, stroke.a)
+	case hasStroke:
+		mode = 1
+		cv.setAlpha(1, stroke.a)
+	default:
+		cv.setAlpha(fillAlpha, 1)
+	}
+	if hasFill {
+		cc.Add_rg(fill.color.r, fill.color.g, fill.color.b)
+	}
+	if hasStroke {
+		cc.Add_RG(stroke.r, stroke.g, stroke.b)
+		r.setLineStyle(ctx)
+	}
+	cc.Add_BT()
+	r.showText(run, mode, transform.IdentityMatrix())
+	cc.Add_ET()
+
+	if !hasFill {
+		return
+	}
+	decoration := style.get("text-decoration")
+	thickness := run.size / 20
+	for name, offset := range map[string]float64{"underline": 0.1, "line-through": -0.3, "overline": -0.8} {
+		if strings.Contains(decoration, name) {
+			cc.Add_re(run.x, run.y+offset*run.size, run.width, thickness)
+		}
+	}
+	if strings.Contains(decoration, "line") {
+		cc.Add_f()
+	}
+}
+
+// renderImage renders the specified image element. Raster images and SVG
+// documents are supported, referenced using data URLs or file paths.
+func (r *svgRenderer) renderImage(ctx svgContext, e *GraphicSVGElement) {
+	if ctx.style.get("visibility") != "visible" {
+		return
+	}
+	data, isSVG, err := svgImageData(e.Attributes["href"])
+	if err != nil {
+		common.Log.Debug("Unable to load SVG image: %v", err)
+		return
+	}
+	x, y := ctx.length(e, "x", 'x', 0), ctx.length(e, "y", 'y', 0)
+	par := e.Attributes["preserveAspectRatio"]
+	cv, cc := ctx.canvas, ctx.canvas.cc
+
+	if isSVG {
+		doc, err := ParseFromSVGStream(bytes.NewReader(data))
+		if err != nil || doc == nil {
+			common.Log.Debug("Unable to parse SVG image: %v", err)
+			return
+		}
+		w := ctx.length(e, "width", 'x', doc.Width/_degac)
+		h := ctx.length(e, "height", 'y', doc.Height/_degac)
+		cc.Add_q()
+		cv.setAlpha(ctx.alpha, ctx.alpha)
+		newSVGRenderer(doc, r.fonts).render(cv, svgRect{X: x, Y: y, W: w, H: h}, par)
+		cc.Add_Q()
+		return
+	}
+
+	img, err := model.ImageHandling.Read(bytes.NewReader(data))
+	if err != nil {
+		common.Log.Debug("Unable to read SVG image: %v", err)
+		return
+	}
+	iw, ih := float64(img.Width), float64(img.Height)
+	w, h := ctx.length(e, "width", 'x', iw), ctx.length(e, "height", 'y', ih)
+	if w <= 0 || h <= 0 || iw <= 0 || ih <= 0 {
+		return
+	}
+	ximg, err := model.NewXObjectImageFromImage(img, nil, core.NewFlateEncoder())
+	if err != nil {
+		common.Log.Debug("Unable to create SVG image XObject: %v", err)
+		return
+	}
+
+	cc.Add_q()
+	defer cc.Add_Q()
+	cc.Add_re(x, y, w, h)
+	cc.Add_W()
+	cc.Add_n()
+	cv.setAlpha(ctx.alpha, ctx.alpha)
+	m := transform.TranslationMatrix(x, y).Mult(svgViewBoxTransform(svgRect{W: iw, H: ih}, par, w, h))
+	svgConcat(cc, m.Mult(transform.NewMatrix(iw, 0, 0, -ih, 0, ih)))
+	cc.Add_Do(cv.addImage(ximg))
+}
+
+// svgImageData returns the data of the image referenced by the specified
+// URL, and whether the image is an SVG document.
+func svgImageData(href string) ([]byte, bool, error) {
+	href = strings.TrimSpace(href)
+	if !strings.HasPrefix(href, "data:") {
+		path := strings.TrimPrefix(href, "file://")
+		data, err := os.ReadFile(path)
+		return data, strings.HasSuffix(strings.ToLower(path), ".svg"), err
+	}
+
+	comma := strings.IndexByte(href, ',')
+	if comma < 0 {
+		return nil, false, fmt.Errorf("invalid data URL")
+	}
+	header, payload := strings.ToLower(href[5:comma]), href[comma+1:]
+	isSVG := strings.HasPrefix(header, "image/svg+xml")
+	if strings.HasSuffix(header, ";base64") {
+		payload = strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+				return -1
+			}
+			return r
+		}, payload)
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+		}
+		return data, isSVG, err
+	}
+	data, err := url.PathUnescape(payload)
+	return []byte(data), isSVG, err
+}
@@ -0,0 +1,269 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// svgOutput holds the content stream operations generated for an SVG
+// fixture, including the operations of the nested forms and patterns, along
+// with the resources they use.
+type svgOutput struct {
+	ops       []*contentstream.ContentStreamOperation
+	resources map[core.PdfObjectName][]core.PdfObject
+}
+
+// count returns the number of operations having the specified operand.
+func (out *svgOutput) count(operand string) int {
+	n := 0
+	for _, op := range out.ops {
+		if op.Operand == operand {
+			n++
+		}
+	}
+	return n
+}
+
+// hasColor returns true if a fill (`rg`) or stroke (`RG`) operation sets the
+// specified color.
+func (out *svgOutput) hasColor(operand string, r, g, b float64) bool {
+	for _, op := range out.ops {
+		if op.Operand != operand || len(op.Params) != 3 {
+			continue
+		}
+		vals, err := core.GetNumbersAsFloat(op.Params)
+		if err == nil && vals[0] == r && vals[1] == g && vals[2] == b {
+			return true
+		}
+	}
+	return false
+}
+
+// text returns the strings shown by the text showing operations.
+func (out *svgOutput) text() []string {
+	var texts []string
+	for _, op := range out.ops {
+		if op.Operand == "Tj" && len(op.Params) == 1 {
+			if s, ok := core.GetStringVal(op.Params[0]); ok {
+				texts = append(texts, s)
+			}
+		}
+	}
+	return texts
+}
+
+// add parses the specified content stream and appends its operations to
+// the output, followed by the operations of the forms and patterns found in
+// its resources.
+func (out *svgOutput) add(t *testing.T, content string, resources core.PdfObject) {
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		t.Fatalf("unable to parse content stream: %v", err)
+	}
+	out.ops = append(out.ops, *ops...)
+
+	dict, ok := core.GetDict(resources)
+	if !ok {
+		return
+	}
+	for _, category := range []core.PdfObjectName{"ExtGState", "Font", "Pattern", "Shading", "XObject"} {
+		entries, ok := core.GetDict(dict.Get(category))
+		if !ok {
+			continue
+		}
+		for _, key := range entries.Keys() {
+			obj := core.TraceToDirectObject(entries.Get(key))
+			out.resources[category] = append(out.resources[category], obj)
+			if stream, ok := obj.(*core.PdfObjectStream); ok {
+				data, err := core.DecodeStream(stream)
+				if err != nil {
+					t.Fatalf("unable to decode %s %s: %v", category, key, err)
+				}
+				out.add(t, string(data), stream.Get("Resources"))
+			}
+		}
+	}
+}
+
+// renderSVGFixture renders the specified SVG fixture and returns the
+// generated output.
+func renderSVGFixture(t *testing.T, name string) *svgOutput {
+	root, err := ParseFromSVGFile(filepath.Join("testdata", "svg", name))
+	if err != nil {
+		t.Fatalf("unable to parse %s: %v", name, err)
+	}
+	cc := contentstream.NewContentCreator()
+	res := model.NewPdfPageResources()
+	renderSVGDocument(root, cc, res)
+
+	out := &svgOutput{resources: map[core.PdfObjectName][]core.PdfObject{}}
+	out.add(t, cc.Operations().String(), res.ToPdfObject())
+	return out
+}
+
+// resourceInt returns the integer value of the specified key of the
+// dictionary of a resource.
+func resourceInt(obj core.PdfObject, key core.PdfObjectName) (int, bool) {
+	dict, ok := core.GetDict(obj)
+	if stream, isStream := obj.(*core.PdfObjectStream); isStream {
+		dict, ok = stream.PdfObjectDictionary, true
+	}
+	if !ok {
+		return 0, false
+	}
+	return core.GetIntVal(dict.Get(key))
+}
+
+func TestSVGGradients(t *testing.T) {
+	out := renderSVGFixture(t, "gradients.svg")
+	if n := out.count("sh"); n != 2 {
+		t.Fatalf("expected 2 shading operations, got %d", n)
+	}
+
+	types := map[int]bool{}
+	for _, shading := range out.resources["Shading"] {
+		if st, ok := resourceInt(shading, "ShadingType"); ok {
+			types[st] = true
+		}
+	}
+	if !types[2] || !types[3] {
+		t.Fatalf("expected axial and radial shadings, got %v", types)
+	}
+
+	// The stop opacity of the radial gradient is applied using a soft mask.
+	if len(out.resources["ExtGState"]) == 0 {
+		t.Fatalf("expected soft mask graphics state for gradient opacity")
+	}
+}
+
+func TestSVGPatterns(t *testing.T) {
+	out := renderSVGFixture(t, "pattern.svg")
+
+	patterns := out.resources["Pattern"]
+	if len(patterns) != 2 {
+		t.Fatalf("expected 2 tiling patterns, got %d", len(patterns))
+	}
+	for _, pattern := range patterns {
+		if pt, _ := resourceInt(pattern, "PatternType"); pt != 1 {
+			t.Fatalf("expected tiling pattern, got pattern type %d", pt)
+		}
+	}
+	stream, ok := patterns[0].(*core.PdfObjectStream)
+	if !ok {
+		t.Fatalf("expected pattern stream, got %T", patterns[0])
+	}
+	if step, _ := core.GetNumberAsFloat(stream.Get("XStep")); step != 2 {
+		t.Fatalf("expected XStep 2, got %v", step)
+	}
+
+	// The tiles are painted by the pattern and not one by one, so
+	// patterns having a large number of tiles are not dropped.
+	if n := out.count("scn"); n != 2 {
+		t.Fatalf("expected 2 pattern fills, got %d", n)
+	}
+	if n := out.count("Do"); n != 2 {
+		t.Fatalf("expected 2 form invocations, got %d", n)
+	}
+	if !out.hasColor("rg", 0.2, 0.4, 0.6) {
+		t.Fatalf("pattern tile content not rendered")
+	}
+}
+
+func TestSVGClipAndMask(t *testing.T) {
+	out := renderSVGFixture(t, "clip_mask.svg")
+
+	// The viewport clip, followed by the circle clip path.
+	if n := out.count("W"); n < 2 {
+		t.Fatalf("expected clipping path operations, got %d", n)
+	}
+
+	var masks int
+	for _, gs := range out.resources["ExtGState"] {
+		if dict, ok := core.GetDict(gs); ok && dict.Get("SMask") != nil {
+			masks++
+		}
+	}
+	if masks != 1 {
+		t.Fatalf("expected 1 soft mask, got %d", masks)
+	}
+	if !out.hasColor("rg", 1, 0, 0) || !out.hasColor("rg", 0, 0, 1) {
+		t.Fatalf("clipped and masked shapes not rendered")
+	}
+}
+
+func TestSVGUseSymbol(t *testing.T) {
+	out := renderSVGFixture(t, "use_symbol.svg")
+
+	var boxes int
+	for _, op := range out.ops {
+		if op.Operand != "rg" {
+			continue
+		}
+		if vals, err := core.GetNumbersAsFloat(op.Params); err == nil && vals[0] == 1 && vals[1] == 0 {
+			boxes++
+		}
+	}
+	if boxes != 3 {
+		t.Fatalf("expected 3 symbol instances, got %d", boxes)
+	}
+	if !out.hasColor("rg", 0, 1, 0) {
+		t.Fatalf("referenced element not rendered")
+	}
+	if n := out.count("f"); n != 4 {
+		t.Fatalf("expected 4 filled shapes, got %d", n)
+	}
+}
+
+func TestSVGStylesheet(t *testing.T) {
+	out := renderSVGFixture(t, "css.svg")
+
+	if !out.hasColor("rg", 0, 1, 0) {
+		t.Fatalf("class selector not applied")
+	}
+	if !out.hasColor("rg", 0, 0, 1) {
+		t.Fatalf("id selector not applied")
+	}
+	if !out.hasColor("rg", 1, 1, 0) {
+		t.Fatalf("style attribute not applied")
+	}
+	if !out.hasColor("RG", 1, 0, 0) {
+		t.Fatalf("descendant selector not applied")
+	}
+	if n := out.count("S"); n != 1 {
+		t.Fatalf("expected 1 stroked shape, got %d", n)
+	}
+}
+
+func TestSVGText(t *testing.T) {
+	out := renderSVGFixture(t, "text.svg")
+
+	expected := []string{"Hello ", "bold", " world", "Anchored"}
+	texts := out.text()
+	if len(texts) != len(expected) {
+		t.Fatalf("expected text %q, got %q", expected, texts)
+	}
+	for i := range expected {
+		if texts[i] != expected[i] {
+			t.Fatalf("expected text %q, got %q", expected, texts)
+		}
+	}
+	if n := len(out.resources["Font"]); n != 3 {
+		t.Fatalf("expected 3 fonts (regular, bold and serif), got %d", n)
+	}
+	if !out.hasColor("rg", 1, 0, 0) {
+		t.Fatalf("tspan fill not applied")
+	}
+}
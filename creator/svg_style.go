@@ -0,0 +1,821 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/internal/graphic2d"
+	"github.com/unidoc/unipdf/v4/internal/transform"
+)
+
+// svgProperties lists the SVG properties which can be specified using
+// presentation attributes, and whether they are inherited.
+var svgProperties = map[string]bool{
+	"clip-path":         false,
+	"clip-rule":         true,
+	"color":             true,
+	"display":           false,
+	"fill":              true,
+	"fill-opacity":      true,
+	"fill-rule":         true,
+	"font":              true,
+	"font-family":       true,
+	"font-size":         true,
+	"font-style":        true,
+	"font-weight":       true,
+	"letter-spacing":    true,
+	"mask":              false,
+	"opacity":           false,
+	"overflow":          false,
+	"stop-color":        false,
+	"stop-opacity":      false,
+	"stroke":            true,
+	"stroke-dasharray":  true,
+	"stroke-dashoffset": true,
+	"stroke-linecap":    true,
+	"stroke-linejoin":   true,
+	"stroke-miterlimit": true,
+	"stroke-opacity":    true,
+	"stroke-width":      true,
+	"text-anchor":       true,
+	"text-decoration":   true,
+	"visibility":        true,
+	"word-spacing":      true,
+}
+
+// svgDefaults contains the initial values of the SVG properties.
+var svgDefaults = map[string]string{
+	"color":             "black",
+	"display":           "inline",
+	"fill":              "black",
+	"fill-opacity":      "1",
+	"fill-rule":         "nonzero",
+	"clip-rule":         "nonzero",
+	"font-family":       "sans-serif",
+	"font-size":         "16",
+	"font-style":        "normal",
+	"font-weight":       "normal",
+	"opacity":           "1",
+	"stop-color":        "black",
+	"stop-opacity":      "1",
+	"stroke":            "none",
+	"stroke-dashoffset": "0",
+	"stroke-linecap":    "butt",
+	"stroke-linejoin":   "miter",
+	"stroke-miterlimit": "4",
+	"stroke-opacity":    "1",
+	"stroke-width":      "1",
+	"text-anchor":       "start",
+	"visibility":        "visible",
+}
+
+// svgStyle holds the computed property values of an SVG element.
+type svgStyle struct {
+	props map[string]string
+}
+
+// get returns the computed value of the specified property.
+func (s *svgStyle) get(name string) string {
+	if v, ok := s.props[name]; ok {
+		return v
+	}
+	return svgDefaults[name]
+}
+
+// number returns the computed value of the specified numeric property.
+func (s *svgStyle) number(name string) float64 {
+	v, ok := parseSVGFloat(strings.TrimSpace(s.get(name)))
+	if !ok {
+		v, _ = parseSVGFloat(svgDefaults[name])
+	}
+	return v
+}
+
+// opacity returns the computed value of the specified opacity property,
+// clamped to the [0, 1] interval.
+func (s *svgStyle) opacity(name string) float64 {
+	v := strings.TrimSpace(s.get(name))
+	o, ok := 1.0, false
+	if strings.HasSuffix(v, "%") {
+		o, ok = parseSVGFloat(strings.TrimSuffix(v, "%"))
+		o /= 100
+	} else {
+		o, ok = parseSVGFloat(v)
+	}
+	if !ok {
+		return 1
+	}
+	return math.Max(0, math.Min(o, 1))
+}
+
+// fontSize returns the computed font size, in user units.
+func (s *svgStyle) fontSize() float64 {
+	return s.number("font-size")
+}
+
+// svgSelector represents a compound selector of a CSS rule, along with the
+// combinator linking it to the previous compound selector.
+type svgSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   [][2]string
+	child   bool
+	invalid bool
+}
+
+// matches returns true if the selector matches the specified element,
+// ignoring the combinators.
+func (sel *svgSelector) matches(e *GraphicSVGElement) bool {
+	if sel.invalid {
+		return false
+	}
+	if sel.tag != "" && sel.tag != "*" && sel.tag != e.Name {
+		return false
+	}
+	if sel.id != "" && e.Attributes["id"] != sel.id {
+		return false
+	}
+	if len(sel.classes) > 0 {
+		classes := strings.Fields(e.Attributes["class"])
+		for _, c := range sel.classes {
+			found := false
+			for _, ec := range classes {
+				if ec == c {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, attr := range sel.attrs {
+		v, ok := e.Attributes[attr[0]]
+		if !ok || (attr[1] != "" && v != attr[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// svgStyleRule represents a rule of a CSS style sheet.
+type svgStyleRule struct {
+	selector    []svgSelector
+	specificity int
+	order       int
+	decls       []svgDeclaration
+}
+
+// matches returns true if the rule applies to the specified element, having
+// the specified ancestors (the closest ancestor last).
+func (r *svgStyleRule) matches(e *GraphicSVGElement, ancestors []*GraphicSVGElement) bool {
+	n := len(r.selector)
+	if n == 0 || !r.selector[n-1].matches(e) {
+		return false
+	}
+
+	// Match the remaining compound selectors against the ancestors.
+	i := len(ancestors) - 1
+	for j := n - 2; j >= 0; j-- {
+		child := r.selector[j+1].child
+		found := false
+		for ; i >= 0; i-- {
+			if r.selector[j].matches(ancestors[i]) {
+				found = true
+				i--
+				break
+			}
+			if child {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// svgDeclaration represents a CSS property declaration.
+type svgDeclaration struct {
+	name      string
+	value     string
+	important bool
+}
+
+// svgStyleSheet represents the CSS style sheets of an SVG document.
+type svgStyleSheet struct {
+	rules []*svgStyleRule
+}
+
+// splitSVGList splits the specified string by the specified separator,
+// ignoring the separators inside parentheses and quotes.
+func splitSVGList(s string, sep byte) []string {
+	var (
+		parts []string
+		depth int
+		quote byte
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseSVGDeclarations parses a list of CSS declarations, as specified by
+// style attributes and the blocks of CSS rules.
+func parseSVGDeclarations(s string) []svgDeclaration {
+	var decls []svgDeclaration
+	for _, part := range splitSVGList(s, ';') {
+		i := strings.IndexByte(part, ':')
+		if i < 0 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(part[:i]))
+		value := strings.TrimSpace(part[i+1:])
+		important := false
+		if j := strings.Index(strings.ToLower(value), "!important"); j >= 0 {
+			value, important = strings.TrimSpace(value[:j]), true
+		}
+		if name != "" && value != "" {
+			decls = append(decls, svgDeclaration{name: name, value: value, important: important})
+		}
+	}
+	return decls
+}
+
+// parseSVGSelector parses a complex CSS selector. Returns the compound
+// selectors and the specificity of the selector.
+func parseSVGSelector(s string) ([]svgSelector, int) {
+	var (
+		sels        []svgSelector
+		specificity int
+		child       bool
+	)
+	s = strings.ReplaceAll(s, ">", " > ")
+	for _, tok := range strings.Fields(s) {
+		if tok == ">" {
+			child = true
+			continue
+		}
+		if tok == "+" || tok == "~" {
+			// Sibling combinators are not supported.
+			return nil, 0
+		}
+
+		sel := svgSelector{child: child}
+		child = false
+		for len(tok) > 0 {
+			end := strings.IndexAny(tok[1:], ".#[:")
+			if end < 0 {
+				end = len(tok)
+			} else {
+				end++
+			}
+			part := tok[:end]
+			switch part[0] {
+			case '.':
+				sel.classes = append(sel.classes, part[1:])
+				specificity += 100
+			case '#':
+				sel.id = part[1:]
+				specificity += 10000
+			case '[':
+				end = strings.IndexByte(tok, ']')
+				if end < 0 {
+					return nil, 0
+				}
+				attr := tok[1:end]
+				end++
+				name, value := attr, ""
+				if i := strings.IndexByte(attr, '='); i >= 0 {
+					name, value = attr[:i], strings.Trim(attr[i+1:], `"'`)
+				}
+				sel.attrs = append(sel.attrs, [2]string{name, value})
+				specificity += 100
+			case ':':
+				// Pseudo-classes and pseudo-elements cannot be matched
+				// statically.
+				sel.invalid = true
+				specificity += 100
+			default:
+				sel.tag = part
+				if part != "*" {
+					specificity++
+				}
+			}
+			tok = tok[end:]
+		}
+		sels = append(sels, sel)
+	}
+	return sels, specificity
+}
+
+// add parses the specified CSS style sheet and adds its rules to the style
+// sheet. At-rules are ignored.
+func (ss *svgStyleSheet) add(css string) {
+	// Strip comments.
+	for {
+		i := strings.Index(css, "/*")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(css[i+2:], "*/")
+		if j < 0 {
+			css = css[:i]
+			break
+		}
+		css = css[:i] + css[i+2+j+2:]
+	}
+	css = strings.NewReplacer("<![CDATA[", "", "]]>", "").Replace(css)
+
+	for {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			return
+		}
+		prelude := strings.TrimSpace(css[:open])
+
+		// Find the matching closing brace.
+		depth, end := 0, -1
+		for i := open; i < len(css); i++ {
+			if css[i] == '{' {
+				depth++
+			} else if css[i] == '}' {
+				depth--
+				if depth == 0 {
+					end = i
+					break
+				}
+			}
+		}
+		if end < 0 {
+			return
+		}
+		body := css[open+1 : end]
+		css = css[end+1:]
+
+		if strings.HasPrefix(prelude, "@") {
+			continue
+		}
+		decls := parseSVGDeclarations(body)
+		for _, s := range strings.Split(prelude, ",") {
+			sel, specificity := parseSVGSelector(s)
+			if len(sel) == 0 {
+				continue
+			}
+			ss.rules = append(ss.rules, &svgStyleRule{
+				selector:    sel,
+				specificity: specificity,
+				order:       len(ss.rules),
+				decls:       decls,
+			})
+		}
+	}
+}
+
+// declarations returns the declarations of the rules matching the specified
+// element, in increasing order of precedence.
+func (ss *svgStyleSheet) declarations(e *GraphicSVGElement, ancestors []*GraphicSVGElement) []svgDeclaration {
+	if ss == nil || len(ss.rules) == 0 {
+		return nil
+	}
+
+	var matched []*svgStyleRule
+	for _, r := range ss.rules {
+		if r.matches(e, ancestors) {
+			matched = append(matched, r)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].specificity != matched[j].specificity {
+			return matched[i].specificity < matched[j].specificity
+		}
+		return matched[i].order < matched[j].order
+	})
+
+	var decls []svgDeclaration
+	for _, r := range matched {
+		decls = append(decls, r.decls...)
+	}
+	return decls
+}
+
+// computeStyle returns the computed style of the specified element, based on
+// the computed style of its parent. The properties are resolved in increasing
+// order of precedence: inherited values, presentation attributes, style sheet
+// rules and the style attribute. Important declarations take precedence over
+// regular ones.
+func (ss *svgStyleSheet) computeStyle(e *GraphicSVGElement, parent *svgStyle, ancestors []*GraphicSVGElement) *svgStyle {
+	style := &svgStyle{props: map[string]string{}}
+	if parent != nil {
+		for name, v := range parent.props {
+			if svgProperties[name] {
+				style.props[name] = v
+			}
+		}
+	}
+
+	var decls []svgDeclaration
+	for name, v := range e.Attributes {
+		if _, ok := svgProperties[name]; ok {
+			decls = append(decls, svgDeclaration{name: name, value: strings.TrimSpace(v)})
+		}
+	}
+	// Sort the presentation attributes, so that the shorthand font
+	// property is applied before the longhand ones.
+	sort.Slice(decls, func(i, j int) bool { return decls[i].name < decls[j].name })
+	decls = append(decls, ss.declarations(e, ancestors)...)
+	decls = append(decls, parseSVGDeclarations(e.Attributes["style"])...)
+
+	parentFontSize := 16.0
+	if parent != nil {
+		parentFontSize = parent.fontSize()
+	}
+	apply := func(d svgDeclaration) {
+		if d.value == "inherit" {
+			if parent != nil {
+				d.value = parent.get(d.name)
+			} else {
+				d.value = svgDefaults[d.name]
+			}
+		}
+		switch d.name {
+		case "font":
+			style.applyFontShorthand(d.value, parentFontSize)
+		case "font-size":
+			if size, ok := parseSVGFontSize(d.value, parentFontSize); ok {
+				style.props[d.name] = strconv.FormatFloat(size, 'f', -1, 64)
+			}
+		default:
+			style.props[d.name] = d.value
+		}
+	}
+	for _, d := range decls {
+		if !d.important {
+			apply(d)
+		}
+	}
+	for _, d := range decls {
+		if d.important {
+			apply(d)
+		}
+	}
+	return style
+}
+
+// applyFontShorthand applies the specified value of the font shorthand
+// property (e.g. "italic bold 12px Arial, sans-serif").
+func (s *svgStyle) applyFontShorthand(value string, parentFontSize float64) {
+	fields := strings.Fields(value)
+	for i, f := range fields {
+		switch lf := strings.ToLower(f); lf {
+		case "italic", "oblique":
+			s.props["font-style"] = lf
+			continue
+		case "bold", "bolder", "lighter", "100", "200", "300", "400", "500", "600", "700", "800", "900":
+			s.props["font-weight"] = lf
+			continue
+		case "normal", "small-caps":
+			continue
+		}
+
+		size := f
+		if j := strings.IndexByte(size, '/'); j >= 0 {
+			size = size[:j]
+		}
+		if v, ok := parseSVGFontSize(size, parentFontSize); ok {
+			s.props["font-size"] = strconv.FormatFloat(v, 'f', -1, 64)
+			if family := strings.Join(fields[i+1:], " "); family != "" {
+				s.props["font-family"] = family
+			}
+		}
+		return
+	}
+}
+
+// parseSVGFloat parses the specified floating point number.
+func parseSVGFloat(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+// svgUnits maps the absolute CSS length units to user units. User units are
+// interpreted as pixels, which are converted to points using the same ratio
+// as the rest of the SVG implementation.
+var svgUnits = map[string]float64{
+	"":   1,
+	"px": 1,
+	"pt": 1 / _degac,
+	"pc": 12 / _degac,
+	"mm": PPMM / _degac,
+	"cm": 10 * PPMM / _degac,
+	"in": PPI / _degac,
+}
+
+// parseSVGLength parses the specified length. Percentages are resolved
+// relative to `ref`, and font relative units relative to `fontSize`.
+func parseSVGLength(s string, ref, fontSize float64) (float64, bool) {
+	s = strings.TrimSpace(s)
+	end := len(s)
+	for end > 0 {
+		c := s[end-1]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '%' {
+			end--
+			continue
+		}
+		break
+	}
+	v, ok := parseSVGFloat(s[:end])
+	if !ok {
+		return 0, false
+	}
+
+	switch unit := strings.ToLower(s[end:]); unit {
+	case "%":
+		return v * ref / 100, true
+	case "em":
+		return v * fontSize, true
+	case "ex":
+		return v * fontSize / 2, true
+	default:
+		factor, ok := svgUnits[unit]
+		if !ok {
+			return 0, false
+		}
+		return v * factor, true
+	}
+}
+
+// parseSVGFontSize parses the specified font size, relative to the font size
+// of the parent element.
+func parseSVGFontSize(s string, parentFontSize float64) (float64, bool) {
+	keywords := map[string]float64{
+		"xx-small": 9, "x-small": 10, "small": 13, "medium": 16,
+		"large": 18, "x-large": 24, "xx-large": 32,
+		"smaller": parentFontSize / 1.2, "larger": parentFontSize * 1.2,
+	}
+	if v, ok := keywords[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return v, true
+	}
+	return parseSVGLength(s, parentFontSize, parentFontSize)
+}
+
+// parseSVGNumbers parses a list of numbers separated by whitespace and/or
+// commas.
+func parseSVGNumbers(s string) []float64 {
+	var nums []float64
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}) {
+		v, ok := parseSVGFloat(f)
+		if !ok {
+			return nums
+		}
+		nums = append(nums, v)
+	}
+	return nums
+}
+
+// parseSVGTransform parses the specified transform list attribute.
+func parseSVGTransform(s string) transform.Matrix {
+	m := transform.IdentityMatrix()
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,")
+		open := strings.IndexByte(s, '(')
+		if open < 0 {
+			return m
+		}
+		end := strings.IndexByte(s, ')')
+		if end < open {
+			return m
+		}
+		name := strings.TrimSpace(s[:open])
+		args := parseSVGNumbers(s[open+1 : end])
+		s = s[end+1:]
+
+		arg := func(i int, def float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return def
+		}
+		var t transform.Matrix
+		switch name {
+		case "matrix":
+			if len(args) != 6 {
+				return m
+			}
+			t = transform.NewMatrix(args[0], args[1], args[2], args[3], args[4], args[5])
+		case "translate":
+			t = transform.TranslationMatrix(arg(0, 0), arg(1, 0))
+		case "scale":
+			sx := arg(0, 1)
+			t = transform.ScaleMatrix(sx, arg(1, sx))
+		case "rotate":
+			a := arg(0, 0) * math.Pi / 180
+			cx, cy := arg(1, 0), arg(2, 0)
+			t = transform.TranslationMatrix(cx, cy).Mult(transform.RotationMatrix(a)).Mult(transform.TranslationMatrix(-cx, -cy))
+		case "skewX":
+			t = transform.NewMatrix(1, 0, math.Tan(arg(0, 0)*math.Pi/180), 1, 0, 0)
+		case "skewY":
+			t = transform.NewMatrix(1, math.Tan(arg(0, 0)*math.Pi/180), 0, 1, 0, 0)
+		default:
+			return m
+		}
+		m = m.Mult(t)
+	}
+}
+
+// svgColor represents an RGB color with an alpha component.
+type svgColor struct {
+	r, g, b, a float64
+}
+
+// parseSVGColor parses the specified color value. The `currentColor` keyword
+// is resolved to the specified current color.
+func parseSVGColor(s string, current svgColor) (svgColor, bool) {
+	s = strings.TrimSpace(s)
+	ls := strings.ToLower(s)
+	switch {
+	case ls == "currentcolor":
+		return current, true
+	case ls == "transparent":
+		return svgColor{a: 0}, true
+	case strings.HasPrefix(ls, "#"):
+		hex := ls[1:]
+		if len(hex) == 3 || len(hex) == 4 {
+			var expanded []byte
+			for i := 0; i < len(hex); i++ {
+				expanded = append(expanded, hex[i], hex[i])
+			}
+			hex = string(expanded)
+		}
+		if len(hex) != 6 && len(hex) != 8 {
+			return svgColor{}, false
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return svgColor{}, false
+		}
+		alpha := 1.0
+		if len(hex) == 8 {
+			alpha = float64(v&0xff) / 255
+			v >>= 8
+		}
+		return svgColor{
+			r: float64(v>>16&0xff) / 255,
+			g: float64(v>>8&0xff) / 255,
+			b: float64(v&0xff) / 255,
+			a: alpha,
+		}, true
+	case strings.HasPrefix(ls, "rgb"), strings.HasPrefix(ls, "hsl"):
+		open, end := strings.IndexByte(ls, '('), strings.IndexByte(ls, ')')
+		if open < 0 || end < open {
+			return svgColor{}, false
+		}
+		args := strings.FieldsFunc(ls[open+1:end], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '/'
+		})
+		if len(args) < 3 {
+			return svgColor{}, false
+		}
+		component := func(s string, max float64) float64 {
+			if strings.HasSuffix(s, "%") {
+				v, _ := parseSVGFloat(strings.TrimSuffix(s, "%"))
+				return math.Max(0, math.Min(v/100, 1))
+			}
+			v, _ := parseSVGFloat(strings.TrimSuffix(s, "deg"))
+			return math.Max(0, math.Min(v/max, 1))
+		}
+		c := svgColor{a: 1}
+		if len(args) > 3 {
+			c.a = component(args[3], 1)
+		}
+		if strings.HasPrefix(ls, "rgb") {
+			c.r, c.g, c.b = component(args[0], 255), component(args[1], 255), component(args[2], 255)
+			return c, true
+		}
+
+		h, _ := parseSVGFloat(strings.TrimSuffix(args[0], "deg"))
+		h = math.Mod(math.Mod(h, 360)+360, 360) / 60
+		sat, light := component(args[1], 1), component(args[2], 1)
+		chroma := (1 - math.Abs(2*light-1)) * sat
+		x := chroma * (1 - math.Abs(math.Mod(h, 2)-1))
+		var r, g, b float64
+		switch int(h) {
+		case 0:
+			r, g = chroma, x
+		case 1:
+			r, g = x, chroma
+		case 2:
+			g, b = chroma, x
+		case 3:
+			g, b = x, chroma
+		case 4:
+			r, b = x, chroma
+		default:
+			r, b = chroma, x
+		}
+		m := light - chroma/2
+		c.r, c.g, c.b = r+m, g+m, b+m
+		return c, true
+	}
+
+	if rgba, ok := graphic2d.ColorMap[ls]; ok {
+		return svgColor{r: float64(rgba.R) / 255, g: float64(rgba.G) / 255, b: float64(rgba.B) / 255, a: 1}, true
+	}
+	return svgColor{}, false
+}
+
+// svgPaint represents the value of the fill and stroke properties.
+type svgPaint struct {
+	none     bool
+	color    svgColor
+	ref      string
+	fallback *svgPaint
+}
+
+// parseSVGPaint parses the specified paint value.
+func parseSVGPaint(s string, current svgColor) svgPaint {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "none") {
+		return svgPaint{none: true}
+	}
+	if ref, rest := parseSVGURL(s); ref != "" {
+		p := svgPaint{ref: ref}
+		if rest = strings.TrimSpace(rest); rest != "" {
+			fallback := parseSVGPaint(rest, current)
+			p.fallback = &fallback
+		}
+		return p
+	}
+	c, ok := parseSVGColor(s, current)
+	if !ok {
+		return svgPaint{none: true}
+	}
+	return svgPaint{color: c}
+}
+
+// parseSVGURL parses a functional IRI reference (e.g. "url(#id)"). Returns
+// the referenced ID and the remaining text.
+func parseSVGURL(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "url(") {
+		return "", s
+	}
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return "", s
+	}
+	ref := strings.Trim(strings.TrimSpace(s[4:end]), `"'`)
+	return strings.TrimPrefix(ref, "#"), s[end+1:]
+}
+
+// svgHref returns the ID referenced by the href attribute of the specified
+// element.
+func svgHref(e *GraphicSVGElement) string {
+	href, ok := e.Attributes["href"]
+	if !ok {
+		return ""
+	}
+	href = strings.TrimSpace(href)
+	if !strings.HasPrefix(href, "#") {
+		return ""
+	}
+	return href[1:]
+}
@@ -0,0 +1,93 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+)
+
+// contentText writes the text shown by the specified content stream to `b`,
+// including the text of the forms found in its resources. Text objects end
+// with a new line, and words separated by a kerning adjustment are separated
+// by spaces.
+func contentText(t *testing.T, b *strings.Builder, content string, resources core.PdfObject, depth int) {
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		t.Fatalf("unable to parse content stream: %v", err)
+	}
+	for _, op := range *ops {
+		switch op.Operand {
+		case "ET":
+			b.WriteString("\n")
+		case "Tj", "'":
+			if len(op.Params) == 1 {
+				s, _ := core.GetStringVal(op.Params[0])
+				b.WriteString(s)
+			}
+		case "TJ":
+			if len(op.Params) != 1 {
+				continue
+			}
+			arr, _ := core.GetArray(op.Params[0])
+			for _, obj := range arr.Elements() {
+				if s, ok := core.GetStringVal(obj); ok {
+					b.WriteString(s)
+				} else if n, err := core.GetNumberAsFloat(obj); err == nil && n < -100 {
+					b.WriteString(" ")
+				}
+			}
+		case "Do":
+			if len(op.Params) != 1 || depth > 8 {
+				continue
+			}
+			name, _ := core.GetName(op.Params[0])
+			dict, _ := core.GetDict(resources)
+			xobjs, _ := core.GetDict(dict.Get("XObject"))
+			if name == nil || xobjs == nil {
+				continue
+			}
+			stream, ok := core.GetStream(xobjs.Get(*name))
+			if !ok {
+				continue
+			}
+			if subtype, _ := core.GetNameVal(stream.Get("Subtype")); subtype != "Form" {
+				continue
+			}
+			data, err := core.DecodeStream(stream)
+			if err != nil {
+				t.Fatalf("unable to decode form %s: %v", *name, err)
+			}
+			contentText(t, b, string(data), stream.Get("Resources"), depth+1)
+		}
+	}
+}
+
+// creatorPageTexts finalizes the document of the creator and returns the
+// text shown on each of its pages.
+func creatorPageTexts(t *testing.T, c *Creator) []string {
+	if err := c.Finalize(); err != nil {
+		t.Fatalf("unable to finalize document: %v", err)
+	}
+	texts := make([]string, len(c._gcfe))
+	for i, page := range c._gcfe {
+		content, err := page.GetAllContentStreams()
+		if err != nil {
+			t.Fatalf("page %d: unable to get contents: %v", i+1, err)
+		}
+		var b strings.Builder
+		contentText(t, &b, content, page.Resources.ToPdfObject(), 0)
+		texts[i] = b.String()
+	}
+	return texts
+}
@@ -0,0 +1,1122 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/contentstream/draw"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// ChartType represents the type of a vector chart.
+type ChartType int
+
+// Supported vector chart types.
+const (
+	// ChartTypeLine draws each series as a line connecting its values.
+	ChartTypeLine ChartType = iota
+
+	// ChartTypeBar draws the values of the series as grouped vertical bars.
+	ChartTypeBar
+
+	// ChartTypeStackedBar draws the values of the series as vertical bars,
+	// stacked on top of each other.
+	ChartTypeStackedBar
+
+	// ChartTypePie draws the values of the first series as the slices of
+	// a pie.
+	ChartTypePie
+
+	// ChartTypeDonut draws the values of the first series as the slices of
+	// a ring.
+	ChartTypeDonut
+
+	// ChartTypeScatter draws each series as a set of points, positioned
+	// using the X values of the series.
+	ChartTypeScatter
+
+	// ChartTypeArea draws each series as a line, with the area below the
+	// line filled.
+	ChartTypeArea
+)
+
+// isCircular returns true if the chart type has no axes.
+func (t ChartType) isCircular() bool {
+	return t == ChartTypePie || t == ChartTypeDonut
+}
+
+// ChartSeries represents a data series of a vector chart.
+type ChartSeries struct {
+	// Name is the name of the series, displayed in the legend.
+	Name string
+
+	// Values are the values of the series. For pie and donut charts, each
+	// value represents a slice.
+	Values []float64
+
+	// XValues are the X values of scatter chart series. If not set, the
+	// values are distributed evenly.
+	XValues []float64
+
+	// Color is the color of the series. If not set, a color of the chart
+	// palette is used.
+	Color Color
+
+	// AltText is the alternate description of the series, stored in the
+	// structure tree of tagged documents. If not set, a description is
+	// generated out of the name and the values of the series.
+	AltText string
+}
+
+// altText returns the alternate description of the series.
+func (s *ChartSeries) altText(categories []string, format func(float64) string) string {
+	if s.AltText != "" {
+		return s.AltText
+	}
+
+	values := make([]string, len(s.Values))
+	for i, v := range s.Values {
+		switch {
+		case i < len(s.XValues):
+			values[i] = format(s.XValues[i]) + ": " + format(v)
+		case i < len(categories):
+			values[i] = categories[i] + ": " + format(v)
+		default:
+			values[i] = format(v)
+		}
+	}
+
+	name := s.Name
+	if name == "" {
+		name = "Series"
+	}
+	return name + " (" + strings.Join(values, ", ") + ")"
+}
+
+// VectorChart represents a chart component, which is drawn using vector
+// graphics, without relying on an external charting library. The axes,
+// gridlines, legend and data labels of the chart are drawn using the text
+// styles of the chart. In tagged documents, each series of the chart is
+// tagged as a figure having an alternate description, and the remaining
+// contents of the chart are marked as artifacts.
+type VectorChart struct {
+	taggedDrawable
+	kind          ChartType
+	width, height float64
+
+	title      string
+	categories []string
+	series     []*ChartSeries
+
+	titleStyle     TextStyle
+	axisStyle      TextStyle
+	legendStyle    TextStyle
+	dataLabelStyle TextStyle
+
+	showGrid       bool
+	showLegend     bool
+	showDataLabels bool
+
+	axisColor  Color
+	gridColor  Color
+	palette    []Color
+	lineWidth  float64
+	holeRatio  float64
+	valueRange *[2]float64
+	format     func(value float64) string
+
+	margins     Margins
+	positioning Positioning
+	x, y        float64
+}
+
+// NewVectorChart returns a new vector chart component of the specified type
+// and size.
+func (c *Creator) NewVectorChart(kind ChartType, width, height float64) *VectorChart {
+	style := c.NewTextStyle()
+	style.FontSize = 8
+
+	titleStyle := c.NewTextStyle()
+	titleStyle.Font = c._ceeag
+	titleStyle.FontSize = 12
+
+	return &VectorChart{
+		kind:           kind,
+		width:          width,
+		height:         height,
+		titleStyle:     titleStyle,
+		axisStyle:      style,
+		legendStyle:    style,
+		dataLabelStyle: style,
+		showGrid:       true,
+		showLegend:     true,
+		axisColor:      ColorRGBFrom8bit(80, 80, 80),
+		gridColor:      ColorRGBFrom8bit(220, 220, 220),
+		palette: []Color{
+			ColorRGBFromHex("#4e79a7"),
+			ColorRGBFromHex("#f28e2b"),
+			ColorRGBFromHex("#e15759"),
+			ColorRGBFromHex("#76b7b2"),
+			ColorRGBFromHex("#59a14f"),
+			ColorRGBFromHex("#edc948"),
+			ColorRGBFromHex("#b07aa1"),
+			ColorRGBFromHex("#ff9da7"),
+			ColorRGBFromHex("#9c755f"),
+			ColorRGBFromHex("#bab0ac"),
+		},
+		lineWidth:      1.5,
+		holeRatio:      0.5,
+		positioning:    PositionRelative,
+		taggedDrawable: taggedDrawable{_edggf: model.StructureTypeDivision},
+	}
+}
+
+// Type returns the type of the chart.
+func (vc *VectorChart) Type() ChartType {
+	return vc.kind
+}
+
+// SetTitle sets the title of the chart, displayed above the plot area.
+func (vc *VectorChart) SetTitle(title string) {
+	vc.title = title
+}
+
+// SetCategories sets the category labels of the chart, displayed along the
+// horizontal axis. For pie and donut charts, the categories are the labels
+// of the slices.
+func (vc *VectorChart) SetCategories(categories ...string) {
+	vc.categories = categories
+}
+
+// AddSeries adds a new data series to the chart and returns it.
+func (vc *VectorChart) AddSeries(name string, values ...float64) *ChartSeries {
+	series := &ChartSeries{Name: name, Values: values}
+	vc.series = append(vc.series, series)
+	return series
+}
+
+// Series returns the data series of the chart.
+func (vc *VectorChart) Series() []*ChartSeries {
+	return vc.series
+}
+
+// SetTitleStyle sets the text style of the title of the chart.
+func (vc *VectorChart) SetTitleStyle(style TextStyle) {
+	vc.titleStyle = style
+}
+
+// SetAxisStyle sets the text style of the labels of the axes.
+func (vc *VectorChart) SetAxisStyle(style TextStyle) {
+	vc.axisStyle = style
+}
+
+// SetLegendStyle sets the text style of the legend entries.
+func (vc *VectorChart) SetLegendStyle(style TextStyle) {
+	vc.legendStyle = style
+}
+
+// SetDataLabelStyle sets the text style of the data labels.
+func (vc *VectorChart) SetDataLabelStyle(style TextStyle) {
+	vc.dataLabelStyle = style
+}
+
+// SetShowGrid sets whether horizontal gridlines are drawn at the ticks of
+// the value axis.
+func (vc *VectorChart) SetShowGrid(showGrid bool) {
+	vc.showGrid = showGrid
+}
+
+// SetShowLegend sets whether the legend of the chart is displayed.
+func (vc *VectorChart) SetShowLegend(showLegend bool) {
+	vc.showLegend = showLegend
+}
+
+// SetShowDataLabels sets whether the values of the series are displayed
+// next to the data points.
+func (vc *VectorChart) SetShowDataLabels(showDataLabels bool) {
+	vc.showDataLabels = showDataLabels
+}
+
+// SetAxisColor sets the color of the axes.
+func (vc *VectorChart) SetAxisColor(color Color) {
+	vc.axisColor = color
+}
+
+// SetGridColor sets the color of the gridlines.
+func (vc *VectorChart) SetGridColor(color Color) {
+	vc.gridColor = color
+}
+
+// SetPalette sets the colors used for the series which do not have a color.
+func (vc *VectorChart) SetPalette(colors ...Color) {
+	if len(colors) > 0 {
+		vc.palette = colors
+	}
+}
+
+// SetLineWidth sets the width of the lines of line, area and scatter charts.
+func (vc *VectorChart) SetLineWidth(width float64) {
+	vc.lineWidth = width
+}
+
+// SetHoleRatio sets the ratio between the radius of the hole and the radius
+// of donut charts, in the [0, 1) interval. The default ratio is 0.5.
+func (vc *VectorChart) SetHoleRatio(ratio float64) {
+	vc.holeRatio = math.Max(0, math.Min(ratio, 0.95))
+}
+
+// SetValueRange sets the range of the value axis. By default, the range is
+// computed out of the values of the series.
+func (vc *VectorChart) SetValueRange(min, max float64) {
+	vc.valueRange = &[2]float64{min, max}
+}
+
+// SetValueFormat sets the function used to format the values displayed on
+// the value axis and in the data labels.
+func (vc *VectorChart) SetValueFormat(format func(value float64) string) {
+	vc.format = format
+}
+
+// SetMargins sets the margins of the chart.
+func (vc *VectorChart) SetMargins(left, right, top, bottom float64) {
+	vc.margins.Left = left
+	vc.margins.Right = right
+	vc.margins.Top = top
+	vc.margins.Bottom = bottom
+}
+
+// GetMargins returns the margins of the chart: left, right, top, bottom.
+func (vc *VectorChart) GetMargins() (float64, float64, float64, float64) {
+	return vc.margins.Left, vc.margins.Right, vc.margins.Top, vc.margins.Bottom
+}
+
+// SetPos sets the absolute position of the chart. Changes the positioning
+// of the chart to absolute.
+func (vc *VectorChart) SetPos(x, y float64) {
+	vc.positioning = PositionAbsolute
+	vc.x = x
+	vc.y = y
+}
+
+// Width returns the width of the chart.
+func (vc *VectorChart) Width() float64 {
+	return vc.width
+}
+
+// Height returns the height of the chart.
+func (vc *VectorChart) Height() float64 {
+	return vc.height
+}
+
+// AddTag adds the structure element of the chart to the specified parent
+// structure element. The structure elements of the series of the chart are
+// added to the structure element of the chart, when the chart is drawn.
+func (vc *VectorChart) AddTag(rootKObj *model.KDict) {
+	if rootKObj == nil {
+		return
+	}
+	if vc._bffbg == nil {
+		vc._bffbg = model.NewStructureTagInfo()
+		vc._bffbg.StructureType = vc._edggf
+	}
+	vc._bffbg.ApplyTag = true
+	vc._bffbg.ParentKObj = rootKObj
+	vc._bffbg.ComponentKObj = model.NewKDictionary()
+	vc._bffbg.ComponentKObj.S = core.MakeName(string(vc._bffbg.StructureType))
+	vc._bffbg.ParentKObj.AddKChild(vc._bffbg.ComponentKObj)
+}
+
+// markedContentCount returns the number of marked content sequences the
+// tagged chart uses, starting at its marked content ID.
+func (vc *VectorChart) markedContentCount() int64 {
+	if n := len(vc.visibleSeries()); n > 0 {
+		return int64(n)
+	}
+	return 1
+}
+
+// isTagged returns true if the chart is tagged for accessibility.
+func (vc *VectorChart) isTagged() bool {
+	return vc._bffbg != nil && vc._bffbg.ApplyTag && vc._bffbg.ComponentKObj != nil
+}
+
+// visibleSeries returns the series drawn by the chart.
+func (vc *VectorChart) visibleSeries() []*ChartSeries {
+	if vc.kind.isCircular() && len(vc.series) > 1 {
+		return vc.series[:1]
+	}
+	return vc.series
+}
+
+// seriesColor returns the color of the series at the specified index.
+func (vc *VectorChart) seriesColor(i int) Color {
+	if i < len(vc.series) && vc.series[i].Color != nil {
+		return vc.series[i].Color
+	}
+	return vc.palette[i%len(vc.palette)]
+}
+
+// sliceColor returns the color of the pie slice at the specified index.
+func (vc *VectorChart) sliceColor(i int) Color {
+	return vc.palette[i%len(vc.palette)]
+}
+
+// formatValue formats the specified value, using the specified number of
+// decimals, unless a custom value format is set.
+func (vc *VectorChart) formatValue(value float64, decimals int) string {
+	if vc.format != nil {
+		return vc.format(value)
+	}
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
+
+// niceNumber returns a "nice" number approximately equal to x. The number is
+// rounded if `round` is true, and ceiled otherwise.
+func niceNumber(x float64, round bool) float64 {
+	exp := math.Floor(math.Log10(x))
+	f := x / math.Pow(10, exp)
+
+	var nf float64
+	if round {
+		switch {
+		case f < 1.5:
+			nf = 1
+		case f < 3:
+			nf = 2
+		case f < 7:
+			nf = 5
+		default:
+			nf = 10
+		}
+	} else {
+		switch {
+		case f <= 1:
+			nf = 1
+		case f <= 2:
+			nf = 2
+		case f <= 5:
+			nf = 5
+		default:
+			nf = 10
+		}
+	}
+	return nf * math.Pow(10, exp)
+}
+
+// chartAxis represents a linear axis of a chart.
+type chartAxis struct {
+	min, max, step float64
+	decimals       int
+}
+
+// newChartAxis returns an axis covering the specified range, having round
+// tick values.
+func newChartAxis(min, max float64, ticks int) chartAxis {
+	if max < min {
+		min, max = max, min
+	}
+	if max == min {
+		switch {
+		case max > 0:
+			min = 0
+		case max < 0:
+			max = 0
+		default:
+			max = 1
+		}
+	}
+
+	step := niceNumber(niceNumber(max-min, false)/float64(ticks-1), true)
+	axis := chartAxis{
+		min:  math.Floor(min/step) * step,
+		max:  math.Ceil(max/step) * step,
+		step: step,
+	}
+	if d := -int(math.Floor(math.Log10(step))); d > 0 {
+		axis.decimals = d
+	}
+	return axis
+}
+
+// ticks returns the tick values of the axis.
+func (a chartAxis) ticks() []float64 {
+	var ticks []float64
+	for v := a.min; v <= a.max+a.step/2; v += a.step {
+		ticks = append(ticks, v)
+	}
+	return ticks
+}
+
+// scale maps the specified value of the axis into the [from, to] interval.
+func (a chartAxis) scale(value, from, to float64) float64 {
+	if a.max == a.min {
+		return from
+	}
+	return from + (value-a.min)/(a.max-a.min)*(to-from)
+}
+
+// valueAxis returns the value (vertical) axis of the chart.
+func (vc *VectorChart) valueAxis() chartAxis {
+	if vc.valueRange != nil {
+		axis := newChartAxis(vc.valueRange[0], vc.valueRange[1], 6)
+		axis.min, axis.max = vc.valueRange[0], vc.valueRange[1]
+		return axis
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	include := func(v float64) {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	if vc.kind == ChartTypeBar || vc.kind == ChartTypeStackedBar || vc.kind == ChartTypeArea {
+		include(0)
+	}
+	if vc.kind == ChartTypeStackedBar {
+		for i := 0; i < vc.categoryCount(); i++ {
+			var pos, neg float64
+			for _, s := range vc.series {
+				if i < len(s.Values) {
+					if s.Values[i] >= 0 {
+						pos += s.Values[i]
+					} else {
+						neg += s.Values[i]
+					}
+				}
+			}
+			include(pos)
+			include(neg)
+		}
+	} else {
+		for _, s := range vc.series {
+			for _, v := range s.Values {
+				include(v)
+			}
+		}
+	}
+	if math.IsInf(min, 0) {
+		min, max = 0, 1
+	}
+	return newChartAxis(min, max, 6)
+}
+
+// xAxis returns the horizontal axis of scatter charts.
+func (vc *VectorChart) xAxis() chartAxis {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, s := range vc.series {
+		for i := range s.Values {
+			x := float64(i)
+			if i < len(s.XValues) {
+				x = s.XValues[i]
+			}
+			min = math.Min(min, x)
+			max = math.Max(max, x)
+		}
+	}
+	if math.IsInf(min, 0) {
+		min, max = 0, 1
+	}
+	return newChartAxis(min, max, 6)
+}
+
+// categoryCount returns the number of categories of the chart.
+func (vc *VectorChart) categoryCount() int {
+	n := len(vc.categories)
+	for _, s := range vc.series {
+		if len(s.Values) > n {
+			n = len(s.Values)
+		}
+	}
+	return n
+}
+
+// chartCanvas holds the state of the drawing of a chart.
+type chartCanvas struct {
+	block  *Block
+	height float64
+}
+
+// pdfY converts the specified vertical position, relative to the top of the
+// chart, to the coordinate system of the content stream.
+func (cv *chartCanvas) pdfY(y float64) float64 {
+	return cv.height - y
+}
+
+// add appends the specified content stream to the canvas.
+func (cv *chartCanvas) add(content []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	return cv.block.addContentsByString(string(content))
+}
+
+// drawShape draws the specified shape on the canvas.
+func (cv *chartCanvas) drawShape(shape interface {
+	Draw(gsName string) ([]byte, *model.PdfRectangle, error)
+}) error {
+	content, _, err := shape.Draw("")
+	return cv.add(content, err)
+}
+
+// drawText draws the specified text on the canvas, aligned inside the box
+// starting at the specified position, having the specified width.
+func (cv *chartCanvas) drawText(text string, style TextStyle, x, y, width float64, align TextAlignment) error {
+	p := _dfae(style)
+	p.SetEnableWrap(false)
+	p.SetTextAlignment(align)
+	p.SetWidth(width)
+	p.SetPos(x, y)
+	p.Append(text)
+	return cv.block.Draw(p)
+}
+
+// beginMarkedContent starts a marked content sequence, having the specified
+// tag and properties.
+func (cv *chartCanvas) beginMarkedContent(tag string, properties map[string]core.PdfObject) error {
+	cc := contentstream.NewContentCreator()
+	if properties == nil {
+		cc.Add_BMC(*core.MakeName(tag))
+	} else {
+		cc.Add_BDC(*core.MakeName(tag), properties)
+	}
+	return cv.block.addContentsByString(cc.String())
+}
+
+// endMarkedContent ends the current marked content sequence.
+func (cv *chartCanvas) endMarkedContent() error {
+	return cv.block.addContentsByString(contentstream.NewContentCreator().Add_EMC().String())
+}
+
+// textWidth returns the width of the specified text, drawn using the
+// specified style.
+func textWidth(text string, style TextStyle) float64 {
+	p := _dfae(style)
+	p.Append(text)
+	return p.getTextWidth() / 1000
+}
+
+// lighten returns the specified color, blended with white.
+func lighten(color Color, amount float64) Color {
+	r, g, b := color.ToRGB()
+	return ColorRGBFromArithmetic(r+(1-r)*amount, g+(1-g)*amount, b+(1-b)*amount)
+}
+
+// plotArea represents the area of the chart the data is plotted on, relative
+// to the top left corner of the chart.
+type plotArea struct {
+	left, top, right, bottom float64
+}
+
+// GeneratePageBlocks draws the chart on a new block representing the page.
+// Implements the Drawable interface.
+func (vc *VectorChart) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
+	page := ctx.Page
+	if vc.positioning.IsRelative() && vc.height > ctx.Height-vc.margins.Top-vc.margins.Bottom {
+		page++
+	}
+
+	cv := &chartCanvas{block: NewBlock(vc.width, vc.height), height: vc.height}
+	if err := vc.draw(cv, page); err != nil {
+		return nil, ctx, err
+	}
+	return placeBlock(ctx, cv.block, vc.margins, vc.positioning, vc.x, vc.y)
+}
+
+// draw draws the chart on the specified canvas. The page number is used for
+// the structure elements of the series.
+func (vc *VectorChart) draw(cv *chartCanvas, page int) error {
+	tagged := vc.isTagged()
+	if tagged {
+		if err := cv.beginMarkedContent("Artifact", nil); err != nil {
+			return err
+		}
+	}
+
+	area := plotArea{left: 0, top: 0, right: vc.width, bottom: vc.height}
+	if vc.title != "" {
+		if err := cv.drawText(vc.title, vc.titleStyle, 0, 0, vc.width, TextAlignmentCenter); err != nil {
+			return err
+		}
+		area.top += vc.titleStyle.FontSize*1.2 + 4
+	}
+	if vc.showLegend {
+		height, err := vc.drawLegend(cv)
+		if err != nil {
+			return err
+		}
+		area.bottom -= height
+	}
+
+	var drawSeries func(i int, s *ChartSeries) error
+	var err error
+	if vc.kind.isCircular() {
+		drawSeries, err = vc.drawCircular(cv, area)
+	} else {
+		drawSeries, err = vc.drawAxes(cv, area)
+	}
+	if err != nil {
+		return err
+	}
+	if tagged {
+		if err := cv.endMarkedContent(); err != nil {
+			return err
+		}
+	}
+
+	format := func(v float64) string { return vc.formatValue(v, -1) }
+	for i, s := range vc.visibleSeries() {
+		if tagged {
+			mcid := vc._bffbg.Mcid + int64(i)
+			err := cv.beginMarkedContent(string(model.StructureTypeFigure), map[string]core.PdfObject{
+				"MCID": core.MakeInteger(mcid),
+			})
+			if err != nil {
+				return err
+			}
+
+			pageNum := int64(page)
+			info := &model.StructureTagInfo{Mcid: mcid, StructureType: model.StructureTypeFigure, StructPageNumber: &pageNum}
+			kdict := info.GenerateKDict()
+			kdict.Alt = core.MakeString(s.altText(vc.categories, format))
+			vc._bffbg.ComponentKObj.AddKChild(kdict)
+		}
+		if err := drawSeries(i, s); err != nil {
+			return err
+		}
+		if tagged {
+			if err := cv.endMarkedContent(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// drawLegend draws the legend of the chart, at the bottom of the chart.
+// Returns the height of the legend.
+func (vc *VectorChart) drawLegend(cv *chartCanvas) (float64, error) {
+	type entry struct {
+		label string
+		color Color
+	}
+	var entries []entry
+	if vc.kind.isCircular() {
+		for i := range vc.circularValues() {
+			label := ""
+			if i < len(vc.categories) {
+				label = vc.categories[i]
+			}
+			entries = append(entries, entry{label: label, color: vc.sliceColor(i)})
+		}
+	} else {
+		for i, s := range vc.series {
+			entries = append(entries, entry{label: s.Name, color: vc.seriesColor(i)})
+		}
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	fontSize := vc.legendStyle.FontSize
+	swatch := fontSize * 0.8
+	widths := make([]float64, len(entries))
+	total := 0.0
+	for i, e := range entries {
+		widths[i] = swatch + 4 + textWidth(e.label, vc.legendStyle) + 12
+		total += widths[i]
+	}
+
+	lineHeight := fontSize * 1.4
+	height := lineHeight + 6
+	x := math.Max(0, (vc.width-total)/2)
+	y := vc.height - lineHeight
+	for i, e := range entries {
+		if x+widths[i] > vc.width && x > 0 {
+			x = 0
+		}
+		err := cv.drawShape(draw.Rectangle{
+			X:           x,
+			Y:           cv.pdfY(y + (lineHeight+swatch)/2 - fontSize*0.2),
+			Width:       swatch,
+			Height:      swatch,
+			FillEnabled: true,
+			FillColor:   _edaa(e.color),
+			Opacity:     1,
+		})
+		if err != nil {
+			return 0, err
+		}
+		if err := cv.drawText(e.label, vc.legendStyle, x+swatch+4, y, widths[i], TextAlignmentLeft); err != nil {
+			return 0, err
+		}
+		x += widths[i]
+	}
+	return height, nil
+}
+
+// drawAxes draws the axes, gridlines and labels of the chart. Returns the
+// function which draws the specified series.
+func (vc *VectorChart) drawAxes(cv *chartCanvas, area plotArea) (func(i int, s *ChartSeries) error, error) {
+	yAxis := vc.valueAxis()
+	yTicks := yAxis.ticks()
+
+	labelWidth := 0.0
+	for _, v := range yTicks {
+		labelWidth = math.Max(labelWidth, textWidth(vc.formatValue(v, yAxis.decimals), vc.axisStyle))
+	}
+	fontSize := vc.axisStyle.FontSize
+	plot := plotArea{
+		left:   area.left + labelWidth + 6,
+		top:    area.top + fontSize/2 + 2,
+		right:  area.right - 6,
+		bottom: area.bottom - fontSize*1.4 - 4,
+	}
+	if plot.right <= plot.left || plot.bottom <= plot.top {
+		return nil, fmt.Errorf("chart size %.1fx%.1f too small", vc.width, vc.height)
+	}
+
+	scaleY := func(v float64) float64 {
+		return cv.pdfY(yAxis.scale(v, plot.bottom, plot.top))
+	}
+	axisColor, gridColor := _edaa(vc.axisColor), _edaa(vc.gridColor)
+
+	for _, v := range yTicks {
+		y := scaleY(v)
+		if vc.showGrid {
+			err := cv.drawShape(draw.BasicLine{X1: plot.left, Y1: y, X2: plot.right, Y2: y, LineColor: gridColor, LineWidth: 0.5, Opacity: 1})
+			if err != nil {
+				return nil, err
+			}
+		}
+		label := vc.formatValue(v, yAxis.decimals)
+		err := cv.drawText(label, vc.axisStyle, area.left, cv.pdfY(y)-fontSize*0.6, labelWidth, TextAlignmentRight)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Horizontal axis, drawn at the zero value if in range.
+	baseValue := math.Max(yAxis.min, math.Min(0, yAxis.max))
+	axisY := scaleY(baseValue)
+	lines := []draw.BasicLine{
+		{X1: plot.left, Y1: cv.pdfY(plot.bottom), X2: plot.left, Y2: cv.pdfY(plot.top), LineColor: axisColor, LineWidth: 0.75, Opacity: 1},
+		{X1: plot.left, Y1: axisY, X2: plot.right, Y2: axisY, LineColor: axisColor, LineWidth: 0.75, Opacity: 1},
+	}
+	for _, line := range lines {
+		if err := cv.drawShape(line); err != nil {
+			return nil, err
+		}
+	}
+
+	plotWidth := plot.right - plot.left
+	labelY := plot.bottom + 3
+	if vc.kind == ChartTypeScatter {
+		xAxis := vc.xAxis()
+		for _, v := range xAxis.ticks() {
+			x := xAxis.scale(v, plot.left, plot.right)
+			label := vc.formatValue(v, xAxis.decimals)
+			w := textWidth(label, vc.axisStyle) + 2
+			if err := cv.drawText(label, vc.axisStyle, x-w/2, labelY, w, TextAlignmentCenter); err != nil {
+				return nil, err
+			}
+		}
+		return func(i int, s *ChartSeries) error {
+			return vc.drawPoints(cv, s, vc.seriesColor(i), func(j int) (float64, float64) {
+				x := float64(j)
+				if j < len(s.XValues) {
+					x = s.XValues[j]
+				}
+				return xAxis.scale(x, plot.left, plot.right), scaleY(s.Values[j])
+			})
+		}, nil
+	}
+
+	n := vc.categoryCount()
+	if n == 0 {
+		return func(int, *ChartSeries) error { return nil }, nil
+	}
+	band := plotWidth / float64(n)
+	for i := 0; i < n && i < len(vc.categories); i++ {
+		err := cv.drawText(vc.categories[i], vc.axisStyle, plot.left+float64(i)*band, labelY, band, TextAlignmentCenter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	center := func(j int) float64 {
+		return plot.left + (float64(j)+0.5)*band
+	}
+
+	switch vc.kind {
+	case ChartTypeBar:
+		groupWidth := band * 0.8
+		barWidth := groupWidth / float64(len(vc.series))
+		return func(i int, s *ChartSeries) error {
+			for j, v := range s.Values {
+				x := center(j) - groupWidth/2 + float64(i)*barWidth
+				if err := vc.drawBar(cv, s, vc.seriesColor(i), x, barWidth, scaleY(baseValue), scaleY(v), v, false); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case ChartTypeStackedBar:
+		barWidth := band * 0.6
+		pos, neg := make([]float64, n), make([]float64, n)
+		return func(i int, s *ChartSeries) error {
+			for j, v := range s.Values {
+				stack := pos
+				if v < 0 {
+					stack = neg
+				}
+				from := stack[j]
+				stack[j] += v
+				if err := vc.drawBar(cv, s, vc.seriesColor(i), center(j)-barWidth/2, barWidth, scaleY(from), scaleY(stack[j]), v, true); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	}
+
+	return func(i int, s *ChartSeries) error {
+		point := func(j int) (float64, float64) {
+			return center(j), scaleY(s.Values[j])
+		}
+		color := vc.seriesColor(i)
+		if vc.kind == ChartTypeArea && len(s.Values) > 0 {
+			points := []draw.Point{draw.NewPoint(center(0), axisY)}
+			for j := range s.Values {
+				points = append(points, draw.NewPoint(point(j)))
+			}
+			points = append(points, draw.NewPoint(center(len(s.Values)-1), axisY))
+			err := cv.drawShape(draw.Polygon{
+				Points:      [][]draw.Point{points},
+				FillEnabled: true,
+				FillColor:   _edaa(lighten(color, 0.5)),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(s.Values) > 1 {
+			line := draw.Polyline{LineColor: _edaa(color), LineWidth: vc.lineWidth}
+			for j := range s.Values {
+				line.Points = append(line.Points, draw.NewPoint(point(j)))
+			}
+			if err := cv.drawShape(line); err != nil {
+				return err
+			}
+		}
+		if vc.kind == ChartTypeLine || len(s.Values) == 1 {
+			return vc.drawPoints(cv, s, color, point)
+		}
+		return vc.drawDataLabels(cv, s, point)
+	}, nil
+}
+
+// drawBar draws a bar of the specified series, spanning vertically between
+// the specified content stream coordinates.
+func (vc *VectorChart) drawBar(cv *chartCanvas, s *ChartSeries, color Color, x, width, from, to, value float64, inside bool) error {
+	err := cv.drawShape(draw.Rectangle{
+		X:           x,
+		Y:           math.Min(from, to),
+		Width:       width,
+		Height:      math.Abs(to - from),
+		FillEnabled: true,
+		FillColor:   _edaa(color),
+		Opacity:     1,
+	})
+	if err != nil || !vc.showDataLabels {
+		return err
+	}
+
+	fontSize := vc.dataLabelStyle.FontSize
+	y := cv.pdfY(to) - fontSize*1.2
+	switch {
+	case inside:
+		y = cv.pdfY((from+to)/2) - fontSize*0.6
+	case value < 0:
+		y = cv.pdfY(to) + 1
+	}
+	label := vc.formatValue(value, -1)
+	w := math.Max(width, textWidth(label, vc.dataLabelStyle)+2)
+	return cv.drawText(label, vc.dataLabelStyle, x+width/2-w/2, y, w, TextAlignmentCenter)
+}
+
+// drawPoints draws the data points of the specified series as markers.
+func (vc *VectorChart) drawPoints(cv *chartCanvas, s *ChartSeries, color Color, point func(j int) (float64, float64)) error {
+	size := vc.lineWidth*2 + 2
+	for j := range s.Values {
+		x, y := point(j)
+		err := cv.drawShape(draw.Circle{
+			X:           x - size/2,
+			Y:           y - size/2,
+			Width:       size,
+			Height:      size,
+			FillEnabled: true,
+			FillColor:   _edaa(color),
+			Opacity:     1,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return vc.drawDataLabels(cv, s, point)
+}
+
+// drawDataLabels draws the values of the specified series above the data
+// points, if enabled.
+func (vc *VectorChart) drawDataLabels(cv *chartCanvas, s *ChartSeries, point func(j int) (float64, float64)) error {
+	if !vc.showDataLabels {
+		return nil
+	}
+	fontSize := vc.dataLabelStyle.FontSize
+	for j, v := range s.Values {
+		x, y := point(j)
+		label := vc.formatValue(v, -1)
+		w := textWidth(label, vc.dataLabelStyle) + 2
+		if err := cv.drawText(label, vc.dataLabelStyle, x-w/2, cv.pdfY(y)-fontSize*1.4, w, TextAlignmentCenter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// circularValues returns the positive values of the first series, which are
+// drawn as the slices of pie and donut charts.
+func (vc *VectorChart) circularValues() []float64 {
+	if len(vc.series) == 0 {
+		return nil
+	}
+	values := make([]float64, len(vc.series[0].Values))
+	for i, v := range vc.series[0].Values {
+		values[i] = math.Max(v, 0)
+	}
+	return values
+}
+
+// arcCurves returns the Bézier curves approximating the arc of the circle
+// having the specified center and radius, between the specified angles.
+func arcCurves(cx, cy, r, from, to float64) []draw.CubicBezierCurve {
+	segments := int(math.Ceil(math.Abs(to-from) / (math.Pi / 2)))
+	if segments < 1 {
+		segments = 1
+	}
+	delta := (to - from) / float64(segments)
+	k := 4.0 / 3.0 * math.Tan(delta/4)
+
+	curves := make([]draw.CubicBezierCurve, segments)
+	for i := range curves {
+		a0 := from + float64(i)*delta
+		a1 := a0 + delta
+		x0, y0 := cx+r*math.Cos(a0), cy+r*math.Sin(a0)
+		x3, y3 := cx+r*math.Cos(a1), cy+r*math.Sin(a1)
+		curves[i] = draw.NewCubicBezierCurve(
+			x0, y0,
+			x0-k*r*math.Sin(a0), y0+k*r*math.Cos(a0),
+			x3+k*r*math.Sin(a1), y3-k*r*math.Cos(a1),
+			x3, y3,
+		)
+	}
+	return curves
+}
+
+// lineCurve returns a Bézier curve representing the straight line between
+// the specified points.
+func lineCurve(x0, y0, x1, y1 float64) draw.CubicBezierCurve {
+	return draw.NewCubicBezierCurve(x0, y0, x0, y0, x1, y1, x1, y1)
+}
+
+// drawCircular returns the function which draws the slices of pie and donut
+// charts.
+func (vc *VectorChart) drawCircular(cv *chartCanvas, area plotArea) (func(i int, s *ChartSeries) error, error) {
+	values := vc.circularValues()
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+
+	radius := math.Min(area.right-area.left, area.bottom-area.top)/2 - 4
+	if radius <= 0 {
+		return nil, fmt.Errorf("chart size %.1fx%.1f too small", vc.width, vc.height)
+	}
+	cx := (area.left + area.right) / 2
+	cy := cv.pdfY((area.top + area.bottom) / 2)
+	inner := 0.0
+	if vc.kind == ChartTypeDonut {
+		inner = radius * vc.holeRatio
+	}
+
+	return func(_ int, s *ChartSeries) error {
+		if total <= 0 {
+			return nil
+		}
+
+		// Slices start at the top of the circle and go clockwise.
+		angle := math.Pi / 2
+		for j, v := range values {
+			if v == 0 {
+				continue
+			}
+			sweep := v / total * 2 * math.Pi
+			from, to := angle, angle-sweep
+			angle = to
+
+			outer := arcCurves(cx, cy, radius, from, to)
+			var ring []draw.CubicBezierCurve
+			if inner > 0 {
+				innerArc := arcCurves(cx, cy, inner, to, from)
+				ring = append(ring, outer...)
+				ring = append(ring, lineCurve(outer[len(outer)-1].P3.X, outer[len(outer)-1].P3.Y, innerArc[0].P0.X, innerArc[0].P0.Y))
+				ring = append(ring, innerArc...)
+				ring = append(ring, lineCurve(innerArc[len(innerArc)-1].P3.X, innerArc[len(innerArc)-1].P3.Y, outer[0].P0.X, outer[0].P0.Y))
+			} else {
+				ring = append(ring, lineCurve(cx, cy, outer[0].P0.X, outer[0].P0.Y))
+				ring = append(ring, outer...)
+				ring = append(ring, lineCurve(outer[len(outer)-1].P3.X, outer[len(outer)-1].P3.Y, cx, cy))
+			}
+
+			err := cv.drawShape(draw.CurvePolygon{
+				Rings:         [][]draw.CubicBezierCurve{ring},
+				FillEnabled:   true,
+				FillColor:     _edaa(vc.sliceColor(j)),
+				BorderEnabled: true,
+				BorderColor:   model.NewPdfColorDeviceRGB(1, 1, 1),
+				BorderWidth:   1,
+			})
+			if err != nil {
+				return err
+			}
+
+			if vc.showDataLabels {
+				mid := (from + to) / 2
+				r := (radius + inner) / 2
+				if inner == 0 {
+					r = radius * 0.65
+				}
+				label := vc.formatValue(s.Values[j], -1)
+				w := textWidth(label, vc.dataLabelStyle) + 2
+				x := cx + r*math.Cos(mid)
+				y := cv.pdfY(cy + r*math.Sin(mid))
+				err := cv.drawText(label, vc.dataLabelStyle, x-w/2, y-vc.dataLabelStyle.FontSize*0.6, w, TextAlignmentCenter)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}, nil
+}
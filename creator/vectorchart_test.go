@@ -0,0 +1,49 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package creator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVectorChartBar(t *testing.T) {
+	c := New()
+	c.NewPage()
+	chart := c.NewVectorChart(ChartTypeBar, 300, 200)
+	chart.SetTitle("Quarterly sales")
+	chart.SetCategories("Q1", "Q2", "Q3")
+	chart.AddSeries("North", 10, 25, 40)
+	chart.AddSeries("South", 5, 15, 35)
+
+	axis := chart.valueAxis()
+	if ticks := axis.ticks(); !reflect.DeepEqual(ticks, []float64{0, 10, 20, 30, 40}) {
+		t.Fatalf("unexpected value axis ticks %v", ticks)
+	}
+
+	// The values of stacked bars add up.
+	stacked := c.NewVectorChart(ChartTypeStackedBar, 300, 200)
+	stacked.AddSeries("North", 10, 25, 40)
+	stacked.AddSeries("South", 5, 15, 35)
+	if axis := stacked.valueAxis(); axis.min != 0 || axis.max != 80 {
+		t.Fatalf("unexpected stacked value axis range %.2f-%.2f", axis.min, axis.max)
+	}
+
+	if err := c.Draw(chart); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	text := creatorPageTexts(t, c)[0]
+	for _, expected := range []string{"Quarterly sales", "Q1", "Q2", "Q3", "North", "South", "40"} {
+		if !strings.Contains(text, expected) {
+			t.Fatalf("%q not drawn: %q", expected, text)
+		}
+	}
+}
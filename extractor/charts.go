@@ -0,0 +1,1048 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/internal/transform"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// ChartKind is the kind of a chart.
+type ChartKind int
+
+// Kinds of charts.
+const (
+	ChartBar ChartKind = iota
+	ChartLine
+	ChartPie
+)
+
+// String returns the name of the chart kind.
+func (k ChartKind) String() string {
+	switch k {
+	case ChartBar:
+		return "bar"
+	case ChartLine:
+		return "line"
+	case ChartPie:
+		return "pie"
+	}
+	return "unknown"
+}
+
+// ChartDetectionOptions are the options of the detection of charts.
+type ChartDetectionOptions struct {
+	// MinTicks is the minimum number of labeled ticks of a value axis.
+	MinTicks int
+
+	// ColumnGap is the minimum horizontal gap between separate labels, as a ratio of the font size.
+	ColumnGap float64
+}
+
+// DefaultChartDetectionOptions returns the default options of the detection of charts.
+func DefaultChartDetectionOptions() *ChartDetectionOptions {
+	return &ChartDetectionOptions{MinTicks: 3, ColumnGap: 0.9}
+}
+
+// Chart is a chart recovered from the vector graphics and the text of a page.
+type Chart struct {
+	Kind ChartKind
+
+	// Horizontal is true for bar charts with horizontal bars. Their value axis is horizontal and their
+	// category axis vertical.
+	Horizontal bool
+
+	// BBox is the plot area of bar and line charts and the bounding box of pie charts.
+	BBox model.PdfRectangle
+
+	// ValueAxis is the numeric axis of the values of bar and line charts. CategoryAxis is the other axis,
+	// with the labels of the categories or numeric labels. They are nil for pie charts.
+	ValueAxis, CategoryAxis *ChartAxis
+
+	Series []ChartSeries
+}
+
+// ChartAxis is an axis of a chart and its labeled ticks.
+type ChartAxis struct {
+	Ticks []ChartTick
+
+	// Numeric is true for axes with numeric labels. Their positions map to values as
+	// Scale*position + Offset.
+	Numeric       bool
+	Scale, Offset float64
+}
+
+// Value returns the value at the page coordinate `position` along a numeric axis.
+func (a *ChartAxis) Value(position float64) float64 {
+	return a.Scale*position + a.Offset
+}
+
+// ChartTick is a labeled tick of an axis.
+type ChartTick struct {
+	Label string
+
+	// Value is the value of the label of a numeric axis.
+	Value float64
+
+	// Position is the page coordinate of the tick along the axis: the y coordinate for vertical axes and
+	// the x coordinate for horizontal axes.
+	Position float64
+}
+
+// ChartSeries is a data series of a chart.
+type ChartSeries struct {
+	// Name is the label of the series in the legend of the chart, if any.
+	Name  string
+	Color color.Color
+
+	Points []ChartPoint
+}
+
+// ChartPoint is a data point of a series.
+type ChartPoint struct {
+	// Category is the label of the category of the point, if any.
+	Category string
+
+	// X is the index of the category of the point on a category axis, or its value on a numeric category
+	// axis. For pie charts, it is the index of the slice.
+	X float64
+
+	// Y is the value of the point. For pie charts, it is the fraction of the slice of the whole pie.
+	Y float64
+
+	// Color is the color the point is drawn with.
+	Color color.Color
+}
+
+// ExtractCharts returns the charts of the page, detected from its text and vector graphics. See
+// PageText.DetectCharts. The options parameter can be nil for the default options.
+func (e *Extractor) ExtractCharts(options *ChartDetectionOptions) ([]Chart, error) {
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := e.ExtractPageVectors(&VectorExtractOptions{DisableShapes: true})
+	if err != nil {
+		return nil, err
+	}
+	return pt.DetectCharts(vectors, options), nil
+}
+
+// DetectCharts returns the bar, line and pie charts drawn by `vectors`, the vector graphics of the page.
+// Bar and line charts are found from a numeric value axis: a column (or, for horizontal bar charts, a row)
+// of numeric tick labels whose values are linear in their positions. The bars and lines in the plot area
+// next to the axis are mapped to values through the axis, assigned to the category labels along the other
+// axis and grouped into series by color. Pie charts are found from filled slices around a common center.
+// Series are named from legend entries: color swatches followed by a label.
+func (pt PageText) DetectCharts(vectors *PageVectors, options *ChartDetectionOptions) []Chart {
+	if options == nil {
+		options = DefaultChartDetectionOptions()
+	}
+	if vectors == nil {
+		return nil
+	}
+	scene := newChartScene(pt, vectors, options)
+	charts := scene.axisCharts(options, false)
+	for _, c := range scene.transpose().axisCharts(options, true) {
+		if c.Kind != ChartBar || chartsOverlap(c, charts) {
+			continue
+		}
+		charts = append(charts, c)
+	}
+	for _, c := range scene.pieCharts() {
+		if !chartsOverlap(c, charts) {
+			charts = append(charts, c)
+		}
+	}
+	sort.SliceStable(charts, func(i, j int) bool {
+		if math.Abs(charts[i].BBox.Ury-charts[j].BBox.Ury) > 1 {
+			return charts[i].BBox.Ury > charts[j].BBox.Ury
+		}
+		return charts[i].BBox.Llx < charts[j].BBox.Llx
+	})
+	return charts
+}
+
+// chartsOverlap returns true if the plot area of `c` overlaps the plot area of one of `charts` by more
+// than half.
+func chartsOverlap(c Chart, charts []Chart) bool {
+	for _, o := range charts {
+		w := math.Min(c.BBox.Urx, o.BBox.Urx) - math.Max(c.BBox.Llx, o.BBox.Llx)
+		h := math.Min(c.BBox.Ury, o.BBox.Ury) - math.Max(c.BBox.Lly, o.BBox.Lly)
+		if w > 0 && h > 0 && w*h > 0.5*math.Min(c.BBox.Width()*c.BBox.Height(), o.BBox.Width()*o.BBox.Height()) {
+			return true
+		}
+	}
+	return false
+}
+
+// chartLabel is a run of words of the page that may label a tick, a category or a legend entry.
+type chartLabel struct {
+	bbox    model.PdfRectangle
+	text    string
+	value   float64
+	numeric bool
+	size    float64
+}
+
+// height returns the height of the label in the orientation of the page, also in transposed scenes.
+func (l *chartLabel) height() float64 { return l.size }
+func (l *chartLabel) cx() float64     { return (l.bbox.Llx + l.bbox.Urx) / 2 }
+func (l *chartLabel) cy() float64     { return (l.bbox.Lly + l.bbox.Ury) / 2 }
+
+// chartPart is a subpath of a painted path.
+type chartPart struct {
+	path     *VectorPath
+	segments []VectorSegment
+	vertices []transform.Point
+	bbox     model.PdfRectangle
+	closed   bool
+	curves   bool
+	used     bool
+}
+
+// axisAligned returns true if the part is a horizontal (`horizontal`) or vertical polyline.
+func (p *chartPart) axisAligned(horizontal bool, eps float64) bool {
+	if p.curves || len(p.vertices) < 2 {
+		return false
+	}
+	if horizontal {
+		return p.bbox.Height() <= eps
+	}
+	return p.bbox.Width() <= eps
+}
+
+// rect returns true if the part is a closed axis aligned rectangle.
+func (p *chartPart) rect() bool {
+	if !p.closed || p.curves || len(p.vertices) != 4 || p.bbox.Width() <= 0 || p.bbox.Height() <= 0 {
+		return false
+	}
+	for _, v := range p.vertices {
+		onX := math.Abs(v.X-p.bbox.Llx) < 1e-3 || math.Abs(v.X-p.bbox.Urx) < 1e-3
+		onY := math.Abs(v.Y-p.bbox.Lly) < 1e-3 || math.Abs(v.Y-p.bbox.Ury) < 1e-3
+		if !onX || !onY {
+			return false
+		}
+	}
+	return true
+}
+
+// chartScene holds the labels and the parts of the paths of a page.
+type chartScene struct {
+	labels []*chartLabel
+	parts  []*chartPart
+}
+
+func newChartScene(pt PageText, vectors *PageVectors, options *ChartDetectionOptions) *chartScene {
+	scene := &chartScene{}
+	for _, line := range tableLines(tableWords(pt.Marks().Elements()), options.ColumnGap) {
+		for _, seg := range line.segments {
+			label := &chartLabel{bbox: seg.bbox, text: seg.text(), size: seg.bbox.Height()}
+			label.value, label.numeric = parseChartNumber(label.text)
+			scene.labels = append(scene.labels, label)
+		}
+	}
+	for _, p := range vectors.Paths {
+		for _, sp := range p.Subpaths {
+			if len(sp.Segments) == 0 {
+				continue
+			}
+			part := &chartPart{path: p, segments: sp.Segments, vertices: sp.Vertices(), bbox: sp.BBox(), closed: sp.Closed}
+			for _, s := range sp.Segments {
+				part.curves = part.curves || s.IsCurve()
+			}
+			if !part.closed && len(part.vertices) > 2 && part.vertices[0].Distance(part.vertices[len(part.vertices)-1]) < 1e-6 {
+				part.closed = true
+				part.vertices = part.vertices[:len(part.vertices)-1]
+			}
+			scene.parts = append(scene.parts, part)
+		}
+	}
+	return scene
+}
+
+// transpose returns the scene with the x and y coordinates swapped, so that horizontal bar charts become
+// vertical ones.
+func (s *chartScene) transpose() *chartScene {
+	t := &chartScene{}
+	for _, l := range s.labels {
+		c := *l
+		c.bbox = transposeRect(l.bbox)
+		t.labels = append(t.labels, &c)
+	}
+	for _, p := range s.parts {
+		c := *p
+		c.bbox = transposeRect(p.bbox)
+		c.vertices = transposePoints(p.vertices)
+		c.segments = make([]VectorSegment, len(p.segments))
+		for i, seg := range p.segments {
+			c.segments[i].Points = transposePoints(seg.Points)
+		}
+		t.parts = append(t.parts, &c)
+	}
+	return t
+}
+
+func transposeRect(r model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{Llx: r.Lly, Lly: r.Llx, Urx: r.Ury, Ury: r.Urx}
+}
+
+func transposePoints(points []transform.Point) []transform.Point {
+	out := make([]transform.Point, len(points))
+	for i, p := range points {
+		out[i] = transform.Point{X: p.Y, Y: p.X}
+	}
+	return out
+}
+
+// chartAxis is a vertical value axis found from a column of numeric labels.
+type chartAxis struct {
+	ticks         []*chartLabel
+	positions     []float64
+	scale, offset float64
+	left, right   float64
+	low, high     float64
+	size          float64
+}
+
+func (a *chartAxis) value(y float64) float64 { return a.scale*y + a.offset }
+
+// valueAxes returns the vertical value axes of the scene: columns of at least `minTicks` numeric labels,
+// aligned on their right edges and regularly spaced, whose values are linear in their positions.
+func (s *chartScene) valueAxes(minTicks int) []*chartAxis {
+	var numeric []*chartLabel
+	for _, l := range s.labels {
+		if l.numeric && l.height() > 0 {
+			numeric = append(numeric, l)
+		}
+	}
+	sort.Slice(numeric, func(i, j int) bool { return numeric[i].bbox.Urx < numeric[j].bbox.Urx })
+	var axes []*chartAxis
+	for i := 0; i < len(numeric); {
+		j := i + 1
+		for j < len(numeric) && numeric[j].bbox.Urx-numeric[i].bbox.Urx <= numeric[i].height() {
+			j++
+		}
+		column := append([]*chartLabel(nil), numeric[i:j]...)
+		i = j
+		sort.Slice(column, func(a, b int) bool { return column[a].cy() < column[b].cy() })
+		for _, run := range regularRuns(column, func(l *chartLabel) float64 { return l.cy() }) {
+			if len(run) < minTicks {
+				continue
+			}
+			if axis, ok := linearAxis(run, labelCenters(run)); ok {
+				axes = append(axes, axis)
+			}
+		}
+	}
+	return axes
+}
+
+// regularRuns splits `labels`, sorted by `pos`, at gaps much larger than the typical gap and at labels on
+// the same position.
+func regularRuns(labels []*chartLabel, pos func(*chartLabel) float64) [][]*chartLabel {
+	if len(labels) < 2 {
+		return [][]*chartLabel{labels}
+	}
+	gaps := make([]float64, 0, len(labels)-1)
+	for i := 1; i < len(labels); i++ {
+		gaps = append(gaps, pos(labels[i])-pos(labels[i-1]))
+	}
+	sorted := append([]float64(nil), gaps...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	var runs [][]*chartLabel
+	start := 0
+	for i, gap := range gaps {
+		if gap > 1.6*median || gap < 0.5*labels[i].height() {
+			runs = append(runs, labels[start:i+1])
+			start = i + 1
+		}
+	}
+	return append(runs, labels[start:])
+}
+
+// labelCenters returns the vertical centers of `labels`.
+func labelCenters(labels []*chartLabel) []float64 {
+	centers := make([]float64, len(labels))
+	for i, l := range labels {
+		centers[i] = l.cy()
+	}
+	return centers
+}
+
+// linearAxis returns the axis of the labels `run` if their values are a linear function of their vertical
+// positions `positions`.
+func linearAxis(run []*chartLabel, positions []float64) (*chartAxis, bool) {
+	n := float64(len(run))
+	var sy, sv, syy, syv float64
+	for i, l := range run {
+		y := positions[i]
+		sy += y
+		sv += l.value
+		syy += y * y
+		syv += y * l.value
+	}
+	d := n*syy - sy*sy
+	if d == 0 {
+		return nil, false
+	}
+	scale := (n*syv - sy*sv) / d
+	offset := (sv - scale*sy) / n
+	if scale == 0 || math.IsNaN(scale) {
+		return nil, false
+	}
+	axis := &chartAxis{ticks: run, positions: positions, scale: scale, offset: offset, left: math.Inf(1),
+		right: math.Inf(-1), low: positions[0], high: positions[len(run)-1]}
+	for i, l := range run {
+		if math.Abs(positions[i]-(l.value-offset)/scale) > 0.35*l.height() {
+			return nil, false
+		}
+		axis.left = math.Min(axis.left, l.bbox.Llx)
+		axis.right = math.Max(axis.right, l.bbox.Urx)
+		axis.size = math.Max(axis.size, l.height())
+	}
+	return axis, true
+}
+
+// axisCharts returns the bar and line charts with a vertical value axis. `horizontal` tells that the
+// scene is transposed and the charts are horizontal.
+func (s *chartScene) axisCharts(options *ChartDetectionOptions, horizontal bool) []Chart {
+	axes := s.valueAxes(options.MinTicks)
+	var charts []Chart
+	for _, axis := range axes {
+		if c, ok := s.axisChart(axis, axes, options, horizontal); ok {
+			charts = append(charts, c)
+		}
+	}
+	return charts
+}
+
+// axisChart returns the chart of the value axis `axis` whose plot area is to the right of its labels.
+func (s *chartScene) axisChart(axis *chartAxis, axes []*chartAxis, options *ChartDetectionOptions,
+	horizontal bool) (Chart, bool) {
+	h := axis.size
+	low, high := axis.low-1.5*h, axis.high+1.5*h
+	barrier := math.Inf(1)
+	for _, o := range axes {
+		if o != axis && o.left > axis.right && o.low < high && o.high > low {
+			barrier = math.Min(barrier, o.left)
+		}
+	}
+	var inside []*chartPart
+	for _, p := range s.parts {
+		if p.bbox.Lly >= low && p.bbox.Ury <= high && p.bbox.Llx >= axis.right-h && p.bbox.Urx <= barrier {
+			inside = append(inside, p)
+		}
+	}
+	sort.SliceStable(inside, func(i, j int) bool { return inside[i].bbox.Llx < inside[j].bbox.Llx })
+	plot := model.PdfRectangle{Llx: axis.right, Urx: axis.right, Lly: axis.low, Ury: axis.high}
+	swatches := s.legend(model.PdfRectangle{Llx: math.Inf(-1), Lly: math.Inf(-1), Urx: math.Inf(1), Ury: math.Inf(1)},
+		h).swatches
+	var parts []*chartPart
+	for _, p := range inside {
+		if swatches[p] {
+			continue
+		}
+		if p.bbox.Llx > plot.Urx+math.Max(3*h, 0.25*(plot.Urx-plot.Llx)) {
+			break
+		}
+		parts = append(parts, p)
+		plot.Urx = math.Max(plot.Urx, p.bbox.Urx)
+		plot.Lly = math.Min(plot.Lly, p.bbox.Lly)
+		plot.Ury = math.Max(plot.Ury, p.bbox.Ury)
+	}
+	w, ht := plot.Width(), plot.Height()
+	if w < 3*h || len(parts) == 0 {
+		return Chart{}, false
+	}
+
+	eps := math.Max(0.5, 0.01*math.Max(w, ht))
+	axis = axis.snap(parts, eps)
+	legend := s.legend(plot, h)
+	var rects, lines []*chartPart
+	for _, p := range parts {
+		switch {
+		case p.used || legend.swatches[p]:
+		case p.axisAligned(true, eps) && (len(p.vertices) == 2 || p.bbox.Width() >= 0.5*w):
+		case p.axisAligned(false, eps) && (len(p.vertices) == 2 || p.bbox.Height() >= 0.5*ht):
+		case p.rect() && p.bbox.Width() >= 0.8*w && p.bbox.Height() >= 0.8*ht:
+		case p.rect() && p.path.Filled:
+			rects = append(rects, p)
+		case p.path.Stroked && !p.path.Filled && !p.curves && len(p.vertices) >= 2:
+			lines = append(lines, p)
+		}
+	}
+
+	chart := Chart{Kind: ChartBar, Horizontal: horizontal, BBox: plot}
+	valueAxis := &ChartAxis{Numeric: true, Scale: axis.scale, Offset: axis.offset}
+	for i, l := range axis.ticks {
+		valueAxis.Ticks = append(valueAxis.Ticks, ChartTick{Label: l.text, Value: l.value, Position: axis.positions[i]})
+	}
+	chart.ValueAxis = valueAxis
+	ticks := axis.ticksSet()
+	categories, categoryAxis := s.categoryAxis(plot, h, ticks, legend.labels, options.MinTicks, horizontal)
+	chart.CategoryAxis = categoryAxis
+
+	position := func(x float64) (float64, string) {
+		return chartCategory(x, categories, categoryAxis)
+	}
+	series := &chartSeriesSet{legend: legend}
+	for _, bar := range axis.bars(rects, eps) {
+		x, category := position((bar.part.bbox.Llx + bar.part.bbox.Urx) / 2)
+		c := bar.part.path.FillColor
+		series.add(c, ChartPoint{Category: category, X: x, Y: bar.value, Color: c})
+	}
+	bars := len(series.series)
+	for _, line := range chartLines(lines, w, eps) {
+		for _, v := range line.vertices {
+			x, category := position(v.X)
+			series.add(line.color, ChartPoint{Category: category, X: x, Y: axis.value(v.Y), Color: line.color})
+		}
+	}
+	if len(series.series) == 0 {
+		return Chart{}, false
+	}
+	if bars == 0 {
+		chart.Kind = ChartLine
+	}
+	chart.Series = series.result(bars)
+	if horizontal {
+		chart.BBox = transposeRect(chart.BBox)
+	}
+	return chart, true
+}
+
+// snap returns the axis with its ticks on the gridlines or tick marks of `parts`, the horizontal lines that
+// start at the axis next to its labels. Labels are not exactly centered on their ticks, so the values of
+// the axis are more accurate this way. The axis is returned as is if a label has no line.
+func (a *chartAxis) snap(parts []*chartPart, eps float64) *chartAxis {
+	positions := make([]float64, len(a.ticks))
+	for i, l := range a.ticks {
+		best := math.Inf(1)
+		for _, p := range parts {
+			if !p.axisAligned(true, eps) || p.bbox.Llx > a.right+2*a.size {
+				continue
+			}
+			y := (p.bbox.Lly + p.bbox.Ury) / 2
+			if d := math.Abs(y - l.cy()); d <= 0.5*l.height() && d < best {
+				best, positions[i] = d, y
+			}
+		}
+		if math.IsInf(best, 1) {
+			return a
+		}
+	}
+	if snapped, ok := linearAxis(a.ticks, positions); ok {
+		return snapped
+	}
+	return a
+}
+
+func (a *chartAxis) ticksSet() map[*chartLabel]bool {
+	set := map[*chartLabel]bool{}
+	for _, l := range a.ticks {
+		set[l] = true
+	}
+	return set
+}
+
+// chartBar is a bar of a bar chart and its value.
+type chartBar struct {
+	part  *chartPart
+	value float64
+}
+
+// bars returns the bars of `rects`: rectangles standing on the base line of the axis (the zero line, or
+// the most common bottom of the rectangles), hanging from it for negative values, or stacked on another
+// bar. Other rectangles, such as markers, are ignored.
+func (a *chartAxis) bars(rects []*chartPart, eps float64) []chartBar {
+	if len(rects) == 0 {
+		return nil
+	}
+	base := (0 - a.offset) / a.scale
+	if base < a.low-eps || base > a.high+eps {
+		counts := map[float64]int{}
+		best := 0
+		for _, r := range rects {
+			key := math.Round(r.bbox.Lly/eps) * eps
+			counts[key]++
+			if counts[key] > best {
+				best, base = counts[key], key
+			}
+		}
+	}
+	sorted := append([]*chartPart(nil), rects...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].bbox.Lly < sorted[j].bbox.Lly })
+	var bars []chartBar
+	var placed []*chartPart
+	for _, r := range sorted {
+		b := r.bbox
+		var bar chartBar
+		switch {
+		case math.Abs(b.Lly-base) <= eps:
+			bar = chartBar{part: r, value: a.value(b.Ury)}
+		case math.Abs(b.Ury-base) <= eps:
+			bar = chartBar{part: r, value: a.value(b.Lly)}
+		default:
+			stacked := false
+			for _, o := range placed {
+				if math.Abs(o.bbox.Ury-b.Lly) <= eps && o.bbox.Llx < b.Urx-eps && b.Llx < o.bbox.Urx-eps {
+					stacked = true
+					break
+				}
+			}
+			if !stacked {
+				continue
+			}
+			bar = chartBar{part: r, value: a.value(b.Ury) - a.value(b.Lly)}
+		}
+		bars = append(bars, bar)
+		placed = append(placed, r)
+	}
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].part.bbox.Llx < bars[j].part.bbox.Llx })
+	return bars
+}
+
+// chartLine is a line of a line chart: the vertices of the lines drawn with a color.
+type chartLine struct {
+	color    color.Color
+	vertices []transform.Point
+}
+
+// chartLines returns the lines of the stroked polylines `parts` of a plot of width `width`. The vertices
+// of the polylines of a color are merged, so that lines drawn segment by segment are joined. Lines that span
+// less than a fifth of the plot are ignored.
+func chartLines(parts []*chartPart, width, eps float64) []chartLine {
+	var lines []chartLine
+	index := map[string]int{}
+	for _, p := range parts {
+		key := colorKey(p.path.StrokeColor)
+		i, ok := index[key]
+		if !ok {
+			i = len(lines)
+			index[key] = i
+			lines = append(lines, chartLine{color: p.path.StrokeColor})
+		}
+		lines[i].vertices = append(lines[i].vertices, p.vertices...)
+	}
+	var out []chartLine
+	for _, line := range lines {
+		sort.SliceStable(line.vertices, func(i, j int) bool { return line.vertices[i].X < line.vertices[j].X })
+		var vertices []transform.Point
+		for _, v := range line.vertices {
+			if n := len(vertices); n > 0 && v.X-vertices[n-1].X <= eps && math.Abs(v.Y-vertices[n-1].Y) <= eps {
+				continue
+			}
+			vertices = append(vertices, v)
+		}
+		if len(vertices) < 2 || vertices[len(vertices)-1].X-vertices[0].X < 0.2*width {
+			continue
+		}
+		line.vertices = vertices
+		out = append(out, line)
+	}
+	return out
+}
+
+// categoryAxis returns the labels of the categories under the plot area `plot` and the category axis. The
+// axis is numeric if the labels are numbers linear in their positions.
+func (s *chartScene) categoryAxis(plot model.PdfRectangle, h float64, ticks, legend map[*chartLabel]bool,
+	minTicks int, horizontal bool) ([]*chartLabel, *ChartAxis) {
+	var below []*chartLabel
+	top := math.Inf(-1)
+	for _, l := range s.labels {
+		if ticks[l] || legend[l] || l.cx() < plot.Llx-h || l.cx() > plot.Urx+h {
+			continue
+		}
+		if l.bbox.Ury <= plot.Lly+0.3*l.height() && l.bbox.Ury >= plot.Lly-3*l.height() {
+			below = append(below, l)
+			top = math.Max(top, l.bbox.Ury)
+		}
+	}
+	var categories []*chartLabel
+	for _, l := range below {
+		if top-l.bbox.Ury <= 0.5*l.height() {
+			categories = append(categories, l)
+		}
+	}
+	if len(categories) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		if horizontal {
+			return categories[i].cx() > categories[j].cx()
+		}
+		return categories[i].cx() < categories[j].cx()
+	})
+	axis := &ChartAxis{}
+	for _, l := range categories {
+		axis.Ticks = append(axis.Ticks, ChartTick{Label: l.text, Value: l.value, Position: l.cx()})
+	}
+	numeric := len(categories) >= minTicks
+	for _, l := range categories {
+		numeric = numeric && l.numeric
+	}
+	if numeric {
+		sorted := append([]*chartLabel(nil), categories...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].cx() < sorted[j].cx() })
+		positions := make([]float64, len(sorted))
+		for i, l := range sorted {
+			positions[i] = l.cx()
+		}
+		if linear, ok := linearAxis(sorted, positions); ok {
+			axis.Numeric, axis.Scale, axis.Offset = true, linear.scale, linear.offset
+		}
+	}
+	return categories, axis
+}
+
+// chartCategory returns the x value of the page coordinate `x` on the category axis `axis` and the label
+// of the category there: the index of the nearest category label within half the spacing of the labels,
+// or the value on a numeric axis.
+func chartCategory(x float64, categories []*chartLabel, axis *ChartAxis) (float64, string) {
+	if axis == nil || len(categories) == 0 {
+		return x, ""
+	}
+	best, dist := -1, math.Inf(1)
+	for i, l := range categories {
+		if d := math.Abs(l.cx() - x); d < dist {
+			best, dist = i, d
+		}
+	}
+	spacing := math.Inf(1)
+	for i := 1; i < len(categories); i++ {
+		spacing = math.Min(spacing, math.Abs(categories[i].cx()-categories[i-1].cx()))
+	}
+	if math.IsInf(spacing, 1) {
+		spacing = 2 * categories[0].bbox.Width()
+	}
+	near := dist <= 0.5*spacing+0.5*categories[best].height()
+	if axis.Numeric {
+		if near && dist <= categories[best].height() {
+			return axis.Value(x), categories[best].text
+		}
+		return axis.Value(x), ""
+	}
+	if !near {
+		return x, ""
+	}
+	return float64(best), categories[best].text
+}
+
+// chartSeriesSet collects the points of the series of a chart by color.
+type chartSeriesSet struct {
+	legend *chartLegend
+	series []ChartSeries
+	index  map[string]int
+}
+
+func (s *chartSeriesSet) add(c color.Color, point ChartPoint) {
+	if s.index == nil {
+		s.index = map[string]int{}
+	}
+	key := colorKey(c)
+	i, ok := s.index[key]
+	if !ok {
+		i = len(s.series)
+		s.index[key] = i
+		s.series = append(s.series, ChartSeries{Name: s.legend.names[key], Color: c})
+	}
+	s.series[i].Points = append(s.series[i].Points, point)
+}
+
+// result returns the series with their points sorted by X. The first `bars` series are bar series. Single
+// bar series without a legend, such as bars colored by category, are merged into one series.
+func (s *chartSeriesSet) result(bars int) []ChartSeries {
+	for _, series := range s.series {
+		points := series.Points
+		sort.SliceStable(points, func(i, j int) bool { return points[i].X < points[j].X })
+	}
+	single := bars >= 2
+	for _, series := range s.series[:bars] {
+		single = single && len(series.Points) == 1 && series.Name == ""
+	}
+	if !single {
+		return s.series
+	}
+	merged := ChartSeries{}
+	for _, series := range s.series[:bars] {
+		merged.Points = append(merged.Points, series.Points...)
+	}
+	sort.SliceStable(merged.Points, func(i, j int) bool { return merged.Points[i].X < merged.Points[j].X })
+	return append([]ChartSeries{merged}, s.series[bars:]...)
+}
+
+// chartLegend are the legend entries of a chart: color swatches followed by a label.
+type chartLegend struct {
+	names    map[string]string
+	swatches map[*chartPart]bool
+	labels   map[*chartLabel]bool
+}
+
+// legend returns the legend entries near the area `area`, with labels of height about `h`. Swatches are
+// small filled rectangles or short horizontal lines.
+func (s *chartScene) legend(area model.PdfRectangle, h float64) *chartLegend {
+	legend := &chartLegend{names: map[string]string{}, swatches: map[*chartPart]bool{}, labels: map[*chartLabel]bool{}}
+	margin := 0.3 * math.Max(area.Width(), area.Height())
+	for _, p := range s.parts {
+		b := p.bbox
+		if b.Urx < area.Llx-margin || b.Llx > area.Urx+margin || b.Ury < area.Lly-margin || b.Lly > area.Ury+margin {
+			continue
+		}
+		var c color.Color
+		switch {
+		case p.rect() && p.path.Filled && b.Width() <= 2.5*h && b.Height() <= 2.5*h:
+			c = p.path.FillColor
+		case p.path.Stroked && !p.closed && !p.curves && len(p.vertices) == 2 && b.Height() <= 0.1*h &&
+			b.Width() <= 4*h:
+			c = p.path.StrokeColor
+		default:
+			continue
+		}
+		cy := (b.Lly + b.Ury) / 2
+		var label *chartLabel
+		for _, l := range s.labels {
+			if l.bbox.Llx >= b.Urx-0.1*h && l.bbox.Llx <= b.Urx+2*h && math.Abs(l.cy()-cy) <= 0.5*l.height() &&
+				(label == nil || l.bbox.Llx < label.bbox.Llx) {
+				label = l
+			}
+		}
+		if label == nil {
+			continue
+		}
+		legend.swatches[p] = true
+		legend.labels[label] = true
+		if key := colorKey(c); legend.names[key] == "" {
+			legend.names[key] = label.text
+		}
+	}
+	return legend
+}
+
+// chartSlice is a slice of a pie chart.
+type chartSlice struct {
+	part          *chartPart
+	center        transform.Point
+	radius        float64
+	start, sweep  float64
+	label         string
+	labelPriority int
+}
+
+// pieCharts returns the pie charts of the scene: filled slices with a common center whose sweeps make a
+// full circle.
+func (s *chartScene) pieCharts() []Chart {
+	var slices []*chartSlice
+	for _, p := range s.parts {
+		if p.path.Filled && p.closed && p.curves {
+			if slice, ok := pieSlice(p); ok {
+				slices = append(slices, slice)
+			}
+		}
+	}
+	var charts []Chart
+	used := make([]bool, len(slices))
+	for i, first := range slices {
+		if used[i] {
+			continue
+		}
+		pie := []*chartSlice{first}
+		total := math.Abs(first.sweep)
+		for j := i + 1; j < len(slices); j++ {
+			o := slices[j]
+			if !used[j] && o.center.Distance(first.center) <= 0.05*first.radius &&
+				math.Abs(o.radius-first.radius) <= 0.05*first.radius {
+				pie = append(pie, o)
+				used[j] = true
+				total += math.Abs(o.sweep)
+			}
+		}
+		if len(pie) < 2 || total < 0.95*2*math.Pi || total > 1.05*2*math.Pi {
+			continue
+		}
+		charts = append(charts, s.pieChart(pie, total))
+	}
+	return charts
+}
+
+// pieSlice returns the slice of the part `p` if it starts at the center of its arc. Curves with control
+// points on the line between their end points, which some producers use for the radii of slices, are
+// lines and not part of the arc.
+func pieSlice(p *chartPart) (*chartSlice, bool) {
+	center := p.vertices[0]
+	var arc []transform.Point
+	for _, seg := range p.segments {
+		if !seg.IsCurve() || collinear(seg.Points, 1e-3*seg.Start().Distance(seg.End())) {
+			continue
+		}
+		for _, t := range []float64{0, 0.25, 0.5, 0.75, 1} {
+			arc = append(arc, bezierPoint(seg.Points, t))
+		}
+	}
+	if len(arc) == 0 {
+		return nil, false
+	}
+	radius := 0.0
+	for _, q := range arc {
+		radius += q.Distance(center)
+	}
+	radius /= float64(len(arc))
+	if radius <= 0 {
+		return nil, false
+	}
+	for _, q := range arc {
+		if math.Abs(q.Distance(center)-radius) > 0.05*radius {
+			return nil, false
+		}
+	}
+	angle := func(q transform.Point) float64 { return math.Atan2(q.Y-center.Y, q.X-center.X) }
+	start := angle(arc[0])
+	sweep, prev := 0.0, start
+	for _, q := range arc[1:] {
+		a := angle(q)
+		d := a - prev
+		for d > math.Pi {
+			d -= 2 * math.Pi
+		}
+		for d < -math.Pi {
+			d += 2 * math.Pi
+		}
+		sweep += d
+		prev = a
+	}
+	return &chartSlice{part: p, center: center, radius: radius, start: start, sweep: sweep}, true
+}
+
+// pieChart returns the chart of the slices `pie` of total sweep `total`. The slices are labeled from the
+// legend, or else from the nearest label within their angle.
+func (s *chartScene) pieChart(pie []*chartSlice, total float64) Chart {
+	c, r := pie[0].center, pie[0].radius
+	bbox := model.PdfRectangle{Llx: c.X - r, Lly: c.Y - r, Urx: c.X + r, Ury: c.Y + r}
+	h := 0.0
+	for _, l := range s.labels {
+		if l.cx() > bbox.Llx-r && l.cx() < bbox.Urx+r && l.cy() > bbox.Lly-r && l.cy() < bbox.Ury+r {
+			h = math.Max(h, l.height())
+		}
+	}
+	legend := s.legend(bbox, math.Max(h, 0.05*r))
+	for _, slice := range pie {
+		if name := legend.names[colorKey(slice.part.path.FillColor)]; name != "" {
+			slice.label = name
+			continue
+		}
+		best := math.Inf(1)
+		mid := slice.start + slice.sweep/2
+		for _, l := range s.labels {
+			if legend.labels[l] {
+				continue
+			}
+			d := math.Hypot(l.cx()-c.X, l.cy()-c.Y)
+			if d > 2*r || !withinSweep(math.Atan2(l.cy()-c.Y, l.cx()-c.X), slice.start, slice.sweep) {
+				continue
+			}
+			inner := math.Hypot(l.cx()-c.X-0.6*r*math.Cos(mid), l.cy()-c.Y-0.6*r*math.Sin(mid))
+			outer := math.Hypot(l.cx()-c.X-1.25*r*math.Cos(mid), l.cy()-c.Y-1.25*r*math.Sin(mid))
+			priority := 1
+			if l.numeric {
+				priority = 0
+			}
+			dist := math.Min(inner, outer)
+			if priority > slice.labelPriority || priority == slice.labelPriority && dist < best {
+				slice.label, slice.labelPriority, best = l.text, priority, dist
+			}
+		}
+	}
+	series := ChartSeries{}
+	for i, slice := range pie {
+		series.Points = append(series.Points, ChartPoint{
+			Category: slice.label,
+			X:        float64(i),
+			Y:        math.Abs(slice.sweep) / total,
+			Color:    slice.part.path.FillColor,
+		})
+	}
+	return Chart{Kind: ChartPie, BBox: bbox, Series: []ChartSeries{series}}
+}
+
+// withinSweep returns true if the angle `a` is within the arc from `start` sweeping `sweep`.
+func withinSweep(a, start, sweep float64) bool {
+	d := a - start
+	if sweep < 0 {
+		d, sweep = -d, -sweep
+	}
+	d = math.Mod(d, 2*math.Pi)
+	if d < 0 {
+		d += 2 * math.Pi
+	}
+	return d <= sweep
+}
+
+// colorKey returns a key identifying the color `c`.
+func colorKey(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+	r, g, b, a := c.RGBA()
+	return fmt.Sprintf("%d,%d,%d,%d", r>>8, g>>8, b>>8, a>>8)
+}
+
+// parseChartNumber parses the number of a tick label, such as "1,200", "$5k", "(3.5)", "−2" or "40%".
+func parseChartNumber(text string) (float64, bool) {
+	s := strings.TrimSpace(text)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative, s = true, s[1:len(s)-1]
+	}
+	s = strings.Replace(s, "−", "-", 1)
+	if strings.HasPrefix(s, "-") {
+		negative, s = !negative, s[1:]
+	}
+	s = strings.TrimLeft(s, "$€£¥")
+	s = strings.TrimSuffix(s, "%")
+	multiplier := 1.0
+	if n := len(s); n > 1 {
+		switch s[n-1] {
+		case 'k', 'K':
+			multiplier, s = 1e3, s[:n-1]
+		case 'M':
+			multiplier, s = 1e6, s[:n-1]
+		case 'B':
+			multiplier, s = 1e9, s[:n-1]
+		}
+	}
+	if strings.Contains(s, ",") {
+		parts := strings.Split(s, ",")
+		for _, part := range parts[1:] {
+			if len(part) < 3 || len(part) > 3 && part[3] != '.' {
+				return 0, false
+			}
+		}
+		s = strings.Join(parts, "")
+	}
+	if s == "" || !(s[0] >= '0' && s[0] <= '9' || s[0] == '.') {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		v = -v
+	}
+	return v * multiplier, true
+}
@@ -0,0 +1,81 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/creator"
+)
+
+// TestExtractChartsRoundTrip checks that the values of the charts drawn by
+// creator.VectorChart are recovered by ExtractCharts.
+func TestExtractChartsRoundTrip(t *testing.T) {
+	categories := []string{"Q1", "Q2", "Q3", "Q4"}
+	series := map[string][]float64{
+		"2023": {10, 20, 30, 40},
+		"2024": {15, 25, 35, 20},
+	}
+	draw := func(kind creator.ChartType) func(c *creator.Creator) {
+		return func(c *creator.Creator) {
+			chart := c.NewVectorChart(kind, 450, 250)
+			chart.SetTitle("Sales")
+			chart.SetCategories(categories...)
+			chart.AddSeries("2023", series["2023"]...)
+			if kind != creator.ChartTypePie {
+				chart.AddSeries("2024", series["2024"]...)
+			}
+			c.Draw(chart)
+		}
+	}
+
+	testcases := []struct {
+		kind     ChartKind
+		expected map[string][]float64
+	}{
+		{ChartBar, series},
+		{ChartLine, series},
+		{ChartPie, map[string][]float64{"": {0.1, 0.2, 0.3, 0.4}}},
+	}
+	extractors := creatorExtractors(t,
+		draw(creator.ChartTypeBar), draw(creator.ChartTypeLine), draw(creator.ChartTypePie))
+	for i, tc := range testcases {
+		charts, err := extractors[i].ExtractCharts(nil)
+		if err != nil {
+			t.Fatalf("%s: unable to extract charts: %v", tc.kind, err)
+		}
+		if len(charts) != 1 {
+			t.Fatalf("%s: expected 1 chart, got %d", tc.kind, len(charts))
+		}
+		chart := charts[0]
+		if chart.Kind != tc.kind {
+			t.Fatalf("expected %s chart, got %s", tc.kind, chart.Kind)
+		}
+		if len(chart.Series) != len(tc.expected) {
+			t.Fatalf("%s: expected %d series, got %d", tc.kind, len(tc.expected), len(chart.Series))
+		}
+		for _, s := range chart.Series {
+			values, ok := tc.expected[s.Name]
+			if !ok {
+				t.Fatalf("%s: unexpected series %q", tc.kind, s.Name)
+			}
+			if len(s.Points) != len(values) {
+				t.Fatalf("%s: series %q: expected %d points, got %d", tc.kind, s.Name, len(values), len(s.Points))
+			}
+			for j, p := range s.Points {
+				if p.Category != categories[j] || math.Abs(p.Y-values[j]) > 0.01*math.Max(1, values[j]) {
+					t.Fatalf("%s: series %q: point %d: expected %s=%g, got %s=%g",
+						tc.kind, s.Name, j, categories[j], values[j], p.Category, p.Y)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,574 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// DocumentNodeType is the type of a DocumentNode.
+type DocumentNodeType string
+
+// Types of DocumentNodes.
+const (
+	DocumentNodeDocument  DocumentNodeType = "document"
+	DocumentNodeHeading   DocumentNodeType = "heading"
+	DocumentNodeParagraph DocumentNodeType = "paragraph"
+	DocumentNodeList      DocumentNodeType = "list"
+	DocumentNodeListItem  DocumentNodeType = "list_item"
+	DocumentNodeTable     DocumentNodeType = "table"
+	DocumentNodeTableRow  DocumentNodeType = "table_row"
+	DocumentNodeTableCell DocumentNodeType = "table_cell"
+	DocumentNodeImage     DocumentNodeType = "image"
+	DocumentNodeHeader    DocumentNodeType = "header"
+	DocumentNodeFooter    DocumentNodeType = "footer"
+)
+
+// DocumentNode is a node of the logical structure of a document, as returned by
+// ExtractDocument. The root node has type DocumentNodeDocument and its
+// children are the top-level blocks of all pages in reading order.
+type DocumentNode struct {
+	Type DocumentNodeType `json:"type"`
+
+	// Level is the level of headings, from 1 to 6.
+	Level int `json:"level,omitempty"`
+
+	// Text is the text of headings, paragraphs, list items, table cells,
+	// headers and footers.
+	Text string `json:"text,omitempty"`
+
+	// Page is the number of the page of the node, starting from 1.
+	Page int `json:"page,omitempty"`
+
+	// BBox is the bounding box of the node on its page, if known.
+	BBox *model.PdfRectangle `json:"bbox,omitempty"`
+
+	// Ordered is set for numbered lists.
+	Ordered bool `json:"ordered,omitempty"`
+
+	// Label is the label of list items, such as a bullet or number.
+	Label string `json:"label,omitempty"`
+
+	// Header is set for header cells of tables.
+	Header bool `json:"header,omitempty"`
+
+	// RowSpan and ColSpan are the numbers of rows and columns spanned by
+	// merged table cells.
+	RowSpan int `json:"row_span,omitempty"`
+	ColSpan int `json:"col_span,omitempty"`
+
+	// Alt is the alternative description of images.
+	Alt string `json:"alt,omitempty"`
+
+	Children []*DocumentNode `json:"children,omitempty"`
+}
+
+// DocumentOptions are the options of ExtractDocument.
+type DocumentOptions struct {
+	// Options are the options used to extract the text of the pages.
+	// The structure tree of tagged documents is ignored if
+	// Options.DisableDocumentTags is set.
+	Options *Options
+
+	// MarginRatio is the height of the top and bottom page margins as a ratio
+	// of the page height. Text repeated in the margins across pages, or page
+	// numbers, are reported as headers and footers in untagged documents.
+	MarginRatio float64
+
+	// HeadingRatio is the minimum ratio of the font size of headings to the
+	// font size of the body text in untagged documents.
+	HeadingRatio float64
+
+	// MinImageSize is the minimum width and height of reported images.
+	MinImageSize float64
+
+	// TableOptions are the options used to detect tables without ruling
+	// lines in untagged documents. The defaults are used if nil.
+	TableOptions *TableDetectionOptions
+}
+
+// DefaultDocumentOptions returns the default options of ExtractDocument.
+func DefaultDocumentOptions() *DocumentOptions {
+	return &DocumentOptions{MarginRatio: 0.08, HeadingRatio: 1.15, MinImageSize: 8}
+}
+
+// documentPage is a page of a document with its extracted text and images.
+type documentPage struct {
+	number int
+	page   *model.PdfPage
+	box    model.PdfRectangle
+	text   *PageText
+	paras  []TextParagraph
+	images []ImageMark
+}
+
+// ExtractDocument extracts the logical structure of the document of `reader`:
+// headings, paragraphs, lists, tables, images, headers and footers.
+// The structure tree of tagged documents is used when present. Otherwise the
+// structure is inferred from the layout of the pages: the levels of headings
+// from the font sizes and weights, headers and footers from text repeated in
+// the page margins, and tables without ruling lines from the alignment of the
+// text (see DetectTables).
+// The result can be serialized with DocumentNode.ToJSON, ToHTML and
+// ToMarkdown.
+func ExtractDocument(reader *model.PdfReader, options *DocumentOptions) (*DocumentNode, error) {
+	if options == nil {
+		options = DefaultDocumentOptions()
+	}
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]*documentPage, 0, numPages)
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return nil, err
+		}
+		p, err := newDocumentPage(i, page, options)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, p)
+	}
+
+	doc := &DocumentNode{Type: DocumentNodeDocument}
+	if options.Options == nil || !options.Options.DisableDocumentTags {
+		if root, ok := reader.GetCatalogStructTreeRoot(); ok {
+			newTaggedDocument(reader, pages, root).build(doc)
+			if len(doc.Children) > 0 {
+				return doc, nil
+			}
+		}
+	}
+	newDocumentLayout(options, pages).build(doc)
+	return doc, nil
+}
+
+func newDocumentPage(number int, page *model.PdfPage, options *DocumentOptions) (*documentPage, error) {
+	box, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	p := &documentPage{number: number, page: page, box: *box}
+	if page.CropBox != nil {
+		p.box = *page.CropBox
+	}
+	ex, err := NewWithOptions(page, options.Options)
+	if err != nil {
+		return nil, err
+	}
+	if p.text, _, _, err = ex.ExtractPageText(); err != nil {
+		return nil, err
+	}
+	p.paras = p.text.ParagraphsWithTables(options.TableOptions)
+	images, err := ex.ExtractPageImages(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range images.Images {
+		if img.Width >= options.MinImageSize && img.Height >= options.MinImageSize {
+			p.images = append(p.images, img)
+		}
+	}
+	return p, nil
+}
+
+var (
+	_ddDigits     = regexp.MustCompile(`[0-9]+`)
+	_ddSpaces     = regexp.MustCompile(`\s+`)
+	_ddPageNumber = regexp.MustCompile(`(?i)^(page\s*)?([0-9]+|[ivxlc]+)(\s*(of|/)\s*[0-9]+)?$`)
+
+	// _ddListLabel matches the labels of list items. The submatches are the
+	// label and, for numbered lists, the number, letter or roman numeral.
+	_ddListLabel = regexp.MustCompile(`^\s*([•◦▪▫‣⁃●○■□–*-]|\(?([0-9]{1,3}|[ivxlcIVXLC]{1,6}|[a-zA-Z])[.)])\s+(\S.*)$`)
+)
+
+// documentLayout infers the structure of an untagged document from the layout
+// of its pages.
+type documentLayout struct {
+	options *DocumentOptions
+	pages   []*documentPage
+
+	// bodySize is the most common font size of the text.
+	bodySize float64
+	bodyBold bool
+
+	// headingSizes are the font sizes of headings, in decreasing order.
+	headingSizes []float64
+
+	// repeated counts the pages on which the normalized text of the
+	// paragraphs in the page margins occurs.
+	repeated map[string]int
+}
+
+// paraFontStats are the font statistics of a paragraph.
+type paraFontStats struct {
+	size  float64
+	bold  float64
+	runes int
+	lines int
+}
+
+func paraFontStatsOf(marks []TextMark) paraFontStats {
+	var st paraFontStats
+	var size, bold float64
+	for _, mark := range marks {
+		if mark.Meta {
+			if mark.Text == "\n" {
+				st.lines++
+			}
+			continue
+		}
+		n := utf8.RuneCountInString(mark.Text)
+		st.runes += n
+		size += mark.FontSize * float64(n)
+		if isBoldFont(mark.Font) {
+			bold += float64(n)
+		}
+	}
+	st.lines++
+	if st.runes > 0 {
+		st.size = size / float64(st.runes)
+		st.bold = bold / float64(st.runes)
+	}
+	return st
+}
+
+// isBoldFont returns true if the font has a bold weight.
+func isBoldFont(font *model.PdfFont) bool {
+	return fontWeight(font) >= 600
+}
+
+func newDocumentLayout(options *DocumentOptions, pages []*documentPage) *documentLayout {
+	l := &documentLayout{options: options, pages: pages, repeated: map[string]int{}}
+	sizes := map[float64]int{}
+	var bold float64
+	var runes int
+	for _, p := range pages {
+		seen := map[string]bool{}
+		for _, para := range p.paras {
+			if l.marginKind(p, para) != "" {
+				if key := normalizeMarginText(para.Text); !seen[key] {
+					seen[key] = true
+					l.repeated[key]++
+				}
+				continue
+			}
+			st := paraFontStatsOf(para.Marks.Elements())
+			sizes[roundFontSize(st.size)] += st.runes
+			bold += st.bold * float64(st.runes)
+			runes += st.runes
+		}
+	}
+	best := 0
+	for size, count := range sizes {
+		if count > best || (count == best && size < l.bodySize) {
+			l.bodySize, best = size, count
+		}
+	}
+	l.bodyBold = runes > 0 && bold/float64(runes) > 0.5
+
+	headings := map[float64]bool{}
+	for _, p := range pages {
+		for _, para := range p.paras {
+			if para.Table != nil || l.marginKind(p, para) != "" {
+				continue
+			}
+			if st := paraFontStatsOf(para.Marks.Elements()); l.isHeadingSize(st) {
+				headings[roundFontSize(st.size)] = true
+			}
+		}
+	}
+	for size := range headings {
+		l.headingSizes = append(l.headingSizes, size)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(l.headingSizes)))
+	return l
+}
+
+// roundFontSize rounds font sizes to half points.
+func roundFontSize(size float64) float64 { return math.Round(size*2) / 2 }
+
+// normalizeMarginText returns `text` with its numbers and spaces normalized, to
+// match running headers and footers across pages.
+func normalizeMarginText(text string) string {
+	text = _ddDigits.ReplaceAllString(strings.ToLower(text), "#")
+	return strings.TrimSpace(_ddSpaces.ReplaceAllString(text, " "))
+}
+
+// marginKind returns the type of node of a paragraph in the top or bottom
+// margin of the page, or an empty string.
+func (l *documentLayout) marginKind(p *documentPage, para TextParagraph) DocumentNodeType {
+	band := (p.box.Ury - p.box.Lly) * l.options.MarginRatio
+	switch {
+	case para.BBox.Lly >= p.box.Ury-band:
+		return DocumentNodeHeader
+	case para.BBox.Ury <= p.box.Lly+band:
+		return DocumentNodeFooter
+	}
+	return ""
+}
+
+// isHeadingSize returns true if the paragraph is short and its font is larger
+// than the body text.
+func (l *documentLayout) isHeadingSize(st paraFontStats) bool {
+	return l.bodySize > 0 && st.runes > 0 && st.runes <= 200 && st.lines <= 3 &&
+		st.size >= l.bodySize*l.options.HeadingRatio
+}
+
+// headingLevel returns the heading level of a paragraph, or 0 if the paragraph
+// is not a heading. Bold paragraphs of body text size are headings of the
+// level below the smallest heading font size.
+func (l *documentLayout) headingLevel(st paraFontStats, text string) int {
+	if l.isHeadingSize(st) {
+		level := sort.Search(len(l.headingSizes), func(i int) bool {
+			return l.headingSizes[i] <= roundFontSize(st.size)
+		}) + 1
+		return min(level, 6)
+	}
+	if !l.bodyBold && st.bold >= 0.8 && st.runes <= 120 && st.lines <= 2 &&
+		!strings.HasSuffix(text, ".") && st.size >= l.bodySize*0.95 {
+		return min(len(l.headingSizes)+1, 6)
+	}
+	return 0
+}
+
+// build appends the nodes of all pages to `doc`.
+func (l *documentLayout) build(doc *DocumentNode) {
+	for _, p := range l.pages {
+		var nodes []*DocumentNode
+		var list *DocumentNode
+		for _, para := range p.paras {
+			text := strings.TrimSpace(para.Text)
+			if text == "" {
+				continue
+			}
+			bbox := para.BBox
+			if kind := l.marginKind(p, para); kind != "" {
+				if l.repeated[normalizeMarginText(text)] >= 2 || _ddPageNumber.MatchString(text) {
+					nodes = append(nodes, &DocumentNode{Type: kind, Text: joinLines(text), Page: p.number, BBox: &bbox})
+					list = nil
+					continue
+				}
+			}
+			if para.Table != nil {
+				nodes = append(nodes, tableNode(p.number, para.Table))
+				list = nil
+				continue
+			}
+			if level := l.headingLevel(paraFontStatsOf(para.Marks.Elements()), text); level > 0 {
+				nodes = append(nodes, &DocumentNode{Type: DocumentNodeHeading, Level: level, Text: joinLines(text), Page: p.number, BBox: &bbox})
+				list = nil
+				continue
+			}
+			if items, ordered := listItemNodes(text, p.number); items != nil {
+				if list == nil || list.Ordered != ordered {
+					list = &DocumentNode{Type: DocumentNodeList, Ordered: ordered, Page: p.number, BBox: &bbox}
+					nodes = append(nodes, list)
+				} else {
+					union := unionDocumentBBox(*list.BBox, bbox)
+					list.BBox = &union
+				}
+				list.Children = append(list.Children, items...)
+				continue
+			}
+			list = nil
+			nodes = append(nodes, &DocumentNode{Type: DocumentNodeParagraph, Text: joinLines(text), Page: p.number, BBox: &bbox})
+		}
+		for _, img := range p.images {
+			bbox := model.PdfRectangle{Llx: img.X, Lly: img.Y, Urx: img.X + img.Width, Ury: img.Y + img.Height}
+			nodes = insertByPosition(nodes, &DocumentNode{Type: DocumentNodeImage, Page: p.number, BBox: &bbox})
+		}
+		doc.Children = append(doc.Children, nodes...)
+	}
+}
+
+// joinLines joins the lines of the text of a paragraph with spaces.
+func joinLines(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// listItemNodes returns the list items of `text` if its first line starts with
+// a list label, and whether the labels are numbers, letters or roman numerals.
+func listItemNodes(text string, page int) ([]*DocumentNode, bool) {
+	var items []*DocumentNode
+	ordered := false
+	for i, line := range strings.Split(text, "\n") {
+		m := _ddListLabel.FindStringSubmatch(line)
+		if m == nil {
+			if i == 0 {
+				return nil, false
+			}
+			item := items[len(items)-1]
+			item.Text = joinLines(item.Text + " " + line)
+			continue
+		}
+		if i == 0 {
+			ordered = m[2] != ""
+		}
+		items = append(items, &DocumentNode{Type: DocumentNodeListItem, Label: m[1], Text: joinLines(m[3]), Page: page})
+	}
+	return items, ordered
+}
+
+// insertByPosition inserts `node` before the first node starting below it,
+// skipping headers and footers.
+func insertByPosition(nodes []*DocumentNode, node *DocumentNode) []*DocumentNode {
+	for i, other := range nodes {
+		if other.Type == DocumentNodeHeader || other.Type == DocumentNodeFooter || other.BBox == nil {
+			continue
+		}
+		if other.BBox.Ury < node.BBox.Ury {
+			return append(nodes[:i], append([]*DocumentNode{node}, nodes[i:]...)...)
+		}
+	}
+	return append(nodes, node)
+}
+
+// tableNode returns the node of a table detected on a page. Empty cells that
+// the text of a neighbouring cell extends into are merged with it. The first
+// row is a header row if all its text is bold.
+func tableNode(page int, table *TextTable) *DocumentNode {
+	bbox := table.PdfRectangle
+	node := &DocumentNode{Type: DocumentNodeTable, Page: page, BBox: &bbox}
+	spans := tableSpans(table)
+	header := table.H > 1
+	for _, cell := range table.Cells[0] {
+		if cell.Text != "" && paraFontStatsOf(cell.Marks.Elements()).bold < 0.8 {
+			header = false
+		}
+	}
+	for y := 0; y < table.H; y++ {
+		row := &DocumentNode{Type: DocumentNodeTableRow, Page: page}
+		for x := 0; x < table.W; x++ {
+			span := spans[y][x]
+			if span.covered {
+				continue
+			}
+			cell := table.Cells[y][x]
+			c := &DocumentNode{Type: DocumentNodeTableCell, Text: joinLines(cell.Text), Page: page, Header: header && y == 0}
+			if span.rows > 1 {
+				c.RowSpan = span.rows
+			}
+			if span.cols > 1 {
+				c.ColSpan = span.cols
+			}
+			if r := cell.PdfRectangle; r.Width() > 0 || r.Height() > 0 {
+				c.BBox = &r
+			}
+			row.Children = append(row.Children, c)
+		}
+		node.Children = append(node.Children, row)
+	}
+	return node
+}
+
+// cellSpan is the span of a table cell.
+type cellSpan struct {
+	rows, cols int
+	covered    bool
+}
+
+// tableSpans returns the spans of the cells of `table`. The spans of the
+// cells are used if any are set. Otherwise the text of a cell spans the next
+// columns (rows) if it extends past the left (top) edge of the text of these
+// columns (rows) and their cells are empty.
+func tableSpans(table *TextTable) [][]cellSpan {
+	if ranges := table.MergedCells(); len(ranges) > 0 {
+		spans := make([][]cellSpan, table.H)
+		for y := range spans {
+			spans[y] = make([]cellSpan, table.W)
+			for x := range spans[y] {
+				spans[y][x] = cellSpan{rows: 1, cols: 1}
+			}
+		}
+		for _, r := range ranges {
+			for y := r.Row; y < r.Row+r.RowSpan && y < table.H; y++ {
+				for x := r.Col; x < r.Col+r.ColSpan && x < table.W; x++ {
+					spans[y][x].covered = y != r.Row || x != r.Col
+				}
+			}
+			spans[r.Row][r.Col].rows, spans[r.Row][r.Col].cols = r.RowSpan, r.ColSpan
+		}
+		return spans
+	}
+	const tol = 1.0
+	empty := func(x, y int) bool { return strings.TrimSpace(table.Cells[y][x].Text) == "" }
+	colLeft := make([]float64, table.W)
+	rowTop := make([]float64, table.H)
+	for x := range colLeft {
+		colLeft[x] = math.Inf(1)
+	}
+	for y := range rowTop {
+		rowTop[y] = math.Inf(-1)
+	}
+	for y := 0; y < table.H; y++ {
+		for x := 0; x < table.W; x++ {
+			if !empty(x, y) {
+				r := table.Cells[y][x].PdfRectangle
+				colLeft[x] = math.Min(colLeft[x], r.Llx)
+				rowTop[y] = math.Max(rowTop[y], r.Ury)
+			}
+		}
+	}
+
+	spans := make([][]cellSpan, table.H)
+	for y := range spans {
+		spans[y] = make([]cellSpan, table.W)
+	}
+	for y := 0; y < table.H; y++ {
+		for x := 0; x < table.W; x++ {
+			span := &spans[y][x]
+			if span.covered {
+				continue
+			}
+			span.rows, span.cols = 1, 1
+			if empty(x, y) {
+				continue
+			}
+			r := table.Cells[y][x].PdfRectangle
+			for x2 := x + 1; x2 < table.W && empty(x2, y) && !spans[y][x2].covered && r.Urx > colLeft[x2]+tol; x2++ {
+				span.cols++
+			}
+		rows:
+			for y2 := y + 1; y2 < table.H && r.Lly < rowTop[y2]-tol; y2++ {
+				for x2 := x; x2 < x+span.cols; x2++ {
+					if !empty(x2, y2) || spans[y2][x2].covered {
+						break rows
+					}
+				}
+				span.rows++
+			}
+			for y2 := y; y2 < y+span.rows; y2++ {
+				for x2 := x; x2 < x+span.cols; x2++ {
+					if y2 != y || x2 != x {
+						spans[y2][x2].covered = true
+					}
+				}
+			}
+		}
+	}
+	return spans
+}
+
+// unionDocumentBBox returns the smallest rectangle containing `a` and `b`.
+func unionDocumentBBox(a, b model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(a.Llx, b.Llx), Lly: math.Min(a.Lly, b.Lly),
+		Urx: math.Max(a.Urx, b.Urx), Ury: math.Max(a.Ury, b.Ury),
+	}
+}
@@ -0,0 +1,202 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// ToJSON returns the JSON encoding of the node and its children.
+func (n *DocumentNode) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(n, "", "  ")
+}
+
+// ToHTML returns the node and its children as semantic HTML. A document node
+// is returned as a complete HTML document, other nodes as HTML fragments.
+// Headers and footers are written as header and footer elements, and images
+// as figure elements with their alternative description as caption.
+func (n *DocumentNode) ToHTML() string {
+	var b strings.Builder
+	if n.Type == DocumentNodeDocument {
+		b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n</head>\n<body>\n")
+		for _, child := range n.Children {
+			child.writeHTML(&b)
+		}
+		b.WriteString("</body>\n</html>\n")
+		return b.String()
+	}
+	n.writeHTML(&b)
+	return b.String()
+}
+
+func (n *DocumentNode) writeHTML(b *strings.Builder) {
+	text := html.EscapeString(n.Text)
+	switch n.Type {
+	case DocumentNodeDocument:
+		for _, child := range n.Children {
+			child.writeHTML(b)
+		}
+	case DocumentNodeHeading:
+		fmt.Fprintf(b, "<h%d>%s</h%d>\n", n.Level, text, n.Level)
+	case DocumentNodeParagraph:
+		fmt.Fprintf(b, "<p>%s</p>\n", text)
+	case DocumentNodeHeader, DocumentNodeFooter:
+		fmt.Fprintf(b, "<%s data-page=\"%d\">%s</%s>\n", n.Type, n.Page, text, n.Type)
+	case DocumentNodeList:
+		tag := "ul"
+		if n.Ordered {
+			tag = "ol"
+		}
+		fmt.Fprintf(b, "<%s>\n", tag)
+		for _, child := range n.Children {
+			child.writeHTML(b)
+		}
+		fmt.Fprintf(b, "</%s>\n", tag)
+	case DocumentNodeListItem:
+		b.WriteString("<li>" + text)
+		if len(n.Children) > 0 {
+			b.WriteString("\n")
+			for _, child := range n.Children {
+				child.writeHTML(b)
+			}
+		}
+		b.WriteString("</li>\n")
+	case DocumentNodeTable:
+		b.WriteString("<table>\n")
+		for _, child := range n.Children {
+			child.writeHTML(b)
+		}
+		b.WriteString("</table>\n")
+	case DocumentNodeTableRow:
+		b.WriteString("<tr>")
+		for _, child := range n.Children {
+			child.writeHTML(b)
+		}
+		b.WriteString("</tr>\n")
+	case DocumentNodeTableCell:
+		tag := "td"
+		if n.Header {
+			tag = "th"
+		}
+		b.WriteString("<" + tag)
+		if n.RowSpan > 1 {
+			fmt.Fprintf(b, " rowspan=\"%d\"", n.RowSpan)
+		}
+		if n.ColSpan > 1 {
+			fmt.Fprintf(b, " colspan=\"%d\"", n.ColSpan)
+		}
+		fmt.Fprintf(b, ">%s</%s>", text, tag)
+	case DocumentNodeImage:
+		fmt.Fprintf(b, "<figure data-page=\"%d\"", n.Page)
+		if n.BBox != nil {
+			fmt.Fprintf(b, " data-bbox=\"%.2f %.2f %.2f %.2f\"", n.BBox.Llx, n.BBox.Lly, n.BBox.Urx, n.BBox.Ury)
+		}
+		b.WriteString(">")
+		if n.Alt != "" {
+			fmt.Fprintf(b, "<figcaption>%s</figcaption>", html.EscapeString(n.Alt))
+		}
+		b.WriteString("</figure>\n")
+	}
+}
+
+// ToMarkdown returns the node and its children as Markdown. Tables are written
+// as pipe tables, where merged cells are repeated as empty cells. Headers and
+// footers are omitted.
+func (n *DocumentNode) ToMarkdown() string {
+	var b strings.Builder
+	n.writeMarkdown(&b, "")
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func (n *DocumentNode) writeMarkdown(b *strings.Builder, indent string) {
+	switch n.Type {
+	case DocumentNodeDocument:
+		for _, child := range n.Children {
+			child.writeMarkdown(b, indent)
+		}
+	case DocumentNodeHeading:
+		fmt.Fprintf(b, "%s %s\n\n", strings.Repeat("#", n.Level), n.Text)
+	case DocumentNodeParagraph:
+		b.WriteString(n.Text + "\n\n")
+	case DocumentNodeList:
+		for i, item := range n.Children {
+			marker := "-"
+			if n.Ordered {
+				marker = strconv.Itoa(i+1) + "."
+			}
+			fmt.Fprintf(b, "%s%s %s\n", indent, marker, item.Text)
+			for _, child := range item.Children {
+				child.writeMarkdown(b, indent+strings.Repeat(" ", len(marker)+1))
+			}
+		}
+		if indent == "" {
+			b.WriteString("\n")
+		}
+	case DocumentNodeTable:
+		writeMarkdownTable(b, n)
+	case DocumentNodeImage:
+		fmt.Fprintf(b, "![%s](#page=%d)\n\n", strings.ReplaceAll(n.Alt, "]", "\\]"), n.Page)
+	}
+}
+
+// writeMarkdownTable writes a table as a pipe table. The first row is the
+// header row.
+func writeMarkdownTable(b *strings.Builder, table *DocumentNode) {
+	// Lay out the cells in a grid, leaving the cells covered by merged cells
+	// empty.
+	var grid [][]string
+	covered := map[[2]int]bool{}
+	cols := 0
+	for y, row := range table.Children {
+		var cells []string
+		x := 0
+		for _, cell := range row.Children {
+			for covered[[2]int{y, x}] {
+				cells = append(cells, "")
+				x++
+			}
+			cells = append(cells, strings.ReplaceAll(cell.Text, "|", "\\|"))
+			for dy := 0; dy < max(cell.RowSpan, 1); dy++ {
+				for dx := 0; dx < max(cell.ColSpan, 1); dx++ {
+					if dy > 0 || dx > 0 {
+						covered[[2]int{y + dy, x + dx}] = true
+					}
+				}
+			}
+			x++
+		}
+		grid = append(grid, cells)
+		cols = max(cols, len(cells))
+	}
+	if len(grid) == 0 || cols == 0 {
+		return
+	}
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for x := 0; x < cols; x++ {
+			cell := ""
+			if x < len(cells) {
+				cell = cells[x]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(grid[0])
+	b.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+	for _, row := range grid[1:] {
+		writeRow(row)
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,580 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// taggedDocument builds the nodes of a tagged document from its structure tree.
+type taggedDocument struct {
+	pages    []*documentPage
+	root     core.PdfObject
+	roleMap  *core.PdfObjectDictionary
+	classMap *core.PdfObjectDictionary
+	content  map[*documentPage]*taggedContent
+	visited  map[core.PdfObject]bool
+}
+
+// taggedContent is the marked content of a page.
+type taggedContent struct {
+	mcids   map[int64]*contentPiece
+	headers []*DocumentNode
+	footers []*DocumentNode
+}
+
+// contentPiece is the text of a marked-content sequence.
+type contentPiece struct {
+	page        *documentPage
+	text        strings.Builder
+	first, last TextMark
+	bbox        model.PdfRectangle
+	hasMarks    bool
+}
+
+func (c *contentPiece) add(mark TextMark, space bool) {
+	if space {
+		c.text.WriteString(" ")
+	}
+	c.text.WriteString(mark.Text)
+	if !c.hasMarks {
+		c.first, c.bbox, c.hasMarks = mark, mark.BBox, true
+	}
+	c.last, c.bbox = mark, unionDocumentBBox(c.bbox, mark.BBox)
+}
+
+// Structure types of the standard structure elements, by the kind of nodes
+// they are extracted to.
+var (
+	_ddContainerTypes = map[string]bool{
+		"Document": true, "DocumentFragment": true, "Part": true, "Art": true, "Div": true,
+		"BlockQuote": true, "Aside": true, "TOC": true, "TOCI": true, "Index": true,
+		"NonStruct": true, "Private": true,
+	}
+	_ddBlockTypes = map[string]bool{
+		"Sect": true, "P": true, "H": true, "Title": true, "L": true, "Table": true, "Figure": true,
+		"Formula": true, "Caption": true, "Note": true, "FENote": true, "Code": true,
+	}
+	_ddHeading    = regexp.MustCompile(`^H([1-9][0-9]*)$`)
+	_ddListLabel2 = regexp.MustCompile(`^\(?([0-9]+|[a-zA-Z]|[ivxlcIVXLC]+)[.)]?$`)
+)
+
+func newTaggedDocument(reader *model.PdfReader, pages []*documentPage, root core.PdfObject) *taggedDocument {
+	t := &taggedDocument{pages: pages, root: root, content: map[*documentPage]*taggedContent{}, visited: map[core.PdfObject]bool{}}
+	if dict, ok := core.GetDict(root); ok {
+		t.roleMap, _ = core.GetDict(dict.Get("RoleMap"))
+		t.classMap, _ = core.GetDict(dict.Get("ClassMap"))
+	}
+	return t
+}
+
+// build appends the nodes of the structure tree to `doc`, with the headers and
+// footers of each page before and after the content of the page.
+func (t *taggedDocument) build(doc *DocumentNode) {
+	root, ok := core.GetDict(t.root)
+	if !ok {
+		return
+	}
+	var nodes []*DocumentNode
+	t.walkKids(root, nil, &nodes, 0)
+
+	next := 1
+	advance := func(page int) {
+		for ; next <= page && next <= len(t.pages); next++ {
+			if next > 1 {
+				doc.Children = append(doc.Children, t.pageContent(t.pages[next-2]).footers...)
+			}
+			doc.Children = append(doc.Children, t.pageContent(t.pages[next-1]).headers...)
+		}
+	}
+	for _, node := range nodes {
+		advance(node.Page)
+		doc.Children = append(doc.Children, node)
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	advance(len(t.pages))
+	if len(t.pages) > 0 {
+		doc.Children = append(doc.Children, t.pageContent(t.pages[len(t.pages)-1]).footers...)
+	}
+}
+
+// pageContent returns the marked content of the page. The text marks are
+// matched to the marked-content sequences by their text objects.
+func (t *taggedDocument) pageContent(p *documentPage) *taggedContent {
+	if c, ok := t.content[p]; ok {
+		return c
+	}
+	c := &taggedContent{mcids: map[int64]*contentPiece{}}
+	t.content[p] = c
+
+	owners := scanMarkedContent(p.text.GetContentStreamOps(), p.page.Resources)
+	artifacts := map[DocumentNodeType]*DocumentNode{}
+	var lastOwner markedContent
+	var pending bool
+	for _, mark := range p.text.Marks().Elements() {
+		if mark.Meta {
+			pending = true
+			continue
+		}
+		o, ok := owners[core.TraceToDirectObject(mark.DirectObject)]
+		if !ok {
+			pending = false
+			continue
+		}
+		space := pending || o != lastOwner
+		lastOwner, pending = o, false
+		var kind DocumentNodeType
+		switch o.subtype {
+		case "Header":
+			kind = DocumentNodeHeader
+		case "Footer":
+			kind = DocumentNodeFooter
+		}
+		switch {
+		case o.artifact && kind != "":
+			artifact, ok := artifacts[kind]
+			if !ok {
+				bbox := mark.BBox
+				artifact = &DocumentNode{Type: kind, Page: p.number, BBox: &bbox}
+				artifacts[kind] = artifact
+				if kind == DocumentNodeHeader {
+					c.headers = append(c.headers, artifact)
+				} else {
+					c.footers = append(c.footers, artifact)
+				}
+			} else if space {
+				artifact.Text += " "
+			}
+			artifact.Text += mark.Text
+			union := unionDocumentBBox(*artifact.BBox, mark.BBox)
+			artifact.BBox = &union
+		case !o.artifact && o.mcid >= 0:
+			piece, ok := c.mcids[o.mcid]
+			if !ok {
+				piece = &contentPiece{page: p}
+				c.mcids[o.mcid] = piece
+			}
+			piece.add(mark, space && piece.hasMarks)
+		}
+	}
+	return c
+}
+
+// standardType returns the standard structure type of an element, following
+// the role map.
+func (t *taggedDocument) standardType(elem *core.PdfObjectDictionary) string {
+	name, _ := core.GetNameVal(elem.Get("S"))
+	return mapStructType(t.roleMap, name)
+}
+
+// page returns the page of the Pg entry of `dict`, or `page`.
+func (t *taggedDocument) page(dict *core.PdfObjectDictionary, page *documentPage) *documentPage {
+	pg := dict.Get("Pg")
+	if pg == nil {
+		return page
+	}
+	target := core.ResolveReference(pg)
+	for _, p := range t.pages {
+		if core.PdfObject(p.page.GetPageAsIndirectObject()) == target {
+			return p
+		}
+	}
+	return page
+}
+
+// kids returns the kids of a structure element.
+func kids(elem *core.PdfObjectDictionary) []core.PdfObject {
+	switch k := core.TraceToDirectObject(elem.Get("K")).(type) {
+	case *core.PdfObjectArray:
+		return k.Elements()
+	case nil:
+		return nil
+	default:
+		return []core.PdfObject{elem.Get("K")}
+	}
+}
+
+// structElem returns the structure element dictionary of a kid, if it is one.
+func structElem(kid core.PdfObject) (*core.PdfObjectDictionary, bool) {
+	dict, ok := core.GetDict(kid)
+	if !ok || dict.Get("S") == nil {
+		return nil, false
+	}
+	return dict, true
+}
+
+// walkKids appends the nodes of the kids of a grouping element to `out`.
+// Content directly in the element is extracted as paragraphs.
+func (t *taggedDocument) walkKids(elem *core.PdfObjectDictionary, page *documentPage, out *[]*DocumentNode, depth int) {
+	var loose []*contentPiece
+	flush := func() {
+		if node := t.textNode(DocumentNodeParagraph, loose, page); node != nil {
+			*out = append(*out, node)
+		}
+		loose = nil
+	}
+	for _, kid := range kids(elem) {
+		if dict, ok := structElem(kid); ok {
+			flush()
+			t.walkElem(dict, page, out, depth)
+			continue
+		}
+		loose = append(loose, t.kidPieces(kid, page)...)
+	}
+	flush()
+}
+
+// walkElem appends the nodes of a structure element to `out`. Headings without
+// a level are nested by the depth of their Sect elements.
+func (t *taggedDocument) walkElem(elem *core.PdfObjectDictionary, page *documentPage, out *[]*DocumentNode, depth int) {
+	if t.visited[elem] {
+		return
+	}
+	t.visited[elem] = true
+	page = t.page(elem, page)
+
+	typ := t.standardType(elem)
+	switch {
+	case typ == "Sect":
+		t.walkKids(elem, page, out, depth+1)
+	case _ddContainerTypes[typ]:
+		t.walkKids(elem, page, out, depth)
+	case typ == "H" || typ == "Title" || _ddHeading.MatchString(typ):
+		level := max(1, min(depth, 6))
+		if m := _ddHeading.FindStringSubmatch(typ); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			level = min(n, 6)
+		} else if typ == "Title" {
+			level = 1
+		}
+		if node := t.textNode(DocumentNodeHeading, t.pieces(elem, page, nil), page); node != nil {
+			node.Level = level
+			*out = append(*out, node)
+		}
+	case typ == "L":
+		*out = append(*out, t.list(elem, page))
+	case typ == "Table":
+		*out = append(*out, t.table(elem, page))
+	case typ == "Figure":
+		*out = append(*out, t.figure(elem, page))
+	default:
+		if t.hasBlockKids(elem) {
+			t.walkKids(elem, page, out, depth)
+		} else if node := t.textNode(DocumentNodeParagraph, t.pieces(elem, page, nil), page); node != nil {
+			*out = append(*out, node)
+		}
+	}
+}
+
+// hasBlockKids returns true if the element has kids extracted to nodes of
+// their own.
+func (t *taggedDocument) hasBlockKids(elem *core.PdfObjectDictionary) bool {
+	for _, kid := range kids(elem) {
+		if dict, ok := structElem(kid); ok {
+			if typ := t.standardType(dict); _ddBlockTypes[typ] || _ddContainerTypes[typ] || _ddHeading.MatchString(typ) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// textNode returns a node of type `typ` with the text of `pieces`, or nil if
+// there is no text.
+func (t *taggedDocument) textNode(typ DocumentNodeType, pieces []*contentPiece, page *documentPage) *DocumentNode {
+	text := joinPieces(pieces)
+	if text == "" {
+		return nil
+	}
+	node := &DocumentNode{Type: typ, Text: text}
+	setPiecesPosition(node, pieces, page)
+	return node
+}
+
+// setPiecesPosition sets the page and bounding box of a node from its content.
+func setPiecesPosition(node *DocumentNode, pieces []*contentPiece, page *documentPage) {
+	for _, piece := range pieces {
+		if piece.page == nil || !piece.hasMarks {
+			continue
+		}
+		if node.BBox == nil {
+			bbox := piece.bbox
+			node.Page, node.BBox = piece.page.number, &bbox
+		} else if piece.page.number == node.Page {
+			union := unionDocumentBBox(*node.BBox, piece.bbox)
+			node.BBox = &union
+		}
+	}
+	if node.Page == 0 && page != nil {
+		node.Page = page.number
+	}
+}
+
+// pieces returns the content of a structure element and its descendants,
+// except the elements of the types for which `skip` returns true. The
+// ActualText of elements replaces their content.
+func (t *taggedDocument) pieces(elem *core.PdfObjectDictionary, page *documentPage, skip func(string) bool) []*contentPiece {
+	page = t.page(elem, page)
+	var pieces []*contentPiece
+	for _, kid := range kids(elem) {
+		if dict, ok := structElem(kid); ok {
+			if skip == nil || !skip(t.standardType(dict)) {
+				pieces = append(pieces, t.pieces(dict, page, skip)...)
+			}
+			continue
+		}
+		pieces = append(pieces, t.kidPieces(kid, page)...)
+	}
+	if actual, ok := core.GetStringVal(elem.Get("ActualText")); ok {
+		piece := &contentPiece{page: page}
+		piece.text.WriteString(actual)
+		for _, p := range pieces {
+			if p.hasMarks {
+				piece.page, piece.first, piece.last, piece.hasMarks = p.page, p.first, p.last, true
+				piece.bbox = p.bbox
+				break
+			}
+		}
+		return []*contentPiece{piece}
+	}
+	return pieces
+}
+
+// kidPieces returns the content of a marked-content kid: an MCID or a
+// marked-content reference.
+func (t *taggedDocument) kidPieces(kid core.PdfObject, page *documentPage) []*contentPiece {
+	mcid, ok := core.GetIntVal(kid)
+	if dict, isDict := core.GetDict(kid); isDict {
+		if typ, _ := core.GetNameVal(dict.Get("Type")); typ != "MCR" {
+			return nil
+		}
+		page = t.page(dict, page)
+		mcid, ok = core.GetIntVal(dict.Get("MCID"))
+	}
+	if !ok || page == nil {
+		return nil
+	}
+	if piece, ok := t.pageContent(page).mcids[int64(mcid)]; ok {
+		return []*contentPiece{piece}
+	}
+	return nil
+}
+
+// joinPieces returns the text of the pieces, separated by spaces unless the
+// pieces are adjacent on the same line.
+func joinPieces(pieces []*contentPiece) string {
+	var b strings.Builder
+	var prev *contentPiece
+	for _, piece := range pieces {
+		text := piece.text.String()
+		if text == "" {
+			continue
+		}
+		if prev != nil && needsSpace(prev, piece) {
+			b.WriteString(" ")
+		}
+		b.WriteString(text)
+		prev = piece
+	}
+	return joinLines(b.String())
+}
+
+func needsSpace(a, b *contentPiece) bool {
+	return !a.hasMarks || !b.hasMarks || a.page != b.page || !marksAdjacent(a.last, b.first)
+}
+
+// list returns the node of a list element. The items of nested lists are the
+// children of their list items.
+func (t *taggedDocument) list(elem *core.PdfObjectDictionary, page *documentPage) *DocumentNode {
+	page = t.page(elem, page)
+	node := &DocumentNode{Type: DocumentNodeList}
+	numbering, _ := core.GetNameVal(t.attribute(elem, "List", "ListNumbering"))
+	switch numbering {
+	case "Decimal", "UpperRoman", "LowerRoman", "UpperAlpha", "LowerAlpha", "Ordered":
+		node.Ordered = true
+	}
+	var all []*contentPiece
+	for _, kid := range kids(elem) {
+		dict, ok := structElem(kid)
+		if !ok || t.standardType(dict) != "LI" || t.visited[dict] {
+			continue
+		}
+		t.visited[dict] = true
+		itemPage := t.page(dict, page)
+		item := &DocumentNode{Type: DocumentNodeListItem}
+		var label, body []*contentPiece
+		for _, k := range kids(dict) {
+			d, ok := structElem(k)
+			if !ok {
+				body = append(body, t.kidPieces(k, itemPage)...)
+				continue
+			}
+			switch t.standardType(d) {
+			case "Lbl":
+				label = append(label, t.pieces(d, itemPage, nil)...)
+			case "L":
+				item.Children = append(item.Children, t.list(d, itemPage))
+			default:
+				body = append(body, t.pieces(d, itemPage, func(typ string) bool { return typ == "L" })...)
+				for _, nested := range kids(d) {
+					if nd, ok := structElem(nested); ok && t.standardType(nd) == "L" {
+						item.Children = append(item.Children, t.list(nd, itemPage))
+					}
+				}
+			}
+		}
+		item.Label, item.Text = joinPieces(label), joinPieces(body)
+		if len(label) == 0 {
+			if m := _ddListLabel.FindStringSubmatch(item.Text); m != nil {
+				item.Label, item.Text = m[1], m[3]
+			}
+		}
+		setPiecesPosition(item, append(label, body...), itemPage)
+		if numbering == "" && len(node.Children) == 0 && _ddListLabel2.MatchString(item.Label) {
+			node.Ordered = true
+		}
+		node.Children = append(node.Children, item)
+		all = append(all, label...)
+		all = append(all, body...)
+	}
+	setPiecesPosition(node, all, page)
+	return node
+}
+
+// table returns the node of a table element.
+func (t *taggedDocument) table(elem *core.PdfObjectDictionary, page *documentPage) *DocumentNode {
+	page = t.page(elem, page)
+	node := &DocumentNode{Type: DocumentNodeTable}
+	var rows []*core.PdfObjectDictionary
+	for _, kid := range kids(elem) {
+		dict, ok := structElem(kid)
+		if !ok {
+			continue
+		}
+		switch t.standardType(dict) {
+		case "TR":
+			rows = append(rows, dict)
+		case "THead", "TBody", "TFoot":
+			for _, k := range kids(dict) {
+				if row, ok := structElem(k); ok && t.standardType(row) == "TR" {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	var all []*contentPiece
+	for _, row := range rows {
+		rowPage := t.page(row, page)
+		rowNode := &DocumentNode{Type: DocumentNodeTableRow}
+		var rowPieces []*contentPiece
+		for _, kid := range kids(row) {
+			dict, ok := structElem(kid)
+			if !ok {
+				continue
+			}
+			typ := t.standardType(dict)
+			if typ != "TH" && typ != "TD" {
+				continue
+			}
+			pieces := t.pieces(dict, rowPage, nil)
+			cell := &DocumentNode{Type: DocumentNodeTableCell, Text: joinPieces(pieces), Header: typ == "TH"}
+			setPiecesPosition(cell, pieces, rowPage)
+			if span, ok := core.GetIntVal(t.attribute(dict, "Table", "RowSpan")); ok && span > 1 {
+				cell.RowSpan = span
+			}
+			if span, ok := core.GetIntVal(t.attribute(dict, "Table", "ColSpan")); ok && span > 1 {
+				cell.ColSpan = span
+			}
+			rowNode.Children = append(rowNode.Children, cell)
+			rowPieces = append(rowPieces, pieces...)
+		}
+		setPiecesPosition(rowNode, rowPieces, rowPage)
+		node.Children = append(node.Children, rowNode)
+		all = append(all, rowPieces...)
+	}
+	setPiecesPosition(node, all, page)
+	return node
+}
+
+// figure returns the node of a figure element. Its bounding box is taken from
+// the Layout attributes.
+func (t *taggedDocument) figure(elem *core.PdfObjectDictionary, page *documentPage) *DocumentNode {
+	page = t.page(elem, page)
+	node := &DocumentNode{Type: DocumentNodeImage}
+	if page != nil {
+		node.Page = page.number
+	}
+	if alt, ok := core.GetStringVal(elem.Get("Alt")); ok {
+		node.Alt = alt
+	} else if actual, ok := core.GetStringVal(elem.Get("ActualText")); ok {
+		node.Alt = actual
+	}
+	if arr, ok := core.GetArray(t.attribute(elem, "Layout", "BBox")); ok {
+		if bbox, err := model.NewPdfRectangle(*arr); err == nil {
+			node.BBox = bbox
+		}
+	}
+	if node.BBox == nil {
+		setPiecesPosition(node, t.pieces(elem, page, nil), page)
+	}
+	return node
+}
+
+// attribute returns the attribute `key` of owner `owner` of a structure
+// element, from its attribute objects or attribute classes.
+func (t *taggedDocument) attribute(elem *core.PdfObjectDictionary, owner, key core.PdfObjectName) core.PdfObject {
+	find := func(obj core.PdfObject) core.PdfObject {
+		var dicts []core.PdfObject
+		switch v := core.TraceToDirectObject(obj).(type) {
+		case *core.PdfObjectDictionary:
+			dicts = []core.PdfObject{v}
+		case *core.PdfObjectArray:
+			dicts = v.Elements()
+		}
+		for _, d := range dicts {
+			dict, ok := core.GetDict(d)
+			if !ok {
+				continue
+			}
+			if o, _ := core.GetNameVal(dict.Get("O")); o == string(owner) && dict.Get(key) != nil {
+				return dict.Get(key)
+			}
+		}
+		return nil
+	}
+	if value := find(elem.Get("A")); value != nil {
+		return value
+	}
+	if t.classMap == nil {
+		return nil
+	}
+	var classes []core.PdfObject
+	switch c := core.TraceToDirectObject(elem.Get("C")).(type) {
+	case *core.PdfObjectName:
+		classes = []core.PdfObject{c}
+	case *core.PdfObjectArray:
+		classes = c.Elements()
+	}
+	for _, class := range classes {
+		if name, ok := core.GetName(class); ok {
+			if value := find(t.classMap.Get(*name)); value != nil {
+				return value
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/creator"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// TestDocumentBorderlessTable checks that the cells of a table without ruling
+// lines are reported as a table node and not merged into the paragraphs.
+func TestDocumentBorderlessTable(t *testing.T) {
+	options := DefaultDocumentOptions()
+	var pages []*documentPage
+	for i, pt := range creatorPageTexts(t, drawTableBetweenParagraphs) {
+		pages = append(pages, &documentPage{
+			number: i + 1,
+			box:    model.PdfRectangle{Urx: creator.PageSizeLetter[0], Ury: creator.PageSizeLetter[1]},
+			text:   pt,
+			paras:  pt.ParagraphsWithTables(options.TableOptions),
+		})
+	}
+
+	doc := &DocumentNode{Type: DocumentNodeDocument}
+	newDocumentLayout(options, pages).build(doc)
+
+	var types []string
+	for _, node := range doc.Children {
+		types = append(types, string(node.Type))
+		if node.Type == DocumentNodeParagraph && strings.Contains(node.Text, "r0c0") {
+			t.Fatalf("table cells merged into paragraph %q", node.Text)
+		}
+	}
+	if got := strings.Join(types, " "); got != "paragraph table paragraph" {
+		t.Fatalf("unexpected document nodes: %s", got)
+	}
+
+	var records [][]string
+	for _, row := range doc.Children[1].Children {
+		var record []string
+		for _, cell := range row.Children {
+			record = append(record, cell.Text)
+		}
+		records = append(records, record)
+	}
+	checkTableRecords(t, records)
+}
@@ -43,7 +43,7 @@ if _cbfb {_gc .Log .Info ("\u006d\u006fv\u0065\u0054\u006f\u003a\u0020\u0025\u00
 func (_dbddc *textTable )String ()string {return _fb .Sprintf ("\u0025\u0064\u0020\u0078\u0020\u0025\u0064\u0020\u0025\u0074",_dbddc ._abgde ,_dbddc ._ecdcd ,_dbddc ._acge );};const _gaegb =10;func (_gded *wordBag )minDepth ()float64 {return _gded ._aded -(_gded .Ury -_gded ._dfed )};
 func (_bbfg *textTable )toTextTable (_fbfe *textContext )TextTable {if _acggg {_gc .Log .Info ("t\u006fT\u0065\u0078\u0074\u0054\u0061\u0062\u006c\u0065:\u0020\u0025\u0064\u0020x \u0025\u0064",_bbfg ._abgde ,_bbfg ._ecdcd );};_ggeea :=make ([][]TableCell ,_bbfg ._ecdcd );
 for _gabc :=0;_gabc < _bbfg ._ecdcd ;_gabc ++{_ggeea [_gabc ]=make ([]TableCell ,_bbfg ._abgde );for _ffeg :=0;_ffeg < _bbfg ._abgde ;_ffeg ++{_cbcf :=_bbfg .get (_ffeg ,_gabc );if _cbcf ==nil {continue ;};_ddgcf (_cbcf ._ggcdg );if _acggg {_fb .Printf ("\u0025\u0034\u0064 \u0025\u0032\u0064\u003a\u0020\u0025\u0073\u000a",_ffeg ,_gabc ,_cbcf );
-};_ggeea [_gabc ][_ffeg ].Text =_cbcf .text ();_bddad :=0;_ggeea [_gabc ][_ffeg ].Marks ._edfaa =_cbcf .toTextMarks (&_bddad ,_fbfe );};};_fdgce :=TextTable {W :_bbfg ._abgde ,H :_bbfg ._ecdcd ,Cells :_ggeea };_fdgce .PdfRectangle =_bbfg .bbox ();return _fdgce ;
+};_ggeea [_gabc ][_ffeg ].PdfRectangle =_cbcf .PdfRectangle ;_ggeea [_gabc ][_ffeg ].Text =_cbcf .text ();_bddad :=0;_ggeea [_gabc ][_ffeg ].Marks ._edfaa =_cbcf .toTextMarks (&_bddad ,_fbfe );};};_fdgce :=TextTable {W :_bbfg ._abgde ,H :_bbfg ._ecdcd ,Cells :_ggeea };_fdgce .PdfRectangle =_bbfg .bbox ();return _fdgce ;
 };func (_cabagf paraList )applyTables (_gacg []*textTable )paraList {var _fffbb paraList ;for _ ,_gfbgd :=range _gacg {_fffbb =append (_fffbb ,_gfbgd .newTablePara ());};for _ ,_cebad :=range _cabagf {if _cebad ._bgdbc {continue ;};_fffbb =append (_fffbb ,_cebad );
 };return _fffbb ;};func (_cggbf rulingList )secMinMax ()(float64 ,float64 ){_cbgfe ,_fdae :=_cggbf [0]._fgfc ,_cggbf [0]._ggbbc ;for _ ,_cgad :=range _cggbf [1:]{if _cgad ._fgfc < _cbgfe {_cbgfe =_cgad ._fgfc ;};if _cgad ._ggbbc > _fdae {_fdae =_cgad ._ggbbc ;
 };};return _cbgfe ,_fdae ;};
@@ -163,7 +163,15 @@ DirectObject _ga .PdfObject ;
 // ObjString is a decoded string operand of a text-showing operator. It has the same value as `Text` attribute except
 // when many glyphs are represented with the same Text Object that contains multiple length string operand in which case
 // ObjString spans more than one character string that falls in different TextMark objects.
-ObjString []string ;Tw float64 ;Th float64 ;Tc float64 ;Index int ;_gagf bool ;_dgde *TextTable ;};func _dfggd (_dcge map[float64 ][]*textLine )[]float64 {_cbaa :=[]float64 {};for _bagf :=range _dcge {_cbaa =append (_cbaa ,_bagf );};_a .Float64s (_cbaa );
+ObjString []string ;
+
+// StructElement references the structure element that the text belongs to in tagged documents.
+// It is nil for untagged content and artifacts, or if Options.DisableDocumentTags is set.
+StructElement *StructElementRef ;
+
+// Source is the provenance of the text: the form XObjects and annotation that drew it and its optional
+// content groups. It is nil for the spaces and line breaks that we insert (i.e. the Meta field is true).
+Source *TextSource ;Tw float64 ;Th float64 ;Tc float64 ;Index int ;_gagf bool ;_dgde *TextTable ;};func _dfggd (_dcge map[float64 ][]*textLine )[]float64 {_cbaa :=[]float64 {};for _bagf :=range _dcge {_cbaa =append (_cbaa ,_bagf );};_a .Float64s (_cbaa );
 return _cbaa ;};func _cabaa (_begf _eg .PdfRectangle )textState {return textState {_beea :100,_bdbc :RenderModeFill ,_agda :_begf };};func (_cddde *textObject )moveText (_fdff ,_efed float64 ){_cddde .moveLP (_fdff ,_efed )};func _dcffb (_cgcg int ,_dbfgc map[int ][]float64 )([]int ,int ){_eabd :=make ([]int ,_cgcg );
 _fdfa :=0;for _cfccd :=0;_cfccd < _cgcg ;_cfccd ++{_eabd [_cfccd ]=_fdfa ;_fdfa +=len (_dbfgc [_cfccd ])+1;};return _eabd ,_fdfa ;};func (_fgeg *PageText )getText ()string {_ebec :="";_cfda :=len (_fgeg ._fbeb );for _eadd :=0;_eadd < 360&&_cfda > 0;_eadd +=90{_acfe :=make ([]*textMark ,0,len (_fgeg ._fbeb )-_cfda );
 for _ ,_bffd :=range _fgeg ._fbeb {if _bffd ._dagg ==_eadd {_acfe =append (_acfe ,_bffd );};};if len (_acfe )> 0{_ebec +=_gece (_acfe ,_fgeg ._cggfb );_cfda -=len (_acfe );};};return _ebec ;};func _cdfcf (_aabgcc []TextMark ,_fddg *int ,_cgdg TextMark )[]TextMark {_cgdg .Offset =*_fddg ;
@@ -219,7 +227,7 @@ func _cdgd (_badg ,_ebcc bounded )float64 {return _badg .bbox ().Llx -_ebcc .bbo
 _ee < _ac ;_ee ,_ac =_ee +1,_ac -1{_cc :=_caa [_ee ];_caa [_ee ]=_caa [_ac ];_caa [_ac ]=_cc ;};};
 
 // PageText represents the layout of text on a device page.
-type PageText struct{_fbeb []*textMark ;_fgb string ;_fffbd []TextMark ;_gacd []TextTable ;_cggfb _eg .PdfRectangle ;_fbdg []pathSection ;_gggf []pathSection ;_eace *_eg .StructTreeRoot ;_geee _ga .PdfObject ;_dcff *_cd .ContentStreamOperations ;_bedea PageTextOptions ;
+type PageText struct{_fbeb []*textMark ;_fgb string ;_fffbd []TextMark ;_gacd []TextTable ;_cggfb _eg .PdfRectangle ;_fbdg []pathSection ;_gggf []pathSection ;_eace *_eg .StructTreeRoot ;_geee _ga .PdfObject ;_dcff *_cd .ContentStreamOperations ;_bedea PageTextOptions ;_structRefs map[_ga .PdfObject ]*StructElementRef ;
 };
 
 // WriteToFile writes the edited content to `outputPath`.
@@ -316,7 +324,7 @@ return _dfge > 0&&!_f .IsSpace (_bdbb );};
 type RenderMode int ;type gridTile struct{_eg .PdfRectangle ;_eedfg ,_daec ,_efadb ,_cadaf bool ;};func (_bdbd *textMark )bbox ()_eg .PdfRectangle {return _bdbd .PdfRectangle };
 
 // ToTextMark returns the public view of `tm`.
-func (_fafd *textMark )ToTextMark ()TextMark {return TextMark {Text :_fafd ._ccgc ,Original :_fafd ._bded ,BBox :_fafd ._gdbcc ,Font :_fafd ._bcgf ,FontSize :_fafd ._dcgb ,FillColor :_fafd ._fda ,StrokeColor :_fafd ._edcdd ,Orientation :_fafd ._dagg ,DirectObject :_fafd ._fgded ,ObjString :_fafd ._ddfb ,Tw :_fafd .Tw ,Th :_fafd .Th ,Tc :_fafd ._cae ,Index :_fafd ._aedfe };
+func (_fafd *textMark )ToTextMark ()TextMark {return TextMark {Text :_fafd ._ccgc ,Original :_fafd ._bded ,BBox :_fafd ._gdbcc ,Font :_fafd ._bcgf ,FontSize :_fafd ._dcgb ,FillColor :_fafd ._fda ,StrokeColor :_fafd ._edcdd ,Orientation :_fafd ._dagg ,DirectObject :_fafd ._fgded ,ObjString :_fafd ._ddfb ,Tw :_fafd .Tw ,Th :_fafd .Th ,Tc :_fafd ._cae ,Index :_fafd ._aedfe ,Source :_fafd ._source };
 };func (_dcdb *subpath )removeDuplicates (){if len (_dcdb ._gcfa )==0{return ;};_cege :=[]_cf .Point {_dcdb ._gcfa [0]};for _ ,_efgb :=range _dcdb ._gcfa [1:]{if !_eadea (_efgb ,_cege [len (_cege )-1]){_cege =append (_cege ,_efgb );};};_dcdb ._gcfa =_cege ;
 };func (_fegf rulingList )snapToGroups ()rulingList {_bgdee ,_ecddc :=_fegf .vertsHorzs ();if len (_bgdee )> 0{_bgdee =_bgdee .snapToGroupsDirection ();};if len (_ecddc )> 0{_ecddc =_ecddc .snapToGroupsDirection ();};_ccfg :=append (_bgdee ,_ecddc ...);
 _ccfg .log ("\u0073\u006e\u0061p\u0054\u006f\u0047\u0072\u006f\u0075\u0070\u0073");return _ccfg ;};func _eadea (_adbe ,_eegbg _cf .Point )bool {return _adbe .X ==_eegbg .X &&_adbe .Y ==_eegbg .Y };func (_fedb paraList )eventNeighbours (_eeag []event )map[*textPara ][]int {_a .Slice (_eeag ,func (_aeeff ,_edefb int )bool {_gebff ,_edcdf :=_eeag [_aeeff ],_eeag [_edefb ];
@@ -332,7 +340,7 @@ for _egbe :=range _edaeg {_bbga [_egbe ]=_egbe ;};_a .SliceStable (_bbga ,func (
 func (_gabb *subpath )String ()string {_bgae :=_gabb ._gcfa ;_gfdb :=len (_bgae );if _gfdb <=5{return _fb .Sprintf ("\u0025d\u003a\u0020\u0025\u0036\u002e\u0032f",_gfdb ,_bgae );};return _fb .Sprintf ("\u0025d\u003a\u0020\u0025\u0036.\u0032\u0066\u0020\u0025\u0036.\u0032f\u0020.\u002e\u002e\u0020\u0025\u0036\u002e\u0032f",_gfdb ,_bgae [0],_bgae [1],_bgae [_gfdb -1]);
 };func _cgdc (_fed ,_efee _eg .PdfRectangle )_eg .PdfRectangle {return _eg .PdfRectangle {Llx :_eb .Min (_fed .Llx ,_efee .Llx ),Lly :_eb .Min (_fed .Lly ,_efee .Lly ),Urx :_eb .Max (_fed .Urx ,_efee .Urx ),Ury :_eb .Max (_fed .Ury ,_efee .Ury )};};func _eecb (_dbgeg *list ,_ccba *_c .Builder ,_gbdb *string ){_fadb :=_fcef (_dbgeg ,_gbdb );
 _ccba .WriteString (_fadb );for _ ,_acdb :=range _dbgeg ._befbc {_agbgg :=*_gbdb +"\u0020\u0020\u0020";_eecb (_acdb ,_ccba ,&_agbgg );};};func (_aeb *imageExtractContext )extractContentStreamImages (_aea string ,_bac *_eg .PdfPageResources )error {_eed :=_cd .NewContentStreamParser (_aea );
-_adb ,_cbae :=_eed .Parse ();if _cbae !=nil {return _cbae ;};if _aeb ._aaee ==nil {_aeb ._aaee =map[*_ga .PdfObjectStream ]*cachedImage {};};if _aeb ._bee ==nil {_aeb ._bee =&ImageExtractOptions {};};_gf :=_cd .NewContentStreamProcessor (*_adb );_gf .AddHandler (_cd .HandlerConditionEnumAllOperands ,"",_aeb .processOperand );
+_adb ,_cbae :=_eed .Parse ();if _cbae !=nil {return _cbae ;};if _aeb ._aaee ==nil {_aeb ._aaee =map[*_ga .PdfObjectStream ]*cachedImage {};};if _aeb ._bee ==nil {_aeb ._bee =&ImageExtractOptions {};};_gf :=_cd .NewContentStreamProcessor (*_adb );_gf .AddHandler (_cd .HandlerConditionEnumAllOperands ,"",_aeb .visibleOperands (_aeb .processOperand ));
 return _gf .Process (_bac );};func (_dbdf gridTile )complete ()bool {return _dbdf .numBorders ()==4};var _edfe =_ca .MustCompile ("\u005e\u005c\u0073\u002a\u0028\u005c\u0064\u002b\u005c\u002e\u003f|\u005b\u0049\u0069\u0076\u005d\u002b\u0029\u005c\u0073\u002a\\\u0029\u003f\u0024");
 func _eagb (_dgafa _cf .Matrix )_cf .Point {_efcf ,_acgg :=_dgafa .Translation ();return _cf .Point {X :_efcf ,Y :_acgg };};func (_efda *textLine )endsInHyphen ()bool {_aadc :=_efda ._aebbg [len (_efda ._aebbg )-1];_bfcf :=_aadc ._ffbde ;_dcbf ,_gfdfa :=_bc .DecodeLastRuneInString (_bfcf );
 if _gfdfa <=0||!_f .Is (_f .Hyphen ,_dcbf ){return false ;};if _aadc ._eebe &&_fgeb (_bfcf ){return true ;};return _fgeb (_efda .text ());};
@@ -518,8 +526,8 @@ _befea :=make ([]int ,0,_deeac );_cacb :=_gadfc .llyOrdering ();var _efea func (
 //
 //	Replace with a function like Extract() (*PageText, error)
 func (_cafb *Extractor )ExtractPageText ()(*PageText ,int ,int ,error ){_cbgf ,_fbef ,_ebff ,_fgdec :=_cafb .extractPageText (_cafb ._fg ,_cafb ._fdg ,_cf .IdentityMatrix (),0,false );if _fgdec !=nil &&_fgdec !=_eg .ErrColorOutOfRange {return nil ,0,0,_fgdec ;
-};if _cafb ._dee !=nil {_cbgf ._bedea ._cbdc =_cafb ._dee .ExtractionMode ;_cbgf ._bedea ._gccc =_cafb ._dee .DisableDocumentTags ;_cbgf ._bedea ._eacd =_cafb ._dee .DisableDehyphenation ;};_cbgf .computeViews ();_fgdec =_fbbdc (_cbgf );if _fgdec !=nil {return nil ,0,0,_fgdec ;
-};if _cafb ._dee !=nil {if _cafb ._dee .ApplyCropBox &&_cafb ._deb !=nil {_cbgf .ApplyArea (*_cafb ._deb );};};return _cbgf ,_fbef ,_ebff ,nil ;};func (_gaaf *textPara )bbox ()_eg .PdfRectangle {return _gaaf .PdfRectangle };func (_dbcc *textObject )moveTextSetLeading (_aacdg ,_bba float64 ){_dbcc ._aabgcd ._abbd =-_bba ;
+};if _cafb ._dee !=nil {_cbgf ._bedea ._cbdc =_cafb ._dee .ExtractionMode ;_cbgf ._bedea ._gccc =_cafb ._dee .DisableDocumentTags ;_cbgf ._bedea ._eacd =_cafb ._dee .DisableDehyphenation ;};_cbgf ._fbeb =_cafb .visibleMarks (_cbgf ._fbeb );_cbgf .computeViews ();_fgdec =_fbbdc (_cbgf );if _fgdec !=nil {return nil ,0,0,_fgdec ;
+};if _cafb ._dee !=nil {if _cafb ._dee .ApplyCropBox &&_cafb ._deb !=nil {_cbgf .ApplyArea (*_cafb ._deb );};};_cbgf .applyStructTree (_cafb );return _cbgf ,_fbef ,_ebff ,nil ;};func (_gaaf *textPara )bbox ()_eg .PdfRectangle {return _gaaf .PdfRectangle };func (_dbcc *textObject )moveTextSetLeading (_aacdg ,_bba float64 ){_dbcc ._aabgcd ._abbd =-_bba ;
 _dbcc .moveLP (_aacdg ,_bba );};func _acfb (_baaf []*textLine ,_abdg ,_bfac float64 )[]*textLine {var _egcec []*textLine ;for _ ,_gcef :=range _baaf {if _abdg ==-1{if _gcef ._egce > _bfac {_egcec =append (_egcec ,_gcef );};}else {if _gcef ._egce > _bfac &&_gcef ._egce < _abdg {_egcec =append (_egcec ,_gcef );
 };};};return _egcec ;};func _aeaa (_eefd *textLine )bool {_edffg :=true ;_abce :=-1;for _ ,_bedbg :=range _eefd ._aebbg {for _ ,_befe :=range _bedbg ._cbcfb {_fbdd :=_befe ._egced ;if _abce ==-1{_abce =_fbdd ;}else {if _abce !=_fbdd {_edffg =false ;break ;
 };};};};return _edffg ;};var _cga =false ;func _eece (_abadg []*textLine )[]*textLine {_gfdd :=[]*textLine {};for _ ,_dcebc :=range _abadg {_gbdge :=_dcebc .text ();_ecec :=_eacb .Find ([]byte (_gbdge ));if _ecec !=nil {_gfdd =append (_gfdd ,_dcebc );};
@@ -580,7 +588,7 @@ type TextMarkArray struct{_edfaa []TextMark };
 
 // Text gets the extracted text contained in `l`.
 func (_ffgc *list )Text ()string {_aafb :=&_c .Builder {};_bebb :="";_eecb (_ffgc ,_aafb ,&_bebb );return _aafb .String ();};type textObject struct{_daa *Extractor ;_bcgg *_eg .PdfPageResources ;_ceba _cd .GraphicsState ;_aabgcd *textState ;_ccfb *stateStack ;
-_fde _cf .Matrix ;_bedb _cf .Matrix ;_fgef []*textMark ;_ggbb bool ;};func (_fdged rulingList )augmentGrid ()(rulingList ,rulingList ){_efcda ,_eceec :=_fdged .vertsHorzs ();if len (_efcda )==0||len (_eceec )==0{return _efcda ,_eceec ;};_efbecb ,_gbff :=_efcda ,_eceec ;
+_fde _cf .Matrix ;_bedb _cf .Matrix ;_fgef []*textMark ;_ggbb bool ;_sources *sourceTracker ;};func (_fdged rulingList )augmentGrid ()(rulingList ,rulingList ){_efcda ,_eceec :=_fdged .vertsHorzs ();if len (_efcda )==0||len (_eceec )==0{return _efcda ,_eceec ;};_efbecb ,_gbff :=_efcda ,_eceec ;
 _afabb :=_efcda .bbox ();_gcebd :=_eceec .bbox ();if _bbab {_gc .Log .Info ("\u0061u\u0067\u006d\u0065\u006e\u0074\u0047\u0072\u0069\u0064\u003a\u0020b\u0062\u006f\u0078\u0056\u003d\u0025\u0036\u002e\u0032\u0066",_afabb );_gc .Log .Info ("\u0061u\u0067\u006d\u0065\u006e\u0074\u0047\u0072\u0069\u0064\u003a\u0020b\u0062\u006f\u0078\u0048\u003d\u0025\u0036\u002e\u0032\u0066",_gcebd );
 };var _acee ,_fgdedg ,_bbdde ,_cdgg *ruling ;if _gcebd .Llx < _afabb .Llx -_efce {_acee =&ruling {_abgd :_ffdb ,_bfgba :_faage ,_gaca :_gcebd .Llx ,_fgfc :_afabb .Lly ,_ggbbc :_afabb .Ury };_efcda =append (rulingList {_acee },_efcda ...);};if _gcebd .Urx > _afabb .Urx +_efce {_fgdedg =&ruling {_abgd :_ffdb ,_bfgba :_faage ,_gaca :_gcebd .Urx ,_fgfc :_afabb .Lly ,_ggbbc :_afabb .Ury };
 _efcda =append (_efcda ,_fgdedg );};if _afabb .Lly < _gcebd .Lly -_efce {_bbdde =&ruling {_abgd :_ffdb ,_bfgba :_afdgf ,_gaca :_afabb .Lly ,_fgfc :_gcebd .Llx ,_ggbbc :_gcebd .Urx };_eceec =append (rulingList {_bbdde },_eceec ...);};if _afabb .Ury > _gcebd .Ury +_efce {_cdgg =&ruling {_abgd :_ffdb ,_bfgba :_afdgf ,_gaca :_afabb .Ury ,_fgfc :_gcebd .Llx ,_ggbbc :_gcebd .Urx };
@@ -622,8 +630,8 @@ _aecc :=_eg .PdfRectangle {Llx :_fdca .X ,Urx :_fdca .X ,Lly :_fdca .Y ,Ury :_fd
 type BidiText struct{_aa string ;_gee string ;};type fontEntry struct{_fffc *_eg .PdfFont ;_dcfad int64 ;};func (_dfb *textObject )getFillColor ()_d .Color {return _ddac (_dfb ._ceba .ColorspaceNonStroking ,_dfb ._ceba .ColorNonStroking );};
 
 // Extractor stores and offers functionality for extracting content from PDF pages.
-type Extractor struct{_fg string ;_fdg *_eg .PdfPageResources ;_egf _eg .PdfRectangle ;_deb *_eg .PdfRectangle ;_add int ;_dgd map[string ]fontEntry ;_gd map[string ]textResult ;_fagf map[string ]textResult ;_fdc int64 ;_dee *Options ;_bbc *_eg .StructTreeRoot ;
-_ead _ga .PdfObject ;_dcf []*_eg .PdfAnnotation ;};func (_dddbd paraList )readBefore (_eadfa []int ,_ecfc ,_gaedf int )bool {_cgdgf ,_dbdd :=_dddbd [_ecfc ],_dddbd [_gaedf ];if _dfdec (_cgdgf ,_dbdd )&&_cgdgf .Lly > _dbdd .Lly {return true ;};if !(_cgdgf ._faef .Urx < _dbdd ._faef .Llx ){return false ;
+type Extractor struct{_fg string ;_fdg *_eg .PdfPageResources ;_egf _eg .PdfRectangle ;_deb *_eg .PdfRectangle ;_add int ;_dgd map[string ]fontEntry ;_gd map[string ]textResult ;_fagf map[string ]textResult ;_fdc int64 ;_dee *Options ;_bbc *_eg .StructTreeRoot ;_structRoot _ga .PdfObject ;
+_ead _ga .PdfObject ;_dcf []*_eg .PdfAnnotation ;_ocVisibility *_eg .OCVisibility ;};func (_dddbd paraList )readBefore (_eadfa []int ,_ecfc ,_gaedf int )bool {_cgdgf ,_dbdd :=_dddbd [_ecfc ],_dddbd [_gaedf ];if _dfdec (_cgdgf ,_dbdd )&&_cgdgf .Lly > _dbdd .Lly {return true ;};if !(_cgdgf ._faef .Urx < _dbdd ._faef .Llx ){return false ;
 };_beda ,_ebcf :=_cgdgf .Lly ,_dbdd .Lly ;if _beda > _ebcf {_ebcf ,_beda =_beda ,_ebcf ;};_dfdfag :=_eb .Max (_cgdgf ._faef .Llx ,_dbdd ._faef .Llx );_aaff :=_eb .Min (_cgdgf ._faef .Urx ,_dbdd ._faef .Urx );_feec :=_dddbd .llyRange (_eadfa ,_beda ,_ebcf );
 for _ ,_fbec :=range _feec {if _fbec ==_ecfc ||_fbec ==_gaedf {continue ;};_aafdd :=_dddbd [_fbec ];if _aafdd ._faef .Llx <=_aaff &&_dfdfag <=_aafdd ._faef .Urx {return false ;};};return true ;};func (_bdda rulingList )bbox ()_eg .PdfRectangle {var _gbcdd _eg .PdfRectangle ;
 if len (_bdda )==0{_gc .Log .Error ("r\u0075\u006c\u0069\u006e\u0067\u004ci\u0073\u0074\u002e\u0062\u0062\u006f\u0078\u003a\u0020n\u006f\u0020\u0072u\u006ci\u006e\u0067\u0073");return _eg .PdfRectangle {};};if _bdda [0]._bfgba ==_afdgf {_gbcdd .Llx ,_gbcdd .Urx =_bdda .secMinMax ();
@@ -687,7 +695,7 @@ func (_feee *TextMarkArray )Len ()int {if _feee ==nil {return 0;};return len (_f
 func (_fdfg *imageExtractContext )extractInlineImage (_baf *_cd .ContentStreamInlineImage ,_acb _cd .GraphicsState ,_dceb *_eg .PdfPageResources )error {_aed ,_eec :=_baf .ToImage (_dceb );if _eec !=nil {return _eec ;};_dga ,_eec :=_baf .GetColorSpace (_dceb );
 if _eec !=nil {return _eec ;};if _dga ==nil {_dga =_eg .NewPdfColorspaceDeviceGray ();};_ceg ,_eec :=_dga .ImageToRGB (*_aed );if _eec !=nil {return _eec ;};_dac :=ImageMark {Image :&_ceg ,Width :_acb .CTM .ScalingFactorX (),Height :_acb .CTM .ScalingFactorY (),Angle :_acb .CTM .Angle ()};
 _dac .X ,_dac .Y =_acb .CTM .Translation ();_fdfg ._cec =append (_fdfg ._cec ,_dac );_fdfg ._ace ++;return nil ;};type imageExtractContext struct{_cec []ImageMark ;_ace int ;_gdc int ;_fecb int ;_aaee map[*_ga .PdfObjectStream ]*cachedImage ;_bee *ImageExtractOptions ;
-_bed bool ;};func (_acegg *ruling )gridIntersecting (_adcf *ruling )bool {return _bfff (_acegg ._fgfc ,_adcf ._fgfc )&&_bfff (_acegg ._ggbbc ,_adcf ._ggbbc );};const (_ffdc markKind =iota ;_dcdaa ;_afacb ;_ffdb ;);func (_gcafb *textTable )put (_fdfb ,_cbec int ,_cbdfe *textPara ){_gcafb ._ceegb [_ccbad (_fdfb ,_cbec )]=_cbdfe ;
+_bed bool ;_ocVisibility *_eg .OCVisibility ;};func (_acegg *ruling )gridIntersecting (_adcf *ruling )bool {return _bfff (_acegg ._fgfc ,_adcf ._fgfc )&&_bfff (_acegg ._ggbbc ,_adcf ._ggbbc );};const (_ffdc markKind =iota ;_dcdaa ;_afacb ;_ffdb ;);func (_gcafb *textTable )put (_fdfb ,_cbec int ,_cbdfe *textPara ){_gcafb ._ceegb [_ccbad (_fdfb ,_cbec )]=_cbdfe ;
 };
 
 // ExtractPageImages returns the image contents of the page extractor, including data
@@ -695,7 +703,7 @@ _bed bool ;};func (_acegg *ruling )gridIntersecting (_adcf *ruling )bool {return
 // A set of options to control page image extraction can be passed in. The options
 // parameter can be nil for the default options. By default, inline stencil masks
 // are not extracted.
-func (_eda *Extractor )ExtractPageImages (options *ImageExtractOptions )(*PageImages ,error ){_eab :=&imageExtractContext {_bee :options };_eeb :=_eab .extractContentStreamImages (_eda ._fg ,_eda ._fdg );if _eeb !=nil {return nil ,_eeb ;};return &PageImages {Images :_eab ._cec },nil ;
+func (_eda *Extractor )ExtractPageImages (options *ImageExtractOptions )(*PageImages ,error ){_eab :=&imageExtractContext {_bee :options ,_ocVisibility :_eda ._ocVisibility };_eeb :=_eab .extractContentStreamImages (_eda ._fg ,_eda ._fdg );if _eeb !=nil {return nil ,_eeb ;};return &PageImages {Images :_eab ._cec },nil ;
 };type textContext struct{_bgcc bool };func _ggcd (_gdbb *textLine )float64 {return _gdbb ._aebbg [0].Llx };func _bacec (_degc ,_gfcg _cf .Point ,_cagg _d .Color )(*ruling ,bool ){_cdefb :=lineRuling {_effb :_degc ,_egacd :_gfcg ,_gdcc :_bbfb (_degc ,_gfcg ),Color :_cagg };
 if _cdefb ._gdcc ==_dbbg {return nil ,false ;};return _cdefb .asRuling ();};func _fbcc (_cbdg ,_gbeb bounded )float64 {_bbcbe :=_cdgd (_cbdg ,_gbeb );if !_bcaee (_bbcbe ){return _bbcbe ;};return _ffcf (_cbdg ,_gbeb );};func (_gdbe rulingList )removeDuplicates ()rulingList {if len (_gdbe )==0{return nil ;
 };_gdbe .sort ();_faacg :=rulingList {_gdbe [0]};for _ ,_cgec :=range _gdbe [1:]{if _cgec .equals (_faacg [len (_faacg )-1]){continue ;};_faacg =append (_faacg ,_cgec );};return _faacg ;};func _bfff (_beggf ,_adbg float64 )bool {return _eb .Abs (_beggf -_adbg )<=_efce };
@@ -715,7 +723,7 @@ switch _efeg %360{case 90:_cddcd .Urx -=_bbbaf ;case 180:_cddcd .Ury -=_bbbaf ;c
 };if _cddcd .Lly > _cddcd .Ury {_cddcd .Lly ,_cddcd .Ury =_cddcd .Ury ,_cddcd .Lly ;};_fgggc :=true ;if _aff ._daa ._egf .Width ()> 0{_gfba ,_deafb :=_cbgc (_cddcd ,_aff ._daa ._egf );if !_deafb {_fgggc =false ;_gc .Log .Debug ("\u0054\u0065\u0078\u0074\u0020m\u0061\u0072\u006b\u0020\u006f\u0075\u0074\u0073\u0069\u0064\u0065\u0020\u0070a\u0067\u0065\u002e\u0020\u0062\u0062\u006f\u0078\u003d\u0025\u0067\u0020\u006d\u0065\u0064\u0069\u0061\u0042\u006f\u0078\u003d\u0025\u0067\u0020\u0074\u0065\u0078\u0074\u003d\u0025q",_cddcd ,_aff ._daa ._egf ,_gggef );
 };_cddcd =_gfba ;};_ccaga :=_cddcd ;_cdcf :=_aff ._daa ._egf ;switch _efeg %360{case 90:_cdcf .Urx ,_cdcf .Ury =_cdcf .Ury ,_cdcf .Urx ;_ccaga =_eg .PdfRectangle {Llx :_cdcf .Urx -_cddcd .Ury ,Urx :_cdcf .Urx -_cddcd .Lly ,Lly :_cddcd .Llx ,Ury :_cddcd .Urx };
 case 180:_ccaga =_eg .PdfRectangle {Llx :_cdcf .Urx -_cddcd .Llx ,Urx :_cdcf .Urx -_cddcd .Urx ,Lly :_cdcf .Ury -_cddcd .Lly ,Ury :_cdcf .Ury -_cddcd .Ury };case 270:_cdcf .Urx ,_cdcf .Ury =_cdcf .Ury ,_cdcf .Urx ;_ccaga =_eg .PdfRectangle {Llx :_cddcd .Ury ,Urx :_cddcd .Lly ,Lly :_cdcf .Ury -_cddcd .Llx ,Ury :_cdcf .Ury -_cddcd .Urx };
-};if _ccaga .Llx > _ccaga .Urx {_ccaga .Llx ,_ccaga .Urx =_ccaga .Urx ,_ccaga .Llx ;};if _ccaga .Lly > _ccaga .Ury {_ccaga .Lly ,_ccaga .Ury =_ccaga .Ury ,_ccaga .Lly ;};_eeggf :=textMark {_ccgc :_gggef ,PdfRectangle :_ccaga ,_gdbcc :_cddcd ,_bcgf :_ffcc ,_dcgb :_bbbaf ,_cae :_cafbe ,_fcbfa :_fbfbd ,_cbdde :_fbfaf ,_dagg :_efeg ,_fda :_gcab ,_edcdd :_eebdd ,_fgded :_gcfgg ,_ddfb :_cdbc ,Th :_aff ._aabgcd ._beea ,Tw :_aff ._aabgcd ._baae ,_egced :_eefdf ,_aedfe :_agae };
+};if _ccaga .Llx > _ccaga .Urx {_ccaga .Llx ,_ccaga .Urx =_ccaga .Urx ,_ccaga .Llx ;};if _ccaga .Lly > _ccaga .Ury {_ccaga .Lly ,_ccaga .Ury =_ccaga .Ury ,_ccaga .Lly ;};_eeggf :=textMark {_ccgc :_gggef ,PdfRectangle :_ccaga ,_gdbcc :_cddcd ,_bcgf :_ffcc ,_dcgb :_bbbaf ,_cae :_cafbe ,_fcbfa :_fbfbd ,_cbdde :_fbfaf ,_dagg :_efeg ,_fda :_gcab ,_edcdd :_eebdd ,_fgded :_gcfgg ,_ddfb :_cdbc ,Th :_aff ._aabgcd ._beea ,Tw :_aff ._aabgcd ._baae ,_egced :_eefdf ,_aedfe :_agae ,_source :_aff ._sources .current ()};
 if _gdfc {_gc .Log .Info ("n\u0065\u0077\u0054\u0065\u0078\u0074M\u0061\u0072\u006b\u003a\u0020\u0073t\u0061\u0072\u0074\u003d\u0025\u002e\u0032f\u0020\u0065\u006e\u0064\u003d\u0025\u002e\u0032\u0066\u0020%\u0073",_cfbb ,_fbfaf ,_eeggf .String ());};return _eeggf ,_fgggc ;
 };func (_dbe *textObject )getFontDict (_agfd string )(_cegc _ga .PdfObject ,_gebed error ){_abfdf :=_dbe ._bcgg ;if _abfdf ==nil {_gc .Log .Debug ("g\u0065\u0074\u0046\u006f\u006e\u0074D\u0069\u0063\u0074\u002e\u0020\u004eo\u0020\u0072\u0065\u0073\u006f\u0075\u0072c\u0065\u0073\u002e\u0020\u006e\u0061\u006d\u0065\u003d\u0025#\u0071",_agfd );
 return nil ,nil ;};_cegc ,_ggbgd :=_abfdf .GetFontByName (_ga .PdfObjectName (_agfd ));if !_ggbgd {_gc .Log .Debug ("\u0045R\u0052\u004fR\u003a\u0020\u0067\u0065t\u0046\u006f\u006et\u0044\u0069\u0063\u0074\u003a\u0020\u0046\u006f\u006et \u006e\u006f\u0074 \u0066\u006fu\u006e\u0064\u003a\u0020\u006e\u0061m\u0065\u003d%\u0023\u0071",_agfd );
@@ -813,14 +821,14 @@ _gaceb :=0;for _cdga :=range _fcbd {_dgbfa [_gaceb ]=_cdga ;_gaceb ++;};_a .Ints
 _efbebd :=map[float64 ][]*textLine {};for _ ,_bgdc :=range _faab {_fgce :=_ggcd (_bgdc );_fgce =_eb .Round (_fgce );_efbebd [_fgce ]=append (_efbebd [_fgce ],_bgdc );};return _efbebd ;};func _ggag (_cfcc _eg .PdfRectangle )*ruling {return &ruling {_bfgba :_faage ,_gaca :_cfcc .Urx ,_fgfc :_cfcc .Lly ,_ggbbc :_cfcc .Ury };
 };func (_cef *stateStack )push (_gad *textState ){_dcb :=*_gad ;*_cef =append (*_cef ,&_dcb )};func (_cfdg *stateStack )empty ()bool {return len (*_cfdg )==0};func (_ccfba *wordBag )allWords ()[]*textWord {var _cegeb []*textWord ;for _ ,_efdd :=range _ccfba ._ffda {_cegeb =append (_cegeb ,_efdd ...);
 };return _cegeb ;};type textMark struct{_eg .PdfRectangle ;_dagg int ;_ccgc string ;_bded string ;_bcgf *_eg .PdfFont ;_dcgb float64 ;_cae float64 ;_fcbfa _cf .Matrix ;_cbdde _cf .Point ;_gdbcc _eg .PdfRectangle ;_fda _d .Color ;_edcdd _d .Color ;_fgded _ga .PdfObject ;
-_ddfb []string ;Tw float64 ;Th float64 ;_egced int ;_aedfe int ;};
+_ddfb []string ;Tw float64 ;Th float64 ;_egced int ;_aedfe int ;_source *TextSource ;};
 
 // NewWithOptions an Extractor instance for extracting content from the input PDF page with options.
 func NewWithOptions (page *_eg .PdfPage ,options *Options )(*Extractor ,error ){const _ega ="\u0065x\u0074\u0072\u0061\u0063\u0074\u006f\u0072\u002e\u004e\u0065\u0077W\u0069\u0074\u0068\u004f\u0070\u0074\u0069\u006f\u006e\u0073";_bcd ,_dea :=page .GetAllContentStreams ();
 if _dea !=nil {return nil ,_dea ;};var _gcaf *_eg .StructTreeRoot ;_age ,_bbgf :=page .GetStructTreeRoot ();if !_bbgf {_gc .Log .Debug ("T\u0068\u0065\u0020\u0070\u0064\u0066\u0020\u0064\u006f\u0063\u0075\u006d\u0065\u006e\u0074\u0020\u0069\u0073\u0020\u006e\u006f\u0074\u0020\u0074\u0061\u0067g\u0065d\u002e\u0020\u0053\u0074r\u0075\u0063t\u0054\u0072\u0065\u0065\u0052\u006f\u006f\u0074\u0020\u0064\u006f\u0065\u0073\u006e\u0027\u0074\u0020\u0065\u0078\u0069\u0073\u0074\u002e");
 }else {_gcaf ,_dea =_eg .NewStructTreeRootFromPdfObject (*_age );if _dea !=nil {return nil ,_fb .Errorf ("\u0065\u0072\u0072or\u0020\u006c\u006f\u0061\u0064\u0069\u006e\u0067\u0020s\u0074r\u0075c\u0074 \u0074\u0072\u0065\u0065\u0020\u0072\u006f\u006f\u0074\u003a\u0020\u0025\u0076",_dea );
 };};_deed :=page .GetContainingPdfObject ();_afg ,_dea :=page .GetMediaBox ();if _dea !=nil {return nil ,_fb .Errorf ("\u0065\u0078\u0074r\u0061\u0063\u0074\u006fr\u0020\u0072\u0065\u0071\u0075\u0069\u0072e\u0073\u0020\u006d\u0065\u0064\u0069\u0061\u0042\u006f\u0078\u002e\u0020\u0025\u0076",_dea );
-};_bagd :=&Extractor {_fg :_bcd ,_fdg :page .Resources ,_egf :*_afg ,_deb :page .CropBox ,_add :page .GetStructParentsKey (),_dgd :map[string ]fontEntry {},_gd :map[string ]textResult {},_fagf :map[string ]textResult {},_dee :options ,_bbc :_gcaf ,_ead :_deed };
+};_bagd :=&Extractor {_fg :_bcd ,_fdg :page .Resources ,_egf :*_afg ,_deb :page .CropBox ,_add :page .GetStructParentsKey (),_dgd :map[string ]fontEntry {},_gd :map[string ]textResult {},_fagf :map[string ]textResult {},_dee :options ,_bbc :_gcaf ,_ead :_deed };if _bbgf {_bagd ._structRoot =*_age ;};_bagd ._ocVisibility =ocVisibility (page ,options );;
 if _bagd ._egf .Llx > _bagd ._egf .Urx {_gc .Log .Info ("\u004d\u0065\u0064\u0069\u0061\u0042o\u0078\u0020\u0068\u0061\u0073\u0020\u0058\u0020\u0063\u006f\u006f\u0072\u0064\u0069\u006e\u0061\u0074\u0065\u0073\u0020r\u0065\u0076\u0065\u0072\u0073\u0065\u0064\u002e\u0020\u0025\u002e\u0032\u0066\u0020F\u0069x\u0069\u006e\u0067\u002e",_bagd ._egf );
 _bagd ._egf .Llx ,_bagd ._egf .Urx =_bagd ._egf .Urx ,_bagd ._egf .Llx ;};if _bagd ._egf .Lly > _bagd ._egf .Ury {_gc .Log .Info ("\u004d\u0065\u0064\u0069\u0061\u0042o\u0078\u0020\u0068\u0061\u0073\u0020\u0059\u0020\u0063\u006f\u006f\u0072\u0064\u0069\u006e\u0061\u0074\u0065\u0073\u0020r\u0065\u0076\u0065\u0072\u0073\u0065\u0064\u002e\u0020\u0025\u002e\u0032\u0066\u0020F\u0069x\u0069\u006e\u0067\u002e",_bagd ._egf );
 _bagd ._egf .Lly ,_bagd ._egf .Ury =_bagd ._egf .Ury ,_bagd ._egf .Lly ;};if _bagd ._dee !=nil {if _bagd ._dee .IncludeAnnotations {_bagd ._dcf ,_dea =page .GetAnnotations ();if _dea !=nil {_gc .Log .Debug ("\u0045\u0072r\u006f\u0072\u0020\u0067\u0065\u0074\u0074\u0069\u006e\u0067\u0020\u0061\u006e\u006e\u006f\u0074\u0061\u0074\u0069\u006f\u006e\u0073: \u0025\u0076",_dea );
@@ -902,7 +910,11 @@ type TableCell struct{_eg .PdfRectangle ;
 Text string ;
 
 // Marks returns the TextMarks corresponding to the text in Text.
-Marks TextMarkArray ;};type textTable struct{_eg .PdfRectangle ;_abgde ,_ecdcd int ;_acge bool ;_ceegb map[uint64 ]*textPara ;_ggefc map[uint64 ]compositeCell ;};func (_cdbec intSet )add (_ggfg int ){_cdbec [_ggfg ]=struct{}{}};func (_fagd *subpath )makeRectRuling (_ddfc _d .Color )(*ruling ,bool ){if _fgbb {_gc .Log .Info ("\u006d\u0061\u006beR\u0065\u0063\u0074\u0052\u0075\u006c\u0069\u006e\u0067\u003a\u0020\u0070\u0061\u0074\u0068\u003d\u0025\u0076",_fagd );
+Marks TextMarkArray ;
+
+// RowSpan and ColSpan are the numbers of rows and columns spanned by a merged cell. Zero means one.
+// The grid positions covered by a merged cell, other than its top left position, hold empty cells.
+RowSpan ,ColSpan int ;};type textTable struct{_eg .PdfRectangle ;_abgde ,_ecdcd int ;_acge bool ;_ceegb map[uint64 ]*textPara ;_ggefc map[uint64 ]compositeCell ;};func (_cdbec intSet )add (_ggfg int ){_cdbec [_ggfg ]=struct{}{}};func (_fagd *subpath )makeRectRuling (_ddfc _d .Color )(*ruling ,bool ){if _fgbb {_gc .Log .Info ("\u006d\u0061\u006beR\u0065\u0063\u0074\u0052\u0075\u006c\u0069\u006e\u0067\u003a\u0020\u0070\u0061\u0074\u0068\u003d\u0025\u0076",_fagd );
 };_ggfc :=_fagd ._gcfa [:4];_ccae :=make (map[int ]rulingKind ,len (_ggfc ));for _agge ,_bcadb :=range _ggfc {_badc :=_fagd ._gcfa [(_agge +1)%4];_ccae [_agge ]=_bgecc (_bcadb ,_badc );if _fgbb {_fb .Printf ("\u0025\u0034\u0064: \u0025\u0073\u0020\u003d\u0020\u0025\u0036\u002e\u0032\u0066\u0020\u002d\u0020\u0025\u0036\u002e\u0032\u0066",_agge ,_ccae [_agge ],_bcadb ,_badc );
 };};if _fgbb {_fb .Printf ("\u0020\u0020\u0020\u006b\u0069\u006e\u0064\u0073\u003d\u0025\u002b\u0076\u000a",_ccae );};var _eeade ,_gddf []int ;for _afaa ,_bgccf :=range _ccae {switch _bgccf {case _afdgf :_gddf =append (_gddf ,_afaa );case _faage :_eeade =append (_eeade ,_afaa );
 };};if _fgbb {_fb .Printf ("\u0020\u0020 \u0068\u006f\u0072z\u0073\u003d\u0025\u0064\u0020\u0025\u002b\u0076\u000a",len (_gddf ),_gddf );_fb .Printf ("\u0020\u0020 \u0076\u0065\u0072t\u0073\u003d\u0025\u0064\u0020\u0025\u002b\u0076\u000a",len (_eeade ),_eeade );
@@ -943,7 +955,22 @@ RelaxedMode bool ;
 // DisableDehyphenation specifies whether to disable de-hyphenation of words that are split across lines.
 // If `true`, words ending in a hyphen will not be combined with the next line.
 // Default is `false` (de-hyphenation enabled).
-DisableDehyphenation bool ;};const (RenderModeStroke RenderMode =1<<iota ;RenderModeFill ;RenderModeClip ;);func (_cbge *shapesState )lastpointEstablished ()(_cf .Point ,bool ){if _cbge ._ddgd {return _cbge ._egbc ,false ;};_gccae :=len (_cbge ._eeae );
+DisableDehyphenation bool ;
+
+// StructureOrder specifies whether the text of tagged documents is returned in the logical order of the
+// structure tree instead of the layout order. Artifacts such as running headers and footers are omitted, and
+// the ActualText, expansion (E) and alternate description (Alt) entries of structure elements are applied.
+// Tables and paragraphs are still returned in layout order.
+// Default is `false`.
+StructureOrder bool ;
+
+// OCVisibility is the visibility of optional content (layers) used to omit the content of hidden optional
+// content groups. If nil, the default configuration of the document for viewing is used.
+OCVisibility *_eg .OCVisibility ;
+
+// IncludeHiddenContent specifies whether to include the content of hidden optional content groups.
+// Default is `false`.
+IncludeHiddenContent bool ;};const (RenderModeStroke RenderMode =1<<iota ;RenderModeFill ;RenderModeClip ;);func (_cbge *shapesState )lastpointEstablished ()(_cf .Point ,bool ){if _cbge ._ddgd {return _cbge ._egbc ,false ;};_gccae :=len (_cbge ._eeae );
 if _gccae > 0&&_cbge ._eeae [_gccae -1]._fagfd {return _cbge ._eeae [_gccae -1].last (),false ;};return _cf .Point {},true ;};func _gg (_aac []string ,_ebf int ,_aeg string )int {_ad :=_ebf ;for ;_ad < len (_aac );_ad ++{if _aac [_ad ]!=_aeg {return _ad ;
 };};return _ad ;};func _acdcc (_bccf *list )[]*textLine {for _ ,_afdg :=range _bccf ._befbc {switch _afdg ._gebg {case "\u004c\u0042\u006fd\u0079":if len (_afdg ._bffg )!=0{return _afdg ._bffg ;};return _acdcc (_afdg );case "\u0053\u0070\u0061\u006e":return _afdg ._bffg ;
 case "I\u006e\u006c\u0069\u006e\u0065\u0053\u0068\u0061\u0070\u0065":return _afdg ._bffg ;};};return nil ;};func (_cab *imageExtractContext )extractXObjectImage (_aaeee *_ga .PdfObjectName ,_adfd _cd .GraphicsState ,_dbc *_eg .PdfPageResources )error {_dgbc ,_ :=_dbc .GetXObjectByName (*_aaeee );
@@ -964,10 +991,10 @@ _gcfad {continue ;};for _ ,_egfb :=range _begde {if _ ,_ccge :=_gaggb [_egfb ];_
 };};};};};func (_dbbb *textPara )text ()string {_fgaa :=new (_ec .Buffer );_dbbb .writeText (_fgaa ,&textContext {});return _fgaa .String ();};func (_afggd *TextMarkArray )getTextMarkAtOffset (_adee int )*TextMark {for _ ,_bbbb :=range _afggd ._edfaa {if _bbbb .Offset ==_adee {return &_bbbb ;
 };};return nil ;};func _adg (_aabfe []byte ,_edc *_eg .PdfFont )string {_gdb :=_edc .BytesToCharcodes (_aabfe );_cdb ,_caca ,_aegg :=_edc .CharcodesToStrings (_gdb ,"");if _aegg > 0{_gc .Log .Debug ("\u0072\u0065nd\u0065\u0072\u0054e\u0078\u0074\u003a\u0020num\u0043ha\u0072\u0073\u003d\u0025\u0064\u0020\u006eum\u004d\u0069\u0073\u0073\u0065\u0073\u003d%\u0064",_caca ,_aegg );
 };_edd :=_c .Join (_cdb ,"");return _edd ;};func (_eee *Extractor )extractPageText (_feeg string ,_ecg *_eg .PdfPageResources ,_dbb _cf .Matrix ,_agc int ,_aga bool )(*PageText ,int ,int ,error ){_gc .Log .Trace ("\u0065x\u0074\u0072\u0061\u0063t\u0050\u0061\u0067\u0065\u0054e\u0078t\u003a \u006c\u0065\u0076\u0065\u006c\u003d\u0025d",_agc );
-_egb :=&PageText {_cggfb :_eee ._egf ,_eace :_eee ._bbc ,_geee :_eee ._ead };_gfgc :=_cabaa (_eee ._egf );var _bdga stateStack ;_gaec :=_gaef (_eee ,_ecg ,_cd .GraphicsState {},&_gfgc ,&_bdga );_cbedd :=shapesState {_fgfag :_dbb ,_cacff :_cf .IdentityMatrix (),_fggb :_gaec };
+_egb :=&PageText {_cggfb :_eee ._egf ,_eace :_eee ._bbc ,_geee :_eee ._ead };_gfgc :=_cabaa (_eee ._egf );var _bdga stateStack ;_gaec :=_gaef (_eee ,_ecg ,_cd .GraphicsState {},&_gfgc ,&_bdga );_srcs :=newSourceTracker (_eee ._ocVisibility );_gaec ._sources =_srcs ;_cbedd :=shapesState {_fgfag :_dbb ,_cacff :_cf .IdentityMatrix (),_fggb :_gaec };
 var _fcbe bool ;_bfcb :=-1;_ebg :="";if _agc > _ecd {_bde :=_g .New ("\u0066\u006f\u0072\u006d s\u0074\u0061\u0063\u006b\u0020\u006f\u0076\u0065\u0072\u0066\u006c\u006f\u0077");_gc .Log .Debug ("\u0045\u0052\u0052\u004f\u0052\u003a \u0065\u0078\u0074\u0072\u0061\u0063\u0074\u0050\u0061\u0067\u0065\u0054\u0065\u0078\u0074\u002e\u0020\u0072\u0065\u0063u\u0072\u0073\u0069\u006f\u006e\u0020\u006c\u0065\u0076\u0065\u006c\u003d\u0025\u0064 \u0065r\u0072\u003d\u0025\u0076",_agc ,_bde );
 return _egb ,_gfgc ._accbd ,_gfgc ._bad ,_bde ;};_abg :=_cd .NewContentStreamParser (_feeg );_ebdf ,_gbge :=_abg .Parse ();if _gbge !=nil {_gc .Log .Debug ("\u0045\u0052\u0052\u004f\u0052\u003a\u0020e\u0078\u0074\u0072a\u0063\u0074\u0050\u0061g\u0065\u0054\u0065\u0078\u0074\u0020\u0070\u0061\u0072\u0073\u0065\u0020\u0066\u0061\u0069\u006c\u0065\u0064\u002e\u0020\u0065\u0072\u0072\u003d\u0025\u0076",_gbge );
-return _egb ,_gfgc ._accbd ,_gfgc ._bad ,_gbge ;};_egb ._dcff =_ebdf ;_ebb :=_cd .NewContentStreamProcessor (*_ebdf );if _eee ._dee !=nil {_ebb .SetRelaxedMode (_eee ._dee .RelaxedMode );};_ebb .AddHandler (_cd .HandlerConditionEnumAllOperands ,"",func (_dgdf *_cd .ContentStreamOperation ,_agf _cd .GraphicsState ,_beb *_eg .PdfPageResources )error {_ebbf :=_dgdf .Operand ;
+return _egb ,_gfgc ._accbd ,_gfgc ._bad ,_gbge ;};_egb ._dcff =_ebdf ;_ebb :=_cd .NewContentStreamProcessor (*_ebdf );if _eee ._dee !=nil {_ebb .SetRelaxedMode (_eee ._dee .RelaxedMode );};_ebb .AddHandler (_cd .HandlerConditionEnumAllOperands ,"",func (_dgdf *_cd .ContentStreamOperation ,_agf _cd .GraphicsState ,_beb *_eg .PdfPageResources )error {_ebbf :=_dgdf .Operand ;_srcs .process (_dgdf ,_beb );if _srcs .hides (_dgdf ,_beb ){_cbedd .clearPath ();return nil ;};
 if _gbbg {_gc .Log .Info ("\u0026&\u0026\u0020\u006f\u0070\u003d\u0025s",_dgdf );};switch _ebbf {case "\u0071":if _cbfb {_gc .Log .Info ("\u0063\u0074\u006d\u003d\u0025\u0073",_cbedd ._cacff );};_bdga .push (&_gfgc );case "\u0051":if !_bdga .empty (){_gfgc =*_bdga .pop ();
 };_cbedd ._cacff =_agf .CTM ;if _cbfb {_gc .Log .Info ("\u0063\u0074\u006d\u003d\u0025\u0073",_cbedd ._cacff );};case "\u0042\u0044\u0043":_bfed ,_aaec :=_ga .GetDict (_dgdf .Params [1]);if !_aaec {_gc .Log .Debug ("\u0045\u0052\u0052O\u0052\u003a\u0020\u0042D\u0043\u0020\u006f\u0070\u003d\u0025\u0073 \u0047\u0065\u0074\u0044\u0069\u0063\u0074\u0020\u0066\u0061\u0069\u006c\u0065\u0064",_dgdf );
 return _gbge ;};_accf :=_bfed .Get ("\u004d\u0043\u0049\u0044");if _accf !=nil {_cggf ,_dbca :=_ga .GetIntVal (_accf );if !_dbca {_gc .Log .Debug ("\u0045R\u0052\u004fR\u003a\u0020\u0042\u0044C\u0020\u006f\u0070=\u0025\u0073\u002e\u0020\u0042\u0061\u0064\u0020\u006eum\u0065\u0072\u0069c\u0061\u006c \u006f\u0062\u006a\u0065\u0063\u0074.\u0020\u006f=\u0025\u0073",_dgdf ,_accf );
@@ -978,7 +1005,7 @@ _caad !=nil {_aacd :=_caad .GetChildren ();if len (_aacd )==1&&_aacd [0].GetMCID
 if _aaca !=nil {_gc .Log .Debug ("\u0045\u0052\u0052\u004f\u0052\u003a \u0042\u0044\u0043\u0020\u006f\u0070\u003d\u0025\u0073\u002e\u0020\u0042\u0061d\u0020\u004b\u0044\u0069\u0063\u0074\u002e \u006f\u003d\u0025\u0073",_dgdf ,_ggef );continue ;};_bda :=_bdcb .GetChildren ();
 if len (_bda )==1&&_bda [0].GetMCID ()!=nil {if *_bda [0].GetMCID ()==_bfcb {if _bdcb .ActualText !=nil {_ebg =_c .TrimSpace (_bdcb .ActualText .Str ());};break ;}else if _bgdb (_bda ){break ;};};};};};};};};};};};if _ebg ==""{_accb :=_bfed .Get ("\u0041\u0063\u0074\u0075\u0061\u006c\u0054\u0065\u0078\u0074");
 if _accb !=nil {_ebg =_c .TrimSpace (_accb .String ());};};case "\u0045\u004d\u0043":_bfcb =-1;_ebg ="";case "\u0042\u0054":if _fcbe {_gc .Log .Debug ("\u0042\u0054\u0020\u0063\u0061\u006c\u006c\u0065\u0064\u0020\u0077\u0068\u0069\u006c\u0065 \u0069n\u0020\u0061\u0020\u0074\u0065\u0078\u0074\u0020\u006f\u0062\u006a\u0065\u0063\u0074");
-_egb ._fbeb =append (_egb ._fbeb ,_gaec ._fgef ...);};_fcbe =true ;_cfc :=_agf ;if _aga {_cfc =_cd .GraphicsState {};_cfc .CTM =_cbedd ._cacff ;};_cfc .CTM =_dbb .Mult (_cfc .CTM );_gaec =_gaef (_eee ,_beb ,_cfc ,&_gfgc ,&_bdga );_cbedd ._fggb =_gaec ;
+_egb ._fbeb =append (_egb ._fbeb ,_gaec ._fgef ...);};_fcbe =true ;_cfc :=_agf ;if _aga {_cfc =_cd .GraphicsState {};_cfc .CTM =_cbedd ._cacff ;};_cfc .CTM =_dbb .Mult (_cfc .CTM );_gaec =_gaef (_eee ,_beb ,_cfc ,&_gfgc ,&_bdga );_gaec ._sources =_srcs ;_cbedd ._fggb =_gaec ;
 case "\u0045\u0054":if !_fcbe {_gc .Log .Debug ("\u0045\u0054\u0020ca\u006c\u006c\u0065\u0064\u0020\u006f\u0075\u0074\u0073i\u0064e\u0020o\u0066 \u0061\u0020\u0074\u0065\u0078\u0074\u0020\u006f\u0062\u006a\u0065\u0063\u0074");};_fcbe =false ;_egb ._fbeb =append (_egb ._fbeb ,_gaec ._fgef ...);
 _gaec .reset ();case "\u0054\u002a":_gaec .nextLine ();case "\u0054\u0064":if _cfa ,_aeac :=_gaec .checkOp (_dgdf ,2,true );!_cfa {_gc .Log .Debug ("\u0045\u0052\u0052\u004f\u0052\u003a\u0020\u0065\u0072\u0072\u003d\u0025\u0076",_aeac );return _aeac ;};
 _bgaf ,_aef ,_dcfa :=_ddcg (_dgdf .Params );if _dcfa !=nil {return _dcfa ;};_gaec .moveText (_bgaf ,_aef );case "\u0054\u0044":if _cade ,_agbd :=_gaec .checkOp (_dgdf ,2,true );!_cade {_gc .Log .Debug ("\u0045\u0052\u0052\u004f\u0052\u003a\u0020\u0065\u0072\u0072\u003d\u0025\u0076",_agbd );
@@ -1019,15 +1046,15 @@ return _ga .ErrTypeError ;};_ ,_aeed :=_beb .GetXObjectByName (*_dbge );if _aeed
 return _bfec ;};_ebgc ,_bfec :=_bdf .GetContentStream ();if _bfec !=nil {_gc .Log .Debug ("\u0045R\u0052\u004f\u0052\u003a\u0020\u0025v",_bfec );return _bfec ;};_eead :=_bdf .Resources ;if _eead ==nil {_eead =_beb ;};_dda :=_agf .CTM ;if _ddeb ,_ffed :=_ga .GetArray (_bdf .Matrix );
 _ffed {_fgcg ,_ddab :=_ddeb .GetAsFloat64Slice ();if _ddab !=nil {return _ddab ;};if len (_fgcg )!=6{return _fe ;};_bbff :=_cf .NewMatrix (_fgcg [0],_fgcg [1],_fgcg [2],_fgcg [3],_fgcg [4],_fgcg [5]);_dda =_agf .CTM .Mult (_bbff );};_cdef ,_ebgd ,_bcga ,_bfec :=_eee .extractPageText (string (_ebgc ),_eead ,_dbb .Mult (_dda ),_agc +1,false );
 if _bfec !=nil {_gc .Log .Debug ("\u0045R\u0052\u004f\u0052\u003a\u0020\u0025v",_bfec );return _bfec ;};_edab =textResult {*_cdef ,_ebgd ,_bcga };_eee ._gd [_dbge .String ()]=_edab ;};_cbedd ._cacff =_agf .CTM ;if _cbfb {_gc .Log .Info ("\u0063\u0074\u006d\u003d\u0025\u0073",_cbedd ._cacff );
-};_egb ._fbeb =append (_egb ._fbeb ,_edab ._cded ._fbeb ...);_egb ._fbdg =append (_egb ._fbdg ,_edab ._cded ._fbdg ...);_egb ._gggf =append (_egb ._gggf ,_edab ._cded ._gggf ...);_gfgc ._accbd +=_edab ._cfef ;_gfgc ._bad +=_edab ._ccdf ;case "\u0072\u0067","\u0067","\u006b","\u0063\u0073","\u0073\u0063","\u0073\u0063\u006e":_gaec ._ceba .ColorspaceNonStroking =_agf .ColorspaceNonStroking ;
+};_egb ._fbeb =append (_egb ._fbeb ,_srcs .formMarks (_edab ._cded ._fbeb ,*_dbge ,_beb )...);_egb ._fbdg =append (_egb ._fbdg ,_edab ._cded ._fbdg ...);_egb ._gggf =append (_egb ._gggf ,_edab ._cded ._gggf ...);_gfgc ._accbd +=_edab ._cfef ;_gfgc ._bad +=_edab ._ccdf ;case "\u0072\u0067","\u0067","\u006b","\u0063\u0073","\u0073\u0063","\u0073\u0063\u006e":_gaec ._ceba .ColorspaceNonStroking =_agf .ColorspaceNonStroking ;
 _gaec ._ceba .ColorNonStroking =_agf .ColorNonStroking ;case "\u0052\u0047","\u0047","\u004b","\u0043\u0053","\u0053\u0043","\u0053\u0043\u004e":_gaec ._ceba .ColorspaceStroking =_agf .ColorspaceStroking ;_gaec ._ceba .ColorStroking =_agf .ColorStroking ;
-};return nil ;});_gbge =_ebb .Process (_ecg );if _eee ._dee !=nil &&_eee ._dee .IncludeAnnotations &&!_aga {for _ ,_efada :=range _eee ._dcf {_dgbcb ,_dfga :=_ga .GetDict (_efada .AP );if !_dfga {continue ;};_dfcg ,_dfga :=_dgbcb .Get ("\u004e").(*_ga .PdfObjectStream );
+};return nil ;});_gbge =_ebb .Process (_ecg );if _eee ._dee !=nil &&_eee ._dee .IncludeAnnotations &&!_aga &&_agc ==0{for _ ,_efada :=range _eee ._dcf {if !_eee ._ocVisibility .IsVisible (_efada .OC ){continue ;};_dgbcb ,_dfga :=_ga .GetDict (_efada .AP );if !_dfga {continue ;};_dfcg ,_dfga :=_dgbcb .Get ("\u004e").(*_ga .PdfObjectStream );
 if !_dfga {continue ;};_cacf ,_dfef :=_ga .DecodeStream (_dfcg );if _dfef !=nil {_gc .Log .Debug ("\u0045\u0072\u0072\u006f\u0072\u0020\u006f\u006e\u0020\u0064\u0065c\u006f\u0064\u0065\u0020\u0073\u0074\u0072\u0065\u0061\u006d:\u0020\u0025\u0076",_dfef );
 continue ;};_cce :=_dfcg .PdfObjectDictionary .Get ("\u0052e\u0073\u006f\u0075\u0072\u0063\u0065s");_fada ,_dfef :=_eg .NewPdfPageResourcesFromDict (_cce .(*_ga .PdfObjectDictionary ));if _dfef !=nil {_gc .Log .Debug ("\u0045\u0072\u0072\u006f\u0072 \u006f\u006e\u0020\u0067\u0065\u0074\u0074\u0069\u006e\u0067\u0020\u0061\u006en\u006f\u0074\u0061\u0074\u0069\u006f\u006e\u0020\u0072\u0065\u0073\u006f\u0075\u0072\u0063\u0065\u0073\u003a\u0020\u0025\u0076",_dfef );
 continue ;};_gceb :=_cf .IdentityMatrix ();_abgbd ,_dfga :=_dfcg .PdfObjectDictionary .Get ("\u004d\u0061\u0074\u0072\u0069\u0078").(*_ga .PdfObjectArray );if _dfga {_ddbe ,_gcaa :=_abgbd .GetAsFloat64Slice ();if _gcaa !=nil {_gc .Log .Debug ("\u0045\u0072\u0072or\u0020\u006f\u006e\u0020\u0067\u0065\u0074\u0074\u0069n\u0067 \u0066l\u006fa\u0074\u0036\u0034\u0020\u0073\u006c\u0069\u0063\u0065\u003a\u0020\u0025\u0076",_gcaa );
 continue ;};if len (_ddbe )!=6{_gc .Log .Debug ("I\u006e\u0076\u0061\u006c\u0069\u0064 \u006d\u0061\u0074\u0072\u0069\u0078\u0020\u0073\u006ci\u0063\u0065\u0020l\u0065n\u0067\u0074\u0068");continue ;};_gceb =_cf .NewMatrix (_ddbe [0],_ddbe [1],_ddbe [2],_ddbe [3],_ddbe [4],_ddbe [5]);
 };_bgda ,_dfga :=_eee ._fagf [_dfcg .String ()];if !_dfga {_gff ,_bfb ,_ceca ,_bge :=_eee .extractPageText (string (_cacf ),_fada ,_gceb ,_agc +1,true );if _bge !=nil {_gc .Log .Debug ("\u0045\u0052R\u004f\u0052\u0020\u0065x\u0074\u0072a\u0063\u0074\u0069\u006e\u0067\u0020\u0061\u006en\u006f\u0074\u0061\u0074\u0069\u006f\u006e\u0020\u0074\u0065\u0078\u0074s\u003a\u0020\u0025\u0076",_bge );
-continue ;};_bgda =textResult {*_gff ,_bfb ,_ceca };_eee ._fagf [_dfcg .String ()]=_bgda ;};_egb ._fbeb =append (_egb ._fbeb ,_bgda ._cded ._fbeb ...);_egb ._fbdg =append (_egb ._fbdg ,_bgda ._cded ._fbdg ...);_egb ._gggf =append (_egb ._gggf ,_bgda ._cded ._gggf ...);
+continue ;};_bgda =textResult {*_gff ,_bfb ,_ceca };_eee ._fagf [_dfcg .String ()]=_bgda ;};_egb ._fbeb =append (_egb ._fbeb ,annotationMarks (_bgda ._cded ._fbeb ,_efada )...);_egb ._fbdg =append (_egb ._fbdg ,_bgda ._cded ._fbdg ...);_egb ._gggf =append (_egb ._gggf ,_bgda ._cded ._gggf ...);
 _gfgc ._accbd +=_bgda ._cfef ;_gfgc ._bad +=_bgda ._ccdf ;};};return _egb ,_gfgc ._accbd ,_gfgc ._bad ,_gbge ;};func _gafb (_eegc []*textLine ,_facc string )string {var _fafa _c .Builder ;_edgf :=0.0;for _bbgfe ,_dfcgd :=range _eegc {_gdd :=_dfcgd .text ();
 _egeb :=_dfcgd ._egce ;if _bbgfe < len (_eegc )-1{_edgf =_eegc [_bbgfe +1]._egce ;}else {_edgf =0.0;};_fafa .WriteString (_facc );_fafa .WriteString (_gdd );if _edgf !=_egeb {_fafa .WriteString ("\u000a");}else {_fafa .WriteString ("\u0020");};};return _fafa .String ();
 };func _bebf (_efbbf []*textWord ,_bdfc int )[]*textWord {_baafc :=len (_efbbf );copy (_efbbf [_bdfc :],_efbbf [_bdfc +1:]);return _efbbf [:_baafc -1];};func (_gaf *PageFonts )extractPageResourcesToFont (_deaf *_eg .PdfPageResources )error {if _deaf .Font ==nil {return _g .New (_fge );
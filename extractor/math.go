@@ -0,0 +1,741 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// MathRegion is a region of the page text that holds mathematics, such as a formula within a line of
+// text or a displayed equation.
+type MathRegion struct {
+	// BBox is the bounding box of the region, including fraction rules.
+	BBox model.PdfRectangle
+
+	// Display is true for equations set on lines of their own and false for formulas within lines of text.
+	Display bool
+
+	// Text is the extracted text of the region.
+	Text string
+
+	// Marks are the TextMarks of the region in the order of the page text.
+	Marks TextMarkArray
+
+	// LaTeX and MathML are best-effort reconstructions of the region with its fractions, superscripts and
+	// subscripts. Glyphs in private use areas of math fonts are copied as they are.
+	LaTeX  string
+	MathML string
+}
+
+// MathRegions returns the regions of the page text that hold mathematics. Math is detected from glyphs of
+// math fonts such as Symbol, CMMI, CMSY and STIX, from mathematical characters, from superscripts and
+// subscripts next to operators, and from fraction rules with text centered above and below them.
+func (pt PageText) MathRegions() []MathRegion {
+	marks := pt.Marks().Elements()
+	if len(marks) == 0 {
+		return nil
+	}
+	m := &mathDetector{marks: marks, rules: pt.textRules()}
+	m.styles = pt.markStyles(marks, m.rules)
+	m.lines()
+	m.fractions()
+	m.runs()
+	return m.regions()
+}
+
+// TextWithMath returns the text of the page with its math regions replaced by `fence(region)`. If `fence`
+// is nil, formulas are fenced as $LaTeX$ and displayed equations as $$LaTeX$$ on lines of their own.
+func (pt PageText) TextWithMath(fence func(region MathRegion) string) string {
+	if fence == nil {
+		fence = func(region MathRegion) string {
+			if region.Display {
+				return "\n$$" + region.LaTeX + "$$\n"
+			}
+			return "$" + region.LaTeX + "$"
+		}
+	}
+	text := pt.Text()
+	type replacement struct {
+		start, end int
+		text       string
+	}
+	var replacements []replacement
+	for _, region := range pt.MathRegions() {
+		marks := append([]TextMark(nil), region.Marks.Elements()...)
+		sort.Slice(marks, func(i, j int) bool { return marks[i].Offset < marks[j].Offset })
+		first := true
+		for i := 0; i < len(marks); {
+			start, end := marks[i].Offset, marks[i].Offset+len(marks[i].Text)
+			for i++; i < len(marks) && marks[i].Offset >= end &&
+				strings.TrimSpace(text[end:marks[i].Offset]) == ""; i++ {
+				end = marks[i].Offset + len(marks[i].Text)
+			}
+			r := replacement{start: start, end: end}
+			if first {
+				r.text = fence(region)
+				first = false
+			}
+			replacements = append(replacements, r)
+		}
+	}
+	sort.Slice(replacements, func(i, j int) bool { return replacements[i].start < replacements[j].start })
+	var buf bytes.Buffer
+	pos := 0
+	for _, r := range replacements {
+		if r.start < pos || r.end > len(text) {
+			continue
+		}
+		buf.WriteString(text[pos:r.start])
+		buf.WriteString(r.text)
+		pos = r.end
+	}
+	buf.WriteString(text[pos:])
+	return buf.String()
+}
+
+// mathDetector detects the math regions of the marks of a page.
+type mathDetector struct {
+	marks  []TextMark
+	styles []TextStyle
+	rules  []textRule
+
+	// line are the indexes of the lines of the marks, and lineMarks the marks of the lines.
+	line      []int
+	lineMarks [][]int
+
+	// fracs are the fractions and frac the index of the fraction of each mark, or -1.
+	fracs []*mathFraction
+	frac  []int
+
+	// candidates are sets of marks that hold math. Weak candidates are kept only when they are merged
+	// with other candidates.
+	candidates [][]int
+	weak       []bool
+}
+
+// mathFraction is a fraction rule with its numerator and denominator.
+type mathFraction struct {
+	rule     textRule
+	num, den []int
+}
+
+func (m *mathDetector) lines() {
+	m.line = make([]int, len(m.marks))
+	m.frac = make([]int, len(m.marks))
+	n := 0
+	for i := range m.marks {
+		if i > 0 && newTextLine(m.marks[i-1], m.marks[i]) {
+			n++
+		}
+		m.line[i] = n
+		m.frac[i] = -1
+	}
+	m.lineMarks = make([][]int, n+1)
+	for i, l := range m.line {
+		m.lineMarks[l] = append(m.lineMarks[l], i)
+	}
+}
+
+// fractions finds the fraction rules: short horizontal rules with text centered directly above and below
+// them. The numerator is set higher above the rule than an underlined word.
+func (m *mathDetector) fractions() {
+	for _, rule := range m.rules {
+		width := rule.urx - rule.llx
+		if width > 300 {
+			continue
+		}
+		var num, den []int
+		isolated := true
+		for i, mark := range m.marks {
+			if mark.Meta || m.frac[i] >= 0 || strings.TrimSpace(mark.Text) == "" {
+				continue
+			}
+			s := mark.FontSize
+			cx := (mark.BBox.Llx + mark.BBox.Urx) / 2
+			above := mark.BBox.Lly >= rule.y+0.15*s && mark.BBox.Lly <= rule.y+1.2*s
+			below := mark.BBox.Ury <= rule.y+0.1*s && mark.BBox.Ury >= rule.y-0.9*s
+			if !above && !below {
+				continue
+			}
+			if cx >= rule.llx && cx <= rule.urx {
+				if above {
+					num = append(num, i)
+				} else {
+					den = append(den, i)
+				}
+			} else if mark.BBox.Urx > rule.llx-0.3*s && mark.BBox.Llx < rule.urx+0.3*s {
+				isolated = false
+			}
+		}
+		if !isolated || len(num) == 0 || len(den) == 0 || !m.centered(num, rule) || !m.centered(den, rule) {
+			continue
+		}
+		for _, i := range append(append([]int(nil), num...), den...) {
+			m.frac[i] = len(m.fracs)
+		}
+		m.fracs = append(m.fracs, &mathFraction{rule: rule, num: num, den: den})
+	}
+}
+
+// centered returns true if the marks `idx` are centered on `rule`.
+func (m *mathDetector) centered(idx []int, rule textRule) bool {
+	bbox := m.bbox(idx)
+	width := rule.urx - rule.llx
+	center := (bbox.Llx + bbox.Urx) / 2
+	return bbox.Width() <= width+1 && math.Abs(center-(rule.llx+rule.urx)/2) <= 0.25*width+1
+}
+
+func (m *mathDetector) bbox(idx []int) model.PdfRectangle {
+	var bbox model.PdfRectangle
+	for k, i := range idx {
+		if k == 0 {
+			bbox = m.marks[i].BBox
+		} else {
+			bbox = unionDocumentBBox(bbox, m.marks[i].BBox)
+		}
+	}
+	return bbox
+}
+
+// mathWord is a word of a line of marks.
+type mathWord struct {
+	marks []int
+	text  string
+	kind  int
+}
+
+// The kinds of math words.
+const (
+	mathWordText = iota
+	mathWordFormulaic
+	mathWordRelation
+	mathWordScripted
+	mathWordStrong
+)
+
+// runs finds the runs of math words of each line. A run is math if it holds a word with a math font or
+// character, or a relation together with scripts or at least two other formulaic words. Other runs with
+// a relation or a script are weak candidates, e.g. the "y =" left of a displayed fraction.
+func (m *mathDetector) runs() {
+	for _, f := range m.fracs {
+		m.candidates = append(m.candidates, append(append([]int(nil), f.num...), f.den...))
+		m.weak = append(m.weak, false)
+	}
+	for _, idx := range m.lineMarks {
+		words := m.words(idx)
+		for i := 0; i < len(words); {
+			if words[i].kind == mathWordText {
+				i++
+				continue
+			}
+			j := i
+			strong, relation, scripted, formulaic := false, false, false, 0
+			for ; j < len(words) && words[j].kind != mathWordText; j++ {
+				switch words[j].kind {
+				case mathWordStrong:
+					strong = true
+				case mathWordRelation:
+					relation = true
+				case mathWordScripted:
+					scripted = true
+				default:
+					formulaic++
+				}
+			}
+			if strong || relation || scripted {
+				var run []int
+				for _, w := range words[i:j] {
+					run = append(run, w.marks...)
+				}
+				m.candidates = append(m.candidates, run)
+				m.weak = append(m.weak, !strong && !(relation && (scripted || formulaic >= 2)) && !(scripted && formulaic >= 2))
+			}
+			i = j
+		}
+	}
+}
+
+// words returns the words of the marks `idx` of a line with their kinds.
+func (m *mathDetector) words(idx []int) []*mathWord {
+	var words []*mathWord
+	var word *mathWord
+	for _, i := range idx {
+		mark := m.marks[i]
+		if mark.Meta || strings.TrimSpace(mark.Text) == "" {
+			word = nil
+			continue
+		}
+		if word != nil {
+			last := m.marks[word.marks[len(word.marks)-1]]
+			if !marksAdjacent(last, mark) && !scriptAdjacent(last, mark) {
+				word = nil
+			}
+		}
+		if word == nil {
+			word = &mathWord{}
+			words = append(words, word)
+		}
+		word.marks = append(word.marks, i)
+		word.text += mark.Text
+	}
+	for _, w := range words {
+		w.kind = m.wordKind(w)
+	}
+	return words
+}
+
+var (
+	_ddMathFunctions = regexp.MustCompile(`^(sin|cos|tan|cot|sec|csc|sinh|cosh|tanh|arcsin|arccos|arctan|log|ln|lg|exp|lim|sup|inf|max|min|det|dim|ker|deg|gcd|arg|mod)$`)
+	_ddLetterRuns    = regexp.MustCompile(`\pL+`)
+	_ddEquationNum   = regexp.MustCompile(`^\(\d+(\.\d+)*[a-z]?\)[.,]?$`)
+)
+
+func (m *mathDetector) wordKind(w *mathWord) int {
+	scripted := false
+	for _, i := range w.marks {
+		mark := m.marks[i]
+		if m.frac[i] >= 0 || isMathFont(mark.Font) {
+			return mathWordStrong
+		}
+		for _, r := range mark.Text {
+			if isMathRune(r) {
+				return mathWordStrong
+			}
+		}
+		if m.styles[i].Superscript || m.styles[i].Subscript {
+			scripted = true
+		}
+	}
+	text := strings.TrimRight(w.text, ".,;:")
+	if text == "" {
+		return mathWordText
+	}
+	if strings.ContainsAny(text, "=<>") {
+		return mathWordRelation
+	}
+	if scripted && len(w.marks) > 1 {
+		return mathWordScripted
+	}
+	for _, letters := range _ddLetterRuns.FindAllString(text, -1) {
+		if len([]rune(letters)) > 1 && !_ddMathFunctions.MatchString(letters) {
+			return mathWordText
+		}
+	}
+	if strings.ContainsAny(text, "+-*/^()[]{}|0123456789") || len([]rune(text)) == 1 && unicode.IsLetter([]rune(text)[0]) {
+		return mathWordFormulaic
+	}
+	return mathWordText
+}
+
+// regions merges overlapping and adjacent candidates into regions.
+func (m *mathDetector) regions() []MathRegion {
+	n := len(m.candidates)
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	owner := map[int]int{}
+	bboxes := make([]model.PdfRectangle, n)
+	for c, idx := range m.candidates {
+		bboxes[c] = m.bbox(idx)
+		for _, i := range idx {
+			if o, ok := owner[i]; ok {
+				parent[find(c)] = find(o)
+			} else {
+				owner[i] = c
+			}
+		}
+	}
+	for _, f := range m.fracs {
+		c := owner[f.num[0]]
+		bboxes[c] = unionDocumentBBox(bboxes[c], model.PdfRectangle{Llx: f.rule.llx, Urx: f.rule.urx, Lly: f.rule.y, Ury: f.rule.y})
+	}
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			ra, rb := bboxes[a], bboxes[b]
+			gap := 0.5 * math.Max(ra.Height(), rb.Height())
+			if ra.Lly < rb.Ury && rb.Lly < ra.Ury && ra.Llx < rb.Urx+gap && rb.Llx < ra.Urx+gap {
+				parent[find(a)] = find(b)
+			}
+		}
+	}
+	groups := map[int][]int{}
+	var roots []int
+	for c := range m.candidates {
+		r := find(c)
+		if _, ok := groups[r]; !ok {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], c)
+	}
+	strong := roots[:0]
+	for _, r := range roots {
+		for _, c := range groups[r] {
+			if !m.weak[c] {
+				strong = append(strong, r)
+				break
+			}
+		}
+	}
+	roots = strong
+
+	var regions []MathRegion
+	for _, r := range roots {
+		inRegion := map[int]bool{}
+		var idx []int
+		var fracs []*mathFraction
+		seen := map[int]bool{}
+		bbox := bboxes[groups[r][0]]
+		for _, c := range groups[r] {
+			bbox = unionDocumentBBox(bbox, bboxes[c])
+			for _, i := range m.candidates[c] {
+				if !inRegion[i] {
+					inRegion[i] = true
+					idx = append(idx, i)
+				}
+				if f := m.frac[i]; f >= 0 && !seen[f] {
+					seen[f] = true
+					fracs = append(fracs, m.fracs[f])
+				}
+			}
+		}
+		sort.Ints(idx)
+		region := MathRegion{BBox: bbox, Display: m.display(idx, inRegion)}
+		for k, i := range idx {
+			if k > 0 && (idx[k-1] != i-1 || m.line[i] != m.line[idx[k-1]]) {
+				region.Text += " "
+			}
+			region.Text += m.marks[i].Text
+			region.Marks._edfaa = append(region.Marks._edfaa, m.marks[i])
+		}
+		rows := m.items(idx, fracs)
+		var latex, mathml []string
+		for _, row := range rows {
+			latex = append(latex, latexItems(row))
+			mathml = append(mathml, "<mrow>"+mathMLItems(row)+"</mrow>")
+		}
+		region.LaTeX = strings.Join(latex, ` \\ `)
+		display := "inline"
+		if region.Display {
+			display = "block"
+		}
+		body := strings.Join(mathml, "")
+		if len(mathml) > 1 {
+			body = "<mtable><mtr><mtd>" + strings.Join(mathml, "</mtd></mtr><mtr><mtd>") + "</mtd></mtr></mtable>"
+		}
+		region.MathML = `<math xmlns="http://www.w3.org/1998/Math/MathML" display="` + display + `">` + body + `</math>`
+		regions = append(regions, region)
+	}
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].Marks._edfaa[0].Offset < regions[j].Marks._edfaa[0].Offset
+	})
+	return regions
+}
+
+// display returns true if the lines of the marks `idx` hold no other text than equation numbers.
+func (m *mathDetector) display(idx []int, inRegion map[int]bool) bool {
+	lines := map[int]bool{}
+	for _, i := range idx {
+		lines[m.line[i]] = true
+	}
+	for l := range lines {
+		for _, w := range m.words(m.lineMarks[l]) {
+			if _ddEquationNum.MatchString(w.text) || strings.Trim(w.text, ".,;:") == "" {
+				continue
+			}
+			for _, i := range w.marks {
+				if !inRegion[i] {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// mathItem is a glyph or a fraction of a formula.
+type mathItem struct {
+	x      float64
+	text   string
+	script int
+	frac   *mathFractionItems
+}
+
+type mathFractionItems struct {
+	num, den []mathItem
+}
+
+// items returns the items of the rows of a region: its lines without the numerators and denominators of
+// fractions, which are placed in the rows closest to their rules.
+func (m *mathDetector) items(idx []int, fracs []*mathFraction) [][]mathItem {
+	toItems := func(idx []int) []mathItem {
+		var items []mathItem
+		for _, i := range idx {
+			mark := m.marks[i]
+			if mark.Meta || strings.TrimSpace(mark.Text) == "" {
+				continue
+			}
+			item := mathItem{x: mark.BBox.Llx, text: mark.Text}
+			if m.styles[i].Superscript {
+				item.script = 1
+			} else if m.styles[i].Subscript {
+				item.script = -1
+			}
+			items = append(items, item)
+		}
+		sort.SliceStable(items, func(a, b int) bool { return items[a].x < items[b].x })
+		return items
+	}
+
+	var lines []int
+	byLine := map[int][]int{}
+	baselines := map[int]float64{}
+	for _, i := range idx {
+		if m.frac[i] >= 0 {
+			continue
+		}
+		l := m.line[i]
+		if _, ok := byLine[l]; !ok {
+			lines = append(lines, l)
+		}
+		byLine[l] = append(byLine[l], i)
+	}
+	for _, l := range lines {
+		marks := make([]TextMark, len(byLine[l]))
+		for k, i := range byLine[l] {
+			marks[k] = m.marks[i]
+		}
+		_, baselines[l] = lineBaseline(marks)
+	}
+	rows := make([][]mathItem, len(lines))
+	for k, l := range lines {
+		rows[k] = toItems(byLine[l])
+	}
+	for _, f := range fracs {
+		item := mathItem{x: f.rule.llx, frac: &mathFractionItems{num: toItems(f.num), den: toItems(f.den)}}
+		best := -1
+		for k, l := range lines {
+			if best < 0 || math.Abs(baselines[l]-f.rule.y) < math.Abs(baselines[lines[best]]-f.rule.y) {
+				best = k
+			}
+		}
+		if best < 0 {
+			rows = append(rows, []mathItem{item})
+			continue
+		}
+		rows[best] = append(rows[best], item)
+		sort.SliceStable(rows[best], func(a, b int) bool { return rows[best][a].x < rows[best][b].x })
+	}
+	return rows
+}
+
+// latexItems returns the LaTeX of `items`.
+func latexItems(items []mathItem) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(items); {
+		item := items[i]
+		if item.frac != nil {
+			buf.WriteString(`\frac{` + latexItems(item.frac.num) + `}{` + latexItems(item.frac.den) + `}`)
+			i++
+			continue
+		}
+		j := i
+		var text strings.Builder
+		for ; j < len(items) && items[j].frac == nil && items[j].script == item.script; j++ {
+			text.WriteString(items[j].text)
+		}
+		latex := latexText(text.String())
+		switch item.script {
+		case 1:
+			buf.WriteString("^{" + latex + "}")
+		case -1:
+			buf.WriteString("_{" + latex + "}")
+		default:
+			buf.WriteString(latex)
+		}
+		i = j
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// latexText returns the LaTeX of a run of text on a single baseline.
+func latexText(text string) string {
+	text = _ddLetterRuns.ReplaceAllStringFunc(text, func(letters string) string {
+		if _ddMathFunctions.MatchString(letters) {
+			return `\` + letters + " "
+		}
+		return letters
+	})
+	var buf bytes.Buffer
+	for _, r := range text {
+		if s, ok := _ddLaTeXSymbols[r]; ok {
+			buf.WriteString(s)
+			if r := []rune(s); unicode.IsLetter(r[len(r)-1]) {
+				buf.WriteByte(' ')
+			}
+			continue
+		}
+		switch r {
+		case '{', '}', '%', '#', '&', '_':
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// mathMLItems returns the MathML of `items`.
+func mathMLItems(items []mathItem) string {
+	var elems []string
+	for i := 0; i < len(items); {
+		item := items[i]
+		if item.frac != nil {
+			elems = append(elems, "<mfrac><mrow>"+mathMLItems(item.frac.num)+"</mrow><mrow>"+mathMLItems(item.frac.den)+"</mrow></mfrac>")
+			i++
+			continue
+		}
+		j := i
+		var text strings.Builder
+		for ; j < len(items) && items[j].frac == nil && items[j].script == item.script; j++ {
+			text.WriteString(items[j].text)
+		}
+		tokens := mathMLTokens(text.String())
+		if item.script == 0 || len(elems) == 0 {
+			elems = append(elems, tokens...)
+		} else {
+			tag := "msup"
+			if item.script < 0 {
+				tag = "msub"
+			}
+			base := elems[len(elems)-1]
+			elems[len(elems)-1] = "<" + tag + ">" + base + "<mrow>" + strings.Join(tokens, "") + "</mrow></" + tag + ">"
+		}
+		i = j
+	}
+	return strings.Join(elems, "")
+}
+
+// mathMLTokens returns the MathML tokens of a run of text: numbers, identifiers and operators.
+func mathMLTokens(text string) []string {
+	var tokens []string
+	runes := []rune(strings.TrimSpace(text))
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		j := i + 1
+		var tag string
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case unicode.IsDigit(r) || r == '.' && j < len(runes) && unicode.IsDigit(runes[j]):
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tag = "mn"
+		case unicode.IsLetter(r):
+			for j < len(runes) && unicode.IsLetter(runes[j]) {
+				j++
+			}
+			if !_ddMathFunctions.MatchString(string(runes[i:j])) {
+				j = i + 1
+			}
+			tag = "mi"
+		default:
+			tag = "mo"
+		}
+		var buf bytes.Buffer
+		for _, c := range runes[i:j] {
+			switch c {
+			case '<':
+				buf.WriteString("&lt;")
+			case '>':
+				buf.WriteString("&gt;")
+			case '&':
+				buf.WriteString("&amp;")
+			default:
+				buf.WriteRune(c)
+			}
+		}
+		tokens = append(tokens, "<"+tag+">"+buf.String()+"</"+tag+">")
+		i = j
+	}
+	return tokens
+}
+
+// _ddMathFonts are substrings of the names of math fonts, in lower case without spaces.
+var _ddMathFonts = []string{
+	"cmmi", "cmsy", "cmex", "cmbsy", "msam", "msbm", "eufm", "eusm", "rsfs", "esint", "wasy",
+	"stix", "xitsmath", "cambriamath", "latinmodernmath", "lmmath", "asanamath", "mathjax",
+	"symbol", "mtextra", "mathematicalpi", "euclid", "txsy", "txex", "pxsy", "pxex", "ntxmi", "ntxsy",
+	"newtxmath", "mathdesign",
+}
+
+// isMathFont returns true if `font` is a math font.
+func isMathFont(font *model.PdfFont) bool {
+	if font == nil {
+		return false
+	}
+	name := strings.ToLower(strings.ReplaceAll(font.BaseFont(), " ", ""))
+	for _, m := range _ddMathFonts {
+		if strings.Contains(name, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMathRune returns true if `r` is a mathematical character: a Greek letter, an operator, arrow or
+// mathematical alphanumeric symbol.
+func isMathRune(r rune) bool {
+	switch {
+	case r >= 0x0391 && r <= 0x03C9, r == 0x03D1 || r == 0x03D5 || r == 0x03D6,
+		r >= 0x2190 && r <= 0x21FF, r >= 0x2200 && r <= 0x22FF, r >= 0x2308 && r <= 0x230B,
+		r >= 0x27C0 && r <= 0x27EF, r >= 0x2980 && r <= 0x2AFF, r >= 0x1D400 && r <= 0x1D7FF,
+		r == 0x2032 || r == 0x2033 || r == 0x2102 || r == 0x2115 || r == 0x211A || r == 0x211D || r == 0x2124,
+		r == 0x00B1 || r == 0x00D7 || r == 0x00F7 || r == 0x00AC:
+		return true
+	}
+	return false
+}
+
+// _ddLaTeXSymbols are the LaTeX commands of mathematical characters.
+var _ddLaTeXSymbols = map[rune]string{
+	'α': `\alpha`, 'β': `\beta`, 'γ': `\gamma`, 'δ': `\delta`, 'ε': `\epsilon`, 'ζ': `\zeta`, 'η': `\eta`,
+	'θ': `\theta`, 'ι': `\iota`, 'κ': `\kappa`, 'λ': `\lambda`, 'μ': `\mu`, 'ν': `\nu`, 'ξ': `\xi`,
+	'π': `\pi`, 'ρ': `\rho`, 'σ': `\sigma`, 'ς': `\varsigma`, 'τ': `\tau`, 'υ': `\upsilon`, 'φ': `\varphi`,
+	'χ': `\chi`, 'ψ': `\psi`, 'ω': `\omega`, 'ϑ': `\vartheta`, 'ϕ': `\phi`, 'ϖ': `\varpi`,
+	'Γ': `\Gamma`, 'Δ': `\Delta`, 'Θ': `\Theta`, 'Λ': `\Lambda`, 'Ξ': `\Xi`, 'Π': `\Pi`, 'Σ': `\Sigma`,
+	'Υ': `\Upsilon`, 'Φ': `\Phi`, 'Ψ': `\Psi`, 'Ω': `\Omega`,
+	'±': `\pm`, '∓': `\mp`, '×': `\times`, '÷': `\div`, '·': `\cdot`, '⋅': `\cdot`, '∗': `\ast`, '∘': `\circ`,
+	'−': `-`, '≤': `\leq`, '≥': `\geq`, '≠': `\neq`, '≈': `\approx`, '≡': `\equiv`, '∼': `\sim`, '≃': `\simeq`,
+	'∝': `\propto`, '≪': `\ll`, '≫': `\gg`, '∈': `\in`, '∉': `\notin`, '∋': `\ni`, '⊂': `\subset`,
+	'⊃': `\supset`, '⊆': `\subseteq`, '⊇': `\supseteq`, '∪': `\cup`, '∩': `\cap`, '∅': `\emptyset`,
+	'∀': `\forall`, '∃': `\exists`, '¬': `\neg`, '∧': `\wedge`, '∨': `\vee`, '⊕': `\oplus`, '⊗': `\otimes`,
+	'∑': `\sum`, '∏': `\prod`, '∫': `\int`, '∬': `\iint`, '∮': `\oint`, '∂': `\partial`, '∇': `\nabla`,
+	'√': `\sqrt`, '∞': `\infty`, '→': `\to`, '←': `\leftarrow`, '↔': `\leftrightarrow`, '⇒': `\Rightarrow`,
+	'⇐': `\Leftarrow`, '⇔': `\Leftrightarrow`, '↦': `\mapsto`, '′': `'`, '″': `''`, '…': `\ldots`,
+	'⋯': `\cdots`, 'ℝ': `\mathbb{R}`, 'ℕ': `\mathbb{N}`, 'ℤ': `\mathbb{Z}`, 'ℚ': `\mathbb{Q}`,
+	'ℂ': `\mathbb{C}`, 'ℓ': `\ell`, '⟨': `\langle`, '⟩': `\rangle`, '⌈': `\lceil`, '⌉': `\rceil`,
+	'⌊': `\lfloor`, '⌋': `\rfloor`, '∣': `\mid`, '∥': `\parallel`, '⊥': `\perp`, '∠': `\angle`,
+}
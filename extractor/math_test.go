@@ -0,0 +1,80 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/creator"
+)
+
+// drawInlineFormula draws a paragraph holding the formula E = mc².
+func drawInlineFormula(c *creator.Creator) {
+	p := c.NewStyledParagraph()
+	p.Append("The energy of a body at rest is E = mc")
+	sup := p.Append("2")
+	sup.Style.FontSize = 6
+	sup.Style.TextRise = 5
+	p.Append(" where c is the speed of light.")
+	c.Draw(p)
+}
+
+// drawDisplayedFraction draws the fraction (a + b) / 2 on a line of its own,
+// with the numerator and the denominator centered above and below a
+// fraction rule.
+func drawDisplayedFraction(c *creator.Creator) {
+	c.Draw(c.NewParagraph("The mean of the values is"))
+	text := func(s string, x, y float64) {
+		p := c.NewParagraph(s)
+		p.SetPos(x, y)
+		c.Draw(p)
+	}
+	text("a + b", 130, 142)
+	line := c.NewLine(128, 155, 158, 155)
+	line.SetLineWidth(0.5)
+	c.Draw(line)
+	text("2", 140, 158)
+	p := c.NewParagraph("which is a fraction.")
+	p.SetPos(72, 200)
+	c.Draw(p)
+}
+
+func TestMathRegions(t *testing.T) {
+	pts := creatorPageTexts(t, drawInlineFormula, drawDisplayedFraction)
+
+	regions := pts[0].MathRegions()
+	if len(regions) != 1 {
+		t.Fatalf("expected 1 inline region, got %d", len(regions))
+	}
+	inline := regions[0]
+	if inline.Display || inline.Text != "E = mc2" || inline.LaTeX != "E=mc^{2}" {
+		t.Fatalf("unexpected inline region %q, display %v, LaTeX %q", inline.Text, inline.Display, inline.LaTeX)
+	}
+	if !strings.Contains(inline.MathML, "<msup><mi>c</mi><mrow><mn>2</mn></mrow></msup>") {
+		t.Fatalf("unexpected MathML %q", inline.MathML)
+	}
+	expected := "The energy of a body at rest is $E=mc^{2}$ where c is the speed of light."
+	if text := pts[0].TextWithMath(nil); !strings.Contains(text, expected) {
+		t.Fatalf("expected %q, got %q", expected, text)
+	}
+
+	regions = pts[1].MathRegions()
+	if len(regions) != 1 {
+		t.Fatalf("expected 1 displayed region, got %d", len(regions))
+	}
+	display := regions[0]
+	if !display.Display || display.LaTeX != `\frac{a+b}{2}` || !strings.Contains(display.MathML, "<mfrac>") {
+		t.Fatalf("unexpected displayed region %q, display %v, LaTeX %q", display.Text, display.Display, display.LaTeX)
+	}
+	if text := pts[1].TextWithMath(nil); !strings.Contains(text, "\n$$\\frac{a+b}{2}$$\n") {
+		t.Fatalf("displayed equation not fenced: %q", text)
+	}
+}
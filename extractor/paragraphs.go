@@ -0,0 +1,79 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"bytes"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// TextParagraph is a paragraph of the text of a page, as laid out by the
+// extractor. A table is returned as a single paragraph.
+type TextParagraph struct {
+	// BBox is the bounding box of the paragraph.
+	BBox model.PdfRectangle
+
+	// Text is the extracted text of the paragraph.
+	Text string
+
+	// Marks are the TextMarks of the paragraph, including the spaces and line
+	// breaks inserted by the extractor. The offsets of the marks are offsets
+	// in the text of the page.
+	Marks TextMarkArray
+
+	// Table is the table of the paragraph, if the paragraph is a table.
+	Table *TextTable
+}
+
+// Paragraphs returns the paragraphs of the page text in reading order. The
+// paragraphs are not available in ExtractionModePlain.
+func (pt PageText) Paragraphs() []TextParagraph {
+	if pt._bedea._cbdc == ExtractionModePlain {
+		return nil
+	}
+	paras := pt.getParagraphs()
+	ctx := &textContext{_bgcc: pt._bedea._eacd}
+	offset := 0
+	var result []TextParagraph
+	for i, para := range paras {
+		if para._geba {
+			continue
+		}
+		var buf bytes.Buffer
+		para.writeText(&buf, ctx)
+		p := TextParagraph{
+			BBox:  para.PdfRectangle,
+			Text:  buf.String(),
+			Marks: TextMarkArray{_edfaa: para.toTextMarks(&offset, ctx)},
+		}
+		pt.setStructElements(p.Marks._edfaa)
+		if para._bgdd != nil && para._bgdd.isExportable() {
+			table := para._bgdd.toTextTable(ctx)
+			for y := range table.Cells {
+				for x := range table.Cells[y] {
+					pt.setStructElements(table.Cells[y][x].Marks._edfaa)
+				}
+			}
+			p.Table = &table
+		}
+		result = append(result, p)
+
+		// Account for the separators between paragraphs in the page text.
+		if i != len(paras)-1 {
+			if _baccb(para, paras[i+1]) {
+				offset += len(" ")
+			} else {
+				offset += len("\n\n")
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,417 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/unidoc/unipdf/v4/internal/transform"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// ShapeKind is the kind of a recognized shape.
+type ShapeKind int
+
+// Kinds of recognized shapes.
+const (
+	ShapeRectangle ShapeKind = iota
+	ShapeCircle
+	ShapeEllipse
+	ShapeLine
+	ShapeArrow
+	ShapeCheckbox
+)
+
+// String returns the name of the shape kind.
+func (k ShapeKind) String() string {
+	switch k {
+	case ShapeRectangle:
+		return "rectangle"
+	case ShapeCircle:
+		return "circle"
+	case ShapeEllipse:
+		return "ellipse"
+	case ShapeLine:
+		return "line"
+	case ShapeArrow:
+		return "arrow"
+	case ShapeCheckbox:
+		return "checkbox"
+	}
+	return "unknown"
+}
+
+// Shape is a primitive recognized in the paths of a page.
+type Shape struct {
+	Kind ShapeKind
+	BBox model.PdfRectangle
+
+	// Points are the corners of rectangles and checkboxes, and the start and end points of lines and
+	// arrows. The end point of an arrow is the tip of its head.
+	Points []transform.Point
+
+	// Center, RadiusX and RadiusY are the center and radii of circles and ellipses.
+	Center           transform.Point
+	RadiusX, RadiusY float64
+
+	// Heads is the number of heads of an arrow: 1, or 2 for an arrow with a head at both ends.
+	Heads int
+
+	// Checked is true for checkboxes containing a check mark.
+	Checked bool
+
+	// Paths are the paths the shape was recognized in.
+	Paths []*VectorPath
+}
+
+// shapePart is a subpath of a path that is a candidate shape or a part of a shape.
+type shapePart struct {
+	shape    Shape
+	triangle bool
+	vertices []transform.Point
+	used     bool
+}
+
+// recognizeShapes returns the shapes recognized in the subpaths of `paths` with relative tolerance
+// `tolerance`. Arrow heads and check marks are merged with the lines and boxes they belong to.
+func recognizeShapes(paths []*VectorPath, tolerance float64) []Shape {
+	var parts []*shapePart
+	for i, p := range paths {
+		for _, sp := range p.Subpaths {
+			if part := recognizeSubpath(sp, tolerance); part != nil {
+				if part.shape.Kind == ShapeCheckbox && !isBoxOutline(p, paths[:i]) {
+					// Filled squares, such as chart legend swatches, are not checkboxes.
+					part.shape.Kind = ShapeRectangle
+				}
+				part.shape.Paths = []*VectorPath{p}
+				parts = append(parts, part)
+			}
+		}
+	}
+	for _, part := range parts {
+		if part.shape.Kind == ShapeLine && !part.triangle {
+			arrowHeads(part, parts, tolerance)
+		}
+	}
+	for _, part := range parts {
+		if part.shape.Kind == ShapeCheckbox && !part.used {
+			checkMark(part, parts, tolerance)
+		}
+	}
+	var shapes []Shape
+	for _, part := range parts {
+		if !part.used && !part.triangle && !(part.shape.Kind == ShapeLine && len(part.vertices) == 3) {
+			shapes = append(shapes, part.shape)
+		}
+	}
+	return shapes
+}
+
+// recognizeSubpath returns the shape of `sp`: a rectangle, circle, ellipse, line, checkbox, or a triangle
+// or open polyline of three points that may be an arrow head. It returns nil for other subpaths.
+func recognizeSubpath(sp VectorSubpath, tolerance float64) *shapePart {
+	if len(sp.Segments) == 0 {
+		return nil
+	}
+	bbox := sp.BBox()
+	size := math.Max(bbox.Width(), bbox.Height())
+	if size <= 0 {
+		return nil
+	}
+	eps := math.Max(0.5, tolerance*size)
+	vertices := sp.Vertices()
+	closed := sp.Closed || sp.Segments[0].Start().Distance(sp.Segments[len(sp.Segments)-1].End()) <= eps
+	curves := 0
+	for _, s := range sp.Segments {
+		if s.IsCurve() {
+			curves++
+		}
+	}
+	if curves == len(sp.Segments) && curves >= 2 && closed {
+		return recognizeEllipse(sp, bbox, tolerance)
+	}
+	if curves > 0 {
+		return nil
+	}
+	if !closed {
+		if len(vertices) >= 2 && collinear(vertices, eps) {
+			start, end := vertices[0], vertices[len(vertices)-1]
+			return &shapePart{shape: Shape{Kind: ShapeLine, BBox: bbox, Points: []transform.Point{start, end}},
+				vertices: []transform.Point{start, end}}
+		}
+		if len(vertices) == 3 {
+			// An open arrow head: two strokes meeting at the tip.
+			return &shapePart{shape: Shape{Kind: ShapeLine, BBox: bbox, Points: vertices}, vertices: vertices}
+		}
+		return nil
+	}
+	if n := len(vertices); n > 3 && vertices[0].Distance(vertices[n-1]) <= eps {
+		vertices = vertices[:n-1]
+	}
+	switch len(vertices) {
+	case 3:
+		return &shapePart{shape: Shape{BBox: bbox, Points: vertices}, triangle: true, vertices: vertices}
+	case 4:
+		if !isRectangle(vertices, tolerance) {
+			return nil
+		}
+		w := vertices[0].Distance(vertices[1])
+		h := vertices[1].Distance(vertices[2])
+		short, long := math.Min(w, h), math.Max(w, h)
+		if short <= 2 && long > 5*short {
+			// A thin filled rectangle is drawn as a line along its center.
+			var start, end transform.Point
+			if w >= h {
+				start, end = midpoint(vertices[0], vertices[3]), midpoint(vertices[1], vertices[2])
+			} else {
+				start, end = midpoint(vertices[0], vertices[1]), midpoint(vertices[3], vertices[2])
+			}
+			return &shapePart{shape: Shape{Kind: ShapeLine, BBox: bbox, Points: []transform.Point{start, end}},
+				vertices: []transform.Point{start, end}}
+		}
+		kind := ShapeRectangle
+		if short >= 5 && long <= 24 && long <= short*(1+4*tolerance) {
+			kind = ShapeCheckbox
+		}
+		return &shapePart{shape: Shape{Kind: kind, BBox: bbox, Points: vertices}, vertices: vertices}
+	}
+	return nil
+}
+
+// recognizeEllipse returns the circle or axis aligned ellipse of the closed curves `sp` with bounding box
+// `bbox`, or nil if the curves are not on an ellipse.
+func recognizeEllipse(sp VectorSubpath, bbox model.PdfRectangle, tolerance float64) *shapePart {
+	var points []transform.Point
+	for _, s := range sp.Segments {
+		for _, t := range []float64{0, 0.25, 0.5, 0.75} {
+			points = append(points, bezierPoint(s.Points, t))
+		}
+	}
+	box := pointsBBox(points)
+	rx, ry := box.Width()/2, box.Height()/2
+	if rx <= 0 || ry <= 0 {
+		return nil
+	}
+	center := transform.Point{X: box.Llx + rx, Y: box.Lly + ry}
+	for _, p := range points {
+		dx, dy := (p.X-center.X)/rx, (p.Y-center.Y)/ry
+		if math.Abs(math.Hypot(dx, dy)-1) > 2*tolerance {
+			return nil
+		}
+	}
+	kind := ShapeEllipse
+	if math.Abs(rx-ry) <= tolerance*math.Max(rx, ry) {
+		kind = ShapeCircle
+	}
+	return &shapePart{shape: Shape{Kind: kind, BBox: box, Center: center, RadiusX: rx, RadiusY: ry}}
+}
+
+// arrowHeads merges the arrow heads at the ends of the line `line` in `parts` with it.
+func arrowHeads(line *shapePart, parts []*shapePart, tolerance float64) {
+	if line.used || len(line.vertices) != 2 {
+		return
+	}
+	ends := [2]transform.Point{line.vertices[0], line.vertices[1]}
+	headed := [2]bool{}
+	length := ends[0].Distance(ends[1])
+	if length == 0 {
+		return
+	}
+	for i := range ends {
+		tip, other := ends[i], ends[1-i]
+		dir := transform.Point{X: (tip.X - other.X) / length, Y: (tip.Y - other.Y) / length}
+		for _, head := range parts {
+			if head == line || head.used || len(head.vertices) != 3 {
+				continue
+			}
+			if !head.triangle && head.shape.Kind != ShapeLine {
+				continue
+			}
+			if newTip, ok := arrowHead(head, tip, dir, length, tolerance); ok {
+				head.used = true
+				ends[i] = newTip
+				headed[i] = true
+				line.shape.Heads++
+				line.shape.Paths = append(line.shape.Paths, head.shape.Paths...)
+				line.shape.BBox = unionRect(line.shape.BBox, head.shape.BBox)
+				break
+			}
+		}
+	}
+	if line.shape.Heads == 0 {
+		return
+	}
+	line.shape.Kind = ShapeArrow
+	if headed[0] && !headed[1] {
+		ends[0], ends[1] = ends[1], ends[0]
+	}
+	line.shape.Points = []transform.Point{ends[0], ends[1]}
+}
+
+// arrowHead returns the tip of the arrow head `head` at the end `end` of a line of length `length` with
+// direction `dir` towards the end, and whether `head` is such an arrow head.
+func arrowHead(head *shapePart, end, dir transform.Point, length, tolerance float64) (transform.Point, bool) {
+	size := math.Max(head.shape.BBox.Width(), head.shape.BBox.Height())
+	if size <= 0 || size > 0.5*length {
+		return transform.Point{}, false
+	}
+	eps := math.Max(0.5, 2*tolerance*size)
+	along := func(p transform.Point) float64 { return (p.X-end.X)*dir.X + (p.Y-end.Y)*dir.Y }
+	across := func(p transform.Point) float64 { return (p.X-end.X)*dir.Y - (p.Y-end.Y)*dir.X }
+	tip := head.vertices[0]
+	for _, v := range head.vertices[1:] {
+		if along(v) > along(tip) {
+			tip = v
+		}
+	}
+	if math.Abs(across(tip)) > eps || along(tip) < -eps || along(tip) > size+eps {
+		return transform.Point{}, false
+	}
+	var wings []transform.Point
+	for _, v := range head.vertices {
+		if v != tip {
+			wings = append(wings, v)
+		}
+	}
+	if len(wings) != 2 || !head.triangle && head.vertices[1] != tip {
+		return transform.Point{}, false
+	}
+	// The wings are behind the tip, on both sides of the line and symmetric.
+	a0, a1 := along(wings[0])-along(tip), along(wings[1])-along(tip)
+	c0, c1 := across(wings[0]), across(wings[1])
+	if a0 >= -eps || a1 >= -eps || c0*c1 >= 0 || math.Abs(c0+c1) > eps || math.Abs(a0-a1) > eps {
+		return transform.Point{}, false
+	}
+	return tip, true
+}
+
+// checkMark marks the checkbox `box` as checked if `parts` have a mark inside it, and merges the mark
+// with it. Other boxes coinciding with `box`, such as its fill painted by a separate path, are merged with
+// it too.
+func checkMark(box *shapePart, parts []*shapePart, tolerance float64) {
+	b := box.shape.BBox
+	eps := math.Max(0.5, tolerance*b.Width())
+	for _, part := range parts {
+		if part == box || part.used {
+			continue
+		}
+		r := part.shape.BBox
+		if !insideRect(r, b, eps) {
+			continue
+		}
+		part.used = true
+		box.shape.Paths = append(box.shape.Paths, part.shape.Paths...)
+		isBox := part.shape.Kind == ShapeCheckbox || part.shape.Kind == ShapeRectangle
+		if !isBox || r.Width() < b.Width()-eps || r.Height() < b.Height()-eps {
+			box.shape.Checked = true
+		}
+	}
+	if box.shape.Checked {
+		return
+	}
+	// A box and its check mark may be subpaths of the same path, or the check mark may not be a shape.
+	for _, p := range box.shape.Paths {
+		for _, sp := range p.Subpaths {
+			r := sp.BBox()
+			if insideRect(r, b, eps) && (r.Width() < b.Width()-eps || r.Height() < b.Height()-eps) {
+				box.shape.Checked = true
+			}
+		}
+	}
+}
+
+// isBoxOutline returns true if the path `p` is painted as the outline of a box: stroked, and either not
+// filled or filled with the color of the background. The background is the fill of the last of the paths
+// `below` that encloses `p`, or white if there is none.
+func isBoxOutline(p *VectorPath, below []*VectorPath) bool {
+	if !p.Stroked {
+		return false
+	}
+	if !p.Filled || p.FillColor == nil {
+		return true
+	}
+	var background color.Color = color.White
+	for i := len(below) - 1; i >= 0; i-- {
+		q := below[i]
+		if q.Filled && q.FillColor != nil && insideRect(p.BBox, q.BBox, 0) &&
+			(q.BBox.Width() > p.BBox.Width()+1 || q.BBox.Height() > p.BBox.Height()+1) {
+			background = q.FillColor
+			break
+		}
+	}
+	return similarColors(p.FillColor, background)
+}
+
+// similarColors returns true if the 8 bit components of the colors `a` and `b` differ by at most 8.
+func similarColors(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	near := func(x, y uint32) bool {
+		x, y = x>>8, y>>8
+		return x <= y+8 && y <= x+8
+	}
+	return near(r1, r2) && near(g1, g2) && near(b1, b2) && near(a1, a2)
+}
+
+// isRectangle returns true if the quadrilateral `v` has right angles.
+func isRectangle(v []transform.Point, tolerance float64) bool {
+	for i := range v {
+		a, b, c := v[i], v[(i+1)%4], v[(i+2)%4]
+		ux, uy := b.X-a.X, b.Y-a.Y
+		wx, wy := c.X-b.X, c.Y-b.Y
+		lu, lw := math.Hypot(ux, uy), math.Hypot(wx, wy)
+		if lu == 0 || lw == 0 || math.Abs(ux*wx+uy*wy)/(lu*lw) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// collinear returns true if `points` are within `eps` of the line through the first and last of them.
+func collinear(points []transform.Point, eps float64) bool {
+	a, b := points[0], points[len(points)-1]
+	length := a.Distance(b)
+	if length == 0 {
+		return false
+	}
+	for _, p := range points[1 : len(points)-1] {
+		if math.Abs((p.X-a.X)*(b.Y-a.Y)-(p.Y-a.Y)*(b.X-a.X))/length > eps {
+			return false
+		}
+	}
+	return true
+}
+
+// bezierPoint returns the point at `t` on the cubic Bézier curve with control points `p`.
+func bezierPoint(p []transform.Point, t float64) transform.Point {
+	u := 1 - t
+	a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+	return transform.Point{
+		X: a*p[0].X + b*p[1].X + c*p[2].X + d*p[3].X,
+		Y: a*p[0].Y + b*p[1].Y + c*p[2].Y + d*p[3].Y,
+	}
+}
+
+// insideRect returns true if `r` is inside `b` with tolerance `eps`.
+func insideRect(r, b model.PdfRectangle, eps float64) bool {
+	return r.Llx >= b.Llx-eps && r.Urx <= b.Urx+eps && r.Lly >= b.Lly-eps && r.Ury <= b.Ury+eps
+}
+
+func midpoint(a, b transform.Point) transform.Point {
+	return transform.Point{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+}
+
+func unionRect(a, b model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(a.Llx, b.Llx), Lly: math.Min(a.Lly, b.Lly),
+		Urx: math.Max(a.Urx, b.Urx), Ury: math.Max(a.Ury, b.Ury),
+	}
+}
@@ -0,0 +1,72 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/creator"
+)
+
+// TestCheckboxShapes checks that only small squares painted as outlines are
+// recognized as checkboxes.
+func TestCheckboxShapes(t *testing.T) {
+	ex := creatorExtractors(t, func(c *creator.Creator) {
+		// An outlined box.
+		box := c.NewRectangle(100, 100, 10, 10)
+		box.SetBorderWidth(1)
+		box.SetBorderColor(creator.ColorBlack)
+		c.Draw(box)
+
+		// An outlined box filled with the background color of a panel.
+		panel := c.NewRectangle(200, 80, 100, 50)
+		panel.SetBorderWidth(0)
+		panel.SetFillColor(creator.ColorRGBFromHex("#eeeeee"))
+		c.Draw(panel)
+		box = c.NewRectangle(220, 100, 10, 10)
+		box.SetBorderWidth(1)
+		box.SetBorderColor(creator.ColorBlack)
+		box.SetFillColor(creator.ColorRGBFromHex("#eeeeee"))
+		c.Draw(box)
+
+		// A legend swatch.
+		swatch := c.NewRectangle(100, 200, 6.4, 6.4)
+		swatch.SetBorderWidth(0)
+		swatch.SetFillColor(creator.ColorRGBFromHex("#4e79a7"))
+		c.Draw(swatch)
+
+		// A filled square with an outline.
+		square := c.NewRectangle(200, 200, 12, 12)
+		square.SetBorderWidth(1)
+		square.SetBorderColor(creator.ColorBlack)
+		square.SetFillColor(creator.ColorRed)
+		c.Draw(square)
+	})[0]
+
+	vectors, err := ex.ExtractPageVectors(nil)
+	if err != nil {
+		t.Fatalf("unable to extract vectors: %v", err)
+	}
+	var checkboxes, squares int
+	for _, shape := range vectors.Shapes {
+		if w := shape.BBox.Width(); w < 5 || w > 24 {
+			continue
+		}
+		switch shape.Kind {
+		case ShapeCheckbox:
+			checkboxes++
+		case ShapeRectangle:
+			squares++
+		}
+	}
+	if checkboxes != 2 || squares != 2 {
+		t.Fatalf("expected 2 checkboxes and 2 rectangles, got %d and %d", checkboxes, squares)
+	}
+}
@@ -0,0 +1,339 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// TextSource is the provenance of the text of a TextMark: the content stream that drew it and the optional
+// content (layers) it belongs to.
+type TextSource struct {
+	// Forms are the form XObjects that drew the text, from the outermost to the innermost. It is empty for
+	// text drawn directly by the content stream of the page or of an annotation appearance.
+	Forms []FormSource
+
+	// Annotation is the annotation whose appearance stream drew the text. It is nil for page content.
+	Annotation *model.PdfAnnotation
+
+	// AnnotationSubtype is the subtype of Annotation, e.g. "Widget" or "FreeText".
+	AnnotationSubtype string
+
+	// OptionalContent are the optional content groups (OCG) and membership dictionaries (OCMD) that
+	// control the visibility of the text, from marked content with the OC tag and from the OC entries of
+	// forms and annotations, outermost first.
+	OptionalContent []core.PdfObject
+
+	// Layers are the names of the optional content groups of OptionalContent.
+	Layers []string
+}
+
+// FormSource is a form XObject that drew text.
+type FormSource struct {
+	// Name is the name of the form in the resources of the content stream that drew it.
+	Name string
+
+	// Stream is the form XObject.
+	Stream *core.PdfObjectStream
+}
+
+// IsPageContent returns true if the text was drawn by the page content stream, outside forms and
+// annotations.
+func (s *TextSource) IsPageContent() bool {
+	return s == nil || s.Annotation == nil && len(s.Forms) == 0
+}
+
+// InLayer returns true if the text belongs to the optional content group named `name`.
+func (s *TextSource) InLayer(name string) bool {
+	if s == nil {
+		return false
+	}
+	for _, layer := range s.Layers {
+		if layer == name {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleIn returns true if all the optional content of `s` is visible in `visibility`.
+func (s *TextSource) visibleIn(visibility *model.OCVisibility) bool {
+	if s == nil {
+		return true
+	}
+	for _, oc := range s.OptionalContent {
+		if !visibility.IsVisible(oc) {
+			return false
+		}
+	}
+	return true
+}
+
+// withContent returns a copy of `s` with the optional content `oc` appended.
+func (s *TextSource) withContent(oc core.PdfObject) *TextSource {
+	names := optionalContentNames(oc)
+	if names == nil {
+		return s
+	}
+	c := *s
+	c.OptionalContent = append(append([]core.PdfObject(nil), s.OptionalContent...), oc)
+	c.Layers = append(append([]string(nil), s.Layers...), names...)
+	return &c
+}
+
+// within returns the source of text with source `s` drawn by a form or annotation appearance with source
+// `outer`.
+func (s *TextSource) within(outer *TextSource) *TextSource {
+	if s == nil {
+		return outer
+	}
+	c := *outer
+	c.Forms = append(append([]FormSource(nil), outer.Forms...), s.Forms...)
+	if s.Annotation != nil {
+		c.Annotation, c.AnnotationSubtype = s.Annotation, s.AnnotationSubtype
+	}
+	c.OptionalContent = append(append([]core.PdfObject(nil), outer.OptionalContent...), s.OptionalContent...)
+	c.Layers = append(append([]string(nil), outer.Layers...), s.Layers...)
+	return &c
+}
+
+// optionalContentNames returns the names of the optional content groups of the OCG or OCMD `oc`, or nil
+// if `oc` is neither.
+func optionalContentNames(oc core.PdfObject) []string {
+	dict, ok := core.GetDict(oc)
+	if !ok {
+		return nil
+	}
+	typ, _ := core.GetNameVal(dict.Get("Type"))
+	switch typ {
+	case "OCG":
+		var name string
+		if str, ok := core.GetString(dict.Get("Name")); ok {
+			name = str.Decoded()
+		}
+		return []string{name}
+	case "OCMD":
+		names := []string{}
+		ocgs := dict.Get("OCGs")
+		if arr, ok := core.GetArray(ocgs); ok {
+			for _, ocg := range arr.Elements() {
+				names = append(names, optionalContentNames(ocg)...)
+			}
+		} else if ocgs != nil {
+			names = append(names, optionalContentNames(ocgs)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// sourceTracker tracks the source of the text drawn by a content stream through its marked content.
+type sourceTracker struct {
+	base       *TextSource
+	stack      []*TextSource
+	visibility *model.OCVisibility
+}
+
+func newSourceTracker(visibility *model.OCVisibility) *sourceTracker {
+	return &sourceTracker{base: &TextSource{}, visibility: visibility}
+}
+
+// current returns the source of text drawn at the current marked content level.
+func (t *sourceTracker) current() *TextSource {
+	if t == nil {
+		return nil
+	}
+	if n := len(t.stack); n > 0 {
+		return t.stack[n-1]
+	}
+	return t.base
+}
+
+// process updates the marked content levels for `op`. Marked content with the OC tag adds its optional
+// content to the source of the text it holds.
+func (t *sourceTracker) process(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources) {
+	switch op.Operand {
+	case "BMC":
+		t.stack = append(t.stack, t.current())
+	case "BDC":
+		source := t.current()
+		if len(op.Params) == 2 {
+			if tag, ok := core.GetNameVal(op.Params[0]); ok && tag == "OC" {
+				if oc := markedContentOC(op.Params[1], resources); oc != nil {
+					source = source.withContent(oc)
+				}
+			}
+		}
+		t.stack = append(t.stack, source)
+	case "EMC":
+		if n := len(t.stack); n > 0 {
+			t.stack = t.stack[:n-1]
+		}
+	}
+}
+
+// hides returns true if `op` paints content of hidden optional content: a path, shading, inline image or
+// XObject painted within hidden marked content, or an XObject whose own optional content is hidden.
+func (t *sourceTracker) hides(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources) bool {
+	if t.visibility == nil {
+		return false
+	}
+	switch op.Operand {
+	case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "sh", "BI":
+		return !t.current().visibleIn(t.visibility)
+	case "Do":
+		if !t.current().visibleIn(t.visibility) {
+			return true
+		}
+		if len(op.Params) == 1 {
+			if name, ok := core.GetName(op.Params[0]); ok {
+				if stream, _ := resources.GetXObjectByName(*name); stream != nil {
+					return !t.visibility.IsVisible(stream.PdfObjectDictionary.Get("OC"))
+				}
+			}
+		}
+	}
+	return false
+}
+
+// visibleOperands returns a handler calling `handler` for the operations of a content stream which do not
+// paint hidden optional content.
+func (ctx *imageExtractContext) visibleOperands(handler contentstream.HandlerFunc) contentstream.HandlerFunc {
+	if ctx._ocVisibility == nil {
+		return handler
+	}
+	tracker := newSourceTracker(ctx._ocVisibility)
+	return func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+		resources *model.PdfPageResources) error {
+		tracker.process(op, resources)
+		if tracker.hides(op, resources) {
+			return nil
+		}
+		return handler(op, gs, resources)
+	}
+}
+
+// markedContentOC returns the optional content of the properties of marked content with the OC tag: an
+// inline dictionary or the name of an entry of the Properties resources.
+func markedContentOC(props core.PdfObject, resources *model.PdfPageResources) core.PdfObject {
+	if name, ok := core.GetName(props); ok {
+		if resources == nil {
+			return nil
+		}
+		properties, ok := core.GetDict(resources.Properties)
+		if !ok {
+			return nil
+		}
+		return properties.Get(*name)
+	}
+	if _, ok := core.GetDict(props); ok {
+		return props
+	}
+	return nil
+}
+
+// formMarks returns copies of the marks of the form XObject `name` of `resources` with the form and the
+// current marked content added to their sources.
+func (t *sourceTracker) formMarks(marks []*textMark, name core.PdfObjectName, resources *model.PdfPageResources) []*textMark {
+	outer := t.current()
+	stream, _ := resources.GetXObjectByName(name)
+	if stream == nil {
+		return marks
+	}
+	c := *outer
+	c.Forms = append(append([]FormSource(nil), outer.Forms...), FormSource{Name: string(name), Stream: stream})
+	outer = &c
+	if oc := stream.PdfObjectDictionary.Get("OC"); oc != nil {
+		outer = outer.withContent(oc)
+	}
+	return sourcedMarks(marks, outer)
+}
+
+// annotationMarks returns copies of the marks of the appearance stream of `annot` with the annotation
+// added to their sources.
+func annotationMarks(marks []*textMark, annot *model.PdfAnnotation) []*textMark {
+	outer := &TextSource{Annotation: annot}
+	if dict, ok := core.GetDict(annot.GetContainingPdfObject()); ok {
+		outer.AnnotationSubtype, _ = core.GetNameVal(dict.Get("Subtype"))
+	}
+	if outer.AnnotationSubtype == "" {
+		outer.AnnotationSubtype = annotationSubtype(annot)
+	}
+	if annot.OC != nil {
+		outer = outer.withContent(annot.OC)
+	}
+	return sourcedMarks(marks, outer)
+}
+
+// annotationSubtype returns the subtype of `annot` from the type of its context.
+func annotationSubtype(annot *model.PdfAnnotation) string {
+	switch annot.GetContext().(type) {
+	case *model.PdfAnnotationWidget:
+		return "Widget"
+	case *model.PdfAnnotationFreeText:
+		return "FreeText"
+	case *model.PdfAnnotationText:
+		return "Text"
+	case *model.PdfAnnotationStamp:
+		return "Stamp"
+	case *model.PdfAnnotationWatermark:
+		return "Watermark"
+	}
+	return ""
+}
+
+// sourcedMarks returns copies of `marks` drawn within `outer`. The marks are copied as the marks of forms
+// and annotation appearances are shared by all their uses.
+func sourcedMarks(marks []*textMark, outer *TextSource) []*textMark {
+	result := make([]*textMark, len(marks))
+	for i, mark := range marks {
+		c := *mark
+		c._source = mark._source.within(outer)
+		result[i] = &c
+	}
+	return result
+}
+
+// ocVisibility returns the visibility of optional content for extracting `page` with `options`: the
+// visibility of the options or else the default configuration of the document for viewing. It returns nil
+// if the content of hidden optional content groups is included.
+func ocVisibility(page *model.PdfPage, options *Options) *model.OCVisibility {
+	if options != nil {
+		if options.IncludeHiddenContent {
+			return nil
+		}
+		if options.OCVisibility != nil {
+			return options.OCVisibility
+		}
+	}
+	visibility, err := page.GetOCVisibility(model.OCEventView)
+	if err != nil {
+		common.Log.Debug("ERROR: optional content properties: %v", err)
+		return nil
+	}
+	return visibility
+}
+
+// visibleMarks returns the marks of `marks` that are not in hidden optional content.
+func (e *Extractor) visibleMarks(marks []*textMark) []*textMark {
+	if e._ocVisibility == nil {
+		return marks
+	}
+	visible := marks[:0]
+	for _, mark := range marks {
+		if mark._source.visibleIn(e._ocVisibility) {
+			visible = append(visible, mark)
+		}
+	}
+	return visible
+}
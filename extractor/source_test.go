@@ -0,0 +1,201 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// hiddenLayerContents is a content stream drawing text, a path, an image XObject, an inline image and a
+// shading in the hidden layer MC0, and the same content outside of it. Im1 is hidden by its own OC entry.
+const hiddenLayerContents = `/OC /MC0 BDC
+BT /F1 12 Tf 100 700 Td (Hidden) Tj ET
+0 0 1 rg 100 600 50 50 re f
+q 20 0 0 20 100 500 cm /Im0 Do Q
+q 20 0 0 20 100 450 cm BI /W 1 /H 1 /CS /G /BPC 8 ID ` + "\x80" + ` EI Q
+/Sh0 sh
+EMC
+BT /F1 12 Tf 100 400 Td (Visible) Tj ET
+0 1 0 rg 300 600 50 50 re f
+q 20 0 0 20 300 500 cm /Im0 Do Q
+q 20 0 0 20 300 300 cm /Im1 Do Q
+`
+
+// hiddenLayerPage returns the resources of hiddenLayerContents and the visibility hiding its layer.
+func hiddenLayerPage(t *testing.T) (*model.PdfPageResources, *model.OCVisibility) {
+	ocg := core.MakeDictMap(map[string]core.PdfObject{
+		"Type": core.MakeName("OCG"),
+		"Name": core.MakeString("Hidden"),
+	})
+	visibility := model.NewOCVisibility(core.MakeDictMap(map[string]core.PdfObject{
+		"OCGs": core.MakeArray(ocg),
+		"D":    core.MakeDictMap(map[string]core.PdfObject{"OFF": core.MakeArray(ocg)}),
+	}), model.OCEventView)
+
+	resources := model.NewPdfPageResources()
+	resources.Properties = core.MakeDictMap(map[string]core.PdfObject{"MC0": ocg})
+	if err := resources.SetFontByName("F1", model.NewStandard14FontMustCompile(model.HelveticaName).ToPdfObject()); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	image := func(oc core.PdfObject) *core.PdfObjectStream {
+		stream, err := core.MakeStream([]byte{0x80}, nil)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		for key, val := range map[core.PdfObjectName]core.PdfObject{
+			"Type": core.MakeName("XObject"), "Subtype": core.MakeName("Image"),
+			"Width": core.MakeInteger(1), "Height": core.MakeInteger(1),
+			"ColorSpace": core.MakeName("DeviceGray"), "BitsPerComponent": core.MakeInteger(8),
+		} {
+			stream.Set(key, val)
+		}
+		if oc != nil {
+			stream.Set("OC", oc)
+		}
+		return stream
+	}
+	for name, stream := range map[core.PdfObjectName]*core.PdfObjectStream{"Im0": image(nil), "Im1": image(ocg)} {
+		if err := resources.SetXObjectByName(name, stream); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	shading := core.MakeDictMap(map[string]core.PdfObject{
+		"ShadingType": core.MakeInteger(2),
+		"ColorSpace":  core.MakeName("DeviceRGB"),
+		"Coords":      core.MakeArrayFromFloats([]float64{0, 0, 1, 0}),
+		"Function": core.MakeDictMap(map[string]core.PdfObject{
+			"FunctionType": core.MakeInteger(2),
+			"Domain":       core.MakeArrayFromFloats([]float64{0, 1}),
+			"C0":           core.MakeArrayFromFloats([]float64{0, 0, 0}),
+			"C1":           core.MakeArrayFromFloats([]float64{1, 1, 1}),
+			"N":            core.MakeInteger(1),
+		}),
+	})
+	if err := resources.SetShadingByName("Sh0", shading); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return resources, visibility
+}
+
+func TestHiddenLayerContent(t *testing.T) {
+	resources, visibility := hiddenLayerPage(t)
+	ex, err := NewFromContents(hiddenLayerContents, resources)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	ex._ocVisibility = visibility
+
+	text, err := ex.ExtractText()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if strings.TrimSpace(text) != "Visible" {
+		t.Fatalf("expected only the visible text, got %q", text)
+	}
+
+	vectors, err := ex.ExtractPageVectors(nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(vectors.Paths) != 1 || vectors.Paths[0].BBox.Llx != 300 {
+		t.Fatalf("expected only the visible path, got %d paths", len(vectors.Paths))
+	}
+
+	images, err := ex.ExtractPageImages(nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(images.Images) != 1 || images.Images[0].X != 300 || images.Images[0].Y != 500 {
+		t.Fatalf("expected only the visible image, got %d images", len(images.Images))
+	}
+
+	// The shading and the inline image of the layer are hidden too.
+	ops, err := contentstream.NewContentStreamParser(hiddenLayerContents).Parse()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	tracker := newSourceTracker(visibility)
+	var hidden []string
+	for _, op := range *ops {
+		tracker.process(op, resources)
+		if tracker.hides(op, resources) {
+			hidden = append(hidden, op.Operand)
+		}
+	}
+	if expected := []string{"f", "Do", "BI", "sh", "Do"}; !reflect.DeepEqual(hidden, expected) {
+		t.Fatalf("expected hidden operations %v, got %v", expected, hidden)
+	}
+}
+
+// TestTextSources checks the sources of text drawn by the content stream of a page, by the form XObject
+// Fm0 in the layer "Stamps" and by the appearance stream of a FreeText annotation.
+func TestTextSources(t *testing.T) {
+	f, err := os.Open("testdata/text_sources.pdf")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	page, err := reader.GetPage(1)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	ex, err := NewWithOptions(page, &Options{IncludeAnnotations: true})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	pageText, _, _, err := ex.ExtractPageText()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// The sources of the marks of the first letters of Body, Stamp and Note.
+	sources := map[string]*TextSource{}
+	for _, mark := range pageText.Marks().Elements() {
+		if text := strings.TrimSpace(mark.Text); text != "" && sources[text] == nil {
+			sources[text] = mark.Source
+		}
+	}
+	for _, letter := range []string{"B", "S", "N"} {
+		if _, ok := sources[letter]; !ok {
+			t.Fatalf("expected %q in %q", letter, pageText.Text())
+		}
+	}
+
+	if body := sources["B"]; !body.IsPageContent() || len(body.Layers) != 0 {
+		t.Fatalf("expected page content, got %+v", body)
+	}
+
+	stamp := sources["S"]
+	if stamp.IsPageContent() || len(stamp.Forms) != 1 || stamp.Forms[0].Name != "Fm0" || stamp.Annotation != nil {
+		t.Fatalf("expected text of form Fm0, got %+v", stamp)
+	}
+	if !stamp.InLayer("Stamps") || stamp.InLayer("Notes") {
+		t.Fatalf("expected text in layer Stamps, got %v", stamp.Layers)
+	}
+
+	note := sources["N"]
+	if note.IsPageContent() || len(note.Forms) != 0 || note.AnnotationSubtype != "FreeText" {
+		t.Fatalf("expected text of a FreeText annotation, got %+v", note)
+	}
+	if note.Annotation == nil || note.Annotation.GetContext() == nil {
+		t.Fatalf("expected the annotation of the text, got %+v", note)
+	}
+}
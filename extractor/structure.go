@@ -0,0 +1,581 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// StructElementRef references the structure element of the text of a TextMark in a tagged document.
+type StructElementRef struct {
+	// Type is the structure type of the element, mapped to a standard structure type through the role map
+	// of the document when possible, e.g. P, TD or H2.
+	Type string
+
+	// MCID is the marked-content identifier of the text in the content stream.
+	MCID int
+
+	// Element is the structure element dictionary.
+	Element *core.PdfObjectDictionary
+}
+
+// KDict returns the structure element as a model.KDict.
+func (r *StructElementRef) KDict() (*model.KDict, error) {
+	return model.NewKDictFromPdfObject(r.Element)
+}
+
+// _ddStandardTypes are the standard structure types of PDF 1.7 and PDF 2.0.
+var _ddStandardTypes = map[string]bool{
+	"Document": true, "DocumentFragment": true, "Part": true, "Art": true, "Sect": true, "Div": true,
+	"BlockQuote": true, "Caption": true, "TOC": true, "TOCI": true, "Index": true, "NonStruct": true,
+	"Private": true, "Aside": true, "Title": true, "FENote": true, "Sub": true, "P": true, "H": true,
+	"L": true, "LI": true, "Lbl": true, "LBody": true, "Table": true, "TR": true, "TH": true, "TD": true,
+	"THead": true, "TBody": true, "TFoot": true, "Span": true, "Quote": true, "Note": true,
+	"Reference": true, "BibEntry": true, "Code": true, "Link": true, "Annot": true, "Ruby": true,
+	"RB": true, "RT": true, "RP": true, "Warichu": true, "WT": true, "WP": true, "Figure": true,
+	"Formula": true, "Form": true, "Em": true, "Strong": true, "Artifact": true,
+}
+
+// _ddInlineTypes are the structure types whose content is not separated from the surrounding text by line
+// breaks when text is ordered by the structure tree.
+var _ddInlineTypes = map[string]bool{
+	"Span": true, "Quote": true, "Note": true, "Reference": true, "BibEntry": true, "Code": true,
+	"Link": true, "Annot": true, "Ruby": true, "RB": true, "RT": true, "RP": true, "Warichu": true,
+	"WT": true, "WP": true, "Em": true, "Strong": true, "Sub": true, "Lbl": true, "LBody": true,
+	"Form": true,
+}
+
+// mapStructType returns the standard structure type that `name` is mapped to by `roleMap`, or `name` if it
+// is not mapped to a standard type.
+func mapStructType(roleMap *core.PdfObjectDictionary, name string) string {
+	mapped := name
+	for i := 0; i < 10 && roleMap != nil; i++ {
+		if _ddStandardTypes[mapped] || _ddHeading.MatchString(mapped) {
+			return mapped
+		}
+		next, ok := core.GetNameVal(roleMap.Get(core.PdfObjectName(mapped)))
+		if !ok {
+			break
+		}
+		mapped = next
+	}
+	if _ddStandardTypes[mapped] || _ddHeading.MatchString(mapped) {
+		return mapped
+	}
+	return name
+}
+
+// markedContent is the innermost marked-content sequence of a text object: an MCID, or an artifact with
+// its subtype.
+type markedContent struct {
+	mcid     int64
+	artifact bool
+	subtype  string
+}
+
+// scanMarkedContent returns the marked-content sequences of the string operands of the text-showing
+// operators of `ops`, keyed by the operands.
+func scanMarkedContent(ops *contentstream.ContentStreamOperations, resources *model.PdfPageResources) map[core.PdfObject]markedContent {
+	owners := map[core.PdfObject]markedContent{}
+	if ops == nil {
+		return owners
+	}
+	var stack []markedContent
+	for _, op := range *ops {
+		switch op.Operand {
+		case "BMC", "BDC":
+			mc := markedContent{mcid: -1}
+			if len(stack) > 0 {
+				mc = stack[len(stack)-1]
+			}
+			if len(op.Params) == 0 {
+				stack = append(stack, mc)
+				continue
+			}
+			if tag, _ := core.GetNameVal(op.Params[0]); tag == "Artifact" {
+				mc.artifact, mc.mcid = true, -1
+			}
+			if len(op.Params) == 2 {
+				if props := markedContentProperties(resources, op.Params[1]); props != nil {
+					if mc.artifact {
+						mc.subtype, _ = core.GetNameVal(props.Get("Subtype"))
+					} else if mcid, ok := core.GetIntVal(props.Get("MCID")); ok {
+						mc.mcid = int64(mcid)
+					}
+				}
+			}
+			stack = append(stack, mc)
+		case "EMC":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case "Tj", "'", "\"", "TJ":
+			if len(stack) == 0 || len(op.Params) == 0 {
+				continue
+			}
+			mc := stack[len(stack)-1]
+			param := op.Params[len(op.Params)-1]
+			if arr, ok := core.GetArray(param); ok {
+				for _, elem := range arr.Elements() {
+					owners[core.TraceToDirectObject(elem)] = mc
+				}
+			} else {
+				owners[core.TraceToDirectObject(param)] = mc
+			}
+		}
+	}
+	return owners
+}
+
+// markedContentProperties returns the property list of a marked-content operator, either inline or a named
+// resource.
+func markedContentProperties(resources *model.PdfPageResources, obj core.PdfObject) *core.PdfObjectDictionary {
+	if dict, ok := core.GetDict(obj); ok {
+		return dict
+	}
+	name, ok := core.GetName(obj)
+	if !ok || resources == nil {
+		return nil
+	}
+	properties, ok := core.GetDict(resources.Properties)
+	if !ok {
+		return nil
+	}
+	dict, _ := core.GetDict(properties.Get(*name))
+	return dict
+}
+
+// numberTreeValue returns the value of `key` in the number tree `node`.
+func numberTreeValue(node core.PdfObject, key int64) core.PdfObject {
+	visited := map[core.PdfObject]bool{}
+	for depth := 0; depth < 32; depth++ {
+		dict, ok := core.GetDict(node)
+		if !ok || visited[dict] {
+			return nil
+		}
+		visited[dict] = true
+		if nums, ok := core.GetArray(dict.Get("Nums")); ok {
+			for i := 0; i+1 < nums.Len(); i += 2 {
+				if k, ok := core.GetIntVal(nums.Get(i)); ok && int64(k) == key {
+					return nums.Get(i + 1)
+				}
+			}
+			return nil
+		}
+		kids, ok := core.GetArray(dict.Get("Kids"))
+		if !ok {
+			return nil
+		}
+		node = nil
+		for _, kid := range kids.Elements() {
+			kidDict, ok := core.GetDict(kid)
+			if !ok {
+				continue
+			}
+			if limits, ok := core.GetArray(kidDict.Get("Limits")); ok && limits.Len() == 2 {
+				lo, _ := core.GetIntVal(limits.Get(0))
+				hi, _ := core.GetIntVal(limits.Get(1))
+				if key < int64(lo) || key > int64(hi) {
+					continue
+				}
+			}
+			node = kid
+			break
+		}
+	}
+	return nil
+}
+
+// pageStructure is the structure of the content of a page of a tagged document.
+type pageStructure struct {
+	root    *core.PdfObjectDictionary
+	roleMap *core.PdfObjectDictionary
+	page    core.PdfObject
+
+	// parents are the structure elements of the MCIDs of the page, from the parent tree.
+	parents *core.PdfObjectArray
+	refs    map[int]*StructElementRef
+	owners  map[core.PdfObject]markedContent
+
+	// kids are the structure elements owning the MCIDs of the page through their K entries.
+	kids map[int64]*core.PdfObjectDictionary
+}
+
+// ref returns the reference to the structure element of `mcid`, or nil. The element owning the MCID
+// through its K entry takes precedence over the entry of the parent tree, which some producers write
+// with an offset.
+func (s *pageStructure) ref(mcid int64) *StructElementRef {
+	if ref, ok := s.refs[int(mcid)]; ok {
+		return ref
+	}
+	var elem *core.PdfObjectDictionary
+	if s.parents != nil && mcid >= 0 && int(mcid) < s.parents.Len() {
+		elem, _ = core.GetDict(s.parents.Get(int(mcid)))
+	}
+	if owner, ok := s.kids[mcid]; ok {
+		elem = owner
+	}
+	var ref *StructElementRef
+	if elem != nil {
+		name, _ := core.GetNameVal(elem.Get("S"))
+		ref = &StructElementRef{Type: mapStructType(s.roleMap, name), MCID: int(mcid), Element: elem}
+	}
+	s.refs[int(mcid)] = ref
+	return ref
+}
+
+// collectKids records the structure elements of the tree of `elem` owning MCIDs of the page through their
+// K entries. `pg` is the page inherited from the ancestors of `elem`.
+func (s *pageStructure) collectKids(elem *core.PdfObjectDictionary, pg core.PdfObject,
+	visited map[*core.PdfObjectDictionary]bool) {
+	if visited[elem] {
+		return
+	}
+	visited[elem] = true
+	if p := elem.Get("Pg"); p != nil {
+		pg = p
+	}
+	onPage := func(pg core.PdfObject) bool {
+		return pg != nil && core.ResolveReference(pg) == core.ResolveReference(s.page)
+	}
+	add := func(mcid int, pg core.PdfObject) {
+		if _, ok := s.kids[int64(mcid)]; !ok && elem.Get("S") != nil && onPage(pg) {
+			s.kids[int64(mcid)] = elem
+		}
+	}
+
+	var kids []core.PdfObject
+	switch k := core.TraceToDirectObject(elem.Get("K")).(type) {
+	case *core.PdfObjectArray:
+		kids = k.Elements()
+	case nil:
+	default:
+		kids = []core.PdfObject{elem.Get("K")}
+	}
+	for _, kid := range kids {
+		if mcid, ok := core.GetIntVal(kid); ok {
+			add(mcid, pg)
+			continue
+		}
+		dict, ok := core.GetDict(kid)
+		if !ok {
+			continue
+		}
+		if typ, _ := core.GetNameVal(dict.Get("Type")); typ == "MCR" {
+			if mcid, ok := core.GetIntVal(dict.Get("MCID")); ok {
+				kidPg := pg
+				if p := dict.Get("Pg"); p != nil {
+					kidPg = p
+				}
+				add(mcid, kidPg)
+			}
+			continue
+		}
+		if dict.Get("S") != nil {
+			s.collectKids(dict, pg, visited)
+		}
+	}
+}
+
+// applyStructTree sets the structure element references of the marks of a page of a tagged document and,
+// if Options.StructureOrder is set, orders its text by the structure tree.
+func (pt *PageText) applyStructTree(e *Extractor) {
+	if e._structRoot == nil || e._add < 0 || (e._dee != nil && e._dee.DisableDocumentTags) {
+		return
+	}
+	root, ok := core.GetDict(e._structRoot)
+	if !ok {
+		return
+	}
+	s := &pageStructure{root: root, page: e._ead, refs: map[int]*StructElementRef{}}
+	s.roleMap, _ = core.GetDict(root.Get("RoleMap"))
+	s.parents, _ = core.GetArray(numberTreeValue(root.Get("ParentTree"), int64(e._add)))
+	s.kids = map[int64]*core.PdfObjectDictionary{}
+	s.collectKids(root, nil, map[*core.PdfObjectDictionary]bool{})
+	s.owners = scanMarkedContent(pt._dcff, e._fdg)
+
+	pt._structRefs = map[core.PdfObject]*StructElementRef{}
+	for obj, mc := range s.owners {
+		if mc.mcid >= 0 {
+			if ref := s.ref(mc.mcid); ref != nil {
+				pt._structRefs[obj] = ref
+			}
+		}
+	}
+	pt.setStructElements(pt._fffbd)
+	for i := range pt._gacd {
+		for y := range pt._gacd[i].Cells {
+			for x := range pt._gacd[i].Cells[y] {
+				pt.setStructElements(pt._gacd[i].Cells[y][x].Marks._edfaa)
+			}
+		}
+	}
+	if e._dee != nil && e._dee.StructureOrder {
+		pt.orderByStructure(s)
+	}
+}
+
+// setStructElements sets the structure element references of `marks`.
+func (pt *PageText) setStructElements(marks []TextMark) {
+	if len(pt._structRefs) == 0 {
+		return
+	}
+	for i := range marks {
+		if marks[i].DirectObject != nil {
+			marks[i].StructElement = pt._structRefs[core.TraceToDirectObject(marks[i].DirectObject)]
+		}
+	}
+}
+
+// structOrder accumulates the text and marks of a page in structure order.
+type structOrder struct {
+	s     *pageStructure
+	text  strings.Builder
+	marks []TextMark
+
+	// groups are the marks of each MCID in layout order, including the spaces and line breaks between
+	// them.
+	groups  map[int64][]TextMark
+	emitted map[int64]bool
+	visited map[*core.PdfObjectDictionary]bool
+
+	// last is the last mark with text of a content item.
+	last    *TextMark
+	pending string
+}
+
+// orderByStructure replaces the text and marks of the page by the content of the structure elements in
+// the order of the structure tree. Content that is not reached from the structure tree follows in layout
+// order. Artifacts are omitted.
+func (pt *PageText) orderByStructure(s *pageStructure) {
+	o := &structOrder{s: s, groups: map[int64][]TextMark{}, emitted: map[int64]bool{},
+		visited: map[*core.PdfObjectDictionary]bool{}}
+
+	// mcidOf returns the MCID of a mark, -1 for unmarked content and -2 for artifacts.
+	mcidOf := func(mark TextMark) int64 {
+		mc, ok := s.owners[core.TraceToDirectObject(mark.DirectObject)]
+		switch {
+		case !ok:
+			return -1
+		case mc.artifact:
+			return -2
+		}
+		return mc.mcid
+	}
+	groupMarks := func(keep func(int64) bool, add func(int64, TextMark)) {
+		last := int64(-3)
+		var metas []TextMark
+		for _, mark := range pt._fffbd {
+			if mark.Meta {
+				metas = append(metas, mark)
+				continue
+			}
+			mcid := mcidOf(mark)
+			if !keep(mcid) {
+				last, metas = -3, nil
+				continue
+			}
+			if last != -3 {
+				for _, meta := range metas {
+					add(mcid, meta)
+				}
+			}
+			add(mcid, mark)
+			last, metas = mcid, nil
+		}
+	}
+
+	// The marks of each MCID keep the spaces and line breaks between them.
+	groupMarks(func(mcid int64) bool { return mcid >= 0 }, func(mcid int64, mark TextMark) {
+		if len(o.groups[mcid]) > 0 || !mark.Meta {
+			o.groups[mcid] = append(o.groups[mcid], mark)
+		}
+	})
+	o.walkKids(s.root)
+
+	o.separate("\n")
+	o.last = nil
+	groupMarks(func(mcid int64) bool { return mcid == -1 || mcid >= 0 && !o.emitted[mcid] }, func(_ int64, mark TextMark) {
+		if mark.Meta {
+			o.separate(mark.Text)
+		} else {
+			o.emit(mark)
+		}
+	})
+	pt._fgb = o.text.String()
+	pt._fffbd = o.marks
+}
+
+// walkKids appends the content of the kids of a structure element.
+func (o *structOrder) walkKids(elem *core.PdfObjectDictionary) {
+	var kids []core.PdfObject
+	switch k := core.TraceToDirectObject(elem.Get("K")).(type) {
+	case *core.PdfObjectArray:
+		kids = k.Elements()
+	case nil:
+	default:
+		kids = []core.PdfObject{elem.Get("K")}
+	}
+	for _, kid := range kids {
+		if mcid, ok := core.GetIntVal(kid); ok {
+			o.content(elem, int64(mcid), nil)
+			continue
+		}
+		dict, ok := core.GetDict(kid)
+		if !ok {
+			continue
+		}
+		if dict.Get("S") != nil {
+			o.walk(dict)
+			continue
+		}
+		if typ, _ := core.GetNameVal(dict.Get("Type")); typ == "MCR" {
+			if mcid, ok := core.GetIntVal(dict.Get("MCID")); ok {
+				o.content(elem, int64(mcid), dict.Get("Pg"))
+			}
+		}
+	}
+}
+
+// walk appends the content of a structure element. The content of elements with replacement text is
+// replaced by a single mark.
+func (o *structOrder) walk(elem *core.PdfObjectDictionary) {
+	if o.visited[elem] {
+		return
+	}
+	o.visited[elem] = true
+	name, _ := core.GetNameVal(elem.Get("S"))
+	typ := mapStructType(o.s.roleMap, name)
+	inline := _ddInlineTypes[typ]
+	switch {
+	case typ == "TD" || typ == "TH":
+		o.separate("\t")
+	case !inline:
+		o.separate("\n")
+		defer o.separate("\n")
+	}
+
+	replacement, ok := core.GetStringVal(elem.Get("ActualText"))
+	if !ok {
+		replacement, ok = core.GetStringVal(elem.Get("E"))
+	}
+	start, startLen, pending, last := len(o.marks), o.text.Len(), o.pending, o.last
+	o.walkKids(elem)
+	if len(o.marks) == start {
+		if typ == "Figure" || typ == "Formula" {
+			if alt, ok := core.GetStringVal(elem.Get("Alt")); ok && alt != "" && o.onPage(elem) {
+				o.emit(TextMark{Text: alt, StructElement: &StructElementRef{Type: typ, MCID: -1, Element: elem}})
+			}
+		}
+		return
+	}
+	if !ok {
+		return
+	}
+
+	// Replace the content of the element, keeping its position.
+	replaced := append([]TextMark(nil), o.marks[start:]...)
+	mark := TextMark{Text: replacement, StructElement: &StructElementRef{Type: typ, MCID: -1, Element: elem}}
+	for _, m := range replaced {
+		if m.Meta {
+			continue
+		}
+		if mark.DirectObject == nil {
+			mark.Font, mark.FontSize, mark.FillColor, mark.StrokeColor = m.Font, m.FontSize, m.FillColor, m.StrokeColor
+			mark.DirectObject, mark.BBox = m.DirectObject, m.BBox
+		} else {
+			mark.BBox = unionDocumentBBox(mark.BBox, m.BBox)
+		}
+		if m.StructElement != nil && m.StructElement.Element == elem && mark.StructElement.MCID < 0 {
+			mark.StructElement.MCID = m.StructElement.MCID
+		}
+	}
+	text := o.text.String()[:startLen]
+	o.text.Reset()
+	o.text.WriteString(text)
+	o.marks, o.pending, o.last = o.marks[:start], pending, last
+	o.emit(mark)
+}
+
+// onPage returns true if a structure element is on the page of the text.
+func (o *structOrder) onPage(elem *core.PdfObjectDictionary) bool {
+	return core.ResolveReference(elem.Get("Pg")) == core.ResolveReference(o.s.page)
+}
+
+// content appends the marks of `mcid` if it belongs to `elem` on the page of the text.
+func (o *structOrder) content(elem *core.PdfObjectDictionary, mcid int64, pg core.PdfObject) {
+	if o.emitted[mcid] {
+		return
+	}
+	if pg != nil && core.ResolveReference(pg) != core.ResolveReference(o.s.page) {
+		return
+	}
+	if ref := o.s.ref(mcid); ref == nil || ref.Element != elem {
+		if ref != nil || !o.onPage(elem) {
+			return
+		}
+	}
+	marks, ok := o.groups[mcid]
+	if !ok {
+		return
+	}
+	o.emitted[mcid] = true
+	for _, mark := range marks {
+		if mark.Meta {
+			o.separate(mark.Text)
+		} else {
+			o.emit(mark)
+		}
+	}
+}
+
+// separate requests a separator before the next mark. Line breaks take precedence over tabs and tabs over
+// spaces.
+func (o *structOrder) separate(sep string) {
+	if o.text.Len() == 0 {
+		return
+	}
+	if sep == "\n" || o.pending == "" || (sep == "\t" && o.pending == " ") {
+		o.pending = sep
+	}
+}
+
+// emit appends a mark, preceded by the pending separator, or by a space if the mark is not adjacent to the
+// previous one.
+func (o *structOrder) emit(mark TextMark) {
+	sep := o.pending
+	if sep == "" && o.last != nil && !marksAdjacent(*o.last, mark) {
+		sep = " "
+	}
+	if sep != "" && o.text.Len() > 0 {
+		o.marks = append(o.marks, TextMark{Text: sep, Meta: true, Offset: o.text.Len()})
+		o.text.WriteString(sep)
+	}
+	o.pending = ""
+	mark.Offset = o.text.Len()
+	o.text.WriteString(mark.Text)
+	o.marks = append(o.marks, mark)
+	o.last = &o.marks[len(o.marks)-1]
+}
+
+// marksAdjacent returns true if mark `b` directly follows mark `a` on the same line.
+func marksAdjacent(a, b TextMark) bool {
+	if a.BBox == (model.PdfRectangle{}) || b.BBox == (model.PdfRectangle{}) {
+		return false
+	}
+	size := math.Max(a.FontSize, 1)
+	sameLine := math.Abs(a.BBox.Lly-b.BBox.Lly) < size*0.5
+	return sameLine && b.BBox.Llx-a.BBox.Urx <= size*0.15
+}
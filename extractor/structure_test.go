@@ -0,0 +1,64 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// TestStructureOrderCreatorTagged checks the structure order of a document tagged by the creator, whose
+// paragraphs are drawn in reverse order and whose parent tree is offset from the MCIDs of the content.
+func TestStructureOrderCreatorTagged(t *testing.T) {
+	f, err := os.Open("testdata/creator_tagged.pdf")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	page, err := reader.GetPage(1)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	ex, err := NewWithOptions(page, &Options{StructureOrder: true})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	pt, _, _, err := ex.ExtractPageText()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// The text of the unlicensed watermark follows the tagged content.
+	const tagged = "First logical\nSecond logical"
+	if text := pt.Text(); !strings.HasPrefix(text, tagged) {
+		t.Fatalf("unexpected text %q", text)
+	}
+	for _, mark := range pt.Marks().Elements() {
+		if mark.Meta || mark.Offset >= len(tagged) {
+			continue
+		}
+		ref := mark.StructElement
+		if ref == nil || ref.Type != "P" {
+			t.Fatalf("mark %q: expected P structure element, got %v", mark.Text, ref)
+		}
+		mcid, _ := core.GetIntVal(ref.Element.Get("K"))
+		if mcid != ref.MCID {
+			t.Fatalf("mark %q: MCID %d is not owned by its element (K %d)", mark.Text, ref.MCID, mcid)
+		}
+	}
+}
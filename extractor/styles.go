@@ -0,0 +1,396 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// TextStyle is the style of a run of text.
+type TextStyle struct {
+	// FontName is the name of the font without the subset prefix, e.g. "Helvetica-BoldOblique".
+	FontName string
+
+	// FontFamily is the family of the font, e.g. "Helvetica".
+	FontFamily string
+
+	// FontSize is the font size of the text.
+	FontSize float64
+
+	// Weight is the weight of the font from 100 (thin) to 900 (black). 400 is normal and 700 is bold.
+	Weight int
+
+	// Italic is true for italic and oblique fonts.
+	Italic bool
+
+	// Underline and Strikethrough are true for text with a line drawn under or through it.
+	Underline, Strikethrough bool
+
+	// Superscript and Subscript are true for text raised above or lowered below the baseline of its line.
+	Superscript, Subscript bool
+
+	// Color is the fill color of the text.
+	Color color.Color
+}
+
+// Bold returns true if the weight of the font is bold or heavier.
+func (s TextStyle) Bold() bool {
+	return s.Weight >= 600
+}
+
+// equals returns true if `s` and `o` are the same style.
+func (s TextStyle) equals(o TextStyle) bool {
+	if s.FontName != o.FontName || s.FontFamily != o.FontFamily || math.Abs(s.FontSize-o.FontSize) > 0.25 ||
+		s.Weight != o.Weight || s.Italic != o.Italic || s.Underline != o.Underline ||
+		s.Strikethrough != o.Strikethrough || s.Superscript != o.Superscript || s.Subscript != o.Subscript {
+		return false
+	}
+	if s.Color == nil || o.Color == nil {
+		return s.Color == o.Color
+	}
+	r1, g1, b1, a1 := s.Color.RGBA()
+	r2, g2, b2, a2 := o.Color.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+// StyledText is a run of text in a single style.
+type StyledText struct {
+	// Text is the extracted text of the run.
+	Text string
+
+	// BBox is the bounding box of the run.
+	BBox model.PdfRectangle
+
+	// Style is the style of the run.
+	Style TextStyle
+
+	// Marks are the TextMarks of the run.
+	Marks TextMarkArray
+}
+
+// StyledParagraph is a paragraph of the text of a page with the styles of its text.
+type StyledParagraph struct {
+	TextParagraph
+
+	// Style is the style of most of the text of the paragraph.
+	Style TextStyle
+
+	// Runs are the runs of text of the paragraph in a single style, including the spaces and line breaks
+	// between them. The texts of the runs add up to the text of the paragraph.
+	Runs []StyledText
+}
+
+// StyledWords returns the words of the page text with their styles. Words are split where the style
+// changes, e.g. at the superscript of "mc²".
+// Underlines and strikethroughs are lines or thin filled rectangles drawn under or through the text.
+// Superscripts and subscripts are text raised or lowered from the baseline of the text of its line.
+func (pt PageText) StyledWords() []StyledText {
+	marks := pt.Marks().Elements()
+	styles := pt.markStyles(marks, pt.textRules())
+	var words []StyledText
+	var word *StyledText
+	for i, mark := range marks {
+		if mark.Meta || strings.TrimSpace(mark.Text) == "" {
+			word = nil
+			continue
+		}
+		if word != nil {
+			last := word.Marks._edfaa[len(word.Marks._edfaa)-1]
+			if !word.Style.equals(styles[i]) || !marksAdjacent(last, mark) && !scriptAdjacent(last, mark) {
+				word = nil
+			}
+		}
+		if word == nil {
+			words = append(words, StyledText{BBox: mark.BBox, Style: styles[i]})
+			word = &words[len(words)-1]
+		}
+		word.Text += mark.Text
+		word.BBox = unionDocumentBBox(word.BBox, mark.BBox)
+		word.Marks._edfaa = append(word.Marks._edfaa, mark)
+	}
+	return words
+}
+
+// StyledParagraphs returns the paragraphs of the page text in reading order with the styles of their
+// text. See StyledWords for the detection of the styles.
+func (pt PageText) StyledParagraphs() []StyledParagraph {
+	paras := pt.Paragraphs()
+	rules := pt.textRules()
+	result := make([]StyledParagraph, len(paras))
+	for i, para := range paras {
+		marks := para.Marks.Elements()
+		styles := pt.markStyles(marks, rules)
+		sp := StyledParagraph{TextParagraph: para}
+		var run *StyledText
+		weights := make([]int, 0, len(marks))
+		var dominant []TextStyle
+		for j, mark := range marks {
+			if !mark.Meta {
+				if run == nil || !run.Style.equals(styles[j]) {
+					sp.Runs = append(sp.Runs, StyledText{BBox: mark.BBox, Style: styles[j]})
+					run = &sp.Runs[len(sp.Runs)-1]
+				}
+				run.BBox = unionDocumentBBox(run.BBox, mark.BBox)
+
+				k := 0
+				for k < len(dominant) && !dominant[k].equals(styles[j]) {
+					k++
+				}
+				if k == len(dominant) {
+					dominant = append(dominant, styles[j])
+					weights = append(weights, 0)
+				}
+				weights[k] += utf8.RuneCountInString(mark.Text)
+			} else if run == nil {
+				continue
+			}
+			run.Text += mark.Text
+			run.Marks._edfaa = append(run.Marks._edfaa, mark)
+		}
+		best := -1
+		for k, w := range weights {
+			if best < 0 || w > weights[best] {
+				best = k
+			}
+		}
+		if best >= 0 {
+			sp.Style = dominant[best]
+		}
+		result[i] = sp
+	}
+	return result
+}
+
+// scriptAdjacent returns true if `b` is a superscript or subscript directly following `a`, or vice versa.
+func scriptAdjacent(a, b TextMark) bool {
+	gap := b.BBox.Llx - a.BBox.Urx
+	size := math.Max(a.FontSize, b.FontSize)
+	return gap > -0.1*size && gap < 0.1*size && a.BBox.Lly < b.BBox.Ury && b.BBox.Lly < a.BBox.Ury
+}
+
+// textRule is a horizontal line or thin filled rectangle that may underline or strike through text.
+type textRule struct {
+	llx, urx, y, thickness float64
+}
+
+// textRules returns the horizontal lines and thin filled rectangles of the page.
+func (pt PageText) textRules() []textRule {
+	var rules []textRule
+	for _, section := range pt._fbdg {
+		for _, path := range section._aggg {
+			for i := 1; i < len(path._gcfa); i++ {
+				p, q := path._gcfa[i-1], path._gcfa[i]
+				if math.Abs(p.Y-q.Y) < 0.5 && math.Abs(p.X-q.X) > 1 {
+					rules = append(rules, textRule{llx: math.Min(p.X, q.X), urx: math.Max(p.X, q.X), y: (p.Y + q.Y) / 2, thickness: 1})
+				}
+			}
+		}
+	}
+	for _, section := range pt._gggf {
+		for _, path := range section._aggg {
+			if len(path._gcfa) == 0 {
+				continue
+			}
+			r := model.PdfRectangle{Llx: path._gcfa[0].X, Urx: path._gcfa[0].X, Lly: path._gcfa[0].Y, Ury: path._gcfa[0].Y}
+			for _, p := range path._gcfa[1:] {
+				r.Llx, r.Urx = math.Min(r.Llx, p.X), math.Max(r.Urx, p.X)
+				r.Lly, r.Ury = math.Min(r.Lly, p.Y), math.Max(r.Ury, p.Y)
+			}
+			if h := r.Height(); h <= 3 && r.Width() > 3*h {
+				rules = append(rules, textRule{llx: r.Llx, urx: r.Urx, y: (r.Lly + r.Ury) / 2, thickness: h})
+			}
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].y < rules[j].y })
+	return rules
+}
+
+// markStyles returns the styles of `marks`, which are consecutive marks of the page text. The styles of
+// meta marks are zero.
+func (pt PageText) markStyles(marks []TextMark, rules []textRule) []TextStyle {
+	styles := make([]TextStyle, len(marks))
+	for i, mark := range marks {
+		if !mark.Meta {
+			styles[i] = fontStyle(mark.Font)
+			styles[i].FontSize = mark.FontSize
+			styles[i].Color = mark.FillColor
+		}
+	}
+
+	// The marks of a line are raised or lowered relative to the baseline of its most common font size.
+	for start := 0; start < len(marks); {
+		end := start + 1
+		for end < len(marks) && !newTextLine(marks[end-1], marks[end]) {
+			end++
+		}
+		size, base := lineBaseline(marks[start:end])
+		for i := start; i < end; i++ {
+			mark := marks[i]
+			if mark.Meta || size == 0 {
+				continue
+			}
+			shift := mark.BBox.Lly - base
+			smaller := mark.FontSize < 0.9*size
+			switch {
+			case shift > 0.25*size || smaller && shift > 0.1*size:
+				styles[i].Superscript = true
+			case shift < -0.25*size || smaller && shift < -0.08*size:
+				styles[i].Subscript = true
+			}
+		}
+		start = end
+	}
+
+	// The baseline of a mark is the bottom of its bounding box.
+	for i, mark := range marks {
+		if mark.Meta || mark.BBox.Width() <= 0 {
+			continue
+		}
+		size := mark.FontSize
+		lo := sort.Search(len(rules), func(k int) bool { return rules[k].y >= mark.BBox.Lly-0.35*size })
+		for _, rule := range rules[lo:] {
+			if rule.y > mark.BBox.Lly+0.6*size {
+				break
+			}
+			if rule.thickness > 0.15*size {
+				continue
+			}
+			overlap := math.Min(rule.urx, mark.BBox.Urx) - math.Max(rule.llx, mark.BBox.Llx)
+			if overlap < 0.5*mark.BBox.Width() {
+				continue
+			}
+			if rule.y <= mark.BBox.Lly+0.05*size {
+				styles[i].Underline = true
+			} else if rule.y >= mark.BBox.Lly+0.2*size {
+				styles[i].Strikethrough = true
+			}
+		}
+	}
+	return styles
+}
+
+// newTextLine returns true if mark `b` starts a new line after mark `a`.
+func newTextLine(a, b TextMark) bool {
+	if a.Meta && a.Text == "\n" {
+		return true
+	}
+	if a.Meta || b.Meta {
+		return false
+	}
+	return b.BBox.Llx < a.BBox.Llx && (b.BBox.Ury < a.BBox.Lly || b.BBox.Lly > a.BBox.Ury)
+}
+
+// lineBaseline returns the most common font size of the marks of a line and the median baseline of the
+// marks of that size.
+func lineBaseline(marks []TextMark) (float64, float64) {
+	counts := map[float64]int{}
+	for _, mark := range marks {
+		if !mark.Meta {
+			counts[math.Round(mark.FontSize*2)/2] += utf8.RuneCountInString(mark.Text)
+		}
+	}
+	size, best := 0.0, 0
+	for s, n := range counts {
+		if n > best || n == best && s > size {
+			size, best = s, n
+		}
+	}
+	var bases []float64
+	for _, mark := range marks {
+		if !mark.Meta && math.Round(mark.FontSize*2)/2 == size {
+			bases = append(bases, mark.BBox.Lly)
+		}
+	}
+	if len(bases) == 0 {
+		return 0, 0
+	}
+	sort.Float64s(bases)
+	return size, bases[len(bases)/2]
+}
+
+// fontStyle returns the name, family, weight and slant of `font`.
+func fontStyle(font *model.PdfFont) TextStyle {
+	if font == nil {
+		return TextStyle{Weight: 400}
+	}
+	name := font.BaseFont()
+	if i := strings.IndexByte(name, '+'); i == 6 {
+		name = name[i+1:]
+	}
+	style := TextStyle{FontName: name, Weight: fontWeight(font)}
+	lower := strings.ToLower(name)
+	for _, slant := range []string{"italic", "oblique", "slanted", "inclined"} {
+		if strings.Contains(lower, slant) {
+			style.Italic = true
+		}
+	}
+	family := name
+	if i := strings.IndexAny(family, "-,"); i > 0 {
+		family = family[:i]
+	}
+	for _, suffix := range []string{"PSMT", "MT", "PS"} {
+		if f := strings.TrimSuffix(family, suffix); f != family && f != "" {
+			family = f
+			break
+		}
+	}
+	style.FontFamily = family
+	if descriptor := font.FontDescriptor(); descriptor != nil {
+		if f, ok := core.GetStringVal(descriptor.FontFamily); ok && f != "" {
+			style.FontFamily = f
+		}
+		if flags, ok := core.GetIntVal(descriptor.Flags); ok && flags&(1<<6) != 0 {
+			style.Italic = true
+		}
+		if angle, err := core.GetNumberAsFloat(descriptor.ItalicAngle); err == nil && angle != 0 {
+			style.Italic = true
+		}
+	}
+	return style
+}
+
+// _ddWeights are the font weights of the weight names in font names, longest names first.
+var _ddWeights = []struct {
+	name   string
+	weight int
+}{
+	{"extralight", 200}, {"ultralight", 200}, {"extrabold", 800}, {"ultrabold", 800},
+	{"semibold", 600}, {"demibold", 600}, {"medium", 500}, {"black", 900}, {"heavy", 800},
+	{"light", 300}, {"bold", 700}, {"demi", 600}, {"thin", 100},
+}
+
+// fontWeight returns the weight of `font` from its font descriptor or, failing that, its name.
+func fontWeight(font *model.PdfFont) int {
+	if font == nil {
+		return 400
+	}
+	if descriptor := font.FontDescriptor(); descriptor != nil {
+		if weight, err := core.GetNumberAsFloat(descriptor.FontWeight); err == nil && weight >= 100 && weight <= 900 {
+			return int(weight)
+		}
+		if flags, ok := core.GetIntVal(descriptor.Flags); ok && flags&(1<<18) != 0 {
+			return 700
+		}
+	}
+	name := strings.ToLower(font.BaseFont())
+	for _, w := range _ddWeights {
+		if strings.Contains(name, w.name) {
+			return w.weight
+		}
+	}
+	return 400
+}
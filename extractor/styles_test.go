@@ -0,0 +1,106 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/creator"
+)
+
+// drawStyledText draws a paragraph having underlined, superscript and
+// subscript text.
+func drawStyledText(c *creator.Creator) {
+	p := c.NewStyledParagraph()
+	p.Append("Plain and ")
+	p.Append("underlined").Style.Underline = true
+	p.Append(" text, E = mc")
+	sup := p.Append("2")
+	sup.Style.FontSize = 6
+	sup.Style.TextRise = 5
+	p.Append(" and H")
+	sub := p.Append("2")
+	sub.Style.FontSize = 6
+	sub.Style.TextRise = -2
+	p.Append("O.")
+	c.Draw(p)
+}
+
+func TestStyledWords(t *testing.T) {
+	pt := creatorPageTexts(t, drawStyledText)[0]
+	words := map[string]TextStyle{}
+	var order []string
+	for _, word := range pt.StyledWords() {
+		words[word.Text] = word.Style
+		order = append(order, word.Text)
+	}
+
+	check := func(text string, valid func(s TextStyle) bool) {
+		t.Helper()
+		style, ok := words[text]
+		if !ok {
+			t.Fatalf("word %q not found in %q", text, order)
+		}
+		if !valid(style) {
+			t.Fatalf("unexpected style of %q: %+v", text, style)
+		}
+	}
+	check("Plain", func(s TextStyle) bool {
+		return !s.Bold() && !s.Underline && !s.Superscript && !s.Subscript && s.FontSize == 10
+	})
+	check("underlined", func(s TextStyle) bool { return s.Underline && !s.Strikethrough })
+	check("mc", func(s TextStyle) bool { return !s.Superscript && !s.Subscript && !s.Underline })
+	check("H", func(s TextStyle) bool { return !s.Superscript && !s.Subscript })
+
+	// The superscript of "mc²" and the subscript of "H₂O" are separate words.
+	var scripts []TextStyle
+	for _, word := range pt.StyledWords() {
+		if word.Text == "2" {
+			scripts = append(scripts, word.Style)
+		}
+	}
+	if len(scripts) != 2 {
+		t.Fatalf("expected 2 script words, got %d in %q", len(scripts), order)
+	}
+	if !scripts[0].Superscript || scripts[0].Subscript || scripts[0].FontSize != 6 {
+		t.Fatalf("expected a superscript, got %+v", scripts[0])
+	}
+	if !scripts[1].Subscript || scripts[1].Superscript {
+		t.Fatalf("expected a subscript, got %+v", scripts[1])
+	}
+}
+
+func TestStyledParagraphs(t *testing.T) {
+	pt := creatorPageTexts(t, drawStyledText)[0]
+	paras := pt.StyledParagraphs()
+	if len(paras) != 1 {
+		t.Fatalf("expected 1 paragraph, got %d", len(paras))
+	}
+	para := paras[0]
+	if para.Style.Underline || para.Style.Superscript || para.Style.FontSize != 10 {
+		t.Fatalf("unexpected dominant style %+v", para.Style)
+	}
+
+	var text string
+	var underlined []string
+	for _, run := range para.Runs {
+		text += run.Text
+		if run.Style.Underline {
+			underlined = append(underlined, strings.TrimSpace(run.Text))
+		}
+	}
+	if text != para.Text {
+		t.Fatalf("runs %q do not add up to the paragraph text %q", text, para.Text)
+	}
+	if len(underlined) != 1 || underlined[0] != "underlined" {
+		t.Fatalf("unexpected underlined runs %q", underlined)
+	}
+}
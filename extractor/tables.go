@@ -0,0 +1,749 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// TableDetectionOptions are the options of the detection of borderless tables from the alignment of text.
+type TableDetectionOptions struct {
+	// MinRows is the minimum number of rows with text in more than one column.
+	MinRows int
+
+	// MinColumns is the minimum number of columns.
+	MinColumns int
+
+	// ColumnGap is the minimum horizontal gap between the columns of a table, as a ratio of the font size.
+	ColumnGap float64
+
+	// MaxWordsPerCell is the maximum average number of words of the cells. Aligned text with longer runs
+	// of words is taken to be multi-column text rather than a table.
+	MaxWordsPerCell float64
+}
+
+// DefaultTableDetectionOptions returns the default options of the detection of borderless tables.
+func DefaultTableDetectionOptions() *TableDetectionOptions {
+	return &TableDetectionOptions{MinRows: 3, MinColumns: 2, ColumnGap: 0.9, MaxWordsPerCell: 5}
+}
+
+// DetectTables returns the tables of the page: the tables found by Tables, followed by the borderless
+// tables detected from the alignment of the text into columns separated by white space. The bounding
+// boxes of the tables found by Tables are computed from their cells if missing, and their short tables
+// whose rows are far apart, such as the axis labels of charts, are omitted. Rows of the borderless tables
+// that continue the text of the previous row, such as wrapped descriptions, are merged with it. Text
+// spanning several columns is returned as merged cells.
+func (pt PageText) DetectTables(options *TableDetectionOptions) []TextTable {
+	if options == nil {
+		options = DefaultTableDetectionOptions()
+	}
+	var tables []TextTable
+	for _, table := range pt.Tables() {
+		if sparseRows(table, options.MinRows) {
+			continue
+		}
+		table.PdfRectangle = tableBBox(table)
+		tables = append(tables, table)
+	}
+	ruled := len(tables)
+	lines := tableLines(tableWords(pt.Marks().Elements()), options.ColumnGap)
+	for i := 0; i < len(lines); {
+		if len(lines[i].segments) < 2 {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(lines) && lines[j-1].near(lines[j]) {
+			if len(lines[j].segments) < 2 && !(j+1 < len(lines) && lines[j].near(lines[j+1]) && len(lines[j+1].segments) >= 2) {
+				break
+			}
+			j++
+		}
+		table, ok := alignedTable(lines[i:j], options)
+		if ok && !overlapsTables(table.PdfRectangle, tables[:ruled]) {
+			tables = append(tables, table)
+		}
+		i = j
+	}
+	return tables
+}
+
+// ParagraphsWithTables returns the paragraphs of the page text in reading order, as returned by Paragraphs,
+// with the borderless tables found by DetectTables as table paragraphs. The text of the cells of these
+// tables is removed from the paragraphs it was extracted to, and the table paragraph is placed at the
+// position of the first of these paragraphs.
+func (pt PageText) ParagraphsWithTables(options *TableDetectionOptions) []TextParagraph {
+	paras := pt.Paragraphs()
+	var known []TextTable
+	for _, para := range paras {
+		if para.Table != nil {
+			table := *para.Table
+			table.PdfRectangle = tableBBox(table)
+			known = append(known, table)
+		}
+	}
+
+	for _, table := range pt.DetectTables(options) {
+		if overlapsTables(table.PdfRectangle, known) {
+			continue
+		}
+		inTable := map[tableMarkKey]bool{}
+		tablePara := TextParagraph{BBox: table.PdfRectangle, Table: &table}
+		var texts []string
+		for _, row := range table.Cells {
+			var cells []string
+			for _, cell := range row {
+				for _, mark := range cell.Marks.Elements() {
+					inTable[markKey(mark)] = true
+					tablePara.Marks.Append(mark)
+				}
+				if text := strings.TrimSpace(cell.Text); text != "" {
+					cells = append(cells, text)
+				}
+			}
+			texts = append(texts, strings.Join(cells, " "))
+		}
+		tablePara.Text = strings.Join(texts, "\n")
+
+		result := make([]TextParagraph, 0, len(paras)+2)
+		placed := false
+		for _, para := range paras {
+			before, after, removed := splitParagraph(para, inTable)
+			result = append(result, before...)
+			if removed && !placed {
+				result = append(result, tablePara)
+				placed = true
+			}
+			result = append(result, after...)
+		}
+		if placed {
+			paras = result
+			known = append(known, table)
+		}
+	}
+	return paras
+}
+
+// tableMarkKey identifies the text marks of a page.
+type tableMarkKey struct {
+	bbox model.PdfRectangle
+	text string
+}
+
+func markKey(mark TextMark) tableMarkKey {
+	return tableMarkKey{bbox: mark.BBox, text: mark.Text}
+}
+
+// splitParagraph returns the parts of the paragraph before and after the marks in `marks`, and whether
+// the paragraph has any of these marks. The paragraph is returned unchanged in `before` otherwise.
+func splitParagraph(para TextParagraph, marks map[tableMarkKey]bool) (before, after []TextParagraph, removed bool) {
+	if para.Table != nil {
+		return []TextParagraph{para}, nil, false
+	}
+	var run []TextMark
+	flush := func() {
+		if part, ok := paragraphOf(run); ok {
+			if removed {
+				after = append(after, part)
+			} else {
+				before = append(before, part)
+			}
+		}
+		run = nil
+	}
+	for _, mark := range para.Marks.Elements() {
+		if !mark.Meta && marks[markKey(mark)] {
+			flush()
+			removed = true
+			continue
+		}
+		run = append(run, mark)
+	}
+	if !removed {
+		return []TextParagraph{para}, nil, false
+	}
+	flush()
+	return before, after, true
+}
+
+// paragraphOf returns the paragraph of the marks, without the spaces and line breaks at their ends.
+// Returns false if the marks have no text.
+func paragraphOf(marks []TextMark) (TextParagraph, bool) {
+	for len(marks) > 0 && (marks[0].Meta || strings.TrimSpace(marks[0].Text) == "") {
+		marks = marks[1:]
+	}
+	for len(marks) > 0 && (marks[len(marks)-1].Meta || strings.TrimSpace(marks[len(marks)-1].Text) == "") {
+		marks = marks[:len(marks)-1]
+	}
+	if len(marks) == 0 {
+		return TextParagraph{}, false
+	}
+	para := TextParagraph{Marks: TextMarkArray{_edfaa: marks}}
+	var text strings.Builder
+	for _, mark := range marks {
+		text.WriteString(mark.Text)
+	}
+	para.Text = text.String()
+	para.BBox, _ = para.Marks.BBox()
+	return para, true
+}
+
+// tableWord is a word of the text of a page.
+type tableWord struct {
+	bbox  model.PdfRectangle
+	text  string
+	marks []TextMark
+	size  float64
+}
+
+// tableSegment is a run of words of a line separated by less than the column gap.
+type tableSegment struct {
+	bbox  model.PdfRectangle
+	words []*tableWord
+}
+
+func (s *tableSegment) text() string {
+	texts := make([]string, len(s.words))
+	for i, w := range s.words {
+		texts[i] = w.text
+	}
+	return strings.Join(texts, " ")
+}
+
+// tableLine is a line of words, split into segments.
+type tableLine struct {
+	bbox     model.PdfRectangle
+	words    []*tableWord
+	segments []*tableSegment
+}
+
+// near returns true if line `next` directly follows the line.
+func (l *tableLine) near(next *tableLine) bool {
+	height := math.Max(l.bbox.Height(), next.bbox.Height())
+	return l.bbox.Lly-next.bbox.Ury < 1.5*height
+}
+
+// tableWords returns the words of the marks. Words end at spaces and line breaks, and between marks that
+// are not adjacent.
+func tableWords(marks []TextMark) []*tableWord {
+	var words []*tableWord
+	var word *tableWord
+	for _, mark := range marks {
+		if mark.Meta || strings.TrimSpace(mark.Text) == "" {
+			word = nil
+			continue
+		}
+		if word != nil && !marksAdjacent(word.marks[len(word.marks)-1], mark) {
+			word = nil
+		}
+		if word == nil {
+			word = &tableWord{bbox: mark.BBox}
+			words = append(words, word)
+		}
+		word.text += mark.Text
+		word.marks = append(word.marks, mark)
+		word.bbox = unionDocumentBBox(word.bbox, mark.BBox)
+		word.size = math.Max(word.size, mark.FontSize)
+	}
+	return words
+}
+
+// tableLines groups words into lines from top to bottom, and splits the lines into segments at gaps of at
+// least `gap` times the font size.
+func tableLines(words []*tableWord, gap float64) []*tableLine {
+	sorted := append([]*tableWord(nil), words...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].bbox.Ury > sorted[j].bbox.Ury })
+	var lines []*tableLine
+	for _, w := range sorted {
+		center := (w.bbox.Lly + w.bbox.Ury) / 2
+		var line *tableLine
+		if n := len(lines); n > 0 {
+			last := lines[n-1]
+			if tol := 0.5 * math.Min(w.bbox.Height(), last.bbox.Height()); math.Abs(center-(last.bbox.Lly+last.bbox.Ury)/2) < tol {
+				line = last
+			}
+		}
+		if line == nil {
+			line = &tableLine{bbox: w.bbox}
+			lines = append(lines, line)
+		}
+		line.words = append(line.words, w)
+		line.bbox = unionDocumentBBox(line.bbox, w.bbox)
+	}
+	for _, line := range lines {
+		sort.SliceStable(line.words, func(i, j int) bool { return line.words[i].bbox.Llx < line.words[j].bbox.Llx })
+		var seg *tableSegment
+		for _, w := range line.words {
+			if seg != nil {
+				last := seg.words[len(seg.words)-1]
+				if w.bbox.Llx-last.bbox.Urx >= gap*math.Max(w.size, last.size) {
+					seg = nil
+				}
+			}
+			if seg == nil {
+				seg = &tableSegment{bbox: w.bbox}
+				line.segments = append(line.segments, seg)
+			}
+			seg.words = append(seg.words, w)
+			seg.bbox = unionDocumentBBox(seg.bbox, w.bbox)
+		}
+	}
+	return lines
+}
+
+// alignedTable returns the table of a run of lines if their segments are aligned in columns.
+// The columns are taken from the rows with the most common number of segments.
+func alignedTable(lines []*tableLine, options *TableDetectionOptions) (TextTable, bool) {
+	counts := map[int]int{}
+	multi, words, segments := 0, 0, 0
+	for _, line := range lines {
+		if n := len(line.segments); n >= 2 {
+			counts[n]++
+			multi++
+		}
+		words += len(line.words)
+		segments += len(line.segments)
+	}
+	cols, best := 0, 0
+	for n, count := range counts {
+		if count > best || count == best && n > cols {
+			cols, best = n, count
+		}
+	}
+	if multi < options.MinRows || cols < options.MinColumns ||
+		float64(words)/float64(segments) > options.MaxWordsPerCell {
+		return TextTable{}, false
+	}
+
+	// The columns are the union of the segments of the full rows, which must not overlap.
+	columns := make([]model.PdfRectangle, cols)
+	first := true
+	for _, line := range lines {
+		if len(line.segments) != cols {
+			continue
+		}
+		for k, seg := range line.segments {
+			if first {
+				columns[k] = seg.bbox
+			} else {
+				columns[k] = unionDocumentBBox(columns[k], seg.bbox)
+			}
+		}
+		first = false
+	}
+	for k := 1; k < cols; k++ {
+		if columns[k].Llx <= columns[k-1].Urx {
+			return TextTable{}, false
+		}
+	}
+
+	// Assign the segments of each line to the columns they overlap.
+	type cell struct {
+		segs []*tableSegment
+		span int
+	}
+	var rows [][]*cell
+	for _, line := range lines {
+		row := make([]*cell, cols)
+		covered := make([]bool, cols)
+		for _, seg := range line.segments {
+			a, b := -1, -1
+			for k, col := range columns {
+				if seg.bbox.Llx < col.Urx && seg.bbox.Urx > col.Llx {
+					if a < 0 {
+						a = k
+					}
+					b = k
+				}
+			}
+			if a < 0 {
+				center := (seg.bbox.Llx + seg.bbox.Urx) / 2
+				best := math.Inf(1)
+				for k, col := range columns {
+					if d := math.Min(math.Abs(center-col.Llx), math.Abs(center-col.Urx)); d < best {
+						a, b, best = k, k, d
+					}
+				}
+			}
+			for covered[a] && a > 0 && row[a] == nil {
+				a--
+			}
+			if row[a] == nil {
+				row[a] = &cell{span: 1}
+			}
+			row[a].segs = append(row[a].segs, seg)
+			if span := b - a + 1; span > row[a].span {
+				row[a].span = span
+				for k := a + 1; k <= b; k++ {
+					covered[k] = true
+				}
+			}
+		}
+
+		// Lines without text in the first column and in most columns continue the previous row.
+		filled := 0
+		for _, c := range row {
+			if c != nil {
+				filled++
+			}
+		}
+		if len(rows) > 0 && row[0] == nil && 2*filled < cols {
+			prev := rows[len(rows)-1]
+			for k, c := range row {
+				if c == nil {
+					continue
+				}
+				owner := k
+				for owner > 0 && prev[owner] == nil {
+					owner--
+				}
+				if prev[owner] == nil {
+					prev[owner] = &cell{span: 1}
+				}
+				prev[owner].segs = append(prev[owner].segs, c.segs...)
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	// Lines merged into the previous rows, such as the axis labels and legends of charts, do not count
+	// towards the minimum number of rows.
+	if len(rows) < options.MinRows {
+		return TextTable{}, false
+	}
+
+	table := TextTable{W: cols, H: len(rows), Cells: make([][]TableCell, len(rows))}
+	table.PdfRectangle = lines[0].bbox
+	for _, line := range lines[1:] {
+		table.PdfRectangle = unionDocumentBBox(table.PdfRectangle, line.bbox)
+	}
+	for y, row := range rows {
+		table.Cells[y] = make([]TableCell, cols)
+		for x, c := range row {
+			if c == nil {
+				continue
+			}
+			tc := &table.Cells[y][x]
+			texts := make([]string, len(c.segs))
+			for i, seg := range c.segs {
+				texts[i] = seg.text()
+				if i == 0 {
+					tc.PdfRectangle = seg.bbox
+				} else {
+					tc.PdfRectangle = unionDocumentBBox(tc.PdfRectangle, seg.bbox)
+				}
+				for _, w := range seg.words {
+					tc.Marks._edfaa = append(tc.Marks._edfaa, w.marks...)
+				}
+			}
+			tc.Text = strings.Join(texts, " ")
+			if c.span > 1 {
+				tc.ColSpan = c.span
+			}
+		}
+	}
+	return table, true
+}
+
+// tableBBox returns the bounding box of a table. The tables returned by Tables may have an empty bounding
+// box, in which case the bounding box of their cells is returned.
+func tableBBox(t TextTable) model.PdfRectangle {
+	if t.Width() > 0 && t.Height() > 0 {
+		return t.PdfRectangle
+	}
+	var bbox model.PdfRectangle
+	found := false
+	for _, row := range t.Cells {
+		if r, ok := rowBBox(row); ok {
+			if found {
+				bbox = unionDocumentBBox(bbox, r)
+			} else {
+				bbox, found = r, true
+			}
+		}
+	}
+	return bbox
+}
+
+// rowBBox returns the bounding box of the cells of a table row, or of their marks for cells without a
+// bounding box. Returns false if the row is empty.
+func rowBBox(row []TableCell) (model.PdfRectangle, bool) {
+	var bbox model.PdfRectangle
+	found := false
+	add := func(r model.PdfRectangle) {
+		if r.Width() <= 0 && r.Height() <= 0 {
+			return
+		}
+		if found {
+			bbox = unionDocumentBBox(bbox, r)
+		} else {
+			bbox, found = r, true
+		}
+	}
+	for _, cell := range row {
+		if cell.Width() > 0 || cell.Height() > 0 {
+			add(cell.PdfRectangle)
+			continue
+		}
+		for _, mark := range cell.Marks.Elements() {
+			add(mark.BBox)
+		}
+	}
+	return bbox, found
+}
+
+// sparseRows returns true if the table has fewer than `minRows` rows and its rows are separated by more
+// than a few line heights, as the rows of text aligned by chance, such as the axis labels of charts drawn
+// one above the other.
+func sparseRows(t TextTable, minRows int) bool {
+	if t.H >= minRows {
+		return false
+	}
+	var prev model.PdfRectangle
+	for y, row := range t.Cells {
+		bbox, ok := rowBBox(row)
+		if !ok {
+			continue
+		}
+		if y > 0 && prev.Lly-bbox.Ury > 4*math.Max(prev.Height(), bbox.Height()) {
+			return true
+		}
+		prev = bbox
+	}
+	return false
+}
+
+// overlapsTables returns true if more than half of `bbox` is covered by one of `tables`.
+func overlapsTables(bbox model.PdfRectangle, tables []TextTable) bool {
+	area := bbox.Width() * bbox.Height()
+	for _, t := range tables {
+		w := math.Min(bbox.Urx, t.Urx) - math.Max(bbox.Llx, t.Llx)
+		h := math.Min(bbox.Ury, t.Ury) - math.Max(bbox.Lly, t.Lly)
+		if w > 0 && h > 0 && w*h > area/2 {
+			return true
+		}
+	}
+	return false
+}
+
+// MultiPageTable is a logical table whose parts are on consecutive pages.
+type MultiPageTable struct {
+	// TextTable holds the rows of all parts. Its bounding box is the bounding box of the first part.
+	TextTable
+
+	// Pages are the numbers of the pages of the parts, starting from 1.
+	Pages []int
+
+	// HeaderRows is the number of header rows repeated at the top of the parts, which are included once.
+	HeaderRows int
+}
+
+// TableStitchOptions are the options of stitching tables across pages.
+type TableStitchOptions struct {
+	// MaxHeaderRows is the maximum number of header rows repeated on each page.
+	MaxHeaderRows int
+
+	// RequireHeaders specifies whether tables are stitched only if their header rows are repeated.
+	// Otherwise a table at the top of a page also continues the table at the bottom of the previous page if
+	// their columns are aligned.
+	RequireHeaders bool
+
+	// ColumnTolerance is the maximum difference of the positions of the columns of tables stitched without
+	// repeated header rows.
+	ColumnTolerance float64
+}
+
+// DefaultTableStitchOptions returns the default options of stitching tables across pages.
+func DefaultTableStitchOptions() *TableStitchOptions {
+	return &TableStitchOptions{MaxHeaderRows: 3, ColumnTolerance: 3}
+}
+
+// StitchTables stitches the tables of consecutive pages into logical tables. `pages` are the tables of
+// each page, e.g. as returned by PageText.DetectTables. The bottom table of a page is continued by the
+// top table of the next page if they have the same number of columns and their header rows match, or if
+// their columns are aligned and options.RequireHeaders is false.
+func StitchTables(pages [][]TextTable, options *TableStitchOptions) []MultiPageTable {
+	if options == nil {
+		options = DefaultTableStitchOptions()
+	}
+	var result []MultiPageTable
+
+	// open is the index in result of the table at the bottom of the previous page.
+	open := -1
+	for i, tables := range pages {
+		sorted := append([]TextTable(nil), tables...)
+		sort.SliceStable(sorted, func(a, b int) bool { return sorted[a].Ury > sorted[b].Ury })
+		next := -1
+		for j, table := range sorted {
+			if j == 0 && open >= 0 {
+				if headers, ok := continues(&result[open], table, options); ok {
+					mt := &result[open]
+					if len(mt.Pages) == 1 {
+						mt.HeaderRows = headers
+					}
+					mt.Cells = append(mt.Cells, table.Cells[headers:]...)
+					mt.H = len(mt.Cells)
+					mt.Pages = append(mt.Pages, i+1)
+					next = open
+					continue
+				}
+			}
+			result = append(result, MultiPageTable{TextTable: table, Pages: []int{i + 1}})
+			next = len(result) - 1
+		}
+		open = next
+	}
+	return result
+}
+
+// continues returns true and the number of repeated header rows if `table` continues `mt`.
+func continues(mt *MultiPageTable, table TextTable, options *TableStitchOptions) (int, bool) {
+	if mt.W != table.W || table.H == 0 {
+		return 0, false
+	}
+	headers := 0
+	for headers < options.MaxHeaderRows && headers < mt.H && headers < table.H-1 &&
+		rowKey(mt.Cells[headers]) == rowKey(table.Cells[headers]) {
+		headers++
+	}
+	if mt.HeaderRows > 0 && headers != mt.HeaderRows {
+		headers = 0
+	}
+	if headers > 0 {
+		return headers, true
+	}
+	if options.RequireHeaders {
+		return 0, false
+	}
+	a, b := columnLefts(mt.TextTable), columnLefts(table)
+	for x := range a {
+		if !math.IsInf(a[x], 1) && !math.IsInf(b[x], 1) && math.Abs(a[x]-b[x]) > options.ColumnTolerance {
+			return 0, false
+		}
+	}
+	return 0, true
+}
+
+// rowKey returns the normalized text of a row.
+func rowKey(row []TableCell) string {
+	texts := make([]string, len(row))
+	for i, cell := range row {
+		texts[i] = strings.ToLower(strings.Join(strings.Fields(cell.Text), " "))
+	}
+	return strings.Join(texts, "|")
+}
+
+// columnLefts returns the left edge of the text of each column of a table, +Inf for empty columns.
+func columnLefts(t TextTable) []float64 {
+	lefts := make([]float64, t.W)
+	for x := range lefts {
+		lefts[x] = math.Inf(1)
+	}
+	for _, row := range t.Cells {
+		for x, cell := range row {
+			if x < t.W && strings.TrimSpace(cell.Text) != "" && cell.ColSpan <= 1 && cell.Width() > 0 {
+				lefts[x] = math.Min(lefts[x], cell.Llx)
+			}
+		}
+	}
+	return lefts
+}
+
+// TableExtractionOptions are the options of ExtractTables.
+type TableExtractionOptions struct {
+	// Options are the options used to extract the text of the pages.
+	Options *Options
+
+	// Detection are the options of the detection of borderless tables.
+	Detection *TableDetectionOptions
+
+	// Stitch are the options of stitching tables across pages.
+	Stitch *TableStitchOptions
+}
+
+// ExtractTables returns the tables of the document of `reader`, both ruled and borderless, with the tables
+// continued across pages stitched into single logical tables.
+func ExtractTables(reader *model.PdfReader, options *TableExtractionOptions) ([]MultiPageTable, error) {
+	if options == nil {
+		options = &TableExtractionOptions{}
+	}
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	pages := make([][]TextTable, numPages)
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return nil, err
+		}
+		ex, err := NewWithOptions(page, options.Options)
+		if err != nil {
+			return nil, err
+		}
+		pt, _, _, err := ex.ExtractPageText()
+		if err != nil {
+			return nil, err
+		}
+		pages[i-1] = pt.DetectTables(options.Detection)
+	}
+	return StitchTables(pages, options.Stitch), nil
+}
+
+// CellRange is the range of grid positions of a merged table cell.
+type CellRange struct {
+	Row, Col         int
+	RowSpan, ColSpan int
+}
+
+// MergedCells returns the ranges of the merged cells of the table.
+func (t TextTable) MergedCells() []CellRange {
+	var ranges []CellRange
+	for y, row := range t.Cells {
+		for x, cell := range row {
+			if cell.RowSpan > 1 || cell.ColSpan > 1 {
+				ranges = append(ranges, CellRange{Row: y, Col: x, RowSpan: max(cell.RowSpan, 1), ColSpan: max(cell.ColSpan, 1)})
+			}
+		}
+	}
+	return ranges
+}
+
+// Records returns the text of the cells of the table as records of W fields, as used by CSV files and
+// spreadsheets. The text of merged cells is in the field of their top left position, and the fields of
+// the other positions they cover are empty. See MergedCells for the ranges of merged cells.
+func (t TextTable) Records() [][]string {
+	records := make([][]string, len(t.Cells))
+	for y, row := range t.Cells {
+		records[y] = make([]string, t.W)
+		for x, cell := range row {
+			if x < t.W {
+				records[y][x] = strings.TrimSpace(cell.Text)
+			}
+		}
+	}
+	return records
+}
+
+// WriteCSV writes the records of the table to `w` in CSV format.
+func (t TextTable) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(t.Records()); err != nil {
+		return err
+	}
+	return cw.Error()
+}
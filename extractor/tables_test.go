@@ -0,0 +1,140 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/creator"
+)
+
+// drawStatement draws a statement table having a header row and the
+// transactions in the [from, to) range. The table has no borders.
+func drawStatement(c *creator.Creator, from, to int) {
+	table := c.NewTable(3)
+	table.SetColumnWidths(0.2, 0.6, 0.2)
+	for _, h := range []string{"Date", "Description", "Amount"} {
+		table.NewCell().SetContent(c.NewParagraph(h))
+	}
+	for i := from; i < to; i++ {
+		table.NewCell().SetContent(c.NewParagraph(fmt.Sprintf("2024-01-%02d", i+1)))
+		table.NewCell().SetContent(c.NewParagraph(fmt.Sprintf("Item %d", i)))
+		table.NewCell().SetContent(c.NewParagraph(fmt.Sprintf("%d.00", 10*i)))
+	}
+	for r := 1; r <= table.Rows(); r++ {
+		table.SetRowHeight(r, 20)
+	}
+	c.Draw(table)
+}
+
+// statementPages returns the text of a two page statement, with the
+// transactions table continued on the second page.
+func statementPages(t *testing.T) []*PageText {
+	return creatorPageTexts(t,
+		func(c *creator.Creator) {
+			heading := c.NewParagraph("Statement")
+			heading.SetMargins(0, 0, 0, 30)
+			c.Draw(heading)
+			drawStatement(c, 0, 15)
+		},
+		func(c *creator.Creator) {
+			drawStatement(c, 15, 25)
+		},
+	)
+}
+
+func TestDetectTablesDeduplication(t *testing.T) {
+	for i, pt := range statementPages(t) {
+		tables := pt.DetectTables(nil)
+		if len(tables) != 1 {
+			t.Fatalf("page %d: expected 1 table, got %d", i+1, len(tables))
+		}
+		table := tables[0]
+		if table.Width() <= 0 || table.Height() <= 0 {
+			t.Fatalf("page %d: expected table bounding box, got %v", i+1, table.PdfRectangle)
+		}
+		if table.W != 3 {
+			t.Fatalf("page %d: expected 3 columns, got %d", i+1, table.W)
+		}
+		if header := rowKey(table.Cells[0]); header != "date|description|amount" {
+			t.Fatalf("page %d: unexpected header %q", i+1, header)
+		}
+	}
+}
+
+func TestStitchTables(t *testing.T) {
+	var pages [][]TextTable
+	for _, pt := range statementPages(t) {
+		pages = append(pages, pt.DetectTables(nil))
+	}
+
+	stitched := StitchTables(pages, nil)
+	if len(stitched) != 1 {
+		t.Fatalf("expected 1 stitched table, got %d", len(stitched))
+	}
+	mt := stitched[0]
+	if len(mt.Pages) != 2 || mt.Pages[0] != 1 || mt.Pages[1] != 2 {
+		t.Fatalf("expected table on pages [1 2], got %v", mt.Pages)
+	}
+	if mt.HeaderRows != 1 {
+		t.Fatalf("expected 1 repeated header row, got %d", mt.HeaderRows)
+	}
+
+	// The header is included once, followed by the transactions of both pages.
+	records := mt.Records()
+	if len(records) != 26 || mt.H != 26 {
+		t.Fatalf("expected 26 rows, got %d (H=%d)", len(records), mt.H)
+	}
+	for i, record := range records[1:] {
+		expected := []string{fmt.Sprintf("2024-01-%02d", i+1), fmt.Sprintf("Item %d", i), fmt.Sprintf("%d.00", 10*i)}
+		if fmt.Sprint(record) != fmt.Sprint(expected) {
+			t.Fatalf("row %d: expected %q, got %q", i+1, expected, record)
+		}
+	}
+}
+
+func TestDetectTablesChartLabels(t *testing.T) {
+	pages := creatorPageTexts(t, func(c *creator.Creator) {
+		for _, kind := range []creator.ChartType{creator.ChartTypeBar, creator.ChartTypeLine} {
+			chart := c.NewVectorChart(kind, 450, 250)
+			chart.SetTitle("Sales")
+			chart.SetCategories("Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep")
+			chart.AddSeries("2023", 10, 20, 30, 25, 15, 35, 40, 45, 50)
+			chart.AddSeries("2024", 12, 22, 28, 27, 18, 33, 44, 41, 52)
+			c.Draw(chart)
+		}
+	})
+
+	if tables := pages[0].DetectTables(nil); len(tables) != 0 {
+		t.Fatalf("expected no tables, got %d: %v", len(tables), tables[0].Records())
+	}
+}
+
+func TestParagraphsWithTables(t *testing.T) {
+	pages := creatorPageTexts(t, drawTableBetweenParagraphs)
+
+	paras := pages[0].ParagraphsWithTables(nil)
+	if len(paras) != 3 {
+		t.Fatalf("expected 3 paragraphs, got %d", len(paras))
+	}
+	if paras[0].Text != "Introduction paragraph before the table." || paras[0].Table != nil {
+		t.Fatalf("unexpected first paragraph %q", paras[0].Text)
+	}
+	if paras[2].Text != "Closing paragraph after the table." || paras[2].Table != nil {
+		t.Fatalf("unexpected last paragraph %q", paras[2].Text)
+	}
+
+	table := paras[1].Table
+	if table == nil {
+		t.Fatalf("expected table paragraph, got %q", paras[1].Text)
+	}
+	checkTableRecords(t, table.Records())
+}
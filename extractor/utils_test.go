@@ -0,0 +1,102 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/creator"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func init() {
+	// Allow text extraction in tests without a license key.
+	_cga = true
+}
+
+// creatorPages draws each page of a document using the specified functions
+// and returns the generated pages.
+func creatorPages(t *testing.T, draw ...func(c *creator.Creator)) []*model.PdfPage {
+	c := creator.New()
+	pages := make([]*model.PdfPage, len(draw))
+	for i, fn := range draw {
+		pages[i] = c.NewPage()
+		fn(c)
+	}
+	if err := c.Finalize(); err != nil {
+		t.Fatalf("unable to finalize document: %v", err)
+	}
+	return pages
+}
+
+// creatorExtractors draws each page of a document using the specified
+// functions and returns extractors of the generated pages.
+func creatorExtractors(t *testing.T, draw ...func(c *creator.Creator)) []*Extractor {
+	var extractors []*Extractor
+	for i, page := range creatorPages(t, draw...) {
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			t.Fatalf("page %d: unable to get contents: %v", i+1, err)
+		}
+		ex, err := NewFromContents(contents, page.Resources)
+		if err != nil {
+			t.Fatalf("page %d: unable to create extractor: %v", i+1, err)
+		}
+		extractors = append(extractors, ex)
+	}
+	return extractors
+}
+
+// creatorPageTexts draws each page of a document using the specified
+// functions and returns the extracted text of the generated pages.
+func creatorPageTexts(t *testing.T, draw ...func(c *creator.Creator)) []*PageText {
+	var texts []*PageText
+	for i, ex := range creatorExtractors(t, draw...) {
+		pt, _, _, err := ex.ExtractPageText()
+		if err != nil {
+			t.Fatalf("page %d: unable to extract text: %v", i+1, err)
+		}
+		texts = append(texts, pt)
+	}
+	return texts
+}
+
+// drawTableBetweenParagraphs draws a table without borders, having 3 columns
+// and 4 rows of cells named "r<row>c<column>", between two paragraphs.
+func drawTableBetweenParagraphs(c *creator.Creator) {
+	c.Draw(c.NewParagraph("Introduction paragraph before the table."))
+	table := c.NewTable(3)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 3; x++ {
+			table.NewCell().SetContent(c.NewParagraph(fmt.Sprintf("r%dc%d", y, x)))
+		}
+	}
+	c.Draw(table)
+	c.Draw(c.NewParagraph("Closing paragraph after the table."))
+}
+
+// checkTableRecords checks that `records` are the cells of the table drawn
+// by drawTableBetweenParagraphs.
+func checkTableRecords(t *testing.T, records [][]string) {
+	if len(records) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(records))
+	}
+	for y, record := range records {
+		if len(record) != 3 {
+			t.Fatalf("row %d: expected 3 cells, got %d", y, len(record))
+		}
+		for x, text := range record {
+			if expected := fmt.Sprintf("r%dc%d", y, x); text != expected {
+				t.Fatalf("cell %d,%d: expected %q, got %q", y, x, expected, text)
+			}
+		}
+	}
+}
@@ -0,0 +1,496 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package extractor
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/internal/transform"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// VectorExtractOptions are the options for extracting the vector graphics of a page.
+type VectorExtractOptions struct {
+	// DisableShapes disables the recognition of shapes in the paths.
+	DisableShapes bool
+
+	// ShapeTolerance is the relative tolerance of the geometry of recognized shapes. The default is 0.05.
+	ShapeTolerance float64
+}
+
+// PageVectors are the vector graphics of a page.
+type PageVectors struct {
+	// Paths are the painted paths of the page in painting order.
+	Paths []*VectorPath
+
+	// Root is the group of the content of the page. Its subgroups hold the paths drawn by form XObjects and
+	// within clipping paths.
+	Root *VectorGroup
+
+	// Shapes are the primitives recognized in the paths.
+	Shapes []Shape
+}
+
+// VectorSegment is a segment of a subpath.
+type VectorSegment struct {
+	// Points are the points of the segment in page coordinates: the start and end points of a line, or the
+	// start point, the two control points and the end point of a cubic Bézier curve.
+	Points []transform.Point
+}
+
+// IsCurve returns true if the segment is a cubic Bézier curve.
+func (s VectorSegment) IsCurve() bool { return len(s.Points) == 4 }
+
+// Start returns the start point of the segment.
+func (s VectorSegment) Start() transform.Point { return s.Points[0] }
+
+// End returns the end point of the segment.
+func (s VectorSegment) End() transform.Point { return s.Points[len(s.Points)-1] }
+
+// VectorSubpath is a sequence of connected segments of a path.
+type VectorSubpath struct {
+	Segments []VectorSegment
+	Closed   bool
+}
+
+// Vertices returns the start point and the end points of the segments of the subpath. The end point of a
+// closed subpath that coincides with its start point is omitted.
+func (sp VectorSubpath) Vertices() []transform.Point {
+	if len(sp.Segments) == 0 {
+		return nil
+	}
+	points := []transform.Point{sp.Segments[0].Start()}
+	for _, s := range sp.Segments {
+		points = append(points, s.End())
+	}
+	if n := len(points); n > 2 && points[0].Distance(points[n-1]) < 1e-6 {
+		points = points[:n-1]
+	}
+	return points
+}
+
+// BBox returns the bounding box of the points and control points of the subpath.
+func (sp VectorSubpath) BBox() model.PdfRectangle {
+	var points []transform.Point
+	for _, s := range sp.Segments {
+		points = append(points, s.Points...)
+	}
+	return pointsBBox(points)
+}
+
+// FillRule is the rule determining the inside of a path.
+type FillRule int
+
+// Fill rules.
+const (
+	FillRuleNonZero FillRule = iota
+	FillRuleEvenOdd
+)
+
+// VectorPath is a painted path with the graphics state it was painted with.
+type VectorPath struct {
+	Subpaths []VectorSubpath
+
+	// BBox is the bounding box of the path in page coordinates, not including the line width.
+	BBox model.PdfRectangle
+
+	// Stroked and Filled tell how the path is painted. FillRule is the rule of filled paths.
+	Stroked, Filled bool
+	FillRule        FillRule
+
+	// StrokeColor and FillColor are the colors of stroked and filled paths.
+	StrokeColor, FillColor color.Color
+
+	// LineWidth, DashArray and DashPhase are the line width and dash pattern of stroked paths in page units.
+	LineWidth float64
+	DashArray []float64
+	DashPhase float64
+
+	// LineCap and LineJoin are the line cap and line join styles of stroked paths.
+	LineCap, LineJoin int
+
+	// CTM is the transform from the user space of the path to page coordinates.
+	CTM transform.Matrix
+
+	// Group is the group of the path.
+	Group *VectorGroup
+}
+
+// VectorGroup is a group of paths drawn by a form XObject or within a clipping path.
+type VectorGroup struct {
+	// Form is the name of the form XObject of groups of form content.
+	Form string
+
+	// Clip is the clipping path in page coordinates established for the group: the clipping path of groups
+	// within a clipping path and the bounding box of the form of form groups. ClipRule is its fill rule.
+	Clip     []VectorSubpath
+	ClipRule FillRule
+
+	Parent *VectorGroup
+	Groups []*VectorGroup
+	Paths  []*VectorPath
+}
+
+// Forms returns the names of the form XObjects whose content holds the group, outermost first.
+func (g *VectorGroup) Forms() []string {
+	var forms []string
+	for ; g != nil; g = g.Parent {
+		if g.Form != "" {
+			forms = append([]string{g.Form}, forms...)
+		}
+	}
+	return forms
+}
+
+// ExtractPageVectors returns the vector graphics of the page: the stroked and filled paths of the page
+// content and of the form XObjects it draws, and the shapes recognized in them. Paths of hidden optional
+// content are omitted. The options parameter can be nil for the default options.
+func (e *Extractor) ExtractPageVectors(options *VectorExtractOptions) (*PageVectors, error) {
+	if options == nil {
+		options = &VectorExtractOptions{}
+	}
+	ctx := &vectorContext{visibility: e._ocVisibility, page: &PageVectors{Root: &VectorGroup{}}}
+	if err := ctx.extract(e._fg, e._fdg, transform.IdentityMatrix(), ctx.page.Root, 0); err != nil {
+		return nil, err
+	}
+	if !options.DisableShapes {
+		tolerance := options.ShapeTolerance
+		if tolerance <= 0 {
+			tolerance = 0.05
+		}
+		ctx.page.Shapes = recognizeShapes(ctx.page.Paths, tolerance)
+	}
+	return ctx.page, nil
+}
+
+// _ddMaxFormDepth is the maximum nesting depth of forms whose paths are extracted.
+const _ddMaxFormDepth = 20
+
+type vectorContext struct {
+	visibility *model.OCVisibility
+	page       *PageVectors
+}
+
+// vectorState is the part of the graphics state tracked for paths that is not tracked by the content
+// stream processor.
+type vectorState struct {
+	lineWidth float64
+	dashArray []float64
+	dashPhase float64
+	lineCap   int
+	lineJoin  int
+	group     *VectorGroup
+}
+
+// extract extracts the paths of the content stream `content` drawn with the transform `base` to page
+// coordinates into `group`.
+func (ctx *vectorContext) extract(content string, resources *model.PdfPageResources, base transform.Matrix,
+	group *VectorGroup, depth int) error {
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return err
+	}
+	states := []vectorState{{lineWidth: 1, group: group}}
+	tracker := newSourceTracker(ctx.visibility)
+	var path []VectorSubpath
+	var current transform.Point
+	clip, clipRule := false, FillRuleNonZero
+
+	moveTo := func(p transform.Point) {
+		path = append(path, VectorSubpath{})
+		current = p
+	}
+	addSegment := func(points ...transform.Point) {
+		if len(path) == 0 {
+			moveTo(current)
+		}
+		sp := &path[len(path)-1]
+		sp.Segments = append(sp.Segments, VectorSegment{Points: append([]transform.Point{current}, points...)})
+		current = points[len(points)-1]
+	}
+	closePath := func() {
+		if len(path) == 0 {
+			return
+		}
+		sp := &path[len(path)-1]
+		if len(sp.Segments) > 0 {
+			start := sp.Segments[0].Start()
+			if current.Distance(start) > 1e-9 {
+				sp.Segments = append(sp.Segments, VectorSegment{Points: []transform.Point{current, start}})
+			}
+			current = start
+		}
+		sp.Closed = true
+	}
+
+	processor := contentstream.NewContentStreamProcessor(*ops)
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
+			tracker.process(op, resources)
+			state := &states[len(states)-1]
+			switch op.Operand {
+			case "q":
+				states = append(states, *state)
+			case "Q":
+				if len(states) > 1 {
+					states = states[:len(states)-1]
+				}
+			case "w":
+				if v, err := core.GetNumbersAsFloat(op.Params); err == nil && len(v) == 1 {
+					state.lineWidth = v[0]
+				}
+			case "J", "j":
+				if v, err := core.GetNumbersAsFloat(op.Params); err == nil && len(v) == 1 {
+					if op.Operand == "J" {
+						state.lineCap = int(v[0])
+					} else {
+						state.lineJoin = int(v[0])
+					}
+				}
+			case "d":
+				if len(op.Params) == 2 {
+					state.setDash(op.Params[0], op.Params[1])
+				}
+			case "gs":
+				if len(op.Params) == 1 {
+					if name, ok := core.GetName(op.Params[0]); ok && resources != nil {
+						if obj, ok := resources.GetExtGState(*name); ok {
+							state.applyExtGState(obj)
+						}
+					}
+				}
+			case "m", "l":
+				if v, err := core.GetNumbersAsFloat(op.Params); err == nil && len(v) == 2 {
+					p := transform.Point{X: v[0], Y: v[1]}
+					if op.Operand == "m" {
+						moveTo(p)
+					} else {
+						addSegment(p)
+					}
+				}
+			case "c", "v", "y":
+				v, err := core.GetNumbersAsFloat(op.Params)
+				if err != nil {
+					break
+				}
+				switch {
+				case op.Operand == "c" && len(v) == 6:
+					addSegment(transform.Point{X: v[0], Y: v[1]}, transform.Point{X: v[2], Y: v[3]},
+						transform.Point{X: v[4], Y: v[5]})
+				case op.Operand == "v" && len(v) == 4:
+					addSegment(current, transform.Point{X: v[0], Y: v[1]}, transform.Point{X: v[2], Y: v[3]})
+				case op.Operand == "y" && len(v) == 4:
+					end := transform.Point{X: v[2], Y: v[3]}
+					addSegment(transform.Point{X: v[0], Y: v[1]}, end, end)
+				}
+			case "re":
+				if v, err := core.GetNumbersAsFloat(op.Params); err == nil && len(v) == 4 {
+					x, y, w, h := v[0], v[1], v[2], v[3]
+					moveTo(transform.Point{X: x, Y: y})
+					addSegment(transform.Point{X: x + w, Y: y})
+					addSegment(transform.Point{X: x + w, Y: y + h})
+					addSegment(transform.Point{X: x, Y: y + h})
+					closePath()
+				}
+			case "h":
+				closePath()
+			case "W", "W*":
+				clip = true
+				clipRule = FillRuleNonZero
+				if op.Operand == "W*" {
+					clipRule = FillRuleEvenOdd
+				}
+			case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+				if op.Operand == "s" || op.Operand == "b" || op.Operand == "b*" {
+					closePath()
+				}
+				ctm := base.Mult(gs.CTM)
+				if clip {
+					g := &VectorGroup{Clip: transformSubpaths(path, ctm), ClipRule: clipRule, Parent: state.group}
+					state.group.Groups = append(state.group.Groups, g)
+					state.group = g
+				}
+				if op.Operand != "n" && len(path) > 0 && !tracker.hides(op, resources) {
+					ctx.addPath(op.Operand, path, ctm, gs, state)
+				}
+				path = nil
+				clip = false
+			case "Do":
+				if depth >= _ddMaxFormDepth || len(op.Params) != 1 || tracker.hides(op, resources) {
+					break
+				}
+				name, ok := core.GetName(op.Params[0])
+				if !ok || resources == nil {
+					break
+				}
+				if _, typ := resources.GetXObjectByName(*name); typ != model.XObjectTypeForm {
+					break
+				}
+				if err := ctx.extractForm(*name, resources, base.Mult(gs.CTM), state.group, depth); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	err = processor.Process(resources)
+	if err == model.ErrColorOutOfRange {
+		common.Log.Debug("ERROR: extracting paths: %v", err)
+		return nil
+	}
+	return err
+}
+
+// extractForm extracts the paths of the form XObject `name` drawn with the transform `ctm` into a new
+// subgroup of `parent`.
+func (ctx *vectorContext) extractForm(name core.PdfObjectName, resources *model.PdfPageResources,
+	ctm transform.Matrix, parent *VectorGroup, depth int) error {
+	form, err := resources.GetXObjectFormByName(name)
+	if err != nil || form == nil {
+		return err
+	}
+	content, err := form.GetContentStream()
+	if err != nil {
+		return err
+	}
+	formResources := form.Resources
+	if formResources == nil {
+		formResources = resources
+	}
+	if arr, ok := core.GetArray(form.Matrix); ok {
+		if m, err := arr.GetAsFloat64Slice(); err == nil && len(m) == 6 {
+			ctm = ctm.Mult(transform.NewMatrix(m[0], m[1], m[2], m[3], m[4], m[5]))
+		}
+	}
+	group := &VectorGroup{Form: string(name), Parent: parent}
+	if arr, ok := core.GetArray(form.BBox); ok {
+		if b, err := arr.GetAsFloat64Slice(); err == nil && len(b) == 4 {
+			group.Clip = transformSubpaths([]VectorSubpath{rectSubpath(b[0], b[1], b[2], b[3])}, ctm)
+		}
+	}
+	parent.Groups = append(parent.Groups, group)
+	return ctx.extract(string(content), formResources, ctm, group, depth+1)
+}
+
+// addPath adds the path `path` painted by the operator `operand` to the page.
+func (ctx *vectorContext) addPath(operand string, path []VectorSubpath, ctm transform.Matrix,
+	gs contentstream.GraphicsState, state *vectorState) {
+	p := &VectorPath{Subpaths: transformSubpaths(path, ctm), CTM: ctm, Group: state.group}
+	switch operand {
+	case "S", "s":
+		p.Stroked = true
+	case "f", "F", "f*":
+		p.Filled = true
+	default:
+		p.Stroked, p.Filled = true, true
+	}
+	if operand == "f*" || operand == "B*" || operand == "b*" {
+		p.FillRule = FillRuleEvenOdd
+	}
+	if p.Stroked {
+		scale := math.Sqrt(math.Abs(ctm[0]*ctm[4] - ctm[1]*ctm[3]))
+		p.StrokeColor = _ddac(gs.ColorspaceStroking, gs.ColorStroking)
+		p.LineWidth = state.lineWidth * scale
+		for _, d := range state.dashArray {
+			p.DashArray = append(p.DashArray, d*scale)
+		}
+		p.DashPhase = state.dashPhase * scale
+		p.LineCap, p.LineJoin = state.lineCap, state.lineJoin
+	}
+	if p.Filled {
+		p.FillColor = _ddac(gs.ColorspaceNonStroking, gs.ColorNonStroking)
+	}
+	var points []transform.Point
+	for _, sp := range p.Subpaths {
+		for _, s := range sp.Segments {
+			points = append(points, s.Points...)
+		}
+	}
+	p.BBox = pointsBBox(points)
+	state.group.Paths = append(state.group.Paths, p)
+	ctx.page.Paths = append(ctx.page.Paths, p)
+}
+
+// setDash sets the dash pattern to the array `array` and phase `phase`.
+func (s *vectorState) setDash(array, phase core.PdfObject) {
+	arr, ok := core.GetArray(array)
+	if !ok {
+		return
+	}
+	dashes, err := arr.ToFloat64Array()
+	if err != nil {
+		return
+	}
+	s.dashArray = dashes
+	s.dashPhase, _ = core.GetNumberAsFloat(phase)
+}
+
+// applyExtGState applies the line parameters of the graphics state parameter dictionary `obj`.
+func (s *vectorState) applyExtGState(obj core.PdfObject) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	if v, err := core.GetNumberAsFloat(dict.Get("LW")); err == nil {
+		s.lineWidth = v
+	}
+	if v, err := core.GetNumberAsFloat(dict.Get("LC")); err == nil {
+		s.lineCap = int(v)
+	}
+	if v, err := core.GetNumberAsFloat(dict.Get("LJ")); err == nil {
+		s.lineJoin = int(v)
+	}
+	if arr, ok := core.GetArray(dict.Get("D")); ok && arr.Len() == 2 {
+		s.setDash(arr.Get(0), arr.Get(1))
+	}
+}
+
+// transformSubpaths returns `path` transformed by `m`.
+func transformSubpaths(path []VectorSubpath, m transform.Matrix) []VectorSubpath {
+	out := make([]VectorSubpath, len(path))
+	for i, sp := range path {
+		out[i].Closed = sp.Closed
+		out[i].Segments = make([]VectorSegment, len(sp.Segments))
+		for j, s := range sp.Segments {
+			points := make([]transform.Point, len(s.Points))
+			for k, p := range s.Points {
+				points[k].X, points[k].Y = m.Transform(p.X, p.Y)
+			}
+			out[i].Segments[j].Points = points
+		}
+	}
+	return out
+}
+
+// rectSubpath returns the closed subpath of the rectangle with corners (x0, y0) and (x1, y1).
+func rectSubpath(x0, y0, x1, y1 float64) VectorSubpath {
+	corners := []transform.Point{{X: x0, Y: y0}, {X: x1, Y: y0}, {X: x1, Y: y1}, {X: x0, Y: y1}}
+	sp := VectorSubpath{Closed: true}
+	for i, p := range corners {
+		sp.Segments = append(sp.Segments, VectorSegment{Points: []transform.Point{p, corners[(i+1)%4]}})
+	}
+	return sp
+}
+
+// pointsBBox returns the bounding box of `points`.
+func pointsBBox(points []transform.Point) model.PdfRectangle {
+	if len(points) == 0 {
+		return model.PdfRectangle{}
+	}
+	r := model.PdfRectangle{Llx: points[0].X, Lly: points[0].Y, Urx: points[0].X, Ury: points[0].Y}
+	for _, p := range points[1:] {
+		r.Llx, r.Urx = math.Min(r.Llx, p.X), math.Max(r.Urx, p.X)
+		r.Lly, r.Ury = math.Min(r.Lly, p.Y), math.Max(r.Ury, p.Y)
+	}
+	return r
+}
@@ -0,0 +1,366 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package model
+
+import (
+	"github.com/unidoc/unipdf/v4/core"
+)
+
+// OCEvent is the event for which the visibility of optional content is determined.
+type OCEvent string
+
+// Optional content events.
+const (
+	OCEventView   OCEvent = "View"
+	OCEventPrint  OCEvent = "Print"
+	OCEventExport OCEvent = "Export"
+)
+
+// OCLayer is an optional content group (layer) of a document.
+type OCLayer struct {
+	// Name is the name of the group.
+	Name string
+
+	// Group is the optional content group dictionary.
+	Group *core.PdfObjectDictionary
+
+	// Visible is the state of the group.
+	Visible bool
+
+	// Locked is true for groups locked in the default configuration of the document.
+	Locked bool
+
+	// Intent are the intents of the group, "View" by default.
+	Intent []string
+
+	// ViewState, PrintState and ExportState are the states ("ON" or "OFF") recommended by the Usage
+	// dictionary of the group for these events, or empty if not set.
+	ViewState, PrintState, ExportState string
+}
+
+// OCVisibility determines the visibility of optional content from the states of the optional content
+// groups (OCG) of a document. Content controlled by optional content membership dictionaries (OCMD) is
+// visible according to their visibility expressions (VE) or visibility policies (P).
+type OCVisibility struct {
+	_groups []*core.PdfObjectDictionary
+	_states map[*core.PdfObjectDictionary]bool
+	_locked map[*core.PdfObjectDictionary]bool
+
+	// _ignored are the groups whose intent is not an intent of the configuration. They do not hide
+	// content.
+	_ignored map[*core.PdfObjectDictionary]bool
+}
+
+// NewOCVisibility returns the visibility of the optional content groups of the optional content
+// properties `ocProperties` (the OCProperties dictionary of the document catalog) in their default
+// configuration (D) for `event`. The states set by the BaseState, ON and OFF entries of the configuration
+// are changed for `event` by the usage application dictionaries (AS) of the configuration. If the
+// configuration has no usage application dictionary for a print or export event, the PrintState or
+// ExportState entries of the Usage dictionaries of the groups are applied.
+func NewOCVisibility(ocProperties core.PdfObject, event OCEvent) *OCVisibility {
+	v := &OCVisibility{
+		_states:  map[*core.PdfObjectDictionary]bool{},
+		_locked:  map[*core.PdfObjectDictionary]bool{},
+		_ignored: map[*core.PdfObjectDictionary]bool{},
+	}
+	props, ok := core.GetDict(ocProperties)
+	if !ok {
+		return v
+	}
+	for _, ocg := range ocArray(props.Get("OCGs")) {
+		if dict, ok := core.GetDict(ocg); ok {
+			if _, seen := v._states[dict]; !seen {
+				v._groups = append(v._groups, dict)
+				v._states[dict] = true
+			}
+		}
+	}
+	config, _ := core.GetDict(props.Get("D"))
+	if config == nil {
+		return v
+	}
+	if base, _ := core.GetNameVal(config.Get("BaseState")); base == "OFF" {
+		for _, g := range v._groups {
+			v._states[g] = false
+		}
+	}
+	for _, ocg := range ocArray(config.Get("ON")) {
+		v.SetVisible(ocg, true)
+	}
+	for _, ocg := range ocArray(config.Get("OFF")) {
+		v.SetVisible(ocg, false)
+	}
+	for _, ocg := range ocArray(config.Get("Locked")) {
+		if dict, ok := core.GetDict(ocg); ok {
+			v._locked[dict] = true
+		}
+	}
+
+	intents := ocNames(config.Get("Intent"))
+	if len(intents) == 0 {
+		intents = []string{"View"}
+	}
+	for _, g := range v._groups {
+		if !ocIntersects(intents, ocLayerIntent(g)) {
+			v._ignored[g] = true
+		}
+	}
+
+	applied := false
+	for _, as := range ocArray(config.Get("AS")) {
+		asDict, ok := core.GetDict(as)
+		if !ok {
+			continue
+		}
+		if e, _ := core.GetNameVal(asDict.Get("Event")); e != string(event) {
+			continue
+		}
+		applied = true
+		categories := ocNames(asDict.Get("Category"))
+		for _, ocg := range ocArray(asDict.Get("OCGs")) {
+			dict, ok := core.GetDict(ocg)
+			if !ok {
+				continue
+			}
+			for _, category := range categories {
+				if state := ocUsageState(dict, category); state != "" {
+					v._states[dict] = state == "ON"
+				}
+			}
+		}
+	}
+	if !applied && event != OCEventView {
+		for _, g := range v._groups {
+			if state := ocUsageState(g, string(event)); state != "" {
+				v._states[g] = state == "ON"
+			}
+		}
+	}
+	return v
+}
+
+// GetOCVisibility returns the visibility of the optional content of the document for `event` in its
+// default configuration. See NewOCVisibility.
+func (r *PdfReader) GetOCVisibility(event OCEvent) (*OCVisibility, error) {
+	ocProperties, err := r.GetOCProperties()
+	if err != nil {
+		return nil, err
+	}
+	return NewOCVisibility(ocProperties, event), nil
+}
+
+// GetOCVisibility returns the visibility of the optional content of the document of the page for `event`
+// in its default configuration. It returns nil for pages that have not been read from a document.
+func (p *PdfPage) GetOCVisibility(event OCEvent) (*OCVisibility, error) {
+	if p._fadc == nil {
+		return nil, nil
+	}
+	return p._fadc.GetOCVisibility(event)
+}
+
+// SetVisible sets the state of the optional content group `ocg`.
+func (v *OCVisibility) SetVisible(ocg core.PdfObject, visible bool) {
+	dict, ok := core.GetDict(ocg)
+	if !ok {
+		return
+	}
+	if _, known := v._states[dict]; !known {
+		v._groups = append(v._groups, dict)
+	}
+	v._states[dict] = visible
+}
+
+// SetVisibleLayers shows the optional content groups named in `names` and hides all other groups.
+func (v *OCVisibility) SetVisibleLayers(names ...string) {
+	visible := map[string]bool{}
+	for _, name := range names {
+		visible[name] = true
+	}
+	for _, g := range v._groups {
+		v._states[g] = visible[ocGroupName(g)]
+	}
+}
+
+// Layers returns the optional content groups of the document with their states.
+func (v *OCVisibility) Layers() []OCLayer {
+	layers := make([]OCLayer, 0, len(v._groups))
+	for _, g := range v._groups {
+		layers = append(layers, OCLayer{
+			Name:        ocGroupName(g),
+			Group:       g,
+			Visible:     v._states[g],
+			Locked:      v._locked[g],
+			Intent:      ocLayerIntent(g),
+			ViewState:   ocUsageState(g, "View"),
+			PrintState:  ocUsageState(g, "Print"),
+			ExportState: ocUsageState(g, "Export"),
+		})
+	}
+	return layers
+}
+
+// IsVisible returns true if content controlled by `oc`, an optional content group or membership
+// dictionary, is visible. Content of unknown groups and content that is not optional is visible.
+func (v *OCVisibility) IsVisible(oc core.PdfObject) bool {
+	if v == nil {
+		return true
+	}
+	dict, ok := core.GetDict(oc)
+	if !ok {
+		return true
+	}
+	if typ, _ := core.GetNameVal(dict.Get("Type")); typ != "OCMD" {
+		return v.groupVisible(dict)
+	}
+	if ve, ok := core.GetArray(dict.Get("VE")); ok {
+		return v.expression(ve, 0)
+	}
+	var groups []*core.PdfObjectDictionary
+	for _, ocg := range ocArray(dict.Get("OCGs")) {
+		if g, ok := core.GetDict(ocg); ok {
+			groups = append(groups, g)
+		}
+	}
+	if len(groups) == 0 {
+		return true
+	}
+	on := 0
+	for _, g := range groups {
+		if v.groupVisible(g) {
+			on++
+		}
+	}
+	switch policy, _ := core.GetNameVal(dict.Get("P")); policy {
+	case "AllOn":
+		return on == len(groups)
+	case "AnyOff":
+		return on < len(groups)
+	case "AllOff":
+		return on == 0
+	}
+	return on > 0
+}
+
+// groupVisible returns the state of the group `g`.
+func (v *OCVisibility) groupVisible(g *core.PdfObjectDictionary) bool {
+	if v._ignored[g] {
+		return true
+	}
+	visible, known := v._states[g]
+	return !known || visible
+}
+
+// expression evaluates the visibility expression `ve` of an OCMD.
+func (v *OCVisibility) expression(ve *core.PdfObjectArray, depth int) bool {
+	if ve.Len() == 0 || depth > 20 {
+		return true
+	}
+	op, _ := core.GetNameVal(ve.Get(0))
+	operand := func(obj core.PdfObject) bool {
+		if arr, ok := core.GetArray(obj); ok {
+			return v.expression(arr, depth+1)
+		}
+		if g, ok := core.GetDict(obj); ok {
+			return v.groupVisible(g)
+		}
+		return true
+	}
+	operands := ve.Elements()[1:]
+	switch op {
+	case "Not":
+		return len(operands) == 0 || !operand(operands[0])
+	case "And":
+		for _, o := range operands {
+			if !operand(o) {
+				return false
+			}
+		}
+		return true
+	case "Or":
+		for _, o := range operands {
+			if operand(o) {
+				return true
+			}
+		}
+		return len(operands) == 0
+	}
+	return true
+}
+
+// ocGroupName returns the name of the group `g`.
+func ocGroupName(g *core.PdfObjectDictionary) string {
+	if name, ok := core.GetString(g.Get("Name")); ok {
+		return name.Decoded()
+	}
+	return ""
+}
+
+// ocArray returns the elements of an array of objects, or the object itself if it is not an array.
+func ocArray(obj core.PdfObject) []core.PdfObject {
+	obj = core.ResolveReference(obj)
+	if obj == nil {
+		return nil
+	}
+	if arr, ok := core.GetArray(obj); ok {
+		return arr.Elements()
+	}
+	if _, ok := obj.(*core.PdfObjectNull); ok {
+		return nil
+	}
+	return []core.PdfObject{obj}
+}
+
+// ocNames returns the values of a name or an array of names.
+func ocNames(obj core.PdfObject) []string {
+	var names []string
+	for _, o := range ocArray(obj) {
+		if name, ok := core.GetNameVal(o); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ocLayerIntent returns the intents of the group `g`.
+func ocLayerIntent(g *core.PdfObjectDictionary) []string {
+	if intent := ocNames(g.Get("Intent")); len(intent) > 0 {
+		return intent
+	}
+	return []string{"View"}
+}
+
+// ocIntersects returns true if the configuration intents `config` include an intent of `group`.
+func ocIntersects(config, group []string) bool {
+	for _, c := range config {
+		if c == "All" {
+			return true
+		}
+		for _, g := range group {
+			if c == g {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ocUsageState returns the state of the usage `category` (View, Print or Export) of the group `g`.
+func ocUsageState(g *core.PdfObjectDictionary, category string) string {
+	usage, ok := core.GetDict(g.Get("Usage"))
+	if !ok {
+		return ""
+	}
+	entry, ok := core.GetDict(usage.Get(core.PdfObjectName(category)))
+	if !ok {
+		return ""
+	}
+	state, _ := core.GetNameVal(entry.Get(core.PdfObjectName(category + "State")))
+	return state
+}
@@ -0,0 +1,407 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+
+	"github.com/unidoc/unipdf/v4/core"
+)
+
+// OptionalContent is an optional content group (OCG) or membership dictionary (OCMD) that controls the
+// visibility of content marked with it.
+type OptionalContent interface {
+	ToPdfObject() core.PdfObject
+}
+
+// OCGroup is an optional content group (layer) to be added to a document.
+type OCGroup struct {
+	// Name is the name of the group shown by viewers.
+	Name string
+
+	// Intent are the intents of the group, such as "View" (default) or "Design".
+	Intent []string
+
+	// Visible is the initial state of the group.
+	Visible bool
+
+	// Locked prevents viewers from changing the state of the group.
+	Locked bool
+
+	// ViewState, PrintState and ExportState ("ON" or "OFF") are the states recommended for viewing,
+	// printing and exporting the content of the group. Empty states are not set.
+	ViewState, PrintState, ExportState string
+
+	_obj *core.PdfIndirectObject
+}
+
+// NewOCGroup returns a new visible optional content group named `name`.
+func NewOCGroup(name string) *OCGroup {
+	return &OCGroup{Name: name, Visible: true}
+}
+
+// ToPdfObject returns the optional content group dictionary as an indirect object. Each call updates the
+// same dictionary, so that it keeps identifying the group.
+func (g *OCGroup) ToPdfObject() core.PdfObject {
+	if g._obj == nil {
+		g._obj = core.MakeIndirectObject(core.MakeDict())
+	}
+	d := g._obj.PdfObject.(*core.PdfObjectDictionary)
+	d.Set("Type", core.MakeName("OCG"))
+	d.Set("Name", ocTextString(g.Name))
+	d.Remove("Intent")
+	if len(g.Intent) == 1 {
+		d.Set("Intent", core.MakeName(g.Intent[0]))
+	} else if len(g.Intent) > 1 {
+		d.Set("Intent", ocNameArray(g.Intent))
+	}
+	usage := core.MakeDict()
+	for _, u := range []struct{ category, state string }{
+		{"View", g.ViewState}, {"Print", g.PrintState}, {"Export", g.ExportState},
+	} {
+		if u.state != "" {
+			entry := core.MakeDict()
+			entry.Set(core.PdfObjectName(u.category+"State"), core.MakeName(u.state))
+			usage.Set(core.PdfObjectName(u.category), entry)
+		}
+	}
+	d.Remove("Usage")
+	if len(usage.Keys()) > 0 {
+		d.Set("Usage", usage)
+	}
+	return g._obj
+}
+
+// OCPolicy is the visibility policy of an optional content membership dictionary.
+type OCPolicy string
+
+// Visibility policies of optional content membership dictionaries.
+const (
+	OCPolicyAnyOn  OCPolicy = "AnyOn"
+	OCPolicyAllOn  OCPolicy = "AllOn"
+	OCPolicyAnyOff OCPolicy = "AnyOff"
+	OCPolicyAllOff OCPolicy = "AllOff"
+)
+
+// OCExpression is a visibility expression of an optional content membership dictionary. Its operands are
+// optional content groups or expressions.
+type OCExpression struct {
+	// Operator is "And", "Or" or "Not".
+	Operator string
+	Operands []OptionalContent
+}
+
+// OCAnd returns an expression that is true if all `operands` are visible.
+func OCAnd(operands ...OptionalContent) *OCExpression {
+	return &OCExpression{Operator: "And", Operands: operands}
+}
+
+// OCOr returns an expression that is true if any of `operands` is visible.
+func OCOr(operands ...OptionalContent) *OCExpression {
+	return &OCExpression{Operator: "Or", Operands: operands}
+}
+
+// OCNot returns an expression that is true if `operand` is hidden.
+func OCNot(operand OptionalContent) *OCExpression {
+	return &OCExpression{Operator: "Not", Operands: []OptionalContent{operand}}
+}
+
+// ToPdfObject returns the visibility expression array.
+func (e *OCExpression) ToPdfObject() core.PdfObject {
+	arr := core.MakeArray(core.MakeName(e.Operator))
+	for _, o := range e.Operands {
+		arr.Append(o.ToPdfObject())
+	}
+	return arr
+}
+
+// OCMembership is an optional content membership dictionary (OCMD). Content marked with it is visible
+// according to its visibility expression if set, or otherwise according to the states of its groups and its
+// policy.
+type OCMembership struct {
+	Groups     []*OCGroup
+	Policy     OCPolicy
+	Expression *OCExpression
+
+	_obj *core.PdfIndirectObject
+}
+
+// NewOCMembership returns a membership dictionary of `groups` with visibility policy `policy`.
+func NewOCMembership(policy OCPolicy, groups ...*OCGroup) *OCMembership {
+	return &OCMembership{Groups: groups, Policy: policy}
+}
+
+// ToPdfObject returns the membership dictionary as an indirect object. Each call updates the same
+// dictionary.
+func (m *OCMembership) ToPdfObject() core.PdfObject {
+	if m._obj == nil {
+		m._obj = core.MakeIndirectObject(core.MakeDict())
+	}
+	d := m._obj.PdfObject.(*core.PdfObjectDictionary)
+	d.Set("Type", core.MakeName("OCMD"))
+	for _, key := range []core.PdfObjectName{"OCGs", "P", "VE"} {
+		d.Remove(key)
+	}
+	if len(m.Groups) > 0 {
+		d.Set("OCGs", ocGroupArray(m.Groups))
+	}
+	if m.Policy != "" {
+		d.Set("P", core.MakeName(string(m.Policy)))
+	}
+	if m.Expression != nil {
+		d.Set("VE", m.Expression.ToPdfObject())
+	}
+	return m._obj
+}
+
+// OCOrderItem is an entry of the tree of groups presented by viewers. An item shows its group, or its label
+// if it has no group, followed by its children.
+type OCOrderItem struct {
+	Group    *OCGroup
+	Label    string
+	Children []*OCOrderItem
+}
+
+// toPdfObjects returns the objects of the item in an order array.
+func (item *OCOrderItem) toPdfObjects() []core.PdfObject {
+	var objs []core.PdfObject
+	children := core.MakeArray()
+	if item.Group != nil {
+		objs = append(objs, item.Group.ToPdfObject())
+	} else {
+		children.Append(ocTextString(item.Label))
+	}
+	for _, child := range item.Children {
+		children.Append(child.toPdfObjects()...)
+	}
+	if children.Len() > 0 {
+		objs = append(objs, children)
+	}
+	return objs
+}
+
+// OCProperties are the optional content properties of a document: its groups and their default
+// configuration.
+type OCProperties struct {
+	Groups []*OCGroup
+
+	// Name and Creator are the name of the default configuration and the application that created it.
+	Name, Creator string
+
+	// Order is the tree of groups presented by viewers. If nil, all groups are presented in the order they
+	// were added.
+	Order []*OCOrderItem
+
+	// RBGroups are the radio button groups: sets of groups of which at most one is visible at a time.
+	RBGroups [][]*OCGroup
+}
+
+// NewOCProperties returns new empty optional content properties.
+func NewOCProperties() *OCProperties {
+	return &OCProperties{}
+}
+
+// AddGroup adds a new visible optional content group named `name` and returns it.
+func (p *OCProperties) AddGroup(name string) *OCGroup {
+	g := NewOCGroup(name)
+	p.Groups = append(p.Groups, g)
+	return g
+}
+
+// AddRadioButtonGroup adds a radio button group of `groups`. Turning on one of them turns off the others.
+func (p *OCProperties) AddRadioButtonGroup(groups ...*OCGroup) {
+	p.RBGroups = append(p.RBGroups, groups)
+}
+
+// ToPdfObject returns the OCProperties dictionary of the document catalog.
+func (p *OCProperties) ToPdfObject() core.PdfObject {
+	config := core.MakeDict()
+	if p.Name != "" {
+		config.Set("Name", ocTextString(p.Name))
+	}
+	if p.Creator != "" {
+		config.Set("Creator", ocTextString(p.Creator))
+	}
+	var on, off, locked []*OCGroup
+	var intents []string
+	seen := map[string]bool{}
+	events := map[string][]*OCGroup{}
+	for _, g := range p.Groups {
+		if g.Visible {
+			on = append(on, g)
+		} else {
+			off = append(off, g)
+		}
+		if g.Locked {
+			locked = append(locked, g)
+		}
+		for _, intent := range g.Intent {
+			if !seen[intent] {
+				seen[intent] = true
+				intents = append(intents, intent)
+			}
+		}
+		for _, u := range []struct{ event, state string }{
+			{"View", g.ViewState}, {"Print", g.PrintState}, {"Export", g.ExportState},
+		} {
+			if u.state != "" {
+				events[u.event] = append(events[u.event], g)
+			}
+		}
+	}
+	config.Set("BaseState", core.MakeName("ON"))
+	if len(on) > 0 {
+		config.Set("ON", ocGroupArray(on))
+	}
+	if len(off) > 0 {
+		config.Set("OFF", ocGroupArray(off))
+	}
+	if len(intents) > 0 && !(len(intents) == 1 && intents[0] == "View") {
+		if !seen["View"] {
+			intents = append([]string{"View"}, intents...)
+		}
+		config.Set("Intent", ocNameArray(intents))
+	}
+	order := core.MakeArray()
+	if p.Order != nil {
+		for _, item := range p.Order {
+			order.Append(item.toPdfObjects()...)
+		}
+	} else {
+		for _, g := range p.Groups {
+			order.Append(g.ToPdfObject())
+		}
+	}
+	config.Set("Order", order)
+	if len(p.RBGroups) > 0 {
+		rb := core.MakeArray()
+		for _, groups := range p.RBGroups {
+			rb.Append(ocGroupArray(groups))
+		}
+		config.Set("RBGroups", rb)
+	}
+	if len(locked) > 0 {
+		config.Set("Locked", ocGroupArray(locked))
+	}
+	as := core.MakeArray()
+	for _, event := range []string{"View", "Print", "Export"} {
+		if groups := events[event]; len(groups) > 0 {
+			app := core.MakeDict()
+			app.Set("Event", core.MakeName(event))
+			app.Set("Category", core.MakeArray(core.MakeName(event)))
+			app.Set("OCGs", ocGroupArray(groups))
+			as.Append(app)
+		}
+	}
+	if as.Len() > 0 {
+		config.Set("AS", as)
+	}
+
+	d := core.MakeDict()
+	d.Set("OCGs", ocGroupArray(p.Groups))
+	d.Set("D", config)
+	return d
+}
+
+// AddOptionalContent adds `oc` to the Properties resources and returns its name, which is used to mark
+// content with the operators /OC /name BDC ... EMC. An existing name is returned if `oc` has already been
+// added.
+func (r *PdfPageResources) AddOptionalContent(oc OptionalContent) (core.PdfObjectName, error) {
+	if r.Properties == nil {
+		r.Properties = core.MakeDict()
+	}
+	props, ok := core.GetDict(r.Properties)
+	if !ok {
+		return "", core.ErrTypeError
+	}
+	obj := oc.ToPdfObject()
+	for _, key := range props.Keys() {
+		if props.Get(key) == obj {
+			return key, nil
+		}
+	}
+	for i := 1; ; i++ {
+		name := core.PdfObjectName(fmt.Sprintf("OC%d", i))
+		if props.Get(name) == nil {
+			props.Set(name, obj)
+			return name, nil
+		}
+	}
+}
+
+// WrapOptionalContent marks the content of the page as optional content controlled by `oc`.
+func (p *PdfPage) WrapOptionalContent(oc OptionalContent) error {
+	streams := p.GetContentStreamObjs()
+	if len(streams) == 0 {
+		return errors.New("page has no content")
+	}
+	if p.Resources == nil {
+		p.Resources = NewPdfPageResources()
+	}
+	name, err := p.Resources.AddOptionalContent(oc)
+	if err != nil {
+		return err
+	}
+	encoder := core.NewFlateEncoder()
+	begin, err := core.MakeStream([]byte(fmt.Sprintf("/OC /%s BDC\n", name)), encoder)
+	if err != nil {
+		return err
+	}
+	end, err := core.MakeStream([]byte("\nEMC\n"), encoder)
+	if err != nil {
+		return err
+	}
+	contents := core.MakeArray(begin)
+	contents.Append(streams...)
+	contents.Append(end)
+	p.Contents = contents
+	return nil
+}
+
+// AddOptionalContentByString appends `contentStr` to the content of the page as optional content
+// controlled by `oc`.
+func (p *PdfPage) AddOptionalContentByString(contentStr string, oc OptionalContent) error {
+	if p.Resources == nil {
+		p.Resources = NewPdfPageResources()
+	}
+	name, err := p.Resources.AddOptionalContent(oc)
+	if err != nil {
+		return err
+	}
+	return p.AppendContentBytes([]byte(fmt.Sprintf("/OC /%s BDC\n%s\nEMC\n", name, contentStr)), true)
+}
+
+func ocGroupArray(groups []*OCGroup) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	for _, g := range groups {
+		arr.Append(g.ToPdfObject())
+	}
+	return arr
+}
+
+func ocNameArray(names []string) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	for _, name := range names {
+		arr.Append(core.MakeName(name))
+	}
+	return arr
+}
+
+// ocTextString returns `s` as a text string, encoded as UTF-16BE if it is not ASCII.
+func ocTextString(s string) *core.PdfObjectString {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return core.MakeEncodedString(s, true)
+		}
+	}
+	return core.MakeString(s)
+}
@@ -0,0 +1,162 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package model
+
+import (
+	"github.com/unidoc/unipdf/v4/core"
+)
+
+// PageImporter converts pages of existing documents into form XObjects, which
+// can be drawn any number of times, at any size, on other pages.
+//
+// The resources of imported pages are deep-copied, so that the resulting
+// forms do not share objects with the source documents. Each object is copied
+// once per importer: importing pages which share fonts or images, or importing
+// the same page repeatedly, reuses the copies made before, so shared objects
+// are written only once to the output document.
+type PageImporter struct {
+	copies map[core.PdfObject]core.PdfObject
+	forms  map[*PdfPage]*XObjectForm
+}
+
+// NewPageImporter returns a new page importer.
+func NewPageImporter() *PageImporter {
+	return &PageImporter{
+		copies: map[core.PdfObject]core.PdfObject{},
+		forms:  map[*PdfPage]*XObjectForm{},
+	}
+}
+
+// Import returns a form XObject holding the contents of the specified page,
+// which can belong to any document. The bounding box of the form is the
+// visible area of the page (its crop box). The matrix of the form applies the
+// rotation of the page and moves the visible area to the rectangle starting
+// at (0, 0), so that drawing the form is equivalent to drawing the page as
+// displayed by viewers. Annotations of the page are not included.
+// Importing the same page again returns the same form.
+func (pi *PageImporter) Import(page *PdfPage) (*XObjectForm, error) {
+	if form, ok := pi.forms[page]; ok {
+		return form, nil
+	}
+	box, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	visible := *box
+	if page.CropBox != nil {
+		visible = *page.CropBox
+	}
+	visible.Normalize()
+
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+
+	resources := NewPdfPageResources()
+	if page.Resources != nil {
+		dict, ok := core.GetDict(pi.copyObject(page.Resources.ToPdfObject()))
+		if ok {
+			if resources, err = NewPdfPageResourcesFromDict(dict); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var rotation int64
+	if page.Rotate != nil {
+		rotation = (*page.Rotate%360 + 360) % 360
+	}
+	llx, lly, urx, ury := visible.Llx, visible.Lly, visible.Urx, visible.Ury
+	matrix := []float64{1, 0, 0, 1, -llx, -lly}
+	switch rotation {
+	case 90:
+		matrix = []float64{0, -1, 1, 0, -lly, urx}
+	case 180:
+		matrix = []float64{-1, 0, 0, -1, urx, ury}
+	case 270:
+		matrix = []float64{0, 1, -1, 0, ury, -llx}
+	}
+
+	form := NewXObjectForm()
+	form.FormType = core.MakeInteger(1)
+	form.BBox = core.MakeArrayFromFloats([]float64{llx, lly, urx, ury})
+	form.Matrix = core.MakeArrayFromFloats(matrix)
+	form.Resources = resources
+	if page.Group != nil {
+		form.Group = pi.copyObject(page.Group)
+	}
+	if err := form.SetContentStream([]byte(content), core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+	pi.forms[page] = form
+	return form, nil
+}
+
+// copyObject returns a deep copy of the specified object. Containers are
+// copied once, later calls return the copy made before. Parent links are not
+// followed, so copying resources never pulls in the page tree.
+func (pi *PageImporter) copyObject(obj core.PdfObject) core.PdfObject {
+	if obj == nil {
+		return nil
+	}
+	if c, ok := pi.copies[obj]; ok {
+		return c
+	}
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		c := pi.copyObject(t.Resolve())
+		pi.copies[obj] = c
+		return c
+	case *core.PdfIndirectObject:
+		c := core.MakeIndirectObject(nil)
+		pi.copies[obj] = c
+		c.PdfObject = pi.copyObject(t.PdfObject)
+		return c
+	case *core.PdfObjectStream:
+		c := &core.PdfObjectStream{Stream: t.Stream, Lazy: t.Lazy, TempFile: t.TempFile}
+		pi.copies[obj] = c
+		c.PdfObjectDictionary = core.MakeDict()
+		if t.PdfObjectDictionary != nil {
+			pi.copyDict(c.PdfObjectDictionary, t.PdfObjectDictionary)
+		}
+		return c
+	case *core.PdfObjectDictionary:
+		c := core.MakeDict()
+		pi.copies[obj] = c
+		pi.copyDict(c, t)
+		return c
+	case *core.PdfObjectArray:
+		c := core.MakeArray()
+		pi.copies[obj] = c
+		for _, elem := range t.Elements() {
+			c.Append(pi.copyObject(elem))
+		}
+		return c
+	}
+	// Other objects are values which are never modified in place.
+	return obj
+}
+
+func (pi *PageImporter) copyDict(dst, src *core.PdfObjectDictionary) {
+	for _, key := range src.Keys() {
+		if key == "Parent" {
+			continue
+		}
+		dst.Set(key, pi.copyObject(src.Get(key)))
+	}
+}
+
+// ToXObjectForm returns a form XObject holding the contents of the page, as
+// described by PageImporter.Import. The resources of the page are copied on
+// each call; use a PageImporter to share them between imported pages.
+func (p *PdfPage) ToXObjectForm() (*XObjectForm, error) {
+	return NewPageImporter().Import(p)
+}
@@ -0,0 +1,354 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfua
+
+import (
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// checkPages checks the fonts of the pages and, if the document is tagged, the
+// content and annotations of the pages.
+func (v *validator) checkPages(tagged bool) error {
+	fonts := map[core.PdfObject]bool{}
+	for i, page := range v.reader.PageList {
+		num := i + 1
+		var resources *core.PdfObjectDictionary
+		if page.Resources != nil {
+			resources, _ = core.GetDict(page.Resources.ToPdfObject())
+		}
+		v.checkFonts(resources, fonts)
+		if !tagged {
+			continue
+		}
+		content, err := page.GetAllContentStreams()
+		if err != nil {
+			return err
+		}
+		c := &contentChecker{v: v, page: num, forms: map[*core.PdfObjectStream]bool{}}
+		var parents core.PdfObject
+		key, hasKey := core.GetIntVal(page.StructParents)
+		if hasKey {
+			parents = v.parentTree[int64(key)]
+		}
+		if err = c.check(content, resources, parents, true, markedState{}); err != nil {
+			return err
+		}
+		if err = v.checkAnnotations(page, num); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markedState is the state of the enclosing marked-content sequences.
+type markedState struct {
+	artifact bool
+	tagged   bool
+}
+
+// contentChecker checks that the content of a page is either tagged or marked
+// as artifact.
+type contentChecker struct {
+	v     *validator
+	page  int
+	forms map[*core.PdfObjectStream]bool
+}
+
+// paintingOperators are the operators painting content.
+var paintingOperators = map[string]bool{
+	"Tj": true, "TJ": true, "'": true, "\"": true, "S": true, "s": true,
+	"f": true, "F": true, "f*": true, "B": true, "B*": true, "b": true,
+	"b*": true, "sh": true, "BI": true,
+}
+
+// check checks the content stream. parents is the parent tree entry of the
+// marked content of the stream. If checkRefs is false, the marked-content
+// identifiers are not checked against the parent tree.
+func (c *contentChecker) check(content string, resources *core.PdfObjectDictionary, parents core.PdfObject, checkRefs bool, outer markedState) error {
+	v := c.v
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return err
+	}
+	var stack []markedState
+	current := func() markedState {
+		if len(stack) == 0 {
+			return outer
+		}
+		return stack[len(stack)-1]
+	}
+	for _, op := range *ops {
+		state := current()
+		switch op.Operand {
+		case "BMC", "BDC":
+			var tag string
+			if len(op.Params) > 0 {
+				tag, _ = core.GetNameVal(op.Params[0])
+			}
+			var props *core.PdfObjectDictionary
+			if op.Operand == "BDC" && len(op.Params) > 1 {
+				props = c.properties(op.Params[1], resources)
+			}
+			mcid, hasMCID := int64(0), false
+			if props != nil {
+				if id, ok := core.GetIntVal(props.Get("MCID")); ok {
+					mcid, hasMCID = int64(id), true
+				}
+			}
+			next := state
+			if tag == "Artifact" {
+				if state.tagged {
+					v.report("01-003", "content marked as artifact is present inside tagged content on page %d", c.page)
+				}
+				next.artifact = true
+			} else if hasMCID {
+				if state.artifact {
+					v.report("01-004", "tagged content is present inside content marked as artifact on page %d", c.page)
+				}
+				if checkRefs && !referencesMCID(parents, mcid) {
+					v.report("01-005", "marked content on page %d is not referenced by the structure tree", c.page)
+				}
+				next.tagged = true
+			}
+			stack = append(stack, next)
+		case "EMC":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case "Do":
+			if len(op.Params) == 0 {
+				continue
+			}
+			name, _ := core.GetNameVal(op.Params[0])
+			form := c.form(core.PdfObjectName(name), resources)
+			if form == nil {
+				c.paint(state)
+				continue
+			}
+			if c.forms[form] {
+				continue
+			}
+			c.forms[form] = true
+			formContent, err := core.DecodeStream(form)
+			if err != nil {
+				return err
+			}
+			formResources, ok := core.GetDict(form.Get("Resources"))
+			if !ok {
+				formResources = resources
+			}
+			formParents, formRefs := parents, false
+			if key, ok := core.GetIntVal(form.Get("StructParents")); ok {
+				formParents, formRefs = v.parentTree[int64(key)], true
+			}
+			err = c.check(string(formContent), formResources, formParents, formRefs, state)
+			delete(c.forms, form)
+			if err != nil {
+				return err
+			}
+		default:
+			if paintingOperators[op.Operand] {
+				c.paint(state)
+			}
+		}
+	}
+	return nil
+}
+
+// paint checks content painted in the marked-content state.
+func (c *contentChecker) paint(state markedState) {
+	if !state.artifact && !state.tagged {
+		c.v.report("01-005", "content on page %d is neither tagged nor marked as artifact", c.page)
+	}
+}
+
+// properties returns the property list of a marked-content sequence.
+func (c *contentChecker) properties(obj core.PdfObject, resources *core.PdfObjectDictionary) *core.PdfObjectDictionary {
+	if dict, ok := core.GetDict(obj); ok {
+		return dict
+	}
+	name, ok := core.GetNameVal(obj)
+	if !ok || resources == nil {
+		return nil
+	}
+	props, ok := core.GetDict(resources.Get("Properties"))
+	if !ok {
+		return nil
+	}
+	dict, _ := core.GetDict(props.Get(core.PdfObjectName(name)))
+	return dict
+}
+
+// form returns the form XObject of the resources named name, or nil if it is
+// not a form XObject.
+func (c *contentChecker) form(name core.PdfObjectName, resources *core.PdfObjectDictionary) *core.PdfObjectStream {
+	if resources == nil {
+		return nil
+	}
+	xobjects, ok := core.GetDict(resources.Get("XObject"))
+	if !ok {
+		return nil
+	}
+	stream, ok := core.GetStream(xobjects.Get(name))
+	if !ok {
+		return nil
+	}
+	if subtype, _ := core.GetNameVal(stream.Get("Subtype")); subtype != "Form" {
+		return nil
+	}
+	return stream
+}
+
+// referencesMCID returns true if the parent tree entry refers to a structure
+// element for the marked-content identifier.
+func referencesMCID(parents core.PdfObject, mcid int64) bool {
+	arr, ok := core.GetArray(parents)
+	if !ok || mcid < 0 || mcid >= int64(arr.Len()) {
+		return false
+	}
+	_, ok = core.GetDict(arr.Get(int(mcid)))
+	return ok
+}
+
+// checkAnnotations checks that the annotations of the page are tagged and
+// described, and that the tab order of the page follows the structure.
+func (v *validator) checkAnnotations(page *model.PdfPage, num int) error {
+	annots, err := page.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	visible := 0
+	for _, annot := range annots {
+		dict, ok := core.GetDict(annot.GetContainingPdfObject())
+		if !ok {
+			continue
+		}
+		subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+		if subtype == "Popup" || subtype == "PrinterMark" || subtype == "TrapNet" {
+			continue
+		}
+		if flags, ok := core.GetIntVal(annot.F); ok && flags&2 != 0 {
+			continue
+		}
+		visible++
+
+		want, rule, descRule := "Annot", "28-002", "28-004"
+		switch subtype {
+		case "Widget":
+			want, rule = "Form", "28-010"
+		case "Link":
+			want, rule, descRule = "Link", "28-011", "28-012"
+		}
+		var elem *core.PdfObjectDictionary
+		if key, ok := core.GetIntVal(annot.StructParent); ok {
+			elem, _ = core.GetDict(v.parentTree[int64(key)])
+		}
+		if elem == nil {
+			v.report(rule, "a %s annotation on page %d is not tagged", subtype, num)
+		} else {
+			name, _ := core.GetNameVal(elem.Get("S"))
+			if typ := v.standardType(name); typ != want {
+				v.report(rule, "a %s annotation on page %d is tagged as %s instead of %s", subtype, num, name, want)
+			}
+		}
+		if subtype != "Widget" && annot.Contents == nil && (elem == nil || elem.Get("Alt") == nil) {
+			v.report(descRule, "a %s annotation on page %d has no alternative description", subtype, num)
+		}
+	}
+	if visible == 0 {
+		return nil
+	}
+	tabs, ok := core.GetNameVal(page.Tabs)
+	switch {
+	case !ok:
+		v.report("28-008", "page %d contains annotations but has no Tabs entry", num)
+	case tabs != "S":
+		v.report("28-009", "page %d contains annotations but its tab order is %s instead of S", num, tabs)
+	}
+	return nil
+}
+
+// cidOrderings are the character collections whose characters are mapped to
+// Unicode by predefined CMaps.
+var cidOrderings = map[string]bool{"GB1": true, "CNS1": true, "Japan1": true, "Korea1": true}
+
+// checkFonts checks that the fonts of the resources and of their form
+// XObjects are embedded and mapped to Unicode.
+func (v *validator) checkFonts(resources *core.PdfObjectDictionary, visited map[core.PdfObject]bool) {
+	if resources == nil || visited[resources] {
+		return
+	}
+	visited[resources] = true
+	if fonts, ok := core.GetDict(resources.Get("Font")); ok {
+		for _, key := range fonts.Keys() {
+			font, ok := core.GetDict(fonts.Get(key))
+			if !ok || visited[font] {
+				continue
+			}
+			visited[font] = true
+			v.checkFont(font)
+		}
+	}
+	if xobjects, ok := core.GetDict(resources.Get("XObject")); ok {
+		for _, key := range xobjects.Keys() {
+			if stream, ok := core.GetStream(xobjects.Get(key)); ok {
+				if formResources, ok := core.GetDict(stream.Get("Resources")); ok {
+					v.checkFonts(formResources, visited)
+				}
+			}
+		}
+	}
+}
+
+// checkFont checks that the font program is embedded and that the character
+// codes of the font are mapped to Unicode.
+func (v *validator) checkFont(font *core.PdfObjectDictionary) {
+	subtype, _ := core.GetNameVal(font.Get("Subtype"))
+	if subtype == "Type3" {
+		return
+	}
+	name, _ := core.GetNameVal(font.Get("BaseFont"))
+	descriptor, _ := core.GetDict(font.Get("FontDescriptor"))
+	mapped := font.Get("ToUnicode") != nil
+	if subtype == "Type0" {
+		var cidFont *core.PdfObjectDictionary
+		if descendants, ok := core.GetArray(font.Get("DescendantFonts")); ok && descendants.Len() > 0 {
+			cidFont, _ = core.GetDict(descendants.Get(0))
+		}
+		if cidFont != nil {
+			descriptor, _ = core.GetDict(cidFont.Get("FontDescriptor"))
+		}
+		if !mapped {
+			encoding, ok := core.GetNameVal(font.Get("Encoding"))
+			if ok && encoding != "Identity-H" && encoding != "Identity-V" {
+				mapped = true
+			} else if cidFont != nil {
+				if info, ok := core.GetDict(cidFont.Get("CIDSystemInfo")); ok {
+					registry, _ := core.GetStringVal(info.Get("Registry"))
+					ordering, _ := core.GetStringVal(info.Get("Ordering"))
+					mapped = registry == "Adobe" && cidOrderings[ordering]
+				}
+			}
+		}
+	} else if !mapped {
+		mapped = font.Get("Encoding") != nil || subtype == "Type1" || subtype == "MMType1"
+	}
+
+	embedded := descriptor != nil && (descriptor.Get("FontFile") != nil ||
+		descriptor.Get("FontFile2") != nil || descriptor.Get("FontFile3") != nil)
+	if !embedded {
+		v.report("31-009", "the font %s is not embedded", name)
+	}
+	if !mapped {
+		v.report("31-027", "the character codes of the font %s cannot be mapped to Unicode", name)
+	}
+}
@@ -0,0 +1,121 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+// Package pdfua provides verification of documents with respect to the PDF/UA
+// accessibility standards. The checks cover the failure conditions of the
+// Matterhorn Protocol that can be verified by a machine, the violated rules are
+// identified by their Matterhorn failure condition number.
+// NOTE: This implementation is in experimental development state.
+//
+//	Keep in mind that it might change in the subsequent minor versions.
+package pdfua
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// Profile is the model.StandardValidator enhanced by the information about the
+// part of the PDF/UA standard.
+type Profile interface {
+	model.StandardValidator
+
+	// StandardName gets the human-readable name of the standard.
+	StandardName() string
+
+	// Part gets the part of the PDF/UA standard.
+	Part() int
+}
+
+// Validate checks if provided input document reader matches given PDF/UA profile.
+func Validate(d *model.CompliancePdfReader, profile Profile) error {
+	return profile.ValidateStandard(d)
+}
+
+// ViolatedRule is the structure that defines violated PDF/UA rule.
+type ViolatedRule struct {
+	// RuleNo is the Matterhorn Protocol failure condition, e.g. "13-004".
+	RuleNo string
+
+	// Detail describes the violation and where it occurs.
+	Detail string
+}
+
+// String gets a string representation of the violated rule.
+func (r ViolatedRule) String() string {
+	return fmt.Sprintf("%s: %s", r.RuleNo, r.Detail)
+}
+
+// VerificationError is the PDF/UA verification error structure, that contains
+// all violated rules.
+type VerificationError struct {
+	// ViolatedRules are the rules that were violated during error verification.
+	ViolatedRules []ViolatedRule
+
+	// Part defines the part of the standard on verification failed.
+	Part int
+}
+
+// Error implements error interface.
+func (e VerificationError) Error() string {
+	b := strings.Builder{}
+	b.WriteString("Standard: ")
+	b.WriteString(fmt.Sprintf("PDF/UA-%d", e.Part))
+	b.WriteString(" Violated rules: ")
+	for i, rule := range e.ViolatedRules {
+		b.WriteString(rule.String())
+		if i != len(e.ViolatedRules)-1 {
+			b.WriteRune('\n')
+		}
+	}
+	return b.String()
+}
+
+// Profile1 is the PDF/UA-1 (ISO 14289-1) validation profile.
+type Profile1 struct{ profile }
+
+// NewProfile1 creates a new Profile1.
+func NewProfile1() *Profile1 { return &Profile1{profile{part: 1}} }
+
+// Profile2 is the PDF/UA-2 (ISO 14289-2) validation profile. The standard
+// structure types of both PDF 1.7 and PDF 2.0 are accepted.
+type Profile2 struct{ profile }
+
+// NewProfile2 creates a new Profile2.
+func NewProfile2() *Profile2 { return &Profile2{profile{part: 2}} }
+
+var (
+	_ Profile = (*Profile1)(nil)
+	_ Profile = (*Profile2)(nil)
+)
+
+type profile struct {
+	part int
+}
+
+// StandardName gets the human-readable name of the standard.
+func (p *profile) StandardName() string { return fmt.Sprintf("PDF/UA-%d", p.part) }
+
+// Part gets the part of the PDF/UA standard.
+func (p *profile) Part() int { return p.part }
+
+// ValidateStandard checks if the input reader matches the PDF/UA profile.
+// Returns a VerificationError listing the violated rules, if any.
+func (p *profile) ValidateStandard(r *model.CompliancePdfReader) error {
+	v := newValidator(r, p.part)
+	if err := v.validate(); err != nil {
+		return err
+	}
+	if len(v.rules) == 0 {
+		return nil
+	}
+	return VerificationError{ViolatedRules: v.rules, Part: p.part}
+}
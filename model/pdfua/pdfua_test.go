@@ -0,0 +1,95 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfua
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// makePdf returns a PDF document made of the specified objects, numbered
+// from 1, the first one being the catalog.
+func makePdf(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// nonCompliantPdf returns a tagged document having a figure without
+// alternative text, untagged text using a font which is not embedded, and no
+// XMP metadata. The extra entries are added to the catalog and to the
+// structure element of the figure.
+func nonCompliantPdf(catalogEntries, figureEntries string) []byte {
+	content := "/Figure <</MCID 0>> BDC 0 0 10 10 re f EMC BT /F1 12 Tf (Untagged) Tj ET"
+	return makePdf(
+		"<< /Type /Catalog /Pages 2 0 R /StructTreeRoot 5 0 R /MarkInfo << /Marked true >> /ViewerPreferences << /DisplayDocTitle false >> "+catalogEntries+" >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 100] /Contents 4 0 R /StructParents 0 /Resources << /Font << /F1 7 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /StructTreeRoot /K 6 0 R /ParentTree << /Nums [0 [6 0 R]] >> >>",
+		"<< /Type /StructElem /S /Figure /P 5 0 R /Pg 3 0 R /K 0 "+figureEntries+" >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	)
+}
+
+func TestValidateNonCompliant(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected []string
+	}{
+		{
+			name:     "missing language and alternative text",
+			data:     nonCompliantPdf("", ""),
+			expected: []string{"06-001", "07-002", "11-001", "13-004", "31-009", "01-005"},
+		},
+		{
+			name:     "with language and alternative text",
+			data:     nonCompliantPdf("/Lang (en-US)", "/Alt (A square)"),
+			expected: []string{"06-001", "07-002", "31-009", "01-005"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader, err := model.NewCompliancePdfReader(bytes.NewReader(tc.data))
+			if err != nil {
+				t.Fatalf("Error: %v", err)
+			}
+			err = Validate(reader, NewProfile1())
+			var verr VerificationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected a verification error, got %v", err)
+			}
+			var rules []string
+			for _, rule := range verr.ViolatedRules {
+				rules = append(rules, rule.RuleNo)
+			}
+			if !reflect.DeepEqual(rules, tc.expected) {
+				t.Fatalf("expected rules %v, got %v", tc.expected, verr.ViolatedRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfua
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/unidoc/unipdf/v4/core"
+)
+
+// standardTypes are the standard structure types of PDF 1.7.
+var standardTypes = map[string]bool{
+	"Document": true, "Part": true, "Art": true, "Sect": true, "Div": true,
+	"BlockQuote": true, "Caption": true, "TOC": true, "TOCI": true, "Index": true,
+	"NonStruct": true, "Private": true, "P": true, "H": true, "H1": true,
+	"H2": true, "H3": true, "H4": true, "H5": true, "H6": true, "L": true,
+	"LI": true, "Lbl": true, "LBody": true, "Table": true, "TR": true,
+	"TH": true, "TD": true, "THead": true, "TBody": true, "TFoot": true,
+	"Span": true, "Quote": true, "Note": true, "Reference": true,
+	"BibEntry": true, "Code": true, "Link": true, "Annot": true, "Ruby": true,
+	"RB": true, "RT": true, "RP": true, "Warichu": true, "WT": true, "WP": true,
+	"Figure": true, "Formula": true, "Form": true,
+}
+
+// standardTypes2 are the standard structure types added by PDF 2.0, besides
+// the unbounded numbered headings.
+var standardTypes2 = map[string]bool{
+	"DocumentFragment": true, "Aside": true, "Title": true, "FENote": true,
+	"Sub": true, "Em": true, "Strong": true, "Artifact": true,
+}
+
+var reNumberedHeading = regexp.MustCompile(`^H([1-9][0-9]*)$`)
+
+// isStandard returns true if name is a standard structure type.
+func (v *validator) isStandard(name string) bool {
+	if standardTypes[name] {
+		return true
+	}
+	return v.part >= 2 && (standardTypes2[name] || reNumberedHeading.MatchString(name))
+}
+
+// standardType returns the standard structure type name is mapped to by the
+// role map, or an empty string if there is none.
+func (v *validator) standardType(name string) string {
+	visited := map[string]bool{}
+	for !v.isStandard(name) {
+		if visited[name] || v.roleMap == nil {
+			return ""
+		}
+		visited[name] = true
+		mapped, ok := core.GetNameVal(v.roleMap.Get(core.PdfObjectName(name)))
+		if !ok {
+			return ""
+		}
+		name = mapped
+	}
+	return name
+}
+
+// checkStructTree checks the structure tree of the document. Returns false if
+// the document is not tagged.
+func (v *validator) checkStructTree() bool {
+	if obj, ok := v.reader.GetCatalogMarkInfo(); ok {
+		if dict, ok := core.GetDict(obj); ok {
+			if suspects, _ := core.GetBoolVal(dict.Get("Suspects")); suspects {
+				v.report("01-007", "the Suspects entry of the mark info dictionary is true")
+			}
+		}
+	}
+	obj, ok := v.reader.GetCatalogStructTreeRoot()
+	if !ok {
+		v.report("01-005", "the document is not tagged: the document catalog has no structure tree")
+		return false
+	}
+	root, ok := core.GetDict(obj)
+	if !ok {
+		v.report("01-005", "the document is not tagged: the structure tree root is not a dictionary")
+		return false
+	}
+	v.root = root
+	v.roleMap, _ = core.GetDict(root.Get("RoleMap"))
+	v.classMap, _ = core.GetDict(root.Get("ClassMap"))
+	walkNumberTree(root.Get("ParentTree"), map[core.PdfObject]bool{}, func(key int64, value core.PdfObject) {
+		v.parentTree[key] = value
+	})
+
+	v.checkRoleMap()
+	w := &structWalker{v: v, visited: map[core.PdfObject]bool{}}
+	w.walk(structKids(root), v.hasLang)
+	return true
+}
+
+// checkRoleMap checks that the role map entries terminate with a standard
+// structure type and that no standard structure type is remapped.
+func (v *validator) checkRoleMap() {
+	if v.roleMap == nil {
+		return
+	}
+	for _, key := range v.roleMap.Keys() {
+		name := string(key)
+		if v.isStandard(name) {
+			mapped, _ := core.GetNameVal(v.roleMap.Get(key))
+			v.report("02-004", "the standard structure type %s is remapped to %s", name, mapped)
+			continue
+		}
+		visited := map[string]bool{}
+		for !v.isStandard(name) {
+			if visited[name] {
+				v.report("02-003", "the role map contains a circular mapping of %s", key)
+				break
+			}
+			visited[name] = true
+			mapped, ok := core.GetNameVal(v.roleMap.Get(core.PdfObjectName(name)))
+			if !ok {
+				v.report("02-001", "the role mapping of %s does not terminate with a standard structure type", key)
+				break
+			}
+			name = mapped
+		}
+	}
+}
+
+// structWalker visits the structure elements in logical order.
+type structWalker struct {
+	v       *validator
+	visited map[core.PdfObject]bool
+
+	headingLevel int
+	usedH        bool
+	usedHn       bool
+}
+
+// walk checks the structure elements and their descendants. lang is true if
+// the natural language of the elements is specified by an ancestor.
+func (w *structWalker) walk(elems []*core.PdfObjectDictionary, lang bool) {
+	v := w.v
+	numH := 0
+	for _, elem := range elems {
+		if w.visited[elem] {
+			continue
+		}
+		w.visited[elem] = true
+
+		name, _ := core.GetNameVal(elem.Get("S"))
+		typ := v.standardType(name)
+		if typ == "" && (v.roleMap == nil || v.roleMap.Get(core.PdfObjectName(name)) == nil) {
+			v.report("02-001", "the non-standard structure type %s is not role mapped", name)
+		}
+		elemLang := lang
+		if s, ok := core.GetStringVal(elem.Get("Lang")); ok && s != "" {
+			elemLang = true
+		}
+		if !elemLang && hasContent(elem) {
+			v.report("11-001", "the natural language of the content of %s elements cannot be determined", name)
+		}
+
+		switch {
+		case typ == "Figure":
+			if elem.Get("Alt") == nil && elem.Get("ActualText") == nil {
+				v.report("13-004", "Figure element%s has no alternative text", v.location(elem))
+			}
+		case typ == "H":
+			w.usedH = true
+			numH++
+		case typ == "Table":
+			v.checkTable(elem)
+		}
+		if m := reNumberedHeading.FindStringSubmatch(typ); m != nil {
+			w.usedHn = true
+			level, _ := strconv.Atoi(m[1])
+			if w.headingLevel == 0 && level != 1 {
+				v.report("14-002", "the first numbered heading is %s instead of H1", typ)
+			} else if level > w.headingLevel+1 && w.headingLevel > 0 {
+				v.report("14-003", "the heading level skips from H%d to %s%s", w.headingLevel, typ, v.location(elem))
+			}
+			w.headingLevel = level
+		}
+		if w.usedH && w.usedHn {
+			v.report("14-007", "the document uses both H and numbered heading elements")
+		}
+
+		w.walk(structKids(elem), elemLang)
+	}
+	if numH > 1 {
+		v.report("14-006", "a structure element contains more than one H element")
+	}
+}
+
+// checkTable checks that the header cells of a table have a Scope attribute,
+// unless the cells are associated to their headers by the Headers attribute.
+func (v *validator) checkTable(table *core.PdfObjectDictionary) {
+	var headers []*core.PdfObjectDictionary
+	usesHeaders := false
+	var collect func(elems []*core.PdfObjectDictionary)
+	collect = func(elems []*core.PdfObjectDictionary) {
+		for _, elem := range elems {
+			name, _ := core.GetNameVal(elem.Get("S"))
+			switch v.standardType(name) {
+			case "TR", "THead", "TBody", "TFoot":
+				collect(structKids(elem))
+			case "TH":
+				headers = append(headers, elem)
+				fallthrough
+			case "TD":
+				if v.attribute(elem, "Table", "Headers") != nil {
+					usesHeaders = true
+				}
+			}
+		}
+	}
+	collect(structKids(table))
+	if usesHeaders {
+		return
+	}
+	for _, th := range headers {
+		if v.attribute(th, "Table", "Scope") == nil {
+			v.report("15-003", "a TH cell of the table%s has no Scope attribute", v.location(table))
+			return
+		}
+	}
+}
+
+// attribute returns the value of the attribute key of the owner, specified
+// by the structure element or its attribute classes.
+func (v *validator) attribute(elem *core.PdfObjectDictionary, owner, key core.PdfObjectName) core.PdfObject {
+	find := func(obj core.PdfObject) core.PdfObject {
+		var dicts []core.PdfObject
+		switch t := core.TraceToDirectObject(obj).(type) {
+		case *core.PdfObjectDictionary:
+			dicts = []core.PdfObject{t}
+		case *core.PdfObjectArray:
+			dicts = t.Elements()
+		}
+		for _, d := range dicts {
+			dict, ok := core.GetDict(d)
+			if !ok {
+				continue
+			}
+			if o, ok := core.GetNameVal(dict.Get("O")); ok && o == string(owner) && dict.Get(key) != nil {
+				return dict.Get(key)
+			}
+		}
+		return nil
+	}
+	if value := find(elem.Get("A")); value != nil {
+		return value
+	}
+	if v.classMap == nil {
+		return nil
+	}
+	var classes []core.PdfObject
+	switch t := core.TraceToDirectObject(elem.Get("C")).(type) {
+	case *core.PdfObjectName:
+		classes = []core.PdfObject{t}
+	case *core.PdfObjectArray:
+		classes = t.Elements()
+	}
+	for _, class := range classes {
+		if name, ok := core.GetNameVal(class); ok {
+			if value := find(v.classMap.Get(core.PdfObjectName(name))); value != nil {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// location returns a description of the page of the structure element.
+func (v *validator) location(elem *core.PdfObjectDictionary) string {
+	if pg := elem.Get("Pg"); pg != nil {
+		if num := v.pageNumber(pg); num > 0 {
+			return " on page " + strconv.Itoa(num)
+		}
+	}
+	return ""
+}
+
+// pageNumber returns the number of the page object, or 0 if not found.
+func (v *validator) pageNumber(obj core.PdfObject) int {
+	target := core.ResolveReference(obj)
+	for i, page := range v.reader.PageList {
+		if core.PdfObject(page.GetPageAsIndirectObject()) == target {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// structKids returns the structure elements among the kids of the structure
+// element or structure tree root.
+func structKids(elem *core.PdfObjectDictionary) []*core.PdfObjectDictionary {
+	var kids []core.PdfObject
+	switch k := core.TraceToDirectObject(elem.Get("K")).(type) {
+	case *core.PdfObjectArray:
+		kids = k.Elements()
+	case *core.PdfObjectDictionary:
+		kids = []core.PdfObject{k}
+	}
+	var elems []*core.PdfObjectDictionary
+	for _, kid := range kids {
+		if dict, ok := core.GetDict(kid); ok && dict.Get("S") != nil {
+			elems = append(elems, dict)
+		}
+	}
+	return elems
+}
+
+// hasContent returns true if the structure element has marked-content kids.
+func hasContent(elem *core.PdfObjectDictionary) bool {
+	var kids []core.PdfObject
+	switch k := core.TraceToDirectObject(elem.Get("K")).(type) {
+	case *core.PdfObjectArray:
+		kids = k.Elements()
+	default:
+		kids = []core.PdfObject{k}
+	}
+	for _, kid := range kids {
+		switch t := core.TraceToDirectObject(kid).(type) {
+		case *core.PdfObjectInteger:
+			return true
+		case *core.PdfObjectDictionary:
+			if name, ok := core.GetNameVal(t.Get("Type")); ok && name == "MCR" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// walkNumberTree calls fn for each entry of the number tree.
+func walkNumberTree(node core.PdfObject, visited map[core.PdfObject]bool, fn func(int64, core.PdfObject)) {
+	dict, ok := core.GetDict(node)
+	if !ok || visited[dict] {
+		return
+	}
+	visited[dict] = true
+	if nums, ok := core.GetArray(dict.Get("Nums")); ok {
+		for i := 0; i+1 < nums.Len(); i += 2 {
+			if key, ok := core.GetIntVal(nums.Get(i)); ok {
+				fn(int64(key), nums.Get(i+1))
+			}
+		}
+	}
+	if kids, ok := core.GetArray(dict.Get("Kids")); ok {
+		for _, kid := range kids.Elements() {
+			walkNumberTree(kid, visited, fn)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfua
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// validator holds the state of the validation of a document.
+type validator struct {
+	reader *model.CompliancePdfReader
+	part   int
+
+	rules    []ViolatedRule
+	reported map[ViolatedRule]bool
+
+	root       *core.PdfObjectDictionary
+	roleMap    *core.PdfObjectDictionary
+	classMap   *core.PdfObjectDictionary
+	parentTree map[int64]core.PdfObject
+	hasLang    bool
+}
+
+func newValidator(r *model.CompliancePdfReader, part int) *validator {
+	return &validator{
+		reader:     r,
+		part:       part,
+		reported:   map[ViolatedRule]bool{},
+		parentTree: map[int64]core.PdfObject{},
+	}
+}
+
+// report adds a violated rule, unless reported already.
+func (v *validator) report(ruleNo, format string, args ...interface{}) {
+	rule := ViolatedRule{RuleNo: ruleNo, Detail: fmt.Sprintf(format, args...)}
+	if v.reported[rule] {
+		return
+	}
+	v.reported[rule] = true
+	v.rules = append(v.rules, rule)
+}
+
+// validate runs all the checks.
+func (v *validator) validate() error {
+	v.checkMetadata()
+	v.checkViewerPreferences()
+	if lang, ok := v.reader.GetCatalogLanguage(); ok {
+		if s, ok := core.GetStringVal(lang); ok && s != "" {
+			v.hasLang = true
+		}
+	}
+	return v.checkPages(v.checkStructTree())
+}
+
+var (
+	reUAPart  = regexp.MustCompile(`pdfuaid:part\s*(?:=\s*["'](\d+)["']|>\s*(\d+)\s*<)`)
+	reDCTitle = regexp.MustCompile(`<dc:title[\s>]`)
+)
+
+// checkMetadata checks the PDF/UA identification and the title in the XMP
+// metadata of the document.
+func (v *validator) checkMetadata() {
+	obj, ok := v.reader.GetCatalogMetadata()
+	if !ok {
+		v.report("06-001", "the document catalog does not contain an XMP metadata stream")
+		return
+	}
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		v.report("06-001", "the metadata entry of the document catalog is not a stream")
+		return
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		v.report("06-001", "the XMP metadata stream cannot be decoded: %v", err)
+		return
+	}
+	match := reUAPart.FindSubmatch(data)
+	switch {
+	case match == nil:
+		v.report("06-002", "the XMP metadata does not include the PDF/UA identifier")
+	case string(match[1])+string(match[2]) != fmt.Sprint(v.part):
+		v.report("06-002", "the XMP metadata identifies PDF/UA-%s instead of PDF/UA-%d", string(match[1])+string(match[2]), v.part)
+	}
+	if !reDCTitle.Match(data) {
+		v.report("06-003", "the XMP metadata does not contain a dc:title entry")
+	}
+}
+
+// checkViewerPreferences checks that viewers display the title of the
+// document.
+func (v *validator) checkViewerPreferences() {
+	var display core.PdfObject
+	if obj, ok := v.reader.GetCatalogViewerPreferences(); ok {
+		if dict, ok := core.GetDict(obj); ok {
+			display = dict.Get("DisplayDocTitle")
+		}
+	}
+	if display == nil {
+		v.report("07-001", "the viewer preferences do not contain a DisplayDocTitle entry")
+		return
+	}
+	if b, ok := core.GetBoolVal(display); !ok || !b {
+		v.report("07-002", "the DisplayDocTitle entry of the viewer preferences is not true")
+	}
+}
@@ -0,0 +1,22 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package model
+
+// SetParentField sets the field the widget annotation belongs to. The widget
+// becomes a kid of the field when the field is written.
+// NOTE: The widget must not be merged with another field dictionary, as the
+// dictionary entries of that field would remain in the widget dictionary.
+func (widget *PdfAnnotationWidget) SetParentField(field *PdfField) {
+	widget._afd = field
+	widget.Parent = nil
+	if field != nil {
+		widget.Parent = field.GetContainingPdfObject()
+	}
+}
@@ -0,0 +1,622 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfutil
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/internal/transform"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// DuplexMode specifies how the sheets of an imposed document are turned over
+// when printing on both sides. It determines the position and orientation of
+// the pages placed on the back side of each sheet.
+type DuplexMode int
+
+const (
+	// DuplexNone treats every sheet side as a front side.
+	DuplexNone DuplexMode = iota
+
+	// DuplexTurn is used when the sheet is turned over its vertical edge
+	// (work and turn). The columns of the back side are mirrored.
+	DuplexTurn
+
+	// DuplexTumble is used when the sheet is turned over its horizontal edge
+	// (work and tumble). The rows of the back side are mirrored and its pages
+	// are rotated by 180 degrees.
+	DuplexTumble
+)
+
+// ImpositionOptions configures the layout of the sheets generated by
+// ImposeNUp, ImposeBooklet and ImposeStepAndRepeat.
+//
+// Pages are placed by their trim box (TrimBox, defaulting to CropBox, which
+// defaults to MediaBox) on a grid of equally sized cells. When the sheet size
+// is not specified, it is computed from the size of the largest page.
+type ImpositionOptions struct {
+	// Size of the output sheets. If zero, the sheet is sized to fit the grid.
+	SheetWidth  float64
+	SheetHeight float64
+
+	// Number of cells of the grid.
+	Columns int
+	Rows    int
+
+	// Minimum distance between the grid and the edges of the sheet.
+	Margin float64
+
+	// Horizontal and vertical distance between cells.
+	GutterX float64
+	GutterY float64
+
+	// Scale applied to the pages. If zero, the pages are scaled to fit the
+	// sheet when a sheet size is specified, or not scaled otherwise.
+	Scale float64
+
+	// AutoRotate rotates pages by 90 degrees when their orientation differs
+	// from the orientation of the cells. Requires a sheet size.
+	AutoRotate bool
+
+	// Bleed is the maximum amount of bleed shown around each page, taken from
+	// its BleedBox (defaulting to CropBox). The bleed never extends past the
+	// middle of the gutters. If zero, pages are clipped to their trim box.
+	Bleed float64
+
+	// CropMarks draws crop marks outside the grid, aligned with the trim
+	// edges of the cells.
+	CropMarks bool
+
+	// Length, distance from the trim edge and line width of the crop marks.
+	// If zero, the length defaults to 12, the offset defaults to the larger
+	// of 3 and Bleed and the line width defaults to 0.25.
+	CropMarkLength    float64
+	CropMarkOffset    float64
+	CropMarkLineWidth float64
+
+	// Duplex determines the layout of the back side of the sheets. Sheet
+	// sides alternate between front and back when not DuplexNone.
+	Duplex DuplexMode
+}
+
+// NewImpositionOptions returns imposition options for a grid of the specified
+// number of columns and rows, on sheets of the specified size.
+func NewImpositionOptions(sheetWidth, sheetHeight float64, columns, rows int) *ImpositionOptions {
+	return &ImpositionOptions{
+		SheetWidth:  sheetWidth,
+		SheetHeight: sheetHeight,
+		Columns:     columns,
+		Rows:        rows,
+	}
+}
+
+// ImposeNUp places consecutive pages on the cells of each sheet side, in
+// reading order: left to right, top to bottom. When duplex is enabled, the
+// cells of each back side are arranged so that every cell lies behind the
+// same cell of the front side.
+func ImposeNUp(pages []*model.PdfPage, opts *ImpositionOptions) ([]*model.PdfPage, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	cells := opts.Columns * opts.Rows
+	var sides [][]*model.PdfPage
+	for i := 0; i < len(pages); i += cells {
+		side := make([]*model.PdfPage, cells)
+		copy(side, pages[i:])
+		sides = append(sides, side)
+	}
+	return impose(sides, opts, false)
+}
+
+// ImposeBooklet arranges the pages for saddle-stitch binding: each sheet side
+// holds two pages next to each other, and folding the stacked sheets in the
+// middle gives the pages in order. The page count is padded with blank pages
+// to a multiple of 4. The grid is always 2 columns by 1 row and sheets are
+// printed on both sides, using DuplexTurn unless DuplexTumble is specified.
+func ImposeBooklet(pages []*model.PdfPage, opts *ImpositionOptions) ([]*model.PdfPage, error) {
+	bopts := *opts
+	bopts.Columns, bopts.Rows = 2, 1
+	if bopts.Duplex == DuplexNone {
+		bopts.Duplex = DuplexTurn
+	}
+	if err := bopts.validate(); err != nil {
+		return nil, err
+	}
+
+	n := (len(pages) + 3) / 4 * 4
+	page := func(i int) *model.PdfPage {
+		if i < len(pages) {
+			return pages[i]
+		}
+		return nil
+	}
+	var sides [][]*model.PdfPage
+	for i := 0; i < n/2; i += 2 {
+		front := []*model.PdfPage{page(n - 1 - i), page(i)}
+		back := []*model.PdfPage{page(n - 2 - i), page(i + 1)}
+		sides = append(sides, front, back)
+	}
+	return impose(sides, &bopts, true)
+}
+
+// ImposeStepAndRepeat fills all cells of a sheet side with the same page. When
+// duplex is enabled, pages alternate between the front and the back side of
+// the sheets.
+func ImposeStepAndRepeat(pages []*model.PdfPage, opts *ImpositionOptions) ([]*model.PdfPage, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	cells := opts.Columns * opts.Rows
+	var sides [][]*model.PdfPage
+	for _, page := range pages {
+		side := make([]*model.PdfPage, cells)
+		for i := range side {
+			side[i] = page
+		}
+		sides = append(sides, side)
+	}
+	if opts.Duplex != DuplexNone && len(sides)%2 == 1 {
+		sides = append(sides, make([]*model.PdfPage, cells))
+	}
+	return impose(sides, opts, false)
+}
+
+// NUpPdf imposes the pages of inputPath PDF file using ImposeNUp and saves the
+// result as outputPath PDF file.
+func NUpPdf(inputPath, outputPath string, opts *ImpositionOptions) error {
+	return imposeFile(inputPath, outputPath, opts, ImposeNUp)
+}
+
+// BookletPdf imposes the pages of inputPath PDF file using ImposeBooklet and
+// saves the result as outputPath PDF file.
+func BookletPdf(inputPath, outputPath string, opts *ImpositionOptions) error {
+	return imposeFile(inputPath, outputPath, opts, ImposeBooklet)
+}
+
+// StepAndRepeatPdf imposes the pages of inputPath PDF file using
+// ImposeStepAndRepeat and saves the result as outputPath PDF file.
+func StepAndRepeatPdf(inputPath, outputPath string, opts *ImpositionOptions) error {
+	return imposeFile(inputPath, outputPath, opts, ImposeStepAndRepeat)
+}
+
+func imposeFile(inputPath, outputPath string,
+	opts *ImpositionOptions,
+	imposeFunc func([]*model.PdfPage, *ImpositionOptions) ([]*model.PdfPage, error)) error {
+	reader, file, err := model.NewPdfReaderFromFile(inputPath, nil)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	pages := make([]*model.PdfPage, numPages)
+	for i := range pages {
+		if pages[i], err = reader.GetPage(i + 1); err != nil {
+			return err
+		}
+	}
+
+	sheets, err := imposeFunc(pages, opts)
+	if err != nil {
+		return err
+	}
+	writer := model.NewPdfWriter()
+	for _, sheet := range sheets {
+		if err = writer.AddPage(sheet); err != nil {
+			return err
+		}
+	}
+	return writer.WriteToFile(outputPath)
+}
+
+func (opts *ImpositionOptions) validate() error {
+	if opts == nil {
+		return errors.New("imposition options required")
+	}
+	if opts.Columns < 1 || opts.Rows < 1 {
+		return errors.New("imposition grid must have at least one column and one row")
+	}
+	if (opts.SheetWidth == 0) != (opts.SheetHeight == 0) || opts.SheetWidth < 0 || opts.SheetHeight < 0 {
+		return errors.New("invalid imposition sheet size")
+	}
+	if opts.Scale < 0 || opts.Margin < 0 || opts.GutterX < 0 || opts.GutterY < 0 || opts.Bleed < 0 {
+		return errors.New("invalid imposition options")
+	}
+	return nil
+}
+
+// cropMarkGeometry returns the length, offset and line width of the crop
+// marks, applying defaults.
+func (opts *ImpositionOptions) cropMarkGeometry() (float64, float64, float64) {
+	length := opts.CropMarkLength
+	if length <= 0 {
+		length = 12
+	}
+	offset := opts.CropMarkOffset
+	if offset <= 0 {
+		offset = math.Max(3, opts.Bleed)
+	}
+	lineWidth := opts.CropMarkLineWidth
+	if lineWidth <= 0 {
+		lineWidth = 0.25
+	}
+	return length, offset, lineWidth
+}
+
+// imposedPage holds the geometry of a page converted to a form XObject. The
+// trim and bleed boxes are expressed in the coordinate system of the parent
+// of the form, i.e. after applying the form matrix.
+type imposedPage struct {
+	form   *model.XObjectForm
+	trim   model.PdfRectangle
+	bleed  model.PdfRectangle
+	rotate bool
+}
+
+func (p *imposedPage) size() (float64, float64) {
+	if p.rotate {
+		return p.trim.Height(), p.trim.Width()
+	}
+	return p.trim.Width(), p.trim.Height()
+}
+
+// impose generates a sheet for each of the specified sides. Each side lists
+// the pages placed on the cells of the grid, in reading order. Nil entries
+// are left blank. When duplex is enabled, odd sides are back sides, which
+// are listed as seen through the front side. If spine is true, the pages of
+// the 2 columns of the grid are aligned to the middle of the grid instead of
+// being centered in their cells.
+func impose(sides [][]*model.PdfPage, opts *ImpositionOptions, spine bool) ([]*model.PdfPage, error) {
+	if len(sides) == 0 {
+		return nil, errors.New("no pages to impose")
+	}
+
+	importer := model.NewPageImporter()
+	imported := map[*model.PdfPage]*imposedPage{}
+	for _, side := range sides {
+		for _, page := range side {
+			if page == nil || imported[page] != nil {
+				continue
+			}
+			ip, err := importImposedPage(importer, page)
+			if err != nil {
+				return nil, err
+			}
+			imported[page] = ip
+		}
+	}
+	if len(imported) == 0 {
+		return nil, errors.New("no pages to impose")
+	}
+
+	cols, rows := float64(opts.Columns), float64(opts.Rows)
+	hasSheet := opts.SheetWidth > 0
+	var availWidth, availHeight float64
+	if hasSheet {
+		availWidth = (opts.SheetWidth - 2*opts.Margin - (cols-1)*opts.GutterX) / cols
+		availHeight = (opts.SheetHeight - 2*opts.Margin - (rows-1)*opts.GutterY) / rows
+		if availWidth <= 0 || availHeight <= 0 {
+			return nil, errors.New("imposition grid does not fit the sheet")
+		}
+	}
+
+	// The cells are sized to fit the largest page.
+	var pageWidth, pageHeight float64
+	for _, ip := range imported {
+		w, h := ip.size()
+		if opts.AutoRotate && hasSheet && (w > h) != (availWidth > availHeight) && w != h {
+			ip.rotate = true
+			w, h = h, w
+		}
+		pageWidth = math.Max(pageWidth, w)
+		pageHeight = math.Max(pageHeight, h)
+	}
+	if pageWidth <= 0 || pageHeight <= 0 {
+		return nil, errors.New("imposed pages have an empty trim box")
+	}
+
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+		if hasSheet {
+			scale = math.Min(availWidth/pageWidth, availHeight/pageHeight)
+		}
+	}
+	cellWidth, cellHeight := pageWidth*scale, pageHeight*scale
+	gridWidth := cols*cellWidth + (cols-1)*opts.GutterX
+	gridHeight := rows*cellHeight + (rows-1)*opts.GutterY
+
+	sheetWidth, sheetHeight := opts.SheetWidth, opts.SheetHeight
+	if !hasSheet {
+		margin := opts.Margin
+		if opts.CropMarks {
+			length, offset, _ := opts.cropMarkGeometry()
+			margin = math.Max(margin, offset+length)
+		}
+		sheetWidth = gridWidth + 2*margin
+		sheetHeight = gridHeight + 2*margin
+	}
+	if gridWidth > sheetWidth+1e-6 || gridHeight > sheetHeight+1e-6 {
+		return nil, errors.New("imposed pages do not fit the sheet")
+	}
+	gridX := (sheetWidth - gridWidth) / 2
+	gridY := (sheetHeight - gridHeight) / 2
+
+	layout := &impositionLayout{
+		opts:       opts,
+		scale:      scale,
+		cellWidth:  cellWidth,
+		cellHeight: cellHeight,
+		gridX:      gridX,
+		gridY:      gridY,
+		gridWidth:  gridWidth,
+		gridHeight: gridHeight,
+		spine:      spine,
+	}
+
+	sheets := make([]*model.PdfPage, 0, len(sides))
+	for i, side := range sides {
+		back := opts.Duplex != DuplexNone && i%2 == 1
+		sheet, err := layout.drawSheet(side, imported, back, sheetWidth, sheetHeight)
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, nil
+}
+
+// importImposedPage converts the page to a form XObject and computes its trim
+// and bleed boxes.
+func importImposedPage(importer *model.PageImporter, page *model.PdfPage) (*imposedPage, error) {
+	form, err := importer.Import(page)
+	if err != nil {
+		return nil, err
+	}
+	mediaBox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+
+	// Show the whole media box, so that the bleed area is not clipped by the
+	// crop box. The visible region is clipped by the imposition.
+	form.BBox = mediaBox.ToPdfObject()
+
+	cropBox := mediaBox
+	if page.CropBox != nil {
+		cropBox = page.CropBox
+	}
+	trimBox, bleedBox := cropBox, cropBox
+	if page.TrimBox != nil {
+		trimBox = page.TrimBox
+	}
+	if page.BleedBox != nil {
+		bleedBox = page.BleedBox
+	}
+
+	matrix := transform.IdentityMatrix()
+	if values, err := core.GetNumbersAsFloat(formElements(form.Matrix)); err == nil && len(values) == 6 {
+		matrix = transform.NewMatrix(values[0], values[1], values[2], values[3], values[4], values[5])
+	}
+	return &imposedPage{
+		form:  form,
+		trim:  transformRect(*trimBox, matrix),
+		bleed: transformRect(*bleedBox, matrix),
+	}, nil
+}
+
+func formElements(obj core.PdfObject) []core.PdfObject {
+	if arr, ok := core.GetArray(obj); ok && arr != nil {
+		return arr.Elements()
+	}
+	return nil
+}
+
+// transformRect returns the bounding box of the rectangle transformed by the
+// specified matrix.
+func transformRect(rect model.PdfRectangle, m transform.Matrix) model.PdfRectangle {
+	rect.Normalize()
+	var out model.PdfRectangle
+	corners := [][2]float64{{rect.Llx, rect.Lly}, {rect.Urx, rect.Lly}, {rect.Urx, rect.Ury}, {rect.Llx, rect.Ury}}
+	for i, corner := range corners {
+		x, y := m.Transform(corner[0], corner[1])
+		if i == 0 {
+			out = model.PdfRectangle{Llx: x, Lly: y, Urx: x, Ury: y}
+			continue
+		}
+		out.Llx, out.Lly = math.Min(out.Llx, x), math.Min(out.Lly, y)
+		out.Urx, out.Ury = math.Max(out.Urx, x), math.Max(out.Ury, y)
+	}
+	return out
+}
+
+// impositionLayout holds the geometry of the grid shared by all sheets.
+type impositionLayout struct {
+	opts                  *ImpositionOptions
+	scale                 float64
+	cellWidth, cellHeight float64
+	gridX, gridY          float64
+	gridWidth, gridHeight float64
+	spine                 bool
+}
+
+// cellRect returns the rectangle of the cell at the specified column and row,
+// with row 0 at the top of the sheet.
+func (l *impositionLayout) cellRect(col, row int) model.PdfRectangle {
+	x := l.gridX + float64(col)*(l.cellWidth+l.opts.GutterX)
+	y := l.gridY + l.gridHeight - float64(row+1)*l.cellHeight - float64(row)*l.opts.GutterY
+	return model.PdfRectangle{Llx: x, Lly: y, Urx: x + l.cellWidth, Ury: y + l.cellHeight}
+}
+
+func (l *impositionLayout) drawSheet(side []*model.PdfPage, imported map[*model.PdfPage]*imposedPage,
+	back bool, sheetWidth, sheetHeight float64) (*model.PdfPage, error) {
+	sheet := model.NewPdfPage()
+	sheet.MediaBox = &model.PdfRectangle{Urx: sheetWidth, Ury: sheetHeight}
+	resources := model.NewPdfPageResources()
+	sheet.Resources = resources
+
+	cols, rows := l.opts.Columns, l.opts.Rows
+	forms := map[*model.XObjectForm]core.PdfObjectName{}
+	cc := contentstream.NewContentCreator()
+	for i, page := range side {
+		if page == nil || i >= cols*rows {
+			continue
+		}
+		ip := imported[page]
+		col, row := i%cols, i/cols
+		rotation := 0.0
+		if ip.rotate {
+			rotation = 90
+		}
+		if back {
+			switch l.opts.Duplex {
+			case DuplexTurn:
+				col = cols - 1 - col
+			case DuplexTumble:
+				row = rows - 1 - row
+				rotation += 180
+			}
+		}
+
+		name, ok := forms[ip.form]
+		if !ok {
+			name = resources.GenerateXObjectName()
+			if err := resources.SetXObjectFormByName(name, ip.form); err != nil {
+				return nil, err
+			}
+			forms[ip.form] = name
+		}
+
+		// Map the center of the trim box to the center of the cell, or to the
+		// spine side of the cell.
+		cell := l.cellRect(col, row)
+		x := (cell.Llx + cell.Urx) / 2
+		if l.spine {
+			w, _ := ip.size()
+			if col == 0 {
+				x = cell.Urx - w*l.scale/2
+			} else {
+				x = cell.Llx + w*l.scale/2
+			}
+		}
+		m := transform.TranslationMatrix(x, (cell.Lly+cell.Ury)/2).
+			Mult(transform.RotationMatrix(rotation * math.Pi / 180)).
+			Mult(transform.ScaleMatrix(l.scale, l.scale)).
+			Mult(transform.TranslationMatrix(-(ip.trim.Llx+ip.trim.Urx)/2, -(ip.trim.Lly+ip.trim.Ury)/2))
+
+		clip := l.clipRect(transformRect(ip.trim, m), transformRect(ip.bleed, m), col, row)
+		cc.Add_q().
+			Add_re(clip.Llx, clip.Lly, clip.Width(), clip.Height()).Add_W().Add_n().
+			Add_cm(m[0], m[1], m[3], m[4], m[6], m[7]).
+			Add_Do(name).
+			Add_Q()
+	}
+
+	if l.opts.CropMarks {
+		if err := l.drawCropMarks(cc, resources); err != nil {
+			return nil, err
+		}
+	}
+	if err := sheet.SetContentStreams([]string{cc.String()}, core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+	return sheet, nil
+}
+
+// clipRect returns the visible region of a page placed in the cell at the
+// specified column and row. The trim box is extended by the available bleed,
+// limited by the Bleed option and the middle of the gutters.
+func (l *impositionLayout) clipRect(trim, bleed model.PdfRectangle, col, row int) model.PdfRectangle {
+	if l.opts.Bleed <= 0 {
+		return trim
+	}
+	limit := func(gutter float64, inner bool) float64 {
+		if inner {
+			return math.Min(l.opts.Bleed, gutter/2)
+		}
+		return l.opts.Bleed
+	}
+	extend := func(edge, bleedEdge, max, sign float64) float64 {
+		d := sign * (bleedEdge - edge)
+		return edge + sign*math.Max(0, math.Min(d, max))
+	}
+	clip := trim
+	clip.Llx = extend(trim.Llx, bleed.Llx, limit(l.opts.GutterX, col > 0), -1)
+	clip.Urx = extend(trim.Urx, bleed.Urx, limit(l.opts.GutterX, col < l.opts.Columns-1), 1)
+	clip.Lly = extend(trim.Lly, bleed.Lly, limit(l.opts.GutterY, row < l.opts.Rows-1), -1)
+	clip.Ury = extend(trim.Ury, bleed.Ury, limit(l.opts.GutterY, row > 0), 1)
+	return clip
+}
+
+// drawCropMarks draws crop marks around the grid, in registration color, at
+// the edges of every column and row of cells.
+func (l *impositionLayout) drawCropMarks(cc *contentstream.ContentCreator, resources *model.PdfPageResources) error {
+	length, offset, lineWidth := l.opts.cropMarkGeometry()
+	csName := resourceName("CSReg", resources.HasColorspaceByName)
+	if err := resources.SetColorspaceByName(csName, registrationColorspace()); err != nil {
+		return err
+	}
+	cc.Add_q().
+		Add_CS(csName).Add_SCN(1).
+		Add_w(lineWidth)
+
+	bottom, top := l.gridY, l.gridY+l.gridHeight
+	left, right := l.gridX, l.gridX+l.gridWidth
+	for col := 0; col < l.opts.Columns; col++ {
+		cell := l.cellRect(col, 0)
+		for _, x := range []float64{cell.Llx, cell.Urx} {
+			cc.Add_m(x, top+offset).Add_l(x, top+offset+length)
+			cc.Add_m(x, bottom-offset).Add_l(x, bottom-offset-length)
+		}
+	}
+	for row := 0; row < l.opts.Rows; row++ {
+		cell := l.cellRect(0, row)
+		for _, y := range []float64{cell.Lly, cell.Ury} {
+			cc.Add_m(left-offset, y).Add_l(left-offset-length, y)
+			cc.Add_m(right+offset, y).Add_l(right+offset+length, y)
+		}
+	}
+	cc.Add_S().Add_Q()
+	return nil
+}
+
+// resourceName returns the specified resource name, or the first name
+// made of the name followed by a number, which is not used according to the
+// specified function. This prevents overwriting existing page resources.
+func resourceName(name string, used func(core.PdfObjectName) bool) core.PdfObjectName {
+	key := core.PdfObjectName(name)
+	for i := 1; used(key); i++ {
+		key = core.PdfObjectName(name + strconv.Itoa(i))
+	}
+	return key
+}
+
+// registrationColorspace returns the Separation colorspace of the All
+// colorant, which prints on all separations.
+func registrationColorspace() *model.PdfColorspaceSpecialSeparation {
+	cs := model.NewPdfColorspaceSpecialSeparation()
+	cs.ColorantName = core.MakeName("All")
+	cs.AlternateSpace = model.NewPdfColorspaceDeviceCMYK()
+	cs.TintTransform = &model.PdfFunctionType2{
+		Domain: []float64{0, 1},
+		Range:  []float64{0, 1, 0, 1, 0, 1, 0, 1},
+		C0:     []float64{0, 0, 0, 0},
+		C1:     []float64{1, 1, 1, 1},
+		N:      1,
+	}
+	return cs
+}
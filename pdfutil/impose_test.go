@@ -0,0 +1,55 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func TestCropMarksResourceName(t *testing.T) {
+	l := &impositionLayout{
+		opts:       &ImpositionOptions{Columns: 1, Rows: 1, CropMarks: true},
+		cellWidth:  100,
+		cellHeight: 100,
+		gridX:      20,
+		gridY:      20,
+		gridWidth:  100,
+		gridHeight: 100,
+	}
+
+	resources := model.NewPdfPageResources()
+	existing := model.NewPdfColorspaceDeviceCMYK()
+	if err := resources.SetColorspaceByName("CSReg", existing); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	cc := contentstream.NewContentCreator()
+	if err := l.drawCropMarks(cc, resources); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	colorspaces, err := resources.GetColorspaces()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if cs := colorspaces.Colorspaces["CSReg"]; cs != existing {
+		t.Fatalf("existing colorspace overwritten by %v", cs)
+	}
+	if _, ok := colorspaces.Colorspaces["CSReg1"]; !ok {
+		t.Fatalf("registration colorspace not added as CSReg1")
+	}
+	if content := cc.String(); !strings.Contains(content, "/CSReg1 CS") {
+		t.Fatalf("content does not use CSReg1: %s", content)
+	}
+}
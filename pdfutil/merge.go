@@ -0,0 +1,715 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// FieldConflictPolicy specifies how a Merger resolves top-level form fields
+// having the same name in different documents.
+type FieldConflictPolicy int
+
+const (
+	// FieldConflictRename renames the clashing fields of the later documents
+	// by appending a numeric suffix to their name.
+	FieldConflictRename FieldConflictPolicy = iota
+
+	// FieldConflictMerge merges clashing fields into a single field, whose
+	// widgets show the same value. Only terminal fields of the same type can
+	// be merged, other clashing fields are renamed. The value of the first
+	// field is kept.
+	FieldConflictMerge
+)
+
+// MergeOptions configures a Merger.
+type MergeOptions struct {
+	// FieldConflict specifies how clashing form fields are resolved.
+	FieldConflict FieldConflictPolicy
+}
+
+// Merger merges documents. The outline of each document is nested under a
+// new outline item pointing to its first page. Clashing named destinations
+// are renamed and the links, outline items and actions referring to them are
+// updated. Form fields and page labels are combined. The structure trees of
+// tagged documents are combined into a single Document element, holding a
+// Part element per document with its language, if it differs from the
+// language of the merged document.
+type Merger struct {
+	opts    MergeOptions
+	sources []*mergeSource
+}
+
+// mergeSource is a document added to a Merger.
+type mergeSource struct {
+	reader *model.PdfReader
+	title  string
+}
+
+// NewMerger returns a new merger. Options can be nil.
+func NewMerger(opts *MergeOptions) *Merger {
+	m := &Merger{}
+	if opts != nil {
+		m.opts = *opts
+	}
+	return m
+}
+
+// AddReader adds the document of the reader to the merger. The title is
+// used for the outline item of the document. If empty, the title of the
+// document information dictionary is used, if any.
+// NOTE: The document model of the reader is modified when writing the merged
+// document.
+func (m *Merger) AddReader(reader *model.PdfReader, title string) error {
+	if reader == nil {
+		return errors.New("reader required")
+	}
+	for _, src := range m.sources {
+		if src.reader == reader {
+			return errors.New("reader already added")
+		}
+	}
+	m.sources = append(m.sources, &mergeSource{reader: reader, title: title})
+	return nil
+}
+
+// Add adds the document read from rs to the merger. The title is used as in
+// AddReader. rs must remain open until the merged document is written.
+func (m *Merger) Add(rs io.ReadSeeker, title string) error {
+	reader, err := model.NewPdfReader(rs)
+	if err != nil {
+		return err
+	}
+	return m.AddReader(reader, title)
+}
+
+// WriteToFile writes the merged document to outputPath.
+func (m *Merger) WriteToFile(outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return m.Write(file)
+}
+
+// Write writes the merged document to w.
+func (m *Merger) Write(w io.Writer) error {
+	if len(m.sources) == 0 {
+		return errors.New("no documents to merge")
+	}
+	writer := model.NewPdfWriter()
+	state := &mergeState{
+		opts:       &m.opts,
+		writer:     &writer,
+		outline:    model.NewPdfOutline(),
+		nameTrees:  map[core.PdfObjectName]*mergedNameTree{},
+		fieldNames: map[string]*model.PdfField{},
+	}
+	for i, src := range m.sources {
+		if err := state.addSource(i, src); err != nil {
+			return err
+		}
+	}
+	if err := state.finish(); err != nil {
+		return err
+	}
+	return writer.Write(w)
+}
+
+// mergeState holds the combined document parts while merging.
+type mergeState struct {
+	opts   *MergeOptions
+	writer *model.PdfWriter
+
+	numPages int
+
+	outline     *model.PdfOutline
+	lastOutline *model.PdfOutlineItem
+	outlineSize int64
+
+	nameTrees map[core.PdfObjectName]*mergedNameTree
+
+	pageLabels    *core.PdfObjectArray
+	hasPageLabels bool
+
+	acroForm   *model.PdfAcroForm
+	fields     []*model.PdfField
+	fieldNames map[string]*model.PdfField
+
+	structRoot    *core.PdfIndirectObject
+	structDoc     *core.PdfIndirectObject
+	structKids    *core.PdfObjectArray
+	parentTree    *core.PdfObjectArray
+	nextParentKey int64
+	roleMap       *core.PdfObjectDictionary
+	classMap      *core.PdfObjectDictionary
+	ids           *mergedNameTree
+	lang          core.PdfObject
+}
+
+// mergedNameTree holds the entries of a name tree merged from several
+// documents.
+type mergedNameTree struct {
+	keys   map[string]bool
+	values []core.PdfObject
+}
+
+func newMergedNameTree() *mergedNameTree {
+	return &mergedNameTree{keys: map[string]bool{}}
+}
+
+// add adds the entry to the tree, renaming its key if already used. Returns
+// the key of the entry.
+func (t *mergedNameTree) add(key string, value core.PdfObject) string {
+	name := key
+	for i := 2; t.keys[name]; i++ {
+		name = fmt.Sprintf("%s_%d", key, i)
+	}
+	t.keys[name] = true
+	t.values = append(t.values, core.MakeString(name), value)
+	return name
+}
+
+// toPdfObject returns the name tree as a single node with sorted entries.
+func (t *mergedNameTree) toPdfObject() *core.PdfObjectDictionary {
+	indices := make([]int, 0, len(t.values)/2)
+	for i := 0; i < len(t.values); i += 2 {
+		indices = append(indices, i)
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return t.values[indices[i]].(*core.PdfObjectString).Str() < t.values[indices[j]].(*core.PdfObjectString).Str()
+	})
+	names := core.MakeArray()
+	for _, i := range indices {
+		names.Append(t.values[i], t.values[i+1])
+	}
+	return core.MakeDictMap(map[string]core.PdfObject{"Names": names})
+}
+
+func (s *mergeState) addSource(index int, src *mergeSource) error {
+	reader := src.reader
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	pages := make([]*model.PdfPage, numPages)
+	for i := range pages {
+		if pages[i], err = reader.GetPage(i + 1); err != nil {
+			return err
+		}
+	}
+
+	renames, err := s.mergeNameTrees(reader)
+	if err != nil {
+		return err
+	}
+	if err = renameDestsInPages(pages, renames, s.numPages); err != nil {
+		return err
+	}
+	if err = s.mergeStructTree(reader, pages); err != nil {
+		return err
+	}
+	if err = s.mergePageLabels(reader, numPages); err != nil {
+		return err
+	}
+
+	for _, page := range pages {
+		if err = s.writer.AddPage(page); err != nil {
+			return err
+		}
+	}
+
+	s.mergeOutline(index, src, pages, renames)
+	if err = s.mergeForm(reader); err != nil {
+		return err
+	}
+	if s.lang == nil {
+		if lang, ok := reader.GetCatalogLanguage(); ok {
+			s.lang = lang
+		}
+	}
+	s.numPages += numPages
+	return nil
+}
+
+// mergeNameTrees merges the name trees of the name dictionary of the
+// document, and its Dests dictionary. Returns the renamed destinations.
+func (s *mergeState) mergeNameTrees(reader *model.PdfReader) (map[string]string, error) {
+	renames := map[string]string{}
+	dests := s.nameTree("Dests")
+	addDest := func(name string, value core.PdfObject) {
+		value = shiftNamedDest(value, s.numPages)
+		if newName := dests.add(name, value); newName != name {
+			renames[name] = newName
+		}
+	}
+
+	names, err := reader.GetNameDictionary()
+	if err != nil {
+		return nil, err
+	}
+	if namesDict, ok := core.GetDict(names); ok {
+		for _, key := range namesDict.Keys() {
+			tree := s.nameTree(key)
+			walkNameTree(namesDict.Get(key), map[core.PdfObject]bool{}, func(name *core.PdfObjectString, value core.PdfObject) {
+				if key == "Dests" {
+					addDest(name.Str(), value)
+				} else {
+					tree.add(name.Str(), value)
+				}
+			})
+		}
+	}
+
+	// Destinations of the Dests dictionary of the catalog are moved to the
+	// Dests name tree.
+	catalogDests, err := reader.GetNamedDestinations()
+	if err != nil {
+		return nil, err
+	}
+	if dict, ok := core.GetDict(catalogDests); ok {
+		for _, key := range dict.Keys() {
+			addDest(string(key), dict.Get(key))
+		}
+	}
+	return renames, nil
+}
+
+func (s *mergeState) nameTree(key core.PdfObjectName) *mergedNameTree {
+	tree, ok := s.nameTrees[key]
+	if !ok {
+		tree = newMergedNameTree()
+		s.nameTrees[key] = tree
+	}
+	return tree
+}
+
+// renameDest returns the destination, as a string, referring to the renamed
+// named destination. Explicit destinations referring to pages by their index
+// are shifted by `offset`, the number of the pages merged before the
+// document. Other explicit destinations are returned unchanged.
+func renameDest(dest core.PdfObject, renames map[string]string, offset int) core.PdfObject {
+	var name string
+	switch t := core.TraceToDirectObject(dest).(type) {
+	case *core.PdfObjectName:
+		name = string(*t)
+	case *core.PdfObjectString:
+		name = t.Str()
+	case *core.PdfObjectArray:
+		return shiftDest(t, offset)
+	default:
+		return dest
+	}
+	if newName, ok := renames[name]; ok {
+		name = newName
+	}
+	return core.MakeString(name)
+}
+
+// shiftDest returns the explicit destination `dest` with its page index
+// shifted by `offset`. Destinations referring to page objects are returned
+// unchanged. The destination is copied, as it may be shared.
+func shiftDest(dest *core.PdfObjectArray, offset int) core.PdfObject {
+	if offset == 0 || dest.Len() == 0 {
+		return dest
+	}
+	index, ok := core.GetIntVal(dest.Get(0))
+	if !ok {
+		return dest
+	}
+	elements := append([]core.PdfObject{core.MakeInteger(int64(index + offset))}, dest.Elements()[1:]...)
+	return core.MakeArray(elements...)
+}
+
+// shiftNamedDest returns the value of a named destination, an explicit
+// destination or a dictionary holding it in its D entry, with the page index
+// of the destination shifted by `offset`.
+func shiftNamedDest(value core.PdfObject, offset int) core.PdfObject {
+	switch t := core.TraceToDirectObject(value).(type) {
+	case *core.PdfObjectArray:
+		return shiftDest(t, offset)
+	case *core.PdfObjectDictionary:
+		if dest, ok := core.GetArray(t.Get("D")); ok {
+			dict := core.MakeDict().Merge(t)
+			dict.Set("D", shiftDest(dest, offset))
+			return dict
+		}
+	}
+	return value
+}
+
+// renameActionDest updates the destination of GoTo actions.
+func renameActionDest(action core.PdfObject, renames map[string]string, offset int) {
+	dict, ok := core.GetDict(action)
+	if !ok {
+		return
+	}
+	if s, _ := core.GetNameVal(dict.Get("S")); s == "GoTo" {
+		if d := dict.Get("D"); d != nil {
+			dict.Set("D", renameDest(d, renames, offset))
+		}
+	}
+}
+
+// renameDestsInPages updates the links of the pages referring to named
+// destinations or to page indexes.
+func renameDestsInPages(pages []*model.PdfPage, renames map[string]string, offset int) error {
+	for _, page := range pages {
+		annots, err := page.GetAnnotations()
+		if err != nil {
+			return err
+		}
+		for _, annot := range annots {
+			link, ok := annot.GetContext().(*model.PdfAnnotationLink)
+			if !ok {
+				continue
+			}
+			if link.Dest != nil {
+				link.Dest = renameDest(link.Dest, renames, offset)
+			}
+			renameActionDest(link.A, renames, offset)
+		}
+	}
+	return nil
+}
+
+// mergeOutline nests the outline of the document under a new outline item.
+func (s *mergeState) mergeOutline(index int, src *mergeSource, pages []*model.PdfPage, renames map[string]string) {
+	title := src.title
+	if title == "" {
+		if info, err := src.reader.GetPdfInfo(); err == nil && info != nil && info.Title != nil {
+			title = info.Title.Decoded()
+		}
+	}
+	if title == "" {
+		title = fmt.Sprintf("Document %d", index+1)
+	}
+
+	item := model.NewPdfOutlineItem()
+	item.Title = core.MakeEncodedString(title, true)
+	item.Parent = &s.outline.PdfOutlineTreeNode
+	if len(pages) > 0 {
+		item.Dest = core.MakeArray(pages[0].GetPageAsIndirectObject(), core.MakeName("Fit"))
+	}
+
+	var count int64
+	if root := src.reader.GetOutlineTree(); root != nil && root.First != nil {
+		item.First, item.Last = root.First, root.Last
+		for node := root.First; node != nil; {
+			child, ok := node.GetContext().(*model.PdfOutlineItem)
+			if !ok {
+				break
+			}
+			child.Parent = &item.PdfOutlineTreeNode
+			count += 1 + renameOutlineDests(child, renames, s.numPages)
+			node = child.Next
+		}
+	}
+	if count > 0 {
+		item.Count = &count
+	}
+
+	if s.lastOutline == nil {
+		s.outline.First = &item.PdfOutlineTreeNode
+	} else {
+		s.lastOutline.Next = &item.PdfOutlineTreeNode
+		item.Prev = &s.lastOutline.PdfOutlineTreeNode
+	}
+	s.outline.Last = &item.PdfOutlineTreeNode
+	s.lastOutline = item
+	s.outlineSize += 1 + count
+}
+
+// renameOutlineDests updates the destinations of the outline item and its
+// descendants. Returns the number of visible descendants of the item.
+func renameOutlineDests(item *model.PdfOutlineItem, renames map[string]string, offset int) int64 {
+	if item.Dest != nil {
+		item.Dest = renameDest(item.Dest, renames, offset)
+	}
+	renameActionDest(item.A, renames, offset)
+
+	var count int64
+	for node := item.First; node != nil; {
+		child, ok := node.GetContext().(*model.PdfOutlineItem)
+		if !ok {
+			break
+		}
+		count += 1 + renameOutlineDests(child, renames, offset)
+		node = child.Next
+	}
+	if item.Count != nil && *item.Count < 0 {
+		return 0
+	}
+	return count
+}
+
+// mergePageLabels appends the page labels of the document. Documents without
+// page labels are numbered from 1.
+func (s *mergeState) mergePageLabels(reader *model.PdfReader, numPages int) error {
+	if s.pageLabels == nil {
+		s.pageLabels = core.MakeArray()
+	}
+	labels, err := reader.GetPageLabels()
+	if err != nil {
+		common.Log.Debug("ERROR: invalid page labels: %v", err)
+		labels = nil
+	}
+	part := &SplitPart{From: 1, To: numPages}
+	var nums *core.PdfObjectArray
+	if labels != nil {
+		nums = splitPageLabels(labels, part)
+	}
+	if nums == nil {
+		s.pageLabels.Append(core.MakeInteger(int64(s.numPages)), core.MakeDictMap(map[string]core.PdfObject{
+			"S": core.MakeName("D"),
+		}))
+		return nil
+	}
+	s.hasPageLabels = true
+	for i := 0; i+1 < nums.Len(); i += 2 {
+		start, _ := core.GetIntVal(nums.Get(i))
+		s.pageLabels.Append(core.MakeInteger(int64(s.numPages+start)), nums.Get(i+1))
+	}
+	return nil
+}
+
+// mergeForm adds the fields of the document, resolving name conflicts.
+func (s *mergeState) mergeForm(reader *model.PdfReader) error {
+	form := reader.AcroForm
+	if form == nil || form.Fields == nil {
+		return nil
+	}
+	if s.acroForm == nil {
+		s.acroForm = form
+	} else {
+		mergeFormDefaults(s.acroForm, form)
+	}
+
+	for _, field := range *form.Fields {
+		name := field.PartialName()
+		existing, clash := s.fieldNames[name]
+		if !clash {
+			s.fieldNames[name] = field
+			s.fields = append(s.fields, field)
+			continue
+		}
+		if s.opts.FieldConflict == FieldConflictMerge && canMergeFields(existing, field) {
+			merged := mergeFields(existing, field)
+			if merged != existing {
+				for i, f := range s.fields {
+					if f == existing {
+						s.fields[i] = merged
+					}
+				}
+				s.fieldNames[name] = merged
+			}
+			continue
+		}
+
+		newName := name
+		for i := 2; s.fieldNames[newName] != nil; i++ {
+			newName = fmt.Sprintf("%s_%d", name, i)
+		}
+		common.Log.Debug("Renaming form field %q to %q", name, newName)
+		field.T = core.MakeString(newName)
+		s.fieldNames[newName] = field
+		s.fields = append(s.fields, field)
+	}
+	return nil
+}
+
+// mergeFormDefaults merges the document-wide entries of the interactive form
+// dictionaries.
+func mergeFormDefaults(dst, src *model.PdfAcroForm) {
+	if src.NeedAppearances != nil && bool(*src.NeedAppearances) {
+		dst.NeedAppearances = src.NeedAppearances
+	}
+	if src.SigFlags != nil {
+		flags := *src.SigFlags
+		if dst.SigFlags != nil {
+			flags |= *dst.SigFlags
+		}
+		dst.SigFlags = core.MakeInteger(int64(flags))
+	}
+	if src.CO != nil {
+		if dst.CO == nil {
+			dst.CO = core.MakeArray()
+		}
+		dst.CO.Append(src.CO.Elements()...)
+	}
+	if dst.DA == nil {
+		dst.DA = src.DA
+	}
+	if dst.DR == nil {
+		dst.DR = src.DR
+	} else if src.DR != nil {
+		// Resources of the first document take precedence.
+		dstDict, ok1 := core.GetDict(dst.DR.ToPdfObject())
+		srcDict, ok2 := core.GetDict(src.DR.ToPdfObject())
+		if ok1 && ok2 {
+			for _, category := range srcDict.Keys() {
+				srcRes, ok := core.GetDict(srcDict.Get(category))
+				if !ok {
+					continue
+				}
+				dstRes, ok := core.GetDict(dstDict.Get(category))
+				if !ok {
+					dstDict.Set(category, srcDict.Get(category))
+					continue
+				}
+				for _, key := range srcRes.Keys() {
+					if dstRes.Get(key) == nil {
+						dstRes.Set(key, srcRes.Get(key))
+					}
+				}
+			}
+			if dr, err := model.NewPdfPageResourcesFromDict(dstDict); err == nil {
+				dst.DR = dr
+			}
+		}
+	}
+}
+
+// fieldDictKeys are the entries of a field dictionary, as opposed to the
+// entries of a widget annotation dictionary.
+var fieldDictKeys = []core.PdfObjectName{
+	"FT", "T", "TU", "TM", "Ff", "V", "DV", "Kids", "Opt", "TI", "I", "MaxLen", "Lock", "SV", "RV", "DS",
+}
+
+func fieldType(field *model.PdfField) string {
+	if field.FT != nil {
+		return string(*field.FT)
+	}
+	return ""
+}
+
+// isMergedField returns true if the field dictionary is also the dictionary of
+// its widget annotation.
+func isMergedField(field *model.PdfField) bool {
+	return len(field.Annotations) == 1 &&
+		field.Annotations[0].GetContainingPdfObject() == field.GetContainingPdfObject()
+}
+
+func canMergeFields(a, b *model.PdfField) bool {
+	return len(a.Kids) == 0 && len(b.Kids) == 0 &&
+		len(a.Annotations) > 0 && len(b.Annotations) > 0 &&
+		fieldType(a) == fieldType(b)
+}
+
+// syncField updates the dictionary of the field from its model.
+func syncField(field *model.PdfField) {
+	if ctx := field.GetContext(); ctx != nil {
+		ctx.ToPdfObject()
+	} else {
+		field.ToPdfObject()
+	}
+}
+
+// mergeFields moves the widgets of the second field to the first one. If the
+// first field is merged with its widget, a new field is created holding both
+// widgets, and returned.
+func mergeFields(dst, src *model.PdfField) *model.PdfField {
+	if isMergedField(dst) {
+		syncField(dst)
+		widget := dst.Annotations[0]
+		parent := model.NewPdfField()
+		moveFieldEntries(dst, parent)
+		widget.SetParentField(parent)
+		parent.Annotations = []*model.PdfAnnotationWidget{widget}
+		dst = parent
+	}
+	if isMergedField(src) {
+		syncField(src)
+		moveFieldEntries(src, nil)
+	}
+	for _, widget := range src.Annotations {
+		widget.SetParentField(dst)
+		dst.Annotations = append(dst.Annotations, widget)
+	}
+	return dst
+}
+
+// moveFieldEntries removes the field entries from the dictionary of the
+// field, and copies them to the dictionary of dst, if not nil.
+func moveFieldEntries(field, dst *model.PdfField) {
+	dict, ok := core.GetDict(field.GetContainingPdfObject())
+	if !ok {
+		return
+	}
+	var dstDict *core.PdfObjectDictionary
+	if dst != nil {
+		dstDict, _ = core.GetDict(dst.GetContainingPdfObject())
+	}
+	for _, key := range append(fieldDictKeys, "DA", "Q") {
+		value := dict.Get(key)
+		if value == nil {
+			continue
+		}
+		if dstDict != nil {
+			dstDict.Set(key, value)
+		}
+		if key != "DA" && key != "Q" {
+			dict.Remove(key)
+		}
+	}
+	dict.Remove("Parent")
+}
+
+// finish sets the merged document-wide parts on the writer.
+func (s *mergeState) finish() error {
+	writer := s.writer
+	if s.outline.First != nil {
+		count := s.outlineSize
+		s.outline.Count = &count
+		writer.AddOutlineTree(&s.outline.PdfOutlineTreeNode)
+	}
+
+	names := core.MakeDict()
+	for key, tree := range s.nameTrees {
+		if len(tree.values) > 0 {
+			names.Set(key, tree.toPdfObject())
+		}
+	}
+	if len(names.Keys()) > 0 {
+		if err := writer.SetNameDictionary(names); err != nil {
+			return err
+		}
+	}
+
+	if s.hasPageLabels {
+		if err := writer.SetPageLabels(core.MakeDictMap(map[string]core.PdfObject{"Nums": s.pageLabels})); err != nil {
+			return err
+		}
+	}
+
+	if s.acroForm != nil && len(s.fields) > 0 {
+		s.acroForm.Fields = &s.fields
+		if err := writer.SetForms(s.acroForm); err != nil {
+			return err
+		}
+	}
+
+	if err := s.finishStructTree(); err != nil {
+		return err
+	}
+	if s.lang != nil {
+		return writer.SetCatalogLanguage(s.lang)
+	}
+	return nil
+}
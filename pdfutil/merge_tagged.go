@@ -0,0 +1,449 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfutil
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// mergeStructTree appends the structure tree of the document to the merged
+// structure tree, under a Part element of the merged Document element. The
+// keys of the parent tree are shifted, together with the StructParents and
+// StructParent entries of the pages, annotations and form XObjects of the
+// document. The marked-content identifiers of the pages are renumbered to be
+// contiguous, and clashing role and class map entries are renamed.
+func (s *mergeState) mergeStructTree(reader *model.PdfReader, pages []*model.PdfPage) error {
+	rootObj, ok := reader.GetCatalogStructTreeRoot()
+	if !ok {
+		return nil
+	}
+	root, ok := core.GetDict(rootObj)
+	if !ok {
+		return nil
+	}
+	if s.structRoot == nil {
+		s.structRoot = core.MakeIndirectObject(core.MakeDict())
+		s.structDoc = core.MakeIndirectObject(core.MakeDict())
+		s.structKids = core.MakeArray()
+		s.parentTree = core.MakeArray()
+		s.roleMap = core.MakeDict()
+		s.classMap = core.MakeDict()
+		s.ids = newMergedNameTree()
+	}
+	offset := s.nextParentKey
+
+	var kids []core.PdfObject
+	switch k := core.TraceToDirectObject(root.Get("K")).(type) {
+	case *core.PdfObjectArray:
+		kids = k.Elements()
+	case *core.PdfObjectDictionary:
+		kids = []core.PdfObject{root.Get("K")}
+	}
+	var elems []*core.PdfObjectDictionary
+	walkStructElems(kids, map[core.PdfObject]bool{}, func(elem *core.PdfObjectDictionary) {
+		elems = append(elems, elem)
+	})
+
+	// Role and class maps: the entries of the first documents take
+	// precedence, clashing entries of the document are renamed.
+	roles := mergeStructMap(s.roleMap, root.Get("RoleMap"))
+	classes := mergeStructMap(s.classMap, root.Get("ClassMap"))
+	for _, elem := range elems {
+		if name, ok := core.GetNameVal(elem.Get("S")); ok {
+			if newName, ok := roles[name]; ok {
+				elem.Set("S", core.MakeName(newName))
+			}
+		}
+		renameStructClasses(elem, classes)
+	}
+
+	// Parent tree.
+	entries := map[int64]core.PdfObject{}
+	nextKey := int64(0)
+	walkNumberTree(root.Get("ParentTree"), map[core.PdfObject]bool{}, func(key int64, value core.PdfObject) {
+		entries[key] = value
+		nextKey = max(nextKey, key+1)
+	})
+	if next, ok := core.GetIntVal(root.Get("ParentTreeNextKey")); ok {
+		nextKey = max(nextKey, int64(next))
+	}
+	for _, page := range pages {
+		key, ok := core.GetIntVal(page.StructParents)
+		if !ok {
+			continue
+		}
+		if err := renumberMCIDs(page, entries, int64(key)); err != nil {
+			return err
+		}
+	}
+	keys := make([]int64, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, key := range keys {
+		s.parentTree.Append(core.MakeInteger(key+offset), entries[key])
+	}
+
+	// Element identifiers are renamed on conflict.
+	walkNameTree(root.Get("IDTree"), map[core.PdfObject]bool{}, func(id *core.PdfObjectString, elem core.PdfObject) {
+		if newID := s.ids.add(id.Str(), elem); newID != id.Str() {
+			if dict, ok := core.GetDict(elem); ok {
+				dict.Set("ID", core.MakeString(newID))
+			}
+		}
+	})
+
+	part := s.structPart(kids)
+	if lang, ok := reader.GetCatalogLanguage(); ok && s.lang != nil && part.Get("Lang") == nil {
+		if !sameObject(s.lang, lang) {
+			part.Set("Lang", lang)
+		}
+	}
+
+	if offset > 0 {
+		if err := shiftStructParents(pages, offset); err != nil {
+			return err
+		}
+	}
+	s.nextParentKey = offset + nextKey
+	return nil
+}
+
+// structPart returns the Part element holding the top-level structure
+// elements of a document, appended to the merged Document element. A single
+// top-level Document element is reused as the Part element.
+func (s *mergeState) structPart(kids []core.PdfObject) *core.PdfObjectDictionary {
+	if len(kids) == 1 {
+		if dict, ok := core.GetDict(kids[0]); ok {
+			if name, ok := core.GetNameVal(dict.Get("S")); ok && name == "Document" {
+				dict.Set("S", core.MakeName("Part"))
+				dict.Set("P", s.structDoc)
+				s.structKids.Append(kids[0])
+				return dict
+			}
+		}
+	}
+	part := core.MakeIndirectObject(core.MakeDict())
+	dict := part.PdfObject.(*core.PdfObjectDictionary)
+	dict.Set("Type", core.MakeName("StructElem"))
+	dict.Set("S", core.MakeName("Part"))
+	dict.Set("P", s.structDoc)
+	dict.Set("K", core.MakeArray(kids...))
+	for _, kid := range kids {
+		if kidDict, ok := core.GetDict(kid); ok {
+			kidDict.Set("P", part)
+		}
+	}
+	s.structKids.Append(part)
+	return dict
+}
+
+// finishStructTree sets the merged structure tree and the marked info on the
+// writer.
+func (s *mergeState) finishStructTree() error {
+	if s.structRoot == nil {
+		return nil
+	}
+	doc := s.structDoc.PdfObject.(*core.PdfObjectDictionary)
+	doc.Set("Type", core.MakeName("StructElem"))
+	doc.Set("S", core.MakeName("Document"))
+	doc.Set("P", s.structRoot)
+	doc.Set("K", s.structKids)
+
+	root := s.structRoot.PdfObject.(*core.PdfObjectDictionary)
+	root.Set("Type", core.MakeName("StructTreeRoot"))
+	root.Set("K", s.structDoc)
+	root.Set("ParentTree", core.MakeIndirectObject(core.MakeDictMap(map[string]core.PdfObject{"Nums": s.parentTree})))
+	root.Set("ParentTreeNextKey", core.MakeInteger(s.nextParentKey))
+	if len(s.roleMap.Keys()) > 0 {
+		root.Set("RoleMap", s.roleMap)
+	}
+	if len(s.classMap.Keys()) > 0 {
+		root.Set("ClassMap", s.classMap)
+	}
+	if len(s.ids.values) > 0 {
+		root.Set("IDTree", s.ids.toPdfObject())
+	}
+	if err := s.writer.SetCatalogStructTreeRoot(s.structRoot); err != nil {
+		return err
+	}
+	return s.writer.SetCatalogMarkInfo(core.MakeDictMap(map[string]core.PdfObject{
+		"Marked": core.MakeBool(true),
+	}))
+}
+
+// walkStructElems calls fn for each structure element of the subtrees.
+func walkStructElems(kids []core.PdfObject, visited map[core.PdfObject]bool, fn func(*core.PdfObjectDictionary)) {
+	for _, kid := range kids {
+		dict, ok := core.GetDict(kid)
+		if !ok || visited[dict] || dict.Get("S") == nil {
+			continue
+		}
+		visited[dict] = true
+		fn(dict)
+		switch k := core.TraceToDirectObject(dict.Get("K")).(type) {
+		case *core.PdfObjectArray:
+			walkStructElems(k.Elements(), visited, fn)
+		case *core.PdfObjectDictionary:
+			walkStructElems([]core.PdfObject{k}, visited, fn)
+		}
+	}
+}
+
+// mergeStructMap adds the entries of the role or class map src to dst. Entries
+// whose key is already mapped to a different value are added under a new key.
+// Returns the renamed keys.
+func mergeStructMap(dst *core.PdfObjectDictionary, src core.PdfObject) map[string]string {
+	dict, ok := core.GetDict(src)
+	if !ok {
+		return nil
+	}
+	renames := map[string]string{}
+	for _, key := range dict.Keys() {
+		value := dict.Get(key)
+		existing := dst.Get(key)
+		if existing == nil {
+			dst.Set(key, value)
+			continue
+		}
+		if sameObject(existing, value) {
+			continue
+		}
+		newKey := key
+		for i := 2; dst.Get(newKey) != nil; i++ {
+			newKey = core.PdfObjectName(fmt.Sprintf("%s_%d", key, i))
+		}
+		common.Log.Debug("Renaming structure map entry %q to %q", key, newKey)
+		dst.Set(newKey, value)
+		renames[string(key)] = string(newKey)
+	}
+	return renames
+}
+
+// renameStructClasses renames the attribute classes of the structure element.
+func renameStructClasses(elem *core.PdfObjectDictionary, renames map[string]string) {
+	if len(renames) == 0 {
+		return
+	}
+	rename := func(obj core.PdfObject) core.PdfObject {
+		if name, ok := core.GetNameVal(obj); ok {
+			if newName, ok := renames[name]; ok {
+				return core.MakeName(newName)
+			}
+		}
+		return obj
+	}
+	switch c := core.TraceToDirectObject(elem.Get("C")).(type) {
+	case *core.PdfObjectName:
+		elem.Set("C", rename(c))
+	case *core.PdfObjectArray:
+		classes := make([]core.PdfObject, c.Len())
+		for i, class := range c.Elements() {
+			classes[i] = rename(class)
+		}
+		elem.Set("C", core.MakeArray(classes...))
+	}
+}
+
+// sameObject returns true if the direct objects of a and b have the same
+// serialization.
+func sameObject(a, b core.PdfObject) bool {
+	return bytes.Equal(core.TraceToDirectObject(a).Write(), core.TraceToDirectObject(b).Write())
+}
+
+// renumberMCIDs renumbers the marked-content identifiers of the page to be
+// contiguous from 0, in their original order, so that the page entry of the
+// parent tree has no gaps. The content streams, the page entry of the parent
+// tree entries and the structure elements referring to the marked content
+// are updated. Pages whose identifiers are already contiguous are left
+// unchanged.
+func renumberMCIDs(page *model.PdfPage, entries map[int64]core.PdfObject, key int64) error {
+	parents, ok := core.GetArray(entries[key])
+	if !ok {
+		return nil
+	}
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return err
+	}
+	var props []*core.PdfObjectDictionary
+	seen := map[int64]bool{}
+	var mcids []int64
+	for _, op := range *ops {
+		if op.Operand != "BDC" || len(op.Params) != 2 {
+			continue
+		}
+		dict, ok := core.GetDict(op.Params[1])
+		if !ok {
+			continue
+		}
+		mcid, ok := core.GetIntVal(dict.Get("MCID"))
+		if !ok {
+			continue
+		}
+		props = append(props, dict)
+		if !seen[int64(mcid)] {
+			seen[int64(mcid)] = true
+			mcids = append(mcids, int64(mcid))
+		}
+	}
+	sort.Slice(mcids, func(i, j int) bool { return mcids[i] < mcids[j] })
+	if len(mcids) == 0 || mcids[len(mcids)-1] == int64(len(mcids)-1) {
+		return nil
+	}
+	mapping := make(map[int64]int64, len(mcids))
+	for i, mcid := range mcids {
+		mapping[mcid] = int64(i)
+	}
+
+	for _, dict := range props {
+		mcid, _ := core.GetIntVal(dict.Get("MCID"))
+		dict.Set("MCID", core.MakeInteger(mapping[int64(mcid)]))
+	}
+	if err = page.SetContentStreams([]string{ops.String()}, core.NewFlateEncoder()); err != nil {
+		return err
+	}
+
+	newParents := make([]core.PdfObject, len(mcids))
+	var elems []*core.PdfObjectDictionary
+	visited := map[core.PdfObject]bool{}
+	for i, mcid := range mcids {
+		newParents[i] = core.MakeNull()
+		if mcid >= int64(parents.Len()) {
+			continue
+		}
+		parent := parents.Get(int(mcid))
+		newParents[i] = parent
+		if dict, ok := core.GetDict(parent); ok && !visited[dict] {
+			visited[dict] = true
+			elems = append(elems, dict)
+		}
+	}
+	entries[key] = core.MakeArray(newParents...)
+
+	pageObj := page.GetPageAsIndirectObject()
+	onPage := func(pg core.PdfObject, inherited bool) bool {
+		if pg == nil {
+			return inherited
+		}
+		return core.ResolveReference(pg) == core.PdfObject(pageObj)
+	}
+	remap := func(obj core.PdfObject, elemOnPage bool) core.PdfObject {
+		switch t := core.TraceToDirectObject(obj).(type) {
+		case *core.PdfObjectInteger:
+			if elemOnPage {
+				if newID, ok := mapping[int64(*t)]; ok {
+					return core.MakeInteger(newID)
+				}
+			}
+		case *core.PdfObjectDictionary:
+			if name, ok := core.GetNameVal(t.Get("Type")); !ok || name != "MCR" || !onPage(t.Get("Pg"), elemOnPage) {
+				break
+			}
+			if mcid, ok := core.GetIntVal(t.Get("MCID")); ok {
+				if newID, ok := mapping[int64(mcid)]; ok {
+					t.Set("MCID", core.MakeInteger(newID))
+				}
+			}
+		}
+		return obj
+	}
+	for _, elem := range elems {
+		elemOnPage := onPage(elem.Get("Pg"), true)
+		switch k := core.TraceToDirectObject(elem.Get("K")).(type) {
+		case *core.PdfObjectArray:
+			kids := make([]core.PdfObject, k.Len())
+			for i, kid := range k.Elements() {
+				kids[i] = remap(kid, elemOnPage)
+			}
+			elem.Set("K", core.MakeArray(kids...))
+		default:
+			elem.Set("K", remap(elem.Get("K"), elemOnPage))
+		}
+	}
+	return nil
+}
+
+// shiftStructParents adds offset to the keys of the parent tree referred to by
+// the pages, their annotations and their form XObjects.
+func shiftStructParents(pages []*model.PdfPage, offset int64) error {
+	shift := func(obj core.PdfObject) core.PdfObject {
+		if v, ok := core.GetIntVal(obj); ok {
+			return core.MakeInteger(int64(v) + offset)
+		}
+		return obj
+	}
+	visited := map[core.PdfObject]bool{}
+	for _, page := range pages {
+		if page.StructParents != nil {
+			page.StructParents = shift(page.StructParents)
+		}
+		annots, err := page.GetAnnotations()
+		if err != nil {
+			return err
+		}
+		for _, annot := range annots {
+			if annot.StructParent != nil {
+				annot.StructParent = shift(annot.StructParent)
+			}
+		}
+		if page.Resources == nil {
+			continue
+		}
+		xobjects, ok := core.GetDict(page.Resources.XObject)
+		if !ok {
+			continue
+		}
+		for _, key := range xobjects.Keys() {
+			stream, ok := core.GetStream(xobjects.Get(key))
+			if !ok || visited[stream] {
+				continue
+			}
+			visited[stream] = true
+			if sp := stream.Get("StructParents"); sp != nil {
+				stream.Set("StructParents", shift(sp))
+			}
+		}
+	}
+	return nil
+}
+
+// walkNumberTree calls fn for each entry of the number tree.
+func walkNumberTree(node core.PdfObject, visited map[core.PdfObject]bool, fn func(int64, core.PdfObject)) {
+	dict, ok := core.GetDict(node)
+	if !ok || visited[dict] {
+		return
+	}
+	visited[dict] = true
+	if nums, ok := core.GetArray(dict.Get("Nums")); ok {
+		for i := 0; i+1 < nums.Len(); i += 2 {
+			if key, ok := core.GetIntVal(nums.Get(i)); ok {
+				fn(int64(key), nums.Get(i+1))
+			}
+		}
+	}
+	if kids, ok := core.GetArray(dict.Get("Kids")); ok {
+		for _, kid := range kids.Elements() {
+			walkNumberTree(kid, visited, fn)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfutil
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// destPage returns the page index of the explicit destination `dest`.
+func destPage(t *testing.T, dest core.PdfObject) int {
+	arr, ok := core.GetArray(dest)
+	if !ok || arr.Len() == 0 {
+		t.Fatalf("invalid destination %v", dest)
+	}
+	index, ok := core.GetIntVal(arr.Get(0))
+	if !ok {
+		t.Fatalf("destination %v does not refer to a page index", dest)
+	}
+	return index
+}
+
+func TestRenameOutlineDestsPageIndexes(t *testing.T) {
+	shared := core.MakeArray(core.MakeInteger(1), core.MakeName("XYZ"),
+		core.MakeInteger(0), core.MakeInteger(700), core.MakeInteger(0))
+
+	parent := model.NewPdfOutlineItem()
+	parent.Dest = shared
+	child := model.NewPdfOutlineItem()
+	child.A = core.MakeDictMap(map[string]core.PdfObject{
+		"S": core.MakeName("GoTo"),
+		"D": core.MakeArray(core.MakeInteger(0), core.MakeName("Fit")),
+	})
+	named := model.NewPdfOutlineItem()
+	named.Dest = core.MakeString("chapter")
+	child.Next = &named.PdfOutlineTreeNode
+	parent.First, parent.Last = &child.PdfOutlineTreeNode, &named.PdfOutlineTreeNode
+
+	if count := renameOutlineDests(parent, map[string]string{"chapter": "chapter_2"}, 3); count != 2 {
+		t.Fatalf("expected 2 descendants, got %d", count)
+	}
+	if page := destPage(t, parent.Dest); page != 4 {
+		t.Fatalf("expected page index 4, got %d", page)
+	}
+	action, _ := core.GetDict(child.A)
+	if page := destPage(t, action.Get("D")); page != 3 {
+		t.Fatalf("expected page index 3, got %d", page)
+	}
+	if name, _ := core.GetStringVal(named.Dest); name != "chapter_2" {
+		t.Fatalf("expected renamed destination, got %v", named.Dest)
+	}
+
+	// Destinations are copied, as they may be shared by links and outline
+	// items.
+	if page := destPage(t, shared); page != 1 {
+		t.Fatalf("shared destination modified: %v", shared)
+	}
+	if page := destPage(t, renameDest(shared, nil, 3)); page != 4 {
+		t.Fatalf("expected page index 4, got %d", page)
+	}
+
+	// Explicit destinations of named destinations are shifted too.
+	value := shiftNamedDest(core.MakeDictMap(map[string]core.PdfObject{"D": shared}), 5)
+	dict, _ := core.GetDict(value)
+	if page := destPage(t, dict.Get("D")); page != 6 {
+		t.Fatalf("expected page index 6, got %d", page)
+	}
+}
+
+// taggedPdf returns a tagged document having a single page, whose content
+// is a marked-content sequence having the specified MCID, owned by a
+// structure element of the specified role, mapped to the specified standard
+// structure type.
+func taggedPdf(mcid int, role, stdType string) []byte {
+	content := fmt.Sprintf("/P <</MCID %d>> BDC BT ET EMC", mcid)
+	parents := strings.Repeat("null ", mcid) + "6 0 R"
+	return makePdf(
+		"<< /Type /Catalog /Pages 2 0 R /StructTreeRoot 5 0 R /MarkInfo << /Marked true >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 100] /Contents 4 0 R /StructParents 0 >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		fmt.Sprintf("<< /Type /StructTreeRoot /K 6 0 R /ParentTree << /Nums [0 [%s]] >> /RoleMap << /%s /%s >> >>", parents, role, stdType),
+		fmt.Sprintf("<< /Type /StructElem /S /%s /P 5 0 R /Pg 3 0 R /K %d >>", role, mcid),
+	)
+}
+
+func TestMergeStructTrees(t *testing.T) {
+	writer := model.NewPdfWriter()
+	s := &mergeState{
+		opts:       &MergeOptions{},
+		writer:     &writer,
+		outline:    model.NewPdfOutline(),
+		nameTrees:  map[core.PdfObjectName]*mergedNameTree{},
+		fieldNames: map[string]*model.PdfField{},
+	}
+	var pages []*model.PdfPage
+	for i, data := range [][]byte{taggedPdf(0, "Heading", "H1"), taggedPdf(5, "Heading", "H2")} {
+		reader, err := model.NewPdfReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if err := s.addSource(i, &mergeSource{reader: reader}); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		page, err := reader.GetPage(1)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		pages = append(pages, page)
+	}
+	if err := s.finish(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	root := s.structRoot.PdfObject.(*core.PdfObjectDictionary)
+	doc := s.structDoc.PdfObject.(*core.PdfObjectDictionary)
+	if kids, ok := core.GetArray(doc.Get("K")); !ok || kids.Len() != 2 {
+		t.Fatalf("expected a Part element per document, got %v", doc.Get("K"))
+	}
+
+	// The parent tree key of the second page is shifted.
+	if key, _ := core.GetIntVal(pages[1].StructParents); key != 1 {
+		t.Fatalf("expected StructParents 1, got %v", pages[1].StructParents)
+	}
+	var keys []int
+	walkNumberTree(root.Get("ParentTree"), map[core.PdfObject]bool{}, func(key int64, value core.PdfObject) {
+		keys = append(keys, int(key))
+		if parents, ok := core.GetArray(value); !ok || parents.Len() != 1 {
+			t.Fatalf("parent tree entry %d: expected a single parent, got %v", key, value)
+		}
+	})
+	if len(keys) != 2 || keys[0] != 0 || keys[1] != 1 {
+		t.Fatalf("unexpected parent tree keys %v", keys)
+	}
+
+	// The clashing role of the second document is renamed.
+	roles, _ := core.GetDict(root.Get("RoleMap"))
+	if h1, _ := core.GetNameVal(roles.Get("Heading")); h1 != "H1" {
+		t.Fatalf("expected Heading mapped to H1, got %v", roles)
+	}
+	if h2, _ := core.GetNameVal(roles.Get("Heading_2")); h2 != "H2" {
+		t.Fatalf("expected Heading_2 mapped to H2, got %v", roles)
+	}
+
+	// The MCID of the second page is renumbered from 0, in the content and
+	// in the structure element.
+	content, err := pages[1].GetAllContentStreams()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !strings.Contains(content, "/MCID 0") {
+		t.Fatalf("MCID not renumbered: %s", content)
+	}
+	parts, _ := core.GetArray(doc.Get("K"))
+	part, _ := core.GetDict(parts.Get(1))
+	elem, ok := core.GetDict(part.Get("K"))
+	if arr, isArray := core.GetArray(part.Get("K")); isArray && arr.Len() == 1 {
+		elem, ok = core.GetDict(arr.Get(0))
+	}
+	if !ok {
+		t.Fatalf("missing structure element of the second document: %v", part)
+	}
+	if role, _ := core.GetNameVal(elem.Get("S")); role != "Heading_2" {
+		t.Fatalf("expected role Heading_2, got %v", elem.Get("S"))
+	}
+	if mcid, _ := core.GetIntVal(elem.Get("K")); mcid != 0 {
+		t.Fatalf("expected MCID 0, got %v", elem.Get("K"))
+	}
+}
@@ -0,0 +1,544 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfutil
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// PrinterMarksMode specifies how printer marks are added to a page.
+type PrinterMarksMode int
+
+const (
+	// PrinterMarksContent draws the printer marks in the content stream of
+	// the page.
+	PrinterMarksContent PrinterMarksMode = iota
+
+	// PrinterMarksAnnotations adds the printer marks as PrinterMark
+	// annotations, which can be shown or hidden by printing applications.
+	PrinterMarksAnnotations
+)
+
+// PrinterMarksOptions configures the printer marks added by AddPrinterMarks.
+type PrinterMarksOptions struct {
+	// Bleed is the size of the bleed area around the trim box of pages which
+	// do not have a BleedBox larger than their TrimBox.
+	Bleed float64
+
+	// MirrorBleed fills the bleed area of pages which do not have bleed by
+	// mirroring the content along the edges of their trim box.
+	MirrorBleed bool
+
+	// SlugSize is the size of the area around the bleed box holding the
+	// marks. If zero, it is computed from the size of the marks.
+	SlugSize float64
+
+	// Marks to draw.
+	CropMarks         bool
+	BleedMarks        bool
+	RegistrationMarks bool
+	ColorBars         bool
+
+	// SlugText is a line of job information drawn below the bleed box. The
+	// placeholders {page} and {pages} are replaced by AddPrinterMarksPdf with
+	// the page number and the page count.
+	SlugText string
+
+	// Length, distance from the bleed box and line width of the marks. If
+	// zero, they default to 18, 3 and 0.25.
+	MarkLength    float64
+	MarkOffset    float64
+	MarkLineWidth float64
+
+	// Mode specifies whether the marks are drawn as content or added as
+	// annotations.
+	Mode PrinterMarksMode
+}
+
+// NewPrinterMarksOptions returns printer marks options drawing all marks as
+// content, with the specified bleed.
+func NewPrinterMarksOptions(bleed float64) *PrinterMarksOptions {
+	return &PrinterMarksOptions{
+		Bleed:             bleed,
+		CropMarks:         true,
+		BleedMarks:        true,
+		RegistrationMarks: true,
+		ColorBars:         true,
+	}
+}
+
+// AddPrinterMarksPdf adds printer marks to all pages of inputPath PDF file and
+// saves the result as outputPath PDF file.
+func AddPrinterMarksPdf(inputPath, outputPath string, opts *PrinterMarksOptions) error {
+	if opts == nil {
+		return errors.New("printer marks options required")
+	}
+	reader, file, err := model.NewPdfReaderFromFile(inputPath, nil)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	writer := model.NewPdfWriter()
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return err
+		}
+		pageOpts := *opts
+		pageOpts.SlugText = strings.NewReplacer(
+			"{page}", strconv.Itoa(i),
+			"{pages}", strconv.Itoa(numPages),
+		).Replace(opts.SlugText)
+		if err = AddPrinterMarks(page, &pageOpts); err != nil {
+			return err
+		}
+		if err = writer.AddPage(page); err != nil {
+			return err
+		}
+	}
+	return writer.WriteToFile(outputPath)
+}
+
+// AddPrinterMarks prepares the page for printing: the media box is enlarged
+// to hold the bleed area and the printer marks, which are drawn around the
+// bleed box. The TrimBox (defaulting to CropBox, which defaults to MediaBox)
+// of the page is kept as the finished size of the page. The BleedBox and
+// TrimBox entries of the page are set and the CropBox is set to the new media
+// box.
+//
+// The content of the page is clipped to the bleed box. When MirrorBleed is
+// set and the page has no bleed, the original content of the page is moved
+// to a form XObject, which is also drawn mirrored in the bleed area.
+func AddPrinterMarks(page *model.PdfPage, opts *PrinterMarksOptions) error {
+	if opts == nil {
+		return errors.New("printer marks options required")
+	}
+	if opts.Bleed < 0 || opts.SlugSize < 0 {
+		return errors.New("invalid printer marks options")
+	}
+	mediaBox, err := page.GetMediaBox()
+	if err != nil {
+		return err
+	}
+	trim := *mediaBox
+	if page.CropBox != nil {
+		trim = *page.CropBox
+	}
+	if page.TrimBox != nil {
+		trim = *page.TrimBox
+	}
+	trim.Normalize()
+
+	bleed := trim
+	hasBleed := false
+	if page.BleedBox != nil {
+		bleed = *page.BleedBox
+		bleed.Normalize()
+		hasBleed = bleed.Llx < trim.Llx || bleed.Lly < trim.Lly || bleed.Urx > trim.Urx || bleed.Ury > trim.Ury
+	}
+	if !hasBleed {
+		bleed = expandRect(trim, opts.Bleed)
+	}
+
+	marks := newPrinterMarks(opts, trim, bleed)
+	media := expandRect(bleed, marks.slugSize())
+
+	if err = clipPageContent(page, bleed, trim, opts.MirrorBleed && !hasBleed && opts.Bleed > 0); err != nil {
+		return err
+	}
+	if err = marks.apply(page); err != nil {
+		return err
+	}
+
+	page.MediaBox = &media
+	page.CropBox = &model.PdfRectangle{Llx: media.Llx, Lly: media.Lly, Urx: media.Urx, Ury: media.Ury}
+	page.BleedBox = &bleed
+	page.TrimBox = &trim
+	return nil
+}
+
+func expandRect(rect model.PdfRectangle, d float64) model.PdfRectangle {
+	return model.PdfRectangle{Llx: rect.Llx - d, Lly: rect.Lly - d, Urx: rect.Urx + d, Ury: rect.Ury + d}
+}
+
+// clipPageContent clips the content of the page to the bleed box. If mirror
+// is true, the content is moved to a form XObject, drawn once in place and
+// mirrored along each edge and corner of the trim box.
+func clipPageContent(page *model.PdfPage, bleed, trim model.PdfRectangle, mirror bool) error {
+	clip := contentstream.NewContentCreator().
+		Add_q().
+		Add_re(bleed.Llx, bleed.Lly, bleed.Width(), bleed.Height()).Add_W().Add_n()
+
+	if !mirror {
+		prefix, err := core.MakeStream(clip.Bytes(), core.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+		suffix, err := core.MakeStream([]byte("Q\n"), core.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+		contents := core.MakeArray(prefix)
+		contents.Append(page.GetContentStreamObjs()...)
+		contents.Append(suffix)
+		page.Contents = contents
+		return nil
+	}
+
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	form := model.NewXObjectForm()
+	form.Resources = page.Resources
+	form.BBox = core.MakeArrayFromFloats([]float64{bleed.Llx, bleed.Lly, bleed.Urx, bleed.Ury})
+	if err = form.SetContentStream([]byte(content), core.NewFlateEncoder()); err != nil {
+		return err
+	}
+	resources := model.NewPdfPageResources()
+	name := core.PdfObjectName("Fm0")
+	if err = resources.SetXObjectFormByName(name, form); err != nil {
+		return err
+	}
+
+	// Each strip of the bleed area is filled with the content reflected along
+	// the nearest edges of the trim box.
+	xs := []float64{bleed.Llx, trim.Llx, trim.Urx, bleed.Urx}
+	ys := []float64{bleed.Lly, trim.Lly, trim.Ury, bleed.Ury}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			a, e := 1.0, 0.0
+			switch i {
+			case 0:
+				a, e = -1, 2*trim.Llx
+			case 2:
+				a, e = -1, 2*trim.Urx
+			}
+			d, f := 1.0, 0.0
+			switch j {
+			case 0:
+				d, f = -1, 2*trim.Lly
+			case 2:
+				d, f = -1, 2*trim.Ury
+			}
+			clip.Add_q().
+				Add_re(xs[i], ys[j], xs[i+1]-xs[i], ys[j+1]-ys[j]).Add_W().Add_n().
+				Add_cm(a, 0, 0, d, e, f).
+				Add_Do(name).
+				Add_Q()
+		}
+	}
+	clip.Add_Q()
+
+	page.Resources = resources
+	return page.SetContentStreams([]string{clip.String()}, core.NewFlateEncoder())
+}
+
+// printerMarks draws the marks around the bleed box of a page.
+type printerMarks struct {
+	opts           *PrinterMarksOptions
+	trim, bleed    model.PdfRectangle
+	length, offset float64
+	lineWidth      float64
+	font           *model.PdfFont
+	slugFontSize   float64
+
+	// Names of the registration colorspace and of the slug font in the
+	// resources of the marks.
+	csName, fontName core.PdfObjectName
+}
+
+// printerMarkGroup holds the drawing of a kind of printer mark.
+type printerMarkGroup struct {
+	name    string
+	cc      *contentstream.ContentCreator
+	bbox    model.PdfRectangle
+	hasFont bool
+}
+
+func newPrinterMarks(opts *PrinterMarksOptions, trim, bleed model.PdfRectangle) *printerMarks {
+	m := &printerMarks{
+		opts:         opts,
+		trim:         trim,
+		bleed:        bleed,
+		length:       opts.MarkLength,
+		offset:       opts.MarkOffset,
+		lineWidth:    opts.MarkLineWidth,
+		slugFontSize: 6,
+		csName:       "CSReg",
+		fontName:     "SlugFont",
+	}
+	if m.length <= 0 {
+		m.length = 18
+	}
+	if m.offset <= 0 {
+		m.offset = 3
+	}
+	if m.lineWidth <= 0 {
+		m.lineWidth = 0.25
+	}
+	return m
+}
+
+// slugSize returns the size of the area around the bleed box.
+func (m *printerMarks) slugSize() float64 {
+	if m.opts.SlugSize > 0 {
+		return m.opts.SlugSize
+	}
+	size := m.offset + m.length
+	if m.opts.SlugText != "" {
+		size = math.Max(size, m.offset+2*m.slugFontSize)
+	}
+	return size + m.offset
+}
+
+// groups returns the drawings of the enabled marks.
+func (m *printerMarks) groups() ([]*printerMarkGroup, error) {
+	var groups []*printerMarkGroup
+	if m.opts.CropMarks {
+		groups = append(groups, m.cornerMarks("CropMarks", m.trim, m.length))
+	}
+	if m.opts.BleedMarks && m.bleed != m.trim {
+		groups = append(groups, m.cornerMarks("BleedMarks", m.bleed, m.length/2))
+	}
+	if m.opts.RegistrationMarks {
+		groups = append(groups, m.registrationTargets())
+	}
+	if m.opts.ColorBars {
+		groups = append(groups, m.colorBars())
+	}
+	if m.opts.SlugText != "" {
+		group, err := m.slugLine()
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// cornerMarks draws marks aligned with the edges of the specified rectangle,
+// outside of the bleed box, at each corner.
+func (m *printerMarks) cornerMarks(name string, rect model.PdfRectangle, length float64) *printerMarkGroup {
+	cc := m.newRegistrationContent()
+	b := m.bleed
+	start, end := m.offset, m.offset+length
+	for _, x := range []float64{rect.Llx, rect.Urx} {
+		cc.Add_m(x, b.Ury+start).Add_l(x, b.Ury+end)
+		cc.Add_m(x, b.Lly-start).Add_l(x, b.Lly-end)
+	}
+	for _, y := range []float64{rect.Lly, rect.Ury} {
+		cc.Add_m(b.Llx-start, y).Add_l(b.Llx-end, y)
+		cc.Add_m(b.Urx+start, y).Add_l(b.Urx+end, y)
+	}
+	cc.Add_S().Add_Q()
+	return &printerMarkGroup{
+		name: name,
+		cc:   cc,
+		bbox: expandRect(b, end+m.lineWidth),
+	}
+}
+
+// registrationTargets draws a registration target in the middle of each side
+// of the bleed box.
+func (m *printerMarks) registrationTargets() *printerMarkGroup {
+	cc := m.newRegistrationContent()
+	b := m.bleed
+	r := m.length / 2
+	d := m.offset + r
+	midX, midY := (b.Llx+b.Urx)/2, (b.Lly+b.Ury)/2
+	for _, c := range [][2]float64{{midX, b.Ury + d}, {midX, b.Lly - d}, {b.Llx - d, midY}, {b.Urx + d, midY}} {
+		x, y := c[0], c[1]
+		drawCircle(cc, x, y, r*0.6)
+		cc.Add_m(x-r, y).Add_l(x+r, y).
+			Add_m(x, y-r).Add_l(x, y+r)
+		cc.Add_S()
+		drawCircle(cc, x, y, r*0.3)
+		cc.Add_f()
+	}
+	cc.Add_Q()
+	return &printerMarkGroup{
+		name: "RegistrationTarget",
+		cc:   cc,
+		bbox: expandRect(b, m.offset+m.length+m.lineWidth),
+	}
+}
+
+// colorBars draws process color, overprint and gray patches above the bleed
+// box, between its left edge and the top registration target.
+func (m *printerMarks) colorBars() *printerMarkGroup {
+	patches := [][4]float64{
+		{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1},
+		{0, 1, 1, 0}, {1, 0, 1, 0}, {1, 1, 0, 0},
+		{0, 0, 0, 0.75}, {0, 0, 0, 0.5}, {0, 0, 0, 0.25}, {0, 0, 0, 0.1},
+	}
+	b := m.bleed
+	size := m.length / 2
+	x := b.Llx + m.length
+	y := b.Ury + m.offset
+	maxX := (b.Llx+b.Urx)/2 - m.length/2 - m.offset
+	if !m.opts.RegistrationMarks {
+		maxX = b.Urx - m.length
+	}
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	startX := x
+	for _, p := range patches {
+		if x+size > maxX {
+			break
+		}
+		cc.Add_k(p[0], p[1], p[2], p[3]).
+			Add_re(x, y, size, size).
+			Add_f()
+		x += size
+	}
+	cc.Add_Q()
+	return &printerMarkGroup{
+		name: "ColorBar",
+		cc:   cc,
+		bbox: model.PdfRectangle{Llx: startX, Lly: y, Urx: math.Max(x, startX+size), Ury: y + size},
+	}
+}
+
+// slugLine draws the slug text below the bleed box.
+func (m *printerMarks) slugLine() (*printerMarkGroup, error) {
+	if m.font == nil {
+		font, err := model.NewStandard14Font(model.HelveticaName)
+		if err != nil {
+			return nil, err
+		}
+		m.font = font
+	}
+	b := m.bleed
+	x := b.Llx + m.length
+	y := b.Lly - m.offset - m.slugFontSize
+	text, _ := m.font.StringToCharcodeBytes(m.opts.SlugText)
+
+	var width float64
+	for _, r := range m.opts.SlugText {
+		if metrics, ok := m.font.GetRuneMetrics(r); ok {
+			width += metrics.Wx * m.slugFontSize / 1000
+		}
+	}
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q().
+		Add_g(0).
+		Add_BT().
+		Add_Tf(m.fontName, m.slugFontSize).
+		Add_Td(x, y).
+		Add_Tj(*core.MakeStringFromBytes(text)).
+		Add_ET().
+		Add_Q()
+	return &printerMarkGroup{
+		name:    "SlugLine",
+		cc:      cc,
+		bbox:    model.PdfRectangle{Llx: x, Lly: y - m.slugFontSize/2, Urx: x + width, Ury: y + m.slugFontSize},
+		hasFont: true,
+	}, nil
+}
+
+// newRegistrationContent returns a content creator set up to stroke and fill
+// in registration color.
+func (m *printerMarks) newRegistrationContent() *contentstream.ContentCreator {
+	cc := contentstream.NewContentCreator()
+	cc.Add_q().
+		Add_CS(m.csName).Add_SCN(1).
+		Add_cs(m.csName).Add_scn(1).
+		Add_w(m.lineWidth)
+	return cc
+}
+
+// setResources adds the resources used by the marks.
+func (m *printerMarks) setResources(resources *model.PdfPageResources, hasFont bool) error {
+	if err := resources.SetColorspaceByName(m.csName, registrationColorspace()); err != nil {
+		return err
+	}
+	if hasFont && m.font != nil {
+		return resources.SetFontByName(m.fontName, m.font.ToPdfObject())
+	}
+	return nil
+}
+
+// apply adds the marks to the page, as content or as annotations.
+func (m *printerMarks) apply(page *model.PdfPage) error {
+	if m.opts.Mode != PrinterMarksAnnotations && page.Resources != nil {
+		// Avoid replacing the resources used by the content of the page.
+		m.csName = resourceName("CSReg", page.Resources.HasColorspaceByName)
+		m.fontName = resourceName("SlugFont", page.Resources.HasFontByName)
+	}
+	groups, err := m.groups()
+	if err != nil || len(groups) == 0 {
+		return err
+	}
+
+	if m.opts.Mode == PrinterMarksAnnotations {
+		for _, group := range groups {
+			form := model.NewXObjectForm()
+			form.Resources = model.NewPdfPageResources()
+			if err := m.setResources(form.Resources, group.hasFont); err != nil {
+				return err
+			}
+			bbox := group.bbox
+			form.BBox = core.MakeArrayFromFloats([]float64{bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury})
+			if err := form.SetContentStream(group.cc.Bytes(), core.NewFlateEncoder()); err != nil {
+				return err
+			}
+
+			annot := model.NewPdfAnnotationPrinterMark()
+			annot.MN = core.MakeName(group.name)
+			annot.Rect = bbox.ToPdfObject()
+			annot.F = core.MakeInteger(4)
+			annot.AP = core.MakeDictMap(map[string]core.PdfObject{
+				"N": form.ToPdfObject(),
+			})
+			page.AddAnnotation(annot.PdfAnnotation)
+		}
+		return nil
+	}
+
+	if page.Resources == nil {
+		page.Resources = model.NewPdfPageResources()
+	}
+	hasFont := false
+	var content strings.Builder
+	for _, group := range groups {
+		hasFont = hasFont || group.hasFont
+		content.Write(group.cc.Bytes())
+	}
+	if err := m.setResources(page.Resources, hasFont); err != nil {
+		return err
+	}
+	return page.AddContentStreamByString(content.String())
+}
+
+// drawCircle appends a circle to the current path.
+func drawCircle(cc *contentstream.ContentCreator, x, y, r float64) {
+	k := 0.5523 * r
+	cc.Add_m(x+r, y).
+		Add_c(x+r, y+k, x+k, y+r, x, y+r).
+		Add_c(x-k, y+r, x-r, y+k, x-r, y).
+		Add_c(x-r, y-k, x-k, y-r, x, y-r).
+		Add_c(x+k, y-r, x+r, y-k, x+r, y).
+		Add_h()
+}
@@ -0,0 +1,63 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func TestPrinterMarksResourceNames(t *testing.T) {
+	page := model.NewPdfPage()
+	page.Resources = model.NewPdfPageResources()
+	existingCS := model.NewPdfColorspaceDeviceCMYK()
+	if err := page.Resources.SetColorspaceByName("CSReg", existingCS); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	existingFont := model.DefaultFont().ToPdfObject()
+	if err := page.Resources.SetFontByName("SlugFont", existingFont); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	trim := model.PdfRectangle{Urx: 200, Ury: 200}
+	opts := &PrinterMarksOptions{CropMarks: true, SlugText: "Slug"}
+	if err := newPrinterMarks(opts, trim, expandRect(trim, 9)).apply(page); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	colorspaces, err := page.Resources.GetColorspaces()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if cs := colorspaces.Colorspaces["CSReg"]; cs != existingCS {
+		t.Fatalf("existing colorspace overwritten by %v", cs)
+	}
+	if _, ok := colorspaces.Colorspaces["CSReg1"]; !ok {
+		t.Fatalf("registration colorspace not added as CSReg1")
+	}
+	if font, _ := page.Resources.GetFontByName("SlugFont"); font != existingFont {
+		t.Fatalf("existing font overwritten by %v", font)
+	}
+	if !page.Resources.HasFontByName("SlugFont1") {
+		t.Fatalf("slug font not added as SlugFont1")
+	}
+
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for _, op := range []string{"/CSReg1 CS", "/CSReg1 cs", "/SlugFont1 6 Tf"} {
+		if !strings.Contains(content, op) {
+			t.Fatalf("content does not contain %q: %s", op, content)
+		}
+	}
+}
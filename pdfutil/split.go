@@ -0,0 +1,854 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfutil
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+	"github.com/unidoc/unipdf/v4/render"
+)
+
+// SplitMode specifies how a document is split by a Splitter.
+type SplitMode int
+
+const (
+	// SplitByOutline starts a new part at the page of each top-level outline
+	// item. Pages preceding the first outline item form a separate part.
+	SplitByOutline SplitMode = iota
+
+	// SplitByPageCount splits the document every PageCount pages.
+	SplitByPageCount
+
+	// SplitBySize splits the document into parts not exceeding MaxSize bytes,
+	// unless a single page exceeds it.
+	SplitBySize
+
+	// SplitByBlankPages splits the document at blank separator pages, which
+	// are not included in the parts.
+	SplitByBlankPages
+)
+
+// SplitOptions configures a Splitter.
+type SplitOptions struct {
+	// Mode specifies how the document is split.
+	Mode SplitMode
+
+	// PageCount is the number of pages of each part, for SplitByPageCount.
+	PageCount int
+
+	// MaxSize is the maximum size of each part in bytes, for SplitBySize.
+	MaxSize int64
+
+	// BlankThreshold is the maximum fraction of non-white pixels of a blank
+	// page, for SplitByBlankPages. If zero, it defaults to 0.002.
+	BlankThreshold float64
+
+	// NameTemplate is the template of the names of the output files written
+	// by SplitPdf. It can contain the placeholders {name} (name of the input
+	// file without extension), {n} (part number, starting at 1), {from} and
+	// {to} (page range of the part) and {title} (title of the outline item of
+	// the part). Numeric placeholders can specify a zero-padded width, e.g.
+	// {n:3}. If empty, it defaults to "{name}_{n}.pdf".
+	NameTemplate string
+}
+
+// SplitPart represents a part of a split document.
+type SplitPart struct {
+	// Number of the part, starting at 1.
+	Number int
+
+	// Page range of the part, starting at 1, inclusive.
+	From, To int
+
+	// Title of the top-level outline item starting the part, for
+	// SplitByOutline.
+	Title string
+}
+
+// Splitter splits a document into parts. Each part keeps the outline items,
+// named destinations, form fields and page labels referring to its pages.
+type Splitter struct {
+	rs       io.ReadSeeker
+	reader   *model.PdfReader
+	numPages int
+	opts     SplitOptions
+}
+
+// NewSplitter returns a new splitter for the document read from rs.
+func NewSplitter(rs io.ReadSeeker, opts *SplitOptions) (*Splitter, error) {
+	if opts == nil {
+		return nil, errors.New("split options required")
+	}
+	s := &Splitter{rs: rs, opts: *opts}
+	switch s.opts.Mode {
+	case SplitByOutline, SplitByBlankPages:
+	case SplitByPageCount:
+		if s.opts.PageCount < 1 {
+			return nil, errors.New("split page count must be positive")
+		}
+	case SplitBySize:
+		if s.opts.MaxSize < 1 {
+			return nil, errors.New("split max size must be positive")
+		}
+	default:
+		return nil, errors.New("unsupported split mode")
+	}
+
+	reader, err := s.newReader()
+	if err != nil {
+		return nil, err
+	}
+	if s.numPages, err = reader.GetNumPages(); err != nil {
+		return nil, err
+	}
+	s.reader = reader
+	return s, nil
+}
+
+// SplitPdf splits inputPath PDF file and saves the parts in outputDir,
+// named after opts.NameTemplate. Returns the paths of the output files.
+func SplitPdf(inputPath string, outputDir string, opts *SplitOptions) ([]string, error) {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	splitter, err := NewSplitter(file, opts)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := splitter.Parts()
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	paths := make([]string, 0, len(parts))
+	for _, part := range parts {
+		path := filepath.Join(outputDir, splitter.PartName(part, name))
+		if err := writePartFile(splitter, part, path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func writePartFile(splitter *Splitter, part *SplitPart, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return splitter.WritePart(part, file)
+}
+
+// newReader returns a new reader of the document. Each part is written from
+// its own reader, so that the changes made to the document model of a part
+// do not affect the others.
+func (s *Splitter) newReader() (*model.PdfReader, error) {
+	if _, err := s.rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	reader, err := model.NewPdfReaderLazy(s.rs)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := reader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if encrypted {
+		ok, err := reader.Decrypt(nil)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("cannot split encrypted document")
+		}
+	}
+	resolveOutlineDests(reader)
+	return reader, nil
+}
+
+// resolveOutlineDests replaces the named destinations of the outline items
+// of the document, set as Dest or as the destination of a GoTo action, with
+// the explicit destinations they refer to. This lets the items be mapped to
+// pages when splitting by outline and when pruning the outline of a part.
+func resolveOutlineDests(reader *model.PdfReader) {
+	tree := reader.GetOutlineTree()
+	if tree == nil {
+		return
+	}
+
+	named := map[string]core.PdfObject{}
+	addDest := func(name string, value core.PdfObject) {
+		value = core.TraceToDirectObject(value)
+		if dict, ok := core.GetDict(value); ok {
+			value = core.TraceToDirectObject(dict.Get("D"))
+		}
+		if arr, ok := core.GetArray(value); ok {
+			named[name] = arr
+		}
+	}
+	if dests, err := reader.GetNamedDestinations(); err == nil {
+		if dict, ok := core.GetDict(dests); ok {
+			for _, key := range dict.Keys() {
+				addDest(string(key), dict.Get(key))
+			}
+		}
+	}
+	if names, err := reader.GetNameDictionary(); err == nil {
+		if namesDict, ok := core.GetDict(names); ok {
+			walkNameTree(namesDict.Get("Dests"), map[core.PdfObject]bool{}, func(name *core.PdfObjectString, value core.PdfObject) {
+				addDest(name.Str(), value)
+			})
+		}
+	}
+	if len(named) == 0 {
+		return
+	}
+
+	var resolve func(node *model.PdfOutlineTreeNode, visited map[*model.PdfOutlineTreeNode]bool)
+	resolve = func(node *model.PdfOutlineTreeNode, visited map[*model.PdfOutlineTreeNode]bool) {
+		for node != nil && !visited[node] {
+			visited[node] = true
+			item, ok := node.GetContext().(*model.PdfOutlineItem)
+			if !ok {
+				return
+			}
+			dest := core.TraceToDirectObject(item.Dest)
+			if dest == nil || core.IsNullObject(dest) {
+				if action, ok := core.GetDict(item.A); ok {
+					if s, _ := core.GetNameVal(action.Get("S")); s == "GoTo" {
+						dest = core.TraceToDirectObject(action.Get("D"))
+					}
+				}
+			}
+			var name string
+			switch t := dest.(type) {
+			case *core.PdfObjectName:
+				name = string(*t)
+			case *core.PdfObjectString:
+				name = t.Str()
+			}
+			if arr, ok := named[name]; ok {
+				item.Dest = arr
+			}
+			resolve(item.First, visited)
+			node = item.Next
+		}
+	}
+	resolve(tree.First, map[*model.PdfOutlineTreeNode]bool{})
+}
+
+// Parts returns the parts of the document.
+func (s *Splitter) Parts() ([]*SplitPart, error) {
+	var (
+		parts []*SplitPart
+		err   error
+	)
+	switch s.opts.Mode {
+	case SplitByOutline:
+		parts, err = s.outlineParts()
+	case SplitByPageCount:
+		for from := 1; from <= s.numPages; from += s.opts.PageCount {
+			parts = append(parts, &SplitPart{From: from, To: min(from+s.opts.PageCount-1, s.numPages)})
+		}
+	case SplitBySize:
+		parts, err = s.sizeParts()
+	case SplitByBlankPages:
+		parts, err = s.blankPageParts()
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, part := range parts {
+		part.Number = i + 1
+	}
+	return parts, nil
+}
+
+func (s *Splitter) outlineParts() ([]*SplitPart, error) {
+	outline, err := s.reader.GetOutlines()
+	if err != nil {
+		return nil, err
+	}
+	titles := map[int]string{}
+	var starts []int
+	for _, item := range outline.Entries {
+		page := int(item.Dest.Page) + 1
+		if item.Dest.Mode == "" || page < 1 || page > s.numPages {
+			continue
+		}
+		if _, ok := titles[page]; !ok {
+			titles[page] = item.Title
+			starts = append(starts, page)
+		}
+	}
+	if len(starts) == 0 {
+		return nil, errors.New("document has no top-level outline items")
+	}
+	sort.Ints(starts)
+	if starts[0] > 1 {
+		starts = append([]int{1}, starts...)
+	}
+
+	parts := make([]*SplitPart, len(starts))
+	for i, from := range starts {
+		to := s.numPages
+		if i+1 < len(starts) {
+			to = starts[i+1] - 1
+		}
+		parts[i] = &SplitPart{From: from, To: to, Title: titles[from]}
+	}
+	return parts, nil
+}
+
+func (s *Splitter) blankPageParts() ([]*SplitPart, error) {
+	threshold := s.opts.BlankThreshold
+	if threshold <= 0 {
+		threshold = 0.002
+	}
+	device := render.NewImageDevice()
+	device.OutputWidth = 200
+
+	var parts []*SplitPart
+	var part *SplitPart
+	for i := 1; i <= s.numPages; i++ {
+		page, err := s.reader.GetPage(i)
+		if err != nil {
+			return nil, err
+		}
+		img, err := device.RenderWithOpts(page, true)
+		if err != nil {
+			return nil, err
+		}
+
+		bounds := img.Bounds()
+		var ink int
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				if c.A >= 128 && (int(c.R)+int(c.G)+int(c.B))/3 < 230 {
+					ink++
+				}
+			}
+		}
+		if area := bounds.Dx() * bounds.Dy(); area == 0 || float64(ink) <= threshold*float64(area) {
+			common.Log.Debug("Page %d is a blank separator page", i)
+			part = nil
+			continue
+		}
+
+		if part == nil {
+			part = &SplitPart{From: i}
+			parts = append(parts, part)
+		}
+		part.To = i
+	}
+	return parts, nil
+}
+
+func (s *Splitter) sizeParts() ([]*SplitPart, error) {
+	var parts []*SplitPart
+	for from := 1; from <= s.numPages; {
+		// Estimate the page range fitting the maximum size, then check the
+		// actual size of the part.
+		est := newSizeEstimator()
+		to := from
+		for i := from; i <= s.numPages; i++ {
+			page, err := s.reader.GetPage(i)
+			if err != nil {
+				return nil, err
+			}
+			est.addPage(page)
+			if est.size > s.opts.MaxSize && i > from {
+				break
+			}
+			to = i
+		}
+
+		for {
+			part := &SplitPart{From: from, To: to}
+			counter := &countingWriter{}
+			if err := s.WritePart(part, counter); err != nil {
+				return nil, err
+			}
+			if counter.n <= s.opts.MaxSize || to == from {
+				parts = append(parts, part)
+				break
+			}
+			count := int(float64(to-from+1) * float64(s.opts.MaxSize) / float64(counter.n))
+			to = max(from, min(from+count-1, to-1))
+		}
+		from = to + 1
+	}
+	return parts, nil
+}
+
+// PartName returns the name of the output file of the part, formatted from
+// the name template of the splitter. The name of the input document is used
+// for the {name} placeholder.
+func (s *Splitter) PartName(part *SplitPart, name string) string {
+	template := s.opts.NameTemplate
+	if template == "" {
+		template = "{name}_{n}.pdf"
+	}
+	title := strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(part.Title))
+
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		sb.WriteString(template[:start])
+		key, width, _ := strings.Cut(template[start+1:start+end], ":")
+		number := -1
+		switch key {
+		case "name":
+			sb.WriteString(name)
+		case "title":
+			sb.WriteString(title)
+		case "n":
+			number = part.Number
+		case "from":
+			number = part.From
+		case "to":
+			number = part.To
+		default:
+			sb.WriteString(template[start : start+end+1])
+		}
+		if number >= 0 {
+			w, _ := strconv.Atoi(width)
+			sb.WriteString(fmt.Sprintf("%0*d", w, number))
+		}
+		template = template[start+end+1:]
+	}
+	sb.WriteString(template)
+	return sb.String()
+}
+
+// WritePart writes the pages of the part to w, with the outline items, named
+// destinations, form fields and page labels referring to them. Links to
+// pages outside of the part are removed.
+func (s *Splitter) WritePart(part *SplitPart, w io.Writer) error {
+	if part.From < 1 || part.To > s.numPages || part.From > part.To {
+		return fmt.Errorf("invalid part page range: %d-%d", part.From, part.To)
+	}
+	reader, err := s.newReader()
+	if err != nil {
+		return err
+	}
+
+	pages := make([]*model.PdfPage, 0, part.To-part.From+1)
+	inPart := map[int64]bool{}
+	for i := part.From; i <= part.To; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, page)
+		if ind := page.GetPageAsIndirectObject(); ind != nil {
+			inPart[ind.ObjectNumber] = true
+		}
+	}
+	destInPart := func(dest core.PdfObject) bool {
+		arr, ok := core.GetArray(core.TraceToDirectObject(dest))
+		if !ok || arr.Len() == 0 {
+			return false
+		}
+		var obj core.PdfObject = arr.Get(0)
+		if ref, ok := obj.(*core.PdfObjectReference); ok {
+			obj = ref.Resolve()
+		}
+		ind, ok := obj.(*core.PdfIndirectObject)
+		return ok && inPart[ind.ObjectNumber]
+	}
+
+	// Remove links to pages outside of the part, as writing them would pull
+	// the pages in the output.
+	annots := map[core.PdfObject]bool{}
+	for _, page := range pages {
+		pageAnnots, err := page.GetAnnotations()
+		if err != nil {
+			return err
+		}
+		kept := pageAnnots[:0]
+		for _, annot := range pageAnnots {
+			if link, ok := annot.GetContext().(*model.PdfAnnotationLink); ok && !linkInPart(link, destInPart) {
+				continue
+			}
+			annot.P = page.GetPageAsIndirectObject()
+			annots[annot.GetContainingPdfObject()] = true
+			kept = append(kept, annot)
+		}
+		page.SetAnnotations(kept)
+	}
+
+	writer := model.NewPdfWriter()
+	for _, page := range pages {
+		if err := writer.AddPage(page); err != nil {
+			return err
+		}
+	}
+
+	if reader.GetOutlineTree() != nil {
+		if outline, err := reader.GetOutlines(); err == nil {
+			entries := pruneOutlineItems(outline.Entries, part)
+			if len(entries) > 0 {
+				writer.AddOutlineTree((&model.Outline{Entries: entries}).ToOutlineTree())
+			}
+		}
+	}
+
+	if err := splitNamedDestinations(reader, &writer, destInPart); err != nil {
+		return err
+	}
+
+	if labels, err := reader.GetPageLabels(); err == nil && labels != nil {
+		if nums := splitPageLabels(labels, part); nums != nil {
+			if err := writer.SetPageLabels(core.MakeDictMap(map[string]core.PdfObject{"Nums": nums})); err != nil {
+				return err
+			}
+		}
+	}
+
+	if acroForm := reader.AcroForm; acroForm != nil && acroForm.Fields != nil {
+		fields := pruneFields(*acroForm.Fields, annots)
+		if len(fields) > 0 {
+			acroForm.Fields = &fields
+			if err := writer.SetForms(acroForm); err != nil {
+				return err
+			}
+		}
+	}
+	return writer.Write(w)
+}
+
+// linkInPart returns true if the link does not point to a page outside of the
+// part.
+func linkInPart(link *model.PdfAnnotationLink, destInPart func(core.PdfObject) bool) bool {
+	isExplicitDest := func(dest core.PdfObject) bool {
+		_, ok := core.GetArray(core.TraceToDirectObject(dest))
+		return ok
+	}
+	if link.Dest != nil && isExplicitDest(link.Dest) && !destInPart(link.Dest) {
+		return false
+	}
+	if action, ok := core.GetDict(link.A); ok {
+		if s, _ := core.GetNameVal(action.Get("S")); s == "GoTo" {
+			if d := action.Get("D"); isExplicitDest(d) && !destInPart(d) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// pruneOutlineItems returns copies of the outline items pointing to pages of
+// the part, or having descendants which do. The destinations of the items
+// pointing to other pages are removed.
+func pruneOutlineItems(items []*model.OutlineItem, part *SplitPart) []*model.OutlineItem {
+	var pruned []*model.OutlineItem
+	for _, item := range items {
+		entries := pruneOutlineItems(item.Entries, part)
+		page := int(item.Dest.Page) + 1
+		inPart := item.Dest.Mode != "" && page >= part.From && page <= part.To
+		if !inPart && len(entries) == 0 {
+			continue
+		}
+		dest := item.Dest
+		if !inPart {
+			dest = model.OutlineDest{Page: -1}
+		}
+		pruned = append(pruned, &model.OutlineItem{Title: item.Title, Dest: dest, Entries: entries})
+	}
+	return pruned
+}
+
+// splitNamedDestinations sets the named destinations of the document, from
+// the Dests entry of the catalog and the Dests name tree, which point to
+// pages of the part.
+func splitNamedDestinations(reader *model.PdfReader, writer *model.PdfWriter, destInPart func(core.PdfObject) bool) error {
+	namedDestInPart := func(value core.PdfObject) bool {
+		value = core.TraceToDirectObject(value)
+		if dict, ok := core.GetDict(value); ok {
+			value = dict.Get("D")
+		}
+		return destInPart(value)
+	}
+
+	dests, err := reader.GetNamedDestinations()
+	if err != nil {
+		return err
+	}
+	if dict, ok := core.GetDict(dests); ok {
+		kept := core.MakeDict()
+		for _, key := range dict.Keys() {
+			if value := dict.Get(key); namedDestInPart(value) {
+				kept.Set(key, value)
+			}
+		}
+		if len(kept.Keys()) > 0 {
+			if err := writer.SetNamedDestinations(kept); err != nil {
+				return err
+			}
+		}
+	}
+
+	names, err := reader.GetNameDictionary()
+	if err != nil {
+		return err
+	}
+	namesDict, ok := core.GetDict(names)
+	if !ok {
+		return nil
+	}
+	out := core.MakeDict()
+	for _, key := range namesDict.Keys() {
+		if key != "Dests" {
+			out.Set(key, namesDict.Get(key))
+		}
+	}
+	var entries []core.PdfObject
+	walkNameTree(namesDict.Get("Dests"), map[core.PdfObject]bool{}, func(name *core.PdfObjectString, value core.PdfObject) {
+		if namedDestInPart(value) {
+			entries = append(entries, name, value)
+		}
+	})
+	if len(entries) > 0 {
+		// The entries of a name tree are sorted by key.
+		type entry struct{ key, value core.PdfObject }
+		sorted := make([]entry, 0, len(entries)/2)
+		for i := 0; i < len(entries); i += 2 {
+			sorted = append(sorted, entry{entries[i], entries[i+1]})
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].key.(*core.PdfObjectString).Str() < sorted[j].key.(*core.PdfObjectString).Str()
+		})
+		arr := core.MakeArray()
+		for _, e := range sorted {
+			arr.Append(e.key, e.value)
+		}
+		out.Set("Dests", core.MakeDictMap(map[string]core.PdfObject{"Names": arr}))
+	}
+	if len(out.Keys()) == 0 {
+		return nil
+	}
+	return writer.SetNameDictionary(out)
+}
+
+// walkNameTree calls fn for each entry of the name tree.
+func walkNameTree(node core.PdfObject, visited map[core.PdfObject]bool, fn func(*core.PdfObjectString, core.PdfObject)) {
+	dict, ok := core.GetDict(node)
+	if !ok || visited[dict] {
+		return
+	}
+	visited[dict] = true
+	if names, ok := core.GetArray(dict.Get("Names")); ok {
+		for i := 0; i+1 < names.Len(); i += 2 {
+			if name, ok := core.GetString(names.Get(i)); ok {
+				fn(name, names.Get(i+1))
+			}
+		}
+	}
+	if kids, ok := core.GetArray(dict.Get("Kids")); ok {
+		for _, kid := range kids.Elements() {
+			walkNameTree(kid, visited, fn)
+		}
+	}
+}
+
+// splitPageLabels returns the Nums array of the page labels of the part,
+// given the page labels number tree of the document.
+func splitPageLabels(labels core.PdfObject, part *SplitPart) *core.PdfObjectArray {
+	type labelRange struct {
+		start int64
+		dict  *core.PdfObjectDictionary
+	}
+	var ranges []labelRange
+	var walk func(node core.PdfObject, visited map[core.PdfObject]bool)
+	walk = func(node core.PdfObject, visited map[core.PdfObject]bool) {
+		dict, ok := core.GetDict(node)
+		if !ok || visited[dict] {
+			return
+		}
+		visited[dict] = true
+		if nums, ok := core.GetArray(dict.Get("Nums")); ok {
+			for i := 0; i+1 < nums.Len(); i += 2 {
+				start, ok := core.GetIntVal(nums.Get(i))
+				label, isDict := core.GetDict(nums.Get(i + 1))
+				if ok && isDict {
+					ranges = append(ranges, labelRange{int64(start), label})
+				}
+			}
+		}
+		if kids, ok := core.GetArray(dict.Get("Kids")); ok {
+			for _, kid := range kids.Elements() {
+				walk(kid, visited)
+			}
+		}
+	}
+	walk(labels, map[core.PdfObject]bool{})
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	first, last := int64(part.From-1), int64(part.To-1)
+	nums := core.MakeArray()
+	for i, r := range ranges {
+		end := last
+		if i+1 < len(ranges) {
+			end = ranges[i+1].start - 1
+		}
+		if end < first || r.start > last {
+			continue
+		}
+		label := r.dict
+		start := r.start - first
+		if start < 0 {
+			// The range starts before the part: continue its numbering.
+			label = core.MakeDict()
+			label.Merge(r.dict)
+			st := int64(1)
+			if v, ok := core.GetIntVal(r.dict.Get("St")); ok {
+				st = int64(v)
+			}
+			label.Set("St", core.MakeInteger(st-start))
+			start = 0
+		}
+		nums.Append(core.MakeInteger(start), label)
+	}
+	if nums.Len() == 0 {
+		return nil
+	}
+	return nums
+}
+
+// pruneFields returns the fields having widgets among the specified
+// annotations, or descendants which do. The widgets and kids of the fields
+// are pruned accordingly.
+func pruneFields(fields []*model.PdfField, annots map[core.PdfObject]bool) []*model.PdfField {
+	var pruned []*model.PdfField
+	for _, field := range fields {
+		var widgets []*model.PdfAnnotationWidget
+		for _, widget := range field.Annotations {
+			if annots[widget.GetContainingPdfObject()] {
+				widgets = append(widgets, widget)
+			}
+		}
+		kids := pruneFields(field.Kids, annots)
+		if len(widgets) == 0 && len(kids) == 0 {
+			continue
+		}
+		field.Annotations = widgets
+		field.Kids = kids
+		pruned = append(pruned, field)
+	}
+	return pruned
+}
+
+// sizeEstimator estimates the size of the objects written for a set of
+// pages. Objects shared by several pages are counted once.
+type sizeEstimator struct {
+	size    int64
+	visited map[core.PdfObject]bool
+}
+
+func newSizeEstimator() *sizeEstimator {
+	return &sizeEstimator{size: 1024, visited: map[core.PdfObject]bool{}}
+}
+
+func (e *sizeEstimator) addPage(page *model.PdfPage) {
+	e.size += 32
+	if ind := page.GetPageAsIndirectObject(); ind != nil {
+		e.add(ind.PdfObject, ind.PdfObject)
+	}
+}
+
+func (e *sizeEstimator) add(obj, pageDict core.PdfObject) {
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		e.add(t.Resolve(), pageDict)
+	case *core.PdfIndirectObject:
+		if e.visited[t] {
+			return
+		}
+		e.visited[t] = true
+		e.size += 24
+		e.add(t.PdfObject, pageDict)
+	case *core.PdfObjectStream:
+		if e.visited[t] {
+			return
+		}
+		e.visited[t] = true
+		e.size += int64(len(t.Stream)) + 40
+		if t.PdfObjectDictionary != nil {
+			e.add(t.PdfObjectDictionary, pageDict)
+		}
+	case *core.PdfObjectDictionary:
+		// Do not follow references to other pages.
+		if t != pageDict {
+			if name, ok := core.GetNameVal(t.Get("Type")); ok && name == "Page" {
+				return
+			}
+		}
+		e.size += 4
+		for _, key := range t.Keys() {
+			if key == "Parent" || key == "P" {
+				continue
+			}
+			e.size += int64(len(key)) + 2
+			e.add(t.Get(key), pageDict)
+		}
+	case *core.PdfObjectArray:
+		e.size += 2
+		for _, elem := range t.Elements() {
+			e.add(elem, pageDict)
+		}
+	case nil:
+	default:
+		e.size += int64(len(t.Write())) + 1
+	}
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
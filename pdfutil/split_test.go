@@ -0,0 +1,87 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package pdfutil
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// makePdf returns a PDF document made of the specified objects, numbered
+// from 1, the first one being the catalog.
+func makePdf(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestSplitByOutlineNamedDests(t *testing.T) {
+	data := makePdf(
+		"<< /Type /Catalog /Pages 2 0 R /Outlines 6 0 R /Dests 9 0 R /Names << /Dests 10 0 R >> >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R 5 0 R] /Count 3 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 100] >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 100] >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 100] >>",
+		"<< /Type /Outlines /First 7 0 R /Last 8 0 R /Count 2 >>",
+		"<< /Title (Chapter 1) /Parent 6 0 R /Next 8 0 R /Dest /ch1 >>",
+		"<< /Title (Chapter 2) /Parent 6 0 R /Prev 7 0 R /A << /S /GoTo /D (ch2) >> >>",
+		"<< /ch1 [4 0 R /Fit] >>",
+		"<< /Names [(ch2) << /D [5 0 R /XYZ 0 100 0] >>] >>",
+	)
+
+	s, err := NewSplitter(bytes.NewReader(data), &SplitOptions{Mode: SplitByOutline})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	parts, err := s.Parts()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	expected := []SplitPart{
+		{Number: 1, From: 1, To: 1},
+		{Number: 2, From: 2, To: 2, Title: "Chapter 1"},
+		{Number: 3, From: 3, To: 3, Title: "Chapter 2"},
+	}
+	if len(parts) != len(expected) {
+		t.Fatalf("expected %d parts, got %d", len(expected), len(parts))
+	}
+	for i, part := range parts {
+		if *part != expected[i] {
+			t.Fatalf("part %d: expected %+v, got %+v", i, expected[i], *part)
+		}
+	}
+
+	// The outline of a part keeps the items pointing to its pages.
+	reader, err := s.newReader()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	outline, err := reader.GetOutlines()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	entries := pruneOutlineItems(outline.Entries, parts[2])
+	if len(entries) != 1 || entries[0].Title != "Chapter 2" || entries[0].Dest.Page != 2 {
+		t.Fatalf("unexpected outline of the last part: %+v", entries)
+	}
+}
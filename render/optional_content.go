@@ -0,0 +1,120 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package render
+
+import (
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+	"github.com/unidoc/unipdf/v4/render/internal/context"
+)
+
+// ocVisibility returns the visibility of optional content for rendering `page`: the visibility of the
+// device or else the default configuration of the document for viewing. It returns nil if the content of
+// hidden optional content groups is rendered.
+func (d *ImageDevice) ocVisibility(page *model.PdfPage) *model.OCVisibility {
+	if d.IncludeHiddenContent {
+		return nil
+	}
+	if d.OCVisibility != nil {
+		return d.OCVisibility
+	}
+	visibility, err := page.GetOCVisibility(model.OCEventView)
+	if err != nil {
+		common.Log.Debug("ERROR: optional content properties: %v", err)
+		return nil
+	}
+	return visibility
+}
+
+// ocState tracks the visibility of the marked content of a content stream.
+type ocState struct {
+	visibility *model.OCVisibility
+
+	// hidden holds for each marked content level whether its content is hidden.
+	hidden []bool
+
+	// ts is the text state whose rendering mode is restored to tr after text of hidden content.
+	ts *context.TextState
+	tr context.TextRenderingMode
+}
+
+func newOCState(visibility *model.OCVisibility) *ocState {
+	return &ocState{visibility: visibility}
+}
+
+// skip returns true if `op` draws hidden content and is not to be processed. Paths of hidden content are
+// ended without painting them, so that they still clip, and hidden text is shown invisibly, so that the
+// text position is updated.
+func (s *ocState) skip(op *contentstream.ContentStreamOperation, resources *model.PdfPageResources, ts *context.TextState) bool {
+	if s == nil || s.visibility == nil {
+		return false
+	}
+	if s.ts != nil {
+		s.ts.Tr = s.tr
+		s.ts = nil
+	}
+	hidden := len(s.hidden) > 0 && s.hidden[len(s.hidden)-1]
+	switch op.Operand {
+	case "BMC":
+		s.hidden = append(s.hidden, hidden)
+	case "BDC":
+		if !hidden && len(op.Params) == 2 {
+			if tag, ok := core.GetNameVal(op.Params[0]); ok && tag == "OC" {
+				hidden = !s.visibility.IsVisible(markedContentOC(op.Params[1], resources))
+			}
+		}
+		s.hidden = append(s.hidden, hidden)
+	case "EMC":
+		if n := len(s.hidden); n > 0 {
+			s.hidden = s.hidden[:n-1]
+		}
+	case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*":
+		if hidden {
+			op.Operand = "n"
+		}
+	case "Tj", "TJ", "'", "\"":
+		if hidden && ts != nil {
+			s.ts, s.tr = ts, ts.Tr
+			ts.Tr = context.TextRenderingModeInvisible
+		}
+	case "sh", "BI":
+		return hidden
+	case "Do":
+		if hidden {
+			return true
+		}
+		if len(op.Params) == 1 {
+			if name, ok := core.GetName(op.Params[0]); ok {
+				if stream, _ := resources.GetXObjectByName(*name); stream != nil {
+					return !s.visibility.IsVisible(stream.PdfObjectDictionary.Get("OC"))
+				}
+			}
+		}
+	}
+	return false
+}
+
+// markedContentOC returns the optional content of the properties of marked content with the OC tag: an
+// inline dictionary or the name of an entry of the Properties resources.
+func markedContentOC(props core.PdfObject, resources *model.PdfPageResources) core.PdfObject {
+	if name, ok := core.GetName(props); ok {
+		if resources == nil {
+			return nil
+		}
+		properties, ok := core.GetDict(resources.Properties)
+		if !ok {
+			return nil
+		}
+		return properties.Get(*name)
+	}
+	return props
+}
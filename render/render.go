@@ -9,11 +9,9 @@
 // Use of this source code is governed by the UniDoc End User License Agreement
 // terms that can be accessed at https://unidoc.io/eula/
 
-package render ;import (_f "errors";_ac "fmt";_acb "github.com/adrg/sysfont";_gc "github.com/unidoc/unipdf/v4/annotator";_db "github.com/unidoc/unipdf/v4/common";_bf "github.com/unidoc/unipdf/v4/contentstream";_bd "github.com/unidoc/unipdf/v4/contentstream/draw";
+package render ;import (_f "errors";_ac "fmt";_acb "github.com/adrg/sysfont";_db "github.com/unidoc/unipdf/v4/common";_bf "github.com/unidoc/unipdf/v4/contentstream";_bd "github.com/unidoc/unipdf/v4/contentstream/draw";
 _ag "github.com/unidoc/unipdf/v4/core";_af "github.com/unidoc/unipdf/v4/internal/license";_ge "github.com/unidoc/unipdf/v4/internal/transform";_cg "github.com/unidoc/unipdf/v4/model";_de "github.com/unidoc/unipdf/v4/render/internal/context";_c "github.com/unidoc/unipdf/v4/render/internal/context/imagerender";
-_ae "golang.org/x/image/draw";_eg "image";_ee "image/color";_ea "image/draw";_dg "image/jpeg";_aa "image/png";_d "math";_a "os";_b "path/filepath";_g "strings";);func (_ega *renderer )renderPage (_bdc _de .Context ,_bg *_cg .PdfPage ,_aec _ge .Matrix ,_aae bool )error {if !_aae {_gad :=_cg .FieldFlattenOpts {AnnotFilterFunc :func (_bge *_cg .PdfAnnotation )bool {switch _bge .GetContext ().(type ){case *_cg .PdfAnnotationLine :return true ;
-case *_cg .PdfAnnotationSquare :return true ;case *_cg .PdfAnnotationCircle :return true ;case *_cg .PdfAnnotationPolygon :return true ;case *_cg .PdfAnnotationPolyLine :return true ;};return false ;}};_aga :=_gc .FieldAppearance {};_ad :=_bg .FlattenFieldsWithOpts (_aga ,&_gad );
-if _ad !=nil {_db .Log .Debug ("\u0045\u0072r\u006f\u0072\u0020\u0064u\u0072\u0069n\u0067\u0020\u0061\u006e\u006e\u006f\u0074\u0061t\u0069\u006f\u006e\u0020\u0066\u006c\u0061\u0074\u0074\u0065\u006e\u0069n\u0067\u0020\u0025\u0076",_ad );};};_fe ,_bc :=_bg .GetAllContentStreams ();
+_ae "golang.org/x/image/draw";_eg "image";_ee "image/color";_ea "image/draw";_dg "image/jpeg";_aa "image/png";_d "math";_a "os";_b "path/filepath";_g "strings";);func (_ega *renderer )renderPage (_bdc _de .Context ,_bg *_cg .PdfPage ,_aec _ge .Matrix ,_aae bool )error {if !_aae {flattenAnnotations (_bg );};_fe ,_bc :=_bg .GetAllContentStreams ();
 if _bc !=nil {return _bc ;};if _gcc :=_aec ;!_gcc .Identity (){_fe =_ac .Sprintf ("%\u002e\u0032\u0066\u0020\u0025\u002e2\u0066\u0020\u0025\u002e\u0032\u0066 \u0025\u002e\u0032\u0066\u0020\u0025\u002e2\u0066\u0020\u0025\u002e\u0032\u0066\u0020\u0063\u006d\u0020%\u0073",_gcc [0],_gcc [1],_gcc [3],_gcc [4],_gcc [6],_gcc [7],_fe );
 };_bdc .Translate (0,float64 (_bdc .Height ()));_bdc .Scale (1,-1);_bdc .Push ();_bdc .SetRGBA (1,1,1,1);_bdc .DrawRectangle (0,0,float64 (_bdc .Width ()),float64 (_bdc .Height ()));_bdc .Fill ();_bdc .Pop ();_bdc .SetLineWidth (1.0);_bdc .SetRGBA (0,0,0,1);
 return _ega .renderContentStream (_bdc ,_fe ,_bg .Resources );};
@@ -54,7 +52,7 @@ if _aeb ==int64 (ShadingTypeAxial ){return _bbfg .processLinearShading (_geaa ,_
 // Source: PDF32000_2008.pdf. Chapter 8.7.4.5
 type PdfShadingType int64 ;func (_fad *renderer )renderContentStream (_gcda _de .Context ,_dc string ,_ef *_cg .PdfPageResources )error {_dec ,_ggc :=_bf .NewContentStreamParser (_dc ).Parse ();if _ggc !=nil {return _ggc ;};if _fad ._cee ==nil {_fad ._cee =map[string ]*_de .TextFont {};
 };if _fad ._ccf ==nil {_fad ._ccf =map[string ]*_de .TextFont {};};_afc :=_gcda .TextState ();_afc .GlobalScale =_fad ._cge ;if _fad ._ege ==nil {_fad ._ege =_acb .NewFinder (&_acb .FinderOpts {Extensions :[]string {"\u002e\u0074\u0074\u0066","\u002e\u0074\u0074\u0063"}});
-};var _eeb *_bf .ContentStreamOperation ;var _bb bool ;var _gdd _de .FillRule ;_bfg :=_bf .NewContentStreamProcessor (*_dec );_bfg .AddHandler (_bf .HandlerConditionEnumAllOperands ,"",func (_fd *_bf .ContentStreamOperation ,_dee _bf .GraphicsState ,_gca *_cg .PdfPageResources )error {_db .Log .Debug ("\u0050\u0072\u006f\u0063\u0065\u0073\u0073\u0069\u006e\u0067\u0020\u0025\u0073",_fd .Operand );
+};var _eeb *_bf .ContentStreamOperation ;var _bb bool ;var _gdd _de .FillRule ;_ocs :=newOCState (_fad ._ocVisibility );_bfg :=_bf .NewContentStreamProcessor (*_dec );_bfg .AddHandler (_bf .HandlerConditionEnumAllOperands ,"",func (_fd *_bf .ContentStreamOperation ,_dee _bf .GraphicsState ,_gca *_cg .PdfPageResources )error {_db .Log .Debug ("\u0050\u0072\u006f\u0063\u0065\u0073\u0073\u0069\u006e\u0067\u0020\u0025\u0073",_fd .Operand );if _ocs .skip (_fd ,_gca ,_afc ){return nil ;};
 switch _fd .Operand {case "\u0071":_gcda .Push ();case "\u0051":_gcda .Pop ();_afc =_gcda .TextState ();case "\u0063\u006d":if len (_fd .Params )!=6{return _fa ;};_dfe ,_abg :=_ag .GetNumbersAsFloat (_fd .Params );if _abg !=nil {return _abg ;};_ec :=_ge .NewMatrix (_dfe [0],_dfe [1],_dfe [2],_dfe [3],_dfe [4],_dfe [5]);
 _db .Log .Debug ("\u0047\u0072\u0061\u0070\u0068\u0069\u0063\u0073\u0020\u0073\u0074a\u0074\u0065\u0020\u006d\u0061\u0074\u0072\u0069\u0078\u003a \u0025\u002b\u0076",_ec );_gcda .SetMatrix (_gcda .Matrix ().Mult (_ec ));case "\u0077":if len (_fd .Params )!=1{return _fa ;
 };_be ,_da :=_ag .GetNumbersAsFloat (_fd .Params );if _da !=nil {return _da ;};_gcda .SetLineWidth (_be [0]);case "\u004a":if len (_fd .Params )!=1{return _fa ;};_aaa ,_cfg :=_ag .GetIntVal (_fd .Params [0]);if !_cfg {return _ce ;};switch _aaa {case 0:_gcda .SetLineCap (_de .LineCapButt );
@@ -200,7 +198,7 @@ func (_df *ImageDevice )RenderWithOpts (page *_cg .PdfPage ,skipFlattening bool
 _aed ,_dbe =_dd .Width (),_dd .Height ();};_deg :=page .Rotate ;_gf ,_cfe ,_fg ,_bdg :=_eace .Llx ,_eace .Lly ,_eace .Width (),_eace .Height ();_egb :=_ge .IdentityMatrix ();if _deg !=nil &&*_deg %360!=0&&*_deg %90==0{_gcg :=-float64 (*_deg );_fgb :=_egc (_fg ,_bdg ,_gcg );
 _egb =_egb .Translate ((_fgb .Width -_fg )/2+_fg /2,(_fgb .Height -_bdg )/2+_bdg /2).Rotate (_gcg *_d .Pi /180).Translate (-_fg /2,-_bdg /2);_fg ,_bdg =_fgb .Width ,_fgb .Height ;if _dd !=nil {_geb :=_egc (_aed ,_dbe ,_gcg );_aed ,_dbe =_geb .Width ,_geb .Height ;
 };};if _gf !=0||_cfe !=0{_egb =_egb .Translate (-_gf ,-_cfe );};_df ._cge =1.0;if _df .OutputWidth !=0{_gg :=_fg ;if _dd !=nil {_gg =_aed ;};_df ._cge =float64 (_df .OutputWidth )/_gg ;_fg ,_bdg ,_aed ,_dbe =_fg *_df ._cge ,_bdg *_df ._cge ,_aed *_df ._cge ,_dbe *_df ._cge ;
-_egb =_ge .ScaleMatrix (_df ._cge ,_df ._cge ).Mult (_egb );};_ddd :=_c .NewContext (int (_fg ),int (_bdg ));_ddd .SetInterpolator (_df ._ca );if _ga :=_df .renderPage (_ddd ,page ,_egb ,skipFlattening );_ga !=nil {return nil ,_ga ;};_abd :=_ddd .Image ();
+_egb =_ge .ScaleMatrix (_df ._cge ,_df ._cge ).Mult (_egb );};_ddd :=_c .NewContext (int (_fg ),int (_bdg ));_ddd .SetInterpolator (_df ._ca );_df ._ocVisibility =_df .ocVisibility (page );if _ga :=_df .renderPage (_ddd ,page ,_egb ,skipFlattening );_ga !=nil {return nil ,_ga ;};_abd :=_ddd .Image ();
 if _dd !=nil {_ed ,_eea :=(_dd .Llx -_gf )*_df ._cge ,(_dd .Lly -_cfe )*_df ._cge ;_gcd :=_eg .Rect (0,0,int (_aed ),int (_dbe ));_gd :=_eg .Pt (int (_ed ),int (_bdg -_eea -_dbe ));_dea :=_eg .NewRGBA (_gcd );_ea .Draw (_dea ,_gcd ,_abd ,_gd ,_ea .Src );
 _abd =_dea ;};return _abd ,nil ;};func _eebc (_dddc ,_gfa _eg .Image ,_eaed _ae .Interpolator )_eg .Image {_bfgc ,_fdac :=_gfa .Bounds ().Size (),_dddc .Bounds ().Size ();_cca ,_bdac :=_bfgc .X ,_bfgc .Y ;if _fdac .X > _cca {_cca =_fdac .X ;};if _fdac .Y > _bdac {_bdac =_fdac .Y ;
 };_abgf :=_eg .Rect (0,0,_cca ,_bdac );if _bfgc .X !=_cca ||_bfgc .Y !=_bdac {_ffbc :=_eg .NewRGBA (_abgf );_eaed .Scale (_ffbc ,_abgf ,_gfa ,_gfa .Bounds (),_ae .Over ,nil );_gfa =_ffbc ;};if _fdac .X !=_cca ||_fdac .Y !=_bdac {_ededg :=_eg .NewRGBA (_abgf );
@@ -222,6 +220,13 @@ type ImageDevice struct{*renderer ;
 // OutputWidth represents the width of the rendered images in pixels.
 // The heights of the output images are calculated based on the selected
 // width and the original height of each rendered page.
-OutputWidth int ;_ca _ae .Interpolator ;};var (_ce =_f .New ("\u0074\u0079p\u0065\u0020\u0063h\u0065\u0063\u006b\u0020\u0065\u0072\u0072\u006f\u0072");_fa =_f .New ("\u0072\u0061\u006e\u0067\u0065\u0020\u0063\u0068\u0065\u0063\u006b\u0020e\u0072\u0072\u006f\u0072");
+OutputWidth int ;
+
+// OCVisibility is the visibility of optional content (layers) used to omit the content of hidden
+// optional content groups. If nil, the default configuration of the document for viewing is used.
+OCVisibility *_cg .OCVisibility ;
+
+// IncludeHiddenContent specifies whether to render the content of hidden optional content groups.
+IncludeHiddenContent bool ;_ca _ae .Interpolator ;};var (_ce =_f .New ("\u0074\u0079p\u0065\u0020\u0063h\u0065\u0063\u006b\u0020\u0065\u0072\u0072\u006f\u0072");_fa =_f .New ("\u0072\u0061\u006e\u0067\u0065\u0020\u0063\u0068\u0065\u0063\u006b\u0020e\u0072\u0072\u006f\u0072");
 );func _ace (_dce string ,_eaccc _eg .Image )error {_cab ,_cabb :=_a .Create (_dce );if _cabb !=nil {return _cabb ;};defer _cab .Close ();return _aa .Encode (_cab ,_eaccc );};type renderer struct{_cge float64 ;_cee map[string ]*_de .TextFont ;_ccf map[string ]*_de .TextFont ;
-_ege *_acb .Finder ;};
\ No newline at end of file
+_ege *_acb .Finder ;_ocVisibility *_cg .OCVisibility ;};
\ No newline at end of file
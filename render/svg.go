@@ -0,0 +1,1403 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+
+	"github.com/unidoc/unipdf/v4/annotator"
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/internal/license"
+	"github.com/unidoc/unipdf/v4/internal/transform"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// SVGTextMode specifies how text is written by an SVGDevice.
+type SVGTextMode int
+
+const (
+	// SVGTextModeText writes text as <text> elements. Embedded TrueType
+	// programs are included in the output when browsers can use them as is.
+	// The programs are copied without subsetting them to the glyphs used on
+	// the page, so fully embedded fonts can make the output large. Use
+	// SVGTextModePaths to keep the size proportional to the text drawn.
+	// Glyphs which cannot be represented faithfully that way are drawn as
+	// paths, with an invisible text layer on top to keep them searchable.
+	SVGTextModeText SVGTextMode = iota
+
+	// SVGTextModePaths draws all glyphs with embedded outlines as paths. An
+	// invisible text layer keeps the output searchable and selectable.
+	SVGTextModePaths
+)
+
+// svgMaxDepth limits the nesting of form XObjects and tiling patterns.
+const svgMaxDepth = 32
+
+// SVGDevice is used to convert PDF pages to standalone SVG documents.
+// Vector content is kept as SVG paths, clipping paths and axial and radial
+// shadings are preserved, and images are embedded as data URIs.
+type SVGDevice struct {
+	// TextMode specifies how text is written. Defaults to SVGTextModeText.
+	TextMode SVGTextMode
+}
+
+// NewSVGDevice returns a new SVG device.
+func NewSVGDevice() *SVGDevice {
+	const usage = "render.NewSVGDevice"
+	license.TrackUse(usage)
+	return &SVGDevice{}
+}
+
+// Render converts the specified PDF page into an SVG document, flattens
+// annotations by default and returns the result.
+//
+// NOTE: In SVGTextModeText, each usable TrueType program is embedded whole
+// in the output, once per page, even if the page only uses a few of its
+// glyphs.
+func (d *SVGDevice) Render(page *model.PdfPage) ([]byte, error) {
+	return d.RenderWithOpts(page, false)
+}
+
+// RenderToPath converts the specified PDF page into an SVG document and saves
+// the result at the specified location.
+func (d *SVGDevice) RenderToPath(page *model.PdfPage, outputPath string) error {
+	data, err := d.Render(page)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, data, 0644)
+}
+
+// RenderWithOpts converts the specified PDF page into an SVG document.
+// The visible area of the output is the crop box of the page, rotated
+// according to the Rotate entry of the page. If skipFlattening is false,
+// annotations are flattened into the page content before the conversion.
+func (d *SVGDevice) RenderWithOpts(page *model.PdfPage, skipFlattening bool) ([]byte, error) {
+	mediaBox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	mediaBox.Normalize()
+	box := *mediaBox
+	if page.CropBox != nil {
+		box = *page.CropBox
+		box.Normalize()
+	}
+	if !skipFlattening {
+		flattenAnnotations(page)
+	}
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+
+	// The page matrix maps the default user space of the page to the SVG
+	// coordinate system, where the y axis points down.
+	width, height := box.Width(), box.Height()
+	pageMatrix := transform.NewMatrix(1, 0, 0, -1, -box.Llx, height+box.Lly)
+	var rotation int64
+	if page.Rotate != nil {
+		rotation = (*page.Rotate%360 + 360) % 360
+	}
+	switch rotation {
+	case 90:
+		pageMatrix = transform.NewMatrix(0, 1, 1, 0, -box.Lly, -box.Llx)
+		width, height = height, width
+	case 180:
+		pageMatrix = transform.NewMatrix(-1, 0, 0, 1, width+box.Llx, -box.Lly)
+	case 270:
+		pageMatrix = transform.NewMatrix(0, -1, -1, 0, height+box.Lly, width+box.Llx)
+		width, height = height, width
+	}
+
+	w := newSVGWriter(d.TextMode)
+	conv := w.newConverter(box)
+	if err := conv.process(content, page.Resources); err != nil {
+		return nil, err
+	}
+	conv.finish()
+
+	var buf bytes.Buffer
+	buf.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.1" width="%spt" height="%spt" viewBox="0 0 %s %s">`+"\n",
+		svgNum(width), svgNum(height), svgNum(width), svgNum(height))
+	buf.WriteString("<defs>\n<style>\ntext{font-kerning:none;font-variant-ligatures:none}\n")
+	buf.Write(w.style.Bytes())
+	buf.WriteString("</style>\n")
+	buf.Write(w.defs.Bytes())
+	buf.WriteString("</defs>\n")
+	fmt.Fprintf(&buf, `<rect width="%s" height="%s" fill="#ffffff"/>`+"\n", svgNum(width), svgNum(height))
+	fmt.Fprintf(&buf, `<g transform="%s">`+"\n", svgMatrix(pageMatrix))
+	buf.Write(conv.body.Bytes())
+	buf.WriteString("</g>\n</svg>\n")
+	return buf.Bytes(), nil
+}
+
+// flattenAnnotations flattens the annotations supported by the render
+// devices into the content of the page.
+func flattenAnnotations(page *model.PdfPage) {
+	opts := model.FieldFlattenOpts{AnnotFilterFunc: func(annot *model.PdfAnnotation) bool {
+		switch annot.GetContext().(type) {
+		case *model.PdfAnnotationLine, *model.PdfAnnotationSquare, *model.PdfAnnotationCircle,
+			*model.PdfAnnotationPolygon, *model.PdfAnnotationPolyLine:
+			return true
+		}
+		return false
+	}}
+	if err := page.FlattenFieldsWithOpts(annotator.FieldAppearance{}, &opts); err != nil {
+		common.Log.Debug("Error during annotation flattening %v", err)
+	}
+}
+
+// svgWriter holds the output shared by all content streams of a page.
+type svgWriter struct {
+	textMode SVGTextMode
+	defs     bytes.Buffer
+	style    bytes.Buffer
+	lastID   int
+
+	fonts       map[core.PdfObject]*svgFont
+	defaultFont *svgFont
+	images      map[string]string
+	paints      map[string]string
+}
+
+func newSVGWriter(textMode SVGTextMode) *svgWriter {
+	return &svgWriter{
+		textMode: textMode,
+		fonts:    map[core.PdfObject]*svgFont{},
+		images:   map[string]string{},
+		paints:   map[string]string{},
+	}
+}
+
+// newID returns a document-wide unique element identifier.
+func (w *svgWriter) newID(prefix string) string {
+	w.lastID++
+	return prefix + strconv.Itoa(w.lastID)
+}
+
+// newConverter returns a converter for content which is visible within the
+// specified area of its coordinate system.
+func (w *svgWriter) newConverter(extent model.PdfRectangle) *svgConverter {
+	gray := model.NewPdfColorspaceDeviceGray()
+	return &svgConverter{
+		w:      w,
+		extent: extent,
+		base:   transform.IdentityMatrix(),
+		tm:     transform.IdentityMatrix(),
+		tlm:    transform.IdentityMatrix(),
+		state: svgState{
+			ctm:         transform.IdentityMatrix(),
+			fillCS:      gray,
+			strokeCS:    gray,
+			fillAlpha:   1,
+			strokeAlpha: 1,
+			lineWidth:   1,
+			miterLimit:  10,
+			hScale:      1,
+		},
+	}
+}
+
+// svgPaint is a fill or stroke paint. It is either a plain color or a named
+// pattern, which is resolved when the paint is used.
+type svgPaint struct {
+	rgb     [3]float64
+	pattern core.PdfObjectName
+	res     *model.PdfPageResources
+	base    transform.Matrix
+	under   *[3]float64
+}
+
+// svgState is the graphics state of an svgConverter.
+type svgState struct {
+	ctm                    transform.Matrix
+	fillCS, strokeCS       model.PdfColorspace
+	fill, stroke           svgPaint
+	fillAlpha, strokeAlpha float64
+	lineWidth, miterLimit  float64
+	lineCap, lineJoin      int
+	dash                   []float64
+	dashPhase              float64
+	blend                  string
+	clip                   string
+	charSpacing, wordSpace float64
+	hScale, leading, rise  float64
+	font                   *svgFont
+	fontSize               float64
+	renderMode             int
+}
+
+// svgConverter converts content streams into SVG elements. All elements are
+// expressed in the coordinate system of the content (the page or a tiling
+// pattern cell), with the CTM written as an element transform.
+type svgConverter struct {
+	w      *svgWriter
+	body   bytes.Buffer
+	extent model.PdfRectangle
+
+	state svgState
+	stack []svgState
+	floor int
+
+	// base is the CTM at the start of the current content stream. Pattern
+	// space is defined relative to it.
+	base transform.Matrix
+
+	path     strings.Builder
+	hasPath  bool
+	cx, cy   float64
+	sx, sy   float64
+	clipRule string
+	openClip string
+
+	tm, tlm   transform.Matrix
+	depth     int
+	lockColor bool
+}
+
+// process converts the content stream `content` using resources `res`.
+func (c *svgConverter) process(content string, res *model.PdfPageResources) error {
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return err
+	}
+	floor := c.floor
+	c.floor = len(c.stack)
+	proc := contentstream.NewContentStreamProcessor(*ops)
+	proc.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, _ contentstream.GraphicsState, res *model.PdfPageResources) error {
+			c.handle(op, res)
+			return nil
+		})
+	err = proc.Process(res)
+	for len(c.stack) > c.floor {
+		c.restore()
+	}
+	c.floor = floor
+	return err
+}
+
+// finish closes the clipping group left open by the converted content.
+func (c *svgConverter) finish() {
+	if c.openClip != "" {
+		c.body.WriteString("</g>\n")
+		c.openClip = ""
+	}
+}
+
+func (c *svgConverter) save() {
+	st := c.state
+	st.dash = append([]float64(nil), c.state.dash...)
+	c.stack = append(c.stack, st)
+}
+
+func (c *svgConverter) restore() {
+	if len(c.stack) <= c.floor {
+		common.Log.Debug("Unbalanced Q operator")
+		return
+	}
+	c.state = c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+}
+
+func (c *svgConverter) handle(op *contentstream.ContentStreamOperation, res *model.PdfPageResources) {
+	nums := func(n int) ([]float64, bool) {
+		if len(op.Params) < n {
+			common.Log.Debug("Too few parameters for %s: %d", op.Operand, len(op.Params))
+			return nil, false
+		}
+		vals, err := core.GetNumbersAsFloat(op.Params[:n])
+		if err != nil {
+			common.Log.Debug("Invalid parameters for %s: %v", op.Operand, err)
+			return nil, false
+		}
+		return vals, true
+	}
+	name := func() (core.PdfObjectName, bool) {
+		if len(op.Params) == 0 {
+			return "", false
+		}
+		n, ok := core.GetName(op.Params[0])
+		if !ok {
+			return "", false
+		}
+		return *n, true
+	}
+
+	st := &c.state
+	switch op.Operand {
+	// Graphics state.
+	case "q":
+		c.save()
+	case "Q":
+		c.restore()
+	case "cm":
+		if v, ok := nums(6); ok {
+			st.ctm = st.ctm.Mult(transform.NewMatrix(v[0], v[1], v[2], v[3], v[4], v[5]))
+		}
+	case "w":
+		if v, ok := nums(1); ok {
+			st.lineWidth = v[0]
+		}
+	case "J":
+		if v, ok := nums(1); ok {
+			st.lineCap = int(v[0])
+		}
+	case "j":
+		if v, ok := nums(1); ok {
+			st.lineJoin = int(v[0])
+		}
+	case "M":
+		if v, ok := nums(1); ok {
+			st.miterLimit = v[0]
+		}
+	case "d":
+		if len(op.Params) == 2 {
+			c.setDash(op.Params[0], op.Params[1])
+		}
+	case "gs":
+		if n, ok := name(); ok && res != nil {
+			c.setExtGState(n, res)
+		}
+
+	// Path construction.
+	case "m":
+		if v, ok := nums(2); ok {
+			c.moveTo(v[0], v[1])
+		}
+	case "l":
+		if v, ok := nums(2); ok {
+			c.pathOp("L", v[0], v[1])
+			c.cx, c.cy = v[0], v[1]
+		}
+	case "c":
+		if v, ok := nums(6); ok {
+			c.pathOp("C", v...)
+			c.cx, c.cy = v[4], v[5]
+		}
+	case "v":
+		if v, ok := nums(4); ok {
+			c.pathOp("C", c.cx, c.cy, v[0], v[1], v[2], v[3])
+			c.cx, c.cy = v[2], v[3]
+		}
+	case "y":
+		if v, ok := nums(4); ok {
+			c.pathOp("C", v[0], v[1], v[2], v[3], v[2], v[3])
+			c.cx, c.cy = v[2], v[3]
+		}
+	case "h":
+		c.closePath()
+	case "re":
+		if v, ok := nums(4); ok {
+			c.moveTo(v[0], v[1])
+			c.pathOp("L", v[0]+v[2], v[1])
+			c.pathOp("L", v[0]+v[2], v[1]+v[3])
+			c.pathOp("L", v[0], v[1]+v[3])
+			c.closePath()
+		}
+
+	// Path painting and clipping.
+	case "S":
+		c.paintPath(false, true, "")
+	case "s":
+		c.closePath()
+		c.paintPath(false, true, "")
+	case "f", "F":
+		c.paintPath(true, false, "nonzero")
+	case "f*":
+		c.paintPath(true, false, "evenodd")
+	case "B":
+		c.paintPath(true, true, "nonzero")
+	case "B*":
+		c.paintPath(true, true, "evenodd")
+	case "b":
+		c.closePath()
+		c.paintPath(true, true, "nonzero")
+	case "b*":
+		c.closePath()
+		c.paintPath(true, true, "evenodd")
+	case "n":
+		c.paintPath(false, false, "")
+	case "W":
+		c.clipRule = "nonzero"
+	case "W*":
+		c.clipRule = "evenodd"
+
+	// Colors.
+	case "CS", "cs":
+		if c.lockColor {
+			break
+		}
+		n, ok := name()
+		if !ok {
+			break
+		}
+		cs, ok := svgColorspace(n, res)
+		if !ok {
+			common.Log.Debug("Unknown colorspace: %s", n)
+			break
+		}
+		paint := svgPaint{rgb: svgInitialColor(cs)}
+		if op.Operand == "CS" {
+			st.strokeCS, st.stroke = cs, paint
+		} else {
+			st.fillCS, st.fill = cs, paint
+		}
+	case "SC", "SCN":
+		if !c.lockColor {
+			c.setColor(&st.stroke, st.strokeCS, op.Params, res)
+		}
+	case "sc", "scn":
+		if !c.lockColor {
+			c.setColor(&st.fill, st.fillCS, op.Params, res)
+		}
+	case "G", "g", "RG", "rg", "K", "k":
+		if c.lockColor {
+			break
+		}
+		var cs model.PdfColorspace
+		switch op.Operand {
+		case "G", "g":
+			cs = model.NewPdfColorspaceDeviceGray()
+		case "RG", "rg":
+			cs = model.NewPdfColorspaceDeviceRGB()
+		default:
+			cs = model.NewPdfColorspaceDeviceCMYK()
+		}
+		if op.Operand == strings.ToUpper(op.Operand) {
+			st.strokeCS = cs
+			c.setColor(&st.stroke, cs, op.Params, res)
+		} else {
+			st.fillCS = cs
+			c.setColor(&st.fill, cs, op.Params, res)
+		}
+
+	// Shadings, images and forms.
+	case "sh":
+		if n, ok := name(); ok && res != nil {
+			c.paintShading(n, res)
+		}
+	case "Do":
+		if n, ok := name(); ok && res != nil {
+			stream, xtype := res.GetXObjectByName(n)
+			switch xtype {
+			case model.XObjectTypeImage:
+				c.drawImage(n, stream, res)
+			case model.XObjectTypeForm:
+				c.drawForm(n, res)
+			}
+		}
+	case "BI":
+		if len(op.Params) == 1 {
+			if img, ok := op.Params[0].(*contentstream.ContentStreamInlineImage); ok {
+				c.drawInlineImage(img, res)
+			}
+		}
+
+	// Text.
+	case "BT":
+		c.tm = transform.IdentityMatrix()
+		c.tlm = transform.IdentityMatrix()
+	case "Tc":
+		if v, ok := nums(1); ok {
+			st.charSpacing = v[0]
+		}
+	case "Tw":
+		if v, ok := nums(1); ok {
+			st.wordSpace = v[0]
+		}
+	case "Tz":
+		if v, ok := nums(1); ok {
+			st.hScale = v[0] / 100
+		}
+	case "TL":
+		if v, ok := nums(1); ok {
+			st.leading = v[0]
+		}
+	case "Ts":
+		if v, ok := nums(1); ok {
+			st.rise = v[0]
+		}
+	case "Tr":
+		if v, ok := nums(1); ok {
+			st.renderMode = int(v[0])
+		}
+	case "Tf":
+		if len(op.Params) != 2 {
+			break
+		}
+		if n, ok := name(); ok && res != nil {
+			if obj, ok := res.GetFontByName(n); ok {
+				st.font = c.w.font(obj)
+			}
+		}
+		if size, err := core.GetNumberAsFloat(op.Params[1]); err == nil {
+			st.fontSize = size
+		}
+	case "Td":
+		if v, ok := nums(2); ok {
+			c.moveText(v[0], v[1])
+		}
+	case "TD":
+		if v, ok := nums(2); ok {
+			st.leading = -v[1]
+			c.moveText(v[0], v[1])
+		}
+	case "Tm":
+		if v, ok := nums(6); ok {
+			c.tlm = transform.NewMatrix(v[0], v[1], v[2], v[3], v[4], v[5])
+			c.tm = c.tlm
+		}
+	case "T*":
+		c.moveText(0, -st.leading)
+	case "Tj":
+		if len(op.Params) == 1 {
+			c.showText(op.Params)
+		}
+	case "'":
+		if len(op.Params) == 1 {
+			c.moveText(0, -st.leading)
+			c.showText(op.Params)
+		}
+	case "\"":
+		if v, ok := nums(2); ok && len(op.Params) == 3 {
+			st.wordSpace, st.charSpacing = v[0], v[1]
+			c.moveText(0, -st.leading)
+			c.showText(op.Params[2:])
+		}
+	case "TJ":
+		if len(op.Params) == 1 {
+			if arr, ok := core.GetArray(op.Params[0]); ok {
+				c.showText(arr.Elements())
+			}
+		}
+	}
+}
+
+func (c *svgConverter) setDash(arrObj, phaseObj core.PdfObject) {
+	c.state.dash = nil
+	c.state.dashPhase = 0
+	if arr, ok := core.GetArray(arrObj); ok {
+		if vals, err := core.GetNumbersAsFloat(arr.Elements()); err == nil {
+			c.state.dash = vals
+		}
+	}
+	if phase, err := core.GetNumberAsFloat(phaseObj); err == nil {
+		c.state.dashPhase = phase
+	}
+}
+
+func (c *svgConverter) setExtGState(name core.PdfObjectName, res *model.PdfPageResources) {
+	obj, ok := res.GetExtGState(name)
+	if !ok {
+		return
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	st := &c.state
+	for _, key := range dict.Keys() {
+		val := dict.Get(key)
+		switch key {
+		case "LW":
+			if v, err := core.GetNumberAsFloat(val); err == nil {
+				st.lineWidth = v
+			}
+		case "LC":
+			if v, ok := core.GetIntVal(val); ok {
+				st.lineCap = v
+			}
+		case "LJ":
+			if v, ok := core.GetIntVal(val); ok {
+				st.lineJoin = v
+			}
+		case "ML":
+			if v, err := core.GetNumberAsFloat(val); err == nil {
+				st.miterLimit = v
+			}
+		case "D":
+			if arr, ok := core.GetArray(val); ok && arr.Len() == 2 {
+				c.setDash(arr.Get(0), arr.Get(1))
+			}
+		case "CA":
+			if v, err := core.GetNumberAsFloat(val); err == nil {
+				st.strokeAlpha = v
+			}
+		case "ca":
+			if v, err := core.GetNumberAsFloat(val); err == nil {
+				st.fillAlpha = v
+			}
+		case "BM":
+			if arr, ok := core.GetArray(val); ok && arr.Len() > 0 {
+				val = arr.Get(0)
+			}
+			if v, ok := core.GetNameVal(val); ok {
+				st.blend = svgBlendMode(v)
+			}
+		case "Font":
+			if arr, ok := core.GetArray(val); ok && arr.Len() == 2 {
+				st.font = c.w.font(arr.Get(0))
+				if size, err := core.GetNumberAsFloat(arr.Get(1)); err == nil {
+					st.fontSize = size
+				}
+			}
+		}
+	}
+}
+
+// svgBlendMode returns the CSS mix-blend-mode value for the PDF blend mode
+// `name`, or an empty string for the normal blend mode.
+func svgBlendMode(name string) string {
+	switch name {
+	case "Normal", "Compatible", "":
+		return ""
+	}
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (c *svgConverter) moveTo(x, y float64) {
+	c.pathOp("M", x, y)
+	c.cx, c.cy = x, y
+	c.sx, c.sy = x, y
+}
+
+func (c *svgConverter) pathOp(cmd string, vals ...float64) {
+	if c.path.Len() > 0 {
+		c.path.WriteByte(' ')
+	}
+	c.path.WriteString(cmd)
+	for _, v := range vals {
+		c.path.WriteByte(' ')
+		c.path.WriteString(svgNum(v))
+	}
+	c.hasPath = true
+}
+
+func (c *svgConverter) closePath() {
+	if !c.hasPath {
+		return
+	}
+	c.path.WriteString(" Z")
+	c.cx, c.cy = c.sx, c.sy
+}
+
+// paintPath paints the current path, applies a pending clip and starts a
+// new path.
+func (c *svgConverter) paintPath(fill, stroke bool, rule string) {
+	d := c.path.String()
+	c.path.Reset()
+	clipRule := c.clipRule
+	c.clipRule = ""
+	if !c.hasPath {
+		return
+	}
+	c.hasPath = false
+	if fill || stroke {
+		m := c.state.ctm
+		c.emit(fmt.Sprintf(`<path d="%s" transform="%s"%s/>`, d, svgMatrix(m), c.paintAttrs(m, fill, stroke, rule, 1)))
+	}
+	if clipRule != "" {
+		c.addClip(d, clipRule, c.state.ctm)
+	}
+}
+
+// paintAttrs returns the presentation attributes for an element with matrix
+// `m`. The stroke width is divided by `scale`, which accounts for scaling
+// between the CTM and the user space of the element.
+func (c *svgConverter) paintAttrs(m transform.Matrix, fill, stroke bool, rule string, scale float64) string {
+	st := &c.state
+	var b strings.Builder
+	if fill {
+		fmt.Fprintf(&b, ` fill="%s"`, c.paintValue(st.fill, m))
+		if rule == "evenodd" {
+			b.WriteString(` fill-rule="evenodd"`)
+		}
+		if st.fillAlpha < 1 {
+			fmt.Fprintf(&b, ` fill-opacity="%s"`, svgNum(st.fillAlpha))
+		}
+	} else {
+		b.WriteString(` fill="none"`)
+	}
+	if stroke {
+		fmt.Fprintf(&b, ` stroke="%s"`, c.paintValue(st.stroke, m))
+		if st.lineWidth <= 0 {
+			b.WriteString(` stroke-width="1" vector-effect="non-scaling-stroke"`)
+		} else {
+			fmt.Fprintf(&b, ` stroke-width="%s"`, svgNum(st.lineWidth/scale))
+		}
+		switch st.lineCap {
+		case 1:
+			b.WriteString(` stroke-linecap="round"`)
+		case 2:
+			b.WriteString(` stroke-linecap="square"`)
+		}
+		switch st.lineJoin {
+		case 1:
+			b.WriteString(` stroke-linejoin="round"`)
+		case 2:
+			b.WriteString(` stroke-linejoin="bevel"`)
+		default:
+			if st.miterLimit >= 1 {
+				fmt.Fprintf(&b, ` stroke-miterlimit="%s"`, svgNum(st.miterLimit))
+			}
+		}
+		var dashSum float64
+		for _, v := range st.dash {
+			dashSum += math.Abs(v)
+		}
+		if dashSum > 0 {
+			dash := make([]string, len(st.dash))
+			for i, v := range st.dash {
+				dash[i] = svgNum(math.Abs(v) / scale)
+			}
+			fmt.Fprintf(&b, ` stroke-dasharray="%s"`, strings.Join(dash, " "))
+			if st.dashPhase != 0 {
+				fmt.Fprintf(&b, ` stroke-dashoffset="%s"`, svgNum(st.dashPhase/scale))
+			}
+		}
+		if st.strokeAlpha < 1 {
+			fmt.Fprintf(&b, ` stroke-opacity="%s"`, svgNum(st.strokeAlpha))
+		}
+	}
+	if st.blend != "" {
+		fmt.Fprintf(&b, ` style="mix-blend-mode:%s"`, st.blend)
+	}
+	return b.String()
+}
+
+// emit writes a painted element to the body, opening the clipping group of
+// the current clipping path first.
+func (c *svgConverter) emit(element string) {
+	if c.openClip != c.state.clip {
+		c.finish()
+		if c.state.clip != "" {
+			fmt.Fprintf(&c.body, `<g clip-path="url(#%s)">`+"\n", c.state.clip)
+			c.openClip = c.state.clip
+		}
+	}
+	c.body.WriteString(element)
+	c.body.WriteByte('\n')
+}
+
+// addClip intersects the current clipping path with path `d` whose
+// coordinates are transformed by `m`. Intersections are built by chaining
+// the clipPath elements.
+func (c *svgConverter) addClip(d, rule string, m transform.Matrix) {
+	id := c.w.newID("clip")
+	var parent string
+	if c.state.clip != "" {
+		parent = fmt.Sprintf(` clip-path="url(#%s)"`, c.state.clip)
+	}
+	fmt.Fprintf(&c.w.defs, `<clipPath id="%s" clipPathUnits="userSpaceOnUse"%s><path d="%s" transform="%s" clip-rule="%s"/></clipPath>`+"\n",
+		id, parent, d, svgMatrix(m), rule)
+	c.state.clip = id
+}
+
+// setColor sets `paint` from the color operands `params` in colorspace `cs`.
+func (c *svgConverter) setColor(paint *svgPaint, cs model.PdfColorspace, params []core.PdfObject, res *model.PdfPageResources) {
+	if pcs, ok := cs.(*model.PdfColorspaceSpecialPattern); ok {
+		if len(params) == 0 {
+			return
+		}
+		name, ok := core.GetName(params[len(params)-1])
+		if !ok {
+			return
+		}
+		p := svgPaint{pattern: *name, res: res, base: c.base}
+		if pcs.UnderlyingCS != nil && len(params) > 1 {
+			if vals, err := core.GetNumbersAsFloat(params[:len(params)-1]); err == nil {
+				if rgb, ok := svgColorRGB(pcs.UnderlyingCS, vals); ok {
+					p.under = &rgb
+					p.rgb = rgb
+				}
+			}
+		}
+		*paint = p
+		return
+	}
+	vals, err := core.GetNumbersAsFloat(params)
+	if err != nil {
+		common.Log.Debug("Invalid color operands: %v", err)
+		return
+	}
+	rgb, ok := svgColorRGB(cs, vals)
+	if !ok {
+		common.Log.Debug("Error converting color: %v", vals)
+		return
+	}
+	*paint = svgPaint{rgb: rgb}
+}
+
+// paintValue returns the value of a fill or stroke attribute for an element
+// with matrix `m`.
+func (c *svgConverter) paintValue(p svgPaint, m transform.Matrix) string {
+	if p.pattern == "" || p.res == nil {
+		return svgHex(p.rgb)
+	}
+	inv, ok := m.Inverse()
+	if !ok {
+		return svgHex(p.rgb)
+	}
+	pattern, ok := p.res.GetPatternByName(p.pattern)
+	if !ok {
+		common.Log.Debug("Pattern not found: %s", p.pattern)
+		return svgHex(p.rgb)
+	}
+	var patternMatrix *core.PdfObjectArray
+	switch {
+	case pattern.IsShading():
+		patternMatrix = pattern.GetAsShadingPattern().Matrix
+	case pattern.IsTiling():
+		patternMatrix = pattern.GetAsTilingPattern().Matrix
+	}
+	g := inv.Mult(p.base.Mult(svgArrayMatrix(patternMatrix)))
+
+	key := fmt.Sprintf("%p/%s/%s/%v", p.res, p.pattern, svgMatrix(g), p.rgb)
+	if id, ok := c.w.paints[key]; ok {
+		return "url(#" + id + ")"
+	}
+	var id string
+	switch {
+	case pattern.IsShading():
+		id = c.w.gradient(pattern.GetAsShadingPattern().Shading, g)
+	case pattern.IsTiling():
+		id = c.tilingPattern(pattern.GetAsTilingPattern(), p.res, g, p.under)
+	}
+	if id == "" {
+		return svgHex(p.rgb)
+	}
+	c.w.paints[key] = id
+	return "url(#" + id + ")"
+}
+
+// tilingPattern writes a pattern element for tiling pattern `tp`, whose
+// pattern space is mapped by `g` to the user space of the painted element.
+func (c *svgConverter) tilingPattern(tp *model.PdfTilingPattern, res *model.PdfPageResources, g transform.Matrix, under *[3]float64) string {
+	if c.depth >= svgMaxDepth || tp.BBox == nil {
+		return ""
+	}
+	content, err := tp.GetContentStream()
+	if err != nil {
+		common.Log.Debug("Error reading tiling pattern content: %v", err)
+		return ""
+	}
+	bbox := *tp.BBox
+	bbox.Normalize()
+	xstep, ystep := bbox.Width(), bbox.Height()
+	if tp.XStep != nil && *tp.XStep != 0 {
+		xstep = math.Abs(float64(*tp.XStep))
+	}
+	if tp.YStep != nil && *tp.YStep != 0 {
+		ystep = math.Abs(float64(*tp.YStep))
+	}
+	if xstep == 0 || ystep == 0 {
+		return ""
+	}
+
+	child := c.w.newConverter(bbox)
+	child.depth = c.depth + 1
+	if tp.PaintType != nil && *tp.PaintType == 2 {
+		// Uncolored patterns are painted with the color given along with
+		// the pattern name, ignoring the colors set by the pattern cell.
+		if under != nil {
+			child.state.fill = svgPaint{rgb: *under}
+			child.state.stroke = svgPaint{rgb: *under}
+		}
+		child.lockColor = true
+	}
+	resources := tp.Resources
+	if resources == nil {
+		resources = res
+	}
+	if err := child.process(string(content), resources); err != nil {
+		common.Log.Debug("Error rendering tiling pattern: %v", err)
+	}
+	child.finish()
+
+	id := c.w.newID("pattern")
+	fmt.Fprintf(&c.w.defs, `<pattern id="%s" patternUnits="userSpaceOnUse" x="%s" y="%s" width="%s" height="%s" patternTransform="%s">`+"\n",
+		id, svgNum(bbox.Llx), svgNum(bbox.Lly), svgNum(xstep), svgNum(ystep), svgMatrix(g))
+	c.w.defs.Write(child.body.Bytes())
+	c.w.defs.WriteString("</pattern>\n")
+	return id
+}
+
+// paintShading fills the current clipping region with a shading.
+func (c *svgConverter) paintShading(name core.PdfObjectName, res *model.PdfPageResources) {
+	shading, ok := res.GetShadingByName(name)
+	if !ok {
+		common.Log.Debug("Shading not found: %s", name)
+		return
+	}
+	m := c.state.ctm
+	inv, ok := m.Inverse()
+	if !ok {
+		return
+	}
+	var d string
+	if shading.BBox != nil {
+		bbox := *shading.BBox
+		bbox.Normalize()
+		d = svgRectPath(bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury)
+	} else {
+		// Cover the visible extent of the content, mapped to user space.
+		e := c.extent
+		minX, minY := math.Inf(1), math.Inf(1)
+		maxX, maxY := math.Inf(-1), math.Inf(-1)
+		for _, p := range [][2]float64{{e.Llx, e.Lly}, {e.Urx, e.Lly}, {e.Urx, e.Ury}, {e.Llx, e.Ury}} {
+			x, y := inv.Transform(p[0], p[1])
+			minX, minY = math.Min(minX, x), math.Min(minY, y)
+			maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+		}
+		d = svgRectPath(minX, minY, maxX, maxY)
+	}
+	id := c.w.gradient(shading, transform.IdentityMatrix())
+	if id == "" {
+		return
+	}
+	var opacity string
+	if c.state.fillAlpha < 1 {
+		opacity = fmt.Sprintf(` fill-opacity="%s"`, svgNum(c.state.fillAlpha))
+	}
+	c.emit(fmt.Sprintf(`<path d="%s" transform="%s" fill="url(#%s)"%s/>`, d, svgMatrix(m), id, opacity))
+}
+
+// gradient writes a gradient element for an axial or radial shading and
+// returns its identifier. Other shading types are not supported and yield
+// an empty identifier.
+func (w *svgWriter) gradient(shading *model.PdfShading, g transform.Matrix) string {
+	if shading == nil {
+		return ""
+	}
+	var transformAttr string
+	if !g.Identity() {
+		transformAttr = fmt.Sprintf(` gradientTransform="%s"`, svgMatrix(g))
+	}
+	id := w.newID("grad")
+	switch sh := shading.GetContext().(type) {
+	case *model.PdfShadingType2:
+		coords := svgArrayNumbers(sh.Coords)
+		if len(coords) != 4 || len(sh.Function) == 0 {
+			return ""
+		}
+		t0, t1 := svgDomain(sh.Domain)
+		fmt.Fprintf(&w.defs, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s"%s>`+"\n%s</linearGradient>\n",
+			id, svgNum(coords[0]), svgNum(coords[1]), svgNum(coords[2]), svgNum(coords[3]), transformAttr,
+			svgGradientStops(sh.ColorSpace, sh.Function, t0, t1, false))
+	case *model.PdfShadingType3:
+		coords := svgArrayNumbers(sh.Coords)
+		if len(coords) != 6 || len(sh.Function) == 0 {
+			return ""
+		}
+		t0, t1 := svgDomain(sh.Domain)
+		// SVG interpolates from the focal circle to the end circle, which
+		// must be the larger one.
+		reverse := coords[2] > coords[5]
+		if reverse {
+			coords = []float64{coords[3], coords[4], coords[5], coords[0], coords[1], coords[2]}
+		}
+		fmt.Fprintf(&w.defs, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s" fx="%s" fy="%s" fr="%s"%s>`+"\n%s</radialGradient>\n",
+			id, svgNum(coords[3]), svgNum(coords[4]), svgNum(coords[5]), svgNum(coords[0]), svgNum(coords[1]), svgNum(coords[2]),
+			transformAttr, svgGradientStops(sh.ColorSpace, sh.Function, t0, t1, reverse))
+	default:
+		common.Log.Debug("Shading type %v not supported by the SVG device", shading.ShadingType)
+		return ""
+	}
+	return id
+}
+
+// svgGradientStops samples the shading functions `fns` over [t0, t1] and
+// returns the corresponding stop elements.
+func svgGradientStops(cs model.PdfColorspace, fns []model.PdfFunction, t0, t1 float64, reverse bool) string {
+	var ts []float64
+	if len(fns) == 1 {
+		switch fn := fns[0].(type) {
+		case *model.PdfFunctionType2:
+			if fn.N == 1 {
+				ts = []float64{t0, t1}
+			}
+		case *model.PdfFunctionType3:
+			linear := true
+			for _, sub := range fn.Functions {
+				if f2, ok := sub.(*model.PdfFunctionType2); !ok || f2.N != 1 {
+					linear = false
+				}
+			}
+			if linear {
+				ts = append(ts, t0)
+				for _, b := range fn.Bounds {
+					ts = append(ts, b-1e-6*(t1-t0), b)
+				}
+				ts = append(ts, t1)
+			}
+		}
+	}
+	if ts == nil {
+		const samples = 32
+		for i := 0; i <= samples; i++ {
+			ts = append(ts, t0+(t1-t0)*float64(i)/samples)
+		}
+	}
+
+	var stops []string
+	for _, t := range ts {
+		var vals []float64
+		for _, fn := range fns {
+			out, err := fn.Evaluate([]float64{t})
+			if err != nil {
+				common.Log.Debug("Error evaluating shading function: %v", err)
+				return ""
+			}
+			vals = append(vals, out...)
+		}
+		rgb, _ := svgColorRGB(cs, vals)
+		offset := 0.0
+		if t1 != t0 {
+			offset = math.Max(0, math.Min(1, (t-t0)/(t1-t0)))
+		}
+		if reverse {
+			offset = 1 - offset
+		}
+		stops = append(stops, fmt.Sprintf(`<stop offset="%s" stop-color="%s"/>`, svgNum(offset), svgHex(rgb)))
+	}
+	if reverse {
+		for i, j := 0, len(stops)-1; i < j; i, j = i+1, j-1 {
+			stops[i], stops[j] = stops[j], stops[i]
+		}
+	}
+	return strings.Join(stops, "\n") + "\n"
+}
+
+// drawForm draws the form XObject `name`.
+func (c *svgConverter) drawForm(name core.PdfObjectName, res *model.PdfPageResources) {
+	if c.depth >= svgMaxDepth {
+		common.Log.Debug("Form XObjects nested too deeply: %s", name)
+		return
+	}
+	xform, err := res.GetXObjectFormByName(name)
+	if err != nil {
+		common.Log.Debug("Error loading form XObject %s: %v", name, err)
+		return
+	}
+	content, err := xform.GetContentStream()
+	if err != nil {
+		common.Log.Debug("Error reading form XObject %s: %v", name, err)
+		return
+	}
+	formRes := xform.Resources
+	if formRes == nil {
+		formRes = res
+	}
+
+	c.save()
+	if arr, ok := core.GetArray(xform.Matrix); ok {
+		c.state.ctm = c.state.ctm.Mult(svgArrayMatrix(arr))
+	}
+	if bbox := svgArrayNumbers(xform.BBox); len(bbox) == 4 {
+		c.addClip(svgRectPath(bbox[0], bbox[1], bbox[2], bbox[3]), "nonzero", c.state.ctm)
+	}
+	base, tm, tlm := c.base, c.tm, c.tlm
+	c.base = c.state.ctm
+	c.depth++
+	if err := c.process(string(content), formRes); err != nil {
+		common.Log.Debug("Error rendering form XObject %s: %v", name, err)
+	}
+	c.depth--
+	c.base, c.tm, c.tlm = base, tm, tlm
+	c.restore()
+}
+
+// drawImage draws the image XObject `name`. Image data is written once per
+// page and referenced by every placement.
+func (c *svgConverter) drawImage(name core.PdfObjectName, stream *core.PdfObjectStream, res *model.PdfPageResources) {
+	ximg, err := res.GetXObjectImageByName(name)
+	if err != nil || ximg == nil {
+		common.Log.Debug("Error loading image XObject %s: %v", name, err)
+		return
+	}
+	isMask, _ := core.GetBoolVal(ximg.ImageMask)
+	key := fmt.Sprintf("%p", stream)
+	if isMask {
+		key += svgHex(c.state.fill.rgb)
+	}
+	id, ok := c.w.images[key]
+	if !ok {
+		href, err := c.imageHref(stream, ximg, isMask)
+		if err != nil {
+			common.Log.Debug("Error converting image %s: %v", name, err)
+			return
+		}
+		id = c.w.imageDef(href)
+		c.w.images[key] = id
+	}
+	c.placeImage(id)
+}
+
+func (c *svgConverter) drawInlineImage(iimg *contentstream.ContentStreamInlineImage, res *model.PdfPageResources) {
+	img, err := iimg.ToImage(res)
+	if err != nil {
+		common.Log.Debug("Error converting inline image: %v", err)
+		return
+	}
+	var goImg image.Image
+	if isMask, _ := iimg.IsMask(); isMask {
+		goImg = _daga(img, c.fillColor())
+	} else {
+		if cs, err := iimg.GetColorSpace(res); err == nil {
+			if _, ok := cs.(*model.PdfColorspaceSpecialIndexed); ok {
+				if rgbImg, err := cs.ImageToRGB(*img); err == nil {
+					img = &rgbImg
+				}
+			}
+		}
+		if goImg, err = img.ToGoImage(); err != nil {
+			common.Log.Debug("Error converting inline image: %v", err)
+			return
+		}
+	}
+	href, err := svgPNGHref(goImg)
+	if err != nil {
+		common.Log.Debug("Error encoding inline image: %v", err)
+		return
+	}
+	c.placeImage(c.w.imageDef(href))
+}
+
+// imageDef writes an image element filling the unit square and returns its
+// identifier.
+func (w *svgWriter) imageDef(href string) string {
+	id := w.newID("img")
+	fmt.Fprintf(&w.defs, `<image id="%s" width="1" height="1" preserveAspectRatio="none" xlink:href="%s"/>`+"\n", id, href)
+	return id
+}
+
+// placeImage places the image definition `id` in the unit square of the
+// current user space. The first row of the image is at the top.
+func (c *svgConverter) placeImage(id string) {
+	m := c.state.ctm.Mult(transform.NewMatrix(1, 0, 0, -1, 0, 1))
+	var opacity string
+	if c.state.fillAlpha < 1 {
+		opacity = fmt.Sprintf(` opacity="%s"`, svgNum(c.state.fillAlpha))
+	}
+	c.emit(fmt.Sprintf(`<use xlink:href="#%s" transform="%s"%s/>`, id, svgMatrix(m), opacity))
+}
+
+// imageHref returns a data URI for an image XObject. Baseline JPEG images
+// which need no further processing are embedded as is, everything else is
+// converted to PNG with masks applied as the alpha channel.
+func (c *svgConverter) imageHref(stream *core.PdfObjectStream, ximg *model.XObjectImage, isMask bool) (string, error) {
+	if !isMask && ximg.Mask == nil && ximg.SMask == nil && ximg.Decode == nil && svgIsDCT(stream) {
+		switch ximg.ColorSpace.(type) {
+		case *model.PdfColorspaceDeviceRGB, *model.PdfColorspaceDeviceGray:
+			return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(stream.Stream), nil
+		}
+	}
+	img, err := ximg.ToImage()
+	if err != nil {
+		return "", err
+	}
+	if _, ok := ximg.ColorSpace.(*model.PdfColorspaceSpecialIndexed); ok {
+		if rgbImg, err := ximg.ColorSpace.ImageToRGB(*img); err == nil {
+			img = &rgbImg
+		}
+	}
+	fill := c.fillColor()
+	var mask image.Image
+	if ximg.Mask != nil {
+		if mask, err = _caab(ximg.Mask, fill); err != nil {
+			common.Log.Debug("Could not get explicit image mask: %v", err)
+			mask = nil
+		}
+	} else if ximg.SMask != nil {
+		if mask, err = _cbbe(ximg.SMask); err != nil {
+			common.Log.Debug("Could not get soft image mask: %v", err)
+			mask = nil
+		}
+	}
+	var goImg image.Image
+	if isMask {
+		goImg = _daga(img, fill)
+	} else if goImg, err = img.ToGoImage(); err != nil {
+		return "", err
+	}
+	if mask != nil {
+		goImg = _eebc(goImg, mask, xdraw.BiLinear)
+	}
+	return svgPNGHref(goImg)
+}
+
+func (c *svgConverter) fillColor() color.Color {
+	rgb := c.state.fill.rgb
+	return color.RGBA{R: svgByte(rgb[0]), G: svgByte(rgb[1]), B: svgByte(rgb[2]), A: 255}
+}
+
+// svgIsDCT returns true if the only filter of `stream` is DCTDecode.
+func svgIsDCT(stream *core.PdfObjectStream) bool {
+	filter := stream.PdfObjectDictionary.Get("Filter")
+	if arr, ok := core.GetArray(filter); ok {
+		if arr.Len() != 1 {
+			return false
+		}
+		filter = arr.Get(0)
+	}
+	name, ok := core.GetNameVal(filter)
+	return ok && name == core.StreamEncodingFilterNameDCT
+}
+
+func svgPNGHref(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// svgColorspace returns the colorspace `name`, which is either a device
+// colorspace or a colorspace resource.
+func svgColorspace(name core.PdfObjectName, res *model.PdfPageResources) (model.PdfColorspace, bool) {
+	switch name {
+	case "DeviceGray", "G":
+		return model.NewPdfColorspaceDeviceGray(), true
+	case "DeviceRGB", "RGB":
+		return model.NewPdfColorspaceDeviceRGB(), true
+	case "DeviceCMYK", "CMYK":
+		return model.NewPdfColorspaceDeviceCMYK(), true
+	case "Pattern":
+		return model.NewPdfColorspaceSpecialPattern(), true
+	}
+	if res == nil {
+		return nil, false
+	}
+	return res.GetColorspaceByName(name)
+}
+
+// svgInitialColor returns the initial color of colorspace `cs`.
+func svgInitialColor(cs model.PdfColorspace) [3]float64 {
+	if _, ok := cs.(*model.PdfColorspaceSpecialPattern); ok {
+		return [3]float64{}
+	}
+	vals := make([]float64, cs.GetNumComponents())
+	switch cs.(type) {
+	case *model.PdfColorspaceDeviceCMYK:
+		vals[3] = 1
+	case *model.PdfColorspaceSpecialSeparation, *model.PdfColorspaceDeviceN:
+		for i := range vals {
+			vals[i] = 1
+		}
+	}
+	rgb, _ := svgColorRGB(cs, vals)
+	return rgb
+}
+
+// svgColorRGB converts the color components `vals` in colorspace `cs` to RGB.
+func svgColorRGB(cs model.PdfColorspace, vals []float64) ([3]float64, bool) {
+	if cs == nil {
+		return [3]float64{}, false
+	}
+	col, err := cs.ColorFromFloats(vals)
+	if err != nil {
+		return [3]float64{}, false
+	}
+	rgbCol, err := cs.ColorToRGB(col)
+	if err != nil {
+		return [3]float64{}, false
+	}
+	rgb, ok := rgbCol.(*model.PdfColorDeviceRGB)
+	if !ok {
+		return [3]float64{}, false
+	}
+	return [3]float64{rgb.R(), rgb.G(), rgb.B()}, true
+}
+
+func svgByte(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+func svgHex(rgb [3]float64) string {
+	return fmt.Sprintf("#%02x%02x%02x", svgByte(rgb[0]), svgByte(rgb[1]), svgByte(rgb[2]))
+}
+
+// svgNum formats a number with six significant digits.
+func svgNum(v float64) string {
+	s := strconv.FormatFloat(v, 'g', 6, 64)
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func svgMatrix(m transform.Matrix) string {
+	return fmt.Sprintf("matrix(%s %s %s %s %s %s)", svgNum(m[0]), svgNum(m[1]), svgNum(m[3]), svgNum(m[4]), svgNum(m[6]), svgNum(m[7]))
+}
+
+func svgRectPath(x0, y0, x1, y1 float64) string {
+	return fmt.Sprintf("M %s %s L %s %s L %s %s L %s %s Z",
+		svgNum(x0), svgNum(y0), svgNum(x1), svgNum(y0), svgNum(x1), svgNum(y1), svgNum(x0), svgNum(y1))
+}
+
+func svgArrayNumbers(obj core.PdfObject) []float64 {
+	arr, ok := core.GetArray(obj)
+	if !ok || arr == nil {
+		return nil
+	}
+	vals, err := core.GetNumbersAsFloat(arr.Elements())
+	if err != nil {
+		return nil
+	}
+	return vals
+}
+
+// svgArrayMatrix returns the matrix stored in `arr`, or the identity matrix.
+func svgArrayMatrix(arr *core.PdfObjectArray) transform.Matrix {
+	if arr == nil {
+		return transform.IdentityMatrix()
+	}
+	vals := svgArrayNumbers(arr)
+	if len(vals) != 6 {
+		return transform.IdentityMatrix()
+	}
+	return transform.NewMatrix(vals[0], vals[1], vals[2], vals[3], vals[4], vals[5])
+}
+
+func svgDomain(arr *core.PdfObjectArray) (float64, float64) {
+	if arr == nil {
+		return 0, 1
+	}
+	if vals := svgArrayNumbers(arr); len(vals) == 2 {
+		return vals[0], vals[1]
+	}
+	return 0, 1
+}
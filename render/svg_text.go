@@ -0,0 +1,525 @@
+//
+// Copyright 2020 FoxyUtils ehf. All rights reserved.
+//
+// This is a commercial product and requires a license to operate.
+// A trial license can be obtained at https://unidoc.io
+//
+// Use of this source code is governed by the UniDoc End User License Agreement
+// terms that can be accessed at https://unidoc.io/eula/
+
+package render
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/unidoc/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/unidoc/unipdf/v4/common"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/internal/textencoding"
+	"github.com/unidoc/unipdf/v4/internal/transform"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// svgFont is a PDF font prepared for SVG output.
+type svgFont struct {
+	font *model.PdfFont
+
+	// family is the CSS font family used by <text> elements. It refers to
+	// the embedded font program if `embedded` is set.
+	family   string
+	embedded bool
+
+	// generic is the font family used when the font program cannot be
+	// used, along with the weight and style deduced from the font name.
+	generic       string
+	weight, style string
+
+	// ttf is the TrueType font program of the font, if any, which provides
+	// glyph outlines.
+	ttf      *truetype.Font
+	identity bool
+	cidToGID []byte
+	glyphs   map[truetype.Index]string
+}
+
+// font returns the svgFont for the font object `obj`.
+func (w *svgWriter) font(obj core.PdfObject) *svgFont {
+	if f, ok := w.fonts[obj]; ok {
+		return f
+	}
+	pdfFont, err := model.NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		common.Log.Debug("Error loading font: %v", err)
+		w.fonts[obj] = nil
+		return nil
+	}
+	f := w.newFont(pdfFont)
+	if dict, ok := core.GetDict(obj); ok {
+		encoding, _ := core.GetNameVal(dict.Get("Encoding"))
+		f.identity = strings.HasPrefix(encoding, "Identity-")
+	}
+	w.fonts[obj] = f
+	return f
+}
+
+func (w *svgWriter) newFont(pdfFont *model.PdfFont) *svgFont {
+	f := &svgFont{font: pdfFont, glyphs: map[truetype.Index]string{}}
+	f.generic, f.weight, f.style = svgGenericFamily(pdfFont.BaseFont())
+	f.family = f.generic
+
+	data := svgFontProgram(pdfFont)
+	if data == nil {
+		return f
+	}
+	ttf, err := truetype.Parse(data)
+	if err != nil {
+		common.Log.Debug("Error parsing font program of %s: %v", pdfFont.BaseFont(), err)
+		return f
+	}
+	f.ttf = ttf
+	if pdfFont.IsCID() {
+		if stream, ok := core.GetStream(pdfFont.GetCIDToGIDMapObject()); ok {
+			if f.cidToGID, err = core.DecodeStream(stream); err != nil {
+				common.Log.Debug("Error reading CIDToGIDMap: %v", err)
+				f.ttf = nil
+				return f
+			}
+		}
+	}
+	if w.textMode == SVGTextModeText && svgWebFont(data) {
+		// The program is embedded as is. Font programs of PDF files are
+		// usually subsets already, fully embedded ones are not reduced.
+		// Glyphs are drawn as paths in SVGTextModePaths, which does not
+		// need the program.
+		name := w.newID("font")
+		fmt.Fprintf(&w.style, "@font-face{font-family:\"%s\";src:url(data:font/ttf;base64,%s)}\n",
+			name, base64.StdEncoding.EncodeToString(data))
+		f.family = "'" + name + "'"
+		f.embedded = true
+	}
+	return f
+}
+
+// svgFontProgram returns the decoded TrueType program embedded for `pdfFont`.
+func svgFontProgram(pdfFont *model.PdfFont) []byte {
+	if subtype := pdfFont.Subtype(); subtype != "TrueType" && !strings.HasPrefix(subtype, "Type0") {
+		return nil
+	}
+	desc := pdfFont.FontDescriptor()
+	if desc == nil {
+		return nil
+	}
+	stream, ok := core.GetStream(desc.FontFile2)
+	if !ok {
+		return nil
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("Error decoding font program: %v", err)
+		return nil
+	}
+	return data
+}
+
+// svgWebFont returns true if the TrueType program `data` has all the tables
+// browsers require to load it. Subsets embedded in PDF files often omit
+// some of them.
+func svgWebFont(data []byte) bool {
+	if len(data) < 12 {
+		return false
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 && version != 0x74727565 {
+		return false
+	}
+	required := map[string]bool{
+		"cmap": false, "head": false, "hhea": false, "hmtx": false, "maxp": false,
+		"name": false, "OS/2": false, "post": false, "glyf": false, "loca": false,
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		offset := 12 + 16*i
+		if offset+16 > len(data) {
+			return false
+		}
+		tag := string(data[offset : offset+4])
+		if _, ok := required[tag]; ok {
+			required[tag] = true
+		}
+	}
+	for _, found := range required {
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// svgGenericFamily returns a CSS font family list, weight and style that
+// approximate the font named `baseFont`.
+func svgGenericFamily(baseFont string) (family, weight, style string) {
+	name := baseFont
+	if i := strings.IndexByte(name, '+'); i >= 0 {
+		name = name[i+1:]
+	}
+	lower := strings.ToLower(name)
+	containsAny := func(words ...string) bool {
+		for _, word := range words {
+			if strings.Contains(lower, word) {
+				return true
+			}
+		}
+		return false
+	}
+
+	generic := "sans-serif"
+	switch {
+	case containsAny("courier", "mono", "consol", "code"):
+		generic = "monospace"
+	case containsAny("sans", "arial", "helvetica", "verdana", "tahoma", "calibri"):
+	case containsAny("times", "serif", "roman", "georgia", "garamond", "minion", "cambria", "palatino", "bodoni", "book"):
+		generic = "serif"
+	}
+	weight, style = "normal", "normal"
+	if containsAny("bold", "black", "heavy", "demi", "semibold") {
+		weight = "bold"
+	}
+	if containsAny("italic", "oblique") {
+		style = "italic"
+	}
+
+	if i := strings.IndexAny(name, "-,"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == ' ' {
+			return r
+		}
+		return -1
+	}, name)
+	if name == "" {
+		return generic, weight, style
+	}
+	return fmt.Sprintf("'%s', %s", name, generic), weight, style
+}
+
+// gid returns the glyph index of character code `code`, which maps to
+// `text`, in the TrueType program of the font.
+func (f *svgFont) gid(code textencoding.CharCode, text string) (truetype.Index, bool) {
+	if f.ttf == nil {
+		return 0, false
+	}
+	if f.font.IsCID() {
+		if !f.identity {
+			return 0, false
+		}
+		cid := int(code)
+		if f.cidToGID == nil {
+			return truetype.Index(cid), true
+		}
+		if 2*cid+1 >= len(f.cidToGID) {
+			return 0, false
+		}
+		return truetype.Index(binary.BigEndian.Uint16(f.cidToGID[2*cid:])), true
+	}
+	if runes := []rune(text); len(runes) == 1 {
+		if gid := f.ttf.Index(runes[0]); gid != 0 {
+			return gid, true
+		}
+	}
+	if gid := f.ttf.Index(rune(code)); gid != 0 {
+		return gid, true
+	}
+	if gid := f.ttf.Index(0xF000 | rune(code)); gid != 0 {
+		return gid, true
+	}
+	return 0, false
+}
+
+// glyph returns the identifier of the path definition of glyph `gid` of
+// font `f`, in font units. An empty string is returned for empty glyphs.
+func (w *svgWriter) glyph(f *svgFont, gid truetype.Index) string {
+	if id, ok := f.glyphs[gid]; ok {
+		return id
+	}
+	var buf truetype.GlyphBuf
+	err := buf.Load(f.ttf, fixed.Int26_6(f.ttf.FUnitsPerEm()), gid, font.HintingNone)
+	if err != nil || len(buf.Ends) == 0 {
+		f.glyphs[gid] = ""
+		return ""
+	}
+	var d strings.Builder
+	start := 0
+	for _, end := range buf.Ends {
+		svgContour(&d, buf.Points[start:end])
+		start = end
+	}
+	id := w.newID("glyph")
+	fmt.Fprintf(&w.defs, `<path id="%s" d="%s"/>`+"\n", id, d.String())
+	f.glyphs[gid] = id
+	return id
+}
+
+// svgContour writes a closed TrueType contour made of quadratic segments.
+// With a scale of one em per unit, point coordinates are in font units.
+func svgContour(d *strings.Builder, pts []truetype.Point) {
+	n := len(pts)
+	if n == 0 {
+		return
+	}
+	onCurve := func(p truetype.Point) bool { return p.Flags&1 != 0 }
+	xy := func(p truetype.Point) (float64, float64) { return float64(p.X), float64(p.Y) }
+	mid := func(a, b truetype.Point) (float64, float64) {
+		ax, ay := xy(a)
+		bx, by := xy(b)
+		return (ax + bx) / 2, (ay + by) / 2
+	}
+	write := func(cmd string, vals ...float64) {
+		if d.Len() > 0 {
+			d.WriteByte(' ')
+		}
+		d.WriteString(cmd)
+		for _, v := range vals {
+			d.WriteByte(' ')
+			d.WriteString(svgNum(v))
+		}
+	}
+
+	first := -1
+	for i, p := range pts {
+		if onCurve(p) {
+			first = i
+			break
+		}
+	}
+	var sx, sy float64
+	if first >= 0 {
+		sx, sy = xy(pts[first])
+	} else {
+		// All points are off-curve: start at an implied on-curve point.
+		sx, sy = mid(pts[n-1], pts[0])
+		first = n - 1
+	}
+	write("M", sx, sy)
+
+	var ctrl *truetype.Point
+	for k := 1; k <= n; k++ {
+		p := pts[(first+k)%n]
+		if onCurve(p) {
+			x, y := xy(p)
+			if ctrl != nil {
+				cx, cy := xy(*ctrl)
+				write("Q", cx, cy, x, y)
+				ctrl = nil
+			} else {
+				write("L", x, y)
+			}
+			continue
+		}
+		if ctrl != nil {
+			cx, cy := xy(*ctrl)
+			mx, my := mid(*ctrl, p)
+			write("Q", cx, cy, mx, my)
+		}
+		off := p
+		ctrl = &off
+	}
+	if ctrl != nil {
+		cx, cy := xy(*ctrl)
+		write("Q", cx, cy, sx, sy)
+	}
+	d.WriteString(" Z")
+}
+
+// svgGlyph is a shown glyph, positioned in unscaled text space. Word gaps
+// made with positioning adjustments are represented by spaces marked as
+// synthetic, which only appear in text elements.
+type svgGlyph struct {
+	x, adv    float64
+	code      textencoding.CharCode
+	text      string
+	synthetic bool
+}
+
+func (c *svgConverter) moveText(tx, ty float64) {
+	c.tlm = c.tlm.Mult(transform.TranslationMatrix(tx, ty))
+	c.tm = c.tlm
+}
+
+// showText shows the strings in `items`, which may be interleaved with
+// positioning adjustments as in TJ operands, and advances the text matrix.
+func (c *svgConverter) showText(items []core.PdfObject) {
+	st := &c.state
+	f := st.font
+	if f == nil {
+		if c.w.defaultFont == nil {
+			c.w.defaultFont = c.w.newFont(model.DefaultFont())
+		}
+		f = c.w.defaultFont
+	}
+	fs := st.fontSize
+	var glyphs []svgGlyph
+	var x float64
+	for _, item := range items {
+		data, ok := core.GetStringBytes(item)
+		if !ok {
+			if v, err := core.GetNumberAsFloat(item); err == nil {
+				dx := -v / 1000 * fs
+				if n := len(glyphs); n > 0 && dx > 0.2*math.Abs(fs) && !strings.HasSuffix(glyphs[n-1].text, " ") {
+					glyphs = append(glyphs, svgGlyph{x: x, adv: dx, text: " ", synthetic: true})
+				}
+				x += dx
+			}
+			continue
+		}
+		codes := f.font.BytesToCharcodes(data)
+		texts, _, _ := f.font.CharcodesToStrings(codes, "")
+		for i, code := range codes {
+			metrics, _ := f.font.GetCharMetrics(code)
+			adv := metrics.Wx/1000*fs + st.charSpacing
+			if code == 32 && f.font.IsSimple() {
+				adv += st.wordSpace
+			}
+			var text string
+			if i < len(texts) {
+				text = texts[i]
+			}
+			glyphs = append(glyphs, svgGlyph{x: x, adv: adv, code: code, text: text})
+			x += adv
+		}
+	}
+	if len(glyphs) > 0 {
+		c.drawGlyphs(f, glyphs)
+	}
+	c.tm = c.tm.Mult(transform.TranslationMatrix(x*st.hScale, 0))
+}
+
+// drawGlyphs paints `glyphs` according to the text rendering mode. Clipping
+// text rendering modes are painted like their non-clipping counterparts.
+func (c *svgConverter) drawGlyphs(f *svgFont, glyphs []svgGlyph) {
+	st := &c.state
+	mode := st.renderMode
+	if mode >= 4 {
+		mode -= 4
+	}
+	invisible := mode == 3 || mode < 0
+	fill := mode == 0 || mode == 2
+	stroke := mode == 1 || mode == 2
+	textMatrix := st.ctm.Mult(c.tm.Mult(transform.NewMatrix(st.hScale, 0, 0, 1, 0, st.rise)))
+
+	// Scale between the CTM and text space, for stroke widths.
+	tm := c.tm
+	scale := math.Sqrt(math.Abs((tm[0]*tm[4] - tm[1]*tm[3]) * st.hScale))
+	if scale == 0 {
+		scale = 1
+	}
+
+	gids := make([]truetype.Index, len(glyphs))
+	found, consistent := f.ttf != nil, true
+	for i, g := range glyphs {
+		if g.synthetic {
+			continue
+		}
+		gid, ok := f.gid(g.code, g.text)
+		if !ok {
+			found = false
+			break
+		}
+		gids[i] = gid
+		runes := []rune(g.text)
+		if gid == 0 || len(runes) != 1 || f.ttf.Index(runes[0]) != gid {
+			consistent = false
+		}
+	}
+
+	usePaths := found && !invisible && (c.w.textMode == SVGTextModePaths || !f.embedded || !consistent)
+	if !usePaths {
+		family := f.generic
+		if f.embedded && consistent {
+			family = f.family
+		}
+		c.drawTextElement(f, family, glyphs, textMatrix, fill, stroke, invisible, scale)
+		return
+	}
+
+	upem := float64(f.ttf.FUnitsPerEm())
+	s := st.fontSize / upem
+	var uses strings.Builder
+	for i, g := range glyphs {
+		if g.synthetic {
+			continue
+		}
+		id := c.w.glyph(f, gids[i])
+		if id == "" {
+			continue
+		}
+		fmt.Fprintf(&uses, `<use xlink:href="#%s" transform="matrix(%s 0 0 %s %s 0)"/>`+"\n", id, svgNum(s), svgNum(s), svgNum(g.x))
+	}
+	if uses.Len() > 0 && s != 0 {
+		attrs := c.paintAttrs(textMatrix, fill, stroke, "nonzero", scale*s)
+		c.emit(fmt.Sprintf(`<g transform="%s"%s>`+"\n%s</g>", svgMatrix(textMatrix), attrs, uses.String()))
+	}
+	c.drawTextElement(f, f.generic, glyphs, textMatrix, false, false, true, scale)
+}
+
+// drawTextElement writes a <text> element with each character placed at the
+// position of its glyph. Invisible text keeps the content searchable.
+func (c *svgConverter) drawTextElement(f *svgFont, family string, glyphs []svgGlyph, textMatrix transform.Matrix,
+	fill, stroke, invisible bool, scale float64) {
+	var text strings.Builder
+	var xs []string
+	for _, g := range glyphs {
+		var runes []rune
+		for _, r := range g.text {
+			switch {
+			case r == '\t' || r == '\n' || r == '\r':
+				r = ' '
+			case !svgValidRune(r):
+				continue
+			}
+			runes = append(runes, r)
+		}
+		for k, r := range runes {
+			xs = append(xs, svgNum(g.x+g.adv*float64(k)/float64(len(runes))))
+			text.WriteRune(r)
+		}
+	}
+	if text.Len() == 0 {
+		return
+	}
+	m := textMatrix.Mult(transform.NewMatrix(1, 0, 0, -1, 0, 0))
+
+	var attrs strings.Builder
+	fmt.Fprintf(&attrs, ` font-family="%s" font-size="%s"`, family, svgNum(c.state.fontSize))
+	if !f.embedded || family != f.family {
+		if f.weight != "normal" {
+			fmt.Fprintf(&attrs, ` font-weight="%s"`, f.weight)
+		}
+		if f.style != "normal" {
+			fmt.Fprintf(&attrs, ` font-style="%s"`, f.style)
+		}
+	}
+	if invisible {
+		attrs.WriteString(` fill="#000000" fill-opacity="0"`)
+	} else {
+		attrs.WriteString(c.paintAttrs(m, fill, stroke, "nonzero", scale))
+	}
+	c.emit(fmt.Sprintf(`<text xml:space="preserve" x="%s" y="0" transform="%s"%s>%s</text>`,
+		strings.Join(xs, " "), svgMatrix(m), attrs.String(), svgEscaper.Replace(text.String())))
+}
+
+var svgEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;")
+
+// svgValidRune returns true if `r` may appear in XML character data.
+func svgValidRune(r rune) bool {
+	return r == 0x9 || r == 0xA || r == 0xD ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}